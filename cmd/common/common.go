@@ -47,6 +47,8 @@ const hiddenConfigFlagAnnotation = "walg_annotation_hidden_config_flag"
 func Init(cmd *cobra.Command, dbName string) {
 	internal.ConfigureSettings(dbName)
 	cobra.OnInitialize(conf.InitConfig, conf.Configure)
+	// the version is the first of the tab-separated version, git revision, build date and database
+	internal.WalgVersion, _, _ = strings.Cut(cmd.Version, "\t")
 
 	cmd.InitDefaultVersionFlag()
 	conf.AddConfigFlags(cmd, hiddenConfigFlagAnnotation)
@@ -64,6 +66,9 @@ func Init(cmd *cobra.Command, dbName string) {
 	// Add storage tools
 	cmd.AddCommand(st.StorageToolsCmd)
 
+	// Add self-update of the binary
+	cmd.AddCommand(newSelfUpdateCmd(dbName))
+
 	// profiler
 	persistentPreRun := cmd.PersistentPreRun
 	persistentPostRun := cmd.PersistentPostRun
@@ -77,6 +82,9 @@ func Init(cmd *cobra.Command, dbName string) {
 		var err error
 		p, err = internal.Profile()
 		tracelog.ErrorLogger.FatalOnError(err)
+
+		err = internal.ConfigureCompressionImplementation()
+		tracelog.ErrorLogger.FatalOnError(err)
 	}
 	cmd.PersistentPostRun = func(cmd *cobra.Command, args []string) {
 		if persistentPostRun != nil {
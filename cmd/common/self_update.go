@@ -0,0 +1,88 @@
+package common
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/selfupdate"
+)
+
+const (
+	selfUpdateShortDescription = "Replaces the wal-g binary with the release binary of the channel or the pinned version"
+	selfUpdateLongDescription  = `Downloads the release binary built for the same database and platform, verifies its checksum
+and the signature of the checksum against the release keys embedded into wal-g, and atomically replaces the
+current binary. The update is refused if there are no release keys to verify the signature with, unless
+the verification is turned off with --verify-signature=false. Once wal-g is pinned to a version, self-update keeps it at that version until it is unpinned.`
+
+	channelFlag         = "channel"
+	verifySignatureFlag = "verify-signature"
+	pinFlag             = "pin"
+	unpinFlag           = "unpin"
+	platformFlag        = "platform"
+	releasesURLFlag     = "releases-url"
+	releaseKeysFlag     = "release-keys"
+)
+
+var (
+	selfUpdateChannel         string
+	selfUpdateVerifySignature bool
+	selfUpdatePin             string
+	selfUpdateUnpin           bool
+	selfUpdatePlatform        string
+	selfUpdateReleasesURL     string
+	selfUpdateReleaseKeys     string
+)
+
+func newSelfUpdateCmd(dbName string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "self-update",
+		Short: selfUpdateShortDescription,
+		Long:  selfUpdateLongDescription,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if selfUpdateUnpin {
+				if selfUpdatePin != "" {
+					tracelog.ErrorLogger.Fatalf("--%s and --%s can't be used together", pinFlag, unpinFlag)
+				}
+				tracelog.ErrorLogger.FatalOnError(selfupdate.Unpin())
+				return
+			}
+
+			updater, err := selfupdate.NewUpdater(dbName, selfUpdatePlatform, internal.WalgVersion)
+			tracelog.ErrorLogger.FatalOnError(err)
+			updater.ReleasesURL = selfUpdateReleasesURL
+			if selfUpdateReleaseKeys != "" {
+				tracelog.ErrorLogger.FatalOnError(updater.AddKeys(selfUpdateReleaseKeys))
+			}
+			updater.VerifySignature = selfUpdateVerifySignature
+			if !updater.VerifySignature {
+				tracelog.WarningLogger.Println("The release signature verification is turned off, " +
+					"only the release checksum is verified")
+			}
+			if selfUpdatePin != "" {
+				tracelog.ErrorLogger.FatalOnError(updater.Pin(context.Background(), selfUpdatePin))
+				return
+			}
+			tracelog.ErrorLogger.FatalOnError(updater.Update(context.Background(), selfUpdateChannel))
+		},
+		PersistentPreRun: func(*cobra.Command, []string) {
+			// self-update doesn't use the storage or the database, so no settings are required
+		},
+	}
+	cmd.Flags().StringVar(&selfUpdateChannel, channelFlag, selfupdate.StableChannel,
+		"Release channel to update from: stable or prerelease")
+	cmd.Flags().BoolVar(&selfUpdateVerifySignature, verifySignatureFlag, true,
+		"Verify the signature of the release checksum, pass false to update from the releases that aren't signed")
+	cmd.Flags().StringVar(&selfUpdateReleaseKeys, releaseKeysFlag, "",
+		"File with the PEM-encoded Ed25519 release keys to trust in addition to the embedded ones")
+	cmd.Flags().StringVar(&selfUpdatePin, pinFlag, "",
+		"Install the version, e.g. v3.0.0, and keep the following updates at it")
+	cmd.Flags().BoolVar(&selfUpdateUnpin, unpinFlag, false, "Remove the pin, so the updates follow the channel again")
+	cmd.Flags().StringVar(&selfUpdatePlatform, platformFlag, "",
+		"Platform of the release binary, e.g. ubuntu-20.04-amd64, detected by default")
+	cmd.Flags().StringVar(&selfUpdateReleasesURL, releasesURLFlag, selfupdate.DefaultReleasesURL,
+		"GitHub API URL of the releases, e.g. of a mirror")
+	return cmd
+}
@@ -0,0 +1,75 @@
+package st
+
+import (
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/multistorage/exec"
+	"github.com/wal-g/wal-g/internal/storagetools"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	inventoryShortDescription = "Export the full listing of storage objects for offline analysis"
+	inventoryLongDescription  = "Export path, size, modification time and checksum (when available) of every object " +
+		"in the storage folder. The listing can be taken from a downloaded S3 Inventory or GCS Storage Insights " +
+		"report instead of the live storage to avoid listing API costs."
+
+	inventoryFormatFlag   = "format"
+	inventoryOutputFlag   = "output"
+	inventoryManifestFlag = "from-manifest"
+)
+
+// inventoryCmd represents the inventory command
+var inventoryCmd = &cobra.Command{
+	Use:   "inventory [relative folder path]",
+	Short: inventoryShortDescription,
+	Long:  inventoryLongDescription,
+	Args:  cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// not to leave an empty output file behind
+		err := storagetools.ValidateInventoryFormat(inventoryFormat)
+		tracelog.ErrorLogger.FatalOnError(err)
+
+		var output io.Writer = os.Stdout
+		if inventoryOutput != "" {
+			file, err := os.Create(inventoryOutput)
+			tracelog.ErrorLogger.FatalOnError(err)
+			defer utility.LoggedClose(file, "")
+			output = file
+		}
+
+		if inventoryManifest != "" {
+			err := storagetools.HandleInventory(nil, inventoryManifest, inventoryFormat, output)
+			tracelog.ErrorLogger.FatalOnError(err)
+			return
+		}
+
+		err = exec.OnStorage(targetStorage, func(folder storage.Folder) error {
+			if len(args) > 0 {
+				folder = folder.GetSubFolder(args[0])
+			}
+			return storagetools.HandleInventory(folder, "", inventoryFormat, output)
+		})
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+}
+
+var (
+	inventoryFormat   string
+	inventoryOutput   string
+	inventoryManifest string
+)
+
+func init() {
+	StorageToolsCmd.AddCommand(inventoryCmd)
+	inventoryCmd.Flags().StringVar(&inventoryFormat, inventoryFormatFlag, storagetools.InventoryFormatCSV,
+		"Output format: csv or json (one object per line), parquet is not supported")
+	inventoryCmd.Flags().StringVarP(&inventoryOutput, inventoryOutputFlag, "o", "",
+		"File to write the inventory to instead of stdout")
+	inventoryCmd.Flags().StringVar(&inventoryManifest, inventoryManifestFlag, "",
+		"Local path to an S3 Inventory or GCS Storage Insights manifest.json, the report files must be next to it")
+}
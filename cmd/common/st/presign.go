@@ -0,0 +1,45 @@
+package st
+
+import (
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/multistorage/exec"
+	"github.com/wal-g/wal-g/internal/storagetools"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const (
+	presignShortDescription = "Print a time-limited URL to download the specified storage object"
+	presignLongDescription  = "Print a pre-signed URL that allows anyone to download the object without credentials " +
+		"until it expires. Supported for S3, GCS (service account key required) and Azure (access key required)."
+
+	ttlFlag = "ttl"
+)
+
+// presignCmd represents the presign command
+var presignCmd = &cobra.Command{
+	Use:   "presign relative_object_path",
+	Short: presignShortDescription,
+	Long:  presignLongDescription,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if targetStorage == "all" {
+			tracelog.ErrorLogger.Fatalf("'all' target is not supported for st presign command")
+		}
+
+		err := exec.OnStorage(targetStorage, func(folder storage.Folder) error {
+			return storagetools.HandlePresign(args[0], presignTTL, folder, os.Stdout)
+		})
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+}
+
+var presignTTL time.Duration
+
+func init() {
+	StorageToolsCmd.AddCommand(presignCmd)
+	presignCmd.Flags().DurationVar(&presignTTL, ttlFlag, time.Hour, "How long the URL stays valid")
+}
@@ -7,6 +7,8 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/storagetools/transfer"
 )
 
 const transferShortDescription = "Moves objects from one storage to another (Postgres only)"
@@ -35,6 +37,10 @@ var (
 	transferMaxFiles                 uint
 	transferAppearanceChecks         uint
 	transferAppearanceChecksInterval time.Duration
+	transferStateFile                string
+	transferVerify                   bool
+	transferBandwidthLimit           int64
+	transferAtomic                   bool
 )
 
 func init() {
@@ -54,6 +60,15 @@ func init() {
 		"number of times to check if a file is appeared for reading in the target storage after writing it. Value 0 turns checking off")
 	transferCmd.PersistentFlags().DurationVar(&transferAppearanceChecksInterval, "appearance-checks-interval", time.Second,
 		"minimum time interval between performing checks for files to appear in the target storage")
+	transferCmd.PersistentFlags().StringVar(&transferStateFile, "state-file", "",
+		"path to a local file to record transferred files in. Rerunning the command with the same file skips them")
+	transferCmd.PersistentFlags().BoolVar(&transferVerify, "verify", false,
+		"whether to read each file back from the target storage and compare its checksum with the source one")
+	transferCmd.PersistentFlags().Int64Var(&transferBandwidthLimit, "bandwidth-limit", 0,
+		"max number of bytes per second to read from the source storage. Value 0 turns limiting off")
+	transferCmd.PersistentFlags().BoolVar(&transferAtomic, "atomic", false,
+		"whether to write each file to a temporary object and copy it to its place, so an interrupted transfer "+
+			"leaves no partially written files in the target storage")
 
 	StorageToolsCmd.AddCommand(transferCmd)
 }
@@ -74,5 +89,28 @@ func validateCommonFlags() error {
 	if transferConcurrency < 1 {
 		return fmt.Errorf("concurrency level must be >= 1 (which turns it off)")
 	}
+	if transferBandwidthLimit < 0 {
+		return fmt.Errorf("bandwidth limit must be >= 0 (which turns it off)")
+	}
 	return nil
 }
+
+func transferHandlerConfig() *transfer.HandlerConfig {
+	var temporaryTTL time.Duration
+	if transferAtomic {
+		var err error
+		temporaryTTL, err = internal.GetTemporaryObjectsTTL()
+		tracelog.ErrorLogger.FatalOnError(err)
+	}
+	return &transfer.HandlerConfig{
+		PreserveInSource:         transferPreserveInSource,
+		FailOnFirstErr:           transferFailFast,
+		Concurrency:              transferConcurrency,
+		AppearanceChecks:         transferAppearanceChecks,
+		AppearanceChecksInterval: transferAppearanceChecksInterval,
+		StateFilePath:            transferStateFile,
+		VerifyChecksums:          transferVerify,
+		BandwidthLimit:           transferBandwidthLimit,
+		TemporaryTTL:             temporaryTTL,
+	}
+}
@@ -23,15 +23,7 @@ var backupsCmd = &cobra.Command{
 			fileLister = transfer.NewSingleBackupFileLister(args[0], transferOverwrite, int(transferMaxFiles))
 		}
 
-		cfg := &transfer.HandlerConfig{
-			PreserveInSource:         transferPreserveInSource,
-			FailOnFirstErr:           transferFailFast,
-			Concurrency:              transferConcurrency,
-			AppearanceChecks:         transferAppearanceChecks,
-			AppearanceChecksInterval: transferAppearanceChecksInterval,
-		}
-
-		handler, err := transfer.NewHandler(transferSourceStorage, targetStorage, fileLister, cfg)
+		handler, err := transfer.NewHandler(transferSourceStorage, targetStorage, fileLister, transferHandlerConfig())
 		tracelog.ErrorLogger.FatalOnError(err)
 
 		err = handler.Handle()
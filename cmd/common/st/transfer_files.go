@@ -21,15 +21,7 @@ var filesCmd = &cobra.Command{
 func transferFiles(prefix string) {
 	separateFileLister := transfer.NewRegularFileLister(prefix, transferOverwrite, int(transferMaxFiles))
 
-	cfg := &transfer.HandlerConfig{
-		PreserveInSource:         transferPreserveInSource,
-		FailOnFirstErr:           transferFailFast,
-		Concurrency:              transferConcurrency,
-		AppearanceChecks:         transferAppearanceChecks,
-		AppearanceChecksInterval: transferAppearanceChecksInterval,
-	}
-
-	handler, err := transfer.NewHandler(transferSourceStorage, targetStorage, separateFileLister, cfg)
+	handler, err := transfer.NewHandler(transferSourceStorage, targetStorage, separateFileLister, transferHandlerConfig())
 	tracelog.ErrorLogger.FatalOnError(err)
 
 	err = handler.Handle()
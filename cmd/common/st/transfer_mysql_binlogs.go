@@ -0,0 +1,25 @@
+package st
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const mysqlBinlogsShortDescription = "Moves all MySQL binlog files from one storage to another"
+
+// mysqlBinlogPath is the same as mysql.BinlogPath, which isn't imported to keep the common commands DB-agnostic
+const mysqlBinlogPath = "binlog_" + utility.VersionStr + "/"
+
+// mysqlBinlogsCmd represents the mysql-binlogs command
+var mysqlBinlogsCmd = &cobra.Command{
+	Use:   "mysql-binlogs --source='source_storage' [--target='target_storage']",
+	Short: mysqlBinlogsShortDescription,
+	Args:  cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		transferFiles(mysqlBinlogPath)
+	},
+}
+
+func init() {
+	transferCmd.AddCommand(mysqlBinlogsCmd)
+}
@@ -0,0 +1,68 @@
+package st
+
+import (
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/multistorage/exec"
+	"github.com/wal-g/wal-g/internal/storagetools"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	verifyReplicaShortDescription = "Compare the storage with its replica made by the provider-native replication"
+	verifyReplicaLongDescription  = "Compare the objects in the storage with the ones under the replica prefix " +
+		"(e.g. the destination of S3 Cross-Region Replication) and report the missing, damaged and extra objects " +
+		"and the replication lag. The replica is accessed with the same settings as the storage."
+
+	replicaPrefixFlag = "replica-prefix"
+	checksumsFlag     = "checksums"
+	maxLagFlag        = "max-lag"
+)
+
+// verifyReplicaCmd represents the verify-replica command
+var verifyReplicaCmd = &cobra.Command{
+	Use:   "verify-replica [relative folder path] --replica-prefix <prefix>",
+	Short: verifyReplicaShortDescription,
+	Long:  verifyReplicaLongDescription,
+	Args:  cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if targetStorage == "all" {
+			tracelog.ErrorLogger.Fatalf("'all' target is not supported for st verify-replica command")
+		}
+
+		replicaStorage, err := internal.ConfigureStorageWithPrefix(replicaPrefix)
+		tracelog.ErrorLogger.FatalfOnError("Failed to configure the replica storage: %v", err)
+		defer utility.LoggedClose(replicaStorage, "close replica storage")
+
+		err = exec.OnStorage(targetStorage, func(folder storage.Folder) error {
+			replicaFolder := replicaStorage.RootFolder()
+			if len(args) > 0 {
+				folder = folder.GetSubFolder(args[0])
+				replicaFolder = replicaFolder.GetSubFolder(args[0])
+			}
+			return storagetools.HandleVerifyReplica(folder, replicaFolder, verifyChecksums, replicaMaxLag, os.Stdout)
+		})
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+}
+
+var (
+	replicaPrefix   string
+	verifyChecksums bool
+	replicaMaxLag   time.Duration
+)
+
+func init() {
+	StorageToolsCmd.AddCommand(verifyReplicaCmd)
+	verifyReplicaCmd.Flags().StringVar(&replicaPrefix, replicaPrefixFlag, "", "Prefix of the replica, e.g. s3://bucket-replica/path")
+	verifyReplicaCmd.Flags().BoolVar(&verifyChecksums, checksumsFlag, false,
+		"Also compare the checksums of the contents, every object is read from both storages")
+	verifyReplicaCmd.Flags().DurationVar(&replicaMaxLag, maxLagFlag, time.Hour,
+		"Objects that are not replicated for longer than this are reported as missing")
+	_ = verifyReplicaCmd.MarkFlagRequired(replicaPrefixFlag)
+}
@@ -8,6 +8,7 @@ import (
 )
 
 var confirmed = false
+var yesIKnow = ""
 
 // deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
@@ -39,31 +40,40 @@ var deleteEverythingCmd = &cobra.Command{
 }
 
 func runDeleteBefore(cmd *cobra.Command, args []string) {
+	tracelog.ErrorLogger.FatalOnError(internal.CheckMinPITRWindowUnsupported())
 	storage, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	defer internal.LockMetadataForDeletion(storage.RootFolder(), confirmed)()
 
 	deleteHandler, err := etcd.NewEtcdDeleteHandler(storage.RootFolder())
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
 
 	deleteHandler.HandleDeleteBefore(args, confirmed)
 }
 
 func runDeleteRetain(cmd *cobra.Command, args []string) {
+	tracelog.ErrorLogger.FatalOnError(internal.CheckMinPITRWindowUnsupported())
 	storage, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	defer internal.LockMetadataForDeletion(storage.RootFolder(), confirmed)()
 
 	deleteHandler, err := etcd.NewEtcdDeleteHandler(storage.RootFolder())
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
 
 	deleteHandler.HandleDeleteRetain(args, confirmed)
 }
 
 func runDeleteEverything(cmd *cobra.Command, args []string) {
+	tracelog.ErrorLogger.FatalOnError(internal.CheckMinPITRWindowUnsupported())
 	storage, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	defer internal.LockMetadataForDeletion(storage.RootFolder(), confirmed)()
 
 	deleteHandler, err := etcd.NewEtcdDeleteHandler(storage.RootFolder())
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
 
 	deleteHandler.DeleteEverything(confirmed)
 }
@@ -72,4 +82,5 @@ func init() {
 	cmd.AddCommand(deleteCmd)
 	deleteCmd.AddCommand(deleteBeforeCmd, deleteRetainCmd, deleteEverythingCmd)
 	deleteCmd.PersistentFlags().BoolVar(&confirmed, internal.ConfirmFlag, false, "Confirms backup deletion")
+	deleteCmd.PersistentFlags().StringVar(&yesIKnow, internal.YesIKnowFlag, "", internal.YesIKnowDescription)
 }
@@ -33,8 +33,10 @@ var walPushCmd = &cobra.Command{
 		signalHandler := utility.NewSignalHandler(ctx, cancel, []os.Signal{syscall.SIGINT, syscall.SIGTERM})
 		defer func() { _ = signalHandler.Close() }()
 
-		uploader, err := internal.ConfigureUploader()
+		uploader, err := internal.ConfigureWalStreamUploader()
 		tracelog.ErrorLogger.FatalOnError(err)
+		// the backup metadata which failed to upload at the end of backup-push is uploaded by the next wal-push
+		internal.FlushSpooledMetadata(uploader.Folder())
 
 		dataDir, err := conf.GetRequiredSetting(conf.ETCDMemberDataDirectory)
 		tracelog.ErrorLogger.FatalOnError(err)
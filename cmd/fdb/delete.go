@@ -9,6 +9,7 @@ import (
 )
 
 var confirmed = false
+var yesIKnow = ""
 
 // deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
@@ -47,41 +48,53 @@ var deleteEverythingCmd = &cobra.Command{
 }
 
 func runDeleteEverything(cmd *cobra.Command, args []string) {
+	tracelog.ErrorLogger.FatalOnError(internal.CheckMinPITRWindowUnsupported())
 	st, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	defer internal.LockMetadataForDeletion(st.RootFolder(), confirmed)()
 
 	deleteHandler, err := newFdbDeleteHandler(st.RootFolder())
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
 
 	deleteHandler.DeleteEverything(confirmed)
 }
 
 func runDeleteBefore(cmd *cobra.Command, args []string) {
+	tracelog.ErrorLogger.FatalOnError(internal.CheckMinPITRWindowUnsupported())
 	st, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	defer internal.LockMetadataForDeletion(st.RootFolder(), confirmed)()
 
 	deleteHandler, err := newFdbDeleteHandler(st.RootFolder())
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
 
 	deleteHandler.HandleDeleteBefore(args, confirmed)
 }
 
 func runDeleteRetain(args []string) {
+	tracelog.ErrorLogger.FatalOnError(internal.CheckMinPITRWindowUnsupported())
 	st, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	defer internal.LockMetadataForDeletion(st.RootFolder(), confirmed)()
 
 	deleteHandler, err := newFdbDeleteHandler(st.RootFolder())
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
 
 	deleteHandler.HandleDeleteRetain(args, confirmed)
 }
 
 func runDeleteRetainAfter(args []string) {
+	tracelog.ErrorLogger.FatalOnError(internal.CheckMinPITRWindowUnsupported())
 	st, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	defer internal.LockMetadataForDeletion(st.RootFolder(), confirmed)()
 
 	deleteHandler, err := newFdbDeleteHandler(st.RootFolder())
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
 
 	deleteHandler.HandleDeleteRetainAfter(args, confirmed)
 }
@@ -91,6 +104,7 @@ func init() {
 	deleteRetainCmd.Flags().StringP("after", "a", "", "Set the time after which retain backups")
 	deleteCmd.AddCommand(deleteBeforeCmd, deleteRetainCmd, deleteEverythingCmd)
 	deleteCmd.PersistentFlags().BoolVar(&confirmed, internal.ConfirmFlag, false, "Confirms backup deletion")
+	deleteCmd.PersistentFlags().StringVar(&yesIKnow, internal.YesIKnowFlag, "", internal.YesIKnowDescription)
 }
 
 func newFdbDeleteHandler(folder storage.Folder) (*internal.DeleteHandler, error) {
@@ -43,8 +43,8 @@ var (
 			if userDataRaw == "" {
 				userDataRaw = viper.GetString(conf.SentinelUserDataSetting)
 			}
-			userData, err := internal.UnmarshalSentinelUserData(userDataRaw)
-			tracelog.ErrorLogger.FatalfOnError("Failed to unmarshal the provided UserData: %s", err)
+			userData, err := internal.PrepareSentinelUserData(cmd.Context(), userDataRaw)
+			tracelog.ErrorLogger.FatalfOnError("Failed to prepare the UserData: %s", err)
 
 			deltaBaseSelector, err := internal.NewDeltaBaseSelector(
 				deltaFromName, deltaFromUserData, greenplum.NewGenericMetaFetcher())
@@ -29,7 +29,7 @@ var (
 
 			greenplum.SetSegmentStoragePrefix(contentID)
 
-			uploader, err := internal.ConfigureUploader()
+			uploader, err := internal.ConfigureBackupUploader()
 			tracelog.ErrorLogger.FatalOnError(err)
 
 			dataDirectory := args[0]
@@ -9,6 +9,7 @@ import (
 
 var confirmed = false
 var deleteTargetUserData = ""
+var yesIKnow = ""
 
 const DeleteGarbageExamples = `  garbage           Deletes outdated WAL archives and leftover backups files from storage`
 const DeleteGarbageUse = "garbage"
@@ -56,41 +57,52 @@ var deleteGarbageCmd = &cobra.Command{
 }
 
 func runDeleteBefore(cmd *cobra.Command, args []string) {
+	tracelog.ErrorLogger.FatalOnError(internal.CheckMinPITRWindowUnsupported())
 	storage, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	defer internal.LockMetadataForDeletion(storage.RootFolder(), confirmed)()
 
 	delArgs := greenplum.DeleteArgs{Confirmed: confirmed}
 	deleteHandler, err := greenplum.NewDeleteHandler(storage.RootFolder(), delArgs)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
 
 	deleteHandler.HandleDeleteBefore(args)
 }
 
 func runDeleteRetain(cmd *cobra.Command, args []string) {
+	tracelog.ErrorLogger.FatalOnError(internal.CheckMinPITRWindowUnsupported())
 	storage, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	defer internal.LockMetadataForDeletion(storage.RootFolder(), confirmed)()
 
 	delArgs := greenplum.DeleteArgs{Confirmed: confirmed}
 	deleteHandler, err := greenplum.NewDeleteHandler(storage.RootFolder(), delArgs)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
 
 	deleteHandler.HandleDeleteRetain(args)
 }
 
 func runDeleteEverything(cmd *cobra.Command, args []string) {
+	tracelog.ErrorLogger.FatalOnError(internal.CheckMinPITRWindowUnsupported())
 	storage, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	defer internal.LockMetadataForDeletion(storage.RootFolder(), confirmed)()
 
 	delArgs := greenplum.DeleteArgs{Confirmed: confirmed}
 	deleteHandler, err := greenplum.NewDeleteHandler(storage.RootFolder(), delArgs)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
 
 	deleteHandler.HandleDeleteEverything(args)
 }
 
 func runDeleteTarget(cmd *cobra.Command, args []string) {
+	tracelog.ErrorLogger.FatalOnError(internal.CheckMinPITRWindowUnsupported())
 	storage, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	defer internal.LockMetadataForDeletion(storage.RootFolder(), confirmed)()
 
 	findFullBackup := false
 	modifier := internal.ExtractDeleteTargetModifierFromArgs(args)
@@ -102,11 +114,11 @@ func runDeleteTarget(cmd *cobra.Command, args []string) {
 
 	delArgs := greenplum.DeleteArgs{Confirmed: confirmed, FindFull: findFullBackup}
 	deleteHandler, err := greenplum.NewDeleteHandler(storage.RootFolder(), delArgs)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
 
 	targetBackupSelector, err := internal.CreateTargetDeleteBackupSelector(
 		cmd, args, deleteTargetUserData, greenplum.NewGenericMetaFetcher())
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
 
 	deleteHandler.HandleDeleteTarget(targetBackupSelector)
 }
@@ -114,13 +126,15 @@ func runDeleteTarget(cmd *cobra.Command, args []string) {
 func runDeleteGarbage(cmd *cobra.Command, args []string) {
 	storage, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	defer internal.LockMetadataForDeletion(storage.RootFolder(), confirmed)()
 
 	delArgs := greenplum.DeleteArgs{Confirmed: confirmed}
 	deleteHandler, err := greenplum.NewDeleteHandler(storage.RootFolder(), delArgs)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
 
 	err = deleteHandler.HandleDeleteGarbage(args)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
 }
 
 func init() {
@@ -131,4 +145,5 @@ func init() {
 
 	deleteCmd.AddCommand(deleteRetainCmd, deleteBeforeCmd, deleteEverythingCmd, deleteTargetCmd, deleteGarbageCmd)
 	deleteCmd.PersistentFlags().BoolVar(&confirmed, internal.ConfirmFlag, false, "Confirms backup deletion")
+	deleteCmd.PersistentFlags().StringVar(&yesIKnow, internal.YesIKnowFlag, "", internal.YesIKnowDescription)
 }
@@ -0,0 +1,32 @@
+package gp
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	globalsFetchShortDescription = "Fetches the dump of the global objects (roles, tablespaces) taken with the backup"
+	globalsApplyDescription      = "Apply the dump to the running cluster with WALG_GLOBALS_APPLY_COMMAND instead of printing it"
+)
+
+var applyGlobals bool
+
+// globalsFetchCmd represents the globalsFetch command
+var globalsFetchCmd = &cobra.Command{
+	Use:   "globals-fetch backup_name",
+	Short: globalsFetchShortDescription,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		storage, err := internal.ConfigureStorage()
+		tracelog.ErrorLogger.FatalOnError(err)
+		postgres.HandleGlobalsFetch(cmd.Context(), storage.RootFolder(), args[0], applyGlobals)
+	},
+}
+
+func init() {
+	cmd.AddCommand(globalsFetchCmd)
+	globalsFetchCmd.Flags().BoolVar(&applyGlobals, "apply", false, globalsApplyDescription)
+}
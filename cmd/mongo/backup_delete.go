@@ -17,6 +17,7 @@ const backupDeleteShortDescription = "Deletes backup data from storage"
 
 var (
 	confirmedBackupDelete bool
+	yesIKnowBackupDelete  string
 )
 
 // backupDeleteCmd represents the backupDelete command
@@ -25,11 +26,16 @@ var backupDeleteCmd = &cobra.Command{
 	Short: backupDeleteShortDescription,
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		tracelog.ErrorLogger.FatalOnError(internal.CheckMinPITRWindowUnsupported())
 		ctx, cancel := context.WithCancel(context.Background())
 		signalHandler := utility.NewSignalHandler(ctx, cancel, []os.Signal{syscall.SIGINT, syscall.SIGTERM})
 		defer func() { _ = signalHandler.Close() }()
 
 		backupName := args[0]
+		if confirmedBackupDelete {
+			err := internal.CheckWideDeletionGuardrail("backup-delete "+backupName, 1, yesIKnowBackupDelete)
+			tracelog.ErrorLogger.FatalOnError(err)
+		}
 
 		// set up storage downloader client
 		downloader, err := archive.NewStorageDownloader(archive.NewDefaultStorageSettings())
@@ -46,5 +52,6 @@ var backupDeleteCmd = &cobra.Command{
 
 func init() {
 	backupDeleteCmd.Flags().BoolVar(&confirmedBackupDelete, internal.ConfirmFlag, false, "Confirms backup deletion")
+	backupDeleteCmd.Flags().StringVar(&yesIKnowBackupDelete, internal.YesIKnowFlag, "", internal.YesIKnowDescription)
 	cmd.AddCommand(backupDeleteCmd)
 }
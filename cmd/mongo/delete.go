@@ -23,6 +23,7 @@ var (
 	purgeGarbage bool
 	retainAfter  string
 	retainCount  uint
+	yesIKnow     string
 )
 
 // deleteCmd represents the delete command
@@ -33,32 +34,36 @@ var deleteCmd = &cobra.Command{
 }
 
 func runPurge(cmd *cobra.Command, args []string) {
+	tracelog.ErrorLogger.FatalOnError(internal.CheckMinPITRWindowUnsupported())
 	opts := []mongo.PurgeOption{
 		mongo.PurgeDryRun(!confirmed),
 		mongo.PurgeOplog(purgeOplog),
-		mongo.PurgeGarbage(purgeGarbage)}
+		mongo.PurgeGarbage(purgeGarbage),
+		mongo.PurgeGuardrailConfirmation(yesIKnow)}
 	if cmd.Flags().Changed(retainAfterFlag) {
 		retainAfterTime, err := time.Parse(time.RFC3339, retainAfter)
 		tracelog.ErrorLogger.FatalfOnError("Can not parse retain time: %v", err)
 		opts = append(opts, mongo.PurgeRetainAfter(retainAfterTime))
-	} else if cmd.Flags().Changed(purgeOplogFlag) {
-		tracelog.ErrorLogger.Fatalf("Flag %q requires %q to be passed\n", purgeOplogFlag, retainAfterFlag)
 	}
 
 	if cmd.Flags().Changed(retainCountFlag) {
 		opts = append(opts, mongo.PurgeRetainCount(int(retainCount)))
 	}
 
+	st, err := internal.ConfigureStorage()
+	tracelog.ErrorLogger.FatalOnError(err)
+	defer internal.LockMetadataForDeletion(st.RootFolder(), confirmed)()
+
 	// set up storage downloader client
 	downloader, err := archive.NewStorageDownloader(archive.NewDefaultStorageSettings())
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
 
 	// set up storage downloader client
 	purger, err := archive.NewStoragePurger(archive.NewDefaultStorageSettings())
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
 
 	err = mongo.HandlePurge(downloader, purger, opts...)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
 }
 
 func init() {
@@ -70,4 +75,5 @@ func init() {
 	deleteCmd.Flags().BoolVar(&purgeGarbage, purgeGarbageFlag, false, "Purge garbage in backup folder")
 	deleteCmd.Flags().StringVar(&retainAfter, retainAfterFlag, "", "Keep backups newer")
 	deleteCmd.Flags().UintVar(&retainCount, retainCountFlag, 0, "Keep minimum count, except permanent backups")
+	deleteCmd.Flags().StringVar(&yesIKnow, internal.YesIKnowFlag, "", internal.YesIKnowDescription)
 }
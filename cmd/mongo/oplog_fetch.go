@@ -16,13 +16,14 @@ import (
 )
 
 var (
-	format string
+	format     string
+	fetchToDir string
 )
 
 // oplogFetchCmd represents oplog replay procedure
 var oplogFetchCmd = &cobra.Command{
-	Use:   "oplog-fetch <since ts.inc> <until ts.inc>",
-	Short: "Fetches oplog archives from storage and dumps to stdout",
+	Use:   "oplog-fetch <since ts.inc|RFC3339> <until ts.inc|RFC3339>",
+	Short: "Fetches oplog archives from storage and dumps to stdout or saves them to the directory",
 	Args:  cobra.ExactArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx, cancel := context.WithCancel(context.Background())
@@ -30,15 +31,11 @@ var oplogFetchCmd = &cobra.Command{
 		defer func() { _ = signalHandler.Close() }()
 
 		// resolve archiving settings
-		since, err := models.TimestampFromStr(args[0])
+		since, err := models.ParseTimestamp(args[0])
 		tracelog.ErrorLogger.FatalOnError(err)
-		until, err := models.TimestampFromStr(args[1])
+		until, err := models.ParseTimestamp(args[1])
 		tracelog.ErrorLogger.FatalOnError(err)
 
-		formatApplier, err := oplog.NewWriteApplier(format, os.Stdout)
-		tracelog.ErrorLogger.FatalOnError(err)
-		oplogApplier := stages.NewGenericApplier(formatApplier)
-
 		// set up storage downloader client
 		downloader, err := archive.NewStorageDownloader(archive.NewDefaultStorageSettings())
 		tracelog.ErrorLogger.FatalOnError(err)
@@ -49,6 +46,16 @@ var oplogFetchCmd = &cobra.Command{
 		path, err := archive.SequenceBetweenTS(archives, since, until)
 		tracelog.ErrorLogger.FatalOnError(err)
 
+		if fetchToDir != "" {
+			err = mongo.HandleOplogFetchToDir(ctx, downloader, path, fetchToDir)
+			tracelog.ErrorLogger.FatalOnError(err)
+			return
+		}
+
+		formatApplier, err := oplog.NewWriteApplier(format, os.Stdout)
+		tracelog.ErrorLogger.FatalOnError(err)
+		oplogApplier := stages.NewGenericApplier(formatApplier)
+
 		// setup storage fetcher
 		oplogFetcher := stages.NewStorageFetcher(downloader, path)
 
@@ -62,4 +69,6 @@ func init() {
 	cmd.AddCommand(oplogFetchCmd)
 	oplogFetchCmd.PersistentFlags().StringVarP(
 		&format, "format", "f", "json", "Valid values: json, bson, bson-raw")
+	oplogFetchCmd.PersistentFlags().StringVar(
+		&fetchToDir, "to", "", "Save the oplog archives covering the time window into the directory instead of dumping to stdout")
 }
@@ -34,6 +34,7 @@ func pitrDiscoveryAfterTime() *time.Time {
 }
 
 func runOplogPurge(cmd *cobra.Command, args []string) {
+	tracelog.ErrorLogger.FatalOnError(internal.CheckMinPITRWindowUnsupported())
 	pitrAfterTime := pitrDiscoveryAfterTime()
 	// set up storage downloader client
 	downloader, err := archive.NewStorageDownloader(archive.NewDefaultStorageSettings())
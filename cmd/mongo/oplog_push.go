@@ -55,10 +55,12 @@ func init() {
 func runOplogPush(ctx context.Context, pushArgs oplogPushRunArgs, statsArgs oplogPushStatsArgs) error {
 	// set up storage client
 	tracelog.DebugLogger.Printf("starting oplog archiving with arguments: %+v", pushArgs)
-	uplProvider, err := internal.ConfigureUploader()
+	uplProvider, err := internal.ConfigureWalStreamUploader()
 	if err != nil {
 		return err
 	}
+	// the backup metadata which failed to upload at the end of backup-push is uploaded by the next oplog-push
+	internal.FlushSpooledMetadata(uplProvider.Folder())
 	uplProvider.ChangeDirectory(models.OplogArchBasePath)
 	uploader := archive.NewStorageUploader(uplProvider)
 
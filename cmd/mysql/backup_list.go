@@ -13,6 +13,7 @@ const (
 	PrettyFlag                 = "pretty"
 	JSONFlag                   = "json"
 	DetailFlag                 = "detail"
+	UserDataFilterFlag         = "user-data-filter"
 )
 
 var (
@@ -25,15 +26,18 @@ var (
 			storage, err := internal.ConfigureStorage()
 			tracelog.ErrorLogger.FatalOnError(err)
 			if detail {
-				mysql.HandleDetailedBackupList(storage.RootFolder().GetSubFolder(utility.BaseBackupPath), pretty, json)
+				mysql.HandleDetailedBackupList(storage.RootFolder().GetSubFolder(utility.BaseBackupPath), userDataFilter, pretty, json)
 			} else {
-				internal.HandleDefaultBackupList(storage.RootFolder().GetSubFolder(utility.BaseBackupPath), pretty, json)
+				internal.HandleFilteredBackupList(storage.RootFolder().GetSubFolder(utility.BaseBackupPath),
+					userDataFilter, mysql.NewGenericMetaFetcher(), pretty, json)
 			}
 		},
 	}
 	json   = false
 	pretty = false
 	detail = false
+
+	userDataFilter map[string]string
 )
 
 func init() {
@@ -44,4 +48,6 @@ func init() {
 	backupListCmd.Flags().BoolVar(&pretty, PrettyFlag, false, "Prints more readable output")
 	backupListCmd.Flags().BoolVar(&json, JSONFlag, false, "Prints output in json format")
 	backupListCmd.Flags().BoolVar(&detail, DetailFlag, false, "Prints extra backup details")
+	backupListCmd.Flags().StringToStringVar(&userDataFilter, UserDataFilterFlag, nil,
+		"Prints only the backups with the user data field (dot-separated path for nested ones) equal to the value")
 }
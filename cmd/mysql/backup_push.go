@@ -35,6 +35,14 @@ var (
 			uploader, err := internal.ConfigureSplitUploader()
 			tracelog.ErrorLogger.FatalOnError(err)
 			folder := uploader.Folder()
+			err = internal.EnforceStorageQuota(folder, func(retainCount int) error {
+				deleteHandler, err := mysql.NewDeleteHandler(folder)
+				if err != nil {
+					return err
+				}
+				return deleteHandler.DeleteRetainFull(retainCount, mysql.NewBinlogGapFinder(folder))
+			})
+			tracelog.ErrorLogger.FatalOnError(err)
 			uploader.ChangeDirectory(utility.BaseBackupPath)
 			backupCmd, err := internal.GetCommandSetting(conf.NameStreamCreateCmd)
 			tracelog.ErrorLogger.FatalOnError(err)
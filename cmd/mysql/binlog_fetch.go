@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	conf "github.com/wal-g/wal-g/internal/config"
@@ -16,21 +17,44 @@ const fetchUntilFlagShortDescr = "time in RFC3339 for PITR"
 const fetchUntilBinlogLastModifiedFlagShortDescr = "time in RFC3339 that is used to prevent wal-g from replaying" +
 	" binlogs that was created/modified after this time"
 
+const fetchBetweenFlagShortDescr = "time window in RFC3339 to fetch the binlogs covering it, e.g. --between t1 t2"
+const fetchToFlagShortDescr = "directory to fetch the binlogs into instead of " + conf.MysqlBinlogDstSetting
+const fetchRelayLogsFlagShortDescr = "fetch the relay logs archived from the replica instead of the binlogs"
+
 var fetchBackupName string
 var fetchUntilTS string
 var fetchUntilBinlogLastModifiedTS string
+var fetchBetween []string
+var fetchToDir string
+var fetchRelayLogs bool
 
 // binlogPushCmd represents the cron command
 var binlogFetchCmd = &cobra.Command{
 	Use:   "binlog-fetch",
 	Short: "Fetch binlog from storage and save it to the disk",
-	Args:  cobra.NoArgs,
+	Args:  cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		storage, err := internal.ConfigureStorage()
 		tracelog.ErrorLogger.FatalOnError(err)
-		mysql.HandleBinlogFetch(storage.RootFolder(), fetchBackupName, fetchUntilTS, fetchUntilBinlogLastModifiedTS)
+		if len(fetchBetween) > 0 {
+			// allow "--between t1 t2" along with "--between t1,t2"
+			window := append(append([]string{}, fetchBetween...), args...)
+			if len(window) != 2 {
+				tracelog.ErrorLogger.Fatalf("--between expects two timestamps, got %d", len(window))
+			}
+			mysql.HandleBinlogFetchBetween(storage.RootFolder(), window[0], window[1], fetchToDir)
+			return
+		}
+		if len(args) > 0 {
+			tracelog.ErrorLogger.Fatalf("unexpected argument %q", args[0])
+		}
+		mysql.HandleBinlogFetch(storage.RootFolder(), fetchBackupName, fetchUntilTS, fetchUntilBinlogLastModifiedTS,
+			fetchRelayLogs)
 	},
 	PreRun: func(cmd *cobra.Command, args []string) {
+		if fetchToDir != "" {
+			viper.Set(conf.MysqlBinlogDstSetting, fetchToDir)
+		}
 		conf.RequiredSettings[conf.MysqlBinlogDstSetting] = true
 		err := internal.AssertRequiredSettingsSet()
 		tracelog.ErrorLogger.FatalOnError(err)
@@ -47,5 +71,8 @@ func init() {
 		"until-binlog-last-modified-time",
 		"",
 		fetchUntilBinlogLastModifiedFlagShortDescr)
+	binlogFetchCmd.PersistentFlags().StringSliceVar(&fetchBetween, "between", nil, fetchBetweenFlagShortDescr)
+	binlogFetchCmd.PersistentFlags().StringVar(&fetchToDir, "to", "", fetchToFlagShortDescr)
+	binlogFetchCmd.PersistentFlags().BoolVar(&fetchRelayLogs, "relay-logs", false, fetchRelayLogsFlagShortDescr)
 	cmd.AddCommand(binlogFetchCmd)
 }
@@ -1,6 +1,8 @@
 package mysql
 
 import (
+	"time"
+
 	"github.com/spf13/cobra"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
@@ -10,7 +12,11 @@ import (
 
 const binlogPushShortDescription = "Upload binlogs to the storage"
 
-var untilBinlog string
+var (
+	untilBinlog    string
+	daemon         bool
+	daemonInterval time.Duration
+)
 
 // binlogPushCmd represents the cron command
 var binlogPushCmd = &cobra.Command{
@@ -18,19 +24,49 @@ var binlogPushCmd = &cobra.Command{
 	Short: binlogPushShortDescription,
 	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		uploader, err := internal.ConfigureUploader()
+		uploader, err := internal.ConfigureWalStreamUploader()
 		tracelog.ErrorLogger.FatalOnError(err)
+		// the backup metadata which failed to upload at the end of backup-push is uploaded by the next binlog-push
+		internal.FlushSpooledMetadata(uploader.Folder())
 		checkGTIDs, _ := conf.GetBoolSettingDefault(conf.MysqlCheckGTIDs, false)
-		mysql.HandleBinlogPush(uploader, untilBinlog, checkGTIDs)
+		purgeOptions := mysql.BinlogPurgeOptions{}
+		purgeOptions.Enabled, err = conf.GetBoolSettingDefault(conf.MysqlBinlogPurge, false)
+		tracelog.ErrorLogger.FatalOnError(err)
+		if purgeOptions.Enabled {
+			purgeOptions.SafetyLag, err = conf.GetDurationSetting(conf.MysqlBinlogPurgeSafetyLag)
+			tracelog.ErrorLogger.FatalOnError(err)
+			purgeOptions.Interval, err = conf.GetDurationSetting(conf.MysqlBinlogPurgeInterval)
+			tracelog.ErrorLogger.FatalOnError(err)
+			if purgeOptions.Interval <= 0 {
+				tracelog.ErrorLogger.Fatalf("%s must be positive", conf.MysqlBinlogPurgeInterval)
+			}
+		}
+		archiveRelayLogs, err := conf.GetBoolSettingDefault(conf.MysqlArchiveRelayLogs, false)
+		tracelog.ErrorLogger.FatalOnError(err)
+		if daemon {
+			mysql.HandleBinlogPushDaemon(uploader, checkGTIDs, purgeOptions, archiveRelayLogs, daemonInterval)
+			return
+		}
+		err = mysql.HandleBinlogPush(uploader, untilBinlog, checkGTIDs, purgeOptions, archiveRelayLogs)
+		tracelog.ErrorLogger.FatalOnError(err)
 	},
 	PreRun: func(cmd *cobra.Command, args []string) {
 		conf.RequiredSettings[conf.MysqlDatasourceNameSetting] = true
 		err := internal.AssertRequiredSettingsSet()
 		tracelog.ErrorLogger.FatalOnError(err)
+		if daemon && untilBinlog != "" {
+			tracelog.ErrorLogger.Fatal("--until can't be used with --daemon")
+		}
+		if daemon && daemonInterval <= 0 {
+			tracelog.ErrorLogger.Fatal("--interval must be positive")
+		}
 	},
 }
 
 func init() {
 	cmd.AddCommand(binlogPushCmd)
 	binlogPushCmd.Flags().StringVar(&untilBinlog, "until", "", "binlog file name to stop at. Current active by default")
+	binlogPushCmd.Flags().BoolVar(&daemon, "daemon", false,
+		"keep running and upload the new binlogs every --interval, the archived binlogs are purged in the background")
+	binlogPushCmd.Flags().DurationVar(&daemonInterval, "interval", time.Minute, "how often the daemon uploads the new binlogs")
 }
@@ -15,10 +15,12 @@ const replaySinceFlagShortDescr = "backup name starting from which you want to f
 const replayUntilFlagShortDescr = "time in RFC3339 for PITR"
 const replayUntilBinlogLastModifiedFlagShortDescr = "time in RFC3339 that is used to prevent wal-g from replaying" +
 	" binlogs that was created/modified after this time"
+const replayRelayLogsFlagShortDescr = "replay the relay logs archived from the replica instead of the binlogs"
 
 var replayBackupName string
 var replayUntilTS string
 var replayUntilBinlogLastModifiedTS string
+var replayRelayLogs bool
 
 var binlogReplayCmd = &cobra.Command{
 	Use:   "binlog-replay",
@@ -27,7 +29,8 @@ var binlogReplayCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		storage, err := internal.ConfigureStorage()
 		tracelog.ErrorLogger.FatalOnError(err)
-		mysql.HandleBinlogReplay(storage.RootFolder(), replayBackupName, replayUntilTS, replayUntilBinlogLastModifiedTS)
+		mysql.HandleBinlogReplay(storage.RootFolder(), replayBackupName, replayUntilTS, replayUntilBinlogLastModifiedTS,
+			replayRelayLogs)
 	},
 	PreRun: func(cmd *cobra.Command, args []string) {
 		conf.RequiredSettings[conf.MysqlBinlogReplayCmd] = true
@@ -42,5 +45,6 @@ func init() {
 		utility.TimeNowCrossPlatformUTC().Format(time.RFC3339), replayUntilFlagShortDescr)
 	binlogReplayCmd.PersistentFlags().StringVar(&replayUntilBinlogLastModifiedTS, "until-binlog-last-modified-time",
 		"", replayUntilBinlogLastModifiedFlagShortDescr)
+	binlogReplayCmd.PersistentFlags().BoolVar(&replayRelayLogs, "relay-logs", false, replayRelayLogsFlagShortDescr)
 	cmd.AddCommand(binlogReplayCmd)
 }
@@ -0,0 +1,56 @@
+package mysql
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/mysql"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	cryptoShortDescription = "Manage the encryption of the stored backups"
+	rekeyShortDescription  = "Re-encrypts backups and binlog files from the old key to the currently configured one"
+	rekeyLongDescription   = "Streams every backup and binlog file through decryption with the key from the old config " +
+		"and encryption with the currently configured key, and records the new key fingerprint in the backup sentinels. " +
+		"The progress is saved in the storage, so an interrupted run is resumed by running the command again."
+
+	rekeyOldConfigFlag   = "old-config"
+	rekeyConcurrencyFlag = "concurrency"
+)
+
+var (
+	cryptoCmd = &cobra.Command{
+		Use:   "crypto",
+		Short: cryptoShortDescription,
+	}
+
+	rekeyCmd = &cobra.Command{
+		Use:   "rekey --old-config=old_config_path",
+		Short: rekeyShortDescription,
+		Long:  rekeyLongDescription,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			storage, err := internal.ConfigureStorage()
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			oldCrypter := internal.CrypterFromConfig(rekeyOldConfig)
+			newCrypter := internal.ConfigureCrypter()
+			err = internal.HandleRekey(storage.RootFolder(), rekeyPrefixes, oldCrypter, newCrypter, rekeyConcurrency)
+			tracelog.ErrorLogger.FatalOnError(err)
+		},
+	}
+	rekeyOldConfig   string
+	rekeyConcurrency int
+	// rekeyPrefixes are the storage prefixes the encrypted data is written under
+	rekeyPrefixes = []string{utility.BaseBackupPath, mysql.BinlogPath, mysql.RelayLogPath}
+)
+
+func init() {
+	cmd.AddCommand(cryptoCmd)
+	cryptoCmd.AddCommand(rekeyCmd)
+
+	rekeyCmd.Flags().StringVar(&rekeyOldConfig, rekeyOldConfigFlag, "", "Config file with the old encryption key")
+	rekeyCmd.Flags().IntVar(&rekeyConcurrency, rekeyConcurrencyFlag, 4, "Number of objects to re-encrypt concurrently")
+	_ = rekeyCmd.MarkFlagRequired(rekeyOldConfigFlag)
+}
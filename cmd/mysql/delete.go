@@ -8,6 +8,8 @@ import (
 )
 
 var confirmed = false
+var yesIKnow = ""
+var forcePITRBreak = false
 
 // deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
@@ -48,16 +50,29 @@ var deleteTargetCmd = &cobra.Command{
 func runDeleteEverything(cmd *cobra.Command, args []string) {
 	storage, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	unlock := internal.LockMetadataForDeletion(storage.RootFolder(), confirmed)
+	defer unlock()
 
 	deleteHandler, err := mysql.NewDeleteHandler(storage.RootFolder())
-	tracelog.ErrorLogger.FatalOnError(err)
-
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
+
+	if confirmed {
+		err = internal.CheckDeletionPITRWindow(func() (*internal.DeleteExplanation, error) {
+			return deleteHandler.ExplainDeleteEverything(args)
+		}, mysql.NewBinlogGapFinder(storage.RootFolder()), forcePITRBreak)
+		if err != nil {
+			internal.FatalOnErrorReleasingLocks(err)
+		}
+	}
 	deleteHandler.HandleDeleteEverything(args, confirmed)
 }
 
 func runDeleteTarget(cmd *cobra.Command, args []string) {
 	storage, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	unlock := internal.LockMetadataForDeletion(storage.RootFolder(), confirmed)
+	defer unlock()
 
 	findFullBackup := false
 	modifier := internal.ExtractDeleteTargetModifierFromArgs(args)
@@ -68,32 +83,62 @@ func runDeleteTarget(cmd *cobra.Command, args []string) {
 	}
 
 	deleteHandler, err := mysql.NewDeleteHandler(storage.RootFolder())
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
 
 	backupName := args[0]
 	backupSelector, err := internal.NewBackupNameSelector(backupName, true) //todo: add selection by userdata
 	tracelog.ErrorLogger.PrintOnError(err)
 
+	if confirmed {
+		err = internal.CheckDeletionPITRWindow(func() (*internal.DeleteExplanation, error) {
+			return deleteHandler.ExplainDeleteTarget(backupSelector, findFullBackup)
+		}, mysql.NewBinlogGapFinder(storage.RootFolder()), forcePITRBreak)
+		if err != nil {
+			internal.FatalOnErrorReleasingLocks(err)
+		}
+	}
 	deleteHandler.HandleDeleteTarget(backupSelector, confirmed, findFullBackup)
 }
 
 func runDeleteBefore(cmd *cobra.Command, args []string) {
 	storage, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	unlock := internal.LockMetadataForDeletion(storage.RootFolder(), confirmed)
+	defer unlock()
 
 	deleteHandler, err := mysql.NewDeleteHandler(storage.RootFolder())
-	tracelog.ErrorLogger.FatalOnError(err)
-
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
+
+	if confirmed {
+		err = internal.CheckDeletionPITRWindow(func() (*internal.DeleteExplanation, error) {
+			return deleteHandler.ExplainDeleteBefore(args)
+		}, mysql.NewBinlogGapFinder(storage.RootFolder()), forcePITRBreak)
+		if err != nil {
+			internal.FatalOnErrorReleasingLocks(err)
+		}
+	}
 	deleteHandler.HandleDeleteBefore(args, confirmed)
 }
 
 func runDeleteRetain(cmd *cobra.Command, args []string) {
 	storage, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	unlock := internal.LockMetadataForDeletion(storage.RootFolder(), confirmed)
+	defer unlock()
 
 	deleteHandler, err := mysql.NewDeleteHandler(storage.RootFolder())
-	tracelog.ErrorLogger.FatalOnError(err)
-
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
+
+	if confirmed {
+		err = internal.CheckDeletionPITRWindow(func() (*internal.DeleteExplanation, error) {
+			return deleteHandler.ExplainDeleteRetain(args, "")
+		}, mysql.NewBinlogGapFinder(storage.RootFolder()), forcePITRBreak)
+		if err != nil {
+			internal.FatalOnErrorReleasingLocks(err)
+		}
+	}
 	deleteHandler.HandleDeleteRetain(args, confirmed)
 }
 
@@ -101,4 +146,6 @@ func init() {
 	cmd.AddCommand(deleteCmd)
 	deleteCmd.AddCommand(deleteBeforeCmd, deleteRetainCmd, deleteEverythingCmd, deleteTargetCmd)
 	deleteCmd.PersistentFlags().BoolVar(&confirmed, internal.ConfirmFlag, false, "Confirms backup deletion")
+	deleteCmd.PersistentFlags().StringVar(&yesIKnow, internal.YesIKnowFlag, "", internal.YesIKnowDescription)
+	deleteCmd.PersistentFlags().BoolVar(&forcePITRBreak, internal.ForcePITRBreakFlag, false, internal.ForcePITRBreakDescription)
 }
@@ -0,0 +1,52 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/internal/multistorage"
+	"github.com/wal-g/wal-g/internal/multistorage/policies"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	backupDiffShortDescription = "Compares the files of two backups"
+	backupDiffLongDescription  = `Prints the files added, removed and changed in the second backup compared to the first one
+	and sums up the data churn between the backups. The changed blocks are counted if the second backup is a delta from the first.`
+	SummaryOnlyFlag = "summary-only"
+)
+
+var (
+	// backupDiffCmd represents the backupDiff command
+	backupDiffCmd = &cobra.Command{
+		Use:   "backup-diff backup_a backup_b",
+		Short: backupDiffShortDescription,
+		Long:  backupDiffLongDescription,
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			storage, err := postgres.ConfigureMultiStorage(false)
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			rootFolder := multistorage.SetPolicies(storage.RootFolder(), policies.UniteAllStorages)
+			rootFolder, err = multistorage.UseAllAliveStorages(rootFolder)
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			backupsFolder := rootFolder.GetSubFolder(utility.BaseBackupPath)
+			postgres.HandleBackupDiff(backupsFolder, args[0], args[1], backupDiffPretty, backupDiffJSON, backupDiffSummaryOnly)
+		},
+	}
+	backupDiffPretty      = false
+	backupDiffJSON        = false
+	backupDiffSummaryOnly = false
+)
+
+func init() {
+	Cmd.AddCommand(backupDiffCmd)
+
+	backupDiffCmd.Flags().BoolVar(&backupDiffPretty, PrettyFlag, false,
+		"Prints more readable output in table format")
+	backupDiffCmd.Flags().BoolVar(&backupDiffJSON, JSONFlag, false,
+		"Prints output in JSON format, multiline and indented if combined with --pretty flag")
+	backupDiffCmd.Flags().BoolVar(&backupDiffSummaryOnly, SummaryOnlyFlag, false,
+		"Prints only the summary of the data churn without the list of files")
+}
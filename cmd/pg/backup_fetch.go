@@ -25,6 +25,10 @@ For information about pattern syntax view: https://golang.org/pkg/path/filepath/
 	restoreOnlyDescription        = `[Experimental] Downloads only databases or tables specified by passed names.
 Separate parameters with comma. Use 'database' or 'database/namespace.table' as a parameter ('public' namespace can be omitted).  
 Sets reverse delta unpack & skip redundant tars options automatically. Always downloads system databases and tables.`
+	blockDeviceDescription = `Writes the backup file directly onto the block device instead of destination_directory.
+Use 'file=device' as a parameter, e.g. 'base/16384/16385=/dev/vdb'. Can be specified several times`
+	discardDescription               = "Discard the block devices before writing and skip writing zero blocks (for thin-provisioned volumes)"
+	ignoreUnknownFeaturesDescription = "Restore the backup even if it has the format features unknown to this version of WAL-G"
 )
 
 var fileMask string
@@ -33,6 +37,9 @@ var reverseDeltaUnpack bool
 var skipRedundantTars bool
 var fetchTargetUserData string
 var partialRestoreArgs []string
+var blockDeviceArgs []string
+var discardBlockDevices bool
+var ignoreUnknownFeatures bool
 
 var backupFetchCmd = &cobra.Command{
 	Use:   "backup-fetch destination_directory [backup_name | --target-user-data <data>]",
@@ -40,6 +47,9 @@ var backupFetchCmd = &cobra.Command{
 	Args:  cobra.RangeArgs(1, 2),
 	Run: func(cmd *cobra.Command, args []string) {
 		internal.ConfigureLimiters()
+		if ignoreUnknownFeatures {
+			viper.Set(conf.PgIgnoreUnknownFormatFeaturesSetting, true)
+		}
 
 		if fetchTargetUserData == "" {
 			fetchTargetUserData = viper.GetString(conf.FetchTargetUserDataSetting)
@@ -73,6 +83,11 @@ var backupFetchCmd = &cobra.Command{
 		} else {
 			extractProv = postgres.ExtractProviderImpl{}
 		}
+		if len(blockDeviceArgs) > 0 {
+			devices, err := internal.ParseBlockDeviceMapping(blockDeviceArgs)
+			tracelog.ErrorLogger.FatalOnError(err)
+			extractProv = postgres.BlockDeviceExtractProvider{ExtractProvider: extractProv, Devices: devices, Discard: discardBlockDevices}
+		}
 
 		var pgFetcher internal.Fetcher
 		if reverseDeltaUnpack {
@@ -114,6 +129,12 @@ func init() {
 		nil, restoreOnlyDescription)
 	backupFetchCmd.Flags().StringVar(&targetStorage, "target-storage",
 		"", targetStorageDescription)
+	backupFetchCmd.Flags().StringArrayVar(&blockDeviceArgs, "block-device",
+		nil, blockDeviceDescription)
+	backupFetchCmd.Flags().BoolVar(&discardBlockDevices, "discard",
+		false, discardDescription)
+	backupFetchCmd.Flags().BoolVar(&ignoreUnknownFeatures, "ignore-unknown-features",
+		false, ignoreUnknownFeaturesDescription)
 
 	Cmd.AddCommand(backupFetchCmd)
 }
@@ -15,6 +15,8 @@ const (
 	PrettyFlag                 = "pretty"
 	JSONFlag                   = "json"
 	DetailFlag                 = "detail"
+	UserDataFilterFlag         = "user-data-filter"
+	UserDataFilterDescription  = "Prints only the backups with the user data field (dot-separated path for nested ones) equal to the value"
 )
 
 var (
@@ -38,15 +40,17 @@ var (
 
 			backupsFolder := rootFolder.GetSubFolder(utility.BaseBackupPath)
 			if detail {
-				postgres.HandleDetailedBackupList(backupsFolder, pretty, json)
+				postgres.HandleDetailedBackupList(backupsFolder, userDataFilter, pretty, json)
 			} else {
-				internal.HandleDefaultBackupList(backupsFolder, pretty, json)
+				internal.HandleFilteredBackupList(backupsFolder, userDataFilter, postgres.NewGenericMetaFetcher(), pretty, json)
 			}
 		},
 	}
 	pretty = false
 	json   = false
 	detail = false
+
+	userDataFilter map[string]string
 )
 
 func init() {
@@ -60,6 +64,7 @@ func init() {
 		"Prints output in JSON format, multiline and indented if combined with --pretty flag")
 	backupListCmd.Flags().BoolVar(&detail, DetailFlag, false,
 		"Prints extra DB-specific backup details")
+	backupListCmd.Flags().StringToStringVar(&userDataFilter, UserDataFilterFlag, nil, UserDataFilterDescription)
 	backupListCmd.Flags().StringVar(&targetStorage, "target-storage", "",
 		targetStorageDescription)
 }
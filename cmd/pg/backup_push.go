@@ -59,7 +59,17 @@ var (
 			tracelog.ErrorLogger.FatalOnError(err)
 			tracelog.InfoLogger.Printf("Backup will be pushed to storage: %v", multistorage.UsedStorages(rootFolder)[0])
 
-			uploader, err := internal.ConfigureUploaderToFolder(rootFolder)
+			err = internal.EnforceStorageQuota(rootFolder, func(retainCount int) error {
+				permanentBackups, permanentWals := postgres.GetPermanentBackupsAndWals(rootFolder)
+				deleteHandler, err := postgres.NewDeleteHandler(rootFolder, permanentBackups, permanentWals, false)
+				if err != nil {
+					return err
+				}
+				return deleteHandler.DeleteRetainFull(retainCount, postgres.NewWalGapFinder(rootFolder))
+			})
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			uploader, err := internal.ConfigureBackupUploaderToFolder(rootFolder)
 			tracelog.ErrorLogger.FatalOnError(err)
 
 			var dataDirectory string
@@ -103,8 +113,8 @@ var (
 				deltaFromName, deltaFromUserData, postgres.NewGenericMetaFetcher())
 			tracelog.ErrorLogger.FatalOnError(err)
 
-			userData, err := internal.UnmarshalSentinelUserData(userDataRaw)
-			tracelog.ErrorLogger.FatalfOnError("Failed to unmarshal the provided UserData: %s", err)
+			userData, err := internal.PrepareSentinelUserData(cmd.Context(), userDataRaw)
+			tracelog.ErrorLogger.FatalfOnError("Failed to prepare the UserData: %s", err)
 
 			arguments := postgres.NewBackupArguments(uploader, dataDirectory, utility.BaseBackupPath,
 				permanent, verifyPageChecksums || viper.GetBool(conf.VerifyPageChecksumsSetting),
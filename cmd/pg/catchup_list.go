@@ -22,7 +22,7 @@ var (
 			storage, err := internal.ConfigureStorage()
 			tracelog.ErrorLogger.FatalOnError(err)
 			if detail {
-				postgres.HandleDetailedBackupList(storage.RootFolder().GetSubFolder(utility.CatchupPath), pretty, json)
+				postgres.HandleDetailedBackupList(storage.RootFolder().GetSubFolder(utility.CatchupPath), nil, pretty, json)
 			} else {
 				internal.HandleDefaultBackupList(storage.RootFolder().GetSubFolder(utility.CatchupPath), pretty, json)
 			}
@@ -0,0 +1,62 @@
+package pg
+
+import (
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	complianceShortDescription       = "Evaluate backups against the declared recovery objectives"
+	complianceReportShortDescription = "Prints pass/fail of the RPO/RTO targets for each day of the window"
+	complianceReportLongDescription  = "Evaluates the actual backup frequency, WAL archive lag and estimated " +
+		"restore time against WALG_TARGET_RPO and WALG_TARGET_RTO for every day (UTC) of the window. " +
+		"Exits with a non-zero status if any day fails."
+)
+
+var (
+	complianceCmd = &cobra.Command{
+		Use:   "compliance",
+		Short: complianceShortDescription,
+	}
+
+	complianceReportCmd = &cobra.Command{
+		Use:   "report",
+		Short: complianceReportShortDescription,
+		Long:  complianceReportLongDescription,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if complianceDays < 1 {
+				tracelog.ErrorLogger.Fatal("--days must be at least 1")
+			}
+			storage, err := internal.ConfigureStorage()
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			targets, err := internal.ConfigureComplianceTargets()
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			until := time.Now()
+			since := until.AddDate(0, 0, -complianceDays+1)
+			err = internal.HandleComplianceReport(storage.RootFolder(), utility.WalPath, postgres.NewGenericMetaFetcher(),
+				targets, since, until, os.Stdout, compliancePretty, complianceJSON)
+			tracelog.ErrorLogger.FatalOnError(err)
+		},
+	}
+	complianceDays   int
+	compliancePretty bool
+	complianceJSON   bool
+)
+
+func init() {
+	Cmd.AddCommand(complianceCmd)
+	complianceCmd.AddCommand(complianceReportCmd)
+
+	complianceReportCmd.Flags().IntVar(&complianceDays, "days", 7, "Number of days (including today) to evaluate")
+	complianceReportCmd.Flags().BoolVar(&compliancePretty, PrettyFlag, false, "Prints more readable output in table format")
+	complianceReportCmd.Flags().BoolVar(&complianceJSON, JSONFlag, false, "Prints output in JSON format")
+}
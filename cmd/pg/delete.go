@@ -17,10 +17,15 @@ const DeleteGarbageExamples = `  garbage           Deletes outdated WAL archives
   garbage BACKUPS   Deletes only leftover backups files from storage`
 const DeleteGarbageUse = "garbage [ARCHIVES|BACKUPS]"
 const afterFlag = "after"
+const incompleteFlag = "incomplete"
+const incompleteDescription = "Delete only the files of the backups whose backup-push failed partway"
 
 var confirmed = false
 var useSentinelTime = false
 var deleteTargetUserData = ""
+var deleteIncomplete = false
+var yesIKnow = ""
+var forcePITRBreak = false
 
 // deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
@@ -67,24 +72,46 @@ var deleteGarbageCmd = &cobra.Command{
 
 func runDeleteBefore(cmd *cobra.Command, args []string) {
 	folder := configureFolder()
+	unlock := internal.LockMetadataForDeletion(folder, confirmed)
+	defer unlock()
 
 	permanentBackups, permanentWals := postgres.GetPermanentBackupsAndWals(folder)
 
 	deleteHandler, err := postgres.NewDeleteHandler(folder, permanentBackups, permanentWals, useSentinelTime)
-	tracelog.ErrorLogger.FatalOnError(err)
-
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
+
+	if confirmed {
+		err = internal.CheckDeletionPITRWindow(func() (*internal.DeleteExplanation, error) {
+			return deleteHandler.ExplainDeleteBefore(args)
+		}, postgres.NewWalGapFinder(folder), forcePITRBreak)
+		if err != nil {
+			internal.FatalOnErrorReleasingLocks(err)
+		}
+	}
 	deleteHandler.HandleDeleteBefore(args, confirmed)
 }
 
 func runDeleteRetain(cmd *cobra.Command, args []string) {
 	folder := configureFolder()
+	unlock := internal.LockMetadataForDeletion(folder, confirmed)
+	defer unlock()
 
 	permanentBackups, permanentWals := postgres.GetPermanentBackupsAndWals(folder)
 
 	deleteHandler, err := postgres.NewDeleteHandler(folder, permanentBackups, permanentWals, useSentinelTime)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
 
 	afterValue, _ := cmd.Flags().GetString(afterFlag)
+	if confirmed {
+		err = internal.CheckDeletionPITRWindow(func() (*internal.DeleteExplanation, error) {
+			return deleteHandler.ExplainDeleteRetain(args, afterValue)
+		}, postgres.NewWalGapFinder(folder), forcePITRBreak)
+		if err != nil {
+			internal.FatalOnErrorReleasingLocks(err)
+		}
+	}
 	if afterValue == "" {
 		deleteHandler.HandleDeleteRetain(args, confirmed)
 	} else {
@@ -94,11 +121,14 @@ func runDeleteRetain(cmd *cobra.Command, args []string) {
 
 func runDeleteEverything(cmd *cobra.Command, args []string) {
 	folder := configureFolder()
+	unlock := internal.LockMetadataForDeletion(folder, confirmed)
+	defer unlock()
 
 	permanentBackups, permanentWals := postgres.GetPermanentBackupsAndWals(folder)
 
 	deleteHandler, err := postgres.NewDeleteHandler(folder, permanentBackups, permanentWals, useSentinelTime)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
 
 	permanentBackupNames := make([]string, 0, len(permanentBackups))
 	for backup, isPerm := range permanentBackups {
@@ -106,11 +136,21 @@ func runDeleteEverything(cmd *cobra.Command, args []string) {
 			permanentBackupNames = append(permanentBackupNames, backup.Name)
 		}
 	}
+	if confirmed {
+		err = internal.CheckDeletionPITRWindow(func() (*internal.DeleteExplanation, error) {
+			return deleteHandler.ExplainDeleteEverything(args, permanentBackupNames)
+		}, postgres.NewWalGapFinder(folder), forcePITRBreak)
+		if err != nil {
+			internal.FatalOnErrorReleasingLocks(err)
+		}
+	}
 	deleteHandler.HandleDeleteEverything(args, permanentBackupNames, confirmed)
 }
 
 func runDeleteTarget(cmd *cobra.Command, args []string) {
 	folder := configureFolder()
+	unlock := internal.LockMetadataForDeletion(folder, confirmed)
+	defer unlock()
 
 	permanentBackups, permanentWals := postgres.GetPermanentBackupsAndWals(folder)
 
@@ -123,23 +163,44 @@ func runDeleteTarget(cmd *cobra.Command, args []string) {
 	}
 
 	deleteHandler, err := postgres.NewDeleteHandler(folder, permanentBackups, permanentWals, useSentinelTime)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
 	targetBackupSelector, err := internal.CreateTargetDeleteBackupSelector(cmd, args, deleteTargetUserData, postgres.NewGenericMetaFetcher())
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
+	if confirmed {
+		err = internal.CheckDeletionPITRWindow(func() (*internal.DeleteExplanation, error) {
+			return deleteHandler.ExplainDeleteTarget(targetBackupSelector, findFullBackup)
+		}, postgres.NewWalGapFinder(folder), forcePITRBreak)
+		if err != nil {
+			internal.FatalOnErrorReleasingLocks(err)
+		}
+	}
 
 	deleteHandler.HandleDeleteTarget(targetBackupSelector, confirmed, findFullBackup)
 }
 
 func runDeleteGarbage(cmd *cobra.Command, args []string) {
 	folder := configureFolder()
+	defer internal.LockMetadataForDeletion(folder, confirmed)()
 
 	permanentBackups, permanentWals := postgres.GetPermanentBackupsAndWals(folder)
 
 	deleteHandler, err := postgres.NewDeleteHandler(folder, permanentBackups, permanentWals, false)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
+
+	if confirmed {
+		internal.FatalOnErrorReleasingLocks(deleteHandler.CheckGuardrail("delete garbage"))
+		internal.ScrubExpiredTemporaries(folder)
+	}
+
+	if deleteIncomplete {
+		err := internal.CleanupIncompleteBackups(folder, false, confirmed)
+		internal.FatalOnErrorReleasingLocks(err)
+		return
+	}
 
 	err = deleteHandler.HandleDeleteGarbage(args, confirmed)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
 }
 
 func configureFolder() storage.Folder {
@@ -163,8 +224,11 @@ func init() {
 	deleteTargetCmd.Flags().StringVar(
 		&deleteTargetUserData, internal.DeleteTargetUserDataFlag, "", internal.DeleteTargetUserDataDescription)
 	deleteRetainCmd.Flags().StringP(afterFlag, "a", "", "Set the time after which retain backups")
+	deleteGarbageCmd.Flags().BoolVar(&deleteIncomplete, incompleteFlag, false, incompleteDescription)
 
 	deleteCmd.AddCommand(deleteRetainCmd, deleteBeforeCmd, deleteEverythingCmd, deleteTargetCmd, deleteGarbageCmd)
 	deleteCmd.PersistentFlags().BoolVar(&confirmed, internal.ConfirmFlag, false, "Confirms backup deletion")
 	deleteCmd.PersistentFlags().BoolVar(&useSentinelTime, UseSentinelTimeFlag, false, UseSentinelTimeDescription)
+	deleteCmd.PersistentFlags().StringVar(&yesIKnow, internal.YesIKnowFlag, "", internal.YesIKnowDescription)
+	deleteCmd.PersistentFlags().BoolVar(&forcePITRBreak, internal.ForcePITRBreakFlag, false, internal.ForcePITRBreakDescription)
 }
@@ -0,0 +1,123 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const deleteExplainShortDescription = "Explains which backups and WALs the delete command would keep or delete, " +
+	"without deleting anything"
+
+var deleteExplainJSON = false
+var deleteExplainTargetUserData = ""
+
+var deleteExplainCmd = &cobra.Command{
+	Use:   "explain",
+	Short: deleteExplainShortDescription,
+}
+
+var deleteExplainBeforeCmd = &cobra.Command{
+	Use:     internal.DeleteBeforeUsageExample,
+	Example: internal.DeleteBeforeExamples,
+	Args:    internal.DeleteBeforeArgsValidator,
+	Run: func(cmd *cobra.Command, args []string) {
+		deleteHandler := newExplainDeleteHandler()
+		explanation, err := deleteHandler.ExplainDeleteBefore(args)
+		deleteHandler.HandleDeleteExplain(explanation, err, deleteExplainJSON)
+	},
+}
+
+var deleteExplainRetainCmd = &cobra.Command{
+	Use:       internal.DeleteRetainUsageExample,
+	Example:   internal.DeleteRetainExamples,
+	ValidArgs: internal.StringModifiers,
+	Args:      internal.DeleteRetainArgsValidator,
+	Run: func(cmd *cobra.Command, args []string) {
+		deleteHandler := newExplainDeleteHandler()
+		afterValue, _ := cmd.Flags().GetString(afterFlag)
+		explanation, err := deleteHandler.ExplainDeleteRetain(args, afterValue)
+		deleteHandler.HandleDeleteExplain(explanation, err, deleteExplainJSON)
+	},
+}
+
+var deleteExplainEverythingCmd = &cobra.Command{
+	Use:       internal.DeleteEverythingUsageExample,
+	Example:   internal.DeleteEverythingExamples,
+	ValidArgs: internal.StringModifiersDeleteEverything,
+	Args:      internal.DeleteEverythingArgsValidator,
+	Run:       runDeleteExplainEverything,
+}
+
+var deleteExplainTargetCmd = &cobra.Command{
+	Use:     internal.DeleteTargetUsageExample,
+	Example: internal.DeleteTargetExamples,
+	Args:    internal.DeleteTargetArgsValidator,
+	Run:     runDeleteExplainTarget,
+}
+
+var deleteExplainGarbageCmd = &cobra.Command{
+	Use:     DeleteGarbageUse,
+	Example: DeleteGarbageExamples,
+	Args:    DeleteGarbageArgsValidator,
+	Run: func(cmd *cobra.Command, args []string) {
+		deleteHandler := newExplainDeleteHandler()
+		explanation, err := deleteHandler.ExplainDeleteGarbage(args)
+		deleteHandler.HandleDeleteExplain(explanation, err, deleteExplainJSON)
+	},
+}
+
+func runDeleteExplainEverything(cmd *cobra.Command, args []string) {
+	folder := configureFolder()
+	permanentBackups, permanentWals := postgres.GetPermanentBackupsAndWals(folder)
+
+	deleteHandler, err := postgres.NewDeleteHandler(folder, permanentBackups, permanentWals, useSentinelTime)
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	permanentBackupNames := make([]string, 0, len(permanentBackups))
+	for backup, isPerm := range permanentBackups {
+		if isPerm {
+			permanentBackupNames = append(permanentBackupNames, backup.Name)
+		}
+	}
+	explanation, err := deleteHandler.ExplainDeleteEverything(args, permanentBackupNames)
+	deleteHandler.HandleDeleteExplain(explanation, err, deleteExplainJSON)
+}
+
+func runDeleteExplainTarget(cmd *cobra.Command, args []string) {
+	deleteHandler := newExplainDeleteHandler()
+
+	findFullBackup := false
+	if internal.ExtractDeleteTargetModifierFromArgs(args) == internal.FindFullDeleteModifier {
+		findFullBackup = true
+		args = args[1:]
+	}
+	targetBackupSelector, err := internal.CreateTargetDeleteBackupSelector(cmd, args, deleteExplainTargetUserData,
+		postgres.NewGenericMetaFetcher())
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	explanation, err := deleteHandler.ExplainDeleteTarget(targetBackupSelector, findFullBackup)
+	deleteHandler.HandleDeleteExplain(explanation, err, deleteExplainJSON)
+}
+
+// newExplainDeleteHandler doesn't lock the metadata for deletion, since nothing is deleted
+func newExplainDeleteHandler() *postgres.DeleteHandler {
+	folder := configureFolder()
+	permanentBackups, permanentWals := postgres.GetPermanentBackupsAndWals(folder)
+
+	deleteHandler, err := postgres.NewDeleteHandler(folder, permanentBackups, permanentWals, useSentinelTime)
+	tracelog.ErrorLogger.FatalOnError(err)
+	return deleteHandler
+}
+
+func init() {
+	deleteExplainTargetCmd.Flags().StringVar(
+		&deleteExplainTargetUserData, internal.DeleteTargetUserDataFlag, "", internal.DeleteTargetUserDataDescription)
+	deleteExplainRetainCmd.Flags().StringP(afterFlag, "a", "", "Set the time after which retain backups")
+	deleteExplainCmd.PersistentFlags().BoolVar(&deleteExplainJSON, JSONFlag, false, "Prints output in JSON format")
+
+	deleteExplainCmd.AddCommand(deleteExplainRetainCmd, deleteExplainBeforeCmd, deleteExplainEverythingCmd,
+		deleteExplainTargetCmd, deleteExplainGarbageCmd)
+	deleteCmd.AddCommand(deleteExplainCmd)
+}
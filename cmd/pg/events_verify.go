@@ -0,0 +1,55 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/internal/storageevents"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const eventsVerifyShortDescription = "Verifies the objects changed in the storage as the bucket notifications arrive"
+
+// eventsVerifyCmd represents the events-verify command
+var eventsVerifyCmd = &cobra.Command{
+	Use:   "events-verify",
+	Short: eventsVerifyShortDescription,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithCancel(cmd.Context())
+		defer cancel()
+		utility.NewSignalHandler(ctx, cancel, []os.Signal{syscall.SIGINT, syscall.SIGTERM})
+
+		source, err := configureEventSource(ctx)
+		tracelog.ErrorLogger.FatalOnError(err)
+
+		storage, err := internal.ConfigureStorage()
+		tracelog.ErrorLogger.FatalOnError(err)
+
+		err = postgres.HandleEventsVerify(ctx, storage.RootFolder(), source)
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+}
+
+func configureEventSource(ctx context.Context) (storageevents.Source, error) {
+	if queueURL := viper.GetString(conf.EventsSQSQueueURLSetting); queueURL != "" {
+		return storageevents.NewSQSSource(queueURL)
+	}
+	if subscription := viper.GetString(conf.EventsPubSubSubscription); subscription != "" {
+		return storageevents.NewPubSubSource(ctx, subscription)
+	}
+	return nil, fmt.Errorf("set %s or %s to receive the bucket notifications",
+		conf.EventsSQSQueueURLSetting, conf.EventsPubSubSubscription)
+}
+
+func init() {
+	Cmd.AddCommand(eventsVerifyCmd)
+}
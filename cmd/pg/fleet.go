@@ -0,0 +1,64 @@
+package pg
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/fleet"
+)
+
+const (
+	fleetShortDescription    = "Manages the backups of many clusters listed in the fleet manifest"
+	fleetRunShortDescription = "Backs up the clusters of the fleet manifest due by the schedule and applies their retention"
+
+	fleetManifestFlag    = "manifest"
+	fleetParallelismFlag = "parallelism"
+	fleetForceFlag       = "force"
+)
+
+var (
+	fleetManifestPath string
+	fleetParallelism  int
+	fleetForce        bool
+	fleetJSON         bool
+)
+
+var fleetCmd = &cobra.Command{
+	Use:   "fleet",
+	Short: fleetShortDescription,
+}
+
+var fleetRunCmd = &cobra.Command{
+	Use:         "run",
+	Short:       fleetRunShortDescription,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{"NoStorage": ""},
+	Run: func(cmd *cobra.Command, args []string) {
+		manifest, err := fleet.LoadManifest(fleetManifestPath)
+		tracelog.ErrorLogger.FatalOnError(err)
+
+		var commonArgs []string
+		if conf.CfgFile != "" {
+			commonArgs = append(commonArgs, "--config", conf.CfgFile)
+		}
+		report := fleet.NewRunner(manifest, os.Args[0], commonArgs, fleetForce).Run(context.Background(), fleetParallelism)
+		tracelog.ErrorLogger.FatalOnError(fleet.PrintReport(report, os.Stdout, fleetJSON))
+		if report.Failed > 0 {
+			tracelog.ErrorLogger.Fatalf("%d of %d clusters failed", report.Failed, len(report.Clusters))
+		}
+	},
+}
+
+func init() {
+	fleetRunCmd.Flags().StringVar(&fleetManifestPath, fleetManifestFlag, "", "Path to the fleet manifest")
+	_ = fleetRunCmd.MarkFlagRequired(fleetManifestFlag)
+	fleetRunCmd.Flags().IntVar(&fleetParallelism, fleetParallelismFlag, 0,
+		"How many clusters to process at the same time, overrides the manifest parallelism")
+	fleetRunCmd.Flags().BoolVar(&fleetForce, fleetForceFlag, false, "Back up all the clusters regardless of the schedule")
+	fleetRunCmd.Flags().BoolVar(&fleetJSON, JSONFlag, false, "Prints the report in JSON format")
+	fleetCmd.AddCommand(fleetRunCmd)
+	Cmd.AddCommand(fleetCmd)
+}
@@ -0,0 +1,70 @@
+package pg
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	logsPushShortDescription = "Tails PostgreSQL server logs and pushes them to storage"
+	logsPushLongDescription  = "Pushes the new records of the CSV and JSON server logs from the log directory " +
+		"every interval, following the log file rotation"
+
+	logsPushPatternFlag          = "pattern"
+	logsPushPatternDescription   = "Glob of the log file names to push, can be repeated (default *.csv and *.json)"
+	logsPushStateFileFlag        = "state-file"
+	logsPushStateFileDescription = "File to keep the push progress in " +
+		"(default is " + postgres.LogsPushStateFileName + " in the log directory)"
+	logsPushIntervalFlag          = "interval"
+	logsPushIntervalDescription   = "Interval between the pushes of the new log records"
+	logsPushOnceFlag              = "once"
+	logsPushOnceDescription       = "Push the log records written so far and exit"
+	logsPushRetainFlag            = "retain-with-backups"
+	logsPushRetainFlagDescription = "Delete the logs pushed before the start of the oldest non-permanent backup"
+)
+
+var (
+	logsPushPatterns  []string
+	logsPushStateFile string
+	logsPushInterval  time.Duration
+	logsPushOnce      bool
+	logsPushRetain    bool
+)
+
+// logsPushCmd represents the logs-push command
+var logsPushCmd = &cobra.Command{
+	Use:   "logs-push log_directory",
+	Short: logsPushShortDescription,
+	Long:  logsPushLongDescription,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		uploader, err := internal.ConfigureUploader()
+		tracelog.ErrorLogger.FatalOnError(err)
+
+		handler, err := postgres.NewLogsPushHandler(uploader, postgres.LogsPushArgs{
+			LogDirectory:      args[0],
+			Patterns:          logsPushPatterns,
+			StateFile:         logsPushStateFile,
+			Interval:          logsPushInterval,
+			Once:              logsPushOnce,
+			RetainWithBackups: logsPushRetain,
+		})
+		tracelog.ErrorLogger.FatalOnError(err)
+
+		err = handler.HandleLogsPush(cmd.Context())
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+}
+
+func init() {
+	logsPushCmd.Flags().StringArrayVar(&logsPushPatterns, logsPushPatternFlag, nil, logsPushPatternDescription)
+	logsPushCmd.Flags().StringVar(&logsPushStateFile, logsPushStateFileFlag, "", logsPushStateFileDescription)
+	logsPushCmd.Flags().DurationVar(&logsPushInterval, logsPushIntervalFlag, 10*time.Second, logsPushIntervalDescription)
+	logsPushCmd.Flags().BoolVar(&logsPushOnce, logsPushOnceFlag, false, logsPushOnceDescription)
+	logsPushCmd.Flags().BoolVar(&logsPushRetain, logsPushRetainFlag, false, logsPushRetainFlagDescription)
+	Cmd.AddCommand(logsPushCmd)
+}
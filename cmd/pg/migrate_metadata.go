@@ -0,0 +1,40 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/internal/multistorage"
+	"github.com/wal-g/wal-g/internal/multistorage/policies"
+)
+
+const (
+	migrateMetadataShortDescription = "Rewrites the metadata of the backups made by the older versions in the current format"
+	migrateMetadataLongDescription  = `Rewrites the metadata of the backups made by the older versions of WAL-G and by WAL-E
+in the current format, so the backups stay readable when the support of the legacy formats is dropped.
+The backup data isn't touched. By default, only lists the backups to migrate.`
+)
+
+var migrateMetadataConfirmed = false
+
+// migrateMetadataCmd represents the migrate-metadata command
+var migrateMetadataCmd = &cobra.Command{
+	Use:   "migrate-metadata",
+	Short: migrateMetadataShortDescription,
+	Long:  migrateMetadataLongDescription,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		storage, err := internal.ConfigureStorage()
+		tracelog.ErrorLogger.FatalOnError(err)
+
+		rootFolder := multistorage.SetPolicies(storage.RootFolder(), policies.UniteAllStorages)
+		err = postgres.HandleMigrateMetadata(rootFolder, migrateMetadataConfirmed)
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+}
+
+func init() {
+	migrateMetadataCmd.Flags().BoolVar(&migrateMetadataConfirmed, internal.ConfirmFlag, false, "Confirms the migration")
+	Cmd.AddCommand(migrateMetadataCmd)
+}
@@ -0,0 +1,77 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/internal/multistorage"
+	"github.com/wal-g/wal-g/internal/multistorage/policies"
+)
+
+const (
+	reseedUsage            = "reseed data_directory --as-standby --primary-conninfo <conninfo>"
+	reseedShortDescription = "Turns the failed old primary into a standby of the new primary"
+	reseedLongDescription  = `Rewinds the stopped old primary with pg_rewind fetching the missing WAL from storage,
+or restores the backup onto it reusing the unchanged files if it can't be rewound at all,
+and writes the standby configuration.`
+
+	asStandbyDescription       = "Configure the cluster as a standby of the new primary (required)"
+	primaryConnInfoDescription = "Connection string of the new primary"
+	restoreCommandDescription  = "restore_command of the standby"
+	pgRewindPathDescription    = "Path to the pg_rewind binary"
+	reseedBackupDescription    = "Backup to restore from if the cluster can't be rewound"
+	forceRestoreDescription    = "Restore the backup onto the cluster without trying pg_rewind"
+)
+
+var (
+	reseedAsStandby       bool
+	reseedPrimaryConnInfo string
+	reseedRestoreCommand  string
+	reseedPgRewindPath    string
+	reseedBackupName      string
+	reseedForceRestore    bool
+)
+
+var reseedCmd = &cobra.Command{
+	Use:   reseedUsage,
+	Short: reseedShortDescription,
+	Long:  reseedLongDescription,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !reseedAsStandby {
+			tracelog.ErrorLogger.Fatal("Only reseeding as a standby is supported, specify --as-standby")
+		}
+
+		internal.ConfigureLimiters()
+		storage, err := postgres.ConfigureMultiStorage(false)
+		tracelog.ErrorLogger.FatalOnError(err)
+		rootFolder := multistorage.SetPolicies(storage.RootFolder(), policies.UniteAllStorages)
+		rootFolder, err = multistorage.UseAllAliveStorages(rootFolder)
+		tracelog.ErrorLogger.FatalOnError(err)
+
+		backupSelector, err := internal.NewBackupNameSelector(reseedBackupName, true)
+		tracelog.ErrorLogger.FatalOnError(err)
+
+		postgres.HandleReseed(rootFolder, postgres.ReseedArgs{
+			PgData:          args[0],
+			PrimaryConnInfo: reseedPrimaryConnInfo,
+			RestoreCommand:  reseedRestoreCommand,
+			PgRewindPath:    reseedPgRewindPath,
+			BackupSelector:  backupSelector,
+			ForceRestore:    reseedForceRestore,
+		})
+	},
+}
+
+func init() {
+	reseedCmd.Flags().BoolVar(&reseedAsStandby, "as-standby", false, asStandbyDescription)
+	reseedCmd.Flags().StringVar(&reseedPrimaryConnInfo, "primary-conninfo", "", primaryConnInfoDescription)
+	reseedCmd.Flags().StringVar(&reseedRestoreCommand, "restore-command", `wal-g wal-fetch "%f" "%p"`, restoreCommandDescription)
+	reseedCmd.Flags().StringVar(&reseedPgRewindPath, "pg-rewind", "pg_rewind", pgRewindPathDescription)
+	reseedCmd.Flags().StringVar(&reseedBackupName, "backup-name", internal.LatestString, reseedBackupDescription)
+	reseedCmd.Flags().BoolVar(&reseedForceRestore, "force-restore", false, forceRestoreDescription)
+	_ = reseedCmd.MarkFlagRequired("primary-conninfo")
+
+	Cmd.AddCommand(reseedCmd)
+}
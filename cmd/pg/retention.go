@@ -0,0 +1,38 @@
+package pg
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+)
+
+const (
+	retentionShortDescription     = "Reviews the evaluations of the retention policies"
+	retentionDiffShortDescription = "Shows the backups whose retention decisions have changed since the previous " +
+		"'delete explain retain' or 'delete explain before'"
+)
+
+var retentionDiffJSON = false
+
+var retentionCmd = &cobra.Command{
+	Use:   "retention",
+	Short: retentionShortDescription,
+}
+
+var retentionDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: retentionDiffShortDescription,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := internal.HandleRetentionDiff(configureFolder(), os.Stdout, retentionDiffJSON)
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+}
+
+func init() {
+	retentionDiffCmd.Flags().BoolVar(&retentionDiffJSON, JSONFlag, false, "Prints output in JSON format")
+	retentionCmd.AddCommand(retentionDiffCmd)
+	Cmd.AddCommand(retentionCmd)
+}
@@ -3,6 +3,7 @@ package pg
 import (
 	"github.com/spf13/cobra"
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/postgres"
 )
 
@@ -22,6 +23,9 @@ var walPushCmd = &cobra.Command{
 
 		err = postgres.HandleWALPush(cmd.Context(), walUploader, args[0])
 		tracelog.ErrorLogger.FatalOnError(err)
+
+		// the backup metadata which failed to upload at the end of backup-push is uploaded by the next wal-push
+		internal.FlushSpooledMetadata(storage.RootFolder())
 	},
 }
 
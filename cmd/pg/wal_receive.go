@@ -16,8 +16,10 @@ var walReceiveCmd = &cobra.Command{
 	Short: walReceiveShortDescription,
 	Args:  cobra.ExactArgs(0),
 	Run: func(cmd *cobra.Command, args []string) {
-		baseUploader, err := internal.ConfigureUploader()
+		baseUploader, err := internal.ConfigureWalStreamUploader()
 		tracelog.ErrorLogger.FatalOnError(err)
+		// the backup metadata which failed to upload at the end of backup-push is uploaded by the next wal-receive
+		internal.FlushSpooledMetadata(baseUploader.Folder())
 
 		uploader, err := postgres.ConfigureWalUploader(baseUploader)
 		tracelog.ErrorLogger.FatalOnError(err)
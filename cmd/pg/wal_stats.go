@@ -0,0 +1,55 @@
+package pg
+
+import (
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const (
+	WalStatsUsage            = "wal-stats"
+	WalStatsShortDescription = "Show WAL generation rates based on the archived segments"
+	WalStatsLongDescription  = "Groups the archived WAL segments by the hour or day they were uploaded, and shows " +
+		"the amount of generated WAL, its stored size and rate for each period, as well as the peak rate and " +
+		"the projected archive growth."
+
+	walStatsPeriodFlag = "by"
+	walStatsDaysFlag   = "days"
+)
+
+var (
+	// walStatsCmd represents the walStats command
+	walStatsCmd = &cobra.Command{
+		Use:   WalStatsUsage,
+		Short: WalStatsShortDescription,
+		Long:  WalStatsLongDescription,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			storage, err := internal.ConfigureStorage()
+			tracelog.ErrorLogger.FatalOnError(err)
+
+			until := time.Now()
+			since := until.AddDate(0, 0, -walStatsDays)
+			err = postgres.HandleWalStats(storage.RootFolder(), walStatsPeriod, since, until, os.Stdout,
+				walStatsPretty, walStatsJSON)
+			tracelog.ErrorLogger.FatalOnError(err)
+		},
+	}
+	walStatsPeriod string
+	walStatsDays   int
+	walStatsPretty bool
+	walStatsJSON   bool
+)
+
+func init() {
+	Cmd.AddCommand(walStatsCmd)
+	walStatsCmd.Flags().StringVar(&walStatsPeriod, walStatsPeriodFlag, postgres.WalStatsDaily,
+		"Group segments by 'hour' or 'day'")
+	walStatsCmd.Flags().IntVar(&walStatsDays, walStatsDaysFlag, 7, "Number of days to analyze")
+	walStatsCmd.Flags().BoolVar(&walStatsPretty, PrettyFlag, false, "Prints more readable output in table format")
+	walStatsCmd.Flags().BoolVar(&walStatsJSON, JSONFlag, false, "Prints output in JSON format")
+}
@@ -21,6 +21,13 @@ const (
 	useJSONOutputFlag        = "json"
 	useJSONOutputDescription = "Show output in JSON format."
 
+	walVerifyShardFlag        = "shard"
+	walVerifyShardDescription = "Verify only the shard 'index/count' of the WAL segments range, e.g. 2/8, " +
+		"and write its partial report to merge with 'wal-verify merge'. Only the integrity check can be sharded."
+
+	walVerifyUntilFlag        = "until"
+	walVerifyUntilDescription = "Verify the WAL segments until the given one instead of the current segment of the cluster"
+
 	checkIntegrityArg = "integrity"
 	checkTimelineArg  = "timeline"
 )
@@ -39,19 +46,47 @@ var (
 		Run: func(cmd *cobra.Command, checks []string) {
 			storage, err := internal.ConfigureStorage()
 			tracelog.ErrorLogger.FatalOnError(err)
-			outputType := postgres.WalVerifyTableOutput
-			if useJSONOutput {
-				outputType = postgres.WalVerifyJSONOutput
-			}
-			outputWriter := postgres.NewWalVerifyOutputWriter(outputType, os.Stdout)
 			checkTypes := parseChecks(checks)
+			currentWalSegment := getWalVerifyCurrentSegment()
 
-			postgres.HandleWalVerify(checkTypes, storage.RootFolder(), postgres.QueryCurrentWalSegment(), outputWriter)
+			if walVerifyShard != "" {
+				shard, err := postgres.ParseWalVerifyShard(walVerifyShard)
+				tracelog.ErrorLogger.FatalOnError(err)
+				if len(checkTypes) != 1 || checkTypes[0] != postgres.WalVerifyIntegrityCheck {
+					tracelog.ErrorLogger.Fatalf("Only the %s check can be sharded", checkIntegrityArg)
+				}
+				err = postgres.HandleWalVerifyShard(storage.RootFolder(), currentWalSegment, shard, os.Stdout)
+				tracelog.ErrorLogger.FatalOnError(err)
+				return
+			}
+
+			postgres.HandleWalVerify(checkTypes, storage.RootFolder(), currentWalSegment, newWalVerifyOutputWriter())
 		},
 	}
-	useJSONOutput bool
+	useJSONOutput  bool
+	walVerifyShard string
+	walVerifyUntil string
 )
 
+func newWalVerifyOutputWriter() postgres.WalVerifyOutputWriter {
+	outputType := postgres.WalVerifyTableOutput
+	if useJSONOutput {
+		outputType = postgres.WalVerifyJSONOutput
+	}
+	return postgres.NewWalVerifyOutputWriter(outputType, os.Stdout)
+}
+
+// getWalVerifyCurrentSegment provides the segment to verify the WAL until, the shards running on the different hosts
+// must use the same one
+func getWalVerifyCurrentSegment() postgres.WalSegmentDescription {
+	if walVerifyUntil == "" {
+		return postgres.QueryCurrentWalSegment()
+	}
+	segment, err := postgres.NewWalSegmentDescription(walVerifyUntil)
+	tracelog.ErrorLogger.FatalfOnError("Invalid --"+walVerifyUntilFlag+" WAL segment: %v", err)
+	return segment
+}
+
 func parseChecks(checks []string) []postgres.WalVerifyCheckType {
 	// filter the possible duplicates
 	uniqueChecks := make(map[string]bool)
@@ -89,5 +124,7 @@ func checkArgs(cmd *cobra.Command, args []string) error {
 
 func init() {
 	Cmd.AddCommand(walVerifyCmd)
-	walVerifyCmd.Flags().BoolVar(&useJSONOutput, useJSONOutputFlag, false, useJSONOutputDescription)
+	walVerifyCmd.PersistentFlags().BoolVar(&useJSONOutput, useJSONOutputFlag, false, useJSONOutputDescription)
+	walVerifyCmd.Flags().StringVar(&walVerifyShard, walVerifyShardFlag, "", walVerifyShardDescription)
+	walVerifyCmd.Flags().StringVar(&walVerifyUntil, walVerifyUntilFlag, "", walVerifyUntilDescription)
 }
@@ -0,0 +1,24 @@
+package pg
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+const walVerifyMergeShortDescription = "Merges the partial reports of all the wal-verify shards into the integrity check result"
+
+// walVerifyMergeCmd represents the wal-verify merge command
+var walVerifyMergeCmd = &cobra.Command{
+	Use:   "merge shard_report...",
+	Short: walVerifyMergeShortDescription,
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, reportPaths []string) {
+		err := postgres.HandleWalVerifyMerge(reportPaths, newWalVerifyOutputWriter())
+		tracelog.ErrorLogger.FatalOnError(err)
+	},
+}
+
+func init() {
+	walVerifyCmd.AddCommand(walVerifyMergeCmd)
+}
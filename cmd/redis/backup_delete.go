@@ -22,6 +22,7 @@ var (
 	purgeGarbage bool
 	retainAfter  string
 	retainCount  uint
+	yesIKnow     string
 )
 
 // deleteCmd represents the delete command
@@ -32,9 +33,11 @@ var deleteCmd = &cobra.Command{
 }
 
 func runDelete(cmd *cobra.Command, args []string) {
+	tracelog.ErrorLogger.FatalOnError(internal.CheckMinPITRWindowUnsupported())
 	opts := []redis.PurgeOption{
 		redis.PurgeDryRun(!confirmed),
 		redis.PurgeGarbage(purgeGarbage),
+		redis.PurgeGuardrailConfirmation(yesIKnow),
 	}
 
 	if cmd.Flags().Changed(retainAfterFlag) {
@@ -50,8 +53,12 @@ func runDelete(cmd *cobra.Command, args []string) {
 		opts = append(opts, redis.PurgeRetainCount(int(retainCount)))
 	}
 
-	err := redis.HandlePurge(utility.BaseBackupPath, opts...)
+	st, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	defer internal.LockMetadataForDeletion(st.RootFolder(), confirmed)()
+
+	err = redis.HandlePurge(utility.BaseBackupPath, opts...)
+	internal.FatalOnErrorReleasingLocks(err)
 }
 
 func init() {
@@ -60,4 +67,5 @@ func init() {
 	deleteCmd.Flags().BoolVar(&purgeGarbage, purgeGarbageFlag, false, "Delete garbage in backup folder")
 	deleteCmd.Flags().StringVar(&retainAfter, retainAfterFlag, "", "Keep backups newer")
 	deleteCmd.Flags().UintVar(&retainCount, retainCountFlag, 0, "Keep minimum count, except permanent backups")
+	deleteCmd.Flags().StringVar(&yesIKnow, internal.YesIKnowFlag, "", internal.YesIKnowDescription)
 }
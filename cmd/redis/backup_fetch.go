@@ -14,7 +14,15 @@ import (
 	"github.com/wal-g/wal-g/utility"
 )
 
-const backupFetchShortDescription = "Fetches desired backup from storage"
+const (
+	backupFetchShortDescription = "Fetches desired backup from storage"
+	ReplaceFlag                 = "replace"
+)
+
+var (
+	fetchKeyPatterns []string
+	replaceKeys      bool
+)
 
 var backupFetchCmd = &cobra.Command{
 	Use:   "backup-fetch backup-name",
@@ -30,6 +38,17 @@ var backupFetchCmd = &cobra.Command{
 		storage, err := internal.ConfigureStorage()
 		tracelog.ErrorLogger.FatalOnError(err)
 
+		isKeysBackup, err := redis.IsKeysBackup(storage.RootFolder(), args[0])
+		tracelog.ErrorLogger.FatalOnError(err)
+		if isKeysBackup {
+			err = redis.HandleKeysBackupFetch(ctx, storage.RootFolder(), args[0], fetchKeyPatterns, replaceKeys)
+			tracelog.ErrorLogger.FatalOnError(err)
+			return
+		}
+		if len(fetchKeyPatterns) > 0 {
+			tracelog.ErrorLogger.Fatalf("Backup %s is not a keys backup, its keys can not be restored selectively", args[0])
+		}
+
 		restoreCmd, err := internal.GetCommandSettingContext(ctx, conf.NameStreamRestoreCmd)
 		tracelog.ErrorLogger.FatalOnError(err)
 
@@ -46,5 +65,9 @@ var backupFetchCmd = &cobra.Command{
 }
 
 func init() {
+	backupFetchCmd.Flags().StringArrayVar(&fetchKeyPatterns, KeyPatternFlag, nil,
+		"Restores only the keys matching the pattern from the keys backup, can be repeated")
+	backupFetchCmd.Flags().BoolVar(&replaceKeys, ReplaceFlag, false,
+		"Overwrites the existing keys when restoring the keys backup")
 	cmd.AddCommand(backupFetchCmd)
 }
@@ -16,13 +16,15 @@ import (
 )
 
 var (
-	permanent = false
+	permanent   = false
+	keyPatterns []string
 )
 
 const (
 	backupPushShortDescription = "Makes backup and uploads it to storage"
 	PermanentFlag              = "permanent"
 	PermanentShorthand         = "p"
+	KeyPatternFlag             = "key-pattern"
 )
 
 // backupPushCmd represents the backupPush command
@@ -37,12 +39,19 @@ var backupPushCmd = &cobra.Command{
 		signalHandler := utility.NewSignalHandler(ctx, cancel, []os.Signal{syscall.SIGINT, syscall.SIGTERM})
 		defer func() { _ = signalHandler.Close() }()
 
-		uploader, err := internal.ConfigureUploader()
+		uploader, err := internal.ConfigureBackupUploader()
 		tracelog.ErrorLogger.FatalOnError(err)
 
 		// Configure folder
 		uploader.ChangeDirectory(utility.BaseBackupPath)
 
+		if len(keyPatterns) > 0 {
+			metaConstructor := archive.NewKeysBackupRedisMetaConstructor(ctx, uploader.Folder(), permanent, keyPatterns)
+			err = redis.HandleKeysBackupPush(ctx, uploader, keyPatterns, metaConstructor)
+			tracelog.ErrorLogger.FatalfOnError("Redis keys backup creation failed: %v", err)
+			return
+		}
+
 		backupCmd, err := internal.GetCommandSettingContext(ctx, conf.NameStreamCreateCmd)
 		tracelog.ErrorLogger.FatalOnError(err)
 
@@ -57,7 +66,8 @@ var backupPushCmd = &cobra.Command{
 		tracelog.ErrorLogger.FatalfOnError("Redis backup creation failed: %v", err)
 	},
 	PreRun: func(cmd *cobra.Command, args []string) {
-		conf.RequiredSettings[conf.NameStreamCreateCmd] = true
+		// the keys backup is made by wal-g itself
+		conf.RequiredSettings[conf.NameStreamCreateCmd] = len(keyPatterns) == 0
 		err := internal.AssertRequiredSettingsSet()
 		tracelog.ErrorLogger.FatalOnError(err)
 	},
@@ -65,5 +75,7 @@ var backupPushCmd = &cobra.Command{
 
 func init() {
 	backupPushCmd.Flags().BoolVarP(&permanent, PermanentFlag, PermanentShorthand, false, "Pushes backup with 'permanent' flag")
+	backupPushCmd.Flags().StringArrayVar(&keyPatterns, KeyPatternFlag, nil,
+		"Dumps only the keys matching the pattern instead of running the backup command, can be repeated")
 	cmd.AddCommand(backupPushCmd)
 }
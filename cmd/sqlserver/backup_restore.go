@@ -11,6 +11,7 @@ const backupRestoreShortDescription = "Restores backup from the storage"
 var restoreDatabases []string
 var restoreFrom []string
 var restoreNoRecovery bool
+var restoreJoinAG bool
 
 var backupRestoreCmd = &cobra.Command{
 	Use:   "backup-restore backup-name",
@@ -18,7 +19,7 @@ var backupRestoreCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		internal.ConfigureLimiters()
-		sqlserver.HandleBackupRestore(args[0], restoreDatabases, restoreFrom, restoreNoRecovery)
+		sqlserver.HandleBackupRestore(args[0], restoreDatabases, restoreFrom, restoreNoRecovery, restoreJoinAG)
 	},
 }
 
@@ -30,5 +31,7 @@ func init() {
 			"those every database is restored from self backup")
 	backupRestoreCmd.PersistentFlags().BoolVarP(&restoreNoRecovery, "no-recovery", "n", false,
 		"Restore with NO_RECOVERY option")
+	backupRestoreCmd.PersistentFlags().BoolVar(&restoreJoinAG, "join-ag", false,
+		"Join the restored databases to their availability groups instead of recovering them")
 	cmd.AddCommand(backupRestoreCmd)
 }
@@ -9,6 +9,7 @@ import (
 )
 
 var confirmed = false
+var yesIKnow = ""
 
 // deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
@@ -40,22 +41,40 @@ var deleteEverythingCmd = &cobra.Command{
 }
 
 func runDeleteEverything(cmd *cobra.Command, args []string) {
-	deleteHandler, err := newSQLServerDeleteHandler()
+	tracelog.ErrorLogger.FatalOnError(internal.CheckMinPITRWindowUnsupported())
+	st, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	defer internal.LockMetadataForDeletion(st.RootFolder(), confirmed)()
+
+	deleteHandler, err := newSQLServerDeleteHandler(st.RootFolder())
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
 
 	deleteHandler.DeleteEverything(confirmed)
 }
 
 func runDeleteBefore(cmd *cobra.Command, args []string) {
-	deleteHandler, err := newSQLServerDeleteHandler()
+	tracelog.ErrorLogger.FatalOnError(internal.CheckMinPITRWindowUnsupported())
+	st, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	defer internal.LockMetadataForDeletion(st.RootFolder(), confirmed)()
+
+	deleteHandler, err := newSQLServerDeleteHandler(st.RootFolder())
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
 
 	deleteHandler.HandleDeleteBefore(args, confirmed)
 }
 
 func runDeleteRetain(cmd *cobra.Command, args []string) {
-	deleteHandler, err := newSQLServerDeleteHandler()
+	tracelog.ErrorLogger.FatalOnError(internal.CheckMinPITRWindowUnsupported())
+	st, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	defer internal.LockMetadataForDeletion(st.RootFolder(), confirmed)()
+
+	deleteHandler, err := newSQLServerDeleteHandler(st.RootFolder())
+	internal.FatalOnErrorReleasingLocks(err)
+	deleteHandler.SetGuardrailConfirmation(yesIKnow)
 
 	deleteHandler.HandleDeleteRetain(args, confirmed)
 }
@@ -64,14 +83,10 @@ func init() {
 	cmd.AddCommand(deleteCmd)
 	deleteCmd.AddCommand(deleteBeforeCmd, deleteRetainCmd, deleteEverythingCmd)
 	deleteCmd.PersistentFlags().BoolVar(&confirmed, internal.ConfirmFlag, false, "Confirms backup deletion")
+	deleteCmd.PersistentFlags().StringVar(&yesIKnow, internal.YesIKnowFlag, "", internal.YesIKnowDescription)
 }
 
-func newSQLServerDeleteHandler() (*internal.DeleteHandler, error) {
-	st, err := internal.ConfigureStorage()
-	tracelog.ErrorLogger.FatalOnError(err)
-
-	folder := st.RootFolder()
-
+func newSQLServerDeleteHandler(folder storage.Folder) (*internal.DeleteHandler, error) {
 	backups, err := internal.GetBackupSentinelObjects(folder)
 	if err != nil {
 		return nil, err
@@ -15,6 +15,7 @@ var logRestoreUntilTS string
 var logRestoreDatabases []string
 var logRestoreFrom []string
 var logRestoreNoRecovery bool
+var logRestoreJoinAG bool
 
 var logRestoreCmd = &cobra.Command{
 	Use:   "log-restore log-name",
@@ -22,7 +23,7 @@ var logRestoreCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(0),
 	Run: func(cmd *cobra.Command, args []string) {
 		sqlserver.HandleLogRestore(logRestoreBackupName,
-			logRestoreUntilTS, logRestoreDatabases, logRestoreFrom, logRestoreNoRecovery)
+			logRestoreUntilTS, logRestoreDatabases, logRestoreFrom, logRestoreNoRecovery, logRestoreJoinAG)
 	},
 }
 
@@ -38,5 +39,7 @@ func init() {
 			"those every database log is restored from self backup")
 	logRestoreCmd.PersistentFlags().BoolVarP(&logRestoreNoRecovery, "no-recovery", "n", false,
 		"Restore with NO_RECOVERY option")
+	logRestoreCmd.PersistentFlags().BoolVar(&logRestoreJoinAG, "join-ag", false,
+		"Join the restored databases to their availability groups instead of recovering them")
 	cmd.AddCommand(logRestoreCmd)
 }
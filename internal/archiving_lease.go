@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const (
+	ArchivingLeasePath = "archiving.lease"
+	// minArchivingLeaseTTL leaves the holder the time to prolong the lease, it is written and read back
+	// several times per TTL
+	minArchivingLeaseTTL = 10 * time.Second
+)
+
+type ArchivingLeaseHeldError struct {
+	error
+}
+
+func newArchivingLeaseHeldError(holder *leaseRecord) ArchivingLeaseHeldError {
+	return ArchivingLeaseHeldError{errors.Errorf("archiving lease is held by %s until %s, skipping the upload",
+		holder.Owner, holder.ExpiresAt.Format(time.RFC3339))}
+}
+
+func (err ArchivingLeaseHeldError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// ArchivingLease lets only one of several hosts archive the logs to the same storage. The hosts contend
+// for the lease object in the storage root; the holder renews it on every run, and when it stops doing so,
+// the lease expires and the next host to run takes it over.
+type ArchivingLease struct {
+	lease *storageLease
+}
+
+// AcquireArchivingLease takes or renews the lease if WALG_ARCHIVING_LEASE_TTL is set. It returns a nil lease
+// when the leases are disabled and ArchivingLeaseHeldError when the lease is held by another host.
+func AcquireArchivingLease(rootFolder storage.Folder) (*ArchivingLease, error) {
+	if !viper.IsSet(conf.ArchivingLeaseTTLSetting) {
+		return nil, nil
+	}
+	ttl, err := conf.GetDurationSetting(conf.ArchivingLeaseTTLSetting)
+	if err != nil {
+		return nil, err
+	}
+	if ttl < minArchivingLeaseTTL {
+		return nil, errors.Errorf("%s must be at least %s, got %s", conf.ArchivingLeaseTTLSetting,
+			minArchivingLeaseTTL, ttl)
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to get hostname for the archiving lease: %v", err)
+	}
+	// the owner must be the same across the runs, so the holder renews its own lease
+	owner := hostname
+	if viper.IsSet(conf.ArchivingLeaseOwnerSetting) {
+		owner = viper.GetString(conf.ArchivingLeaseOwnerSetting)
+	}
+	if owner == "" {
+		return nil, errors.Errorf("%s must be set to use the archiving lease", conf.ArchivingLeaseOwnerSetting)
+	}
+
+	lease := newStorageLease(rootFolder, ArchivingLeasePath, ttl, owner, hostname)
+	acquired, holder, err := lease.tryAcquire()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to acquire the archiving lease")
+	}
+	if !acquired {
+		if holder == nil {
+			holder = &leaseRecord{Owner: "unknown"}
+		}
+		return nil, newArchivingLeaseHeldError(holder)
+	}
+	tracelog.InfoLogger.Printf("Holding the archiving lease as %s until %s", owner, lease.record.ExpiresAt.Format(time.RFC3339))
+	lease.startRefresh()
+	return &ArchivingLease{lease: lease}, nil
+}
+
+// Stop stops renewing the lease. The lease object is kept, so the holder renews it on the next run
+// and the other hosts take over only after it expires.
+func (archivingLease *ArchivingLease) Stop() {
+	if archivingLease == nil {
+		return
+	}
+	archivingLease.lease.stop()
+}
+
+// IsLost reports whether the lease has been taken over by another host while we hold it, e.g. after this host
+// has been paused for longer than the TTL. The holder should stop archiving then.
+func (archivingLease *ArchivingLease) IsLost() bool {
+	if archivingLease == nil {
+		return false
+	}
+	select {
+	case <-archivingLease.lease.Lost():
+		return true
+	default:
+		return false
+	}
+}
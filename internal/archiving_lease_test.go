@@ -0,0 +1,64 @@
+package internal_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+func TestAcquireArchivingLease(t *testing.T) {
+	internal.ConfigureSettings("")
+	conf.InitConfig()
+	folder := memory.NewFolder("", memory.NewKVS())
+
+	lease, err := internal.AcquireArchivingLease(folder)
+	require.NoError(t, err)
+	assert.Nil(t, lease)
+	lease.Stop()
+
+	defer viper.Set(conf.ArchivingLeaseTTLSetting, nil)
+	defer viper.Set(conf.ArchivingLeaseOwnerSetting, nil)
+	viper.Set(conf.ArchivingLeaseOwnerSetting, "host-a")
+	// the holder must have the time to prolong the lease
+	for _, ttl := range []string{"0", "1ns", "5s"} {
+		viper.Set(conf.ArchivingLeaseTTLSetting, ttl)
+		_, err = internal.AcquireArchivingLease(folder)
+		assert.Error(t, err)
+	}
+
+	viper.Set(conf.ArchivingLeaseTTLSetting, "1m")
+	lease, err = internal.AcquireArchivingLease(folder)
+	require.NoError(t, err)
+	assert.False(t, lease.IsLost())
+	lease.Stop()
+
+	viper.Set(conf.ArchivingLeaseOwnerSetting, "host-b")
+	_, err = internal.AcquireArchivingLease(folder)
+	assert.IsType(t, internal.ArchivingLeaseHeldError{}, err)
+
+	// the holder renews its own lease
+	viper.Set(conf.ArchivingLeaseOwnerSetting, "host-a")
+	lease, err = internal.AcquireArchivingLease(folder)
+	require.NoError(t, err)
+	lease.Stop()
+
+	// and the other host takes it over once the holder stops renewing
+	expired := `{"owner":"host-a","hostname":"host-a","expires_at":"` +
+		time.Now().Add(-time.Second).Format(time.RFC3339Nano) + `"}`
+	require.NoError(t, folder.PutObject(internal.ArchivingLeasePath, strings.NewReader(expired)))
+	viper.Set(conf.ArchivingLeaseOwnerSetting, "host-b")
+	lease, err = internal.AcquireArchivingLease(folder)
+	require.NoError(t, err)
+	lease.Stop()
+
+	exists, err := folder.Exists(internal.ArchivingLeasePath)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
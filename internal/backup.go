@@ -25,6 +25,9 @@ func (err SentinelMarshallingError) Error() string {
 
 //endregion
 
+// WalgVersion is the version of WAL-G recorded in the metadata of the backups
+var WalgVersion = "devel"
+
 // Backup provides basic functionality to fetch backup-related information from storages.
 //
 // WAL-G stores information about single backup in the following files:
@@ -44,6 +47,8 @@ type Backup struct {
 	Name string
 	// base backup folder or catchup backup folder
 	Folder storage.Folder
+	// RestoreReport collects the report of the backup-fetch restoring the backup, it is nil otherwise
+	RestoreReport *RestoreReporter
 }
 
 func NewBackup(folder storage.Folder, name string) (Backup, error) {
@@ -93,11 +98,21 @@ func (backup *Backup) FetchMetadata(metadataDto interface{}) error {
 }
 
 func (backup *Backup) UploadMetadata(metadataDto interface{}) error {
-	return UploadDto(backup.Folder, metadataDto, backup.getMetadataPath())
+	return UploadDtoDurably(backup.Folder, metadataDto, backup.getMetadataPath())
 }
 
 func (backup *Backup) UploadSentinel(sentinelDto interface{}) error {
-	return UploadDto(backup.Folder, sentinelDto, backup.getStopSentinelPath())
+	return UploadDtoDurably(backup.Folder, sentinelDto, backup.getStopSentinelPath())
+}
+
+// ModifySentinel fetches the sentinel into sentinelDto and uploads it back after applying modify, see ModifyDto
+func (backup *Backup) ModifySentinel(sentinelDto interface{}, modify func() error) error {
+	return ModifyDto(backup.Folder, sentinelDto, backup.getStopSentinelPath(), modify)
+}
+
+// ModifyMetadata fetches the metadata into metadataDto and uploads it back after applying modify, see ModifyDto
+func (backup *Backup) ModifyMetadata(metadataDto interface{}, modify func() error) error {
+	return ModifyDto(backup.Folder, metadataDto, backup.getMetadataPath(), modify)
 }
 
 // FetchDto gets data from path and de-serializes it to given object
@@ -157,7 +172,7 @@ func (backup *Backup) GetStorageName() string {
 // TODO : unit tests
 func UploadSentinel(uploader Uploader, sentinelDto interface{}, backupName string) error {
 	sentinelName := SentinelNameFromBackup(backupName)
-	return UploadDto(uploader.Folder(), sentinelDto, sentinelName)
+	return UploadDtoDurably(uploader.Folder(), sentinelDto, sentinelName)
 }
 
 type ErrWaiter interface {
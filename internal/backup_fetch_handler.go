@@ -88,5 +88,10 @@ func HandleBackupFetch(folder storage.Folder, targetBackupSelector BackupSelecto
 	tracelog.ErrorLogger.FatalfOnError("Failed to select backup: %v\n", err)
 	tracelog.DebugLogger.Printf("HandleBackupFetch(%s)\n", backup.Name)
 
+	backup.RestoreReport = NewRestoreReporter(backup.Name)
+	backup.RestoreReport.Write(folder, false)
+	finishFetch := backup.RestoreReport.StartPhase("fetch")
 	fetcher(folder, backup)
+	finishFetch()
+	backup.RestoreReport.Write(folder, true)
 }
@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"archive/tar"
+	"os"
 	"sort"
 	"time"
 )
@@ -13,10 +15,27 @@ type BackupFileDescription struct {
 	MTime         time.Time
 	CorruptBlocks *CorruptBlocksInfo `json:",omitempty"`
 	UpdatesCount  uint64
+	// Size is the size of the regular file, IncrementSize is the size of the increment
+	// stored in place of the incremented file
+	Size          int64 `json:",omitempty"`
+	IncrementSize int64 `json:",omitempty"`
+	// Ranges are the boundaries of the tar entries the large file is split into
+	Ranges []FileRange `json:",omitempty"`
 }
 
 func NewBackupFileDescription(isIncremented, isSkipped bool, modTime time.Time) *BackupFileDescription {
-	return &BackupFileDescription{isIncremented, isSkipped, modTime, nil, 0}
+	return &BackupFileDescription{IsIncremented: isIncremented, IsSkipped: isSkipped, MTime: modTime}
+}
+
+// SetSizes records the size of the file and the size of its increment from the tar header
+func (desc *BackupFileDescription) SetSizes(tarHeader *tar.Header, fileInfo os.FileInfo) {
+	if !fileInfo.Mode().IsRegular() {
+		return
+	}
+	desc.Size = fileInfo.Size()
+	if desc.IsIncremented {
+		desc.IncrementSize = tarHeader.Size
+	}
 }
 
 type CorruptBlocksInfo struct {
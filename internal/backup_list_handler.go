@@ -9,6 +9,11 @@ import (
 )
 
 func HandleDefaultBackupList(folder storage.Folder, pretty, json bool) {
+	HandleFilteredBackupList(folder, nil, nil, pretty, json)
+}
+
+// HandleFilteredBackupList lists the backups whose user data matches the filter
+func HandleFilteredBackupList(folder storage.Folder, filter UserDataFilter, metaFetcher GenericMetaFetcher, pretty, json bool) {
 	backupTimes, err := GetBackups(folder)
 	_, noBackupsErr := err.(NoBackupsFoundError)
 	if noBackupsErr {
@@ -17,6 +22,9 @@ func HandleDefaultBackupList(folder storage.Folder, pretty, json bool) {
 	}
 	tracelog.ErrorLogger.FatalfOnError("Get backups from folder: %v", err)
 
+	backupTimes, err = FilterBackupsByUserData(folder, backupTimes, filter, metaFetcher)
+	tracelog.ErrorLogger.FatalfOnError("Filter backups by user data: %v", err)
+
 	SortBackupTimeSlices(backupTimes)
 
 	printableEntities := make([]printlist.Entity, len(backupTimes))
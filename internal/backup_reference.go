@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const (
+	LatestFullString = "LATEST_FULL"
+	FullString       = "FULL"
+
+	backupOrdinalSeparator = "~"
+)
+
+// BackupReference is the symbolic reference to the backup counted back from the latest one:
+// LATEST, LATEST~N, LATEST_FULL, LATEST_FULL~N or FULL~N. The ordinal 0 is the latest backup,
+// the ordinal N is the N-th backup before it.
+type BackupReference struct {
+	FullOnly bool
+	Ordinal  int
+}
+
+type InvalidBackupReferenceError struct {
+	error
+}
+
+func NewInvalidBackupReferenceError(reference string, reason string) InvalidBackupReferenceError {
+	return InvalidBackupReferenceError{errors.Errorf("invalid backup reference '%s': %s", reference, reason)}
+}
+
+func (err InvalidBackupReferenceError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// ParseBackupReference parses the symbolic backup reference,
+// it returns nil if the name is not a reference but the name of a backup
+func ParseBackupReference(name string) (*BackupReference, error) {
+	base, ordinalStr, hasOrdinal := strings.Cut(name, backupOrdinalSeparator)
+	reference := &BackupReference{}
+	switch base {
+	case LatestString:
+	case LatestFullString:
+		reference.FullOnly = true
+	case FullString:
+		if !hasOrdinal {
+			return nil, nil
+		}
+		reference.FullOnly = true
+	default:
+		return nil, nil
+	}
+	if hasOrdinal {
+		ordinal, err := strconv.Atoi(ordinalStr)
+		if err != nil || ordinal < 0 {
+			return nil, NewInvalidBackupReferenceError(name, "the ordinal must be a non-negative number")
+		}
+		reference.Ordinal = ordinal
+	}
+	return reference, nil
+}
+
+// IsBackupReference tells if the name is a symbolic backup reference rather than the name of a backup
+func IsBackupReference(name string) bool {
+	reference, err := ParseBackupReference(name)
+	return reference != nil || err != nil
+}
+
+func (reference BackupReference) String() string {
+	base := LatestString
+	if reference.FullOnly {
+		base = LatestFullString
+	}
+	if reference.Ordinal == 0 {
+		return base
+	}
+	return fmt.Sprintf("%s%s%d", base, backupOrdinalSeparator, reference.Ordinal)
+}
+
+// Resolve finds the referenced backup in the base backups folder
+func (reference BackupReference) Resolve(folder storage.Folder) (BackupTime, error) {
+	backupTimes, err := GetBackups(folder)
+	if err != nil {
+		return BackupTime{}, err
+	}
+	SortBackupTimeSlices(backupTimes)
+
+	skip := reference.Ordinal
+	for i := len(backupTimes) - 1; i >= 0; i-- {
+		if reference.FullOnly {
+			isFull, err := isFullBackup(folder, backupTimes[i])
+			if err != nil {
+				return BackupTime{}, err
+			}
+			if !isFull {
+				continue
+			}
+		}
+		if skip == 0 {
+			tracelog.InfoLogger.Printf("%s backup is: '%s'\n", reference, backupTimes[i].BackupName)
+			return backupTimes[i], nil
+		}
+		skip--
+	}
+	tracelog.InfoLogger.Printf("There are not enough backups to resolve %s\n", reference)
+	return BackupTime{}, NewNoBackupsFoundError()
+}
+
+// incrementSentinel holds the sentinel fields that tell the incremental backups of different databases
+type incrementSentinel struct {
+	DeltaFrom     *string `json:"DeltaFrom,omitempty"`
+	IncrementFrom *string `json:"increment_from,omitempty"`
+}
+
+func isFullBackup(folder storage.Folder, backupTime BackupTime) (bool, error) {
+	backup, err := NewBackupInStorage(folder, backupTime.BackupName, backupTime.StorageName)
+	if err != nil {
+		return false, err
+	}
+	var sentinel incrementSentinel
+	err = backup.FetchSentinel(&sentinel)
+	if err != nil {
+		return false, errors.Wrapf(err, "fetch sentinel of backup %s", backupTime.BackupName)
+	}
+	return sentinel.DeltaFrom == nil && sentinel.IncrementFrom == nil, nil
+}
+
+// GetBackupByReference returns the backup the symbolic reference points to in the base backups folder
+func GetBackupByReference(folder storage.Folder, reference BackupReference) (Backup, error) {
+	if reference == (BackupReference{}) {
+		return GetLatestBackup(folder)
+	}
+	backupTime, err := reference.Resolve(folder)
+	if err != nil {
+		return Backup{}, err
+	}
+	return NewBackupInStorage(folder, backupTime.BackupName, backupTime.StorageName)
+}
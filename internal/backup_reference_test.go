@@ -0,0 +1,104 @@
+package internal_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/testtools"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestParseBackupReference(t *testing.T) {
+	cases := []struct {
+		name      string
+		reference *internal.BackupReference
+		wantErr   bool
+	}{
+		{name: "LATEST", reference: &internal.BackupReference{}},
+		{name: "LATEST~1", reference: &internal.BackupReference{Ordinal: 1}},
+		{name: "LATEST_FULL", reference: &internal.BackupReference{FullOnly: true}},
+		{name: "LATEST_FULL~3", reference: &internal.BackupReference{FullOnly: true, Ordinal: 3}},
+		{name: "FULL~2", reference: &internal.BackupReference{FullOnly: true, Ordinal: 2}},
+		{name: "FULL"},
+		{name: "base_000000010000000000000002"},
+		{name: "LATEST~", wantErr: true},
+		{name: "LATEST~-1", wantErr: true},
+		{name: "FULL~x", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reference, err := internal.ParseBackupReference(tc.name)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.reference, reference)
+		})
+	}
+}
+
+// putReferenceTestBackups uploads the backups from the oldest to the latest: full, delta, full, delta, delta
+func putReferenceTestBackups(t *testing.T, folder storage.Folder) {
+	sentinels := []struct {
+		name    string
+		content string
+	}{
+		{"base_000000010000000000000002", `{}`},
+		{"base_000000010000000000000004_D_000000010000000000000002", `{"DeltaFrom":"base_000000010000000000000002"}`},
+		{"base_000000010000000000000006", `{"DeltaFrom":null}`},
+		{"base_000000010000000000000008_D_000000010000000000000006", `{"DeltaFrom":"base_000000010000000000000006"}`},
+		{"base_00000001000000000000000A_D_000000010000000000000008", `{"DeltaFrom":"base_000000010000000000000008"}`},
+	}
+	for _, sentinel := range sentinels {
+		err := folder.PutObject(sentinel.name+utility.SentinelSuffix, strings.NewReader(sentinel.content))
+		require.NoError(t, err)
+	}
+}
+
+func TestGetBackupByName_References(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	putReferenceTestBackups(t, folder.GetSubFolder(utility.BaseBackupPath))
+
+	cases := map[string]string{
+		"LATEST":        "base_00000001000000000000000A_D_000000010000000000000008",
+		"LATEST~1":      "base_000000010000000000000008_D_000000010000000000000006",
+		"LATEST~4":      "base_000000010000000000000002",
+		"LATEST_FULL":   "base_000000010000000000000006",
+		"LATEST_FULL~1": "base_000000010000000000000002",
+		"FULL~1":        "base_000000010000000000000002",
+	}
+	for name, expected := range cases {
+		backup, err := internal.GetBackupByName(name, utility.BaseBackupPath, folder)
+		require.NoError(t, err, name)
+		assert.Equal(t, expected, backup.Name, name)
+	}
+}
+
+func TestGetBackupByName_ReferenceOutOfRange(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	putReferenceTestBackups(t, folder.GetSubFolder(utility.BaseBackupPath))
+
+	_, err := internal.GetBackupByName("LATEST~5", utility.BaseBackupPath, folder)
+	assert.IsType(t, internal.NoBackupsFoundError{}, err)
+
+	_, err = internal.GetBackupByName("FULL~2", utility.BaseBackupPath, folder)
+	assert.IsType(t, internal.NoBackupsFoundError{}, err)
+}
+
+func TestUnwrapLatestModifier_Reference(t *testing.T) {
+	folder := testtools.MakeDefaultInMemoryStorageFolder()
+	putReferenceTestBackups(t, folder)
+
+	name, err := internal.UnwrapLatestModifier("FULL~1", folder)
+	assert.NoError(t, err)
+	assert.Equal(t, "base_000000010000000000000002", name)
+
+	name, err = internal.UnwrapLatestModifier("base_000000010000000000000004", folder)
+	assert.NoError(t, err)
+	assert.Equal(t, "base_000000010000000000000004", name)
+}
@@ -144,7 +144,7 @@ func NewBackupNameSelector(backupName string, checkExistence bool) (BackupNameSe
 }
 
 func (s BackupNameSelector) Select(folder storage.Folder) (Backup, error) {
-	if !s.checkExistence {
+	if !s.checkExistence && !IsBackupReference(s.backupName) {
 		return NewBackupInStorage(folder, s.backupName, consts.DefaultStorage)
 	}
 	return GetBackupByName(s.backupName, utility.BaseBackupPath, folder)
@@ -160,6 +160,10 @@ func NewTargetBackupSelector(targetUserData, targetName string, metaFetcher Gene
 		tracelog.InfoLogger.Printf("Selecting the latest backup...\n")
 		return NewLatestBackupSelector(), nil
 
+	case IsBackupReference(targetName):
+		tracelog.InfoLogger.Printf("Selecting the backup referenced as %s...\n", targetName)
+		return NewBackupNameSelector(targetName, true)
+
 	case targetName != "":
 		tracelog.InfoLogger.Printf("Selecting the backup with name %s...\n", targetName)
 		return NewBackupNameSelector(targetName, true)
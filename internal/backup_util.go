@@ -44,8 +44,12 @@ func (err NoBackupsFoundError) Error() string {
 func GetBackupByName(backupName, subfolder string, folder storage.Folder) (backup Backup, err error) {
 	baseBackupFolder := folder.GetSubFolder(subfolder)
 
-	if backupName == LatestString {
-		return GetLatestBackup(baseBackupFolder)
+	reference, err := ParseBackupReference(backupName)
+	if err != nil {
+		return Backup{}, err
+	}
+	if reference != nil {
+		return GetBackupByReference(baseBackupFolder, *reference)
 	}
 
 	return GetSpecificBackup(baseBackupFolder, backupName)
@@ -169,19 +173,19 @@ func StreamMetadataNameFromBackup(backupName string) string {
 	return backupName + "/" + utility.StreamMetadataFileName
 }
 
-// UnwrapLatestModifier checks if LATEST is provided instead of backupName
-// if so, replaces it with the name of the latest backup
+// UnwrapLatestModifier checks if LATEST or another symbolic reference is provided instead of backupName
+// if so, replaces it with the name of the referenced backup
 func UnwrapLatestModifier(backupName string, folder storage.Folder) (string, error) {
-	if backupName != LatestString {
-		return backupName, nil
+	reference, err := ParseBackupReference(backupName)
+	if err != nil || reference == nil {
+		return backupName, err
 	}
 
-	latest, err := GetLatestBackup(folder)
+	backup, err := GetBackupByReference(folder, *reference)
 	if err != nil {
 		return "", err
 	}
-	tracelog.InfoLogger.Printf("LATEST backup is: '%s'\n", latest)
-	return latest.Name, nil
+	return backup.Name, nil
 }
 
 func FolderSize(folder storage.Folder, path string) (int64, error) {
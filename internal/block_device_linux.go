@@ -0,0 +1,38 @@
+//go:build linux
+// +build linux
+
+package internal
+
+import (
+	"os"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// prepareBlockDevice checks that the content fits the device and discards the range it is going to occupy
+func prepareBlockDevice(device *os.File, size int64, discard bool) error {
+	var deviceSize uint64
+	err := ioctl(device, unix.BLKGETSIZE64, uintptr(unsafe.Pointer(&deviceSize)))
+	if err != nil {
+		return errors.Wrap(err, "failed to get the block device size")
+	}
+	if uint64(size) > deviceSize {
+		return errors.Errorf("the file of %d bytes does not fit the device of %d bytes", size, deviceSize)
+	}
+	if !discard {
+		return nil
+	}
+	discardRange := [2]uint64{0, deviceSize}
+	err = ioctl(device, unix.BLKDISCARD, uintptr(unsafe.Pointer(&discardRange[0])))
+	return errors.Wrap(err, "failed to discard the block device")
+}
+
+func ioctl(device *os.File, request, arg uintptr) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, device.Fd(), request, arg)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package internal
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+func prepareBlockDevice(device *os.File, size int64, discard bool) error {
+	return errors.New("restoring onto block devices is supported only on Linux")
+}
@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const blockDeviceWriteSize = 1024 * 1024
+
+// BlockDeviceTarInterpreter writes the selected files directly onto raw block devices (or volume image files)
+// instead of a filesystem. The rest of the files are passed to the fallback interpreter.
+type BlockDeviceTarInterpreter struct {
+	fallback TarInterpreter
+	devices  map[string]string
+	discard  bool
+}
+
+// NewBlockDeviceTarInterpreter maps the backup file names to the devices. With discard the device is trimmed
+// before writing and the zero blocks are not written at all, so thin-provisioned volumes stay thin.
+// The fallback may be nil, then the files that are not mapped are skipped.
+func NewBlockDeviceTarInterpreter(fallback TarInterpreter, devices map[string]string, discard bool) *BlockDeviceTarInterpreter {
+	return &BlockDeviceTarInterpreter{
+		fallback: fallback,
+		devices:  devices,
+		discard:  discard,
+	}
+}
+
+// ParseBlockDeviceMapping parses the "file=device" pairs
+func ParseBlockDeviceMapping(pairs []string) (map[string]string, error) {
+	devices := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		fileName, device, ok := strings.Cut(pair, "=")
+		if !ok || fileName == "" || device == "" {
+			return nil, errors.Errorf("invalid block device mapping '%s', expected 'file=device'", pair)
+		}
+		devices[strings.TrimPrefix(fileName, "/")] = device
+	}
+	return devices, nil
+}
+
+func (interpreter *BlockDeviceTarInterpreter) Interpret(reader io.Reader, header *tar.Header) error {
+	device, ok := interpreter.devices[header.Name]
+	if !ok {
+		if interpreter.fallback == nil {
+			return nil
+		}
+		return interpreter.fallback.Interpret(reader, header)
+	}
+	if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+		return errors.Errorf("can not restore %s onto %s: not a regular file", header.Name, device)
+	}
+
+	fileRange, _, isRange, err := GetFileRange(header)
+	if err != nil {
+		return err
+	}
+	if isRange {
+		tracelog.InfoLogger.Printf("Writing %s range at %d onto %s", header.Name, fileRange.Offset, device)
+		return writeRangeOntoBlockDevice(device, fileRange, reader)
+	}
+	tracelog.InfoLogger.Printf("Writing %s onto %s", header.Name, device)
+	return writeOntoBlockDevice(device, header.Size, reader, interpreter.discard)
+}
+
+// writeRangeOntoBlockDevice writes the range of the split file. The ranges are written concurrently,
+// so the device is neither trimmed nor resized and the zero blocks are written as well.
+func writeRangeOntoBlockDevice(devicePath string, fileRange FileRange, reader io.Reader) error {
+	device, err := os.OpenFile(devicePath, os.O_WRONLY, 0)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %s", devicePath)
+	}
+	defer utility.LoggedClose(device, "")
+
+	written, err := io.Copy(io.NewOffsetWriter(device, fileRange.Offset), reader)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write onto %s", devicePath)
+	}
+	if written != fileRange.Size {
+		return newTarSizeError(written, fileRange.Size)
+	}
+	return device.Sync()
+}
+
+func writeOntoBlockDevice(devicePath string, size int64, reader io.Reader, discard bool) error {
+	device, err := os.OpenFile(devicePath, os.O_WRONLY, 0)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %s", devicePath)
+	}
+	defer utility.LoggedClose(device, "")
+
+	stat, err := device.Stat()
+	if err != nil {
+		return err
+	}
+	if stat.Mode().IsRegular() {
+		// volume image file: truncating it releases the blocks and lets the zero blocks stay holes
+		if discard {
+			err = device.Truncate(0)
+			if err == nil {
+				err = device.Truncate(size)
+			}
+		}
+	} else {
+		err = prepareBlockDevice(device, size, discard)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to prepare %s", devicePath)
+	}
+
+	written, err := copySkippingZeroBlocks(device, reader, discard)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write onto %s", devicePath)
+	}
+	if written != size {
+		return newTarSizeError(written, size)
+	}
+	return device.Sync()
+}
+
+// copySkippingZeroBlocks copies the content to the beginning of the device, seeking over the zero blocks if skipZeros is set
+func copySkippingZeroBlocks(device *os.File, reader io.Reader, skipZeros bool) (int64, error) {
+	buffer := make([]byte, blockDeviceWriteSize)
+	zeros := make([]byte, blockDeviceWriteSize)
+	var written int64
+	for {
+		n, err := io.ReadFull(reader, buffer)
+		if n > 0 {
+			if skipZeros && bytes.Equal(buffer[:n], zeros[:n]) {
+				_, seekErr := device.Seek(int64(n), io.SeekCurrent)
+				if seekErr != nil {
+					return written, seekErr
+				}
+			} else if _, writeErr := device.Write(buffer[:n]); writeErr != nil {
+				return written, writeErr
+			}
+			written += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package internal_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+)
+
+type recordingTarInterpreter struct {
+	names []string
+}
+
+func (interpreter *recordingTarInterpreter) Interpret(_ io.Reader, header *tar.Header) error {
+	interpreter.names = append(interpreter.names, header.Name)
+	return nil
+}
+
+func TestParseBlockDeviceMapping(t *testing.T) {
+	devices, err := internal.ParseBlockDeviceMapping([]string{"/base/1/16384=/dev/vdb", "pg_wal/archive=/dev/mapper/thin-1"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"base/1/16384": "/dev/vdb", "pg_wal/archive": "/dev/mapper/thin-1"}, devices)
+
+	_, err = internal.ParseBlockDeviceMapping([]string{"base/1/16384"})
+	assert.Error(t, err)
+}
+
+func TestBlockDeviceTarInterpreter(t *testing.T) {
+	content := bytes.Repeat([]byte{0}, 3*1024*1024)
+	content = append(content, []byte("tail of the volume")...)
+	for _, discard := range []bool{false, true} {
+		volume := filepath.Join(t.TempDir(), "volume.img")
+		require.NoError(t, os.WriteFile(volume, bytes.Repeat([]byte{0xff}, 5*1024*1024), 0600))
+
+		fallback := &recordingTarInterpreter{}
+		interpreter := internal.NewBlockDeviceTarInterpreter(fallback, map[string]string{"disk.raw": volume}, discard)
+
+		header := &tar.Header{Name: "disk.raw", Typeflag: tar.TypeReg, Size: int64(len(content))}
+		require.NoError(t, interpreter.Interpret(bytes.NewReader(content), header))
+		require.NoError(t, interpreter.Interpret(bytes.NewReader(nil), &tar.Header{Name: "PG_VERSION", Typeflag: tar.TypeReg}))
+		assert.Equal(t, []string{"PG_VERSION"}, fallback.names)
+
+		written, err := os.ReadFile(volume)
+		require.NoError(t, err)
+		if discard {
+			assert.Equal(t, content, written)
+		} else {
+			// the rest of the volume stays untouched
+			assert.Equal(t, content, written[:len(content)])
+			assert.Len(t, written, 5*1024*1024)
+		}
+	}
+}
+
+func TestBlockDeviceTarInterpreter_SizeMismatch(t *testing.T) {
+	volume := filepath.Join(t.TempDir(), "volume.img")
+	require.NoError(t, os.WriteFile(volume, nil, 0600))
+	interpreter := internal.NewBlockDeviceTarInterpreter(nil, map[string]string{"disk.raw": volume}, false)
+
+	header := &tar.Header{Name: "disk.raw", Typeflag: tar.TypeReg, Size: 100}
+	err := interpreter.Interpret(bytes.NewReader([]byte("short")), header)
+	assert.IsType(t, internal.TarSizeError{}, err)
+}
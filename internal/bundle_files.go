@@ -21,13 +21,15 @@ type RegularBundleFiles struct {
 }
 
 func (files *RegularBundleFiles) AddSkippedFile(tarHeader *tar.Header, fileInfo os.FileInfo) {
-	files.AddFileDescription(tarHeader.Name,
-		BackupFileDescription{IsSkipped: true, IsIncremented: false, MTime: fileInfo.ModTime()})
+	fileDescription := BackupFileDescription{IsSkipped: true, IsIncremented: false, MTime: fileInfo.ModTime()}
+	fileDescription.SetSizes(tarHeader, fileInfo)
+	files.AddFileDescription(tarHeader.Name, fileDescription)
 }
 
 func (files *RegularBundleFiles) AddFile(tarHeader *tar.Header, fileInfo os.FileInfo, isIncremented bool) {
-	files.AddFileDescription(tarHeader.Name,
-		BackupFileDescription{IsSkipped: false, IsIncremented: isIncremented, MTime: fileInfo.ModTime()})
+	fileDescription := BackupFileDescription{IsSkipped: false, IsIncremented: isIncremented, MTime: fileInfo.ModTime()}
+	fileDescription.SetSizes(tarHeader, fileInfo)
+	files.AddFileDescription(tarHeader.Name, fileDescription)
 }
 
 func (files *RegularBundleFiles) AddFileDescription(name string, backupFileDescription BackupFileDescription) {
@@ -37,6 +39,7 @@ func (files *RegularBundleFiles) AddFileDescription(name string, backupFileDescr
 func (files *RegularBundleFiles) AddFileWithCorruptBlocks(tarHeader *tar.Header, fileInfo os.FileInfo,
 	isIncremented bool, corruptedBlocks []uint32, storeAllBlocks bool) {
 	fileDescription := BackupFileDescription{IsSkipped: false, IsIncremented: isIncremented, MTime: fileInfo.ModTime()}
+	fileDescription.SetSizes(tarHeader, fileInfo)
 	fileDescription.SetCorruptBlocks(corruptedBlocks, storeAllBlocks)
 	files.AddFileDescription(tarHeader.Name, fileDescription)
 }
@@ -0,0 +1,329 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/printlist"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	CompliancePass = "PASS"
+	ComplianceFail = "FAIL"
+
+	complianceDay = 24 * time.Hour
+)
+
+// ComplianceTargets are the recovery objectives declared by the user in the config.
+// Zero RPO or RTO means that the corresponding objective is not evaluated.
+type ComplianceTargets struct {
+	RPO         time.Duration
+	RTO         time.Duration
+	RestoreRate int64
+}
+
+func ConfigureComplianceTargets() (ComplianceTargets, error) {
+	targets := ComplianceTargets{}
+	var err error
+	if viper.IsSet(conf.TargetRPOSetting) {
+		targets.RPO, err = conf.GetDurationSetting(conf.TargetRPOSetting)
+		if err != nil {
+			return ComplianceTargets{}, err
+		}
+	}
+	if viper.IsSet(conf.TargetRTOSetting) {
+		targets.RTO, err = conf.GetDurationSetting(conf.TargetRTOSetting)
+		if err != nil {
+			return ComplianceTargets{}, err
+		}
+	}
+	targets.RestoreRate = int64(viper.GetSizeInBytes(conf.ComplianceRestoreRateSetting))
+	if targets.RTO > 0 && targets.RestoreRate <= 0 {
+		return ComplianceTargets{}, fmt.Errorf("%s must be positive to evaluate %s",
+			conf.ComplianceRestoreRateSetting, conf.TargetRTOSetting)
+	}
+	return targets, nil
+}
+
+type ComplianceViolationError struct {
+	error
+}
+
+func newComplianceViolationError(failedDays int) ComplianceViolationError {
+	return ComplianceViolationError{errors.Errorf("%d day(s) failed to meet the recovery objectives", failedDays)}
+}
+
+func (err ComplianceViolationError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// ComplianceDayReport is the evaluation of the recovery objectives for a single day (UTC).
+type ComplianceDayReport struct {
+	Day                    time.Time `json:"day"`
+	BackupCount            int       `json:"backup_count"`
+	MaxBackupAgeSec        int64     `json:"max_backup_age_seconds"`
+	MaxArchiveGapSec       int64     `json:"max_archive_gap_seconds"`
+	WorstRPOSec            int64     `json:"worst_rpo_seconds"`
+	EstimatedRestoreSec    int64     `json:"estimated_restore_seconds"`
+	RecoveryPointAvailable bool      `json:"recovery_point_available"`
+	RPOResult              string    `json:"rpo_result"`
+	RTOResult              string    `json:"rto_result"`
+	Result                 string    `json:"result"`
+}
+
+func (r ComplianceDayReport) PrintableFields() []printlist.TableField {
+	formatSec := func(sec int64) string {
+		return (time.Duration(sec) * time.Second).String()
+	}
+	return []printlist.TableField{
+		{Name: "day", PrettyName: "Day", Value: r.Day.Format("2006-01-02")},
+		{Name: "backups", PrettyName: "Backups", Value: fmt.Sprint(r.BackupCount)},
+		{Name: "max_backup_age", PrettyName: "Max backup age", Value: formatSec(r.MaxBackupAgeSec)},
+		{Name: "max_archive_gap", PrettyName: "Max archive gap", Value: formatSec(r.MaxArchiveGapSec)},
+		{Name: "worst_rpo", PrettyName: "Worst RPO", Value: formatSec(r.WorstRPOSec)},
+		{Name: "estimated_restore", PrettyName: "Estimated restore", Value: formatSec(r.EstimatedRestoreSec)},
+		{Name: "rpo", PrettyName: "RPO", Value: r.RPOResult},
+		{Name: "rto", PrettyName: "RTO", Value: r.RTOResult},
+		{Name: "result", PrettyName: "Result", Value: r.Result},
+	}
+}
+
+// ComplianceBackup is a completed backup as seen by the compliance evaluation.
+type ComplianceBackup struct {
+	Name       string
+	FinishTime time.Time
+	Size       int64
+}
+
+// HandleComplianceReport evaluates the actual backup frequency, archive lag and estimated restore time against
+// the targets for every day in [since, until) and prints the per-day verdicts. Log archives (WAL, binlogs, etc.)
+// are looked up in the logsPath subfolder; empty logsPath means that only backups are recovery points.
+func HandleComplianceReport(
+	rootFolder storage.Folder,
+	logsPath string,
+	metaFetcher GenericMetaFetcher,
+	targets ComplianceTargets,
+	since, until time.Time,
+	output io.Writer,
+	pretty, json bool,
+) error {
+	backups, err := getComplianceBackups(rootFolder.GetSubFolder(utility.BaseBackupPath), metaFetcher, until)
+	if err != nil {
+		return err
+	}
+
+	var logs []storage.Object
+	if logsPath != "" {
+		logs, err = storage.ListFolderRecursively(rootFolder.GetSubFolder(logsPath))
+		if err != nil {
+			return fmt.Errorf("list log archives: %w", err)
+		}
+	}
+
+	reports := EvaluateCompliance(backups, logs, targets, since, until)
+
+	entities := make([]printlist.Entity, len(reports))
+	failedDays := 0
+	for i := range reports {
+		entities[i] = reports[i]
+		if reports[i].Result == ComplianceFail {
+			failedDays++
+		}
+	}
+	err = printlist.List(entities, output, pretty, json)
+	if err != nil {
+		return fmt.Errorf("print compliance report: %w", err)
+	}
+	if failedDays > 0 {
+		return newComplianceViolationError(failedDays)
+	}
+	return nil
+}
+
+func getComplianceBackups(
+	backupsFolder storage.Folder,
+	metaFetcher GenericMetaFetcher,
+	until time.Time,
+) ([]ComplianceBackup, error) {
+	backupTimes, err := GetBackups(backupsFolder)
+	if _, ok := err.(NoBackupsFoundError); ok {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get backups: %w", err)
+	}
+
+	backups := make([]ComplianceBackup, 0, len(backupTimes))
+	for _, backupTime := range backupTimes {
+		if backupTime.Time.After(until) {
+			continue
+		}
+		backup := ComplianceBackup{Name: backupTime.BackupName, FinishTime: backupTime.Time}
+		meta, err := metaFetcher.Fetch(backupTime.BackupName, backupsFolder)
+		if err != nil {
+			tracelog.WarningLogger.Printf("Failed to get metadata of backup %s, its size is unknown: %v\n",
+				backupTime.BackupName, err)
+		} else {
+			backup.Size = meta.CompressedSize
+			if !meta.FinishTime.IsZero() {
+				backup.FinishTime = meta.FinishTime
+			}
+		}
+		backups = append(backups, backup)
+	}
+	return backups, nil
+}
+
+// EvaluateCompliance builds a report for each UTC day overlapping [since, until).
+func EvaluateCompliance(
+	backups []ComplianceBackup,
+	logs []storage.Object,
+	targets ComplianceTargets,
+	since, until time.Time,
+) []ComplianceDayReport {
+	backups = append([]ComplianceBackup(nil), backups...)
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].FinishTime.Before(backups[j].FinishTime)
+	})
+	logs = append([]storage.Object(nil), logs...)
+	sort.Slice(logs, func(i, j int) bool {
+		return logs[i].GetLastModified().Before(logs[j].GetLastModified())
+	})
+
+	backupPoints := make([]time.Time, len(backups))
+	for i := range backups {
+		backupPoints[i] = backups[i].FinishTime
+	}
+	logPoints := make([]time.Time, len(logs))
+	for i := range logs {
+		logPoints[i] = logs[i].GetLastModified()
+	}
+	recoveryPoints := append(append([]time.Time{}, backupPoints...), logPoints...)
+	sort.Slice(recoveryPoints, func(i, j int) bool {
+		return recoveryPoints[i].Before(recoveryPoints[j])
+	})
+
+	reports := make([]ComplianceDayReport, 0)
+	for dayStart := since.UTC().Truncate(complianceDay); dayStart.Before(until); dayStart = dayStart.Add(complianceDay) {
+		dayEnd := dayStart.Add(complianceDay)
+		if dayEnd.After(until) {
+			dayEnd = until
+		}
+
+		report := ComplianceDayReport{Day: dayStart}
+		for _, point := range backupPoints {
+			if !point.Before(dayStart) && point.Before(dayEnd) {
+				report.BackupCount++
+			}
+		}
+		backupAge, _ := maxPointGap(backupPoints, dayStart, dayEnd)
+		report.MaxBackupAgeSec = int64(backupAge.Seconds())
+		if len(logs) > 0 {
+			archiveGap, _ := maxPointGap(logPoints, dayStart, dayEnd)
+			report.MaxArchiveGapSec = int64(archiveGap.Seconds())
+		}
+		worstRPO, ok := maxPointGap(recoveryPoints, dayStart, dayEnd)
+		report.WorstRPOSec = int64(worstRPO.Seconds())
+		report.RecoveryPointAvailable = ok && len(backupPoints) > 0 && !backupPoints[0].After(dayEnd)
+
+		restoreTime := maxRestoreTime(backups, logs, targets.RestoreRate, dayStart, dayEnd)
+		report.EstimatedRestoreSec = int64(restoreTime.Seconds())
+
+		report.RPOResult = evaluateObjective(report.RecoveryPointAvailable, worstRPO, targets.RPO)
+		report.RTOResult = evaluateObjective(report.RecoveryPointAvailable, restoreTime, targets.RTO)
+		report.Result = CompliancePass
+		if report.RPOResult == ComplianceFail || report.RTOResult == ComplianceFail {
+			report.Result = ComplianceFail
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+func evaluateObjective(recoverable bool, actual, target time.Duration) string {
+	if !recoverable {
+		return ComplianceFail
+	}
+	if target > 0 && actual > target {
+		return ComplianceFail
+	}
+	return CompliancePass
+}
+
+// maxPointGap returns the longest time during [from, to) for which no newer point was available, i.e. the worst
+// possible data loss if only the points are recoverable. Returns false if there is no point before the window end.
+func maxPointGap(sortedPoints []time.Time, from, to time.Time) (time.Duration, bool) {
+	idx := sort.Search(len(sortedPoints), func(i int) bool {
+		return !sortedPoints[i].Before(from)
+	})
+	var prev time.Time
+	if idx > 0 {
+		prev = sortedPoints[idx-1]
+	}
+
+	var maxGap time.Duration
+	for ; idx < len(sortedPoints) && sortedPoints[idx].Before(to); idx++ {
+		gapStart := prev
+		if gapStart.IsZero() {
+			gapStart = from
+		}
+		if gap := sortedPoints[idx].Sub(gapStart); gap > maxGap {
+			maxGap = gap
+		}
+		prev = sortedPoints[idx]
+	}
+	if prev.IsZero() {
+		return to.Sub(from), false
+	}
+	if gap := to.Sub(prev); gap > maxGap {
+		maxGap = gap
+	}
+	return maxGap, true
+}
+
+// maxRestoreTime estimates the worst restore duration during [from, to): the latest backup has to be downloaded
+// together with all the log archives uploaded after it. The worst moments are right before a new backup
+// finishes and at the end of the window.
+func maxRestoreTime(backups []ComplianceBackup, logs []storage.Object, rate int64, from, to time.Time) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	checkpoints := []time.Time{to}
+	for i := range backups {
+		if backups[i].FinishTime.After(from) && backups[i].FinishTime.Before(to) {
+			checkpoints = append(checkpoints, backups[i].FinishTime)
+		}
+	}
+
+	var worst time.Duration
+	for _, checkpoint := range checkpoints {
+		var base *ComplianceBackup
+		for i := range backups {
+			if backups[i].FinishTime.Before(checkpoint) {
+				base = &backups[i]
+			}
+		}
+		if base == nil {
+			continue
+		}
+		bytesToRestore := base.Size
+		for _, log := range logs {
+			if log.GetLastModified().After(base.FinishTime) && log.GetLastModified().Before(checkpoint) {
+				bytesToRestore += log.GetSize()
+			}
+		}
+		restoreTime := time.Duration(float64(bytesToRestore) / float64(rate) * float64(time.Second))
+		if restoreTime > worst {
+			worst = restoreTime
+		}
+	}
+	return worst
+}
@@ -0,0 +1,58 @@
+package internal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+func TestEvaluateCompliance(t *testing.T) {
+	day := time.Date(2023, 7, 22, 0, 0, 0, 0, time.UTC)
+	backups := []internal.ComplianceBackup{
+		{Name: "base_1", FinishTime: day.Add(-2 * time.Hour), Size: 3600},
+		{Name: "base_2", FinishTime: day.Add(12 * time.Hour), Size: 1800},
+	}
+	logs := []storage.Object{
+		storage.NewLocalObject("000000010000000000000001", day.Add(-time.Hour), 1800),
+		storage.NewLocalObject("000000010000000000000002", day.Add(time.Hour), 1800),
+		storage.NewLocalObject("000000010000000000000003", day.Add(4*time.Hour), 1800),
+		storage.NewLocalObject("000000010000000000000004", day.Add(20*time.Hour), 1800),
+	}
+
+	t.Run("passes when objectives are met", func(t *testing.T) {
+		targets := internal.ComplianceTargets{RPO: 9 * time.Hour, RTO: 10 * time.Second, RestoreRate: 1000}
+		reports := internal.EvaluateCompliance(backups, logs, targets, day, day.Add(24*time.Hour))
+		require.Len(t, reports, 1)
+
+		report := reports[0]
+		assert.Equal(t, 1, report.BackupCount)
+		assert.Equal(t, int64((14 * time.Hour).Seconds()), report.MaxBackupAgeSec)
+		assert.Equal(t, int64((16 * time.Hour).Seconds()), report.MaxArchiveGapSec)
+		assert.Equal(t, int64((8 * time.Hour).Seconds()), report.WorstRPOSec)
+		// right before base_2 finishes: base_1 + three WAL segments
+		assert.Equal(t, int64(9), report.EstimatedRestoreSec)
+		assert.Equal(t, internal.CompliancePass, report.Result)
+	})
+
+	t.Run("fails when objectives are violated", func(t *testing.T) {
+		targets := internal.ComplianceTargets{RPO: time.Hour, RTO: 5 * time.Second, RestoreRate: 1000}
+		reports := internal.EvaluateCompliance(backups, logs, targets, day, day.Add(24*time.Hour))
+		require.Len(t, reports, 1)
+		assert.Equal(t, internal.ComplianceFail, reports[0].RPOResult)
+		assert.Equal(t, internal.ComplianceFail, reports[0].RTOResult)
+		assert.Equal(t, internal.ComplianceFail, reports[0].Result)
+	})
+
+	t.Run("fails days without any backup", func(t *testing.T) {
+		reports := internal.EvaluateCompliance(nil, logs, internal.ComplianceTargets{}, day, day.Add(48*time.Hour))
+		require.Len(t, reports, 2)
+		for _, report := range reports {
+			assert.False(t, report.RecoveryPointAvailable)
+			assert.Equal(t, internal.ComplianceFail, report.Result)
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"io"
 
@@ -25,6 +26,17 @@ func (err CompressAndEncryptError) Error() string {
 	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
 }
 
+// CompressAndEncryptScanned compresses and encrypts the source uploaded to the path, passing its plaintext
+// to the upload scanner of the uploader
+func CompressAndEncryptScanned(ctx context.Context, uploader Uploader, path string, source io.Reader,
+	compressor compression.Compressor, crypter crypto.Crypter) (io.Reader, error) {
+	scanInput, err := uploader.StartPlaintextScan(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return CompressAndEncrypt(io.TeeReader(source, scanInput), compressor, crypter), nil
+}
+
 // CompressAndEncrypt compresses input to a pipe reader. Output must be used or
 // pipe will block.
 func CompressAndEncrypt(source io.Reader, compressor compression.Compressor, crypter crypto.Crypter) io.Reader {
@@ -4,6 +4,7 @@
 package brotli
 
 import (
+	"fmt"
 	"github.com/wal-g/wal-g/internal/ioextensions"
 	"io"
 
@@ -13,12 +14,30 @@ import (
 const (
 	AlgorithmName = "brotli"
 	FileExtension = "br"
+
+	defaultQuality = 3
+	maxQuality     = 11
 )
 
-type Compressor struct{}
+type Compressor struct {
+	// Level is the brotli quality from 1 to 11, the zero level means the default one
+	Level int
+}
 
 func (compressor Compressor) NewWriter(writer io.Writer) ioextensions.WriteFlushCloser {
-	return cbrotli.NewWriter(writer, cbrotli.WriterOptions{Quality: 3})
+	quality := defaultQuality
+	if compressor.Level != 0 {
+		quality = compressor.Level
+	}
+	return cbrotli.NewWriter(writer, cbrotli.WriterOptions{Quality: quality})
+}
+
+func (compressor Compressor) WithLevel(level int) (Compressor, error) {
+	if level < 1 || level > maxQuality {
+		return compressor, fmt.Errorf("brotli compression level must be from 1 to %d, got %d", maxQuality, level)
+	}
+	compressor.Level = level
+	return compressor, nil
 }
 
 func (compressor Compressor) FileExtension() string {
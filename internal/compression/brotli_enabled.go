@@ -11,4 +11,7 @@ func init() {
 	Decompressors = append(Decompressors, brotli.Decompressor{})
 	Compressors[brotli.AlgorithmName] = brotli.Compressor{}
 	CompressingAlgorithms = append(CompressingAlgorithms, brotli.AlgorithmName)
+	Leveled[brotli.AlgorithmName] = func(compressor Compressor, level int) (Compressor, error) {
+		return compressor.(brotli.Compressor).WithLevel(level)
+	}
 }
@@ -65,3 +65,88 @@ func TestBigDataCompression(t *testing.T) {
 		testCompressor(compressor, testData, t)
 	}
 }
+
+func TestImplementationsAreCompatible(t *testing.T) {
+	sample := NewBenchmarkSample(1 << 20)
+	for algorithm, implementations := range Implementations {
+		for _, compressing := range implementations {
+			var compressed bytes.Buffer
+			writer := compressing.Compressor.NewWriter(&compressed)
+			_, err := writer.Write(sample)
+			assert.NoError(t, err)
+			assert.NoError(t, writer.Close())
+
+			for _, decompressing := range implementations {
+				reader, err := decompressing.Decompressor.Decompress(bytes.NewReader(compressed.Bytes()))
+				assert.NoError(t, err)
+				decompressed, err := io.ReadAll(reader)
+				assert.NoError(t, err, "%s: %s -> %s", algorithm, compressing.Name, decompressing.Name)
+				assert.Equal(t, sample, decompressed, "%s: %s -> %s", algorithm, compressing.Name, decompressing.Name)
+			}
+		}
+	}
+}
+
+func TestBenchmarkImplementations(t *testing.T) {
+	sample := NewBenchmarkSample(1 << 20)
+	for algorithm, implementations := range Implementations {
+		results, err := BenchmarkImplementations(algorithm, sample)
+		assert.NoError(t, err)
+		assert.Len(t, results, len(implementations))
+		for i := 1; i < len(results); i++ {
+			assert.GreaterOrEqual(t, results[i-1].Throughput, results[i].Throughput)
+		}
+	}
+}
+
+func TestDefaultImplementation(t *testing.T) {
+	defer func(defaults string) { DefaultImplementations = defaults }(DefaultImplementations)
+	DefaultImplementations = "lz4:concurrent, zstd:single"
+
+	assert.Equal(t, "concurrent", DefaultImplementation("lz4"))
+	assert.Equal(t, "single", DefaultImplementation("zstd"))
+	assert.Equal(t, "", DefaultImplementation("lzma"))
+}
+
+func TestHasImplementation(t *testing.T) {
+	assert.True(t, HasImplementation("lz4", "concurrent"))
+	assert.True(t, HasImplementation("lz4", AutoImplementation))
+	assert.False(t, HasImplementation("lz4", "libzstd"))
+	assert.False(t, HasImplementation("lzma", AutoImplementation))
+}
+
+func TestLeveledCompression(t *testing.T) {
+	var testData bytes.Buffer
+	testData.Write(NewBenchmarkSample(1 << 20))
+	for algorithm := range Leveled {
+		compressor, err := WithLevel(algorithm, 9)
+		assert.NoError(t, err)
+		testCompressor(compressor, testData, t)
+
+		_, err = WithLevel(algorithm, 100)
+		assert.Error(t, err, algorithm)
+	}
+
+	_, err := WithLevel("lzma", 9)
+	assert.Error(t, err)
+}
+
+func TestUseImplementation(t *testing.T) {
+	for algorithm, implementations := range Implementations {
+		defaultImplementation := implementations[0]
+		for _, implementation := range implementations {
+			UseImplementation(algorithm, implementation)
+
+			assert.Equal(t, implementation.Compressor, Compressors[algorithm], "%s: %s", algorithm, implementation.Name)
+			found := false
+			for _, decompressor := range Decompressors {
+				if decompressor.FileExtension() == implementation.Decompressor.FileExtension() {
+					assert.Equal(t, implementation.Decompressor, decompressor, "%s: %s", algorithm, implementation.Name)
+					found = true
+				}
+			}
+			assert.True(t, found, "%s: %s", algorithm, implementation.Name)
+		}
+		UseImplementation(algorithm, defaultImplementation)
+	}
+}
@@ -0,0 +1,85 @@
+package computils
+
+import (
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/wal-g/wal-g/utility"
+)
+
+// CompressibilitySampleSize is the amount of data read from the beginning of a file to estimate its entropy
+const CompressibilitySampleSize = 64 * 1024
+
+// CompressibilityChecker detects the files that are not worth compressing:
+// files of already compressed types and files whose sampled data has high entropy.
+type CompressibilityChecker struct {
+	extensions       map[string]bool
+	entropyThreshold float64
+}
+
+// NewCompressibilityChecker builds the checker. Extensions are compared case-insensitively
+// and may be given with or without the leading dot. The entropy threshold is measured in bits per byte
+// (the maximum is 8), the zero threshold disables the sampling.
+func NewCompressibilityChecker(extensions []string, entropyThreshold float64) *CompressibilityChecker {
+	checker := &CompressibilityChecker{
+		extensions:       make(map[string]bool, len(extensions)),
+		entropyThreshold: entropyThreshold,
+	}
+	for _, extension := range extensions {
+		extension = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(extension), "."))
+		if extension != "" {
+			checker.extensions[extension] = true
+		}
+	}
+	return checker
+}
+
+// Enabled reports whether the checker may ever consider a file incompressible
+func (checker *CompressibilityChecker) Enabled() bool {
+	return len(checker.extensions) > 0 || checker.entropyThreshold > 0
+}
+
+// IsIncompressible checks the file name first and samples the file content only if the name tells nothing
+func (checker *CompressibilityChecker) IsIncompressible(path string, openFile func() (io.ReadCloser, error)) (bool, error) {
+	extension := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	if checker.extensions[extension] {
+		return true, nil
+	}
+	if checker.entropyThreshold <= 0 {
+		return false, nil
+	}
+
+	file, err := openFile()
+	if err != nil {
+		return false, err
+	}
+	defer utility.LoggedClose(file, "")
+	sample := make([]byte, CompressibilitySampleSize)
+	n, err := io.ReadFull(file, sample)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return n > 0 && Entropy(sample[:n]) >= checker.entropyThreshold, nil
+}
+
+// Entropy estimates Shannon entropy of the data in bits per byte
+func Entropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+		p := float64(count) / float64(len(data))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
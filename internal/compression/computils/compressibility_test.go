@@ -0,0 +1,70 @@
+package computils_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/compression/computils"
+)
+
+func opener(data []byte) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+}
+
+func TestEntropy(t *testing.T) {
+	assert.Equal(t, 0.0, computils.Entropy(nil))
+	assert.Equal(t, 0.0, computils.Entropy(bytes.Repeat([]byte{42}, 1000)))
+	assert.InDelta(t, 1.0, computils.Entropy(bytes.Repeat([]byte{0, 1}, 1000)), 1e-9)
+
+	random := make([]byte, computils.CompressibilitySampleSize)
+	rand.New(rand.NewSource(1)).Read(random)
+	assert.Greater(t, computils.Entropy(random), 7.9)
+}
+
+func TestCompressibilityChecker_Extensions(t *testing.T) {
+	checker := computils.NewCompressibilityChecker([]string{".gz", "ZST", " "}, 0)
+	assert.True(t, checker.Enabled())
+
+	failingOpener := func() (io.ReadCloser, error) {
+		return nil, errors.New("must not be opened")
+	}
+	incompressible, err := checker.IsIncompressible("log/postgresql.log.gz", failingOpener)
+	require.NoError(t, err)
+	assert.True(t, incompressible)
+
+	incompressible, err = checker.IsIncompressible("dump.zst", failingOpener)
+	require.NoError(t, err)
+	assert.True(t, incompressible)
+
+	incompressible, err = checker.IsIncompressible("base/1/1259", failingOpener)
+	require.NoError(t, err)
+	assert.False(t, incompressible)
+}
+
+func TestCompressibilityChecker_Entropy(t *testing.T) {
+	checker := computils.NewCompressibilityChecker(nil, 7.5)
+	assert.True(t, checker.Enabled())
+
+	random := make([]byte, 2*computils.CompressibilitySampleSize)
+	rand.New(rand.NewSource(1)).Read(random)
+	incompressible, err := checker.IsIncompressible("base/1/16384", opener(random))
+	require.NoError(t, err)
+	assert.True(t, incompressible)
+
+	incompressible, err = checker.IsIncompressible("base/1/1259", opener(bytes.Repeat([]byte("tuple"), 1000)))
+	require.NoError(t, err)
+	assert.False(t, incompressible)
+
+	incompressible, err = checker.IsIncompressible("base/1/empty", opener(nil))
+	require.NoError(t, err)
+	assert.False(t, incompressible)
+
+	assert.False(t, computils.NewCompressibilityChecker(nil, 0).Enabled())
+}
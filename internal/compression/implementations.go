@@ -0,0 +1,167 @@
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+)
+
+const (
+	// AutoImplementation selects the implementation which is the fastest on the host by the benchmark
+	AutoImplementation = "auto"
+
+	benchmarkSampleSeed = 0x5eed
+	benchmarkPageSize   = 8192
+)
+
+// DefaultImplementations overrides the default implementations of the algorithms at build time, e.g.
+// -ldflags "-X github.com/wal-g/wal-g/internal/compression.DefaultImplementations=lz4:concurrent,zstd:single"
+var DefaultImplementations = ""
+
+// Implementation is one of the implementations of the compression algorithm. All of them produce the compatible
+// output, but their throughput differs across the platforms: e.g. the pure Go libraries have fewer assembly
+// optimizations on ARM64, where the concurrent ones win on the many-core hosts and the C library built with
+// the libzstd tag is the fastest.
+type Implementation struct {
+	Name         string
+	Compressor   Compressor
+	Decompressor Decompressor
+}
+
+// Implementations lists the implementations of the algorithms that have several ones, the first one is the default.
+// Each of them replaces both the compressor and the decompressor of the algorithm.
+var Implementations = map[string][]Implementation{
+	lz4.AlgorithmName: {
+		{Name: "single", Compressor: lz4.Compressor{}, Decompressor: lz4.Decompressor{}},
+		{Name: "concurrent", Compressor: lz4.Compressor{Concurrent: true}, Decompressor: lz4.Decompressor{Concurrent: true}},
+	},
+}
+
+// FindImplementation returns the implementation of the algorithm by its name
+func FindImplementation(algorithm, name string) (Implementation, error) {
+	names := make([]string, 0, len(Implementations[algorithm]))
+	for _, implementation := range Implementations[algorithm] {
+		if implementation.Name == name {
+			return implementation, nil
+		}
+		names = append(names, implementation.Name)
+	}
+	return Implementation{}, fmt.Errorf("unknown implementation %q of %s compression, the known ones are %v",
+		name, algorithm, names)
+}
+
+// DefaultImplementation returns the implementation of the algorithm chosen at build time, if any
+func DefaultImplementation(algorithm string) string {
+	return ImplementationFromList(DefaultImplementations, algorithm)
+}
+
+// ImplementationFromList returns the implementation of the algorithm from the list of the "algorithm:name" pairs
+// separated by commas, e.g. "lz4:single,zstd:libzstd". It is empty if the algorithm isn't listed.
+func ImplementationFromList(list, algorithm string) string {
+	for _, pair := range strings.Split(list, ",") {
+		if name, ok := strings.CutPrefix(strings.TrimSpace(pair), algorithm+":"); ok {
+			return strings.TrimSpace(name)
+		}
+	}
+	return ""
+}
+
+// HasImplementation tells whether the algorithm has the implementation, any algorithm having several
+// implementations has the "auto" one
+func HasImplementation(algorithm, name string) bool {
+	if name == AutoImplementation {
+		return len(Implementations[algorithm]) > 0
+	}
+	_, err := FindImplementation(algorithm, name)
+	return err == nil
+}
+
+// UseImplementation makes the compressor and the decompressor of the algorithm use the implementation
+func UseImplementation(algorithm string, implementation Implementation) {
+	Compressors[algorithm] = implementation.Compressor
+	for i, decompressor := range Decompressors {
+		if decompressor.FileExtension() == implementation.Decompressor.FileExtension() {
+			Decompressors[i] = implementation.Decompressor
+		}
+	}
+}
+
+type BenchmarkResult struct {
+	Implementation string
+	// Throughput is the sum of the compression and the decompression throughput in bytes per second
+	Throughput float64
+}
+
+// BenchmarkImplementations measures the throughput of the implementations of the algorithm on the sample,
+// the fastest implementation goes first
+func BenchmarkImplementations(algorithm string, sample []byte) ([]BenchmarkResult, error) {
+	results := make([]BenchmarkResult, 0, len(Implementations[algorithm]))
+	for _, implementation := range Implementations[algorithm] {
+		throughput, err := benchmarkImplementation(implementation, sample)
+		if err != nil {
+			return nil, fmt.Errorf("benchmark %s implementation of %s: %v", implementation.Name, algorithm, err)
+		}
+		results = append(results, BenchmarkResult{Implementation: implementation.Name, Throughput: throughput})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Throughput > results[j].Throughput
+	})
+	return results, nil
+}
+
+func benchmarkImplementation(implementation Implementation, sample []byte) (float64, error) {
+	var compressed bytes.Buffer
+	startTime := time.Now()
+	writer := implementation.Compressor.NewWriter(&compressed)
+	if _, err := writer.Write(sample); err != nil {
+		return 0, err
+	}
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+	compressionTime := time.Since(startTime)
+
+	startTime = time.Now()
+	reader, err := implementation.Decompressor.Decompress(&compressed)
+	if err != nil {
+		return 0, err
+	}
+	decompressedSize, err := io.Copy(io.Discard, reader)
+	if err != nil {
+		return 0, err
+	}
+	if err = reader.Close(); err != nil {
+		return 0, err
+	}
+	decompressionTime := time.Since(startTime)
+	if decompressedSize != int64(len(sample)) {
+		return 0, fmt.Errorf("decompressed %d bytes instead of %d", decompressedSize, len(sample))
+	}
+
+	size := float64(len(sample))
+	return size/compressionTime.Seconds() + size/decompressionTime.Seconds(), nil
+}
+
+// NewBenchmarkSample generates the data resembling the database pages: partially filled with the compressible
+// records and zero-padded
+func NewBenchmarkSample(size int) []byte {
+	random := rand.New(rand.NewSource(benchmarkSampleSeed))
+	sample := make([]byte, size)
+	for pageStart := 0; pageStart < size; pageStart += benchmarkPageSize {
+		pageEnd := pageStart + benchmarkPageSize
+		if pageEnd > size {
+			pageEnd = size
+		}
+		filledEnd := pageStart + random.Intn(pageEnd-pageStart+1)
+		for i := pageStart; i < filledEnd; i++ {
+			sample[i] = byte(random.Intn(16))
+		}
+	}
+	return sample
+}
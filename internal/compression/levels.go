@@ -0,0 +1,25 @@
+package compression
+
+import (
+	"fmt"
+
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+)
+
+// Leveled lists the algorithms whose compression level can be set, each function returns the copy
+// of the compressor compressing with the level
+var Leveled = map[string]func(compressor Compressor, level int) (Compressor, error){
+	lz4.AlgorithmName: func(compressor Compressor, level int) (Compressor, error) {
+		return compressor.(lz4.Compressor).WithLevel(level)
+	},
+}
+
+// WithLevel returns the compressor of the algorithm compressing with the level. The level doesn't change
+// the format, so the decompressor is the same.
+func WithLevel(algorithm string, level int) (Compressor, error) {
+	withLevel, ok := Leveled[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("%s compression has no levels", algorithm)
+	}
+	return withLevel(Compressors[algorithm], level)
+}
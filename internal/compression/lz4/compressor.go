@@ -1,10 +1,12 @@
 package lz4
 
 import (
-	"github.com/wal-g/wal-g/internal/ioextensions"
+	"fmt"
 	"io"
 
 	"github.com/pierrec/lz4/v4"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/ioextensions"
 )
 
 const (
@@ -12,10 +14,41 @@ const (
 	FileExtension = "lz4"
 )
 
-type Compressor struct{}
+var levels = []lz4.CompressionLevel{
+	lz4.Level1, lz4.Level2, lz4.Level3, lz4.Level4, lz4.Level5, lz4.Level6, lz4.Level7, lz4.Level8, lz4.Level9,
+}
+
+// Compressor compresses the blocks in a single goroutine by default, Concurrent makes it compress them
+// in GOMAXPROCS goroutines, which is faster on the many-core hosts without the assembly implementation of lz4
+type Compressor struct {
+	Concurrent bool
+	// Level is the lz4 HC compression level from 1 to 9, the zero level means the fast compression
+	Level int
+}
 
 func (compressor Compressor) NewWriter(writer io.Writer) ioextensions.WriteFlushCloser {
-	return lz4.NewWriter(writer)
+	lz4Writer := lz4.NewWriter(writer)
+	options := make([]lz4.Option, 0, 2)
+	if compressor.Concurrent {
+		// the non-positive concurrency means GOMAXPROCS goroutines
+		options = append(options, lz4.ConcurrencyOption(0))
+	}
+	if compressor.Level != 0 {
+		options = append(options, lz4.CompressionLevelOption(levels[compressor.Level-1]))
+	}
+	// the writer that failed to apply the options is left in the error state, so its writes fail with the error too
+	if err := lz4Writer.Apply(options...); err != nil {
+		tracelog.ErrorLogger.Printf("Failed to configure the lz4 writer: %v", err)
+	}
+	return lz4Writer
+}
+
+func (compressor Compressor) WithLevel(level int) (Compressor, error) {
+	if level < 1 || level > len(levels) {
+		return compressor, fmt.Errorf("lz4 compression level must be from 1 to %d, got %d", len(levels), level)
+	}
+	compressor.Level = level
+	return compressor, nil
 }
 
 func (compressor Compressor) FileExtension() string {
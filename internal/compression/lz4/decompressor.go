@@ -6,10 +6,19 @@ import (
 	"github.com/pierrec/lz4/v4"
 )
 
-type Decompressor struct{}
+// Decompressor decompresses the blocks in a single goroutine by default, see Compressor for Concurrent
+type Decompressor struct {
+	Concurrent bool
+}
 
 func (decompressor Decompressor) Decompress(src io.Reader) (io.ReadCloser, error) {
-	return io.NopCloser(lz4.NewReader(src)), nil
+	lz4Reader := lz4.NewReader(src)
+	if decompressor.Concurrent {
+		if err := lz4Reader.Apply(lz4.ConcurrencyOption(0)); err != nil {
+			return nil, err
+		}
+	}
+	return io.NopCloser(lz4Reader), nil
 }
 
 func (decompressor Decompressor) FileExtension() string {
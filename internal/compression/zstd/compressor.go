@@ -1,6 +1,7 @@
 package zstd
 
 import (
+	"fmt"
 	"github.com/wal-g/wal-g/internal/ioextensions"
 	"io"
 
@@ -10,12 +11,29 @@ import (
 const (
 	AlgorithmName = "zstd"
 	FileExtension = "zst"
+
+	maxLevel = 22
 )
 
-type Compressor struct{}
+// Compressor compresses with the concurrent encoder by default, SingleThreaded makes it use a single goroutine
+// and less memory, which is faster on the hosts with few cores or a slow memory allocator
+type Compressor struct {
+	SingleThreaded bool
+	// Level is the zstd compression level from 1 to 22 mapped to the closest level of the encoder,
+	// the zero level means the default one
+	Level int
+}
 
 func (compressor Compressor) NewWriter(writer io.Writer) ioextensions.WriteFlushCloser {
-	zw, err := zstd.NewWriter(writer, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	level := zstd.SpeedDefault
+	if compressor.Level != 0 {
+		level = zstd.EncoderLevelFromZstd(compressor.Level)
+	}
+	options := []zstd.EOption{zstd.WithEncoderLevel(level)}
+	if compressor.SingleThreaded {
+		options = append(options, zstd.WithEncoderConcurrency(1), zstd.WithLowerEncoderMem(true))
+	}
+	zw, err := zstd.NewWriter(writer, options...)
 	if err != nil {
 		panic(err)
 	}
@@ -23,6 +41,14 @@ func (compressor Compressor) NewWriter(writer io.Writer) ioextensions.WriteFlush
 	return zw
 }
 
+func (compressor Compressor) WithLevel(level int) (Compressor, error) {
+	if level < 1 || level > maxLevel {
+		return compressor, fmt.Errorf("zstd compression level must be from 1 to %d, got %d", maxLevel, level)
+	}
+	compressor.Level = level
+	return compressor, nil
+}
+
 func (compressor Compressor) FileExtension() string {
 	return FileExtension
 }
@@ -7,10 +7,17 @@ import (
 	"github.com/wal-g/wal-g/internal/compression/computils"
 )
 
-type Decompressor struct{}
+// Decompressor decompresses with the concurrent decoder by default, see Compressor for SingleThreaded
+type Decompressor struct {
+	SingleThreaded bool
+}
 
 func (decompressor Decompressor) Decompress(src io.Reader) (io.ReadCloser, error) {
-	zstdReader, err := zstd.NewReader(computils.NewUntilEOFReader(src))
+	var options []zstd.DOption
+	if decompressor.SingleThreaded {
+		options = append(options, zstd.WithDecoderConcurrency(1), zstd.WithDecoderLowmem(true))
+	}
+	zstdReader, err := zstd.NewReader(computils.NewUntilEOFReader(src), options...)
 	if err != nil {
 		return nil, err
 	}
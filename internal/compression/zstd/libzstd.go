@@ -0,0 +1,55 @@
+//go:build libzstd && cgo
+// +build libzstd,cgo
+
+package zstd
+
+import (
+	"fmt"
+	"io"
+
+	libzstd "github.com/DataDog/zstd"
+	"github.com/wal-g/wal-g/internal/compression/computils"
+	"github.com/wal-g/wal-g/internal/ioextensions"
+)
+
+// defaultLibLevel is the default level of the zstd command line tool, the closest one to the default level
+// of the pure Go encoder
+const defaultLibLevel = 3
+
+// LibCompressor compresses with the reference C library, which is built with the SIMD optimizations for the target
+// platform, e.g. NEON on ARM64. It produces the same format as Compressor.
+type LibCompressor struct {
+	// Level is the zstd compression level from 1 to 22, the zero level means the default one
+	Level int
+}
+
+func (compressor LibCompressor) NewWriter(writer io.Writer) ioextensions.WriteFlushCloser {
+	level := defaultLibLevel
+	if compressor.Level != 0 {
+		level = compressor.Level
+	}
+	return libzstd.NewWriterLevel(writer, level)
+}
+
+func (compressor LibCompressor) WithLevel(level int) (LibCompressor, error) {
+	if level < 1 || level > maxLevel {
+		return compressor, fmt.Errorf("zstd compression level must be from 1 to %d, got %d", maxLevel, level)
+	}
+	compressor.Level = level
+	return compressor, nil
+}
+
+func (compressor LibCompressor) FileExtension() string {
+	return FileExtension
+}
+
+// LibDecompressor decompresses with the reference C library, see LibCompressor
+type LibDecompressor struct{}
+
+func (decompressor LibDecompressor) Decompress(src io.Reader) (io.ReadCloser, error) {
+	return libzstd.NewReader(computils.NewUntilEOFReader(src)), nil
+}
+
+func (decompressor LibDecompressor) FileExtension() string {
+	return FileExtension
+}
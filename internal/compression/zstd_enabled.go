@@ -8,4 +8,11 @@ func init() {
 	Decompressors = append(Decompressors, zstd.Decompressor{})
 	Compressors[zstd.AlgorithmName] = zstd.Compressor{}
 	CompressingAlgorithms = append(CompressingAlgorithms, zstd.AlgorithmName)
+	Implementations[zstd.AlgorithmName] = []Implementation{
+		{Name: "concurrent", Compressor: zstd.Compressor{}, Decompressor: zstd.Decompressor{}},
+		{Name: "single", Compressor: zstd.Compressor{SingleThreaded: true}, Decompressor: zstd.Decompressor{SingleThreaded: true}},
+	}
+	Leveled[zstd.AlgorithmName] = func(compressor Compressor, level int) (Compressor, error) {
+		return compressor.(zstd.Compressor).WithLevel(level)
+	}
 }
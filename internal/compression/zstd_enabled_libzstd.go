@@ -0,0 +1,19 @@
+//go:build libzstd && cgo
+// +build libzstd,cgo
+
+package compression
+
+import "github.com/wal-g/wal-g/internal/compression/zstd"
+
+// the file goes after zstd_enabled.go, so the pure Go implementations are registered already
+func init() {
+	Implementations[zstd.AlgorithmName] = append(Implementations[zstd.AlgorithmName],
+		Implementation{Name: "libzstd", Compressor: zstd.LibCompressor{}, Decompressor: zstd.LibDecompressor{}})
+	pureGoLeveled := Leveled[zstd.AlgorithmName]
+	Leveled[zstd.AlgorithmName] = func(compressor Compressor, level int) (Compressor, error) {
+		if libCompressor, ok := compressor.(zstd.LibCompressor); ok {
+			return libCompressor.WithLevel(level)
+		}
+		return pureGoLeveled(compressor, level)
+	}
+}
@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/compression"
+	conf "github.com/wal-g/wal-g/internal/config"
+)
+
+const (
+	compressionBenchmarkFileName   = "compression_benchmark.json"
+	compressionBenchmarkSampleSize = 16 << 20
+)
+
+// ConfigureCompressionImplementation selects the implementations of the configured compression methods:
+// the one set in WALG_COMPRESSION_IMPLEMENTATION, else the one chosen at build time, else the default one.
+// The setting is either the list of the "method:implementation" pairs, e.g. "lz4:single,zstd:libzstd", or a single
+// implementation used by every configured method that has it. The "auto" implementation is the fastest one
+// on the host, measured by the benchmark once and cached in the data folder.
+func ConfigureCompressionImplementation() error {
+	setting := strings.TrimSpace(viper.GetString(conf.CompressionImplSetting))
+	perMethod := strings.Contains(setting, ":")
+	methods := configuredCompressionAlgorithms()
+
+	used := setting == "" || perMethod
+	for _, compressionMethod := range methods {
+		name := ""
+		switch {
+		case perMethod:
+			name = compression.ImplementationFromList(setting, compressionMethod)
+		case setting == "":
+		case compression.HasImplementation(compressionMethod, setting):
+			name = setting
+			used = true
+		default:
+			tracelog.DebugLogger.Printf("%s compression has no %s implementation, the default one is used",
+				compressionMethod, setting)
+		}
+		if err := configureCompressionImplementation(compressionMethod, name); err != nil {
+			return err
+		}
+	}
+	if !used {
+		return fmt.Errorf("none of the configured compression methods %v has the %q implementation set in %s",
+			methods, setting, conf.CompressionImplSetting)
+	}
+	return nil
+}
+
+func configureCompressionImplementation(compressionMethod, name string) error {
+	if len(compression.Implementations[compressionMethod]) == 0 {
+		if name != "" {
+			return fmt.Errorf("%s compression has no implementations to choose from, but %q is set in %s",
+				compressionMethod, name, conf.CompressionImplSetting)
+		}
+		return nil
+	}
+
+	if name == "" {
+		name = compression.DefaultImplementation(compressionMethod)
+	}
+	if name == "" {
+		return nil
+	}
+	if name == compression.AutoImplementation {
+		var err error
+		name, err = getFastestCompressionImplementation(compressionMethod)
+		if err != nil {
+			return err
+		}
+	}
+
+	implementation, err := compression.FindImplementation(compressionMethod, name)
+	if err != nil {
+		return err
+	}
+	tracelog.DebugLogger.Printf("Using %s implementation of %s compression", name, compressionMethod)
+	compression.UseImplementation(compressionMethod, implementation)
+	return nil
+}
+
+func getFastestCompressionImplementation(compressionMethod string) (string, error) {
+	// the choice depends on the platform, so the cache is invalidated when the binary or the host changes
+	key := fmt.Sprintf("%s/%s/%s/%d", compressionMethod, runtime.GOOS, runtime.GOARCH, runtime.NumCPU())
+	cachePath := filepath.Join(GetDataFolderPath(), compressionBenchmarkFileName)
+	cache := make(map[string]string)
+	if content, err := os.ReadFile(cachePath); err == nil {
+		if err = json.Unmarshal(content, &cache); err != nil {
+			tracelog.WarningLogger.Printf("Failed to parse the compression benchmark cache %s: %v", cachePath, err)
+		}
+	}
+	if name, ok := cache[key]; ok {
+		return name, nil
+	}
+
+	tracelog.InfoLogger.Printf("Benchmarking the implementations of %s compression", compressionMethod)
+	results, err := compression.BenchmarkImplementations(compressionMethod,
+		compression.NewBenchmarkSample(compressionBenchmarkSampleSize))
+	if err != nil {
+		return "", err
+	}
+	for _, result := range results {
+		tracelog.InfoLogger.Printf("%s: %.1f MB/s", result.Implementation, result.Throughput/(1<<20))
+	}
+	cache[key] = results[0].Implementation
+
+	content, err := json.Marshal(cache)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(cachePath), 0700)
+	}
+	if err == nil {
+		err = os.WriteFile(cachePath, content, 0600)
+	}
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to cache the compression benchmark results to %s: %v", cachePath, err)
+	}
+	return results[0].Implementation, nil
+}
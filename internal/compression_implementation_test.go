@@ -0,0 +1,42 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	conf "github.com/wal-g/wal-g/internal/config"
+)
+
+func TestConfigureCompressionImplementation(t *testing.T) {
+	defer func() {
+		viper.Set(conf.CompressionMethodSetting, nil)
+		viper.Set(conf.BackupCompressionSetting, nil)
+		viper.Set(conf.CompressionImplSetting, nil)
+		compression.UseImplementation(lz4.AlgorithmName, compression.Implementations[lz4.AlgorithmName][0])
+	}()
+	viper.Set(conf.CompressionMethodSetting, lz4.AlgorithmName)
+	viper.Set(conf.BackupCompressionSetting, "lzma")
+
+	// lzma has no implementations, so the single name is used only for lz4
+	viper.Set(conf.CompressionImplSetting, "concurrent")
+	assert.NoError(t, internal.ConfigureCompressionImplementation())
+	assert.Equal(t, lz4.Compressor{Concurrent: true}, compression.Compressors[lz4.AlgorithmName])
+
+	viper.Set(conf.CompressionImplSetting, "lz4:single")
+	assert.NoError(t, internal.ConfigureCompressionImplementation())
+	assert.Equal(t, lz4.Compressor{}, compression.Compressors[lz4.AlgorithmName])
+
+	// none of the configured methods has it
+	viper.Set(conf.CompressionImplSetting, "libzstd")
+	assert.Error(t, internal.ConfigureCompressionImplementation())
+
+	viper.Set(conf.CompressionImplSetting, "lz4:libzstd")
+	assert.Error(t, internal.ConfigureCompressionImplementation())
+
+	viper.Set(conf.CompressionImplSetting, "lzma:single")
+	assert.Error(t, internal.ConfigureCompressionImplementation())
+}
@@ -35,11 +35,15 @@ const (
 	UploadQueueSetting            = "WALG_UPLOAD_QUEUE"
 	DownloadFileRetriesSetting    = "WALG_DOWNLOAD_FILE_RETRIES"
 	SentinelUserDataSetting       = "WALG_SENTINEL_USER_DATA"
+	SentinelUserDataCmdSetting    = "WALG_SENTINEL_USER_DATA_CMD"
 	PreventWalOverwriteSetting    = "WALG_PREVENT_WAL_OVERWRITE"
 	UploadWalMetadata             = "WALG_UPLOAD_WAL_METADATA"
 	DeltaMaxStepsSetting          = "WALG_DELTA_MAX_STEPS"
 	DeltaOriginSetting            = "WALG_DELTA_ORIGIN"
 	CompressionMethodSetting      = "WALG_COMPRESSION_METHOD"
+	CompressionImplSetting        = "WALG_COMPRESSION_IMPLEMENTATION"
+	WalCompressionSetting         = "WALG_WAL_COMPRESSION_METHOD"
+	BackupCompressionSetting      = "WALG_BACKUP_COMPRESSION_METHOD"
 	StoragePrefixSetting          = "WALG_STORAGE_PREFIX"
 	DiskRateLimitSetting          = "WALG_DISK_RATE_LIMIT"
 	NetworkRateLimitSetting       = "WALG_NETWORK_RATE_LIMIT"
@@ -73,6 +77,51 @@ const (
 	PgpEnvelopeYcSaKeyFileSetting = "WALG_ENVELOPE_PGP_YC_SERVICE_ACCOUNT_KEY_FILE"
 	PgpEnvelopeYcEndpointSetting  = "WALG_ENVELOPE_PGP_YC_ENDPOINT"
 	PgpEnvelopeCacheExpiration    = "WALG_ENVELOPE_CACHE_EXPIRATION"
+	TargetRPOSetting              = "WALG_TARGET_RPO"
+	TargetRTOSetting              = "WALG_TARGET_RTO"
+	ComplianceRestoreRateSetting  = "WALG_COMPLIANCE_RESTORE_RATE"
+	ObjectCachePathSetting        = "WALG_OBJECT_CACHE_PATH"
+	ObjectCacheSizeSetting        = "WALG_OBJECT_CACHE_SIZE"
+	ObjectCachePrefetchSetting    = "WALG_OBJECT_CACHE_PREFETCH"
+	StorageQuotaSetting           = "WALG_STORAGE_QUOTA"
+	StorageQuotaActionSetting     = "WALG_STORAGE_QUOTA_ACTION"
+	StorageQuotaRetainSetting     = "WALG_STORAGE_QUOTA_RETAIN"
+	StorageQuotaUsageAgeSetting   = "WALG_STORAGE_QUOTA_USAGE_MAX_AGE"
+	FetchMirrorPrefixSetting      = "WALG_FETCH_MIRROR_PREFIX"
+	FetchPeerCachesSetting        = "WALG_FETCH_PEER_CACHES"
+	FetchAlternatesAfterSetting   = "WALG_FETCH_ALTERNATE_SOURCES_AFTER"
+	MetadataLockTimeoutSetting    = "WALG_METADATA_LOCK_TIMEOUT"
+	IncompleteBackupTTLSetting    = "WALG_INCOMPLETE_BACKUP_TTL"
+	ArchivingLeaseTTLSetting      = "WALG_ARCHIVING_LEASE_TTL"
+	ArchivingLeaseOwnerSetting    = "WALG_ARCHIVING_LEASE_OWNER"
+	RestoreReportPathSetting      = "WALG_RESTORE_REPORT_PATH"
+	RestoreReportUploadSetting    = "WALG_RESTORE_REPORT_UPLOAD"
+	PriorityClassSetting          = "WALG_PRIORITY_CLASS"
+	PriorityClassesSetting        = "WALG_PRIORITY_CLASSES"
+	ErasureStoragesSetting        = "WALG_ERASURE_STORAGES"
+	ErasureDataShardsSetting      = "WALG_ERASURE_DATA_SHARDS"
+	FileRangeSizeSetting          = "WALG_FILE_RANGE_SIZE"
+	MetadataUploadRetriesSetting  = "WALG_METADATA_UPLOAD_RETRIES"
+	MetadataFallbackEndpoint      = "WALG_METADATA_FALLBACK_ENDPOINT"
+	MetadataSpoolPathSetting      = "WALG_METADATA_SPOOL_PATH"
+	MetadataSpoolTTLSetting       = "WALG_METADATA_SPOOL_TTL"
+	ObfuscationKeySetting         = "WALG_OBFUSCATION_KEY"
+	EventsSQSQueueURLSetting      = "WALG_EVENTS_SQS_QUEUE_URL"
+	EventsPubSubSubscription      = "WALG_EVENTS_PUBSUB_SUBSCRIPTION"
+	EventsGracePeriodSetting      = "WALG_EVENTS_GRACE_PERIOD"
+	EventsAlertCmdSetting         = "WALG_EVENTS_ALERT_COMMAND"
+	ClusterNameSetting            = "WALG_CLUSTER_NAME"
+	GuardrailMaxBackupsSetting    = "WALG_GUARDRAIL_MAX_BACKUPS"
+	TemporaryObjectsTTLSetting    = "WALG_TEMPORARY_OBJECTS_TTL"
+	UploadScanCommandSetting      = "WALG_UPLOAD_SCAN_COMMAND"
+	UploadScanActionSetting       = "WALG_UPLOAD_SCAN_ACTION"
+	MinPITRWindowSetting          = "WALG_MIN_PITR_WINDOW"
+	HedgedRequestsPercentile      = "WALG_HEDGED_REQUESTS_PERCENTILE"
+	HedgedRequestsMinDelay        = "WALG_HEDGED_REQUESTS_MIN_DELAY"
+	HedgedRequestsMaxSize         = "WALG_HEDGED_REQUESTS_MAX_SIZE"
+	InterruptionNoticeSources     = "WALG_INTERRUPTION_NOTICE_SOURCES"
+	InterruptionNoticeCommand     = "WALG_INTERRUPTION_NOTICE_COMMAND"
+	InterruptionNoticeInterval    = "WALG_INTERRUPTION_NOTICE_INTERVAL"
 
 	PgDataSetting                          = "PGDATA"
 	UserSetting                            = "USER" // TODO : do something with it
@@ -112,6 +161,19 @@ const (
 	PgFailoverStoragesCheckSize            = "WALG_FAILOVER_STORAGES_CHECK_SIZE"
 	PgDaemonWALUploadTimeout               = "WALG_DAEMON_WAL_UPLOAD_TIMEOUT"
 	PgTargetStorage                        = "WALG_TARGET_STORAGE"
+	PgPatroniURL                           = "WALG_PATRONI_URL"
+	PgPatroniTimeout                       = "WALG_PATRONI_TIMEOUT"
+	PgPrevalidateWal                       = "WALG_PREVALIDATE_WAL"
+	PgCompressionSkipExtensions            = "WALG_COMPRESSION_SKIP_EXTENSIONS"
+	PgCompressionEntropyThreshold          = "WALG_COMPRESSION_ENTROPY_THRESHOLD"
+	PgWalEnvelopeSetting                   = "WALG_WAL_ENVELOPE"
+	PgIgnoreUnknownFormatFeaturesSetting   = "WALG_IGNORE_UNKNOWN_FORMAT_FEATURES"
+	PgBackupGlobalsSetting                 = "WALG_BACKUP_GLOBALS"
+	PgGlobalsDumpCommand                   = "WALG_GLOBALS_DUMP_COMMAND"
+	PgGlobalsApplyCommand                  = "WALG_GLOBALS_APPLY_COMMAND"
+	PgGlobalsRetain                        = "WALG_GLOBALS_RETAIN"
+	PgExcludeDatabases                     = "WALG_EXCLUDE_DATABASES"
+	PgExcludeTablespaces                   = "WALG_EXCLUDE_TABLESPACES"
 
 	ProfileSamplingRatio = "PROFILE_SAMPLING_RATIO"
 	ProfileMode          = "PROFILE_MODE"
@@ -139,6 +201,10 @@ const (
 	MysqlBinlogDstSetting          = "WALG_MYSQL_BINLOG_DST"
 	MysqlBackupPrepareCmd          = "WALG_MYSQL_BACKUP_PREPARE_COMMAND"
 	MysqlCheckGTIDs                = "WALG_MYSQL_CHECK_GTIDS"
+	MysqlBinlogPurge               = "WALG_MYSQL_BINLOG_PURGE"
+	MysqlBinlogPurgeSafetyLag      = "WALG_MYSQL_BINLOG_PURGE_SAFETY_LAG"
+	MysqlBinlogPurgeInterval       = "WALG_MYSQL_BINLOG_PURGE_INTERVAL"
+	MysqlArchiveRelayLogs          = "WALG_MYSQL_ARCHIVE_RELAY_LOGS"
 	MysqlBinlogServerHost          = "WALG_MYSQL_BINLOG_SERVER_HOST"
 	MysqlBinlogServerPort          = "WALG_MYSQL_BINLOG_SERVER_PORT"
 	MysqlBinlogServerUser          = "WALG_MYSQL_BINLOG_SERVER_USER"
@@ -151,6 +217,9 @@ const (
 	MysqlTakeBinlogsFromMaster = "WALG_MYSQL_TAKE_BINLOGS_FROM_MASTER"
 
 	RedisPassword = "WALG_REDIS_PASSWORD"
+	RedisHost     = "WALG_REDIS_HOST"
+	RedisPort     = "WALG_REDIS_PORT"
+	RedisDB       = "WALG_REDIS_DB"
 
 	GPLogsDirectory            = "WALG_GP_LOGS_DIR"
 	GPSegContentID             = "WALG_GP_SEG_CONTENT_ID"
@@ -168,9 +237,11 @@ const (
 
 	GoMaxProcs = "GOMAXPROCS"
 
-	HTTPListen       = "HTTP_LISTEN"
-	HTTPExposePprof  = "HTTP_EXPOSE_PPROF"
-	HTTPExposeExpVar = "HTTP_EXPOSE_EXPVAR"
+	HTTPListen          = "HTTP_LISTEN"
+	HTTPExposePprof     = "HTTP_EXPOSE_PPROF"
+	HTTPExposeExpVar    = "HTTP_EXPOSE_EXPVAR"
+	HTTPExposeHealth    = "HTTP_EXPOSE_HEALTH"
+	HealthMaxLagSetting = "WALG_HEALTH_MAX_LAG"
 
 	SQLServerBlobHostname     = "SQLSERVER_BLOB_HOSTNAME"
 	SQLServerBlobCertFile     = "SQLSERVER_BLOB_CERT_FILE"
@@ -180,6 +251,8 @@ const (
 	SQLServerDBConcurrency    = "SQLSERVER_DB_CONCURRENCY"
 	SQLServerReuseProxy       = "SQLSERVER_REUSE_PROXY"
 
+	SQLServerHonorAGBackupPreference = "SQLSERVER_HONOR_AG_BACKUP_PREFERENCE"
+
 	EndpointSourceSetting = "S3_ENDPOINT_SOURCE"
 	EndpointPortSetting   = "S3_ENDPOINT_PORT"
 
@@ -187,6 +260,9 @@ const (
 	AwsSecretAccessKey = "AWS_SECRET_ACCESS_KEY"
 	AwsSessionToken    = "AWS_SESSION_TOKEN"
 
+	S3UploadSecretAccessKey   = "WALG_S3_UPLOAD_SECRET_ACCESS_KEY"
+	S3DownloadSecretAccessKey = "WALG_S3_DOWNLOAD_SECRET_ACCESS_KEY"
+
 	YcKmsKeyIDSetting  = "YC_CSE_KMS_KEY_ID"
 	YcSaKeyFileSetting = "YC_SERVICE_ACCOUNT_KEY_FILE"
 
@@ -245,6 +321,23 @@ var (
 		PgFailoverStoragesCheckTimeout: "30s",
 		PgFailoverStorageCacheLifetime: "15m",
 		PgpEnvelopeCacheExpiration:     "0",
+		ComplianceRestoreRateSetting:   "100mb",
+		ObjectCacheSizeSetting:         "1gb",
+		ObjectCachePrefetchSetting:     "2",
+		StorageQuotaActionSetting:      "fail",
+		StorageQuotaUsageAgeSetting:    "1h",
+		FetchAlternatesAfterSetting:    "2",
+		MetadataLockTimeoutSetting:     "1m",
+		IncompleteBackupTTLSetting:     "10m",
+		HealthMaxLagSetting:            "5m",
+		MetadataUploadRetriesSetting:   "8",
+		MetadataSpoolTTLSetting:        "72h",
+		EventsGracePeriodSetting:       "1m",
+		TemporaryObjectsTTLSetting:     "24h",
+		UploadScanActionSetting:        "fail",
+		HedgedRequestsMinDelay:         "50ms",
+		HedgedRequestsMaxSize:          "16mb",
+		InterruptionNoticeInterval:     "5s",
 	}
 
 	MongoDefaultSettings = map[string]string{
@@ -262,6 +355,9 @@ var (
 		StreamSplitterBlockSize:     "1048576",
 		MysqlBackupDownloadMaxRetry: "1",
 		MysqlIncrementalBackupDst:   "/tmp",
+		MysqlBinlogPurge:            "false",
+		MysqlBinlogPurgeSafetyLag:   "1h",
+		MysqlBinlogPurgeInterval:    "5m",
 	}
 
 	SQLServerDefaultSettings = map[string]string{
@@ -274,6 +370,11 @@ var (
 		PgAliveCheckInterval:        "1m",
 		PgFailoverStoragesCheckSize: "1mb",
 		PgDaemonWALUploadTimeout:    "60s",
+		PgPatroniTimeout:            "5s",
+		PgBackupGlobalsSetting:      "false",
+		PgGlobalsDumpCommand:        "pg_dumpall --globals-only",
+		PgGlobalsApplyCommand:       "psql -X -q",
+		PgGlobalsRetain:             "0",
 	}
 
 	GPDefaultSettings = map[string]string{
@@ -287,6 +388,10 @@ var (
 		GPAoSegSizeThreshold:       "1048576", // (1 << 20)
 		GPAoDeduplicationAgeLimit:  "720h",    // 30 days
 		GPRelativeRecoveryConfPath: "recovery.conf",
+		PgBackupGlobalsSetting:     "false",
+		PgGlobalsDumpCommand:       "pg_dumpall --globals-only",
+		PgGlobalsApplyCommand:      "psql -X -q",
+		PgGlobalsRetain:            "0",
 	}
 
 	AllowedSettings map[string]bool
@@ -299,11 +404,15 @@ var (
 		UploadQueueSetting:            true,
 		DownloadFileRetriesSetting:    true,
 		SentinelUserDataSetting:       true,
+		SentinelUserDataCmdSetting:    true,
 		PreventWalOverwriteSetting:    true,
 		UploadWalMetadata:             true,
 		DeltaMaxStepsSetting:          true,
 		DeltaOriginSetting:            true,
 		CompressionMethodSetting:      true,
+		CompressionImplSetting:        true,
+		WalCompressionSetting:         true,
+		BackupCompressionSetting:      true,
 		StoragePrefixSetting:          true,
 		DiskRateLimitSetting:          true,
 		NetworkRateLimitSetting:       true,
@@ -343,6 +452,51 @@ var (
 		SerializerTypeSetting:         true,
 		StatsdAddressSetting:          true,
 		StatsdExtraTagsSetting:        true,
+		TargetRPOSetting:              true,
+		TargetRTOSetting:              true,
+		ComplianceRestoreRateSetting:  true,
+		ObjectCachePathSetting:        true,
+		ObjectCacheSizeSetting:        true,
+		ObjectCachePrefetchSetting:    true,
+		StorageQuotaSetting:           true,
+		StorageQuotaActionSetting:     true,
+		StorageQuotaRetainSetting:     true,
+		StorageQuotaUsageAgeSetting:   true,
+		FetchMirrorPrefixSetting:      true,
+		FetchPeerCachesSetting:        true,
+		FetchAlternatesAfterSetting:   true,
+		MetadataLockTimeoutSetting:    true,
+		IncompleteBackupTTLSetting:    true,
+		ArchivingLeaseTTLSetting:      true,
+		ArchivingLeaseOwnerSetting:    true,
+		RestoreReportPathSetting:      true,
+		RestoreReportUploadSetting:    true,
+		PriorityClassSetting:          true,
+		PriorityClassesSetting:        true,
+		ErasureStoragesSetting:        true,
+		ErasureDataShardsSetting:      true,
+		FileRangeSizeSetting:          true,
+		MetadataUploadRetriesSetting:  true,
+		MetadataFallbackEndpoint:      true,
+		MetadataSpoolPathSetting:      true,
+		MetadataSpoolTTLSetting:       true,
+		ObfuscationKeySetting:         true,
+		EventsSQSQueueURLSetting:      true,
+		EventsPubSubSubscription:      true,
+		EventsGracePeriodSetting:      true,
+		EventsAlertCmdSetting:         true,
+		ClusterNameSetting:            true,
+		GuardrailMaxBackupsSetting:    true,
+		MinPITRWindowSetting:          true,
+		TemporaryObjectsTTLSetting:    true,
+		UploadScanCommandSetting:      true,
+		UploadScanActionSetting:       true,
+		HedgedRequestsPercentile:      true,
+		HedgedRequestsMinDelay:        true,
+		HedgedRequestsMaxSize:         true,
+		InterruptionNoticeSources:     true,
+		InterruptionNoticeCommand:     true,
+		InterruptionNoticeInterval:    true,
 
 		ProfileSamplingRatio: true,
 		ProfileMode:          true,
@@ -383,12 +537,20 @@ var (
 		"WALG_S3_MAX_PART_SIZE":       true,
 		"WALG_S3_ENDPOINT_SOURCE":     true,
 		"WALG_S3_ENDPOINT_PORT":       true,
+		"WALG_S3_UPLOAD_ENDPOINT":     true,
+		"WALG_S3_DOWNLOAD_ENDPOINT":   true,
 		"WALG_S3_USE_LIST_OBJECTS_V1": true,
 		"WALG_S3_LOG_LEVEL":           true,
 		"WALG_S3_RANGE_BATCH_ENABLED": true,
 		"WALG_S3_RANGE_MAX_RETRIES":   true,
+		"WALG_S3_CONDITIONAL_WRITES":  true,
 		"WALG_S3_MAX_RETRIES":         true,
 
+		"WALG_S3_UPLOAD_ACCESS_KEY_ID":   true,
+		S3UploadSecretAccessKey:          true,
+		"WALG_S3_DOWNLOAD_ACCESS_KEY_ID": true,
+		S3DownloadSecretAccessKey:        true,
+
 		// Azure
 		"WALG_AZ_PREFIX":         true,
 		AzureStorageAccount:      true,
@@ -420,9 +582,11 @@ var (
 		GoMaxProcs: true,
 
 		// Web server
-		HTTPListen:       true,
-		HTTPExposePprof:  true,
-		HTTPExposeExpVar: true,
+		HTTPListen:          true,
+		HTTPExposePprof:     true,
+		HTTPExposeExpVar:    true,
+		HTTPExposeHealth:    true,
+		HealthMaxLagSetting: true,
 	}
 
 	PGAllowedSettings = map[string]bool{
@@ -453,6 +617,19 @@ var (
 		PgFailoverStorageCacheEMAAlphaDeadMin:  true,
 		PgFailoverStoragesCheckSize:            true,
 		PgDaemonWALUploadTimeout:               true,
+		PgPatroniURL:                           true,
+		PgPatroniTimeout:                       true,
+		PgPrevalidateWal:                       true,
+		PgCompressionSkipExtensions:            true,
+		PgCompressionEntropyThreshold:          true,
+		PgWalEnvelopeSetting:                   true,
+		PgIgnoreUnknownFormatFeaturesSetting:   true,
+		PgExcludeDatabases:                     true,
+		PgExcludeTablespaces:                   true,
+		PgBackupGlobalsSetting:                 true,
+		PgGlobalsDumpCommand:                   true,
+		PgGlobalsApplyCommand:                  true,
+		PgGlobalsRetain:                        true,
 	}
 
 	MongoAllowedSettings = map[string]bool{
@@ -481,6 +658,8 @@ var (
 		SQLServerConnectionString: true,
 		SQLServerDBConcurrency:    true,
 		SQLServerReuseProxy:       true,
+
+		SQLServerHonorAGBackupPreference: true,
 	}
 
 	MysqlAllowedSettings = map[string]bool{
@@ -492,6 +671,10 @@ var (
 		MysqlBackupPrepareCmd:          true,
 		MysqlTakeBinlogsFromMaster:     true,
 		MysqlCheckGTIDs:                true,
+		MysqlBinlogPurge:               true,
+		MysqlBinlogPurgeSafetyLag:      true,
+		MysqlBinlogPurgeInterval:       true,
+		MysqlArchiveRelayLogs:          true,
 		StreamSplitterPartitions:       true,
 		StreamSplitterBlockSize:        true,
 		StreamSplitterMaxFileSize:      true,
@@ -508,6 +691,9 @@ var (
 	RedisAllowedSettings = map[string]bool{
 		// Redis
 		RedisPassword: true,
+		RedisHost:     true,
+		RedisPort:     true,
+		RedisDB:       true,
 	}
 
 	GPAllowedSettings = map[string]bool{
@@ -528,6 +714,7 @@ var (
 		HTTPExposePprof:          webserver.EnablePprofEndpoints,
 		HTTPExposeExpVar:         webserver.EnableExpVarEndpoints,
 		OplogPushStatsExposeHTTP: nil,
+		HTTPExposeHealth:         nil,
 	}
 	Turbo bool
 
@@ -537,6 +724,8 @@ var (
 		AwsAccessKeyID:               true,
 		AwsSecretAccessKey:           true,
 		AwsSessionToken:              true,
+		S3UploadSecretAccessKey:      true,
+		S3DownloadSecretAccessKey:    true,
 		AzureStorageAccessKey:        true,
 		AzureStorageSasToken:         true,
 		GoogleApplicationCredentials: true,
@@ -554,6 +743,17 @@ var (
 	complexSettings = map[string]bool{
 		PgFailoverStorages:     true,
 		StatsdExtraTagsSetting: true,
+		PriorityClassesSetting: true,
+		ErasureStoragesSetting: true,
+	}
+
+	// priorityClassSettings are the settings that the priority classes can override
+	priorityClassSettings = map[string]bool{
+		DiskRateLimitSetting:         true,
+		NetworkRateLimitSetting:      true,
+		DownloadConcurrencySetting:   true,
+		UploadConcurrencySetting:     true,
+		UploadDiskConcurrencySetting: true,
 	}
 )
 
@@ -731,10 +931,35 @@ func InitConfig() {
 	SetGoMaxProcs(globalViper)
 	ReadConfigFromFile(globalViper, CfgFile)
 	CheckAllowedSettings(globalViper)
+	err := ApplyPriorityClass(globalViper)
+	tracelog.ErrorLogger.FatalOnError(err)
 
 	bindConfigToEnv(globalViper)
 }
 
+// ApplyPriorityClass overrides the rate limits and the concurrency with the ones of the priority class
+// chosen by WALG_PRIORITY_CLASS. The classes are defined in WALG_PRIORITY_CLASSES of the shared config,
+// so the commands running on the same host get different shares of the bandwidth.
+func ApplyPriorityClass(config *viper.Viper) error {
+	className := config.GetString(PriorityClassSetting)
+	if className == "" {
+		return nil
+	}
+	classConfig := config.Sub(PriorityClassesSetting + "." + className)
+	if classConfig == nil {
+		return errors.Errorf("priority class '%s' is not defined in %s", className, PriorityClassesSetting)
+	}
+	for setting, value := range classConfig.AllSettings() {
+		setting = strings.ToUpper(setting)
+		if !priorityClassSettings[setting] {
+			return errors.Errorf("%s can not be set by priority class '%s'", setting, className)
+		}
+		config.Set(setting, value)
+	}
+	tracelog.DebugLogger.Printf("Using priority class '%s'", className)
+	return nil
+}
+
 // ReadConfigFromFile read config to the viper instance
 func ReadConfigFromFile(config *viper.Viper, configFile string) {
 	if configFile != "" {
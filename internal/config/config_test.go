@@ -53,6 +53,37 @@ func TestConfigureLogging_WhenLogLevelSettingIsSet(t *testing.T) {
 	resetToDefaults()
 }
 
+func TestApplyPriorityClass(t *testing.T) {
+	v := viper.New()
+	v.Set(config.NetworkRateLimitSetting, 100)
+	v.Set(config.PriorityClassesSetting, map[string]interface{}{
+		"background":  map[string]interface{}{config.NetworkRateLimitSetting: 10, config.DownloadConcurrencySetting: 2},
+		"interactive": map[string]interface{}{config.NetworkRateLimitSetting: 0},
+	})
+
+	v.Set(config.PriorityClassSetting, "background")
+	assert.NoError(t, config.ApplyPriorityClass(v))
+	assert.Equal(t, 10, v.GetInt(config.NetworkRateLimitSetting))
+	assert.Equal(t, 2, v.GetInt(config.DownloadConcurrencySetting))
+
+	v.Set(config.PriorityClassSetting, "interactive")
+	assert.NoError(t, config.ApplyPriorityClass(v))
+	assert.Equal(t, 0, v.GetInt(config.NetworkRateLimitSetting))
+}
+
+func TestApplyPriorityClass_Errors(t *testing.T) {
+	v := viper.New()
+	v.Set(config.PriorityClassesSetting, map[string]interface{}{
+		"background": map[string]interface{}{config.CompressionMethodSetting: "lz4"},
+	})
+
+	v.Set(config.PriorityClassSetting, "unknown")
+	assert.Error(t, config.ApplyPriorityClass(v))
+
+	v.Set(config.PriorityClassSetting, "background")
+	assert.Error(t, config.ApplyPriorityClass(v))
+}
+
 func resetToDefaults() {
 	viper.Reset()
 	internal.ConfigureSettings(config.PG)
@@ -8,7 +8,9 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 
 	conf "github.com/wal-g/wal-g/internal/config"
 	"github.com/wal-g/wal-g/internal/crypto/yckms"
@@ -26,6 +28,8 @@ import (
 	"github.com/wal-g/wal-g/internal/crypto/openpgp"
 	"github.com/wal-g/wal-g/internal/fsutil"
 	"github.com/wal-g/wal-g/internal/limiters"
+	"github.com/wal-g/wal-g/internal/objcache"
+	"github.com/wal-g/wal-g/pkg/storages/erasure"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 	"golang.org/x/time/rate"
 )
@@ -35,6 +39,7 @@ const (
 	DefaultDataBurstRateLimit = 8 * pgDefaultDatabasePageSize
 	DefaultDataFolderPath     = "/tmp"
 	WaleFileHost              = "file://localhost"
+	compressionLevelSeparator = "-"
 )
 
 var DeprecatedExternalGpgMessage = fmt.Sprintf(
@@ -88,13 +93,14 @@ func ConfigureLimiters() {
 	if conf.Turbo {
 		return
 	}
-	if viper.IsSet(conf.DiskRateLimitSetting) {
+	// zero limit lets a priority class lift the limit set for the other classes
+	if viper.IsSet(conf.DiskRateLimitSetting) && viper.GetInt64(conf.DiskRateLimitSetting) > 0 {
 		diskLimit := viper.GetInt64(conf.DiskRateLimitSetting)
 		limiters.DiskLimiter = rate.NewLimiter(rate.Limit(diskLimit),
 			int(diskLimit+DefaultDataBurstRateLimit)) // Add 8 pages to possible bursts
 	}
 
-	if viper.IsSet(conf.NetworkRateLimitSetting) {
+	if viper.IsSet(conf.NetworkRateLimitSetting) && viper.GetInt64(conf.NetworkRateLimitSetting) > 0 {
 		netLimit := viper.GetInt64(conf.NetworkRateLimitSetting)
 		limiters.NetworkLimiter = rate.NewLimiter(rate.Limit(netLimit),
 			int(netLimit+DefaultDataBurstRateLimit)) // Add 8 pages to possible bursts
@@ -103,20 +109,72 @@ func ConfigureLimiters() {
 
 // TODO : unit tests
 func ConfigureStorage() (storage.HashableStorage, error) {
-	var rootWraps []storage.WrapRootFolder
+	rootWraps := configureRootWraps()
+	st, err := ConfigureStorageForSpecificConfig(viper.GetViper(), rootWraps...)
+	if err != nil {
+		return nil, err
+	}
+
+	return configureErasureStorage(st, rootWraps)
+}
+
+// configureRootWraps provides the wrappers of the storage root folder, the first one wraps it first
+func configureRootWraps() []storage.WrapRootFolder {
+	rootWraps := []storage.WrapRootFolder{NewPermissionAwareFolder}
 	if limiters.NetworkLimiter != nil {
 		rootWraps = append(rootWraps, func(prevFolder storage.Folder) (newFolder storage.Folder) {
 			return NewLimitedFolder(prevFolder, limiters.NetworkLimiter)
 		})
 	}
-	rootWraps = append(rootWraps, ConfigureStoragePrefix)
+	return append(rootWraps, ConfigureNameObfuscation, ConfigureStoragePrefix)
+}
 
-	st, err := ConfigureStorageForSpecificConfig(viper.GetViper(), rootWraps...)
-	if err != nil {
-		return nil, err
+// configureErasureStorage spreads the objects across the primary storage and WALG_ERASURE_STORAGES
+// if they are configured
+func configureErasureStorage(primary storage.HashableStorage, rootWraps []storage.WrapRootFolder) (storage.HashableStorage, error) {
+	storageConfigs := viper.GetStringMap(conf.ErasureStoragesSetting)
+	if len(storageConfigs) == 0 {
+		return primary, nil
+	}
+	names := make([]string, 0, len(storageConfigs))
+	for name := range storageConfigs {
+		names = append(names, name)
 	}
+	// the shards are bound to the storages by their order
+	sort.Strings(names)
 
-	return st, nil
+	storages := []storage.HashableStorage{primary}
+	for _, name := range names {
+		cfg := viper.Sub(conf.ErasureStoragesSetting + "." + name)
+		st, err := ConfigureStorageForSpecificConfig(cfg, rootWraps...)
+		if err != nil {
+			for _, configured := range storages {
+				utility.LoggedClose(configured, "Failed to close storage")
+			}
+			return nil, fmt.Errorf("erasure coding storage %s: %v", name, err)
+		}
+		storages = append(storages, st)
+	}
+
+	dataShards := len(storages) - 1
+	if viper.IsSet(conf.ErasureDataShardsSetting) {
+		dataShards = viper.GetInt(conf.ErasureDataShardsSetting)
+	}
+	tracelog.DebugLogger.Printf("Erasure coding objects into %d data shards of %d", dataShards, len(storages))
+	return erasure.NewStorage(dataShards, storages)
+}
+
+// ConfigureObjectCache puts the local object cache in front of the folder if WALG_OBJECT_CACHE_PATH is set.
+func ConfigureObjectCache(folder storage.Folder) (storage.Folder, error) {
+	cachePath, ok := conf.GetSetting(conf.ObjectCachePathSetting)
+	if !ok || cachePath == "" {
+		return folder, nil
+	}
+	cache, err := objcache.NewDiskCache(cachePath, int64(viper.GetSizeInBytes(conf.ObjectCacheSizeSetting)))
+	if err != nil {
+		return nil, fmt.Errorf("configure object cache: %w", err)
+	}
+	return objcache.NewFolder(folder, cache), nil
 }
 
 func ConfigureStoragePrefix(folder storage.Folder) storage.Folder {
@@ -144,7 +202,8 @@ func ConfigureStorageForSpecificConfig(
 		}
 
 		settings := adapter.loadSettings(config)
-		st, err := adapter.configure(prefix, settings, rootWraps...)
+		wraps := append([]storage.WrapRootFolder{hedgedRequestsWrap(adapter.storageType)}, rootWraps...)
+		st, err := adapter.configure(prefix, settings, wraps...)
 		if err != nil {
 			return nil, fmt.Errorf("configure storage with prefix %q: %w", prefix, err)
 		}
@@ -183,12 +242,60 @@ func GetPgSlotName() (pgSlotName string) {
 	return
 }
 
+// ConfigureCompressor returns the compressor of WALG_COMPRESSION_METHOD
 func ConfigureCompressor() (compression.Compressor, error) {
-	compressionMethod := viper.GetString(conf.CompressionMethodSetting)
-	if _, ok := compression.Compressors[compressionMethod]; !ok {
-		return nil, newUnknownCompressionMethodError(compressionMethod)
+	return newCompressor(viper.GetString(conf.CompressionMethodSetting))
+}
+
+// ConfigureWalStreamCompressor returns the compressor of the WAL, binlogs and oplogs:
+// WALG_WAL_COMPRESSION_METHOD if set, else WALG_COMPRESSION_METHOD
+func ConfigureWalStreamCompressor() (compression.Compressor, error) {
+	return newCompressor(getCompressionMethod(conf.WalCompressionSetting))
+}
+
+// ConfigureBackupCompressor returns the compressor of the backups:
+// WALG_BACKUP_COMPRESSION_METHOD if set, else WALG_COMPRESSION_METHOD
+func ConfigureBackupCompressor() (compression.Compressor, error) {
+	return newCompressor(getCompressionMethod(conf.BackupCompressionSetting))
+}
+
+func getCompressionMethod(setting string) string {
+	if method := viper.GetString(setting); method != "" {
+		return method
 	}
-	return compression.Compressors[compressionMethod], nil
+	return viper.GetString(conf.CompressionMethodSetting)
+}
+
+// newCompressor returns the compressor of the method, which is the algorithm optionally followed
+// by the compression level, e.g. "lz4" or "zstd-19"
+func newCompressor(method string) (compression.Compressor, error) {
+	algorithm, levelString, hasLevel := strings.Cut(method, compressionLevelSeparator)
+	compressor, ok := compression.Compressors[algorithm]
+	if !ok {
+		return nil, newUnknownCompressionMethodError(method)
+	}
+	if !hasLevel {
+		return compressor, nil
+	}
+	level, err := strconv.Atoi(levelString)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid compression level in '%s'", method)
+	}
+	return compression.WithLevel(algorithm, level)
+}
+
+// configuredCompressionAlgorithms returns the distinct algorithms of the configured compression methods
+func configuredCompressionAlgorithms() []string {
+	var algorithms []string
+	seen := make(map[string]bool)
+	for _, setting := range []string{conf.CompressionMethodSetting, conf.WalCompressionSetting, conf.BackupCompressionSetting} {
+		algorithm, _, _ := strings.Cut(getCompressionMethod(setting), compressionLevelSeparator)
+		if !seen[algorithm] {
+			seen[algorithm] = true
+			algorithms = append(algorithms, algorithm)
+		}
+	}
+	return algorithms
 }
 
 func getPGArchiveStatusFolderPath() string {
@@ -214,25 +321,54 @@ func ConfigurePGArchiveStatusManager() (fsutil.DataFolder, error) {
 
 // ConfigureUploader is like ConfigureUploaderToFolder, but configures the default storage.
 func ConfigureUploader() (*RegularUploader, error) {
+	return configureUploader(ConfigureCompressor)
+}
+
+// ConfigureUploaderToFolder connects to storage with the specified folder and creates an uploader.
+// It makes sure that a valid session has started; if invalid, returns AWS error and `<nil>` value.
+func ConfigureUploaderToFolder(folder storage.Folder) (uploader *RegularUploader, err error) {
+	return configureUploaderToFolder(folder, ConfigureCompressor)
+}
+
+// ConfigureWalStreamUploader is like ConfigureUploader, but compresses with ConfigureWalStreamCompressor
+func ConfigureWalStreamUploader() (*RegularUploader, error) {
+	return configureUploader(ConfigureWalStreamCompressor)
+}
+
+// ConfigureWalStreamUploaderToFolder is like ConfigureUploaderToFolder, but compresses with ConfigureWalStreamCompressor
+func ConfigureWalStreamUploaderToFolder(folder storage.Folder) (*RegularUploader, error) {
+	return configureUploaderToFolder(folder, ConfigureWalStreamCompressor)
+}
+
+// ConfigureBackupUploader is like ConfigureUploader, but compresses with ConfigureBackupCompressor
+func ConfigureBackupUploader() (*RegularUploader, error) {
+	return configureUploader(ConfigureBackupCompressor)
+}
+
+// ConfigureBackupUploaderToFolder is like ConfigureUploaderToFolder, but compresses with ConfigureBackupCompressor
+func ConfigureBackupUploaderToFolder(folder storage.Folder) (*RegularUploader, error) {
+	return configureUploaderToFolder(folder, ConfigureBackupCompressor)
+}
+
+func configureUploader(configureCompressor func() (compression.Compressor, error)) (*RegularUploader, error) {
 	st, err := ConfigureStorage()
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to configure storage")
 	}
 
-	uploader, err := ConfigureUploaderToFolder(st.RootFolder())
+	uploader, err := configureUploaderToFolder(st.RootFolder(), configureCompressor)
 	return uploader, err
 }
 
-// ConfigureUploaderToFolder connects to storage with the specified folder and creates an uploader.
-// It makes sure that a valid session has started; if invalid, returns AWS error and `<nil>` value.
-func ConfigureUploaderToFolder(folder storage.Folder) (uploader *RegularUploader, err error) {
-	compressor, err := ConfigureCompressor()
+func configureUploaderToFolder(folder storage.Folder,
+	configureCompressor func() (compression.Compressor, error)) (*RegularUploader, error) {
+	compressor, err := configureCompressor()
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to configure compression")
 	}
 
-	uploader = NewRegularUploader(compressor, folder)
-	return uploader, err
+	uploader := NewRegularUploader(compressor, folder)
+	return uploader, configureUploadScanning(uploader)
 }
 
 func ConfigureUploaderWithoutCompressor() (Uploader, error) {
@@ -242,11 +378,12 @@ func ConfigureUploaderWithoutCompressor() (Uploader, error) {
 	}
 
 	uploader := NewRegularUploader(nil, st.RootFolder())
-	return uploader, err
+	return uploader, configureUploadScanning(uploader)
 }
 
+// ConfigureSplitUploader configures the uploader of the backups splitting the stream into the partitions
 func ConfigureSplitUploader() (Uploader, error) {
-	uploader, err := ConfigureUploader()
+	uploader, err := ConfigureBackupUploader()
 	if err != nil {
 		return nil, err
 	}
@@ -382,11 +519,8 @@ func GetDeltaConfig() (maxDeltas int, fromFull bool) {
 }
 
 func GetSentinelUserData() (interface{}, error) {
-	dataStr, ok := conf.GetSetting(conf.SentinelUserDataSetting)
-	if !ok {
-		return nil, nil
-	}
-	return UnmarshalSentinelUserData(dataStr)
+	dataStr, _ := conf.GetSetting(conf.SentinelUserDataSetting)
+	return PrepareSentinelUserData(context.Background(), dataStr)
 }
 
 func UnmarshalSentinelUserData(userDataStr string) (interface{}, error) {
@@ -536,15 +670,7 @@ func ConfigureFailoverStorages() (failovers map[string]storage.HashableStorage,
 
 		cfg := viper.Sub(conf.PgFailoverStorages + "." + name)
 
-		var rootWraps []storage.WrapRootFolder
-		if limiters.NetworkLimiter != nil {
-			rootWraps = append(rootWraps, func(prevFolder storage.Folder) (newFolder storage.Folder) {
-				return NewLimitedFolder(prevFolder, limiters.NetworkLimiter)
-			})
-		}
-		rootWraps = append(rootWraps, ConfigureStoragePrefix)
-
-		st, err := ConfigureStorageForSpecificConfig(cfg, rootWraps...)
+		st, err := ConfigureStorageForSpecificConfig(cfg, configureRootWraps()...)
 		if err != nil {
 			return nil, fmt.Errorf("failover storage %s: %v", name, err)
 		}
@@ -148,6 +148,35 @@ func TestConfigureCompressor_FailsOnInvalidCompressorString(t *testing.T) {
 	resetToDefaults()
 }
 
+func TestConfigureCompressor_WithLevel(t *testing.T) {
+	viper.Set(config.CompressionMethodSetting, "lz4-9")
+	compressor, err := internal.ConfigureCompressor()
+	assert.NoError(t, err)
+	assert.Equal(t, compressor, lz4.Compressor{Level: 9})
+
+	viper.Set(config.CompressionMethodSetting, "lz4-fast")
+	_, err = internal.ConfigureCompressor()
+	assert.Error(t, err)
+
+	viper.Set(config.CompressionMethodSetting, "lzma-9")
+	_, err = internal.ConfigureCompressor()
+	assert.Error(t, err)
+	resetToDefaults()
+}
+
+func TestConfigureWalStreamAndBackupCompressors(t *testing.T) {
+	viper.Set(config.CompressionMethodSetting, "lzma")
+	viper.Set(config.WalCompressionSetting, "lz4")
+	compressor, err := internal.ConfigureWalStreamCompressor()
+	assert.NoError(t, err)
+	assert.Equal(t, compressor, lz4.Compressor{})
+
+	compressor, err = internal.ConfigureBackupCompressor()
+	assert.NoError(t, err)
+	assert.Equal(t, compressor, lzma.Compressor{}, "the backups fall back to %s", config.CompressionMethodSetting)
+	resetToDefaults()
+}
+
 func prepareDataFolder(t *testing.T, name string) string {
 	cwd, err := filepath.Abs("./")
 	if err != nil {
@@ -8,3 +8,8 @@ type Crypter interface {
 	Encrypt(writer io.Writer) (io.WriteCloser, error)
 	Decrypt(reader io.Reader) (io.Reader, error)
 }
+
+// Fingerprinter is implemented by crypters that can identify their key without revealing it
+type Fingerprinter interface {
+	Fingerprint() (string, error)
+}
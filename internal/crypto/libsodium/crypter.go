@@ -6,6 +6,8 @@ package libsodium
 import "C"
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
@@ -91,6 +93,15 @@ func (crypter *Crypter) setup() (err error) {
 	return nil
 }
 
+// Fingerprint returns a hash of the key, so it can be told apart from other keys without being revealed
+func (crypter *Crypter) Fingerprint() (string, error) {
+	if err := crypter.setup(); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(crypter.key)
+	return hex.EncodeToString(sum[:16]), nil
+}
+
 // Encrypt creates encryption writer from ordinary writer
 func (crypter *Crypter) Encrypt(writer io.Writer) (io.WriteCloser, error) {
 	if err := crypter.setup(); err != nil {
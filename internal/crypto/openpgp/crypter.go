@@ -3,6 +3,7 @@ package openpgp
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"io"
 	"strings"
 	"sync"
@@ -107,6 +108,18 @@ func (crypter *Crypter) setupPubKey() error {
 	return nil
 }
 
+// Fingerprint returns the fingerprint of the primary public key
+func (crypter *Crypter) Fingerprint() (string, error) {
+	err := crypter.setupPubKey()
+	if err != nil {
+		return "", err
+	}
+	if len(crypter.PubKey) == 0 {
+		return "", errors.New("opengpg key ring is empty")
+	}
+	return fmt.Sprintf("%X", crypter.PubKey[0].PrimaryKey.Fingerprint), nil
+}
+
 // Encrypt creates encryption writer from ordinary writer
 func (crypter *Crypter) Encrypt(writer io.Writer) (io.WriteCloser, error) {
 	err := crypter.setupPubKey()
@@ -13,6 +13,8 @@ import (
 // TODO: add more metadata
 type streamSentinelDto struct {
 	StartLocalTime time.Time
+	// WalgVersion is the version of WAL-G which made the backup, empty for the backups made before it was recorded
+	WalgVersion string `json:"WalgVersion,omitempty"`
 }
 
 func HandleBackupPush(uploader internal.Uploader, backupCmd *exec.Cmd) {
@@ -30,7 +32,7 @@ func HandleBackupPush(uploader internal.Uploader, backupCmd *exec.Cmd) {
 		tracelog.ErrorLogger.Fatalf("backup create command failed: %v", err)
 	}
 
-	sentinel := streamSentinelDto{StartLocalTime: timeStart}
+	sentinel := streamSentinelDto{StartLocalTime: timeStart, WalgVersion: internal.WalgVersion}
 
 	err = internal.UploadSentinel(uploader, &sentinel, fileName)
 	tracelog.ErrorLogger.FatalOnError(err)
@@ -159,8 +159,12 @@ func (u *AoStorageUploader) regularAoUpload(
 	// TODO: lookup the compression details for each relation and compress it when compression is turned off
 	var compressor compression.Compressor
 
-	uploadContents := internal.CompressAndEncrypt(fileReadCloser, compressor, u.crypter)
 	uploadPath := path.Join(AoStoragePath, storageKey)
+	uploadContents, err := internal.CompressAndEncryptScanned(context.Background(), u.uploader, uploadPath,
+		fileReadCloser, compressor, u.crypter)
+	if err != nil {
+		return err
+	}
 	err = u.uploader.Upload(context.Background(), uploadPath, uploadContents)
 	if err != nil {
 		return err
@@ -203,7 +207,11 @@ func (u *AoStorageUploader) upload(reader io.Reader, storageKey string) error {
 	// TODO: lookup the compression details for each relation and compress it when compression is turned off
 	var compressor compression.Compressor
 
-	uploadContents := internal.CompressAndEncrypt(reader, compressor, u.crypter)
 	uploadPath := path.Join(AoStoragePath, storageKey)
+	uploadContents, err := internal.CompressAndEncryptScanned(context.Background(), u.uploader, uploadPath,
+		reader, compressor, u.crypter)
+	if err != nil {
+		return err
+	}
 	return u.uploader.Upload(context.Background(), uploadPath, uploadContents)
 }
@@ -1,6 +1,7 @@
 package greenplum
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -15,6 +16,7 @@ import (
 	"github.com/greenplum-db/gp-common-go-libs/cluster"
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
 	"github.com/jackc/pgx"
+	"github.com/spf13/viper"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	conf "github.com/wal-g/wal-g/internal/config"
@@ -220,6 +222,12 @@ func (bh *BackupHandler) HandleBackupPush() {
 
 	bh.currBackupInfo.finishTime = utility.TimeNowCrossPlatformUTC()
 
+	if viper.GetBool(conf.PgBackupGlobalsSetting) {
+		tracelog.InfoLogger.Println("Uploading the global objects")
+		err = postgres.UploadGlobals(context.Background(), bh.workers.Uploader.Clone(), bh.currBackupInfo.backupName)
+		tracelog.ErrorLogger.FatalfOnError("Failed to upload the global objects: %v", err)
+	}
+
 	sentinelDto := NewBackupSentinelDto(&bh.currBackupInfo, &bh.prevBackupInfo,
 		restoreLSNs, bh.arguments.userData, bh.arguments.isPermanent)
 	err = bh.uploadSentinel(sentinelDto)
@@ -432,7 +440,9 @@ func (bh *BackupHandler) uploadSentinel(sentinelDto BackupSentinelDto) (err erro
 
 	sentinelUploader := bh.workers.Uploader
 	sentinelUploader.ChangeDirectory(utility.BaseBackupPath)
-	return internal.UploadSentinel(sentinelUploader, sentinelDto, bh.currBackupInfo.backupName)
+	// the backup data is stored in the segment folders, so the spooled sentinel is limited only by its TTL
+	sentinelName := internal.SentinelNameFromBackup(bh.currBackupInfo.backupName)
+	return internal.UploadDtoDurablyForBackup(sentinelUploader.Folder(), sentinelDto, sentinelName, "")
 }
 
 // nolint:unused
@@ -482,7 +492,7 @@ func getGpClusterInfo(conn *pgx.Conn) (globalCluster *cluster.Cluster, version s
 
 // NewBackupHandler returns a backup handler object, which can handle the backup
 func NewBackupHandler(arguments BackupArguments) (bh *BackupHandler, err error) {
-	uploader, err := internal.ConfigureUploader()
+	uploader, err := internal.ConfigureBackupUploader()
 	if err != nil {
 		return nil, err
 	}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/databases/postgres"
 
 	"github.com/greenplum-db/gp-common-go-libs/cluster"
@@ -85,6 +86,9 @@ type BackupSentinelDto struct {
 	IncrementFrom     *string `json:"increment_from,omitempty"`
 	IncrementFullName *string `json:"increment_full_name,omitempty"`
 	IncrementCount    *int    `json:"increment_count,omitempty"`
+
+	// WalgVersion is the version of WAL-G which made the backup, empty for the backups made before it was recorded
+	WalgVersion string `json:"walg_version,omitempty"`
 }
 
 func (s *BackupSentinelDto) String() string {
@@ -114,6 +118,7 @@ func NewBackupSentinelDto(currBackupInfo *CurrBackupInfo, prevBackupInfo *PrevBa
 		GpVersion:        currBackupInfo.gpVersion.String(),
 		IsPermanent:      isPermanent,
 		SystemIdentifier: currBackupInfo.systemIdentifier,
+		WalgVersion:      internal.WalgVersion,
 	}
 
 	if prevBackupInfo.name != "" {
@@ -2,8 +2,8 @@ package greenplum
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
 
@@ -71,47 +71,47 @@ func (h *DeleteHandler) HandleDeleteBefore(args []string) {
 	modifier, beforeStr := internal.ExtractDeleteModifierFromArgs(args)
 
 	target, err := h.FindTargetBefore(beforeStr, modifier)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
 	if target == nil {
 		tracelog.InfoLogger.Printf("No backup found for deletion")
-		os.Exit(0)
+		internal.ExitReleasingLocks(0)
 	}
 
 	err = h.DeleteBeforeTarget(target)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
 }
 
 func (h *DeleteHandler) HandleDeleteRetain(args []string) {
 	modifier, retentionStr := internal.ExtractDeleteModifierFromArgs(args)
 	retentionCount, err := strconv.Atoi(retentionStr)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
 
 	target, err := h.FindTargetRetain(retentionCount, modifier)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
 	if target == nil {
 		tracelog.InfoLogger.Printf("No backup found for deletion")
-		os.Exit(0)
+		internal.ExitReleasingLocks(0)
 	}
 
 	err = h.DeleteBeforeTarget(target)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
 }
 
 func (h *DeleteHandler) HandleDeleteRetainAfter(args []string) {
 	modifier, retentionSir, afterStr := internal.ExtractDeleteRetainAfterModifierFromArgs(args)
 	retentionCount, err := strconv.Atoi(retentionSir)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
 
 	target, err := h.FindTargetRetainAfter(retentionCount, afterStr, modifier)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
 
 	if target == nil {
 		tracelog.InfoLogger.Printf("No backup found for deletion")
-		os.Exit(0)
+		internal.ExitReleasingLocks(0)
 	}
 
 	err = h.DeleteBeforeTarget(target)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
 }
 
 func (h *DeleteHandler) HandleDeleteEverything(args []string) {
@@ -119,6 +119,12 @@ func (h *DeleteHandler) HandleDeleteEverything(args []string) {
 }
 
 func (h *DeleteHandler) DeleteBeforeTarget(target internal.BackupObject) error {
+	if h.args.Confirmed {
+		err := h.CheckWideDeletionGuardrail(target)
+		if err != nil {
+			return err
+		}
+	}
 	tracelog.InfoLogger.Println("Deleting the segments backups...")
 	err := h.dispatchDeleteCmd(target, SegDeleteBefore)
 	if err != nil {
@@ -133,24 +139,24 @@ func (h *DeleteHandler) DeleteBeforeTarget(target internal.BackupObject) error {
 
 func (h *DeleteHandler) HandleDeleteTarget(targetSelector internal.BackupSelector) {
 	target, err := h.FindTargetBySelector(targetSelector)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
 
 	if target == nil {
 		// since we want to delete the target backup, we should fail if
 		// we didn't find the requested backup for deletion
-		tracelog.ErrorLogger.Fatal("Requested backup was not found")
+		internal.FatalOnErrorReleasingLocks(errors.New("requested backup was not found"))
 	}
 
 	tracelog.InfoLogger.Println("Deleting the segments backups...")
 	err = h.dispatchDeleteCmd(target, SegDeleteTarget)
 	if err != nil {
-		tracelog.ErrorLogger.Fatalf("Failed to delete the segments backups: %v", err)
+		internal.FatalOnErrorReleasingLocks(fmt.Errorf("failed to delete the segments backups: %w", err))
 	}
 	tracelog.InfoLogger.Printf("Finished deleting the segments backups")
 
 	folderFilter := func(name string) bool { return true }
 	err = h.DeleteHandler.DeleteTarget(target, h.args.Confirmed, h.args.FindFull, folderFilter)
-	tracelog.ErrorLogger.FatalOnError(err)
+	internal.FatalOnErrorReleasingLocks(err)
 }
 
 func (h *DeleteHandler) dispatchDeleteCmd(target internal.BackupObject, delType SegDeleteType) error {
@@ -200,6 +206,12 @@ func (h *DeleteHandler) dispatchDeleteCmd(target internal.BackupObject, delType
 
 // HandleDeleteGarbage delete outdated WAL archives and leftover backup files
 func (h *DeleteHandler) HandleDeleteGarbage(args []string) error {
+	if h.args.Confirmed {
+		err := h.CheckGuardrail("delete garbage")
+		if err != nil {
+			return err
+		}
+	}
 	predicate := postgres.ExtractDeleteGarbagePredicate(args)
 	backupSelector := internal.NewOldestNonPermanentSelector(NewGenericMetaFetcher())
 	oldestBackup, err := backupSelector.Select(h.Folder)
@@ -39,6 +39,7 @@ func (t FilesToExtractProviderImpl) Get(backup SegBackup, filesToUnwrap map[stri
 			}
 			objPath := path.Join(AoStoragePath, meta.StoragePath)
 			readerMaker := internal.NewRegularFileStorageReaderMarker(backup.Folder, objPath, extractPath, meta.FileMode)
+			readerMaker.RestoreReport = backup.RestoreReport
 			tarsToExtract = append(tarsToExtract, readerMaker)
 		}
 	}
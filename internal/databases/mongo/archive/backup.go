@@ -33,6 +33,7 @@ func (m *MongoMetaConstructor) MetaInfo() interface{} {
 		MongoMeta:       meta.Mongo,
 		CompressedSize:  meta.CompressedSize,
 		Permanent:       meta.Permanent,
+		WalgVersion:     internal.WalgVersion,
 	}
 	return backupSentinel
 }
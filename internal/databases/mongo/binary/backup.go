@@ -103,6 +103,7 @@ func (backupService *BackupService) InitializeMongodBackupMeta(backupName string
 	backupService.Sentinel.StartLocalTime = utility.TimeNowCrossPlatformLocal()
 	backupService.Sentinel.Permanent = permanent
 	backupService.Sentinel.UserData = userData
+	backupService.Sentinel.WalgVersion = internal.WalgVersion
 
 	return nil
 }
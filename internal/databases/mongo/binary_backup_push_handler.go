@@ -22,7 +22,7 @@ func HandleBinaryBackupPush(ctx context.Context, permanent bool, appName string)
 		return err
 	}
 
-	uploader, err := internal.ConfigureUploader()
+	uploader, err := internal.ConfigureBackupUploader()
 	if err != nil {
 		return err
 	}
@@ -1,6 +1,7 @@
 package mongo
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/wal-g/tracelog"
@@ -15,6 +16,8 @@ type PurgeSettings struct {
 	purgeOplog   bool
 	purgeGarbage bool
 	dryRun       bool
+	// guardrailConfirmation is the cluster name passed with --yes-i-know
+	guardrailConfirmation string
 }
 
 type PurgeOption func(*PurgeSettings)
@@ -54,7 +57,16 @@ func PurgeDryRun(dryRun bool) PurgeOption {
 	}
 }
 
-// HandlePurge delete backups and oplog archives according to settings
+// PurgeGuardrailConfirmation ...
+func PurgeGuardrailConfirmation(yesIKnow string) PurgeOption {
+	return func(args *PurgeSettings) {
+		args.guardrailConfirmation = yesIKnow
+	}
+}
+
+// HandlePurge delete backups and oplog archives according to settings. The oplog archives to purge are selected
+// along with the backups, before anything is deleted, so the archives needed to roll forward from the oldest
+// retained backup are kept.
 func HandlePurge(downloader archive.Downloader, purger archive.Purger, setters ...PurgeOption) error {
 	opts := PurgeSettings{purgeOplog: false, dryRun: true}
 	for _, setter := range setters {
@@ -66,14 +78,31 @@ func HandlePurge(downloader archive.Downloader, purger archive.Purger, setters .
 		return err
 	}
 
-	_, _, err = HandleBackupsPurge(backupTimes, downloader, purger, opts)
+	purge, retain, err := selectPurgingBackups(backupTimes, downloader, opts)
 	if err != nil {
 		return err
 	}
 
+	var purgeArchives []models.Archive
+	if opts.purgeOplog {
+		purgeArchives, err = selectPurgingOplogArchives(downloader, retain)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !opts.dryRun {
+		if err := checkPurgeGuardrail(purge, opts); err != nil {
+			return err
+		}
+	}
+
+	if err := deleteBackups(purger, purge, retain, opts.dryRun); err != nil {
+		return err
+	}
+
 	if opts.purgeOplog {
-		// TODO: fix error if retainBackups is empty
-		if err := HandleOplogPurge(downloader, purger, opts.retainAfter, opts.dryRun); err != nil {
+		if err := deleteOplogArchives(purger, purgeArchives, opts.dryRun); err != nil {
 			return err
 		}
 	}
@@ -95,6 +124,19 @@ func HandleBackupsPurge(backupTimes []internal.BackupTime,
 	downloader archive.Downloader,
 	purger archive.Purger,
 	opts PurgeSettings) (purge, retain []*models.Backup, err error) {
+	purge, retain, err = selectPurgingBackups(backupTimes, downloader, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := deleteBackups(purger, purge, retain, opts.dryRun); err != nil {
+		return nil, nil, err
+	}
+	return purge, retain, nil
+}
+
+func selectPurgingBackups(backupTimes []internal.BackupTime,
+	downloader archive.Downloader,
+	opts PurgeSettings) (purge, retain []*models.Backup, err error) {
 	if len(backupTimes) == 0 { // TODO: refactor && support oplog purge even if backups do not exist
 		tracelog.InfoLogger.Println("No backups found")
 		return nil, nil, nil
@@ -117,12 +159,75 @@ func HandleBackupsPurge(backupTimes []internal.BackupTime,
 	purge, retain = archive.SplitMongoBackups(backups, purgeBackups, retainBackups)
 	tracelog.InfoLogger.Printf("Backups selected to be deleted: %v", archive.BackupNamesFromBackups(purge))
 	tracelog.InfoLogger.Printf("Backups selected to be retained: %v", archive.BackupNamesFromBackups(retain))
+	return purge, retain, nil
+}
 
-	if !opts.dryRun {
-		if err := purger.DeleteBackups(purge); err != nil {
-			return nil, nil, err
+// checkPurgeGuardrail applies the guardrail to the confirmed purge before anything is deleted. Like delete garbage
+// of the other databases, the garbage deletion always requires naming the cluster, the backups deletion requires it
+// only if it is wide.
+func checkPurgeGuardrail(purge []*models.Backup, opts PurgeSettings) error {
+	if opts.purgeGarbage {
+		return internal.CheckGuardrail("delete --purge-garbage", opts.guardrailConfirmation)
+	}
+	return internal.CheckWideDeletionGuardrail("delete", len(purge), opts.guardrailConfirmation)
+}
+
+func deleteBackups(purger archive.Purger, purge, retain []*models.Backup, dryRun bool) error {
+	if dryRun {
+		return nil
+	}
+	if err := purger.DeleteBackups(purge); err != nil {
+		return err
+	}
+	tracelog.InfoLogger.Printf("Backups were purged: deleted: %d, retained: %v", len(purge), len(retain))
+	return nil
+}
+
+// selectPurgingOplogArchives selects the oplog archives that are neither needed to roll forward from
+// the oldest retained backup nor overlap any retained backup. Like the binlogs of MySQL, the oplog
+// isn't kept for the permanent backups beyond their own time interval.
+func selectPurgingOplogArchives(downloader archive.Downloader, retain []*models.Backup) ([]models.Archive, error) {
+	if len(retain) == 0 {
+		return nil, fmt.Errorf("no backups are retained, can not select the oplog archives to keep")
+	}
+	archives, err := downloader.ListOplogArchives()
+	if err != nil {
+		return nil, fmt.Errorf("can not load oplog archives: %+v", err)
+	}
+
+	var retainArchivesAfterTS *models.Timestamp
+	if rollForwardBackup := oldestRollForwardBackup(retain); rollForwardBackup != nil {
+		retainArchivesAfterTS = &rollForwardBackup.MongoMeta.Before.LastMajTS
+		tracelog.DebugLogger.Printf("Oplog archives newer than %+v are needed to roll forward from %s\n",
+			*retainArchivesAfterTS, rollForwardBackup.Name())
+	}
+
+	purgeArchives := archive.SelectPurgingOplogArchives(archives, retain, retainArchivesAfterTS)
+	tracelog.InfoLogger.Printf("Oplog archives selected to be deleted: %d", len(purgeArchives))
+	return purgeArchives, nil
+}
+
+// oldestRollForwardBackup is the oldest backup which is not permanent, nil if all the backups are permanent
+func oldestRollForwardBackup(backups []*models.Backup) *models.Backup {
+	var oldest *models.Backup
+	for _, backup := range backups {
+		if backup.IsPermanent() {
+			continue
+		}
+		if oldest == nil || models.LessTS(backup.MongoMeta.Before.LastMajTS, oldest.MongoMeta.Before.LastMajTS) {
+			oldest = backup
 		}
-		tracelog.InfoLogger.Printf("Backups were purged: deleted: %d, retained: %v", len(purge), len(retain))
 	}
-	return purge, retain, nil
+	return oldest
+}
+
+func deleteOplogArchives(purger archive.Purger, purgeArchives []models.Archive, dryRun bool) error {
+	if dryRun {
+		return nil
+	}
+	if err := purger.DeleteOplogArchives(purgeArchives); err != nil {
+		return fmt.Errorf("can not purge oplog archives: %+v", err)
+	}
+	tracelog.InfoLogger.Printf("Oplog archives were purged: %d", len(purgeArchives))
+	return nil
 }
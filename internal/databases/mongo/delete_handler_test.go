@@ -0,0 +1,88 @@
+package mongo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	mocks "github.com/wal-g/wal-g/internal/databases/mongo/archive/mocks"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+)
+
+func TestHandlePurge_KeepsOplogToRollForward(t *testing.T) {
+	backup := func(name string, start, finish int64, permanent bool) *models.Backup {
+		return &models.Backup{
+			BackupName: name, StartLocalTime: time.Unix(start, 0), FinishLocalTime: time.Unix(finish, 0), Permanent: permanent,
+			MongoMeta: models.MongoMeta{
+				Before: models.NodeMeta{LastMajTS: models.Timestamp{TS: uint32(start)}},
+				After:  models.NodeMeta{LastMajTS: models.Timestamp{TS: uint32(finish)}},
+			},
+		}
+	}
+	backups := []*models.Backup{
+		backup("b3", 800, 900, false),
+		backup("b2", 600, 700, false),
+		backup("b1", 300, 400, false),
+		backup("b0", 100, 150, true),
+	}
+	archives := []models.Archive{
+		{Start: models.Timestamp{TS: 50}, End: models.Timestamp{TS: 120}},
+		{Start: models.Timestamp{TS: 120}, End: models.Timestamp{TS: 200}},
+		{Start: models.Timestamp{TS: 200}, End: models.Timestamp{TS: 500}},
+		{Start: models.Timestamp{TS: 500}, End: models.Timestamp{TS: 550}},
+		{Start: models.Timestamp{TS: 550}, End: models.Timestamp{TS: 910}},
+		{Start: models.Timestamp{TS: 910}, End: models.Timestamp{TS: 950}},
+	}
+
+	dl := &mocks.Downloader{}
+	dl.On("ListBackups").Return(make([]internal.BackupTime, 4), []string{}, nil).Once().
+		On("LoadBackups", mock.Anything).Return(backups, nil).Once().
+		On("ListOplogArchives").Return(archives, nil).Once()
+	pr := &mocks.Purger{}
+	pr.On("DeleteBackups", mock.MatchedBy(func(purge []*models.Backup) bool {
+		return len(purge) == 1 && purge[0].BackupName == "b1"
+	})).Return(nil).Once()
+	// the oplog since b2 is kept to roll forward from it, the permanent b0 keeps only the oplog it overlaps
+	pr.On("DeleteOplogArchives", mock.MatchedBy(func(purge []models.Archive) bool {
+		return reflect.DeepEqual(purge, archives[2:4])
+	})).Return(nil).Once()
+
+	err := HandlePurge(dl, pr, PurgeRetainCount(2), PurgeOplog(true), PurgeDryRun(false))
+	assert.NoError(t, err)
+	dl.AssertExpectations(t)
+	pr.AssertExpectations(t)
+}
+
+func TestHandlePurge_ChecksGuardrail(t *testing.T) {
+	defer viper.Set(conf.ClusterNameSetting, nil)
+	defer viper.Set(conf.GuardrailMaxBackupsSetting, nil)
+	viper.Set(conf.ClusterNameSetting, "billing-prod")
+	viper.Set(conf.GuardrailMaxBackupsSetting, 1)
+	backups := []*models.Backup{
+		{BackupName: "b2", FinishLocalTime: time.Unix(300, 0)},
+		{BackupName: "b1", FinishLocalTime: time.Unix(200, 0)},
+		{BackupName: "b0", FinishLocalTime: time.Unix(100, 0)},
+	}
+
+	dl := &mocks.Downloader{}
+	dl.On("ListBackups").Return(make([]internal.BackupTime, 3), []string{}, nil).
+		On("LoadBackups", mock.Anything).Return(backups, nil)
+	pr := &mocks.Purger{}
+
+	// the purge removing two backups isn't confirmed
+	err := HandlePurge(dl, pr, PurgeRetainCount(1), PurgeDryRun(false))
+	assert.IsType(t, internal.GuardrailError{}, err)
+	pr.AssertNotCalled(t, "DeleteBackups", mock.Anything)
+
+	pr.On("DeleteBackups", mock.MatchedBy(func(purge []*models.Backup) bool {
+		return len(purge) == 2
+	})).Return(nil).Once()
+	err = HandlePurge(dl, pr, PurgeRetainCount(1), PurgeDryRun(false), PurgeGuardrailConfirmation("billing-prod"))
+	assert.NoError(t, err)
+	pr.AssertExpectations(t)
+}
@@ -24,6 +24,8 @@ type Backup struct {
 	Permanent        bool        `json:"Permanent"`
 	UncompressedSize int64       `json:"UncompressedSize,omitempty"`
 	CompressedSize   int64       `json:"DataSize,omitempty"`
+	// WalgVersion is the version of WAL-G which made the backup, empty for the backups made before it was recorded
+	WalgVersion string `json:"WalgVersion,omitempty"`
 }
 
 func (b *Backup) Name() string {
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -53,6 +54,14 @@ func TimestampFromStr(s string) (Timestamp, error) {
 	return Timestamp{TS: uint32(ts), Inc: uint32(inc)}, nil
 }
 
+// ParseTimestamp builds Timestamp from "ts.inc" string or from time in RFC3339
+func ParseTimestamp(s string) (Timestamp, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return Timestamp{TS: uint32(t.Unix())}, nil
+	}
+	return TimestampFromStr(s)
+}
+
 // MaxTS returns maximum of two timestamps.
 func MaxTS(ots1, ots2 Timestamp) Timestamp {
 	if LessTS(ots1, ots2) {
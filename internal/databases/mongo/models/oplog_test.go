@@ -292,3 +292,45 @@ func TestTimestampFromStr(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    Timestamp
+		wantErr bool
+	}{
+		{
+			name: "TS, INC",
+			s:    "1579541242.142",
+			want: Timestamp{1579541242, 142},
+		},
+		{
+			name: "RFC3339",
+			s:    "2020-01-20T17:27:22Z",
+			want: Timestamp{1579541242, 0},
+		},
+		{
+			name: "RFC3339 with offset",
+			s:    "2020-01-20T20:27:22+03:00",
+			want: Timestamp{1579541242, 0},
+		},
+		{
+			name:    "String",
+			s:       "string",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTimestamp(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseTimestamp() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTimestamp() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
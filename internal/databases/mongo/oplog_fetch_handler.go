@@ -0,0 +1,57 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// keepOpenWriter prevents the downloader from closing the file before the archive is written
+type keepOpenWriter struct {
+	io.Writer
+}
+
+func (w keepOpenWriter) Close() error {
+	return nil
+}
+
+// HandleOplogFetchToDir downloads the oplog archives of the sequence into dstDir, so they could be
+// processed by external tools. The archives are decompressed and keep their original names without extension.
+func HandleOplogFetchToDir(ctx context.Context, downloader archive.Downloader, sequence archive.Sequence, dstDir string) error {
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return fmt.Errorf("can not create directory '%s': %w", dstDir, err)
+	}
+
+	for _, arch := range sequence {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		dstPath := filepath.Join(dstDir, strings.TrimSuffix(arch.Filename(), "."+arch.Extension()))
+		tracelog.InfoLogger.Printf("Fetching oplog archive %s to %s", arch.Filename(), dstPath)
+		if err := fetchOplogArchive(downloader, arch, dstPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fetchOplogArchive(downloader archive.Downloader, arch models.Archive, dstPath string) error {
+	file, err := os.Create(dstPath)
+	if err != nil {
+		return fmt.Errorf("can not create file '%s': %w", dstPath, err)
+	}
+	defer utility.LoggedClose(file, "")
+
+	if err := downloader.DownloadOplogArchive(arch, keepOpenWriter{file}); err != nil {
+		return fmt.Errorf("can not fetch oplog archive '%s': %w", arch.Filename(), err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package mongo
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/databases/mongo/archive"
+	mocks "github.com/wal-g/wal-g/internal/databases/mongo/archive/mocks"
+	"github.com/wal-g/wal-g/internal/databases/mongo/models"
+)
+
+func TestHandleOplogFetchToDir(t *testing.T) {
+	sequence := archive.Sequence{
+		{Start: models.Timestamp{TS: 100}, End: models.Timestamp{TS: 200}, Ext: "br", Type: models.ArchiveTypeOplog},
+		{Start: models.Timestamp{TS: 200}, End: models.Timestamp{TS: 500}, Ext: "br", Type: models.ArchiveTypeOplog},
+	}
+	downloader := &mocks.Downloader{}
+	downloader.On("DownloadOplogArchive", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			// the storage downloader closes the writer before writing to it
+			writeCloser := args.Get(1).(io.WriteCloser)
+			require.NoError(t, writeCloser.Close())
+			_, err := writeCloser.Write([]byte(args.Get(0).(models.Archive).Start.String()))
+			require.NoError(t, err)
+		}).
+		Return(nil)
+
+	dstDir := filepath.Join(t.TempDir(), "oplog")
+	err := HandleOplogFetchToDir(context.Background(), downloader, sequence, dstDir)
+	require.NoError(t, err)
+
+	for _, arch := range sequence {
+		content, err := os.ReadFile(filepath.Join(dstDir, "oplog_"+arch.Start.String()+"_"+arch.End.String()))
+		require.NoError(t, err)
+		assert.Equal(t, arch.Start.String(), string(content))
+	}
+}
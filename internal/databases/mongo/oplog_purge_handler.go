@@ -38,17 +38,23 @@ func HandleOplogPurge(downloader archive.Downloader, purger archive.Purger, reta
 		return fmt.Errorf("can not find any existed backups")
 	}
 
-	pitrBackup, err := archive.OldestBackupAfterTime(backups, *retainAfter) // TODO: make new setting - PITR point
-	if err != nil {
-		return err
+	// without the PITR interval, the oplog is kept to roll forward from the oldest backup
+	pitrBackup := oldestRollForwardBackup(backups)
+	if retainAfter != nil {
+		pitrBackup, err = archive.OldestBackupAfterTime(backups, *retainAfter) // TODO: make new setting - PITR point
+		if err != nil {
+			return err
+		}
+	}
+	var retainArchivesAfterTS *models.Timestamp
+	if pitrBackup != nil {
+		retainArchivesAfterTS = &pitrBackup.MongoMeta.Before.LastMajTS
+		tracelog.DebugLogger.Printf("Oldest backup in PITR interval is %+v\n", pitrBackup)
+		tracelog.DebugLogger.Printf("Oplog archives newer than %+v will be retained\n", *retainArchivesAfterTS)
 	}
-	retainArchivesAfterTS := pitrBackup.MongoMeta.Before.LastMajTS
-
-	tracelog.DebugLogger.Printf("Oldest backup in PITR interval is %+v\n", pitrBackup)
-	tracelog.DebugLogger.Printf("Oplog archives newer than %+v will be retained\n", retainArchivesAfterTS)
 	tracelog.DebugLogger.Printf("Oplog archives included into backups time interval will be retained: %v\n", backups)
 
-	purgeArchives := archive.SelectPurgingOplogArchives(archives, backups, &retainArchivesAfterTS)
+	purgeArchives := archive.SelectPurgingOplogArchives(archives, backups, retainArchivesAfterTS)
 	tracelog.DebugLogger.Printf("Oplog archives selected to be deleted: %v", purgeArchives)
 	if !dryRun {
 		if err := purger.DeleteOplogArchives(purgeArchives); err != nil {
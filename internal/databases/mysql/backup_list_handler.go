@@ -113,7 +113,7 @@ func NewBackupDetail(backupTime internal.BackupTime, sentinel StreamSentinelDto)
 }
 
 // TODO: unit tests
-func HandleDetailedBackupList(folder storage.Folder, pretty, json bool) {
+func HandleDetailedBackupList(folder storage.Folder, filter internal.UserDataFilter, pretty, json bool) {
 	backupTimes, err := internal.GetBackups(folder)
 	tracelog.ErrorLogger.FatalfOnError("Failed to fetch list of backups in storage: %s", err)
 
@@ -126,6 +126,9 @@ func HandleDetailedBackupList(folder storage.Folder, pretty, json bool) {
 		err = backup.FetchSentinel(&sentinel)
 		tracelog.ErrorLogger.FatalfOnError("Failed to load sentinel for backup %s", err)
 
+		if !filter.Match(sentinel.UserData) {
+			continue
+		}
 		backupDetails = append(backupDetails, NewBackupDetail(backupTime, sentinel))
 	}
 
@@ -7,8 +7,10 @@ import (
 	"os/exec"
 	"strings"
 
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
 	"github.com/wal-g/wal-g/internal/limiters"
 	"github.com/wal-g/wal-g/utility"
 )
@@ -47,6 +49,13 @@ func HandleBackupPush(
 
 	binlogStart, err := getLastUploadedBinlogBeforeGTID(folder, gtidStart, flavor)
 	tracelog.ErrorLogger.FatalfOnError("failed to get last uploaded binlog: %v", err)
+
+	var relayLogStart string
+	if archiveRelayLogs, _ := conf.GetBoolSettingDefault(conf.MysqlArchiveRelayLogs, false); archiveRelayLogs &&
+		flavor == gomysql.MySQLFlavor {
+		relayLogStart, err = getLastUploadedRelayLogBeforeGTID(folder, gtidStart)
+		tracelog.ErrorLogger.FatalfOnError("failed to get last uploaded relay log: %v", err)
+	}
 	timeStart := utility.TimeNowCrossPlatformLocal()
 
 	var backupName string
@@ -77,8 +86,8 @@ func HandleBackupPush(
 		tracelog.ErrorLogger.Printf("Failed to calc raw data size: %v", err)
 	}
 
-	userData, err := internal.UnmarshalSentinelUserData(userDataRaw)
-	tracelog.ErrorLogger.FatalfOnError("Failed to unmarshal the provided UserData: %s", err)
+	userData, err := internal.PrepareSentinelUserData(context.Background(), userDataRaw)
+	tracelog.ErrorLogger.FatalfOnError("Failed to prepare the UserData: %s", err)
 
 	var incrementFrom *string
 	if (prevBackupInfo != PrevBackupInfo{}) {
@@ -93,6 +102,7 @@ func HandleBackupPush(
 	sentinel := StreamSentinelDto{
 		Tool:              tool,
 		BinLogStart:       binlogStart,
+		RelayLogStart:     relayLogStart,
 		BinLogEnd:         binlogEnd,
 		StartLocalTime:    timeStart,
 		StopLocalTime:     timeStop,
@@ -109,6 +119,7 @@ func HandleBackupPush(
 		IncrementFrom:     incrementFrom,
 		IncrementFullName: prevBackupInfo.fullBackupName,
 		IncrementCount:    &incrementCount,
+		WalgVersion:       internal.WalgVersion,
 	}
 	tracelog.InfoLogger.Printf("Backup sentinel: %s", sentinel.String())
 
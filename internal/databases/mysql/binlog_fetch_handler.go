@@ -4,11 +4,14 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"time"
 
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	conf "github.com/wal-g/wal-g/internal/config"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
 )
 
 type indexHandler struct {
@@ -42,17 +45,67 @@ func (ih *indexHandler) createIndexFile() error {
 	return nil
 }
 
-func HandleBinlogFetch(folder storage.Folder, backupName string, untilTS string, untilBinlogLastModifiedTS string) {
+func HandleBinlogFetch(folder storage.Folder, backupName string, untilTS string, untilBinlogLastModifiedTS string,
+	fromRelayLogs bool) {
 	dstDir, err := internal.GetLogsDstSettings(conf.MysqlBinlogDstSetting)
 	tracelog.ErrorLogger.FatalOnError(err)
 
-	startTS, endTS, endBinlogTS, err := getTimestamps(folder, backupName, untilTS, untilBinlogLastModifiedTS)
+	folder, err = internal.ConfigureObjectCache(folder)
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	logsPath := recoveryLogsPath(fromRelayLogs)
+	startTS, endTS, endBinlogTS, err := getTimestamps(folder, backupName, untilTS, untilBinlogLastModifiedTS, logsPath)
 	tracelog.ErrorLogger.FatalOnError(err)
 
 	handler := newIndexHandler(dstDir)
 
 	tracelog.InfoLogger.Printf("Fetching binlogs since %s until %s", startTS, endTS)
-	err = fetchLogs(folder, dstDir, startTS, endTS, endBinlogTS, handler)
+	err = fetchLogs(folder, logsPath, dstDir, startTS, endTS, endBinlogTS, handler)
+	tracelog.ErrorLogger.FatalfOnError("Failed to fetch binlogs: %v", err)
+
+	err = handler.createIndexFile()
+	tracelog.ErrorLogger.FatalfOnError("Failed to create binlog index file: %v", err)
+}
+
+// windowHandler drops the binlog that starts after the end of the time window
+type windowHandler struct {
+	*indexHandler
+	untilTS time.Time
+}
+
+func (wh *windowHandler) handleBinlog(binlogPath string) error {
+	timestamp, err := GetBinlogStartTimestamp(binlogPath, gomysql.MySQLFlavor)
+	if err != nil {
+		return err
+	}
+	if timestamp.After(wh.untilTS) {
+		return os.Remove(binlogPath)
+	}
+	return wh.indexHandler.handleBinlog(binlogPath)
+}
+
+// HandleBinlogFetchBetween fetches exactly the binlogs covering the time window into dstDir with their original names,
+// e.g. to feed them to pt-query-digest or mysqlbinlog.
+func HandleBinlogFetchBetween(folder storage.Folder, sinceTS, untilTS string, dstDir string) {
+	startTS, err := time.Parse(time.RFC3339, sinceTS)
+	tracelog.ErrorLogger.FatalfOnError("Failed to parse the start of the time window: %v", err)
+	endTS, err := utility.ParseUntilTS(untilTS)
+	tracelog.ErrorLogger.FatalfOnError("Failed to parse the end of the time window: %v", err)
+
+	if dstDir == "" {
+		dstDir, err = internal.GetLogsDstSettings(conf.MysqlBinlogDstSetting)
+		tracelog.ErrorLogger.FatalOnError(err)
+	}
+	err = os.MkdirAll(dstDir, 0755)
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	folder, err = internal.ConfigureObjectCache(folder)
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	handler := &windowHandler{indexHandler: newIndexHandler(dstDir), untilTS: endTS}
+
+	tracelog.InfoLogger.Printf("Fetching binlogs between %s and %s into %s", startTS, endTS, dstDir)
+	err = fetchLogs(folder, BinlogPath, dstDir, startTS, endTS, utility.TimeNowCrossPlatformUTC(), handler)
 	tracelog.ErrorLogger.FatalfOnError("Failed to fetch binlogs: %v", err)
 
 	err = handler.createIndexFile()
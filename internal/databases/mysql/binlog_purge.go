@@ -0,0 +1,138 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/walz"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// BinlogPurgeOptions controls purging of archived binlogs from the server. binlog-push purges them right after
+// the upload, binlog-push --daemon purges them in the background every Interval.
+type BinlogPurgeOptions struct {
+	Enabled bool
+	// SafetyLag is the minimum time since the last modification of a binlog before it can be purged.
+	SafetyLag time.Duration
+	Interval  time.Duration
+}
+
+// runBinlogPurger purges the archived binlogs every interval. The binlogs archived by this host are taken
+// from the local cache and purged only if they are present in the storage, so the purge can't race the archiver.
+func runBinlogPurger(binlogsStorageFolder storage.Folder, options BinlogPurgeOptions) {
+	ticker := time.NewTicker(options.Interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		err := purgeArchivedBinlogsOnce(binlogsStorageFolder, options.SafetyLag)
+		if err != nil {
+			tracelog.ErrorLogger.Printf("Failed to purge the archived binlogs: %v", err)
+		}
+	}
+}
+
+func purgeArchivedBinlogsOnce(binlogsStorageFolder storage.Folder, safetyLag time.Duration) error {
+	db, err := getMySQLConnection()
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(db, "")
+	binlogsFolder, err := getMySQLBinlogsFolder(db)
+	if err != nil {
+		return err
+	}
+	binlogs, err := getMySQLBinlogs(db)
+	if err != nil {
+		return err
+	}
+	return purgeArchivedBinlogs(db, binlogsStorageFolder, binlogsFolder, binlogs, getCache().LastArchivedBinlog,
+		safetyLag)
+}
+
+// purgeArchivedBinlogs runs PURGE BINARY LOGS for the binlogs that are archived and present in the storage.
+// The active (last) binlog is never purged.
+func purgeArchivedBinlogs(db *sql.DB, binlogsStorageFolder storage.Folder, binlogsFolder string,
+	binlogs []string, lastArchivedBinlog string, safetyLag time.Duration) error {
+	stored, err := getStoredBinlogs(binlogsStorageFolder)
+	if err != nil {
+		return fmt.Errorf("failed to list archived binlogs: %w", err)
+	}
+
+	modTimes := make(map[string]time.Time, len(binlogs))
+	for _, binlog := range binlogs {
+		info, err := os.Stat(path.Join(binlogsFolder, binlog))
+		if err != nil {
+			return fmt.Errorf("failed to stat binlog %s: %w", binlog, err)
+		}
+		modTimes[binlog] = info.ModTime()
+	}
+
+	purgeTo := selectPurgeTarget(binlogs, lastArchivedBinlog, stored, modTimes, time.Now(), safetyLag)
+	if purgeTo == "" {
+		tracelog.InfoLogger.Println("No binlogs to purge")
+		return nil
+	}
+
+	tracelog.InfoLogger.Printf("Purging binlogs up to %s\n", purgeTo)
+	_, err = db.Exec(fmt.Sprintf("PURGE BINARY LOGS TO '%s'", purgeTo))
+	if err != nil {
+		return fmt.Errorf("failed to purge binlogs up to %s: %w", purgeTo, err)
+	}
+	return nil
+}
+
+// getStoredBinlogs returns the names of non-empty binlogs in the storage folder with the compression extension
+// trimmed. Only the known extensions are trimmed, the binlog number looks like an extension too.
+func getStoredBinlogs(folder storage.Folder) (map[string]bool, error) {
+	objects, _, err := folder.ListFolder()
+	if err != nil {
+		return nil, err
+	}
+	stored := make(map[string]bool, len(objects))
+	for _, object := range objects {
+		if object.GetSize() == 0 {
+			continue
+		}
+		stored[trimBinlogObjectExtension(object.GetName())] = true
+	}
+	return stored, nil
+}
+
+// selectPurgeTarget returns the binlog to pass to PURGE BINARY LOGS TO, i.e. the first binlog that must be kept,
+// or an empty string if nothing can be purged. Binlogs are purged from the oldest one, and the first binlog
+// that is not archived yet, is missing in the storage or is younger than safetyLag stops the purge.
+func selectPurgeTarget(binlogs []string, lastArchivedBinlog string, stored map[string]bool,
+	modTimes map[string]time.Time, now time.Time, safetyLag time.Duration) string {
+	if lastArchivedBinlog == "" {
+		return ""
+	}
+	purgeTo := ""
+	for i := 0; i < len(binlogs)-1; i++ {
+		binlog := binlogs[i]
+		if BinlogPrefix(binlog) != BinlogPrefix(lastArchivedBinlog) || BinlogNum(binlog) > BinlogNum(lastArchivedBinlog) {
+			break
+		}
+		if !stored[binlog] {
+			tracelog.WarningLogger.Printf("Binlog %s is not found in the storage, will not purge it and newer ones\n", binlog)
+			break
+		}
+		if now.Sub(modTimes[binlog]) < safetyLag {
+			break
+		}
+		purgeTo = binlogs[i+1]
+	}
+	return purgeTo
+}
+
+func trimBinlogObjectExtension(name string) string {
+	extension := utility.GetFileExtension(name)
+	if extension == walz.FileExtension || compression.FindDecompressor(extension) != nil {
+		return utility.TrimFileExtension(name)
+	}
+	return name
+}
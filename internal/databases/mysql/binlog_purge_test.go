@@ -0,0 +1,60 @@
+package mysql
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+func TestSelectPurgeTarget(t *testing.T) {
+	now := time.Unix(1692800000, 0)
+	binlogs := []string{"mysql-bin.000001", "mysql-bin.000002", "mysql-bin.000003", "mysql-bin.000004"}
+	modTimes := map[string]time.Time{
+		"mysql-bin.000001": now.Add(-3 * time.Hour),
+		"mysql-bin.000002": now.Add(-2 * time.Hour),
+		"mysql-bin.000003": now.Add(-30 * time.Minute),
+		"mysql-bin.000004": now,
+	}
+	allStored := map[string]bool{"mysql-bin.000001": true, "mysql-bin.000002": true, "mysql-bin.000003": true}
+
+	var tests = []struct {
+		name         string
+		lastArchived string
+		stored       map[string]bool
+		safetyLag    time.Duration
+		exp          string
+	}{
+		{"nothing archived", "", allStored, 0, ""},
+		{"all archived", "mysql-bin.000003", allStored, 0, "mysql-bin.000004"},
+		{"stops at last archived", "mysql-bin.000001", allStored, 0, "mysql-bin.000002"},
+		{"respects safety lag", "mysql-bin.000003", allStored, time.Hour, "mysql-bin.000003"},
+		{"stops at missing binlog", "mysql-bin.000003", map[string]bool{"mysql-bin.000001": true}, 0, "mysql-bin.000002"},
+		{"another binlog prefix", "other-bin.000003", allStored, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := selectPurgeTarget(binlogs, tt.lastArchived, tt.stored, modTimes, now, tt.safetyLag)
+			assert.Equal(t, tt.exp, actual)
+		})
+	}
+}
+
+func TestGetStoredBinlogs(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewKVS())
+	require.NoError(t, folder.PutObject("mysql-bin.000001.lz4", strings.NewReader("binlog")))
+	require.NoError(t, folder.PutObject("mysql-bin.000002.lzma", strings.NewReader("binlog")))
+	require.NoError(t, folder.PutObject("mysql-bin.000003", strings.NewReader("binlog")))
+	require.NoError(t, folder.PutObject("mysql-bin.000004.lz4", strings.NewReader("")))
+
+	stored, err := getStoredBinlogs(folder)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{
+		"mysql-bin.000001": true,
+		"mysql-bin.000002": true,
+		"mysql-bin.000003": true,
+	}, stored)
+}
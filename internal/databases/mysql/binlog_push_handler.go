@@ -10,6 +10,7 @@ import (
 	"os/user"
 	"path"
 	"path/filepath"
+	"time"
 
 	"github.com/go-mysql-org/go-mysql/mysql"
 	"github.com/pkg/errors"
@@ -21,24 +22,61 @@ import (
 const BinlogCacheFileName = ".walg_mysql_binlogs_cache"
 
 type LogsCache struct {
-	LastArchivedBinlog string `json:"LastArchivedBinlog"`
+	LastArchivedBinlog   string `json:"LastArchivedBinlog"`
+	LastArchivedRelayLog string `json:"LastArchivedRelayLog,omitempty"`
+}
+
+// HandleBinlogPushDaemon runs binlog-push every interval until the process is stopped. The archived binlogs are
+// purged by the background process on its own schedule, so the purge doesn't wait for the uploads and vice versa.
+// A failed run, e.g. while MySQL is restarting, is logged and retried on the next interval.
+func HandleBinlogPushDaemon(uploader internal.Uploader, checkGTIDs bool, purgeOptions BinlogPurgeOptions,
+	archiveRelayLogs bool, interval time.Duration) {
+	if purgeOptions.Enabled {
+		go runBinlogPurger(uploader.Folder().GetSubFolder(BinlogPath), purgeOptions)
+	}
+	for {
+		err := HandleBinlogPush(uploader.Clone(), "", checkGTIDs, BinlogPurgeOptions{}, archiveRelayLogs)
+		if err != nil {
+			tracelog.ErrorLogger.Printf("binlog-push failed, retrying in %s: %v", interval, err)
+		}
+		time.Sleep(interval)
+	}
 }
 
 //gocyclo:ignore
 //nolint:funlen
-func HandleBinlogPush(uploader internal.Uploader, untilBinlog string, checkGTIDs bool) {
+func HandleBinlogPush(uploader internal.Uploader, untilBinlog string, checkGTIDs bool, purgeOptions BinlogPurgeOptions,
+	archiveRelayLogs bool) error {
 	rootFolder := uploader.Folder()
+	relayLogUploader := uploader.Clone()
+	relayLogUploader.ChangeDirectory(RelayLogPath)
 	uploader.ChangeDirectory(BinlogPath)
 
+	lease, err := internal.AcquireArchivingLease(rootFolder)
+	if _, ok := err.(internal.ArchivingLeaseHeldError); ok {
+		tracelog.InfoLogger.Println(err.Error())
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer lease.Stop()
+
 	db, err := getMySQLConnection()
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return err
+	}
 	defer utility.LoggedClose(db, "")
 
 	binlogsFolder, err := getMySQLBinlogsFolder(db)
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the binlogs folder")
+	}
 
 	binlogs, err := getMySQLBinlogs(db)
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return errors.Wrap(err, "failed to list the binlogs")
+	}
 
 	lastBinlog := lastOrDefault(binlogs, "")
 	if untilBinlog == "" || BinlogNum(untilBinlog) > BinlogNum(lastBinlog) {
@@ -56,14 +94,16 @@ func HandleBinlogPush(uploader internal.Uploader, untilBinlog string, checkGTIDs
 			BinlogNum(binlogs[len(binlogs)-1]) < BinlogNum(cache.LastArchivedBinlog) {
 			tracelog.WarningLogger.Printf("binlog was reset or naming (%s => %s), clearing cache",
 				cache.LastArchivedBinlog, binlogs[0])
-			cache = LogsCache{}
+			cache = LogsCache{LastArchivedRelayLog: cache.LastArchivedRelayLog}
 		}
 	}
 
 	var filter gtidFilter
 	if checkGTIDs {
 		flavor, err := getMySQLFlavor(db)
-		tracelog.ErrorLogger.FatalOnError(err)
+		if err != nil {
+			return err
+		}
 
 		switch flavor {
 		case mysql.MySQLFlavor:
@@ -76,7 +116,7 @@ func HandleBinlogPush(uploader internal.Uploader, untilBinlog string, checkGTIDs
 				lastGtidSeen:  nil,
 			}
 		default:
-			tracelog.ErrorLogger.Fatalf("Unsupported flavor type: %s. Disable WALG_MYSQL_CHECK_GTIDS for current database.", flavor)
+			return errors.Errorf("unsupported flavor type: %s. Disable WALG_MYSQL_CHECK_GTIDS for current database", flavor)
 		}
 	}
 
@@ -118,9 +158,18 @@ func HandleBinlogPush(uploader internal.Uploader, untilBinlog string, checkGTIDs
 			hadUploadsInThisRun = true
 		}
 
+		if lease.IsLost() {
+			// another host archives the binlogs now, the rest of the run is its job
+			tracelog.WarningLogger.Printf("The archiving lease has been taken over, stopping before %s", binlog)
+			putCache(cache)
+			return nil
+		}
+
 		// Upload binlogs:
 		err = archiveBinLog(uploader, binlogsFolder, binlog)
-		tracelog.ErrorLogger.FatalOnError(err)
+		if err != nil {
+			return err
+		}
 
 		cache.LastArchivedBinlog = binlog
 		putCache(cache)
@@ -130,19 +179,39 @@ func HandleBinlogPush(uploader internal.Uploader, untilBinlog string, checkGTIDs
 			binlogSentinelDto.GTIDArchived = filter.gtidArchived.String()
 			tracelog.InfoLogger.Printf("Uploading binlog sentinel: %s", binlogSentinelDto)
 			err := UploadBinlogSentinel(rootFolder, &binlogSentinelDto)
-			tracelog.ErrorLogger.FatalOnError(err)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
 	// Write Binlog Cache (even when no data uploaded, it will create file on first run)
 	putCache(cache)
+
+	if archiveRelayLogs {
+		err = archiveMySQLRelayLogs(db, rootFolder, relayLogUploader, &cache)
+		if err != nil {
+			return err
+		}
+	}
+
+	if purgeOptions.Enabled {
+		return purgeArchivedBinlogs(db, uploader.Folder(), binlogsFolder, binlogs, cache.LastArchivedBinlog,
+			purgeOptions.SafetyLag)
+	}
+	return nil
 }
 
 func getMySQLBinlogs(db *sql.DB) ([]string, error) {
+	return getMySQLLogs(db, "log_bin_index")
+}
+
+// getMySQLLogs lists the binlogs or the relay logs from the index file kept in indexVariable
+func getMySQLLogs(db *sql.DB, indexVariable string) ([]string, error) {
 	var result []string
 	// SHOW BINARY LOGS acquire binlog mutex and may hang while mysql is committing huge transactions
 	// so we read binlog index from the disk with no locking
-	row := db.QueryRow("SELECT @@log_bin_index")
+	row := db.QueryRow("SELECT @@" + indexVariable)
 	var binlogIndex string
 	err := row.Scan(&binlogIndex)
 	if err != nil {
@@ -152,6 +221,7 @@ func getMySQLBinlogs(db *sql.DB) ([]string, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to open binlog index: %w", err)
 	}
+	defer utility.LoggedClose(fh, "")
 	s := bufio.NewScanner(fh)
 	for s.Scan() {
 		binlog := path.Base(s.Text())
@@ -162,13 +232,17 @@ func getMySQLBinlogs(db *sql.DB) ([]string, error) {
 }
 
 func getMySQLBinlogsFolder(db *sql.DB) (string, error) {
-	row := db.QueryRow("SHOW VARIABLES LIKE 'log_bin_basename'")
-	var nonce, logBinBasename string
-	err := row.Scan(&nonce, &logBinBasename)
+	return getMySQLLogsFolder(db, "log_bin_basename")
+}
+
+func getMySQLLogsFolder(db *sql.DB, basenameVariable string) (string, error) {
+	row := db.QueryRow("SHOW VARIABLES LIKE '" + basenameVariable + "'")
+	var nonce, logBasename string
+	err := row.Scan(&nonce, &logBasename)
 	if err != nil {
 		return "", err
 	}
-	return path.Dir(logBinBasename), nil
+	return path.Dir(logBasename), nil
 }
 
 func archiveBinLog(uploader internal.Uploader, dataDir string, binlog string) error {
@@ -0,0 +1,17 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+func TestHandleBinlogPush_ReturnsError(t *testing.T) {
+	viper.Set(conf.MysqlDatasourceNameSetting, nil)
+	// the daemon keeps running and retries the failed run, e.g. while MySQL is restarting
+	err := HandleBinlogPush(testtools.NewMockUploader(false, false), "", false, BinlogPurgeOptions{}, false)
+	assert.Error(t, err)
+}
@@ -261,7 +261,7 @@ func (h Handler) HandleQuery(query string) (*mysql.Result, error) {
 func HandleBinlogServer(since string, until string) {
 	st, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
-	startTS, untilTS, _, err = getTimestamps(st.RootFolder(), since, until, "")
+	startTS, untilTS, _, err = getTimestamps(st.RootFolder(), since, until, "", BinlogPath)
 	tracelog.ErrorLogger.FatalOnError(err)
 
 	tracelog.InfoLogger.Printf("Starting binlog server")
@@ -1,7 +1,6 @@
 package mysql
 
 import (
-	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 	"github.com/wal-g/wal-g/utility"
@@ -33,7 +32,9 @@ func NewDeleteHandler(folder storage.Folder) (*DeleteHandler, error) {
 	}
 
 	backupObjects, err := MakeMySQLBackupObjects(folder, backupSentinels)
-	tracelog.ErrorLogger.FatalOnError(err)
+	if err != nil {
+		return nil, err
+	}
 
 	permanentBackups := internal.GetPermanentBackups(folder.GetSubFolder(utility.BaseBackupPath), NewGenericMetaFetcher())
 	permanentBackupNames := make([]string, 0, len(permanentBackups))
@@ -58,3 +59,7 @@ func NewDeleteHandler(folder storage.Folder) (*DeleteHandler, error) {
 func (h *DeleteHandler) HandleDeleteEverything(args []string, confirmed bool) {
 	h.DeleteHandler.HandleDeleteEverything(args, h.permanentBackups, confirmed)
 }
+
+func (h *DeleteHandler) ExplainDeleteEverything(args []string) (*internal.DeleteExplanation, error) {
+	return h.DeleteHandler.ExplainDeleteEverything(args, h.permanentBackups)
+}
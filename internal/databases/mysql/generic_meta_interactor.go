@@ -79,14 +79,12 @@ func modifyBackupSentinel(backupName string, backupFolder storage.Folder, modifi
 		return err
 	}
 	var sentinel StreamSentinelDto
-	err = backup.FetchSentinel(&sentinel)
-	if err != nil {
-		return errors.Wrap(err, "failed to fetch the existing backup metadata for modifying")
-	}
-	sentinel = modifier(sentinel)
-	err = backup.UploadSentinel(sentinel)
+	err = backup.ModifySentinel(&sentinel, func() error {
+		sentinel = modifier(sentinel)
+		return nil
+	})
 	if err != nil {
-		return errors.Wrap(err, "failed to upload the modified metadata to the storage")
+		return errors.Wrap(err, "failed to modify the backup metadata in the storage")
 	}
 	return nil
 }
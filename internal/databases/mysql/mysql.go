@@ -16,9 +16,11 @@ import (
 
 	gomysql "github.com/go-mysql-org/go-mysql/mysql"
 	"github.com/go-sql-driver/mysql"
+	"github.com/spf13/viper"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/objcache"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 	"github.com/wal-g/wal-g/utility"
 )
@@ -215,6 +217,8 @@ func replaceHostInDatasourceName(datasourceName string, newHost string) string {
 type StreamSentinelDto struct {
 	Tool        BackupTool `json:"Tool,omitempty"`
 	BinLogStart string     `json:"BinLogStart,omitempty"`
+	// RelayLogStart is the archived relay log to start the recovery from with --relay-logs
+	RelayLogStart string `json:"RelayLogStart,omitempty"`
 	// BinLogEnd field is for debug purpose only.
 	// As we can not guarantee that transactions in BinLogEnd file happened before or after backup
 	BinLogEnd      string    `json:"BinLogEnd,omitempty"`
@@ -237,6 +241,9 @@ type StreamSentinelDto struct {
 	IncrementFrom     *string `json:"DeltaFrom,omitempty"`
 	IncrementFullName *string `json:"DeltaFullName,omitempty"`
 	IncrementCount    *int    `json:"DeltaCount,omitempty"`
+
+	// WalgVersion is the version of WAL-G which made the backup, empty for the backups made before it was recorded
+	WalgVersion string `json:"WalgVersion,omitempty"`
 	//todo: add other fields from internal.GenericMetadata
 }
 
@@ -252,8 +259,9 @@ type binlogHandler interface {
 	handleBinlog(binlogPath string) error
 }
 
-func fetchLogs(folder storage.Folder, dstDir string, startTS, endTS, endBinlogTS time.Time, handler binlogHandler) error {
-	logFolder := folder.GetSubFolder(BinlogPath)
+func fetchLogs(folder storage.Folder, logsPath string, dstDir string, startTS, endTS, endBinlogTS time.Time,
+	handler binlogHandler) error {
+	logFolder := folder.GetSubFolder(logsPath)
 	includeStart := true
 outer:
 	for {
@@ -262,7 +270,8 @@ outer:
 		if err != nil {
 			return err
 		}
-		for _, logFile := range logsToFetch {
+		for i, logFile := range logsToFetch {
+			prefetchBinlogs(logFolder, logsToFetch, i)
 			startTS = logFile.GetLastModified()
 			binlogName := utility.TrimFileExtension(logFile.GetName())
 			binlogPath := path.Join(dstDir, binlogName)
@@ -290,6 +299,23 @@ outer:
 	return nil
 }
 
+// prefetchBinlogs keeps the object cache (if configured) warm with the next WALG_OBJECT_CACHE_PREFETCH binlogs
+// while the current one is being fetched.
+func prefetchBinlogs(logFolder storage.Folder, logsToFetch []storage.Object, current int) {
+	cachedFolder, ok := logFolder.(*objcache.Folder)
+	if !ok {
+		return
+	}
+	prefetchCount := viper.GetInt(conf.ObjectCachePrefetchSetting)
+	from := current + prefetchCount
+	if current == 0 {
+		from = 1
+	}
+	for i := from; i <= current+prefetchCount && i < len(logsToFetch); i++ {
+		cachedFolder.PrefetchInBackground(logsToFetch[i].GetName())
+	}
+}
+
 func provideLogs(folder storage.Folder, dstDir string, startTS, endTS time.Time, p *storage.ObjectProvider) {
 	defer p.Close()
 	_, err := os.Stat(dstDir)
@@ -341,7 +367,15 @@ func provideLogs(folder storage.Folder, dstDir string, startTS, endTS time.Time,
 	}
 }
 
-func getBinlogSinceTS(folder storage.Folder, backup internal.Backup) (time.Time, error) {
+// recoveryLogsPath is the folder of the logs to recover from: the binlogs or the relay logs archived from the replica
+func recoveryLogsPath(fromRelayLogs bool) string {
+	if fromRelayLogs {
+		return RelayLogPath
+	}
+	return BinlogPath
+}
+
+func getBinlogSinceTS(folder storage.Folder, backup internal.Backup, logsPath string) (time.Time, error) {
 	startTS := utility.MaxTime // far future
 	var streamSentinel StreamSentinelDto
 	err := backup.FetchSentinel(&streamSentinel)
@@ -364,12 +398,16 @@ func getBinlogSinceTS(folder storage.Folder, backup internal.Backup) (time.Time,
 		}
 	}
 	// case when binlog was uploaded before backup
-	binlogs, _, err := folder.GetSubFolder(BinlogPath).ListFolder()
+	binlogStart := streamSentinel.BinLogStart
+	if logsPath == RelayLogPath {
+		binlogStart = streamSentinel.RelayLogStart
+	}
+	binlogs, _, err := folder.GetSubFolder(logsPath).ListFolder()
 	if err != nil {
 		return time.Time{}, err
 	}
 	for _, binlog := range binlogs {
-		if strings.HasPrefix(binlog.GetName(), streamSentinel.BinLogStart) {
+		if strings.HasPrefix(binlog.GetName(), binlogStart) {
 			tracelog.InfoLogger.Printf("Backup start binlog: %s (%s)", binlog.GetName(), binlog.GetLastModified())
 			if binlog.GetLastModified().Before(startTS) {
 				startTS = binlog.GetLastModified()
@@ -0,0 +1,92 @@
+package mysql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// NewBinlogGapFinder finds the binlogs missing in the storage from the first binlog of the backup up to the first
+// binlog of the next one, or up to the newest binlog archived.
+func NewBinlogGapFinder(rootFolder storage.Folder) internal.LogGapFinder {
+	return func(backupName, nextBackupName string) (string, error) {
+		stored, err := getStoredBinlogs(rootFolder.GetSubFolder(BinlogPath))
+		if err != nil {
+			return "", err
+		}
+		start, err := getBackupBinlogStart(rootFolder, backupName)
+		if err != nil {
+			return "", err
+		}
+		baseName, startNum, width, err := splitBinlogName(start)
+		if err != nil {
+			return "", err
+		}
+
+		endNum := startNum
+		if nextBackupName != "" {
+			end, err := getBackupBinlogStart(rootFolder, nextBackupName)
+			if err != nil {
+				return "", err
+			}
+			if _, endNum, _, err = splitBinlogName(end); err != nil {
+				return "", err
+			}
+		} else {
+			for binlog := range stored {
+				name, num, _, err := splitBinlogName(binlog)
+				if err == nil && name == baseName && num > endNum {
+					endNum = num
+				}
+			}
+		}
+
+		binlogName := func(num int) string {
+			return fmt.Sprintf("%s.%0*d", baseName, width, num)
+		}
+		for num := startNum; num <= endNum; num++ {
+			if stored[binlogName(num)] {
+				continue
+			}
+			lastMissing := num
+			for lastMissing < endNum && !stored[binlogName(lastMissing+1)] {
+				lastMissing++
+			}
+			return fmt.Sprintf("binlogs %s-%s are missing", binlogName(num), binlogName(lastMissing)), nil
+		}
+		return "", nil
+	}
+}
+
+func getBackupBinlogStart(rootFolder storage.Folder, backupName string) (string, error) {
+	backup, err := internal.GetBackupByName(backupName, utility.BaseBackupPath, rootFolder)
+	if err != nil {
+		return "", err
+	}
+	var sentinel StreamSentinelDto
+	err = backup.FetchSentinel(&sentinel)
+	if err != nil {
+		return "", err
+	}
+	if sentinel.BinLogStart == "" {
+		return "", errors.Errorf("backup %s has no first binlog in its sentinel", backupName)
+	}
+	return sentinel.BinLogStart, nil
+}
+
+// splitBinlogName splits e.g. mysql-bin.000017 into the base name, the sequence number and its width
+func splitBinlogName(binlog string) (baseName string, num, width int, err error) {
+	p := strings.LastIndex(binlog, ".")
+	if p >= 0 {
+		num, err = strconv.Atoi(binlog[p+1:])
+	}
+	if p < 0 || err != nil {
+		return "", 0, 0, errors.Errorf("unexpected binlog name: %s", binlog)
+	}
+	return binlog[:p], num, len(binlog) - p - 1, nil
+}
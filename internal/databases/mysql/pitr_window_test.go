@@ -0,0 +1,49 @@
+package mysql
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestBinlogGapFinder(t *testing.T) {
+	internal.ConfigureSettings(conf.MYSQL)
+	conf.InitConfig()
+	folder := memory.NewFolder("", memory.NewKVS())
+	for _, binlog := range []string{"000003", "000004", "000005", "000008", "000009"} {
+		require.NoError(t, folder.PutObject(BinlogPath+"mysql-bin."+binlog+".lz4", strings.NewReader("binlog")))
+	}
+	putBackup := func(name, binlogStart string) {
+		sentinel, err := json.Marshal(StreamSentinelDto{BinLogStart: binlogStart})
+		require.NoError(t, err)
+		require.NoError(t, folder.PutObject(utility.BaseBackupPath+name+utility.SentinelSuffix,
+			strings.NewReader(string(sentinel))))
+	}
+	putBackup("stream_1", "mysql-bin.000003")
+	putBackup("stream_2", "mysql-bin.000005")
+	putBackup("stream_3", "mysql-bin.000008")
+	findGap := NewBinlogGapFinder(folder)
+
+	gap, err := findGap("stream_1", "stream_2")
+	require.NoError(t, err)
+	assert.Empty(t, gap)
+
+	gap, err = findGap("stream_3", "")
+	require.NoError(t, err)
+	assert.Empty(t, gap)
+
+	gap, err = findGap("stream_2", "stream_3")
+	require.NoError(t, err)
+	assert.Equal(t, "binlogs mysql-bin.000006-mysql-bin.000007 are missing", gap)
+
+	gap, err = findGap("stream_1", "")
+	require.NoError(t, err)
+	assert.Equal(t, "binlogs mysql-bin.000006-mysql-bin.000007 are missing", gap)
+}
@@ -0,0 +1,270 @@
+package mysql
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	RelayLogPath         = "relaylog_" + utility.VersionStr + "/"
+	RelayLogSentinelPath = "relaylog_sentinel_" + utility.VersionStr + ".json"
+)
+
+// RelayLogSentinelDto maps the archived relay logs of the replica back to the GTID sets of the source
+type RelayLogSentinelDto struct {
+	RelayLogs []ArchivedRelayLog `json:"RelayLogs"`
+}
+
+// ArchivedRelayLog describes the transactions of the source kept in the relay log
+type ArchivedRelayLog struct {
+	Name string `json:"Name"`
+	// PreviousGTIDs is the GTID set retrieved from the source before the relay log
+	PreviousGTIDs string `json:"PreviousGtids"`
+	// GTIDs is the GTID set of the transactions in the relay log
+	GTIDs string `json:"Gtids"`
+	// SourceBinlogs are the binlogs of the source the transactions were read from
+	SourceBinlogs []string `json:"SourceBinlogs,omitempty"`
+}
+
+func FetchRelayLogSentinel(folder storage.Folder) (RelayLogSentinelDto, error) {
+	var sentinel RelayLogSentinelDto
+	reader, err := folder.ReadObject(RelayLogSentinelPath)
+	if _, ok := err.(storage.ObjectNotFoundError); ok {
+		return sentinel, nil
+	}
+	if err != nil {
+		return sentinel, err
+	}
+	defer utility.LoggedClose(reader, "")
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return sentinel, err
+	}
+	return sentinel, json.Unmarshal(data, &sentinel)
+}
+
+func UploadRelayLogSentinel(folder storage.Folder, sentinel RelayLogSentinelDto) error {
+	dtoBody, err := json.Marshal(sentinel)
+	if err != nil {
+		return internal.NewSentinelMarshallingError(RelayLogSentinelPath, err)
+	}
+	return folder.PutObject(RelayLogSentinelPath, bytes.NewReader(dtoBody))
+}
+
+// archiveMySQLRelayLogs uploads the relay logs of the replica, except the one being written, so the transactions
+// are archived even if the source purges its binlogs before they are archived there. It does nothing on the source.
+func archiveMySQLRelayLogs(db *sql.DB, rootFolder storage.Folder, uploader internal.Uploader, cache *LogsCache) error {
+	replica, err := isMySQLReplica(db)
+	if err != nil {
+		return errors.Wrap(err, "failed to check the replication status")
+	}
+	if !replica {
+		tracelog.DebugLogger.Println("The server is not a replica, there are no relay logs to archive")
+		return nil
+	}
+	flavor, err := getMySQLFlavor(db)
+	if err != nil {
+		return err
+	}
+	if flavor != mysql.MySQLFlavor {
+		tracelog.WarningLogger.Printf("Relay logs archiving is not supported for %s", flavor)
+		return nil
+	}
+	relayLogsFolder, err := getMySQLLogsFolder(db, "relay_log_basename")
+	if err != nil {
+		return errors.Wrap(err, "failed to get the relay logs folder")
+	}
+	relayLogs, err := getMySQLLogs(db, "relay_log_index")
+	if err != nil {
+		return errors.Wrap(err, "failed to list the relay logs")
+	}
+	if len(relayLogs) > 0 && cache.LastArchivedRelayLog != "" &&
+		(BinlogPrefix(relayLogs[0]) != BinlogPrefix(cache.LastArchivedRelayLog) ||
+			BinlogNum(relayLogs[len(relayLogs)-1]) < BinlogNum(cache.LastArchivedRelayLog)) {
+		tracelog.WarningLogger.Printf("relay log was reset or renamed (%s => %s), clearing cache",
+			cache.LastArchivedRelayLog, relayLogs[0])
+		cache.LastArchivedRelayLog = ""
+	}
+
+	sentinel, err := fetchPrunedRelayLogSentinel(rootFolder)
+	if err != nil {
+		return err
+	}
+	// the last relay log is still being written by the replication I/O thread
+	for i := 0; i < len(relayLogs)-1; i++ {
+		relayLog := relayLogs[i]
+		if cache.LastArchivedRelayLog != "" && BinlogNum(relayLog) <= BinlogNum(cache.LastArchivedRelayLog) {
+			continue
+		}
+		relayLogPath := path.Join(relayLogsFolder, relayLog)
+		if _, err = os.Stat(relayLogPath); os.IsNotExist(err) {
+			tracelog.WarningLogger.Printf("Relay log %s was purged by the replica before it was archived", relayLog)
+			continue
+		}
+		archivedRelayLog, err := parseRelayLog(relayLogPath)
+		if err != nil {
+			return err
+		}
+		if err = archiveBinLog(uploader, relayLogsFolder, relayLog); err != nil {
+			return err
+		}
+		sentinel.add(archivedRelayLog)
+		if err = UploadRelayLogSentinel(rootFolder, sentinel); err != nil {
+			return errors.Wrap(err, "failed to upload the relay log sentinel")
+		}
+		cache.LastArchivedRelayLog = relayLog
+		putCache(*cache)
+	}
+	return nil
+}
+
+func isMySQLReplica(db *sql.DB) (bool, error) {
+	rows, err := db.Query("SHOW REPLICA STATUS")
+	if err != nil {
+		// SHOW REPLICA STATUS appeared in MySQL 8.0.22
+		rows, err = db.Query("SHOW SLAVE STATUS")
+		if err != nil {
+			return false, err
+		}
+	}
+	defer utility.LoggedClose(rows, "")
+	return rows.Next(), rows.Err()
+}
+
+// fetchPrunedRelayLogSentinel fetches the relay log sentinel without the relay logs deleted from the storage
+func fetchPrunedRelayLogSentinel(rootFolder storage.Folder) (RelayLogSentinelDto, error) {
+	sentinel, err := FetchRelayLogSentinel(rootFolder)
+	if err != nil {
+		return sentinel, errors.Wrap(err, "failed to fetch the relay log sentinel")
+	}
+	objects, _, err := rootFolder.GetSubFolder(RelayLogPath).ListFolder()
+	if err != nil {
+		return sentinel, err
+	}
+	stored := make(map[string]bool, len(objects))
+	for _, object := range objects {
+		stored[utility.TrimFileExtension(object.GetName())] = true
+	}
+	relayLogs := make([]ArchivedRelayLog, 0, len(sentinel.RelayLogs))
+	for _, relayLog := range sentinel.RelayLogs {
+		if stored[relayLog.Name] {
+			relayLogs = append(relayLogs, relayLog)
+		}
+	}
+	sentinel.RelayLogs = relayLogs
+	return sentinel, nil
+}
+
+func (sentinel *RelayLogSentinelDto) add(relayLog ArchivedRelayLog) {
+	for i := range sentinel.RelayLogs {
+		if sentinel.RelayLogs[i].Name == relayLog.Name {
+			sentinel.RelayLogs = append(sentinel.RelayLogs[:i], sentinel.RelayLogs[i+1:]...)
+			break
+		}
+	}
+	sentinel.RelayLogs = append(sentinel.RelayLogs, relayLog)
+}
+
+// parseRelayLog reads the GTIDs of the source transactions in the relay log and the source binlogs they come from
+func parseRelayLog(filename string) (ArchivedRelayLog, error) {
+	relayLog := ArchivedRelayLog{Name: path.Base(filename)}
+	gtidSet, _ := mysql.ParseMysqlGTIDSet("")
+	gtids := gtidSet.(*mysql.MysqlGTIDSet)
+
+	parser := replication.NewBinlogParser()
+	parser.SetFlavor(mysql.MySQLFlavor)
+	parser.SetVerifyChecksum(false)
+	parser.SetRawMode(true)
+	// the raw events keep the checksum, the format description event tells if it is there
+	hasChecksum := false
+	err := parser.ParseFile(filename, 0, func(event *replication.BinlogEvent) error {
+		data := event.RawData[replication.EventHeaderSize:]
+		if event.Header.EventType == replication.FORMAT_DESCRIPTION_EVENT {
+			formatDescription := &replication.FormatDescriptionEvent{}
+			if err := formatDescription.Decode(data); err != nil {
+				return err
+			}
+			hasChecksum = formatDescription.ChecksumAlgorithm == replication.BINLOG_CHECKSUM_ALG_CRC32
+			return nil
+		}
+		if hasChecksum && len(data) >= replication.BinlogChecksumLength {
+			data = data[:len(data)-replication.BinlogChecksumLength]
+		}
+		switch event.Header.EventType {
+		case replication.PREVIOUS_GTIDS_EVENT:
+			previousGTIDs := &replication.PreviousGTIDsEvent{}
+			if err := previousGTIDs.Decode(data); err != nil {
+				return err
+			}
+			relayLog.PreviousGTIDs = previousGTIDs.GTIDSets
+		case replication.GTID_EVENT:
+			gtidEvent := &replication.GTIDEvent{}
+			if err := gtidEvent.Decode(data); err != nil {
+				return err
+			}
+			return addGTID(gtids, gtidEvent.SID, gtidEvent.GNO)
+		case replication.ROTATE_EVENT:
+			// the relay log has the rotate events of the source, and the own one in the end
+			rotateEvent := &replication.RotateEvent{}
+			if err := rotateEvent.Decode(data); err != nil {
+				return err
+			}
+			sourceBinlog := string(rotateEvent.NextLogName)
+			isOwnRotation := strings.HasPrefix(sourceBinlog, BinlogPrefix(relayLog.Name)+".")
+			count := len(relayLog.SourceBinlogs)
+			if !isOwnRotation && (count == 0 || relayLog.SourceBinlogs[count-1] != sourceBinlog) {
+				relayLog.SourceBinlogs = append(relayLog.SourceBinlogs, sourceBinlog)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return relayLog, errors.Wrapf(err, "failed to parse the relay log %s", filename)
+	}
+	relayLog.GTIDs = gtids.String()
+	return relayLog, nil
+}
+
+func addGTID(gtids *mysql.MysqlGTIDSet, sid []byte, gno int64) error {
+	if len(sid) != 16 {
+		return fmt.Errorf("unexpected GTID source id %x", sid)
+	}
+	gtid, err := mysql.ParseMysqlGTIDSet(fmt.Sprintf("%x-%x-%x-%x-%x:%d", sid[0:4], sid[4:6], sid[6:8], sid[8:10], sid[10:], gno))
+	if err != nil {
+		return err
+	}
+	return gtids.Add(*gtid.(*mysql.MysqlGTIDSet))
+}
+
+// getLastUploadedRelayLogBeforeGTID finds the archived relay log to start the recovery from the GTID set
+func getLastUploadedRelayLogBeforeGTID(folder storage.Folder, gtid mysql.GTIDSet) (string, error) {
+	sentinel, err := FetchRelayLogSentinel(folder)
+	if err != nil {
+		return "", err
+	}
+	for i := len(sentinel.RelayLogs) - 1; i >= 0; i-- {
+		prevGtid, err := mysql.ParseMysqlGTIDSet(sentinel.RelayLogs[i].PreviousGTIDs)
+		if err != nil {
+			return "", err
+		}
+		if gtid.Contain(prevGtid) {
+			return sentinel.RelayLogs[i].Name, nil
+		}
+	}
+	tracelog.WarningLogger.Printf("failed to find uploaded relay log behind %s", gtid)
+	return "", nil
+}
@@ -0,0 +1,56 @@
+package mysql
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+const testSourceUUID = "3e11fa47-71ca-11e1-9e33-c80aa9429562"
+
+func TestRelayLogSentinel_PrunedAndStartLookup(t *testing.T) {
+	folder := memory.NewFolder("in_memory/", memory.NewKVS())
+	sentinel := RelayLogSentinelDto{}
+	sentinel.add(ArchivedRelayLog{Name: "relay-bin.000001", PreviousGTIDs: "", GTIDs: testSourceUUID + ":1-5"})
+	sentinel.add(ArchivedRelayLog{Name: "relay-bin.000002", PreviousGTIDs: testSourceUUID + ":1-5", GTIDs: testSourceUUID + ":6-9"})
+	sentinel.add(ArchivedRelayLog{Name: "relay-bin.000003", PreviousGTIDs: testSourceUUID + ":1-9"})
+	// the relay log is parsed again if the upload is retried
+	sentinel.add(ArchivedRelayLog{Name: "relay-bin.000003", PreviousGTIDs: testSourceUUID + ":1-9", GTIDs: testSourceUUID + ":10"})
+	require.Len(t, sentinel.RelayLogs, 3)
+	assert.Equal(t, testSourceUUID+":10", sentinel.RelayLogs[2].GTIDs)
+	require.NoError(t, UploadRelayLogSentinel(folder, sentinel))
+
+	// relay-bin.000001 was deleted from the storage
+	for _, name := range []string{"relay-bin.000002.br", "relay-bin.000003.br"} {
+		require.NoError(t, folder.GetSubFolder(RelayLogPath).PutObject(name, strings.NewReader("relay log")))
+	}
+	pruned, err := fetchPrunedRelayLogSentinel(folder)
+	require.NoError(t, err)
+	require.Len(t, pruned.RelayLogs, 2)
+	assert.Equal(t, "relay-bin.000002", pruned.RelayLogs[0].Name)
+
+	gtid, err := mysql.ParseMysqlGTIDSet(testSourceUUID + ":1-7")
+	require.NoError(t, err)
+	start, err := getLastUploadedRelayLogBeforeGTID(folder, gtid)
+	require.NoError(t, err)
+	assert.Equal(t, "relay-bin.000002", start)
+}
+
+func TestAddGTID(t *testing.T) {
+	gtidSet, err := mysql.ParseMysqlGTIDSet("")
+	require.NoError(t, err)
+	gtids := gtidSet.(*mysql.MysqlGTIDSet)
+	sid, err := hex.DecodeString(strings.ReplaceAll(testSourceUUID, "-", ""))
+	require.NoError(t, err)
+
+	for _, gno := range []int64{1, 2, 3, 5} {
+		require.NoError(t, addGTID(gtids, sid, gno))
+	}
+	assert.Equal(t, testSourceUUID+":1-3:5", gtids.String())
+	assert.Error(t, addGTID(gtids, []byte{1, 2, 3}, 1))
+}
@@ -103,7 +103,7 @@ func enrichBackupArgs(backupCmd *exec.Cmd, xtrabackupExtraDirectory string, isFu
 
 func GetXtrabackupFetcher(restoreCmd, prepareCmd *exec.Cmd) func(folder storage.Folder, backup internal.Backup) {
 	return func(folder storage.Folder, backup internal.Backup) {
-		err := xtrabackupFetch(backup.Name, folder, restoreCmd, prepareCmd, true)
+		err := xtrabackupFetch(backup.Name, folder, restoreCmd, prepareCmd, true, backup.RestoreReport)
 		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v", err)
 	}
 }
@@ -113,9 +113,11 @@ func xtrabackupFetch(
 	folder storage.Folder,
 	restoreCmd *exec.Cmd,
 	prepareCmd *exec.Cmd,
-	isLast bool) error {
+	isLast bool,
+	report *internal.RestoreReporter) error {
 	backup, err := internal.GetBackupByName(backupName, utility.BaseBackupPath, folder)
 	tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v", err)
+	backup.RestoreReport = report
 
 	var sentinel StreamSentinelDto
 	err = backup.FetchSentinel(&sentinel)
@@ -135,7 +137,7 @@ func xtrabackupFetch(
 
 	if sentinel.IsIncremental {
 		tracelog.InfoLogger.Printf("Delta from %v at LSN %x \n", *sentinel.IncrementFrom, *sentinel.IncrementFromLSN)
-		err = xtrabackupFetch(*sentinel.IncrementFrom, folder, restoreCmd, prepareCmd, false)
+		err = xtrabackupFetch(*sentinel.IncrementFrom, folder, restoreCmd, prepareCmd, false, report)
 		if err != nil {
 			return err
 		}
@@ -64,6 +64,12 @@ func (backup *Backup) getTarPartitionFolder() storage.Folder {
 	return backup.Folder.GetSubFolder(backup.Name + internal.TarPartitionFolderName)
 }
 
+func (backup *Backup) newTarReaderMaker(tarName string) *internal.StorageReaderMaker {
+	readerMaker := internal.NewStorageReaderMaker(backup.getTarPartitionFolder(), tarName)
+	readerMaker.RestoreReport = backup.RestoreReport
+	return readerMaker
+}
+
 func (backup *Backup) GetTarNames() ([]string, error) {
 	tarPartitionFolder := backup.getTarPartitionFolder()
 	objects, _, err := tarPartitionFolder.ListFolder()
@@ -101,7 +107,12 @@ func (backup *Backup) GetSentinel() (BackupSentinelDto, error) {
 		return BackupSentinelDto{}, err
 	}
 
-	return s.BackupSentinelDto, nil
+	if unknown := unknownFormatFeatures(backup.SentinelDto.FormatFeatures); len(unknown) > 0 {
+		tracelog.WarningLogger.Printf("Backup %s is made by WAL-G %s with the format features %v unknown to this version, "+
+			"it may be read incorrectly", backup.Name, backup.SentinelDto.WalgVersion, unknown)
+	}
+
+	return *backup.SentinelDto, nil
 }
 
 // TODO : unit tests
@@ -120,6 +131,17 @@ func (backup *Backup) readDeprecatedFields(fields DeprecatedSentinelFields) erro
 		backup.SentinelDto.IncrementFromLSN = fields.DeltaFromLSN
 	}
 
+	// WAL-E stored neither the LSNs nor the sizes in the WAL-G fields
+	if backup.SentinelDto.BackupStartLSN == nil {
+		backup.SentinelDto.BackupStartLSN, _ = walEStartLSN(backup.Name)
+	}
+	if backup.SentinelDto.BackupFinishLSN == nil && fields.WalSegmentBackupStop != "" {
+		backup.SentinelDto.BackupFinishLSN, _ = walEFinishLSN(fields.WalSegmentBackupStop)
+	}
+	if backup.SentinelDto.UncompressedSize == 0 {
+		backup.SentinelDto.UncompressedSize = fields.ExpandedSizeBytes
+	}
+
 	return nil
 }
 
@@ -289,8 +311,7 @@ func (backup *Backup) unwrapOld(
 	}
 
 	if needPgControl {
-		err = internal.ExtractAll(tarInterpreter, []internal.ReaderMaker{
-			internal.NewStorageReaderMaker(backup.getTarPartitionFolder(), pgControlKey)})
+		err = internal.ExtractAll(tarInterpreter, []internal.ReaderMaker{backup.newTarReaderMaker(pgControlKey)})
 		if err != nil {
 			return errors.Wrap(err, "failed to extract pg_control")
 		}
@@ -0,0 +1,245 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/printlist"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+type FileChangeType string
+
+const (
+	FileAdded   FileChangeType = "added"
+	FileRemoved FileChangeType = "removed"
+	FileChanged FileChangeType = "changed"
+)
+
+// FileDiff is the difference of the file between two backups. The sizes are zero if the backups were taken
+// by the WAL-G versions which did not record them. ChangedBlocks is known only if the second backup
+// is a delta from the first one and the file is stored as an increment.
+type FileDiff struct {
+	Path          string         `json:"path"`
+	Change        FileChangeType `json:"change"`
+	SizeA         int64          `json:"size_a"`
+	SizeB         int64          `json:"size_b"`
+	ChangedBlocks *int64         `json:"changed_blocks,omitempty"`
+}
+
+func (diff FileDiff) PrintableFields() []printlist.TableField {
+	changedBlocks := ""
+	if diff.ChangedBlocks != nil {
+		changedBlocks = strconv.FormatInt(*diff.ChangedBlocks, 10)
+	}
+	return []printlist.TableField{
+		{
+			Name:       "change",
+			PrettyName: "Change",
+			Value:      string(diff.Change),
+		},
+		{
+			Name:       "path",
+			PrettyName: "Path",
+			Value:      diff.Path,
+		},
+		{
+			Name:       "size_a",
+			PrettyName: "Size A",
+			Value:      strconv.FormatInt(diff.SizeA, 10),
+		},
+		{
+			Name:       "size_b",
+			PrettyName: "Size B",
+			Value:      strconv.FormatInt(diff.SizeB, 10),
+		},
+		{
+			Name:       "changed_blocks",
+			PrettyName: "Changed blocks",
+			Value:      changedBlocks,
+		},
+	}
+}
+
+// BackupDiffSummary sums up the data churn between the backups
+type BackupDiffSummary struct {
+	BackupA        string `json:"backup_a"`
+	BackupB        string `json:"backup_b"`
+	FilesAdded     int    `json:"files_added"`
+	FilesRemoved   int    `json:"files_removed"`
+	FilesChanged   int    `json:"files_changed"`
+	FilesUnchanged int    `json:"files_unchanged"`
+	AddedBytes     int64  `json:"added_bytes"`
+	RemovedBytes   int64  `json:"removed_bytes"`
+	// ChangedBytes is the size in the second backup of the changed files
+	ChangedBytes  int64 `json:"changed_bytes"`
+	SizeDelta     int64 `json:"size_delta"`
+	ChangedBlocks int64 `json:"changed_blocks"`
+
+	UncompressedSizeA int64 `json:"uncompressed_size_a"`
+	UncompressedSizeB int64 `json:"uncompressed_size_b"`
+	CompressedSizeA   int64 `json:"compressed_size_a"`
+	CompressedSizeB   int64 `json:"compressed_size_b"`
+}
+
+type BackupDiff struct {
+	Summary BackupDiffSummary `json:"summary"`
+	Files   []FileDiff        `json:"files"`
+}
+
+// HandleBackupDiff prints the files added, removed and changed in the second backup compared to the first one
+func HandleBackupDiff(folder storage.Folder, nameA, nameB string, pretty, json, summaryOnly bool) {
+	diff, err := GetBackupDiff(folder, nameA, nameB)
+	tracelog.ErrorLogger.FatalOnError(err)
+	if summaryOnly {
+		diff.Files = nil
+	}
+	err = printBackupDiff(diff, os.Stdout, pretty, json)
+	tracelog.ErrorLogger.FatalfOnError("Print backup diff: %v", err)
+}
+
+func GetBackupDiff(folder storage.Folder, nameA, nameB string) (*BackupDiff, error) {
+	sentinelA, filesA, backupA, err := fetchBackupFiles(folder, nameA)
+	if err != nil {
+		return nil, err
+	}
+	sentinelB, filesB, backupB, err := fetchBackupFiles(folder, nameB)
+	if err != nil {
+		return nil, err
+	}
+
+	// the increments of the delta backup hold exactly the blocks changed since the backup it is based on
+	isDeltaFromA := sentinelB.IncrementFrom != nil && *sentinelB.IncrementFrom == backupA
+	diff := DiffBackupFiles(filesA, filesB, isDeltaFromA)
+	diff.Summary.BackupA = backupA
+	diff.Summary.BackupB = backupB
+	diff.Summary.UncompressedSizeA = sentinelA.UncompressedSize
+	diff.Summary.UncompressedSizeB = sentinelB.UncompressedSize
+	diff.Summary.CompressedSizeA = sentinelA.CompressedSize
+	diff.Summary.CompressedSizeB = sentinelB.CompressedSize
+	return diff, nil
+}
+
+func fetchBackupFiles(folder storage.Folder, name string) (BackupSentinelDto, internal.BackupFileList, string, error) {
+	genericBackup, err := internal.GetBackupByName(name, "", folder)
+	if err != nil {
+		return BackupSentinelDto{}, nil, "", errors.Wrapf(err, "failed to find backup %s", name)
+	}
+	backup := ToPgBackup(genericBackup)
+	sentinel, filesMetadata, err := backup.GetSentinelAndFilesMetadata()
+	if err != nil {
+		return BackupSentinelDto{}, nil, "", errors.Wrapf(err, "failed to fetch metadata of backup %s", backup.Name)
+	}
+	if sentinel.FilesMetadataDisabled || len(filesMetadata.Files) == 0 {
+		return BackupSentinelDto{}, nil, "", fmt.Errorf("backup %s has no files metadata to compare", backup.Name)
+	}
+	return sentinel, filesMetadata.Files, backup.Name, nil
+}
+
+// DiffBackupFiles compares the files of two backups. The file is considered changed if its size
+// or modification time differs, except the files skipped in the delta backup. The changed blocks
+// are counted from the increments of the second backup if it is a delta from the first one.
+func DiffBackupFiles(filesA, filesB internal.BackupFileList, isDeltaFromA bool) *BackupDiff {
+	diff := &BackupDiff{Files: make([]FileDiff, 0)}
+	summary := &diff.Summary
+	sizesRecorded := hasSizes(filesA) && hasSizes(filesB)
+	for path, descA := range filesA {
+		if _, ok := filesB[path]; !ok {
+			diff.Files = append(diff.Files, FileDiff{Path: path, Change: FileRemoved, SizeA: descA.Size})
+			summary.FilesRemoved++
+			summary.RemovedBytes += descA.Size
+		}
+	}
+	for path, descB := range filesB {
+		summary.SizeDelta += descB.Size
+		descA, ok := filesA[path]
+		if !ok {
+			diff.Files = append(diff.Files, FileDiff{Path: path, Change: FileAdded, SizeB: descB.Size})
+			summary.FilesAdded++
+			summary.AddedBytes += descB.Size
+			continue
+		}
+		if !isFileChanged(descA, descB, isDeltaFromA, sizesRecorded) {
+			summary.FilesUnchanged++
+			continue
+		}
+		fileDiff := FileDiff{Path: path, Change: FileChanged, SizeA: descA.Size, SizeB: descB.Size}
+		if isDeltaFromA && descB.IsIncremented && descB.IncrementSize > 0 {
+			changedBlocks := IncrementBlockCount(descB.IncrementSize)
+			fileDiff.ChangedBlocks = &changedBlocks
+			summary.ChangedBlocks += changedBlocks
+		}
+		diff.Files = append(diff.Files, fileDiff)
+		summary.FilesChanged++
+		summary.ChangedBytes += descB.Size
+	}
+	for _, descA := range filesA {
+		summary.SizeDelta -= descA.Size
+	}
+	sort.Slice(diff.Files, func(i, j int) bool {
+		return diff.Files[i].Path < diff.Files[j].Path
+	})
+	return diff
+}
+
+// isFileChanged ignores the modification time of the entries without the size, the directories and the empty files,
+// since it changes without any data churn. The backups taken by the WAL-G versions which did not record the sizes
+// are compared by the modification time only.
+func isFileChanged(descA, descB internal.BackupFileDescription, isDeltaFromA, sizesRecorded bool) bool {
+	if isDeltaFromA && descB.IsSkipped {
+		return false
+	}
+	if !sizesRecorded {
+		return !descA.MTime.Equal(descB.MTime)
+	}
+	return descA.Size != descB.Size || descB.Size > 0 && !descA.MTime.Equal(descB.MTime)
+}
+
+func hasSizes(files internal.BackupFileList) bool {
+	for _, desc := range files {
+		if desc.Size > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func printBackupDiff(diff *BackupDiff, output io.Writer, pretty, isJSON bool) error {
+	if isJSON {
+		encoder := json.NewEncoder(output)
+		if pretty {
+			encoder.SetIndent("", "    ")
+		}
+		return encoder.Encode(diff)
+	}
+
+	summary := diff.Summary
+	_, err := fmt.Fprintf(output, "Backup A: %s\nBackup B: %s\n"+
+		"Files added: %d (%d bytes)\nFiles removed: %d (%d bytes)\nFiles changed: %d (%d bytes, %d changed blocks)\n"+
+		"Files unchanged: %d\nSize delta: %d bytes\n"+
+		"Uncompressed size: %d -> %d bytes\nCompressed size: %d -> %d bytes\n",
+		summary.BackupA, summary.BackupB,
+		summary.FilesAdded, summary.AddedBytes, summary.FilesRemoved, summary.RemovedBytes,
+		summary.FilesChanged, summary.ChangedBytes, summary.ChangedBlocks,
+		summary.FilesUnchanged, summary.SizeDelta,
+		summary.UncompressedSizeA, summary.UncompressedSizeB, summary.CompressedSizeA, summary.CompressedSizeB)
+	if err != nil || len(diff.Files) == 0 {
+		return err
+	}
+	_, err = fmt.Fprintln(output)
+	if err != nil {
+		return err
+	}
+	entities := make([]printlist.Entity, len(diff.Files))
+	for i := range diff.Files {
+		entities[i] = diff.Files[i]
+	}
+	return printlist.List(entities, output, pretty, false)
+}
@@ -0,0 +1,96 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+var (
+	diffTimeA = time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	diffTimeB = time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)
+)
+
+func TestDiffBackupFiles(t *testing.T) {
+	filesA := internal.BackupFileList{
+		"/base":            {MTime: diffTimeA},
+		"/base/5/16384":    {MTime: diffTimeA, Size: 8192},
+		"/base/5/16385":    {MTime: diffTimeA, Size: 16384},
+		"/base/5/16386":    {MTime: diffTimeA, Size: 8192},
+		"/global/1262":     {MTime: diffTimeA, Size: 8192},
+		"/postgresql.conf": {MTime: diffTimeA, Size: 100},
+	}
+	filesB := internal.BackupFileList{
+		"/base":            {MTime: diffTimeB},
+		"/base/5/16384":    {MTime: diffTimeB, Size: 8192},
+		"/base/5/16385":    {MTime: diffTimeB, Size: 24576},
+		"/base/5/16387":    {MTime: diffTimeB, Size: 8192},
+		"/global/1262":     {MTime: diffTimeA, Size: 8192},
+		"/postgresql.conf": {MTime: diffTimeA, Size: 100},
+	}
+
+	diff := postgres.DiffBackupFiles(filesA, filesB, false)
+
+	require.Len(t, diff.Files, 4)
+	assert.Equal(t, postgres.FileDiff{Path: "/base/5/16384", Change: postgres.FileChanged, SizeA: 8192, SizeB: 8192}, diff.Files[0])
+	assert.Equal(t, postgres.FileDiff{Path: "/base/5/16385", Change: postgres.FileChanged, SizeA: 16384, SizeB: 24576}, diff.Files[1])
+	assert.Equal(t, postgres.FileDiff{Path: "/base/5/16386", Change: postgres.FileRemoved, SizeA: 8192}, diff.Files[2])
+	assert.Equal(t, postgres.FileDiff{Path: "/base/5/16387", Change: postgres.FileAdded, SizeB: 8192}, diff.Files[3])
+
+	summary := diff.Summary
+	assert.Equal(t, 1, summary.FilesAdded)
+	assert.Equal(t, 1, summary.FilesRemoved)
+	assert.Equal(t, 2, summary.FilesChanged)
+	assert.Equal(t, 3, summary.FilesUnchanged)
+	assert.Equal(t, int64(8192), summary.AddedBytes)
+	assert.Equal(t, int64(8192), summary.RemovedBytes)
+	assert.Equal(t, int64(32768), summary.ChangedBytes)
+	assert.Equal(t, int64(8192), summary.SizeDelta)
+	assert.Zero(t, summary.ChangedBlocks)
+}
+
+func TestDiffBackupFiles_Delta(t *testing.T) {
+	incrementHeaderSize := int64(len(postgres.IncrementFileHeader)) + 8 + 4
+	filesA := internal.BackupFileList{
+		"/base/5/16384": {MTime: diffTimeA, Size: 81920},
+		"/base/5/16385": {MTime: diffTimeA, Size: 8192},
+	}
+	filesB := internal.BackupFileList{
+		"/base/5/16384": {MTime: diffTimeB, Size: 81920, IsIncremented: true,
+			IncrementSize: incrementHeaderSize + 3*(4+postgres.DatabasePageSize)},
+		"/base/5/16385": {MTime: diffTimeA, Size: 8192, IsSkipped: true},
+	}
+
+	diff := postgres.DiffBackupFiles(filesA, filesB, true)
+
+	require.Len(t, diff.Files, 1)
+	require.NotNil(t, diff.Files[0].ChangedBlocks)
+	assert.Equal(t, int64(3), *diff.Files[0].ChangedBlocks)
+	assert.Equal(t, int64(3), diff.Summary.ChangedBlocks)
+	assert.Equal(t, 1, diff.Summary.FilesUnchanged)
+
+	diff = postgres.DiffBackupFiles(filesA, filesB, false)
+	require.Len(t, diff.Files, 1)
+	assert.Nil(t, diff.Files[0].ChangedBlocks)
+}
+
+func TestDiffBackupFiles_WithoutSizes(t *testing.T) {
+	filesA := internal.BackupFileList{
+		"/base/5/16384": {MTime: diffTimeA},
+		"/base/5/16385": {MTime: diffTimeA},
+	}
+	filesB := internal.BackupFileList{
+		"/base/5/16384": {MTime: diffTimeB},
+		"/base/5/16385": {MTime: diffTimeA},
+	}
+
+	diff := postgres.DiffBackupFiles(filesA, filesB, false)
+
+	require.Len(t, diff.Files, 1)
+	assert.Equal(t, "/base/5/16384", diff.Files[0].Path)
+	assert.Equal(t, postgres.FileChanged, diff.Files[0].Change)
+}
@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+)
+
+const (
+	defaultTablespaceName = "pg_default"
+	globalTablespaceName  = "pg_global"
+)
+
+// BackupExclusions holds the databases (WALG_EXCLUDE_DATABASES) and the tablespaces (WALG_EXCLUDE_TABLESPACES)
+// which are intentionally omitted from the backup. The names are resolved to oids with the catalog
+// when the backup starts. The directories of the excluded objects are kept in the backup, so the restored
+// cluster has the same layout, but the files in them are not.
+type BackupExclusions struct {
+	Databases   map[string]uint32 `json:"Databases,omitempty"`
+	Tablespaces map[string]uint32 `json:"Tablespaces,omitempty"`
+}
+
+// resolveBackupExclusions returns nil if nothing is configured to be excluded
+func resolveBackupExclusions(queryRunner *PgQueryRunner) (*BackupExclusions, error) {
+	databaseNames := getExcludedNames(conf.PgExcludeDatabases)
+	tablespaceNames := getExcludedNames(conf.PgExcludeTablespaces)
+	if len(databaseNames) == 0 && len(tablespaceNames) == 0 {
+		return nil, nil
+	}
+
+	exclusions := &BackupExclusions{
+		Databases:   make(map[string]uint32),
+		Tablespaces: make(map[string]uint32),
+	}
+	if len(databaseNames) > 0 {
+		databaseOids, err := queryRunner.getDatabaseOids()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to resolve the excluded databases")
+		}
+		resolveExcludedNames(exclusions.Databases, databaseNames, databaseOids, "database")
+	}
+	if len(tablespaceNames) > 0 {
+		for _, name := range tablespaceNames {
+			if name == defaultTablespaceName || name == globalTablespaceName {
+				return nil, fmt.Errorf("tablespace %s can't be excluded from the backup", name)
+			}
+		}
+		tablespaceOids, err := queryRunner.getTablespaceOids()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to resolve the excluded tablespaces")
+		}
+		resolveExcludedNames(exclusions.Tablespaces, tablespaceNames, tablespaceOids, "tablespace")
+	}
+	return exclusions, nil
+}
+
+func getExcludedNames(setting string) []string {
+	value, ok := conf.GetSetting(setting)
+	if !ok {
+		return nil
+	}
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func resolveExcludedNames(resolved map[string]uint32, names []string, oids map[string]uint32, kind string) {
+	for _, name := range names {
+		oid, ok := oids[name]
+		if !ok {
+			tracelog.WarningLogger.Printf("The %s %s to exclude from the backup is not found\n", kind, name)
+			continue
+		}
+		tracelog.InfoLogger.Printf("Excluding the files of %s %s (oid %d) from the backup\n", kind, name, oid)
+		resolved[name] = oid
+	}
+}
+
+// excludesFile tells if the file with the path relative to PGDATA belongs to an excluded database or tablespace
+func (exclusions *BackupExclusions) excludesFile(relPath string) bool {
+	if exclusions == nil {
+		return false
+	}
+	parts := strings.Split(strings.TrimLeft(filepath.ToSlash(relPath), "/"), "/")
+	switch {
+	case len(parts) > 2 && parts[0] == DefaultTablespace:
+		// base/<database oid>/<file>
+		return containsOid(exclusions.Databases, parts[1])
+	case len(parts) > 2 && parts[0] == NonDefaultTablespace:
+		// pg_tblspc/<tablespace oid>/<version directory>/<database oid>/<file>
+		if containsOid(exclusions.Tablespaces, parts[1]) {
+			return true
+		}
+		return len(parts) > 4 && containsOid(exclusions.Databases, parts[3])
+	}
+	return false
+}
+
+func containsOid(oids map[string]uint32, oidStr string) bool {
+	oid, err := strconv.ParseUint(oidStr, 10, 32)
+	if err != nil {
+		return false
+	}
+	for _, excludedOid := range oids {
+		if excludedOid == uint32(oid) {
+			return true
+		}
+	}
+	return false
+}
+
+// logExcludedObjects reminds that the objects excluded from the backup are intentionally absent after the restore
+func logExcludedObjects(backupName string, exclusions *BackupExclusions) {
+	if exclusions == nil {
+		return
+	}
+	for name := range exclusions.Databases {
+		tracelog.WarningLogger.Printf("Database %s was excluded from backup %s, "+
+			"its files are intentionally absent: drop and recreate it after the restore\n", name, backupName)
+	}
+	for name := range exclusions.Tablespaces {
+		tracelog.WarningLogger.Printf("Tablespace %s was excluded from backup %s, "+
+			"its files are intentionally absent: rebuild its objects after the restore\n", name, backupName)
+	}
+}
@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackupExclusions_ExcludesFile(t *testing.T) {
+	exclusions := &BackupExclusions{
+		Databases:   map[string]uint32{"scratch": 16384},
+		Tablespaces: map[string]uint32{"ephemeral": 16390},
+	}
+
+	assert.True(t, exclusions.excludesFile("/base/16384/16401"))
+	assert.True(t, exclusions.excludesFile("/base/16384/PG_VERSION"))
+	assert.True(t, exclusions.excludesFile("/pg_tblspc/16390/PG_16_202307071/5/16500"))
+	assert.True(t, exclusions.excludesFile("/pg_tblspc/16391/PG_16_202307071/16384/16500"))
+
+	assert.False(t, exclusions.excludesFile("/base/16385/16401"))
+	assert.False(t, exclusions.excludesFile("/base/16384"))
+	assert.False(t, exclusions.excludesFile("/global/16384"))
+	assert.False(t, exclusions.excludesFile("/pg_tblspc/16391/PG_16_202307071/16385/16500"))
+	assert.False(t, exclusions.excludesFile("/pg_tblspc/16391/PG_16_202307071"))
+}
+
+func TestBackupExclusions_Nil(t *testing.T) {
+	var exclusions *BackupExclusions
+	assert.False(t, exclusions.excludesFile("/base/16384/16401"))
+}
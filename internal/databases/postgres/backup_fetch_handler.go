@@ -70,6 +70,11 @@ func deltaFetchRecursionOld(backup Backup, rootFolder storage.Folder, dbDataDire
 	}
 	tablespaceSpec = chooseTablespaceSpecification(sentinelDto.TablespaceSpec, tablespaceSpec)
 	sentinelDto.TablespaceSpec = tablespaceSpec
+	if err = checkFormatFeatures(backup.Name, sentinelDto); err != nil {
+		return err
+	}
+	rememberWalEnvelope(sentinelDto)
+	logExcludedObjects(backup.Name, sentinelDto.ExcludedObjects)
 
 	if sentinelDto.IsIncremental() {
 		tracelog.InfoLogger.Printf("Delta from %v at LSN %s \n", *(sentinelDto.IncrementFrom),
@@ -86,6 +91,7 @@ func deltaFetchRecursionOld(backup Backup, rootFolder storage.Folder, dbDataDire
 		if err != nil {
 			return err
 		}
+		incrementFrom.RestoreReport = backup.RestoreReport
 		err = deltaFetchRecursionOld(incrementFrom, rootFolder, dbDataDirectory, tablespaceSpec, baseFilesToUnwrap, extractProv)
 		if err != nil {
 			return err
@@ -58,12 +58,18 @@ func deltaFetchRecursionNew(cfg *FetchConfig) error {
 	if err != nil {
 		return err
 	}
+	backup.RestoreReport = cfg.backup.RestoreReport
 	sentinelDto, filesMetaDto, err := backup.GetSentinelAndFilesMetadata()
 	if err != nil {
 		return err
 	}
 	cfg.tablespaceSpec = chooseTablespaceSpecification(sentinelDto.TablespaceSpec, cfg.tablespaceSpec)
 	sentinelDto.TablespaceSpec = cfg.tablespaceSpec
+	if err = checkFormatFeatures(backup.Name, sentinelDto); err != nil {
+		return err
+	}
+	rememberWalEnvelope(sentinelDto)
+	logExcludedObjects(backup.Name, sentinelDto.ExcludedObjects)
 
 	if sentinelDto.IsIncremental() {
 		tracelog.InfoLogger.Printf("Delta %v at LSN %s \n",
@@ -9,7 +9,7 @@ import (
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 )
 
-func HandleDetailedBackupList(folder storage.Folder, pretty bool, json bool) {
+func HandleDetailedBackupList(folder storage.Folder, filter internal.UserDataFilter, pretty bool, json bool) {
 	backups, err := internal.GetBackups(folder)
 	if len(backups) == 0 {
 		tracelog.InfoLogger.Println("No backups found")
@@ -17,6 +17,9 @@ func HandleDetailedBackupList(folder storage.Folder, pretty bool, json bool) {
 	}
 	tracelog.ErrorLogger.FatalfOnError("Get backups from folder: %v", err)
 
+	backups, err = internal.FilterBackupsByUserData(folder, backups, filter, NewGenericMetaFetcher())
+	tracelog.ErrorLogger.FatalfOnError("Filter backups by user data: %v", err)
+
 	backupDetails, err := GetBackupsDetails(folder, backups)
 	tracelog.ErrorLogger.FatalOnError(err)
 
@@ -41,7 +41,7 @@ func TestHandleDetailedBackupList(t *testing.T) {
 		os.Stdout = w
 		defer func() { os.Stdout = rescueStdout }()
 
-		HandleDetailedBackupList(folder, true, true)
+		HandleDetailedBackupList(folder, nil, true, true)
 
 		_ = w.Close()
 		captured, _ := io.ReadAll(r)
@@ -136,7 +136,7 @@ func TestHandleDetailedBackupList(t *testing.T) {
 		os.Stdout = w
 		defer func() { os.Stdout = rescueStdout }()
 
-		HandleDetailedBackupList(multiFolder, true, true)
+		HandleDetailedBackupList(multiFolder, nil, true, true)
 
 		_ = w.Close()
 		captured, _ := io.ReadAll(r)
@@ -196,7 +196,7 @@ func TestHandleDetailedBackupList(t *testing.T) {
 		os.Stdout = w
 		defer func() { os.Stdout = rescueStdout }()
 
-		HandleDetailedBackupList(folder, true, false)
+		HandleDetailedBackupList(folder, nil, true, false)
 
 		_ = w.Close()
 		captured, _ := io.ReadAll(r)
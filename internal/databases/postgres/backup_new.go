@@ -86,10 +86,13 @@ func (backup *Backup) unwrapNew(
 	needPgControl := IsPgControlRequired(*backup)
 
 	if pgControlKey == "" && needPgControl {
+		backup.RestoreReport.AddVerification("pg_control of "+backup.Name, newPgControlNotFoundError())
 		return nil, newPgControlNotFoundError()
 	}
 
+	finishExtract := backup.RestoreReport.StartPhase("extract " + backup.Name)
 	err = internal.ExtractAll(tarInterpreter, tarsToExtract)
+	finishExtract()
 	if _, ok := err.(internal.NoFilesToExtractError); ok {
 		// in case of no tars to extract, just ignore this backup and proceed to the next
 		tracelog.InfoLogger.Println("Skipping backup: no useful files found.")
@@ -100,8 +103,9 @@ func (backup *Backup) unwrapNew(
 	}
 
 	if needPgControl {
-		readerMakers := []internal.ReaderMaker{internal.NewStorageReaderMaker(backup.getTarPartitionFolder(), pgControlKey)}
+		readerMakers := []internal.ReaderMaker{backup.newTarReaderMaker(pgControlKey)}
 		err = internal.ExtractAll(tarInterpreter, readerMakers)
+		backup.RestoreReport.AddVerification("pg_control of "+backup.Name, err)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to extract pg_control")
 		}
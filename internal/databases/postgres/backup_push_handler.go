@@ -1,7 +1,6 @@
 package postgres
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -77,6 +76,7 @@ type CurBackupInfo struct {
 	compressedSize   int64
 	dataCatalogSize  int64
 	incrementCount   int
+	exclusions       *BackupExclusions
 }
 
 func NewPrevBackupInfo(name string, sentinel BackupSentinelDto, filesMeta FilesMetadataDto) PrevBackupInfo {
@@ -147,6 +147,7 @@ func (ba *BackupArguments) EnablePreventConcurrentBackups() {
 func (bh *BackupHandler) createAndPushBackup(ctx context.Context) {
 	var err error
 	folder := bh.Arguments.Uploader.Folder()
+	globalsUploader := bh.Arguments.Uploader.Clone()
 	// TODO: AB: this subfolder switch look ugly.
 	// I think typed storage folders could be better (i.e. interface BasebackupStorageFolder, WalStorageFolder etc)
 	bh.Arguments.Uploader.ChangeDirectory(bh.Arguments.backupsFolder)
@@ -160,12 +161,19 @@ func (bh *BackupHandler) createAndPushBackup(ctx context.Context) {
 
 	err = bh.startBackup()
 	tracelog.ErrorLogger.FatalOnError(err)
+	incompleteBackup, err := internal.MarkBackupIncomplete(folder, bh.Arguments.backupsFolder, bh.CurBackupInfo.Name)
+	tracelog.ErrorLogger.FatalfOnError("Failed to upload the incomplete backup marker: %v", err)
 	bh.handleDeltaBackup(folder)
 	tarFileSets := bh.uploadBackup()
 	sentinelDto, filesMetaDto, err := bh.setupDTO(tarFileSets)
 	tracelog.ErrorLogger.FatalOnError(err)
 	bh.markBackups(folder, sentinelDto)
+	bh.uploadGlobals(ctx, globalsUploader)
 	bh.uploadMetadata(ctx, sentinelDto, filesMetaDto)
+	err = incompleteBackup.Complete()
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to remove the incomplete backup marker: %v", err)
+	}
 
 	storageNames := multistorage.UsedStorages(folder)
 	if len(storageNames) == 0 {
@@ -218,6 +226,12 @@ func (bh *BackupHandler) startBackup() error {
 		}
 	}
 
+	bh.CurBackupInfo.exclusions, err = resolveBackupExclusions(bh.Workers.QueryRunner)
+	if err != nil {
+		return err
+	}
+	bh.Workers.Bundle.Exclusions = bh.CurBackupInfo.exclusions
+
 	tracelog.DebugLogger.Println("Running StartBackup.")
 	backupName, backupStartLSN, err := bh.Workers.Bundle.StartBackup(
 		bh.Workers.QueryRunner, utility.CeilTimeUpToMicroseconds(time.Now()).String())
@@ -270,6 +284,7 @@ func (bh *BackupHandler) setupDTO(tarFileSets internal.TarFileSets) (sentinelDto
 		tablespaceSpec = &bh.Workers.Bundle.TablespaceSpec
 	}
 	sentinelDto = NewBackupSentinelDto(bh, tablespaceSpec)
+	sentinelDto.FormatFeatures = backupFormatFeatures(bh.Workers.Bundle.usedFeatures.list())
 	filesMeta.setFiles(bh.Workers.Bundle.GetFiles())
 	filesMeta.TarFileSets = tarFileSets.Get()
 	filesMeta.DatabasesByNames, err = bh.collectDatabaseNamesMetadata()
@@ -359,6 +374,9 @@ func (bh *BackupHandler) uploadBackup() internal.TarFileSets {
 // HandleBackupPush handles the backup being read from Postgres or filesystem and being pushed to the repository
 // TODO : unit tests
 func (bh *BackupHandler) HandleBackupPush(ctx context.Context) {
+	err := CheckPatroniBeforeBackup(ctx)
+	tracelog.ErrorLogger.FatalOnError(err)
+
 	bh.CurBackupInfo.StartTime = utility.TimeNowCrossPlatformUTC()
 
 	if bh.Arguments.pgDataDirectory == "" {
@@ -377,6 +395,10 @@ func (bh *BackupHandler) handleBackupPushRemote(ctx context.Context) {
 	tracelog.InfoLogger.Println("Running remote backup through Postgres connection.")
 	tracelog.InfoLogger.Println("Features like delta backup and partial restore are disabled, there might be a performance impact.")
 	tracelog.InfoLogger.Println("To run with local backup functionalities, supply [db_directory].")
+	if len(getExcludedNames(conf.PgExcludeDatabases)) > 0 || len(getExcludedNames(conf.PgExcludeTablespaces)) > 0 {
+		tracelog.WarningLogger.Println("Excluding databases and tablespaces is not supported for remote backup, " +
+			"all of them will be backed up.")
+	}
 	if bh.PgInfo.PgVersion < 110000 && !bh.Arguments.verifyPageChecksums {
 		tracelog.InfoLogger.Println("VerifyPageChecksums=false is only supported for streaming backup since PG11")
 		bh.Arguments.verifyPageChecksums = true
@@ -399,12 +421,17 @@ func (bh *BackupHandler) handleBackupPushLocal(ctx context.Context) {
 	baseBackupFolder := folder.GetSubFolder(bh.Arguments.backupsFolder)
 	tracelog.DebugLogger.Printf("Base backup folder: %s", baseBackupFolder.GetPath())
 
+	// the files of the previous attempts of this host that failed partway are not referenced by anything
+	err := internal.CleanupIncompleteBackups(folder, true, true)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to clean up incomplete backups: %v", err)
+	}
+
 	bh.checkPgVersionAndPgControl()
 
 	if bh.Arguments.isFullBackup {
 		tracelog.InfoLogger.Println("Doing full backup.")
 	} else {
-		var err error
 		bh.prevBackupInfo, bh.CurBackupInfo.incrementCount, err = bh.Arguments.deltaConfigurator.Configure(
 			folder, bh.Arguments.isPermanent)
 		tracelog.ErrorLogger.FatalOnError(err)
@@ -416,6 +443,7 @@ func (bh *BackupHandler) handleBackupPushLocal(ctx context.Context) {
 func (bh *BackupHandler) createAndPushRemoteBackup(ctx context.Context) {
 	var err error
 	uploader := bh.Arguments.Uploader
+	globalsUploader := uploader.Clone()
 	uploader.ChangeDirectory(utility.BaseBackupPath)
 	tracelog.DebugLogger.Printf("Uploading folder: %s", uploader.Folder())
 
@@ -437,6 +465,7 @@ func (bh *BackupHandler) createAndPushRemoteBackup(ctx context.Context) {
 	sentinelDto := NewBackupSentinelDto(bh, baseBackup.GetTablespaceSpec())
 	filesMetadataDto := NewFilesMetadataDto(baseBackup.Files, tarFileSets)
 	bh.CurBackupInfo.Name = baseBackup.BackupName()
+	bh.uploadGlobals(ctx, globalsUploader)
 	tracelog.InfoLogger.Println("Uploading metadata")
 	bh.uploadMetadata(ctx, sentinelDto, filesMetadataDto)
 	// logging backup set Name
@@ -448,18 +477,42 @@ func (bh *BackupHandler) uploadMetadata(ctx context.Context, sentinelDto BackupS
 	meta := NewExtendedMetadataDto(bh.Arguments.isPermanent, bh.PgInfo.PgDataDirectory,
 		bh.CurBackupInfo.StartTime, sentinelDto)
 
+	// the spooled objects are uploaded later in the same order, so the rest of them is spooled too
+	var spooledErr error
 	err := bh.uploadExtendedMetadata(ctx, meta)
+	if internal.IsMetadataSpooled(err) {
+		spooledErr, err = err, nil
+	}
 	if err != nil {
 		tracelog.ErrorLogger.Fatalf("Failed to upload metadata file for backup %s: %v", curBackupName, err)
 	}
 	err = bh.uploadFilesMetadata(ctx, filesMetaDto)
+	if internal.IsMetadataSpooled(err) {
+		spooledErr, err = err, nil
+	}
 	if err != nil {
 		tracelog.ErrorLogger.Fatalf("Failed to upload files metadata for backup %s: %v", curBackupName, err)
 	}
 	err = internal.UploadSentinel(bh.Arguments.Uploader, NewBackupSentinelDtoV2(sentinelDto, meta), bh.CurBackupInfo.Name)
+	if internal.IsMetadataSpooled(err) {
+		spooledErr, err = err, nil
+	}
 	if err != nil {
 		tracelog.ErrorLogger.Fatalf("Failed to upload sentinel file for backup %s: %v", curBackupName, err)
 	}
+	if spooledErr != nil {
+		tracelog.ErrorLogger.Fatalf("Backup %s is not visible until its metadata is uploaded: %v", curBackupName, spooledErr)
+	}
+}
+
+// uploadGlobals uploads the dump of the global objects along with the backup if WALG_BACKUP_GLOBALS is enabled
+func (bh *BackupHandler) uploadGlobals(ctx context.Context, uploader internal.Uploader) {
+	if !viper.GetBool(conf.PgBackupGlobalsSetting) {
+		return
+	}
+	tracelog.InfoLogger.Println("Uploading the global objects")
+	err := UploadGlobals(ctx, uploader, bh.CurBackupInfo.Name)
+	tracelog.ErrorLogger.FatalfOnError("Failed to upload the global objects: %v", err)
 }
 
 func (bh *BackupHandler) collectDatabaseNamesMetadata() (DatabasesByNames, error) {
@@ -600,7 +653,7 @@ func (bh *BackupHandler) uploadExtendedMetadata(ctx context.Context, meta Extend
 		return internal.NewSentinelMarshallingError(metaFile, err)
 	}
 	tracelog.DebugLogger.Printf("Uploading metadata file (%s):\n%s", metaFile, dtoBody)
-	return bh.Arguments.Uploader.Upload(ctx, metaFile, bytes.NewReader(dtoBody))
+	return internal.UploadDurably(ctx, bh.Arguments.Uploader.Folder(), metaFile, dtoBody)
 }
 
 func (bh *BackupHandler) uploadFilesMetadata(ctx context.Context, filesMetaDto FilesMetadataDto) (err error) {
@@ -613,7 +666,7 @@ func (bh *BackupHandler) uploadFilesMetadata(ctx context.Context, filesMetaDto F
 	if err != nil {
 		return err
 	}
-	return bh.Arguments.Uploader.Upload(ctx, getFilesMetadataPath(bh.CurBackupInfo.Name), bytes.NewReader(dtoBody))
+	return internal.UploadDurably(ctx, bh.Arguments.Uploader.Folder(), getFilesMetadataPath(bh.CurBackupInfo.Name), dtoBody)
 }
 
 func (bh *BackupHandler) checkPgVersionAndPgControl() {
@@ -629,6 +682,7 @@ func (bh *BackupHandler) initBackupTerminator() {
 	errCh := make(chan error, 1)
 
 	addSignalListener(errCh)
+	addInterruptionNoticeListener(errCh)
 	addPgIsAliveChecker(bh.Workers.QueryRunner, errCh)
 
 	terminator := NewBackupTerminator(bh.Workers.QueryRunner, bh.PgInfo.PgVersion, bh.PgInfo.PgDataDirectory)
@@ -651,6 +705,19 @@ func addSignalListener(errCh chan error) {
 	}()
 }
 
+// addInterruptionNoticeListener stops the backup cleanly when the instance is about to be interrupted,
+// instead of leaving it to be killed along with the instance
+func addInterruptionNoticeListener(errCh chan error) {
+	ctx, cancel := internal.WithInterruptionNotices(context.Background())
+	go func() {
+		defer cancel()
+		<-ctx.Done()
+		if noticeErr, ok := internal.GetInterruptionNotice(ctx); ok {
+			errCh <- noticeErr
+		}
+	}()
+}
+
 func addPgIsAliveChecker(queryRunner *PgQueryRunner, errCh chan error) {
 	if !viper.IsSet(conf.PgAliveCheckInterval) {
 		return
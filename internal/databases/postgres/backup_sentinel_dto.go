@@ -33,6 +33,15 @@ type BackupSentinelDto struct {
 	UserData interface{} `json:"UserData,omitempty"`
 
 	FilesMetadataDisabled bool `json:"FilesMetadataDisabled,omitempty"`
+
+	ExcludedObjects *BackupExclusions `json:"ExcludedObjects,omitempty"`
+
+	// WalgVersion is the version of WAL-G which made the backup, empty for the backups made before it was recorded
+	WalgVersion    string   `json:"WalgVersion,omitempty"`
+	FormatFeatures []string `json:"FormatFeatures,omitempty"`
+
+	// ScanFindings are the uploads flagged by WALG_UPLOAD_SCAN_COMMAND if WALG_UPLOAD_SCAN_ACTION is "tag"
+	ScanFindings []internal.UploadScanFinding `json:"ScanFindings,omitempty"`
 }
 
 func NewBackupSentinelDto(bh *BackupHandler, tbsSpec *TablespaceSpec) BackupSentinelDto {
@@ -41,6 +50,8 @@ func NewBackupSentinelDto(bh *BackupHandler, tbsSpec *TablespaceSpec) BackupSent
 		IncrementFromLSN: bh.prevBackupInfo.sentinelDto.BackupStartLSN,
 		PgVersion:        bh.PgInfo.PgVersion,
 		TablespaceSpec:   tbsSpec,
+		WalgVersion:      internal.WalgVersion,
+		FormatFeatures:   backupFormatFeatures(nil),
 	}
 	if bh.prevBackupInfo.sentinelDto.BackupStartLSN != nil {
 		sentinel.IncrementFrom = &bh.prevBackupInfo.name
@@ -59,6 +70,8 @@ func NewBackupSentinelDto(bh *BackupHandler, tbsSpec *TablespaceSpec) BackupSent
 	sentinel.CompressedSize = bh.CurBackupInfo.compressedSize
 	sentinel.DataCatalogSize = bh.CurBackupInfo.dataCatalogSize
 	sentinel.FilesMetadataDisabled = bh.Arguments.withoutFilesMetadata
+	sentinel.ExcludedObjects = bh.CurBackupInfo.exclusions
+	sentinel.ScanFindings = bh.Arguments.Uploader.UploadScanFindings()
 	return sentinel
 }
 
@@ -79,6 +92,9 @@ type ExtendedMetadataDto struct {
 	CompressedSize   int64 `json:"compressed_size"`
 
 	UserData interface{} `json:"user_data,omitempty"`
+
+	WalgVersion    string   `json:"walg_version,omitempty"`
+	FormatFeatures []string `json:"format_features,omitempty"`
 }
 
 func NewExtendedMetadataDto(isPermanent bool, dataDir string, startTime time.Time,
@@ -102,6 +118,8 @@ func NewExtendedMetadataDto(isPermanent bool, dataDir string, startTime time.Tim
 	meta.UserData = sentinelDto.UserData
 	meta.UncompressedSize = sentinelDto.UncompressedSize
 	meta.CompressedSize = sentinelDto.CompressedSize
+	meta.WalgVersion = sentinelDto.WalgVersion
+	meta.FormatFeatures = sentinelDto.FormatFeatures
 	return meta
 }
 
@@ -170,4 +188,8 @@ func NewBackupSentinelDtoV2(sentinel BackupSentinelDto, meta ExtendedMetadataDto
 type DeprecatedSentinelFields struct {
 	FilesMetadataDto
 	DeltaFromLSN *LSN `json:"DeltaFromLSN,omitempty"`
+
+	// WAL-E described the backup end by the WAL segment and the size by the expanded size
+	WalSegmentBackupStop string `json:"wal_segment_backup_stop,omitempty"`
+	ExpandedSizeBytes    int64  `json:"expanded_size_bytes,omitempty"`
 }
@@ -2,7 +2,6 @@ package postgres
 
 import (
 	"sort"
-	"time"
 
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
@@ -43,7 +42,7 @@ func GetBackupDetails(folder storage.Folder, backupTime internal.BackupTime) (Ba
 func SortBackupDetails(backupDetails []BackupDetail) {
 	sortOrder := ByCreationTime
 	for i := 0; i < len(backupDetails); i++ {
-		if (backupDetails[i].ExtendedMetadataDto == ExtendedMetadataDto{} || backupDetails[i].StartTime == time.Time{}) {
+		if backupDetails[i].StartTime.IsZero() {
 			sortOrder = ByModificationTime
 		}
 	}
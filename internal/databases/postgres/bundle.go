@@ -69,8 +69,10 @@ type Bundle struct {
 	DeltaMap           PagedFileDeltaMap
 	TablespaceSpec     TablespaceSpec
 	DataCatalogSize    *int64
+	Exclusions         *BackupExclusions
 
 	forceIncremental bool
+	usedFeatures     usedFormatFeatures
 }
 
 // TODO: use DiskDataFolder
@@ -258,6 +260,11 @@ func (bundle *Bundle) addToBundle(path string, info os.FileInfo) error {
 	fileInfoHeader.Name = bundle.GetFileRelPath(path)
 	tracelog.DebugLogger.Println(fileInfoHeader.Name)
 
+	if !isDir && bundle.Exclusions.excludesFile(fileInfoHeader.Name) {
+		tracelog.DebugLogger.Println("Skipped as the file of excluded database or tablespace: " + path)
+		return nil
+	}
+
 	if !excluded && info.Mode().IsRegular() {
 		baseFiles := bundle.getIncrementBaseFiles()
 		baseFile, wasInBase := baseFiles[fileInfoHeader.Name]
@@ -29,22 +29,25 @@ func (files *StatBundleFiles) AddFileWithCorruptBlocks(tarHeader *tar.Header,
 	updatesCount := files.fileStats.getFileUpdateCount(tarHeader.Name)
 	fileDescription := internal.BackupFileDescription{IsSkipped: false, IsIncremented: isIncremented, MTime: fileInfo.ModTime(),
 		UpdatesCount: updatesCount}
+	fileDescription.SetSizes(tarHeader, fileInfo)
 	fileDescription.SetCorruptBlocks(corruptedBlocks, storeAllBlocks)
 	files.AddFileDescription(tarHeader.Name, fileDescription)
 }
 
 func (files *StatBundleFiles) AddSkippedFile(tarHeader *tar.Header, fileInfo os.FileInfo) {
 	updatesCount := files.fileStats.getFileUpdateCount(tarHeader.Name)
-	files.AddFileDescription(tarHeader.Name,
-		internal.BackupFileDescription{IsSkipped: true, IsIncremented: false,
-			MTime: fileInfo.ModTime(), UpdatesCount: updatesCount})
+	fileDescription := internal.BackupFileDescription{IsSkipped: true, IsIncremented: false,
+		MTime: fileInfo.ModTime(), UpdatesCount: updatesCount}
+	fileDescription.SetSizes(tarHeader, fileInfo)
+	files.AddFileDescription(tarHeader.Name, fileDescription)
 }
 
 func (files *StatBundleFiles) AddFile(tarHeader *tar.Header, fileInfo os.FileInfo, isIncremented bool) {
 	updatesCount := files.fileStats.getFileUpdateCount(tarHeader.Name)
-	files.AddFileDescription(tarHeader.Name,
-		internal.BackupFileDescription{IsSkipped: false, IsIncremented: isIncremented,
-			MTime: fileInfo.ModTime(), UpdatesCount: updatesCount})
+	fileDescription := internal.BackupFileDescription{IsSkipped: false, IsIncremented: isIncremented,
+		MTime: fileInfo.ModTime(), UpdatesCount: updatesCount}
+	fileDescription.SetSizes(tarHeader, fileInfo)
+	files.AddFileDescription(tarHeader.Name, fileDescription)
 }
 
 func (files *StatBundleFiles) AddFileDescription(name string, backupFileDescription internal.BackupFileDescription) {
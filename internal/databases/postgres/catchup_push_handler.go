@@ -16,7 +16,7 @@ func extendExcludedFiles() {
 
 // HandleCatchupPush is invoked to perform a wal-g catchup-push
 func HandleCatchupPush(ctx context.Context, pgDataDirectory string, fromLSN LSN) {
-	uploader, err := internal.ConfigureUploader()
+	uploader, err := internal.ConfigureBackupUploader()
 	tracelog.ErrorLogger.FatalOnError(err)
 
 	pgDataDirectory = utility.ResolveSymlink(pgDataDirectory)
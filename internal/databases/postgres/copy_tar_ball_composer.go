@@ -52,6 +52,8 @@ type CopyTarBallComposer struct {
 	prevTarFileSets        internal.TarFileSets
 	fileInfo               map[string]*fileInfo
 	headerInfos            map[string]*headerInfo
+
+	usedFeatures *usedFormatFeatures
 }
 
 type CopyTarBallComposerMaker struct {
@@ -118,9 +120,14 @@ func (maker *CopyTarBallComposerMaker) Make(bundle *Bundle) (internal.TarBallCom
 	files := &internal.RegularBundleFiles{}
 	tarBallFilePacker := NewTarBallFilePacker(bundle.DeltaMap,
 		bundle.IncrementFromLsn, files, maker.filePackerOptions)
-	return NewCopyTarBallComposer(bundle.TarBallQueue, tarBallFilePacker, files,
+	composer, err := NewCopyTarBallComposer(bundle.TarBallQueue, tarBallFilePacker, files,
 		bundle.Crypter, maker.previousBackup, maker.newBackupName, tarUnchangedFilesCount,
 		prevFileTar, prevTarFileSets)
+	if err != nil {
+		return nil, err
+	}
+	composer.usedFeatures = &bundle.usedFeatures
+	return composer, nil
 }
 
 func (c *CopyTarBallComposer) AddFile(info *internal.ComposeFileInfo) {
@@ -176,6 +183,12 @@ func (c *CopyTarBallComposer) copyTar(tarName string) error {
 	if err != nil {
 		return err
 	}
+	// the copied tar keeps the format of the previous backup
+	if c.usedFeatures != nil {
+		for _, feature := range c.prevBackup.SentinelDto.FormatFeatures {
+			c.usedFeatures.add(feature)
+		}
+	}
 	for _, fileName := range c.prevTarFileSets.Get()[tarName] {
 		if file, exists := c.fileInfo[fileName]; exists {
 			file.status = processed
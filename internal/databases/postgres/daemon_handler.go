@@ -16,6 +16,7 @@ import (
 	"github.com/wal-g/wal-g/internal"
 	conf "github.com/wal-g/wal-g/internal/config"
 	"github.com/wal-g/wal-g/internal/daemon"
+	"github.com/wal-g/wal-g/internal/health"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 	"github.com/wal-g/wal-g/utility"
 )
@@ -198,6 +199,8 @@ func HandleDaemon(options DaemonOptions) {
 	if err != nil {
 		tracelog.ErrorLogger.Fatal("Error on listening socket:", err)
 	}
+	monitor, err := configureDaemonHealth()
+	tracelog.ErrorLogger.FatalfOnError("Failed to configure health checks: %v", err)
 
 	sdNotifyTicker := time.NewTicker(30 * time.Second)
 	defer sdNotifyTicker.Stop()
@@ -208,12 +211,13 @@ func HandleDaemon(options DaemonOptions) {
 		if err != nil {
 			tracelog.ErrorLogger.Fatal("Failed to accept, err:", err)
 		}
-		go Listen(context.Background(), fd)
+		go Listen(context.Background(), fd, monitor)
 	}
 }
 
-// Listen is used for listening connection and processing messages
-func Listen(ctx context.Context, c net.Conn) {
+// Listen is used for listening connection and processing messages,
+// the results of archiving are reported to the health monitor
+func Listen(ctx context.Context, c net.Conn, monitor *health.Monitor) {
 	defer utility.LoggedClose(c, fmt.Sprintf("Failed to close connection with %s \n", c.RemoteAddr()))
 	messageReader := NewMessageReader(c)
 	for {
@@ -224,10 +228,14 @@ func Listen(ctx context.Context, c net.Conn) {
 		}
 		err = handleMessage(ctx, messageType, messageBody, c)
 		if err != nil {
+			if messageType == daemon.WalPushType {
+				monitor.ReportError(err)
+			}
 			failAndLogError(c, err)
 			return
 		}
 		if messageType == daemon.WalPushType {
+			monitor.ReportSuccess()
 			tracelog.DebugLogger.Printf("successfully archived: %s\n", string(messageBody))
 			return
 		}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/health"
+	"github.com/wal-g/wal-g/internal/webserver"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// configureDaemonHealth exposes /healthz and /readyz reporting the state of the archiving if HTTP_EXPOSE_HEALTH is set.
+// The archiving lag is the age of the oldest WAL file waiting to be archived.
+func configureDaemonHealth() (*health.Monitor, error) {
+	enabled, err := conf.GetBoolSettingDefault(conf.HTTPExposeHealth, false)
+	if err != nil || !enabled {
+		return nil, err
+	}
+	maxLag, err := conf.GetDurationSetting(conf.HealthMaxLagSetting)
+	if err != nil {
+		return nil, err
+	}
+	archiveStatusPath, err := getFullPath(path.Join("pg_wal", archiveStatusDir))
+	if err != nil {
+		return nil, err
+	}
+	multiSt, err := ConfigureMultiStorage(true)
+	if err != nil {
+		return nil, errors.Wrap(err, "configure multi-storage for health checks")
+	}
+
+	monitor := health.NewMonitor(maxLag, func() (time.Duration, error) {
+		return archiveStatusLag(archiveStatusPath)
+	}, health.NewFolderCheck(multiSt.RootFolder().GetSubFolder(utility.WalPath)))
+	health.EnableHTTPHandlers(webserver.DefaultWebServer, monitor)
+	tracelog.InfoLogger.Printf("Exposing the archiving health at %s and %s", health.HealthzPattern, health.ReadyzPattern)
+	return monitor, nil
+}
+
+// archiveStatusLag returns how long the oldest WAL file marked as ready for archiving has been waiting
+func archiveStatusLag(archiveStatusPath string) (time.Duration, error) {
+	entries, err := os.ReadDir(archiveStatusPath)
+	if err != nil {
+		return 0, err
+	}
+	var oldest time.Time
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), readySuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if os.IsNotExist(err) {
+			// archived in the meantime
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if oldest.IsZero() || info.ModTime().Before(oldest) {
+			oldest = info.ModTime()
+		}
+	}
+	if oldest.IsZero() {
+		return 0, nil
+	}
+	return time.Since(oldest), nil
+}
@@ -226,6 +226,12 @@ func getIncrementInfo(folder storage.Folder, object storage.Object, storageName
 
 // HandleDeleteGarbage delete outdated WAL archives and leftover backup files
 func (dh *DeleteHandler) HandleDeleteGarbage(args []string, confirm bool) error {
+	if confirm {
+		err := dh.CheckGuardrail("delete garbage")
+		if err != nil {
+			return err
+		}
+	}
 	predicate := ExtractDeleteGarbagePredicate(args)
 	backupSelector := internal.NewOldestNonPermanentSelector(NewGenericMetaFetcher())
 	oldestBackup, err := backupSelector.Select(dh.Folder)
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/multistorage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// ExplainDeleteGarbage explains 'delete garbage' with the same arguments
+func (dh *DeleteHandler) ExplainDeleteGarbage(args []string) (*internal.DeleteExplanation, error) {
+	predicate := ExtractDeleteGarbagePredicate(args)
+	rule := "garbage"
+	if len(args) > 0 {
+		rule += " " + args[0]
+	}
+	backupSelector := internal.NewOldestNonPermanentSelector(NewGenericMetaFetcher())
+	oldestBackup, err := backupSelector.Select(dh.Folder)
+	if err != nil {
+		if _, ok := err.(internal.NoBackupsFoundError); ok {
+			return dh.ExplainDeleteBeforeTargetWhere(nil, rule, predicate)
+		}
+		return nil, err
+	}
+
+	target, err := dh.FindTargetByName(oldestBackup.Name)
+	if err != nil {
+		return nil, err
+	}
+	return dh.ExplainDeleteBeforeTargetWhere(target, rule, predicate)
+}
+
+// HandleDeleteExplain prints the decision trace with the WAL boundary and the user data of the backups
+func (dh *DeleteHandler) HandleDeleteExplain(explanation *internal.DeleteExplanation, err error, isJSON bool) {
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	// the WALs are deleted only up to the retained backup, 'delete target' keeps them all
+	if explanation.Target != "" && explanation.Refused == "" && !strings.HasPrefix(explanation.Rule, "target") {
+		if timeline, logSegNo, ok := TryFetchTimelineAndLogSegNo(explanation.Target); ok {
+			explanation.WalBoundary = formatWALFileName(timeline, logSegNo)
+		}
+	}
+	dh.labelExplainedBackups(explanation)
+	internal.RecordRetentionEvaluation(dh.Folder, explanation)
+
+	err = internal.PrintDeleteExplanation(explanation, os.Stdout, isJSON)
+	tracelog.ErrorLogger.FatalfOnError("Print delete explanation: %v", err)
+}
+
+// labelExplainedBackups sets the user data of the backups as their labels. The backups whose metadata
+// can't be fetched are left without the label, since the label doesn't affect the decisions.
+func (dh *DeleteHandler) labelExplainedBackups(explanation *internal.DeleteExplanation) {
+	metaFetcher := NewGenericMetaFetcher()
+	for i := range explanation.Backups {
+		backup := &explanation.Backups[i]
+		specificFolder, err := multistorage.UseSpecificStorage(backup.Storage, dh.Folder)
+		if err != nil {
+			tracelog.DebugLogger.Printf("Failed to use storage %s: %v\n", backup.Storage, err)
+			continue
+		}
+		meta, err := metaFetcher.Fetch(backup.BackupName, specificFolder.GetSubFolder(utility.BaseBackupPath))
+		if err != nil {
+			tracelog.DebugLogger.Printf("Failed to fetch metadata of backup %s: %v\n", backup.BackupName, err)
+			continue
+		}
+		if meta.UserData == nil {
+			continue
+		}
+		userData, err := json.Marshal(meta.UserData)
+		if err != nil {
+			tracelog.DebugLogger.Printf("Failed to marshal user data of backup %s: %v\n", backup.BackupName, err)
+			continue
+		}
+		backup.Label = string(userData)
+	}
+}
@@ -0,0 +1,259 @@
+package postgres
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/storageevents"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+var (
+	// the names of the WAL segments, the history files, the backup history files and the partial segments
+	walObjectRegexp    = regexp.MustCompile(`^[0-9A-F]{8}[^/]*$`)
+	backupObjectRegexp = regexp.MustCompile(`^base_[0-9A-F]{24}`)
+
+	// the other objects WAL-G writes to the storage root
+	expectedRootObjects = []string{
+		utility.CatchupPath,
+		internal.IncompleteBackupsPath + "/",
+		internal.RestoreReportsPath + "/",
+		internal.RetentionEvaluationsPath + "/",
+		ServerLogsPath,
+		GlobalsPath,
+		internal.ArchivingLeasePath,
+		internal.MetadataLockPath,
+		storage.TemporaryFolder,
+	}
+)
+
+// AlertFunc reports the problem found by the EventVerifier
+type AlertFunc func(message string)
+
+type pendingEventCheck struct {
+	deadline time.Time
+	check    func() (alert string, err error)
+}
+
+// EventVerifier verifies the objects changed in the storage as soon as the bucket notifications about them arrive:
+// the created objects must have the expected names and checksums, the WAL segments must arrive in sequence,
+// and the objects must be deleted only by WAL-G holding the metadata lock. The checks which may be affected
+// by the reordered or the concurrent events are postponed by the grace period.
+type EventVerifier struct {
+	root        storage.Folder
+	gracePeriod time.Duration
+	alert       AlertFunc
+
+	mutex          sync.Mutex
+	pending        []pendingEventCheck
+	lockEventTimes []time.Time
+}
+
+func NewEventVerifier(root storage.Folder, gracePeriod time.Duration, alert AlertFunc) *EventVerifier {
+	return &EventVerifier{root: root, gracePeriod: gracePeriod, alert: alert}
+}
+
+// Verify checks the object from the event. The error means the object can't be checked now,
+// e.g. the storage is unreachable, so the event should be delivered again.
+func (verifier *EventVerifier) Verify(event storageevents.Event) error {
+	objectPath, ok := strings.CutPrefix(event.Key, verifier.root.GetPath())
+	if !ok {
+		return nil
+	}
+	tracelog.DebugLogger.Printf("Verifying %s", event)
+
+	if objectPath == internal.MetadataLockPath {
+		verifier.mutex.Lock()
+		verifier.lockEventTimes = append(verifier.lockEventTimes, event.Time)
+		verifier.mutex.Unlock()
+		return nil
+	}
+
+	switch event.Kind {
+	case storageevents.ObjectCreated:
+		return verifier.verifyCreated(objectPath, event)
+	case storageevents.ObjectRemoved:
+		verifier.verifyRemoved(objectPath, event)
+	}
+	return nil
+}
+
+func (verifier *EventVerifier) verifyCreated(objectPath string, event storageevents.Event) error {
+	if !isExpectedObjectPath(objectPath) {
+		verifier.alert(fmt.Sprintf("unexpected object %s is written to the backup storage", event.Key))
+		return nil
+	}
+
+	if event.MD5 != "" {
+		md5Sum, size, err := verifier.readChecksum(objectPath)
+		if _, ok := err.(storage.ObjectNotFoundError); ok {
+			// the object is already overwritten or deleted, the later events tell about it
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if size != event.Size || md5Sum != event.MD5 {
+			verifier.alert(fmt.Sprintf("%s is corrupted: read %d bytes with MD5 %s, but %d bytes with MD5 %s were written",
+				event.Key, size, md5Sum, event.Size, event.MD5))
+		}
+	}
+
+	if segmentName, ok := strings.CutPrefix(objectPath, utility.WalPath); ok {
+		verifier.checkWalSequence(segmentName, event)
+	}
+	return nil
+}
+
+func (verifier *EventVerifier) readChecksum(objectPath string) (string, int64, error) {
+	reader, err := verifier.root.ReadObject(objectPath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer utility.LoggedClose(reader, "")
+	hash := md5.New()
+	size, err := io.Copy(hash, reader)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// checkWalSequence alerts if the predecessor of the segment doesn't arrive within the grace period.
+// The first segment of the new timeline follows the history file instead.
+func (verifier *EventVerifier) checkWalSequence(objectName string, event storageevents.Event) {
+	if strings.Contains(objectName, ".partial") || strings.Contains(objectName, ".backup") {
+		return
+	}
+	segmentName, _, _ := strings.Cut(objectName, ".")
+	timeline, logSegNo, err := ParseWALFilename(segmentName)
+	if err != nil || logSegNo == 0 {
+		return
+	}
+	predecessorName := formatWALFileName(timeline, logSegNo-1)
+	historyName := fmt.Sprintf(walHistoryFileFormat, timeline)
+
+	verifier.postpone(func() (string, error) {
+		walFolder := verifier.root.GetSubFolder(utility.WalPath)
+		exists, err := walObjectExists(walFolder, predecessorName)
+		if err != nil || exists {
+			return "", err
+		}
+		if exists, err = walObjectExists(walFolder, historyName); err != nil || exists {
+			return "", err
+		}
+		return fmt.Sprintf("WAL segment %s is archived, but its predecessor %s is not", event.Key, predecessorName), nil
+	})
+}
+
+// verifyRemoved alerts unless WAL-G held the metadata lock at the time of the deletion, the lock object
+// is rewritten while it is held, so there are lock events at most MetadataLockTTL before the deletion
+func (verifier *EventVerifier) verifyRemoved(objectPath string, event storageevents.Event) {
+	if !strings.HasPrefix(objectPath, utility.WalPath) && !strings.HasPrefix(objectPath, utility.BaseBackupPath) {
+		return
+	}
+	verifier.postpone(func() (string, error) {
+		if verifier.lockHeldAt(event.Time) {
+			return "", nil
+		}
+		locked, err := verifier.root.Exists(internal.MetadataLockPath)
+		if err != nil || locked {
+			return "", err
+		}
+		return fmt.Sprintf("%s is deleted out of band: WAL-G didn't hold the metadata lock", event.Key), nil
+	})
+}
+
+func (verifier *EventVerifier) lockHeldAt(eventTime time.Time) bool {
+	verifier.mutex.Lock()
+	defer verifier.mutex.Unlock()
+	for _, lockEventTime := range verifier.lockEventTimes {
+		if !lockEventTime.Before(eventTime.Add(-internal.MetadataLockTTL)) &&
+			!lockEventTime.After(eventTime.Add(verifier.gracePeriod)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (verifier *EventVerifier) postpone(check func() (string, error)) {
+	verifier.mutex.Lock()
+	defer verifier.mutex.Unlock()
+	verifier.pending = append(verifier.pending, pendingEventCheck{deadline: time.Now().Add(verifier.gracePeriod), check: check})
+}
+
+// RunPendingChecks runs the postponed checks whose grace period is over
+func (verifier *EventVerifier) RunPendingChecks(now time.Time) {
+	verifier.mutex.Lock()
+	var due []pendingEventCheck
+	pending := verifier.pending[:0]
+	for _, check := range verifier.pending {
+		if check.deadline.After(now) {
+			pending = append(pending, check)
+		} else {
+			due = append(due, check)
+		}
+	}
+	verifier.pending = pending
+	verifier.forgetLockEvents(now)
+	verifier.mutex.Unlock()
+
+	for _, check := range due {
+		alert, err := check.check()
+		if err != nil {
+			tracelog.WarningLogger.Printf("Failed to run the postponed check: %v", err)
+			continue
+		}
+		if alert != "" {
+			verifier.alert(alert)
+		}
+	}
+}
+
+// forgetLockEvents drops the lock events which can't authorize the deletions any more
+func (verifier *EventVerifier) forgetLockEvents(now time.Time) {
+	lockEventTimes := verifier.lockEventTimes[:0]
+	for _, lockEventTime := range verifier.lockEventTimes {
+		if now.Sub(lockEventTime) < internal.MetadataLockTTL+2*verifier.gracePeriod {
+			lockEventTimes = append(lockEventTimes, lockEventTime)
+		}
+	}
+	verifier.lockEventTimes = lockEventTimes
+}
+
+func isExpectedObjectPath(objectPath string) bool {
+	if name, ok := strings.CutPrefix(objectPath, utility.WalPath); ok {
+		return walObjectRegexp.MatchString(name)
+	}
+	if name, ok := strings.CutPrefix(objectPath, utility.BaseBackupPath); ok {
+		return backupObjectRegexp.MatchString(name)
+	}
+	for _, expected := range expectedRootObjects {
+		if objectPath == expected || strings.HasSuffix(expected, "/") && strings.HasPrefix(objectPath, expected) {
+			return true
+		}
+	}
+	return false
+}
+
+// walObjectExists checks the object regardless of the compression
+func walObjectExists(walFolder storage.Folder, name string) (bool, error) {
+	exists, err := walFolder.Exists(name)
+	for _, decompressor := range compression.Decompressors {
+		if err != nil || exists {
+			break
+		}
+		exists, err = walFolder.Exists(name + "." + decompressor.FileExtension())
+	}
+	return exists, err
+}
@@ -0,0 +1,100 @@
+package postgres_test
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/internal/storageevents"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const eventsGracePeriod = time.Minute
+
+func newTestEventVerifier() (*postgres.EventVerifier, storage.Folder, *[]string) {
+	folder := memory.NewFolder("in_memory/", memory.NewKVS())
+	alerts := &[]string{}
+	verifier := postgres.NewEventVerifier(folder, eventsGracePeriod, func(message string) {
+		*alerts = append(*alerts, message)
+	})
+	return verifier, folder, alerts
+}
+
+func putWithEvent(t *testing.T, folder storage.Folder, path, content string) storageevents.Event {
+	require.NoError(t, folder.PutObject(path, strings.NewReader(content)))
+	md5Sum := md5.Sum([]byte(content))
+	return storageevents.Event{
+		Kind: storageevents.ObjectCreated,
+		Key:  folder.GetPath() + path,
+		Size: int64(len(content)),
+		MD5:  hex.EncodeToString(md5Sum[:]),
+		Time: time.Now(),
+	}
+}
+
+func TestEventVerifier_AlertsUnexpectedObject(t *testing.T) {
+	verifier, folder, alerts := newTestEventVerifier()
+
+	require.NoError(t, verifier.Verify(putWithEvent(t, folder, "wal_005/000000010000000000000001.lz4", "wal")))
+	require.NoError(t, verifier.Verify(putWithEvent(t, folder, "basebackups_005/evil.sh", "rm -rf")))
+	require.NoError(t, verifier.Verify(putWithEvent(t, folder, "other/object", "data")))
+
+	assert.Len(t, *alerts, 2)
+}
+
+func TestEventVerifier_AlertsChecksumMismatch(t *testing.T) {
+	verifier, folder, alerts := newTestEventVerifier()
+
+	event := putWithEvent(t, folder, "basebackups_005/base_000000010000000000000002/tar_partitions/part_1.tar.lz4", "data")
+	require.NoError(t, verifier.Verify(event))
+	assert.Empty(t, *alerts)
+
+	require.NoError(t, folder.PutObject("basebackups_005/base_000000010000000000000002/tar_partitions/part_1.tar.lz4",
+		strings.NewReader("tampered")))
+	require.NoError(t, verifier.Verify(event))
+	require.Len(t, *alerts, 1)
+	assert.Contains(t, (*alerts)[0], "corrupted")
+}
+
+func TestEventVerifier_AlertsWalGap(t *testing.T) {
+	verifier, folder, alerts := newTestEventVerifier()
+
+	require.NoError(t, verifier.Verify(putWithEvent(t, folder, "wal_005/000000010000000000000002.lz4", "wal")))
+	require.NoError(t, verifier.Verify(putWithEvent(t, folder, "wal_005/000000010000000000000003.lz4", "wal")))
+	require.NoError(t, verifier.Verify(putWithEvent(t, folder, "wal_005/000000010000000000000005.lz4", "wal")))
+
+	verifier.RunPendingChecks(time.Now())
+	assert.Empty(t, *alerts, "the checks must wait for the grace period")
+
+	verifier.RunPendingChecks(time.Now().Add(2 * eventsGracePeriod))
+	require.Len(t, *alerts, 2)
+	assert.Contains(t, (*alerts)[0], "000000010000000000000001")
+	assert.Contains(t, (*alerts)[1], "000000010000000000000004")
+}
+
+func TestEventVerifier_AlertsOutOfBandDeletion(t *testing.T) {
+	verifier, _, alerts := newTestEventVerifier()
+	now := time.Now()
+
+	require.NoError(t, verifier.Verify(storageevents.Event{
+		Kind: storageevents.ObjectRemoved, Key: "in_memory/wal_005/000000010000000000000001.lz4", Time: now,
+	}))
+	verifier.RunPendingChecks(now.Add(2 * eventsGracePeriod))
+	assert.Len(t, *alerts, 1)
+
+	// the deletion made under the metadata lock
+	require.NoError(t, verifier.Verify(storageevents.Event{
+		Kind: storageevents.ObjectCreated, Key: "in_memory/metadata.lock", Time: now,
+	}))
+	require.NoError(t, verifier.Verify(storageevents.Event{
+		Kind: storageevents.ObjectRemoved, Key: "in_memory/wal_005/000000010000000000000002.lz4", Time: now.Add(time.Minute),
+	}))
+	verifier.RunPendingChecks(now.Add(3 * eventsGracePeriod))
+	assert.Len(t, *alerts, 1)
+}
@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/storageevents"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const minPendingChecksInterval = 10 * time.Second
+
+// HandleEventsVerify verifies the objects from the bucket notifications until the context is done
+func HandleEventsVerify(ctx context.Context, root storage.Folder, source storageevents.Source) error {
+	gracePeriod, err := conf.GetDurationSetting(conf.EventsGracePeriodSetting)
+	if err != nil {
+		return err
+	}
+	verifier := NewEventVerifier(root, gracePeriod, alertEvent)
+
+	checksInterval := gracePeriod / 2
+	if checksInterval < minPendingChecksInterval {
+		checksInterval = minPendingChecksInterval
+	}
+	go func() {
+		ticker := time.NewTicker(checksInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				verifier.RunPendingChecks(now)
+			}
+		}
+	}()
+
+	tracelog.InfoLogger.Printf("Verifying the objects changed in %s", root.GetPath())
+	err = source.Consume(ctx, verifier.Verify)
+	if err == context.Canceled {
+		return nil
+	}
+	return err
+}
+
+// alertEvent logs the alert and passes it to WALG_EVENTS_ALERT_COMMAND on stdin, if set
+func alertEvent(message string) {
+	tracelog.ErrorLogger.Printf("ALERT: %s", message)
+	if _, ok := conf.GetSetting(conf.EventsAlertCmdSetting); !ok {
+		return
+	}
+	cmd, err := internal.GetCommandSetting(conf.EventsAlertCmdSetting)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to run the alert command: %v", err)
+		return
+	}
+	cmd.Stdin = strings.NewReader(message + "\n")
+	if err = cmd.Run(); err != nil {
+		tracelog.WarningLogger.Printf("Failed to run the alert command: %v", err)
+	}
+}
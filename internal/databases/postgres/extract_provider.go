@@ -1,6 +1,10 @@
 package postgres
 
 import (
+	"archive/tar"
+	"fmt"
+	"io"
+
 	"github.com/wal-g/wal-g/internal"
 )
 
@@ -30,3 +34,42 @@ func (t ExtractProviderImpl) getTarInterpreter(dbDataDir string, backup Backup,
 	return NewFileTarInterpreter(dbDataDir, *backup.SentinelDto, *backup.FilesMetadataDto,
 		filesToUnwrap, createNewIncrementalFiles)
 }
+
+// BlockDeviceExtractProvider restores the selected files directly onto block devices
+// and passes the rest of the files to the wrapped provider's interpreter.
+type BlockDeviceExtractProvider struct {
+	ExtractProvider
+	Devices map[string]string
+	Discard bool
+}
+
+func (t BlockDeviceExtractProvider) Get(
+	backup Backup,
+	filesToUnwrap map[string]bool,
+	skipRedundantTars bool,
+	dbDataDir string,
+	createNewIncrementalFiles bool,
+) (IncrementalTarInterpreter, []internal.ReaderMaker, string, error) {
+	if backup.SentinelDto != nil && backup.SentinelDto.IsIncremental() {
+		return nil, nil, "", fmt.Errorf("delta backup %s can not be restored onto block devices", backup.Name)
+	}
+	interpreter, tarsToExtract, pgControlKey, err := t.ExtractProvider.Get(
+		backup, filesToUnwrap, skipRedundantTars, dbDataDir, createNewIncrementalFiles)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	blockDeviceInterpreter := &blockDeviceTarInterpreter{
+		IncrementalTarInterpreter: interpreter,
+		blockDevices:              internal.NewBlockDeviceTarInterpreter(interpreter, t.Devices, t.Discard),
+	}
+	return blockDeviceInterpreter, tarsToExtract, pgControlKey, nil
+}
+
+type blockDeviceTarInterpreter struct {
+	IncrementalTarInterpreter
+	blockDevices *internal.BlockDeviceTarInterpreter
+}
+
+func (interpreter *blockDeviceTarInterpreter) Interpret(reader io.Reader, header *tar.Header) error {
+	return interpreter.blockDevices.Interpret(reader, header)
+}
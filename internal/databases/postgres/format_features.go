@@ -0,0 +1,208 @@
+package postgres
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+)
+
+// The features of the backup format written by this version of WAL-G. They are recorded in the sentinel and
+// the metadata, so the later versions can tell which format the backup has without guessing by the fields.
+const (
+	// FilesMetadataFeature means the files of the backup are listed in files_metadata.json, not in the sentinel
+	FilesMetadataFeature = "files_metadata"
+	// ExtendedMetadataFeature means the backup has metadata.json
+	ExtendedMetadataFeature = "extended_metadata"
+	// SentinelV2Feature means the sentinel includes the extended metadata, see BackupSentinelDtoV2
+	SentinelV2Feature = "sentinel_v2"
+	// DeltaLSNFeature means the delta backups store the LSN of the base backup as DeltaLSN, not DeltaFromLSN
+	DeltaLSNFeature = "delta_lsn"
+)
+
+// The optional features are recorded only by the backups using them, the older versions of WAL-G can't
+// restore such backups
+const (
+	// FileRangesFeature means the large files are split into the ranges stored as separate tar entries
+	FileRangesFeature = "file_ranges"
+	// RawTarPartsFeature means the incompressible files are stored in the uncompressed part_raw_NNN.tar parts
+	RawTarPartsFeature = "raw_tar_parts"
+	// WalEnvelopeFeature means the WAL needed to recover from the backup is archived in the walz envelope
+	WalEnvelopeFeature = "wal_envelope"
+)
+
+// FormatFeatures are the features of the backup format every backup made by this version of WAL-G has
+var FormatFeatures = []string{FilesMetadataFeature, ExtendedMetadataFeature, SentinelV2Feature, DeltaLSNFeature}
+
+// OptionalFormatFeatures are the features of the backup format known to this version of WAL-G
+// which are recorded only if the backup uses them
+var OptionalFormatFeatures = []string{FileRangesFeature, RawTarPartsFeature, WalEnvelopeFeature}
+
+type UnknownFormatFeaturesError struct {
+	error
+}
+
+func newUnknownFormatFeaturesError(backupName, walgVersion string, unknown []string) UnknownFormatFeaturesError {
+	return UnknownFormatFeaturesError{fmt.Errorf("backup %s is made by WAL-G %s with the format features %v "+
+		"unknown to this version, upgrade WAL-G to restore it or set %s to restore it anyway",
+		backupName, walgVersion, unknown, conf.PgIgnoreUnknownFormatFeaturesSetting)}
+}
+
+func (err UnknownFormatFeaturesError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// usedFormatFeatures collects the optional features used by the backup while it's written
+type usedFormatFeatures struct {
+	mutex    sync.Mutex
+	features map[string]bool
+}
+
+func (used *usedFormatFeatures) add(feature string) {
+	used.mutex.Lock()
+	defer used.mutex.Unlock()
+	if used.features == nil {
+		used.features = make(map[string]bool)
+	}
+	used.features[feature] = true
+}
+
+// list returns the features in the order of OptionalFormatFeatures
+func (used *usedFormatFeatures) list() []string {
+	used.mutex.Lock()
+	defer used.mutex.Unlock()
+	var features []string
+	for _, feature := range OptionalFormatFeatures {
+		if used.features[feature] {
+			features = append(features, feature)
+		}
+	}
+	return features
+}
+
+// backupFormatFeatures returns the features to record in the sentinel of the new backup
+func backupFormatFeatures(used []string) []string {
+	features := append([]string{}, FormatFeatures...)
+	features = append(features, used...)
+	if viper.GetBool(conf.PgWalEnvelopeSetting) {
+		features = append(features, WalEnvelopeFeature)
+	}
+	return features
+}
+
+// walEBackupNameRegexp matches the names of the backups made by WAL-E: the WAL segment and the decimal offset
+// of the backup start
+var walEBackupNameRegexp = regexp.MustCompile(`^base_([0-9A-F]{24})_(\d+)$`)
+
+// unknownFormatFeatures returns the features of the backup made by a newer WAL-G which this version doesn't know
+func unknownFormatFeatures(features []string) []string {
+	known := make(map[string]bool, len(FormatFeatures)+len(OptionalFormatFeatures))
+	for _, feature := range FormatFeatures {
+		known[feature] = true
+	}
+	for _, feature := range OptionalFormatFeatures {
+		known[feature] = true
+	}
+	var unknown []string
+	for _, feature := range features {
+		if !known[feature] {
+			unknown = append(unknown, feature)
+		}
+	}
+	return unknown
+}
+
+// checkFormatFeatures refuses to restore the backup with the format features unknown to this version of WAL-G,
+// unless it's forced by the setting
+func checkFormatFeatures(backupName string, sentinel BackupSentinelDto) error {
+	unknown := unknownFormatFeatures(sentinel.FormatFeatures)
+	if len(unknown) == 0 {
+		return nil
+	}
+	err := newUnknownFormatFeaturesError(backupName, sentinel.WalgVersion, unknown)
+	if !viper.GetBool(conf.PgIgnoreUnknownFormatFeaturesSetting) {
+		return err
+	}
+	tracelog.WarningLogger.Printf("%v, restoring it anyway, since %s is set",
+		err, conf.PgIgnoreUnknownFormatFeaturesSetting)
+	return nil
+}
+
+// rememberWalEnvelope makes the later wal-fetch look up the WAL in the walz envelope if the backup was made
+// with it, even if WALG_WAL_ENVELOPE isn't set on the restored host
+func rememberWalEnvelope(sentinel BackupSentinelDto) {
+	for _, feature := range sentinel.FormatFeatures {
+		if feature != WalEnvelopeFeature {
+			continue
+		}
+		if err := internal.MarkWalEnvelopeSeen(); err != nil {
+			tracelog.WarningLogger.Printf("Failed to remember that the WAL is archived in the walz envelope: %v", err)
+		}
+		return
+	}
+}
+
+// withFormatFeatures returns the features with the current ones added, keeping the optional ones
+// recorded by the backup
+func withFormatFeatures(features []string) []string {
+	result := append([]string{}, FormatFeatures...)
+	has := make(map[string]bool, len(result))
+	for _, feature := range result {
+		has[feature] = true
+	}
+	for _, feature := range features {
+		if !has[feature] {
+			has[feature] = true
+			result = append(result, feature)
+		}
+	}
+	return result
+}
+
+// hasAllFormatFeatures checks if the backup has the current format
+func hasAllFormatFeatures(features []string) bool {
+	has := make(map[string]bool, len(features))
+	for _, feature := range features {
+		has[feature] = true
+	}
+	for _, feature := range FormatFeatures {
+		if !has[feature] {
+			return false
+		}
+	}
+	return true
+}
+
+// walEStartLSN returns the start LSN of the backup made by WAL-E, which didn't store it in the sentinel
+func walEStartLSN(backupName string) (*LSN, bool) {
+	match := walEBackupNameRegexp.FindStringSubmatch(backupName)
+	if match == nil {
+		return nil, false
+	}
+	_, logSegNo, err := ParseWALFilename(match[1])
+	if err != nil {
+		return nil, false
+	}
+	offset, err := strconv.ParseUint(match[2], 10, 64)
+	if err != nil || offset >= WalSegmentSize {
+		return nil, false
+	}
+	lsn := WalSegmentNo(logSegNo).firstLsn() + LSN(offset)
+	return &lsn, true
+}
+
+// walEFinishLSN returns the end of the WAL segment WAL-E recorded as the backup stop segment. WAL-E didn't store
+// the exact LSN, but the recovery from the backup replays the whole segment anyway.
+func walEFinishLSN(stopSegment string) (*LSN, bool) {
+	_, logSegNo, err := ParseWALFilename(stopSegment)
+	if err != nil {
+		return nil, false
+	}
+	lsn := WalSegmentNo(logSegNo).Next().firstLsn()
+	return &lsn, true
+}
@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	conf "github.com/wal-g/wal-g/internal/config"
+)
+
+func TestCheckFormatFeatures(t *testing.T) {
+	sentinel := BackupSentinelDto{
+		WalgVersion:    "v9.0.0",
+		FormatFeatures: append(append([]string{}, FormatFeatures...), FileRangesFeature),
+	}
+	assert.NoError(t, checkFormatFeatures("base_000000010000000000000002", sentinel))
+
+	sentinel.FormatFeatures = append(sentinel.FormatFeatures, "future_feature")
+	err := checkFormatFeatures("base_000000010000000000000002", sentinel)
+	assert.ErrorAs(t, err, &UnknownFormatFeaturesError{})
+	assert.Contains(t, err.Error(), "future_feature")
+
+	viper.Set(conf.PgIgnoreUnknownFormatFeaturesSetting, true)
+	defer viper.Set(conf.PgIgnoreUnknownFormatFeaturesSetting, false)
+	assert.NoError(t, checkFormatFeatures("base_000000010000000000000002", sentinel))
+}
+
+func TestUsedFormatFeatures(t *testing.T) {
+	var used usedFormatFeatures
+	assert.Empty(t, used.list())
+
+	used.add(WalEnvelopeFeature)
+	used.add(FileRangesFeature)
+	used.add(FileRangesFeature)
+	used.add(DeltaLSNFeature)
+	assert.Equal(t, []string{FileRangesFeature, WalEnvelopeFeature}, used.list())
+}
+
+func TestWithFormatFeatures(t *testing.T) {
+	features := withFormatFeatures([]string{FilesMetadataFeature, RawTarPartsFeature})
+	assert.Equal(t, append(append([]string{}, FormatFeatures...), RawTarPartsFeature), features)
+	assert.True(t, hasAllFormatFeatures(features))
+}
@@ -77,14 +77,12 @@ func modifyBackupMetadata(backupName string, backupFolder storage.Folder, modifi
 		return errors.Wrap(err, "failed to modify metadata")
 	}
 	var meta ExtendedMetadataDto
-	err = backup.FetchMetadata(&meta)
+	err = backup.ModifyMetadata(&meta, func() error {
+		meta = modifier(meta)
+		return nil
+	})
 	if err != nil {
-		return errors.Wrap(err, "failed to fetch the existing backup metadata for modifying")
-	}
-	meta = modifier(meta)
-	err = backup.UploadMetadata(meta)
-	if err != nil {
-		return errors.Wrap(err, "failed to upload the modified metadata to the storage")
+		return errors.Wrap(err, "failed to modify the backup metadata in the storage")
 	}
 	return nil
 }
@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// GlobalsPath is the folder of the dumps of the global objects (roles, tablespaces), shared with Greenplum
+const GlobalsPath = "globals_" + utility.VersionStr + "/"
+
+const globalsFileSuffix = ".sql"
+
+// UploadGlobals dumps the global objects of the cluster with WALG_GLOBALS_DUMP_COMMAND and uploads the dump
+// under the backup name. The uploader has to point to the storage root, the dumps beyond WALG_GLOBALS_RETAIN are deleted.
+func UploadGlobals(ctx context.Context, uploader internal.Uploader, backupName string) error {
+	cmd, err := internal.GetCommandSettingContext(ctx, conf.PgGlobalsDumpCommand)
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return errors.Wrap(err, "failed to start the globals dump")
+	}
+
+	uploader.ChangeDirectory(GlobalsPath)
+	uploader.DisableSizeTracking()
+	dstPath := backupName + globalsFileSuffix + "." + uploader.Compression().FileExtension()
+	uploadErr := uploader.PushStreamToDestination(ctx, stdout, dstPath)
+	if uploadErr != nil {
+		// the dump would block on the pipe nobody reads anymore
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return errors.Wrap(uploadErr, "failed to upload the globals dump")
+	}
+	if err = cmd.Wait(); err != nil {
+		return errors.Wrap(err, "globals dump failed")
+	}
+	tracelog.InfoLogger.Printf("Uploaded the global objects of backup %s", backupName)
+
+	if retain := viper.GetInt(conf.PgGlobalsRetain); retain > 0 {
+		return pruneGlobals(uploader.Folder(), retain)
+	}
+	return nil
+}
+
+// pruneGlobals deletes all the dumps of the global objects except the retain newest ones
+func pruneGlobals(folder storage.Folder, retain int) error {
+	objects, _, err := folder.ListFolder()
+	if err != nil {
+		return errors.Wrap(err, "failed to list the globals dumps")
+	}
+	if len(objects) <= retain {
+		return nil
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].GetLastModified().After(objects[j].GetLastModified())
+	})
+	toDelete := make([]string, 0, len(objects)-retain)
+	for _, object := range objects[retain:] {
+		toDelete = append(toDelete, object.GetName())
+	}
+	tracelog.InfoLogger.Printf("Deleting %d globals dumps beyond the retention of %d", len(toDelete), retain)
+	return folder.DeleteObjects(toDelete)
+}
+
+// HandleGlobalsFetch writes the dump of the global objects taken with the backup to stdout,
+// or passes it to WALG_GLOBALS_APPLY_COMMAND to apply it to the running cluster
+func HandleGlobalsFetch(ctx context.Context, rootFolder storage.Folder, backupName string, apply bool) {
+	dump, backupName, err := fetchGlobalsDump(rootFolder, backupName)
+	tracelog.ErrorLogger.FatalOnError(err)
+	defer utility.LoggedClose(dump, "")
+
+	if !apply {
+		_, err = io.Copy(os.Stdout, dump)
+		tracelog.ErrorLogger.FatalOnError(err)
+		return
+	}
+	cmd, err := internal.GetCommandSettingContext(ctx, conf.PgGlobalsApplyCommand)
+	tracelog.ErrorLogger.FatalOnError(err)
+	cmd.Stdin = dump
+	cmd.Stdout = os.Stdout
+	tracelog.InfoLogger.Printf("Applying the global objects of backup %s", backupName)
+	err = cmd.Run()
+	tracelog.ErrorLogger.FatalfOnError("Failed to apply the globals dump: %v", err)
+}
+
+// fetchGlobalsDump returns the decompressed globals dump of the backup, or of the latest one,
+// along with the name of the backup
+func fetchGlobalsDump(rootFolder storage.Folder, backupName string) (io.ReadCloser, string, error) {
+	folder := rootFolder.GetSubFolder(GlobalsPath)
+	var err error
+	if backupName == internal.LatestString {
+		backupName, err = getLatestGlobalsName(folder)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	dump, err := internal.DownloadAndDecompressStorageFile(internal.NewFolderReader(folder), backupName+globalsFileSuffix)
+	if _, ok := err.(internal.ArchiveNonExistenceError); ok {
+		return nil, "", fmt.Errorf("no globals dump of backup %s, it may have been taken without %s "+
+			"or deleted by %s", backupName, conf.PgBackupGlobalsSetting, conf.PgGlobalsRetain)
+	}
+	return dump, backupName, err
+}
+
+func getLatestGlobalsName(folder storage.Folder) (string, error) {
+	objects, _, err := folder.ListFolder()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to list the globals dumps")
+	}
+	if len(objects) == 0 {
+		return "", fmt.Errorf("no globals dumps found in %s", GlobalsPath)
+	}
+	latest := objects[0]
+	for _, object := range objects[1:] {
+		if object.GetLastModified().After(latest.GetLastModified()) {
+			latest = object
+		}
+	}
+	name := utility.TrimFileExtension(latest.GetName())
+	return strings.TrimSuffix(name, globalsFileSuffix), nil
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+func TestPruneGlobals(t *testing.T) {
+	now := time.Unix(1692800000, 0)
+	kvs := memory.NewKVS(memory.WithCustomTime(func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}))
+	folder := memory.NewFolder("in_memory/", kvs).GetSubFolder(GlobalsPath)
+	names := []string{"base_000000010000000000000004", "base_000000010000000000000002", "base_000000010000000000000008"}
+	for _, name := range names {
+		require.NoError(t, folder.PutObject(name+globalsFileSuffix+".br", strings.NewReader("CREATE ROLE app;")))
+	}
+
+	latest, err := getLatestGlobalsName(folder)
+	require.NoError(t, err)
+	assert.Equal(t, "base_000000010000000000000008", latest)
+
+	require.NoError(t, pruneGlobals(folder, 2))
+	objects, _, err := folder.ListFolder()
+	require.NoError(t, err)
+	var remaining []string
+	for _, object := range objects {
+		remaining = append(remaining, object.GetName())
+	}
+	assert.ElementsMatch(t, []string{
+		"base_000000010000000000000002.sql.br",
+		"base_000000010000000000000008.sql.br",
+	}, remaining)
+}
+
+func TestGetLatestGlobalsName_NoDumps(t *testing.T) {
+	folder := memory.NewFolder("in_memory/", memory.NewKVS()).GetSubFolder(GlobalsPath)
+	_, err := getLatestGlobalsName(folder)
+	assert.Error(t, err)
+}
+
+func TestFetchGlobalsDump(t *testing.T) {
+	rootFolder := memory.NewFolder("in_memory/", memory.NewKVS())
+	compressor := compression.Compressors[lz4.AlgorithmName]
+	var compressed bytes.Buffer
+	writer := compressor.NewWriter(&compressed)
+	_, err := writer.Write([]byte("CREATE ROLE app;"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, rootFolder.GetSubFolder(GlobalsPath).PutObject(
+		"base_000000010000000000000004"+globalsFileSuffix+"."+compressor.FileExtension(), &compressed))
+
+	dump, backupName, err := fetchGlobalsDump(rootFolder, internal.LatestString)
+	require.NoError(t, err)
+	defer dump.Close()
+	assert.Equal(t, "base_000000010000000000000004", backupName)
+	content, err := io.ReadAll(dump)
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE ROLE app;", string(content))
+
+	_, _, err = fetchGlobalsDump(rootFolder, "base_000000010000000000000002")
+	assert.ErrorContains(t, err, "no globals dump of backup base_000000010000000000000002")
+}
@@ -15,6 +15,16 @@ import (
 // format version "1", signature magic number
 var IncrementFileHeader = []byte{'w', 'i', '1', SignatureMagicNumber}
 
+// IncrementBlockCount returns the number of pages in the increment of the given size. The increment is
+// the header with the file size and the block count, the block numbers and the pages themselves.
+func IncrementBlockCount(incrementSize int64) int64 {
+	headerSize := int64(len(IncrementFileHeader)) + sizeofInt64 + sizeofInt32
+	if incrementSize < headerSize {
+		return 0
+	}
+	return (incrementSize - headerSize) / (sizeofInt32 + DatabasePageSize)
+}
+
 // IncrementalPageReader constructs difference map during initialization and than re-read file
 // Diff map may consist of 1Gb/PostgresBlockSize elements == 512Kb
 type IncrementalPageReader struct {
@@ -101,7 +101,7 @@ func (check IntegrityCheckRunner) Run() (WalVerifyCheckResult, error) {
 
 	integrityScanSegmentSequences := collapseSegmentsByStatusAndTimeline(segmentScanner.ScannedSegments)
 
-	return check.newWalIntegrityCheckResult(integrityScanSegmentSequences), nil
+	return newWalIntegrityCheckResult(integrityScanSegmentSequences, check.noBackupsFound), nil
 }
 
 func (check IntegrityCheckRunner) Type() WalVerifyCheckType {
@@ -112,7 +112,7 @@ func (check IntegrityCheckRunner) Type() WalVerifyCheckType {
 // StatusOk if there are no missing segments in storage
 // StatusWarning if storage contains some ProbablyUploading or ProbablyDelayed segments
 // StatusFailure if storage contains Lost segments
-func (check IntegrityCheckRunner) newWalIntegrityCheckResult(segmentSequences []*IntegrityScanSegmentSequence) WalVerifyCheckResult {
+func newWalIntegrityCheckResult(segmentSequences []*IntegrityScanSegmentSequence, noBackupsFound bool) WalVerifyCheckResult {
 	result := WalVerifyCheckResult{
 		Status:  StatusOk,
 		Details: IntegrityCheckDetails(segmentSequences),
@@ -124,7 +124,7 @@ func (check IntegrityCheckRunner) newWalIntegrityCheckResult(segmentSequences []
 		case Found:
 			prevFound = true
 		case Lost:
-			if check.noBackupsFound && !prevFound {
+			if noBackupsFound && !prevFound {
 				// If there are no backups in storage, WAL-G can't determine the first segment to start the scan from.
 				// So, skip the first not found segment sequences instead of failing.
 				result.Status = StatusWarning
@@ -170,6 +170,20 @@ func newIntegrityScanSegmentSequence(sequence *WalSegmentsSequence,
 // all missing segments encountered in this scan are considered as "missing, lost"
 func runWalIntegrityScan(scanner *WalSegmentScanner,
 	uploadingSegmentRangeSize, delayedSegmentRangeSize int) error {
+	err := scanRecentSegments(scanner, uploadingSegmentRangeSize, delayedSegmentRangeSize)
+	if err != nil {
+		return err
+	}
+
+	// Run until stop segment, and mark all missing segments as lost
+	return scanner.Scan(SegmentScanConfig{
+		UnlimitedScan:        true,
+		MissingSegmentStatus: Lost,
+	})
+}
+
+// scanRecentSegments runs the first two scans of runWalIntegrityScan
+func scanRecentSegments(scanner *WalSegmentScanner, uploadingSegmentRangeSize, delayedSegmentRangeSize int) error {
 	// Run to the latest WAL segment available in storage, mark all missing segments as delayed
 	err := scanner.Scan(SegmentScanConfig{
 		ScanSegmentsLimit:       delayedSegmentRangeSize,
@@ -181,19 +195,10 @@ func runWalIntegrityScan(scanner *WalSegmentScanner,
 	}
 
 	// Traverse potentially uploading segments, mark all missing segments as probably uploading
-	err = scanner.Scan(SegmentScanConfig{
+	return scanner.Scan(SegmentScanConfig{
 		ScanSegmentsLimit:    uploadingSegmentRangeSize,
 		MissingSegmentStatus: ProbablyUploading,
 	})
-	if err != nil {
-		return err
-	}
-
-	// Run until stop segment, and mark all missing segments as lost
-	return scanner.Scan(SegmentScanConfig{
-		UnlimitedScan:        true,
-		MissingSegmentStatus: Lost,
-	})
 }
 
 // collapseSegmentsByStatusAndTimeline collapses scanned segments
@@ -0,0 +1,327 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/multistorage"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// ServerLogsPath is the storage folder of the PostgreSQL server logs pushed by logs-push
+const ServerLogsPath = "server_logs/"
+
+const (
+	LogsPushStateFileName = ".walg_logs_push_state.json"
+
+	logsPushMaxChunkSize  = 16 << 20
+	logsPushHeadSize      = 1024
+	logsRetentionInterval = time.Hour
+	logGenerationFormat   = "20060102T150405.000000Z"
+)
+
+var DefaultLogsPushPatterns = []string{"*.csv", "*.json"}
+
+type LogsPushArgs struct {
+	LogDirectory string
+	// Patterns are the globs of the log file names to push
+	Patterns  []string
+	StateFile string
+	Interval  time.Duration
+	// Once makes logs-push exit after pushing the logs written so far
+	Once bool
+	// RetainWithBackups deletes the logs pushed before the start of the oldest non-permanent backup
+	RetainWithBackups bool
+}
+
+// logFileState is the progress of pushing the log file. PostgreSQL reuses the log file names
+// when log_truncate_on_rotation is on, so the file is considered rotated if it is shorter than
+// the pushed part or its head differs from the one seen before. Each rotation starts a new generation
+// of the file in storage.
+type logFileState struct {
+	Generation   string `json:"generation"`
+	Offset       int64  `json:"offset"`
+	HeadSize     int64  `json:"head_size"`
+	HeadChecksum uint32 `json:"head_checksum"`
+}
+
+type LogsPushHandler struct {
+	uploader      internal.Uploader
+	args          LogsPushArgs
+	states        map[string]*logFileState
+	lastRetention time.Time
+}
+
+func NewLogsPushHandler(uploader internal.Uploader, args LogsPushArgs) (*LogsPushHandler, error) {
+	if len(args.Patterns) == 0 {
+		args.Patterns = DefaultLogsPushPatterns
+	}
+	for _, pattern := range args.Patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid log file pattern %q: %v", pattern, err)
+		}
+	}
+	if args.StateFile == "" {
+		args.StateFile = filepath.Join(args.LogDirectory, LogsPushStateFileName)
+	}
+	states, err := loadLogsPushState(args.StateFile)
+	if err != nil {
+		return nil, err
+	}
+	return &LogsPushHandler{uploader: uploader, args: args, states: states}, nil
+}
+
+// HandleLogsPush pushes the new log records every interval until the context is done
+func (h *LogsPushHandler) HandleLogsPush(ctx context.Context) error {
+	for {
+		err := h.pushLogs(ctx)
+		if err == nil && h.args.RetainWithBackups && time.Since(h.lastRetention) >= logsRetentionInterval {
+			err = h.deleteOutdatedLogs()
+			h.lastRetention = time.Now()
+		}
+		if err != nil {
+			if h.args.Once {
+				return err
+			}
+			tracelog.ErrorLogger.PrintError(err)
+		}
+		if h.args.Once {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(h.args.Interval):
+		}
+	}
+}
+
+func (h *LogsPushHandler) pushLogs(ctx context.Context) error {
+	files, err := h.listLogFiles()
+	if err != nil {
+		return err
+	}
+	present := make(map[string]bool, len(files))
+	for i, file := range files {
+		present[file.Name()] = true
+		// the files older than the latest one are not written anymore, so they are pushed up to the end
+		rotatedAway := i < len(files)-1
+		err = h.pushLogFile(ctx, file.Name(), rotatedAway)
+		if err != nil {
+			break
+		}
+	}
+	for name := range h.states {
+		if !present[name] {
+			delete(h.states, name)
+		}
+	}
+	saveErr := saveLogsPushState(h.args.StateFile, h.states)
+	if err != nil {
+		return err
+	}
+	return saveErr
+}
+
+// listLogFiles returns the log files sorted by the modification time
+func (h *LogsPushHandler) listLogFiles() ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(h.args.LogDirectory)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the log directory")
+	}
+	files := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		// the hidden files, like the state file, are never pushed
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") || !h.matchesPatterns(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, info)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+	return files, nil
+}
+
+func (h *LogsPushHandler) matchesPatterns(name string) bool {
+	for _, pattern := range h.args.Patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *LogsPushHandler) pushLogFile(ctx context.Context, name string, rotatedAway bool) error {
+	file, err := os.Open(filepath.Join(h.args.LogDirectory, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(file, "")
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	state, err := h.checkLogFileState(name, file, info.Size())
+	if err != nil {
+		return err
+	}
+	for state.Offset < info.Size() {
+		chunk, err := readLogChunk(file, state.Offset, info.Size(), rotatedAway)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read log file %s", name)
+		}
+		if len(chunk) == 0 {
+			break
+		}
+		dstPath := path.Join(ServerLogsPath, name,
+			fmt.Sprintf("%s_%016d.%s", state.Generation, state.Offset, h.uploader.Compression().FileExtension()))
+		err = h.uploader.PushStreamToDestination(ctx, bytes.NewReader(chunk), dstPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to push log file %s", name)
+		}
+		state.Offset += int64(len(chunk))
+	}
+	return nil
+}
+
+// checkLogFileState returns the push progress of the file, starting a new generation if the file has been rotated
+func (h *LogsPushHandler) checkLogFileState(name string, file *os.File, size int64) (*logFileState, error) {
+	head := make([]byte, utility.Min(int(size), logsPushHeadSize))
+	_, err := io.ReadFull(io.NewSectionReader(file, 0, int64(len(head))), head)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read log file %s", name)
+	}
+
+	state, ok := h.states[name]
+	rotated := ok && (size < state.Offset || int64(len(head)) < state.HeadSize ||
+		crc32.ChecksumIEEE(head[:state.HeadSize]) != state.HeadChecksum)
+	if rotated {
+		tracelog.InfoLogger.Printf("Log file %s has been rotated\n", name)
+	}
+	if !ok || rotated {
+		state = &logFileState{Generation: utility.TimeNowCrossPlatformUTC().Format(logGenerationFormat)}
+		h.states[name] = state
+	}
+	state.HeadSize = int64(len(head))
+	state.HeadChecksum = crc32.ChecksumIEEE(head)
+	return state, nil
+}
+
+// readLogChunk reads the part of the file starting at the offset. The chunk of the file that is still
+// being written is cut at the end of the last complete line.
+func readLogChunk(file io.ReaderAt, offset, size int64, complete bool) ([]byte, error) {
+	chunk := make([]byte, utility.Min(int(size-offset), logsPushMaxChunkSize))
+	_, err := io.ReadFull(io.NewSectionReader(file, offset, int64(len(chunk))), chunk)
+	if err != nil {
+		return nil, err
+	}
+	if complete || len(chunk) == logsPushMaxChunkSize {
+		return chunk, nil
+	}
+	return chunk[:bytes.LastIndexByte(chunk, '\n')+1], nil
+}
+
+// deleteOutdatedLogs deletes the logs pushed before the start of the oldest non-permanent backup,
+// so the logs are kept for the same window as the backups
+func (h *LogsPushHandler) deleteOutdatedLogs() error {
+	folder := h.uploader.Folder()
+	backupTimes, err := internal.GetBackups(folder.GetSubFolder(utility.BaseBackupPath))
+	if _, ok := err.(internal.NoBackupsFoundError); ok {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	internal.SortBackupTimeSlices(backupTimes)
+
+	var threshold time.Time
+	for _, backupTime := range backupTimes {
+		specificFolder, err := multistorage.UseSpecificStorage(backupTime.StorageName, folder)
+		if err != nil {
+			return err
+		}
+		meta, err := NewGenericMetaFetcher().Fetch(backupTime.BackupName, specificFolder.GetSubFolder(utility.BaseBackupPath))
+		if err != nil {
+			return errors.Wrapf(err, "failed to fetch metadata of backup %s", backupTime.BackupName)
+		}
+		if !meta.IsPermanent {
+			threshold = meta.StartTime
+			break
+		}
+	}
+	if threshold.IsZero() {
+		return nil
+	}
+
+	logsFolder := folder.GetSubFolder(ServerLogsPath)
+	objects, err := storage.ListFolderRecursively(logsFolder)
+	if err != nil {
+		return err
+	}
+	var outdated []string
+	for _, object := range objects {
+		if object.GetLastModified().Before(threshold) {
+			outdated = append(outdated, object.GetName())
+		}
+	}
+	if len(outdated) == 0 {
+		return nil
+	}
+	tracelog.InfoLogger.Printf("Deleting %d log parts pushed before %s\n", len(outdated), threshold.Format(time.RFC3339))
+	return logsFolder.DeleteObjects(outdated)
+}
+
+func loadLogsPushState(stateFile string) (map[string]*logFileState, error) {
+	states := make(map[string]*logFileState)
+	content, err := os.ReadFile(stateFile)
+	if os.IsNotExist(err) {
+		return states, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read logs-push state")
+	}
+	err = json.Unmarshal(content, &states)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse logs-push state %s", stateFile)
+	}
+	return states, nil
+}
+
+func saveLogsPushState(stateFile string, states map[string]*logFileState) error {
+	content, err := json.Marshal(states)
+	if err != nil {
+		return err
+	}
+	tmpFile := stateFile + ".tmp"
+	err = os.WriteFile(tmpFile, content, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to write logs-push state")
+	}
+	return os.Rename(tmpFile, stateFile)
+}
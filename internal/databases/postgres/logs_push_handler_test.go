@@ -0,0 +1,80 @@
+package postgres_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+func pushLogsOnce(t *testing.T, kvs *memory.KVS, logDirectory string) {
+	handler, err := postgres.NewLogsPushHandler(testtools.NewStoringMockUploader(kvs), postgres.LogsPushArgs{
+		LogDirectory: logDirectory,
+		Once:         true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, handler.HandleLogsPush(context.Background()))
+}
+
+func readPushedLogs(t *testing.T, kvs *memory.KVS) []string {
+	folder := memory.NewFolder("in_memory/", kvs).GetSubFolder(postgres.ServerLogsPath)
+	objects, err := storage.ListFolderRecursively(folder)
+	require.NoError(t, err)
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].GetName() < objects[j].GetName()
+	})
+	parts := make([]string, 0, len(objects))
+	for _, object := range objects {
+		reader, err := folder.ReadObject(object.GetName())
+		require.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		parts = append(parts, string(content))
+	}
+	return parts
+}
+
+func TestLogsPush_PushesCompleteLines(t *testing.T) {
+	logDirectory := t.TempDir()
+	logFile := filepath.Join(logDirectory, "postgresql.csv")
+	kvs := memory.NewKVS()
+
+	require.NoError(t, os.WriteFile(logFile, []byte("first\nsecond\npart"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(logDirectory, "postgresql.log"), []byte("ignored\n"), 0600))
+	pushLogsOnce(t, kvs, logDirectory)
+	assert.Equal(t, []string{"first\nsecond\n"}, readPushedLogs(t, kvs))
+
+	file, err := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = file.WriteString("ial\nthird\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	pushLogsOnce(t, kvs, logDirectory)
+	assert.Equal(t, []string{"first\nsecond\n", "partial\nthird\n"}, readPushedLogs(t, kvs))
+}
+
+func TestLogsPush_DetectsTruncatingRotation(t *testing.T) {
+	logDirectory := t.TempDir()
+	logFile := filepath.Join(logDirectory, "postgresql-Mon.json")
+	kvs := memory.NewKVS()
+
+	require.NoError(t, os.WriteFile(logFile, []byte("{\"message\":\"old\"}\n"), 0600))
+	pushLogsOnce(t, kvs, logDirectory)
+
+	require.NoError(t, os.WriteFile(logFile, []byte("{\"message\":\"new, and longer than the old one\"}\n"), 0600))
+	pushLogsOnce(t, kvs, logDirectory)
+
+	assert.Equal(t, []string{
+		"{\"message\":\"old\"}\n",
+		"{\"message\":\"new, and longer than the old one\"}\n",
+	}, readPushedLogs(t, kvs))
+}
@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// HandleMigrateMetadata rewrites the metadata of the backups made by the older versions of WAL-G and by WAL-E
+// in the current format: moves the files list out of the sentinel to files_metadata.json, creates the missing
+// metadata.json and records the format features in the sentinel. The backup data isn't touched. Unless confirmed,
+// only logs the backups to migrate.
+func HandleMigrateMetadata(folder storage.Folder, confirmed bool) error {
+	baseBackupFolder := folder.GetSubFolder(utility.BaseBackupPath)
+	backupTimes, err := internal.GetBackups(baseBackupFolder)
+	if err != nil {
+		return err
+	}
+
+	migrated := 0
+	for _, backupTime := range backupTimes {
+		backup, err := NewBackupInStorage(baseBackupFolder, backupTime.BackupName, backupTime.StorageName)
+		if err != nil {
+			return err
+		}
+		ok, err := migrateBackupMetadata(backup, backupTime.Time, confirmed)
+		if err != nil {
+			return fmt.Errorf("migrate metadata of backup %s: %w", backupTime.BackupName, err)
+		}
+		if ok {
+			migrated++
+		}
+	}
+
+	if !confirmed {
+		tracelog.InfoLogger.Printf("%d backups need the metadata migration, add --%s to migrate them",
+			migrated, internal.ConfirmFlag)
+		return nil
+	}
+	tracelog.InfoLogger.Printf("Migrated the metadata of %d backups", migrated)
+	return nil
+}
+
+// migrateBackupMetadata uploads the sentinel last, so the interrupted migration is repeated by the next run
+func migrateBackupMetadata(backup Backup, finishTime time.Time, confirmed bool) (bool, error) {
+	sentinel, filesMetadata, err := backup.GetSentinelAndFilesMetadata()
+	if err != nil {
+		return false, err
+	}
+	if hasAllFormatFeatures(sentinel.FormatFeatures) {
+		return false, nil
+	}
+	if sentinel.BackupStartLSN == nil || sentinel.BackupFinishLSN == nil {
+		return false, fmt.Errorf("the sentinel has no backup LSNs, the format is unknown")
+	}
+	if !confirmed {
+		tracelog.InfoLogger.Printf("Backup %s made by WAL-G %q has the format features %v",
+			backup.Name, sentinel.WalgVersion, sentinel.FormatFeatures)
+		return true, nil
+	}
+
+	filesMetadataExists, err := backup.Folder.Exists(getFilesMetadataPath(backup.Name))
+	if err != nil {
+		return false, err
+	}
+	if !filesMetadataExists && !sentinel.FilesMetadataDisabled {
+		err = internal.UploadDtoDurably(backup.Folder, filesMetadata, getFilesMetadataPath(backup.Name))
+		if err != nil {
+			return false, fmt.Errorf("upload files metadata: %w", err)
+		}
+	}
+
+	metadataExists, err := backup.Folder.Exists(storage.JoinPath(backup.Name, utility.MetadataFileName))
+	if err != nil {
+		return false, err
+	}
+	sentinel.FormatFeatures = withFormatFeatures(sentinel.FormatFeatures)
+	var meta ExtendedMetadataDto
+	if metadataExists {
+		meta, err = backup.FetchMeta()
+	} else {
+		// the finish time of the backup is the only time known for the old backups
+		meta = newMigratedMetadataDto(sentinel, finishTime)
+		err = backup.UploadMetadata(meta)
+	}
+	if err != nil {
+		return false, fmt.Errorf("migrate %s: %w", utility.MetadataFileName, err)
+	}
+
+	if err = backup.UploadSentinel(NewBackupSentinelDtoV2(sentinel, meta)); err != nil {
+		return false, fmt.Errorf("upload sentinel: %w", err)
+	}
+	tracelog.InfoLogger.Printf("Migrated the metadata of backup %s", backup.Name)
+	return true, nil
+}
+
+func newMigratedMetadataDto(sentinel BackupSentinelDto, finishTime time.Time) ExtendedMetadataDto {
+	return ExtendedMetadataDto{
+		StartTime:        finishTime,
+		FinishTime:       finishTime,
+		DatetimeFormat:   MetadataDatetimeFormat,
+		PgVersion:        sentinel.PgVersion,
+		StartLsn:         *sentinel.BackupStartLSN,
+		FinishLsn:        *sentinel.BackupFinishLSN,
+		SystemIdentifier: sentinel.SystemIdentifier,
+		UncompressedSize: sentinel.UncompressedSize,
+		CompressedSize:   sentinel.CompressedSize,
+		UserData:         sentinel.UserData,
+		WalgVersion:      sentinel.WalgVersion,
+		FormatFeatures:   sentinel.FormatFeatures,
+	}
+}
@@ -0,0 +1,84 @@
+package postgres_test
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const walEBackupName = "base_000000010000000000000002_00000040"
+
+// walESentinel is the sentinel of the WAL-E backup with the files list the old WAL-G versions used to add
+const walESentinel = `{
+	"wal_segment_backup_stop": "000000010000000000000003",
+	"wal_segment_offset_backup_stop": "00000248",
+	"expanded_size_bytes": 1024,
+	"Files": {"/PG_VERSION": {"IsSkipped": false, "IsIncremented": false, "MTime": "2015-01-01T00:00:00Z"}}
+}`
+
+func readObjectJSON(t *testing.T, folder storage.Folder, path string, dto interface{}) {
+	reader, err := folder.ReadObject(path)
+	require.NoError(t, err)
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(content, dto))
+}
+
+func TestGetSentinel_ReadsWalEBackup(t *testing.T) {
+	folder := memory.NewFolder("in_memory/", memory.NewKVS())
+	baseBackupFolder := folder.GetSubFolder(utility.BaseBackupPath)
+	require.NoError(t, baseBackupFolder.PutObject(walEBackupName+utility.SentinelSuffix, strings.NewReader(walESentinel)))
+
+	backup, err := postgres.NewBackup(baseBackupFolder, walEBackupName)
+	require.NoError(t, err)
+	sentinel, filesMetadata, err := backup.GetSentinelAndFilesMetadata()
+	require.NoError(t, err)
+
+	assert.Equal(t, postgres.LSN(2*postgres.WalSegmentSize+40), *sentinel.BackupStartLSN)
+	assert.Equal(t, postgres.LSN(4*postgres.WalSegmentSize), *sentinel.BackupFinishLSN)
+	assert.Equal(t, int64(1024), sentinel.UncompressedSize)
+	assert.Contains(t, filesMetadata.Files, "/PG_VERSION")
+}
+
+func TestHandleMigrateMetadata(t *testing.T) {
+	folder := memory.NewFolder("in_memory/", memory.NewKVS())
+	baseBackupFolder := folder.GetSubFolder(utility.BaseBackupPath)
+	sentinelPath := walEBackupName + utility.SentinelSuffix
+	require.NoError(t, baseBackupFolder.PutObject(sentinelPath, strings.NewReader(walESentinel)))
+
+	require.NoError(t, postgres.HandleMigrateMetadata(folder, false))
+	exists, err := baseBackupFolder.Exists(walEBackupName + "/" + utility.MetadataFileName)
+	require.NoError(t, err)
+	assert.False(t, exists, "the metadata must not be migrated without the confirmation")
+
+	require.NoError(t, postgres.HandleMigrateMetadata(folder, true))
+
+	var sentinel postgres.BackupSentinelDtoV2
+	readObjectJSON(t, baseBackupFolder, sentinelPath, &sentinel)
+	assert.Equal(t, 2, sentinel.Version)
+	assert.Equal(t, postgres.FormatFeatures, sentinel.FormatFeatures)
+	assert.Equal(t, postgres.LSN(2*postgres.WalSegmentSize+40), *sentinel.BackupStartLSN)
+
+	var meta postgres.ExtendedMetadataDto
+	readObjectJSON(t, baseBackupFolder, walEBackupName+"/"+utility.MetadataFileName, &meta)
+	assert.Equal(t, postgres.LSN(4*postgres.WalSegmentSize), meta.FinishLsn)
+
+	var filesMetadata postgres.FilesMetadataDto
+	readObjectJSON(t, baseBackupFolder, walEBackupName+"/"+postgres.FilesMetadataName, &filesMetadata)
+	assert.Contains(t, filesMetadata.Files, "/PG_VERSION")
+
+	backup, err := postgres.NewBackup(baseBackupFolder, walEBackupName)
+	require.NoError(t, err)
+	_, filesMetadataAfter, err := backup.GetSentinelAndFilesMetadata()
+	require.NoError(t, err)
+	assert.Equal(t, filesMetadata.Files, filesMetadataAfter.Files)
+}
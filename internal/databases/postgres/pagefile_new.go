@@ -42,10 +42,15 @@ func (rw *ReadWriterAtFileImpl) Size() int64 {
 // RestoreMissingPages restores missing pages (zero blocks)
 // of local file with their base backup version
 func RestoreMissingPages(base io.Reader, target ReadWriterAt) error {
+	return restoreMissingPagesFrom(base, target, 0)
+}
+
+// restoreMissingPagesFrom restores the missing pages starting at the block, the base holds the pages from it on
+func restoreMissingPagesFrom(base io.Reader, target ReadWriterAt, firstBlockNo int64) error {
 	tracelog.DebugLogger.Printf("Restoring missing pages from base backup: %s\n", target.Name())
 
 	targetPageCount := target.Size() / DatabasePageSize
-	for i := int64(0); i < targetPageCount; i++ {
+	for i := firstBlockNo; i < targetPageCount; i++ {
 		_, err := writePage(target, i, base, false)
 		if err == io.EOF {
 			break
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+)
+
+const (
+	PatroniRolePrimary       = "primary"
+	PatroniRoleMaster        = "master"
+	PatroniRoleStandbyLeader = "standby_leader"
+	PatroniRoleReplica       = "replica"
+	PatroniRoleDemoted       = "demoted"
+
+	PatroniStateRunning = "running"
+)
+
+// PatroniStatus is a part of the Patroni REST API response for the local node
+type PatroniStatus struct {
+	State string `json:"state"`
+	Role  string `json:"role"`
+	Pause bool   `json:"pause"`
+}
+
+// IsLeader tells whether the node is the one that should archive WAL,
+// i.e. a primary or the leader of a standby cluster
+func (status *PatroniStatus) IsLeader() bool {
+	switch status.Role {
+	case PatroniRolePrimary, PatroniRoleMaster, PatroniRoleStandbyLeader:
+		return true
+	}
+	return false
+}
+
+type PatroniClient struct {
+	url    string
+	client *http.Client
+}
+
+func NewPatroniClient(url string, timeout time.Duration) *PatroniClient {
+	return &PatroniClient{
+		url:    strings.TrimSuffix(url, "/"),
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// ConfigurePatroniClient returns nil if WALG_PATRONI_URL is not set
+func ConfigurePatroniClient() (*PatroniClient, error) {
+	url := viper.GetString(conf.PgPatroniURL)
+	if url == "" {
+		return nil, nil
+	}
+	timeout, err := conf.GetDurationSetting(conf.PgPatroniTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return NewPatroniClient(url, timeout), nil
+}
+
+// GetStatus queries the /patroni endpoint of the local node
+func (c *PatroniClient) GetStatus(ctx context.Context) (*PatroniStatus, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+"/patroni", nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query Patroni")
+	}
+	defer response.Body.Close()
+
+	// Patroni returns 503 when the local Postgres is not running, but the body still holds the status
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusServiceUnavailable {
+		return nil, fmt.Errorf("unexpected Patroni response status: %s", response.Status)
+	}
+	status := &PatroniStatus{}
+	err = json.NewDecoder(response.Body).Decode(status)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse Patroni response")
+	}
+	return status, nil
+}
+
+// CheckPatroniBeforeBackup refuses to take a backup from a node that is being demoted or
+// has no running Postgres according to Patroni. Backups from healthy replicas are allowed.
+func CheckPatroniBeforeBackup(ctx context.Context) error {
+	client, err := ConfigurePatroniClient()
+	if err != nil || client == nil {
+		return err
+	}
+	status, err := client.GetStatus(ctx)
+	if err != nil {
+		return err
+	}
+	tracelog.InfoLogger.Printf("Patroni reports role '%s' and state '%s'", status.Role, status.State)
+	if status.Role == PatroniRoleDemoted || status.State != PatroniStateRunning {
+		return newPatroniRoleError(status)
+	}
+	return nil
+}
+
+// CheckPatroniBeforeWalPush pauses archiving on replicas: the error makes Postgres keep the segment
+// and retry later, so it is archived once the node is promoted. If Patroni is unreachable, archiving goes on.
+func CheckPatroniBeforeWalPush(ctx context.Context) error {
+	client, err := ConfigurePatroniClient()
+	if err != nil || client == nil {
+		return err
+	}
+	status, err := client.GetStatus(ctx)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to get node role from Patroni, archiving anyway: %v", err)
+		return nil
+	}
+	if !status.IsLeader() {
+		return newPatroniRoleError(status)
+	}
+	return nil
+}
+
+type PatroniRoleError struct {
+	error
+}
+
+func newPatroniRoleError(status *PatroniStatus) PatroniRoleError {
+	return PatroniRoleError{errors.Errorf("Patroni reports role '%s' and state '%s' for this node, refusing to proceed",
+		status.Role, status.State)}
+}
+
+func (err PatroniRoleError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
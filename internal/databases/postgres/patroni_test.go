@@ -0,0 +1,37 @@
+package postgres_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+func TestPatroniClient_GetStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/patroni", r.URL.Path)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"state": "running", "role": "replica", "server_version": 150002}`))
+	}))
+	defer server.Close()
+
+	status, err := postgres.NewPatroniClient(server.URL+"/", time.Second).GetStatus(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, postgres.PatroniStateRunning, status.State)
+	assert.Equal(t, postgres.PatroniRoleReplica, status.Role)
+	assert.False(t, status.IsLeader())
+}
+
+func TestPatroniStatus_IsLeader(t *testing.T) {
+	for _, role := range []string{postgres.PatroniRolePrimary, postgres.PatroniRoleMaster, postgres.PatroniRoleStandbyLeader} {
+		assert.True(t, (&postgres.PatroniStatus{Role: role}).IsLeader(), role)
+	}
+	for _, role := range []string{postgres.PatroniRoleReplica, postgres.PatroniRoleDemoted, ""} {
+		assert.False(t, (&postgres.PatroniStatus{Role: role}).IsLeader(), role)
+	}
+}
@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// NewWalGapFinder finds the WAL segments missing in the storage between the start of the backup and
+// the start of the next one, or the newest segment archived, following the timeline switches.
+func NewWalGapFinder(rootFolder storage.Folder) internal.LogGapFinder {
+	return func(backupName, nextBackupName string) (string, error) {
+		walFolder := rootFolder.GetSubFolder(utility.WalPath)
+		filenames, err := getFolderFilenames(walFolder)
+		if err != nil {
+			return "", err
+		}
+		segments := getSegmentsFromFiles(filenames)
+
+		stopSegment, err := NewWalSegmentDescription(utility.StripWalFileName(backupName))
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to find the start WAL segment of backup %s", backupName)
+		}
+		var startSegment WalSegmentDescription
+		if nextBackupName != "" {
+			startSegment, err = NewWalSegmentDescription(utility.StripWalFileName(nextBackupName))
+			if err != nil {
+				return "", errors.Wrapf(err, "failed to find the start WAL segment of backup %s", nextBackupName)
+			}
+		} else {
+			startSegment = findNewestSegment(segments)
+		}
+		if startSegment.Number <= stopSegment.Number {
+			return "", nil
+		}
+
+		timelineSwitchMap, err := createTimelineSwitchMap(startSegment.Timeline, walFolder)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to initialize timeline history map")
+		}
+		scanner := NewWalSegmentScanner(NewWalSegmentRunner(startSegment, segments, stopSegment.Number, timelineSwitchMap))
+		err = scanner.Scan(SegmentScanConfig{UnlimitedScan: true, MissingSegmentStatus: Lost})
+		if err != nil {
+			return "", err
+		}
+		for _, sequence := range collapseSegmentsByStatusAndTimeline(scanner.ScannedSegments) {
+			if sequence.Status == Lost {
+				return fmt.Sprintf("WAL segments %s-%s are missing", sequence.StartSegment, sequence.EndSegment), nil
+			}
+		}
+		return "", nil
+	}
+}
+
+func findNewestSegment(segments map[WalSegmentDescription]bool) WalSegmentDescription {
+	var newest WalSegmentDescription
+	for segment := range segments {
+		if segment.Number > newest.Number || segment.Number == newest.Number && segment.Timeline > newest.Timeline {
+			newest = segment
+		}
+	}
+	return newest
+}
@@ -0,0 +1,43 @@
+package postgres_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestWalGapFinder(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewKVS())
+	for segmentNo := postgres.WalSegmentNo(2); segmentNo <= 20; segmentNo++ {
+		if segmentNo == 12 || segmentNo == 13 {
+			continue
+		}
+		name := utility.WalPath + segmentNo.GetFilename(1) + ".lz4"
+		require.NoError(t, folder.PutObject(name, bytes.NewBufferString("wal")))
+	}
+	backupName := func(segmentNo postgres.WalSegmentNo) string {
+		return utility.BackupNamePrefix + segmentNo.GetFilename(1)
+	}
+	findGap := postgres.NewWalGapFinder(folder)
+
+	gap, err := findGap(backupName(2), backupName(10))
+	require.NoError(t, err)
+	assert.Empty(t, gap)
+
+	gap, err = findGap(backupName(14), "")
+	require.NoError(t, err)
+	assert.Empty(t, gap)
+
+	gap, err = findGap(backupName(5), backupName(16))
+	require.NoError(t, err)
+	assert.Equal(t, "WAL segments 00000001000000000000000C-00000001000000000000000D are missing", gap)
+
+	gap, err = findGap(backupName(10)+"_D_"+postgres.WalSegmentNo(2).GetFilename(1), "")
+	require.NoError(t, err)
+	assert.NotEmpty(t, gap)
+}
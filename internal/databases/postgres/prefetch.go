@@ -212,7 +212,7 @@ func prefetchFile(location string, reader internal.StorageFolderReader, walFileN
 		tracelog.ErrorLogger.Printf("WAL-prefetch %s, make dirs: %v", walFileName, err)
 	}
 
-	err = internal.DownloadFileTo(reader, walFileName, oldPath)
+	err = internal.DownloadFileToPipelined(reader, walFileName, oldPath, expectedWalFileSize(walFileName))
 	if err != nil {
 		tracelog.ErrorLogger.Printf("WAL-prefetch %s, download: %v", walFileName, err)
 	} else {
@@ -381,6 +381,43 @@ func (queryRunner *PgQueryRunner) GetDatabaseInfos() ([]PgDatabaseInfo, error) {
 	return databases, nil
 }
 
+// getDatabaseOids fetches the oids of all databases in cluster, including the ones which are not allowed to connect
+func (queryRunner *PgQueryRunner) getDatabaseOids() (map[string]uint32, error) {
+	return queryRunner.getNamedOids("SELECT oid, datname FROM pg_database")
+}
+
+// getTablespaceOids fetches the oids of all tablespaces in cluster
+func (queryRunner *PgQueryRunner) getTablespaceOids() (map[string]uint32, error) {
+	return queryRunner.getNamedOids("SELECT oid, spcname FROM pg_tablespace")
+}
+
+func (queryRunner *PgQueryRunner) getNamedOids(query string) (map[string]uint32, error) {
+	queryRunner.Mu.Lock()
+	defer queryRunner.Mu.Unlock()
+
+	rows, err := queryRunner.Connection.Query(query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "QueryRunner getNamedOids: query '%s' failed", query)
+	}
+	defer rows.Close()
+
+	oids := make(map[string]uint32)
+	for rows.Next() {
+		var oid uint32
+		var name string
+		if err := rows.Scan(&oid, &name); err != nil {
+			return nil, errors.Wrap(err, "QueryRunner getNamedOids: failed to scan row")
+		}
+		oids[name] = oid
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return oids, nil
+}
+
 // GetParameter reads a Postgres setting
 // TODO: Unittest
 func (queryRunner *PgQueryRunner) GetParameter(parameterName string) (string, error) {
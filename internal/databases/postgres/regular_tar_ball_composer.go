@@ -3,9 +3,12 @@ package postgres
 import (
 	"archive/tar"
 	"context"
+	"io"
 	"os"
 
+	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression/computils"
 
 	"github.com/wal-g/wal-g/internal/crypto"
 	"golang.org/x/sync/errgroup"
@@ -19,6 +22,15 @@ type RegularTarBallComposer struct {
 	tarFileSets   internal.TarFileSets
 	errorGroup    *errgroup.Group
 	ctx           context.Context
+
+	// incompressible files are packed into the tarballs of rawTarBallQueue that are uploaded without compression
+	compressibility *computils.CompressibilityChecker
+	rawTarBallQueue *internal.TarBallQueue
+
+	// the files larger than rangeSize are split into the ranges packed concurrently
+	rangeSize int64
+
+	usedFeatures *usedFormatFeatures
 }
 
 func NewRegularTarBallComposer(
@@ -44,6 +56,9 @@ type RegularTarBallComposerMaker struct {
 	filePackerOptions TarBallFilePackerOptions
 	files             internal.BundleFiles
 	tarFileSets       internal.TarFileSets
+
+	compressibility *computils.CompressibilityChecker
+	rawTarBallMaker internal.TarBallMaker
 }
 
 func NewRegularTarBallComposerMaker(
@@ -61,11 +76,40 @@ func (maker *RegularTarBallComposerMaker) Make(bundle *Bundle) (internal.TarBall
 	tarFileSets := maker.tarFileSets
 	tarBallFilePacker := NewTarBallFilePacker(bundle.DeltaMap,
 		bundle.IncrementFromLsn, bundleFiles, maker.filePackerOptions)
-	return NewRegularTarBallComposer(bundle.TarBallQueue, tarBallFilePacker, bundleFiles, tarFileSets, bundle.Crypter), nil
+	composer := NewRegularTarBallComposer(bundle.TarBallQueue, tarBallFilePacker, bundleFiles, tarFileSets, bundle.Crypter)
+	composer.usedFeatures = &bundle.usedFeatures
+	// the pages of the split files can't be verified, since the ranges are read independently
+	if !maker.filePackerOptions.verifyPageChecksums {
+		composer.rangeSize = internal.GetFileRangeSize()
+	}
+	if maker.compressibility != nil && maker.compressibility.Enabled() {
+		rawTarBallQueue := internal.NewTarBallQueue(bundle.TarSizeThreshold, maker.rawTarBallMaker)
+		err := rawTarBallQueue.StartQueue()
+		if err != nil {
+			return nil, err
+		}
+		composer.compressibility = maker.compressibility
+		composer.rawTarBallQueue = rawTarBallQueue
+	}
+	return composer, nil
+}
+
+// SkipIncompressibleFiles makes the composer upload the files that are not worth compressing
+// into the separate tarballs made by rawTarBallMaker.
+func (maker *RegularTarBallComposerMaker) SkipIncompressibleFiles(compressibility *computils.CompressibilityChecker,
+	rawTarBallMaker internal.TarBallMaker) {
+	maker.compressibility = compressibility
+	maker.rawTarBallMaker = rawTarBallMaker
 }
 
 func (c *RegularTarBallComposer) AddFile(info *internal.ComposeFileInfo) {
-	tarBall, err := c.tarBallQueue.DequeCtx(c.ctx)
+	tarBallQueue := c.chooseTarBallQueue(info)
+	if ranges := internal.SplitIntoRanges(info.FileInfo.Size(), c.rangeSize); ranges != nil && !info.IsIncremented {
+		c.useFeature(FileRangesFeature)
+		internal.AddFileRanges(c.ctx, c.errorGroup, tarBallQueue, c.crypter, c.files, c.tarFileSets, info, ranges)
+		return
+	}
+	tarBall, err := tarBallQueue.DequeCtx(c.ctx)
 	if err != nil {
 		return
 	}
@@ -76,10 +120,36 @@ func (c *RegularTarBallComposer) AddFile(info *internal.ComposeFileInfo) {
 		if err != nil {
 			return err
 		}
-		return c.tarBallQueue.CheckSizeAndEnqueueBack(tarBall)
+		return tarBallQueue.CheckSizeAndEnqueueBack(tarBall)
 	})
 }
 
+func (c *RegularTarBallComposer) chooseTarBallQueue(info *internal.ComposeFileInfo) *internal.TarBallQueue {
+	if c.rawTarBallQueue == nil {
+		return c.tarBallQueue
+	}
+	incompressible, err := c.compressibility.IsIncompressible(info.Path, func() (io.ReadCloser, error) {
+		return os.Open(info.Path)
+	})
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to check the compressibility of %s, will compress it: %v", info.Path, err)
+		return c.tarBallQueue
+	}
+	if incompressible {
+		tracelog.DebugLogger.Printf("Skipping the compression of %s", info.Path)
+		c.useFeature(RawTarPartsFeature)
+		return c.rawTarBallQueue
+	}
+	return c.tarBallQueue
+}
+
+// useFeature records the optional format feature in the sentinel of the backup
+func (c *RegularTarBallComposer) useFeature(feature string) {
+	if c.usedFeatures != nil {
+		c.usedFeatures.add(feature)
+	}
+}
+
 func (c *RegularTarBallComposer) AddHeader(fileInfoHeader *tar.Header, info os.FileInfo) error {
 	tarBall, err := c.tarBallQueue.DequeCtx(c.ctx)
 	if err != nil {
@@ -101,6 +171,12 @@ func (c *RegularTarBallComposer) FinishComposing() (internal.TarFileSets, error)
 	if err != nil {
 		return nil, err
 	}
+	if c.rawTarBallQueue != nil {
+		err = c.rawTarBallQueue.FinishQueue()
+		if err != nil {
+			return nil, err
+		}
+	}
 	return c.tarFileSets, nil
 }
 
@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const (
+	standbySignalFile   = "standby.signal"
+	recoveryConfFile    = "recovery.conf"
+	autoConfFile        = "postgresql.auto.conf"
+	reseedConfigComment = "# added by wal-g reseed"
+)
+
+// ReseedArgs describes how to turn the old primary into a standby
+type ReseedArgs struct {
+	PgData          string
+	PrimaryConnInfo string
+	RestoreCommand  string
+	PgRewindPath    string
+	BackupSelector  internal.BackupSelector
+	// ForceRestore skips pg_rewind and restores the cluster from the backup
+	ForceRestore bool
+}
+
+// rewindImpossibleMessages are the pg_rewind errors meaning the cluster can't be rewound at all,
+// as opposed to the ones fixed by the user, e.g. the cluster which isn't shut down cleanly
+var rewindImpossibleMessages = []string{
+	"target server needs to use either data checksums or",
+	"source and target clusters are from different systems",
+	"clusters are not compatible with this version of pg_rewind",
+	"could not find common ancestor of the source and target cluster's timelines",
+	"could not find previous WAL record",
+	"could not restore file",
+}
+
+// RewindImpossibleError is returned if the cluster can't be rewound, so it has to be restored from the backup
+type RewindImpossibleError struct {
+	error
+}
+
+func (err RewindImpossibleError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// HandleReseed turns a failed old primary into a standby of the new primary. pg_rewind is tried first since it
+// transfers only the changed blocks; the WAL it is missing is fetched from the storage by the restore command.
+// Only if the cluster can't be rewound at all, the backup is restored onto it reusing the files unchanged since
+// the backup. Other pg_rewind failures are fatal, they are fixed by the user or overridden with ForceRestore.
+// In both cases the standby configuration pointing to the new primary and to the WAL archive is written.
+func HandleReseed(folder storage.Folder, args ReseedArgs) {
+	err := CheckPostmasterStopped(args.PgData)
+	tracelog.ErrorLogger.FatalOnError(err)
+
+	if !args.ForceRestore {
+		err = rewind(args)
+		if err == nil {
+			tracelog.InfoLogger.Printf("%s has been rewound", args.PgData)
+			err = WriteStandbyConfig(args.PgData, args.PrimaryConnInfo, args.RestoreCommand)
+			tracelog.ErrorLogger.FatalfOnError("Failed to write the standby configuration: %v", err)
+			tracelog.InfoLogger.Println("Reseed is done, start the cluster to follow the new primary")
+			return
+		}
+		var impossibleErr RewindImpossibleError
+		if !errors.As(err, &impossibleErr) {
+			tracelog.ErrorLogger.Fatalf("Failed to rewind %s: %v\n"+
+				"Fix the cause and run reseed again, or restore the cluster from the backup with --force-restore",
+				args.PgData, err)
+		}
+		tracelog.WarningLogger.Printf("%s can't be rewound, the backup will be restored onto it: %v", args.PgData, err)
+	}
+
+	err = os.MkdirAll(args.PgData, 0700)
+	tracelog.ErrorLogger.FatalfOnError("Failed to create the data directory: %v", err)
+	internal.HandleBackupFetch(folder, args.BackupSelector, getInPlaceFetcher(args.PgData))
+
+	err = WriteStandbyConfig(args.PgData, args.PrimaryConnInfo, args.RestoreCommand)
+	tracelog.ErrorLogger.FatalfOnError("Failed to write the standby configuration: %v", err)
+	tracelog.InfoLogger.Println("Reseed is done, start the cluster to follow the new primary")
+}
+
+// rewind runs pg_rewind. PostgreSQL 13+ fetches the missing WAL with the restore_command of the target cluster
+// and finishes the crash recovery of the cluster which isn't shut down cleanly, older versions need the WAL
+// in pg_wal and the clean shutdown.
+func rewind(args ReseedArgs) error {
+	version, err := readPgMajorVersion(args.PgData)
+	if err != nil {
+		return RewindImpossibleError{err}
+	}
+	if _, err := ExtractPgControl(args.PgData); err != nil {
+		return RewindImpossibleError{errors.Wrap(err, "no valid cluster to rewind")}
+	}
+	err = WriteStandbyConfig(args.PgData, args.PrimaryConnInfo, args.RestoreCommand)
+	if err != nil {
+		return errors.Wrap(err, "failed to write the standby configuration")
+	}
+
+	rewindArgs := []string{"--target-pgdata=" + args.PgData, "--source-server=" + args.PrimaryConnInfo, "--progress"}
+	if version >= 13 {
+		rewindArgs = append(rewindArgs, "--restore-target-wal")
+	} else {
+		tracelog.WarningLogger.Printf("pg_rewind of PostgreSQL %d can't fetch the WAL from the storage, "+
+			"the WAL since the divergence has to be in pg_wal", version)
+	}
+	cmd := exec.Command(args.PgRewindPath, rewindArgs...)
+	// the errors are matched in English
+	cmd.Env = append(os.Environ(), "LC_MESSAGES=C")
+	output := new(bytes.Buffer)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = io.MultiWriter(os.Stderr, output)
+	tracelog.InfoLogger.Printf("Running %s", strings.Join(cmd.Args[:2], " "))
+	err = cmd.Run()
+	if err != nil && isRewindImpossible(output.String()) {
+		return RewindImpossibleError{errors.Wrap(err, "pg_rewind")}
+	}
+	return err
+}
+
+func isRewindImpossible(output string) bool {
+	for _, message := range rewindImpossibleMessages {
+		if strings.Contains(output, message) {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteStandbyConfig configures the cluster to follow the primary and to fetch the WAL from the archive.
+// PostgreSQL 12+ gets standby.signal and the settings in postgresql.auto.conf, older versions get recovery.conf.
+// The settings written by the previous runs are replaced.
+func WriteStandbyConfig(pgData, primaryConnInfo, restoreCommand string) error {
+	settings := []string{
+		fmt.Sprintf("primary_conninfo = %s", quoteConfigValue(primaryConnInfo)),
+		fmt.Sprintf("restore_command = %s", quoteConfigValue(restoreCommand)),
+		"recovery_target_timeline = 'latest'",
+	}
+
+	version, err := readPgMajorVersion(pgData)
+	if err != nil {
+		return err
+	}
+	if version < 12 {
+		settings = append([]string{"standby_mode = 'on'"}, settings...)
+		content := reseedConfigComment + "\n" + strings.Join(settings, "\n") + "\n"
+		return os.WriteFile(filepath.Join(pgData, recoveryConfFile), []byte(content), 0600)
+	}
+
+	err = os.WriteFile(filepath.Join(pgData, standbySignalFile), nil, 0600)
+	if err != nil {
+		return err
+	}
+	autoConfPath := filepath.Join(pgData, autoConfFile)
+	autoConf, err := os.ReadFile(autoConfPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	lines := make([]string, 0)
+	for _, line := range strings.Split(strings.TrimRight(string(autoConf), "\n"), "\n") {
+		if line == "" || line == reseedConfigComment || isOverriddenSetting(line) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, reseedConfigComment)
+	lines = append(lines, settings...)
+	return os.WriteFile(autoConfPath, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+}
+
+func isOverriddenSetting(line string) bool {
+	name := strings.TrimSpace(strings.SplitN(line, "=", 2)[0])
+	return name == "primary_conninfo" || name == "restore_command" || name == "recovery_target_timeline"
+}
+
+func quoteConfigValue(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+func readPgMajorVersion(pgData string) (int, error) {
+	content, err := os.ReadFile(filepath.Join(pgData, "PG_VERSION"))
+	if err != nil {
+		return 0, errors.Wrapf(err, "%s doesn't look like a data directory", pgData)
+	}
+	// PG_VERSION contains "9.6" or "15"
+	major := strings.SplitN(strings.TrimSpace(string(content)), ".", 2)[0]
+	version, err := strconv.Atoi(major)
+	return version, errors.Wrapf(err, "failed to parse PG_VERSION")
+}
@@ -0,0 +1,110 @@
+package postgres_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+)
+
+func TestWriteStandbyConfig(t *testing.T) {
+	pgData := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(pgData, "PG_VERSION"), []byte("15\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(pgData, "postgresql.auto.conf"),
+		[]byte("work_mem = '64MB'\nprimary_conninfo = 'host=old'\n"), 0600))
+
+	for i := 0; i < 2; i++ {
+		err := postgres.WriteStandbyConfig(pgData, "host=new-primary user=repl", `wal-g wal-fetch "%f" "%p"`)
+		require.NoError(t, err)
+	}
+
+	assert.FileExists(t, filepath.Join(pgData, "standby.signal"))
+	autoConf, err := os.ReadFile(filepath.Join(pgData, "postgresql.auto.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, "work_mem = '64MB'\n"+
+		"# added by wal-g reseed\n"+
+		"primary_conninfo = 'host=new-primary user=repl'\n"+
+		"restore_command = 'wal-g wal-fetch \"%f\" \"%p\"'\n"+
+		"recovery_target_timeline = 'latest'\n", string(autoConf))
+}
+
+func TestWriteStandbyConfig_RecoveryConf(t *testing.T) {
+	pgData := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(pgData, "PG_VERSION"), []byte("9.6\n"), 0600))
+
+	err := postgres.WriteStandbyConfig(pgData, "host=new-primary password='it''s'", "wal-g wal-fetch %f %p")
+	require.NoError(t, err)
+
+	assert.NoFileExists(t, filepath.Join(pgData, "standby.signal"))
+	recoveryConf, err := os.ReadFile(filepath.Join(pgData, "recovery.conf"))
+	require.NoError(t, err)
+	assert.Contains(t, string(recoveryConf), "standby_mode = 'on'\n")
+	assert.Contains(t, string(recoveryConf), "primary_conninfo = 'host=new-primary password=''it''''s'''\n")
+}
+
+func TestWriteStandbyConfig_NotDataDirectory(t *testing.T) {
+	err := postgres.WriteStandbyConfig(t.TempDir(), "host=new-primary", "wal-g wal-fetch %f %p")
+	assert.Error(t, err)
+}
+
+func TestCheckPostmasterStopped(t *testing.T) {
+	pgData := t.TempDir()
+	assert.NoError(t, postgres.CheckPostmasterStopped(pgData))
+
+	pidPath := filepath.Join(pgData, "postmaster.pid")
+	require.NoError(t, os.WriteFile(pidPath, []byte(fmt.Sprintf("%d\n%s\n", os.Getpid(), pgData)), 0600))
+	assert.Error(t, postgres.CheckPostmasterStopped(pgData), "the process is running")
+
+	// the PID is above the Linux pid_max, so it's a stale file
+	require.NoError(t, os.WriteFile(pidPath, []byte("4194305\n"), 0600))
+	assert.NoError(t, postgres.CheckPostmasterStopped(pgData))
+
+	require.NoError(t, os.WriteFile(pidPath, []byte("garbage\n"), 0600))
+	assert.Error(t, postgres.CheckPostmasterStopped(pgData))
+}
+
+func TestPrepareInPlaceRestore(t *testing.T) {
+	pgData := t.TempDir()
+	backupTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeFile := func(name, content string, mtime time.Time) {
+		path := filepath.Join(pgData, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+		require.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+	writeFile("base/1/1259", "unchanged", backupTime)
+	writeFile("base/1/1260", "changed", backupTime.Add(time.Hour))
+	writeFile("base/1/1261", "resized", backupTime)
+	writeFile("base/1/16384", "created after the backup", backupTime.Add(time.Hour))
+	writeFile("global/pg_control", "control", backupTime)
+	writeFile("pg_wal/000000010000000000000001", "diverged WAL", backupTime)
+	writeFile("postmaster.opts", "excluded", backupTime)
+
+	filesMeta := postgres.FilesMetadataDto{Files: internal.BackupFileList{
+		"/base/1/1259":       {MTime: backupTime, Size: int64(len("unchanged"))},
+		"/base/1/1260":       {MTime: backupTime, Size: int64(len("changed"))},
+		"/base/1/1261":       {MTime: backupTime, Size: 1},
+		"/base/1/1262":       {MTime: backupTime},
+		"/global/pg_control": {MTime: backupTime},
+	}}
+	filesToUnwrap, err := postgres.PrepareInPlaceRestore(pgData, filesMeta)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]bool{
+		"/base/1/1260": true, "/base/1/1261": true, "/base/1/1262": true,
+		postgres.PgControlPath: true, postgres.BackupLabelFilename: true, postgres.TablespaceMapFilename: true,
+	}, filesToUnwrap)
+	assert.FileExists(t, filepath.Join(pgData, "base/1/1259"))
+	assert.FileExists(t, filepath.Join(pgData, "postmaster.opts"))
+	for _, removed := range []string{"base/1/1260", "base/1/1261", "base/1/16384", "global/pg_control",
+		"pg_wal/000000010000000000000001"} {
+		assert.NoFileExists(t, filepath.Join(pgData, removed))
+	}
+	assert.DirExists(t, filepath.Join(pgData, "pg_wal"))
+}
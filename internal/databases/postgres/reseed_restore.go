@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const postmasterPidFile = "postmaster.pid"
+
+// CheckPostmasterStopped refuses to touch the data directory of a running cluster. The postmaster.pid left
+// by a crashed server is ignored if its process is gone.
+func CheckPostmasterStopped(pgData string) error {
+	content, err := os.ReadFile(filepath.Join(pgData, postmasterPidFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", postmasterPidFile)
+	}
+	firstLine, _, _ := strings.Cut(string(content), "\n")
+	pid, err := strconv.Atoi(strings.TrimSpace(firstLine))
+	if err != nil || pid <= 0 {
+		return errors.Errorf("malformed %s in %s, stop the cluster or remove the file if it isn't running",
+			postmasterPidFile, pgData)
+	}
+	if isProcessAlive(pid) {
+		return errors.Errorf("the cluster in %s is running (postmaster PID %d), stop it first", pgData, pid)
+	}
+	tracelog.InfoLogger.Printf("Ignoring the stale %s of the stopped cluster (PID %d)", postmasterPidFile, pid)
+	return nil
+}
+
+func isProcessAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	// the process of another user can't be signaled, but it exists
+	return err == nil || errors.Is(err, os.ErrPermission)
+}
+
+// getInPlaceFetcher restores the backup onto the existing data directory reusing its files,
+// see PrepareInPlaceRestore. Only the tar parts holding the changed files are downloaded.
+func getInPlaceFetcher(pgData string) internal.Fetcher {
+	return func(rootFolder storage.Folder, backup internal.Backup) {
+		pgBackup := ToPgBackup(backup)
+		_, filesMeta, err := pgBackup.GetSentinelAndFilesMetadata()
+		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+		if len(filesMeta.Files) == 0 {
+			tracelog.ErrorLogger.Fatalf("Backup %s has no files metadata, so the files of %s can't be reused: "+
+				"restore the backup with backup-fetch into an empty directory instead", backup.Name, pgData)
+		}
+
+		filesToUnwrap, err := PrepareInPlaceRestore(pgData, filesMeta)
+		tracelog.ErrorLogger.FatalfOnError("Failed to prepare the data directory for the restore: %v\n", err)
+		tracelog.InfoLogger.Printf("Restoring %d changed files of %d from backup %s",
+			len(filesToUnwrap)-len(UtilityFilePaths), len(filesMeta.Files), backup.Name)
+
+		config := NewFetchConfig(utility.ResolveSymlink(pgData), pgBackup, rootFolder, nil,
+			filesToUnwrap, true, ExtractProviderImpl{})
+		err = deltaFetchRecursionNew(config)
+		tracelog.ErrorLogger.FatalfOnError("Failed to fetch backup: %v\n", err)
+	}
+}
+
+// PrepareInPlaceRestore turns the data directory into the one the changed files of the backup are restored to,
+// as into an empty one. The files of the same size and modification time as recorded in the backup haven't been
+// written since they were backed up, so they are kept. The other files, including the ones missing from the backup,
+// are removed, the same as the contents of pg_wal, so the WAL of the old timeline isn't replayed. The tablespaces
+// linked from pg_tblspc are handled the same way. The files to restore are returned.
+func PrepareInPlaceRestore(pgData string, filesMeta FilesMetadataDto) (map[string]bool, error) {
+	pgData = utility.ResolveSymlink(pgData)
+	filesToUnwrap := make(map[string]bool)
+	for name := range filesMeta.Files {
+		filesToUnwrap[name] = true
+	}
+	for name := range UtilityFilePaths {
+		filesToUnwrap[name] = true
+	}
+
+	kept := 0
+	err := walkDataFiles(pgData, func(name, path string, info fs.FileInfo) error {
+		description, inBackup := filesMeta.Files[name]
+		if inBackup && !UtilityFilePaths[name] && description.MTime.Equal(info.ModTime()) &&
+			(description.Size == 0 || description.Size == info.Size()) {
+			delete(filesToUnwrap, name)
+			kept++
+			return nil
+		}
+		return os.Remove(path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	tracelog.InfoLogger.Printf("Keeping %d files unchanged since the backup", kept)
+	return filesToUnwrap, clearDirectory(filepath.Join(pgData, "pg_wal"))
+}
+
+// walkDataFiles calls fn for the regular files of the data directory and of the tablespaces with their names
+// in the backup, e.g. /base/1/1259. The files excluded from the backups are skipped.
+func walkDataFiles(pgData string, fn func(name, path string, info fs.FileInfo) error) error {
+	return walkDataFilesFrom(pgData, "", fn)
+}
+
+func walkDataFilesFrom(root, namePrefix string, fn func(name, path string, info fs.FileInfo) error) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		if _, excluded := ExcludedFilenames[entry.Name()]; excluded {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := namePrefix + utility.PathSeparator + filepath.ToSlash(utility.GetSubdirectoryRelativePath(path, root))
+		if entry.Type()&os.ModeSymlink != 0 {
+			if filepath.Base(filepath.Dir(path)) != TablespaceFolder {
+				return nil
+			}
+			location, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				return errors.Wrapf(err, "failed to resolve tablespace %s", path)
+			}
+			return walkDataFilesFrom(location, name, fn)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		return fn(name, path, info)
+	})
+}
+
+// clearDirectory removes the files in the directory recursively, keeping the directories.
+// The directory may be a symlink, e.g. pg_wal moved to another disk.
+func clearDirectory(dir string) error {
+	dir, err := filepath.EvalSymlinks(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		return os.Remove(path)
+	})
+}
@@ -130,8 +130,12 @@ func (bb *StreamingBaseBackup) Upload(ctx context.Context, uploader internal.Upl
 	bb.streamer = NewTarballStreamer(bb, bb.maxTarSize, bundleFiles)
 	for {
 		tbsTar := ioextensions.NewNamedReaderImpl(bb.streamer, bb.FileName())
-		compressedFile := internal.CompressAndEncrypt(tbsTar, bb.uploader.Compression(), internal.ConfigureCrypter())
 		dstPath := fmt.Sprintf("%s.%s", bb.Path(), bb.uploader.Compression().FileExtension())
+		compressedFile, err := internal.CompressAndEncryptScanned(ctx, bb.uploader, dstPath, tbsTar,
+			bb.uploader.Compression(), internal.ConfigureCrypter())
+		if err != nil {
+			return err
+		}
 		err = bb.uploader.Upload(ctx, dstPath, compressedFile)
 		if err != nil {
 			return err
@@ -154,9 +158,13 @@ func (bb *StreamingBaseBackup) Upload(ctx context.Context, uploader internal.Upl
 	// Upload the extra tar
 	if len(bb.streamer.Tee) > 0 {
 		teeTar := ioextensions.NewNamedReaderImpl(bb.streamer.TeeIo, bb.FileName())
-		teeCompressedFile := internal.CompressAndEncrypt(teeTar, bb.uploader.Compression(), internal.ConfigureCrypter())
 		teeFileName := fmt.Sprintf("pg_control.tar.%s", bb.uploader.Compression().FileExtension())
 		teeFilePath := storage.JoinPath(bb.BackupName(), internal.TarPartitionFolderName, teeFileName)
+		teeCompressedFile, err := internal.CompressAndEncryptScanned(ctx, bb.uploader, teeFilePath, teeTar,
+			bb.uploader.Compression(), internal.ConfigureCrypter())
+		if err != nil {
+			return err
+		}
 		err = bb.uploader.Upload(ctx, teeFilePath, teeCompressedFile)
 		if err != nil {
 			return err
@@ -2,10 +2,13 @@ package postgres
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/wal-g/tracelog"
 
 	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression/computils"
+	conf "github.com/wal-g/wal-g/internal/config"
 )
 
 type TarBallComposerType int
@@ -31,12 +34,14 @@ func NewTarBallComposerMaker(composerType TarBallComposerType, queryRunner *PgQu
 		if composerType != RegularComposer {
 			tracelog.InfoLogger.Printf("No files metadata mode is enabled. Choosing the regular tar ball composer.")
 		}
-		return NewRegularTarBallComposerMaker(filePackOptions, &internal.NopBundleFiles{}, internal.NewNopTarFileSets()), nil
+		maker := NewRegularTarBallComposerMaker(filePackOptions, &internal.NopBundleFiles{}, internal.NewNopTarFileSets())
+		return configureCompressionSkipping(maker, uploader, newBackupName)
 	}
 
 	switch composerType {
 	case RegularComposer:
-		return NewRegularTarBallComposerMaker(filePackOptions, &internal.RegularBundleFiles{}, internal.NewRegularTarFileSets()), nil
+		maker := NewRegularTarBallComposerMaker(filePackOptions, &internal.RegularBundleFiles{}, internal.NewRegularTarFileSets())
+		return configureCompressionSkipping(maker, uploader, newBackupName)
 	case RatingComposer:
 		relFileStats, err := newRelFileStatistics(queryRunner)
 		if err != nil {
@@ -49,7 +54,8 @@ func NewTarBallComposerMaker(composerType TarBallComposerType, queryRunner *PgQu
 			tracelog.InfoLogger.Printf(
 				"Failed to init the CopyComposer, will use the RegularComposer instead:"+
 					" couldn't get the previous backup name: %v", err)
-			return NewRegularTarBallComposerMaker(filePackOptions, &internal.RegularBundleFiles{}, internal.NewRegularTarFileSets()), nil
+			maker := NewRegularTarBallComposerMaker(filePackOptions, &internal.RegularBundleFiles{}, internal.NewRegularTarFileSets())
+			return configureCompressionSkipping(maker, uploader, newBackupName)
 		}
 		previousPGBackup := ToPgBackup(previousBackup)
 		prevBackupSentinelDto, _, err := previousPGBackup.GetSentinelAndFilesMetadata()
@@ -74,3 +80,27 @@ func NewTarBallComposerMaker(composerType TarBallComposerType, queryRunner *PgQu
 		return nil, errors.New("NewTarBallComposerMaker: Unknown TarBallComposerType")
 	}
 }
+
+// configureCompressionSkipping sets up the regular composer to upload the files of the already compressed types
+// (WALG_COMPRESSION_SKIP_EXTENSIONS) and the files with high entropy (WALG_COMPRESSION_ENTROPY_THRESHOLD)
+// without compression, so no CPU is wasted on the data that won't shrink.
+func configureCompressionSkipping(maker *RegularTarBallComposerMaker, uploader internal.Uploader,
+	newBackupName string) (TarBallComposerMaker, error) {
+	var extensions []string
+	if value, ok := conf.GetSetting(conf.PgCompressionSkipExtensions); ok {
+		extensions = strings.Split(value, ",")
+	}
+	entropyThreshold, err := conf.GetFloatSettingDefault(conf.PgCompressionEntropyThreshold, 0)
+	if err != nil {
+		return nil, err
+	}
+	if entropyThreshold < 0 || entropyThreshold > 8 {
+		return nil, errors.New("WALG_COMPRESSION_ENTROPY_THRESHOLD must be between 0 and 8 bits per byte")
+	}
+
+	compressibility := computils.NewCompressibilityChecker(extensions, entropyThreshold)
+	if compressibility.Enabled() {
+		maker.SkipIncompressibleFiles(compressibility, internal.NewUncompressedStorageTarBallMaker(newBackupName, uploader))
+	}
+	return maker, nil
+}
@@ -7,6 +7,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
@@ -30,14 +31,24 @@ type FileTarInterpreter struct {
 	UnwrapResult    *UnwrapResult
 
 	createNewIncrementalFiles bool
+
+	// rangeFiles are the split files whose ranges are restored from this backup
+	rangeFiles      map[string]bool
+	rangeFilesMutex sync.Mutex
 }
 
 func NewFileTarInterpreter(
 	dbDataDirectory string, sentinel BackupSentinelDto, filesMetadata FilesMetadataDto,
 	filesToUnwrap map[string]bool, createNewIncrementalFiles bool,
 ) *FileTarInterpreter {
-	return &FileTarInterpreter{dbDataDirectory, sentinel, filesMetadata,
-		filesToUnwrap, newUnwrapResult(), createNewIncrementalFiles}
+	return &FileTarInterpreter{
+		DBDataDirectory:           dbDataDirectory,
+		Sentinel:                  sentinel,
+		FilesMetadata:             filesMetadata,
+		FilesToUnwrap:             filesToUnwrap,
+		UnwrapResult:              newUnwrapResult(),
+		createNewIncrementalFiles: createNewIncrementalFiles,
+	}
 }
 
 func (tarInterpreter *FileTarInterpreter) GetUnwrapResult() *UnwrapResult {
@@ -85,6 +96,9 @@ func (tarInterpreter *FileTarInterpreter) Interpret(fileReader io.Reader, fileIn
 	fsync := !viper.GetBool(conf.TarDisableFsyncSetting)
 	switch fileInfo.Typeflag {
 	case tar.TypeReg, tar.TypeRegA:
+		if _, _, isRange, _ := internal.GetFileRange(fileInfo); isRange {
+			return tarInterpreter.unwrapFileRange(fileReader, fileInfo, targetPath, fsync)
+		}
 		// temporary switch to determine if new unwrap logic should be used
 		if useNewUnwrapImplementation {
 			return tarInterpreter.unwrapRegularFileNew(fileReader, fileInfo, targetPath, fsync)
@@ -110,6 +124,81 @@ func (tarInterpreter *FileTarInterpreter) Interpret(fileReader io.Reader, fileIn
 	return nil
 }
 
+// unwrapFileRange restores the range of the file split into several tar entries
+func (tarInterpreter *FileTarInterpreter) unwrapFileRange(fileReader io.Reader, header *tar.Header,
+	targetPath string, fsync bool) error {
+	if tarInterpreter.FilesToUnwrap != nil {
+		if _, ok := tarInterpreter.FilesToUnwrap[header.Name]; !ok {
+			tracelog.DebugLogger.Printf("Don't have to unwrap '%s' this time\n", header.Name)
+			return nil
+		}
+	}
+	if !useNewUnwrapImplementation {
+		return internal.WriteFileRange(fileReader, header, targetPath, fsync)
+	}
+
+	// the new unwrap logic restores the newest version of the file first,
+	// so the file that exists before its ranges are restored from this backup comes from a newer backup
+	isNewer, isFirstRange, err := tarInterpreter.claimRangeFile(header.Name, targetPath)
+	if err != nil {
+		return err
+	}
+	if !isNewer {
+		err = internal.WriteFileRange(fileReader, header, targetPath, fsync)
+		if err == nil && isFirstRange {
+			tarInterpreter.AddFileUnwrapResult(NewCompletedResult(), header.Name)
+		}
+		return err
+	}
+
+	localFileInfo, err := utility.GetLocalFileInfo(targetPath)
+	if err != nil {
+		return err
+	}
+	if !isPagedFile(localFileInfo, targetPath) {
+		// skip the non-page file because newer version is already on the disk
+		return nil
+	}
+	fileRange, _, _, err := internal.GetFileRange(header)
+	if err != nil {
+		return err
+	}
+	localFile, err := os.OpenFile(targetPath, os.O_RDWR, 0666)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(localFile, "")
+	defer utility.LoggedSync(localFile, "", fsync)
+	targetReadWriterAt, err := NewReadWriterAtFrom(localFile)
+	if err != nil {
+		return err
+	}
+	err = restoreMissingPagesFrom(fileReader, targetReadWriterAt, fileRange.Offset/DatabasePageSize)
+	return errors.Wrapf(err, "Interpret: failed to restore pages for file '%s'", targetPath)
+}
+
+// claimRangeFile tells if the split file has been restored from a newer backup,
+// otherwise it remembers that the file is restored from this backup
+func (tarInterpreter *FileTarInterpreter) claimRangeFile(name, targetPath string) (isNewer, isFirstRange bool, err error) {
+	tarInterpreter.rangeFilesMutex.Lock()
+	defer tarInterpreter.rangeFilesMutex.Unlock()
+	if tarInterpreter.rangeFiles[name] {
+		return false, false, nil
+	}
+	_, err = os.Stat(targetPath)
+	if err == nil {
+		return true, false, nil
+	}
+	if !os.IsNotExist(err) {
+		return false, false, err
+	}
+	if tarInterpreter.rangeFiles == nil {
+		tarInterpreter.rangeFiles = make(map[string]bool)
+	}
+	tarInterpreter.rangeFiles[name] = true
+	return false, true, nil
+}
+
 // PrepareDirs makes sure all dirs exist
 func PrepareDirs(fileName string, targetPath string) error {
 	if fileName == targetPath {
@@ -48,7 +48,7 @@ func (t FilesToExtractProviderImpl) Get(backup Backup, filesToUnwrap map[string]
 			continue
 		}
 
-		tarToExtract := internal.NewStorageReaderMaker(backup.getTarPartitionFolder(), tarName)
+		tarToExtract := backup.newTarReaderMaker(tarName)
 		tarsToExtract = append(tarsToExtract, tarToExtract)
 	}
 	return tarsToExtract, pgControlKey, nil
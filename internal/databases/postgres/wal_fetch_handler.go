@@ -95,7 +95,15 @@ func HandleWALFetch(baseReader internal.StorageFolderReader, walFileName string,
 		time.Sleep(2 * time.Millisecond)
 	}
 
-	return internal.DownloadFileTo(reader, walFileName, location)
+	return internal.DownloadFileToPipelined(reader, walFileName, location, expectedWalFileSize(walFileName))
+}
+
+// expectedWalFileSize is the size of the WAL segment, 0 for the other files like the timeline history
+func expectedWalFileSize(walFileName string) int64 {
+	if _, _, err := ParseWALFilename(walFileName); err != nil {
+		return 0
+	}
+	return int64(WalSegmentSize)
 }
 
 // TODO : unit tests
@@ -11,6 +11,8 @@ import (
 
 	"github.com/wal-g/wal-g/internal"
 	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/walparser"
+	"github.com/wal-g/wal-g/utility"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
@@ -34,8 +36,13 @@ func (err CantOverwriteWalFileError) Error() string {
 // TODO : unit tests
 // HandleWALPush is invoked to perform wal-g wal-push
 func HandleWALPush(ctx context.Context, uploader *WalUploader, walFilePath string) error {
+	err := CheckPatroniBeforeWalPush(ctx)
+	if err != nil {
+		return err
+	}
+
 	if uploader.ArchiveStatusManager.IsWalAlreadyUploaded(walFilePath) {
-		err := uploader.ArchiveStatusManager.UnmarkWalFile(walFilePath)
+		err = uploader.ArchiveStatusManager.UnmarkWalFile(walFilePath)
 
 		if err != nil {
 			tracelog.ErrorLogger.Printf("unmark wal-g status for %s file failed due following error %+v", walFilePath, err)
@@ -88,6 +95,12 @@ func uploadWALFile(ctx context.Context, uploader *WalUploader, walFilePath strin
 			return errors.Wrap(err, "Couldn't check whether there is an overwrite attempt due to inner error")
 		}
 	}
+	if viper.GetBool(conf.PgPrevalidateWal) {
+		err := validateWalSegment(walFilePath)
+		if err != nil {
+			return err
+		}
+	}
 	walFile, err := os.Open(walFilePath)
 	if err != nil {
 		return errors.Wrapf(err, "upload: could not open '%s'\n", walFilePath)
@@ -96,6 +109,23 @@ func uploadWALFile(ctx context.Context, uploader *WalUploader, walFilePath strin
 	return errors.Wrapf(err, "upload: could not Upload '%s'\n", walFilePath)
 }
 
+// validateWalSegment checks the page headers of the WAL segment before it is archived, so that
+// a truncated file or a file of another segment is rejected instead of being acknowledged.
+// Files that are not WAL segments (.history, .partial, .backup) are not validated.
+func validateWalSegment(walFilePath string) error {
+	timeline, logSegNo, err := ParseWALFilename(filepath.Base(walFilePath))
+	if err != nil {
+		return nil
+	}
+	walFile, err := os.Open(walFilePath)
+	if err != nil {
+		return errors.Wrapf(err, "validate: could not open '%s'", walFilePath)
+	}
+	defer utility.LoggedClose(walFile, "")
+	err = walparser.ValidateSegment(walFile, timeline, logSegNo*WalSegmentSize, WalSegmentSize)
+	return errors.Wrapf(err, "validate: '%s' is not a valid WAL segment", walFilePath)
+}
+
 // TODO : unit tests
 func checkWALOverwrite(uploader *WalUploader, walFilePath string) (overwriteAttempt bool, err error) {
 	walFileReader, err := internal.DownloadAndDecompressStorageFile(internal.NewFolderReader(uploader.Folder()), filepath.Base(walFilePath))
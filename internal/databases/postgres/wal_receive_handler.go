@@ -54,6 +54,15 @@ func HandleWALReceive(ctx context.Context, uploader *WalUploader) {
 	var XLogPos pglogrepl.LSN
 	var segment *WalSegment
 
+	// the replication slot keeps the WAL while another host holds the lease, so this one may just exit
+	lease, err := internal.AcquireArchivingLease(uploader.Folder())
+	if _, ok := err.(internal.ArchivingLeaseHeldError); ok {
+		tracelog.InfoLogger.Println(err.Error())
+		return
+	}
+	tracelog.ErrorLogger.FatalOnError(err)
+	defer lease.Stop()
+
 	uploader.ChangeDirectory(utility.WalPath)
 
 	slot, walSegmentBytes, err := getCurrentWalInfo()
@@ -84,6 +93,10 @@ func HandleWALReceive(ctx context.Context, uploader *WalUploader) {
 	segment = NewWalSegment(timeline, XLogPos, walSegmentBytes)
 	startReplication(conn, segment, slot.Name)
 	for {
+		if lease.IsLost() {
+			// the restarted service waits for the lease, the replication slot keeps the WAL meanwhile
+			tracelog.ErrorLogger.Fatal("The archiving lease has been taken over by another host, stopping")
+		}
 		streamResult, err := segment.Stream(conn, StandbyMessageTimeout)
 		tracelog.ErrorLogger.FatalOnError(err)
 		tracelog.DebugLogger.Printf("Successfully received wal segment %s: ", segment.Name())
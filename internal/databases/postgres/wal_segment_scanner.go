@@ -1,6 +1,10 @@
 package postgres
 
-import "github.com/wal-g/wal-g/utility"
+import (
+	"fmt"
+
+	"github.com/wal-g/wal-g/utility"
+)
 
 type ScannedSegmentStatus int
 
@@ -34,6 +38,17 @@ func (status ScannedSegmentStatus) MarshalText() ([]byte, error) {
 	return utility.MarshalEnumToString(status)
 }
 
+// UnmarshalText unmarshals the ScannedSegmentStatus enum from a string, e.g. in the wal-verify shard reports
+func (status *ScannedSegmentStatus) UnmarshalText(text []byte) error {
+	for candidate := Lost; candidate <= Found; candidate++ {
+		if candidate.String() == string(text) {
+			*status = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown WAL segment status: %s", text)
+}
+
 // WalSegmentScanner is used to scan the WAL segments storage
 type WalSegmentScanner struct {
 	ScannedSegments  []ScannedSegmentDescription
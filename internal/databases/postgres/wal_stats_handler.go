@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/wal-g/wal-g/internal/printlist"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	WalStatsHourly = "hour"
+	WalStatsDaily  = "day"
+
+	walStatsProjectionDays = 30
+)
+
+// walSegmentFileRegexp matches the archived WAL segments only, skipping .history, .backup and .partial files
+var walSegmentFileRegexp = regexp.MustCompile(`^` + PatternTimelineAndLogSegNo + `(\.[a-z0-9]+)?$`)
+
+// WalStatsBucket describes the WAL segments archived in a single hour or day.
+type WalStatsBucket struct {
+	Start        time.Time `json:"start"`
+	Segments     int       `json:"segments"`
+	WalBytes     int64     `json:"wal_bytes"`
+	StoredBytes  int64     `json:"stored_bytes"`
+	BytesPerSec  float64   `json:"wal_bytes_per_second"`
+	bucketLength time.Duration
+}
+
+func (b WalStatsBucket) PrintableFields() []printlist.TableField {
+	timeFormat := "2006-01-02"
+	if b.bucketLength < 24*time.Hour {
+		timeFormat = "2006-01-02 15:00"
+	}
+	return []printlist.TableField{
+		{Name: "start", PrettyName: "Start (UTC)", Value: b.Start.Format(timeFormat)},
+		{Name: "segments", PrettyName: "Segments", Value: fmt.Sprint(b.Segments)},
+		{Name: "wal_bytes", PrettyName: "WAL generated", Value: fmt.Sprint(b.WalBytes),
+			PrettyValue: prettySize(b.WalBytes)},
+		{Name: "stored_bytes", PrettyName: "Stored", Value: fmt.Sprint(b.StoredBytes),
+			PrettyValue: prettySize(b.StoredBytes)},
+		{Name: "rate", PrettyName: "Rate", Value: fmt.Sprintf("%.0f", b.BytesPerSec),
+			PrettyValue: prettyRate(b.BytesPerSec)},
+	}
+}
+
+// WalStatsSummary aggregates the buckets and projects the archive growth.
+type WalStatsSummary struct {
+	Segments               int       `json:"segments"`
+	WalBytes               int64     `json:"wal_bytes"`
+	StoredBytes            int64     `json:"stored_bytes"`
+	AvgBytesPerSec         float64   `json:"avg_wal_bytes_per_second"`
+	PeakBytesPerSec        float64   `json:"peak_wal_bytes_per_second"`
+	PeakStart              time.Time `json:"peak_start"`
+	CompressionRatio       float64   `json:"compression_ratio"`
+	ProjectedDailyGrowth   int64     `json:"projected_daily_stored_bytes"`
+	ProjectedMonthlyGrowth int64     `json:"projected_30_days_stored_bytes"`
+}
+
+type WalStats struct {
+	Buckets []WalStatsBucket `json:"buckets"`
+	Summary WalStatsSummary  `json:"summary"`
+}
+
+// HandleWalStats analyzes the archived WAL segments modified in [since, until) and prints the generation rates.
+func HandleWalStats(
+	rootFolder storage.Folder,
+	period string,
+	since, until time.Time,
+	output io.Writer,
+	pretty, jsonOutput bool,
+) error {
+	bucketLength, err := walStatsBucketLength(period)
+	if err != nil {
+		return err
+	}
+	objects, _, err := rootFolder.GetSubFolder(utility.WalPath).ListFolder()
+	if err != nil {
+		return fmt.Errorf("list WAL folder: %w", err)
+	}
+
+	stats := CalculateWalStats(objects, bucketLength, since, until)
+	if jsonOutput {
+		encoder := json.NewEncoder(output)
+		if pretty {
+			encoder.SetIndent("", "    ")
+		}
+		return encoder.Encode(stats)
+	}
+
+	entities := make([]printlist.Entity, len(stats.Buckets))
+	for i := range stats.Buckets {
+		entities[i] = stats.Buckets[i]
+	}
+	err = printlist.List(entities, output, pretty, false)
+	if err != nil {
+		return err
+	}
+	summary := stats.Summary
+	_, err = fmt.Fprintf(output, "\nTotal: %d segments, %s of WAL, %s stored (compression ratio %.2f)\n"+
+		"Average rate: %s, peak rate: %s at %s\n"+
+		"Projected archive growth: %s per day, %s per %d days\n",
+		summary.Segments, *prettySize(summary.WalBytes), *prettySize(summary.StoredBytes), summary.CompressionRatio,
+		*prettyRate(summary.AvgBytesPerSec), *prettyRate(summary.PeakBytesPerSec),
+		summary.PeakStart.Format(time.RFC3339),
+		*prettySize(summary.ProjectedDailyGrowth), *prettySize(summary.ProjectedMonthlyGrowth), walStatsProjectionDays)
+	return err
+}
+
+// CalculateWalStats groups the WAL segments by the time they were archived. Every bucket in the window is reported,
+// including the ones without any segments, so the gaps are visible.
+func CalculateWalStats(objects []storage.Object, bucketLength time.Duration, since, until time.Time) WalStats {
+	since = since.UTC().Truncate(bucketLength)
+	until = until.UTC()
+
+	var buckets []WalStatsBucket
+	for start := since; start.Before(until); start = start.Add(bucketLength) {
+		buckets = append(buckets, WalStatsBucket{Start: start, bucketLength: bucketLength})
+	}
+
+	for _, object := range objects {
+		if !walSegmentFileRegexp.MatchString(object.GetName()) {
+			continue
+		}
+		modified := object.GetLastModified().UTC()
+		if modified.Before(since) || !modified.Before(until) {
+			continue
+		}
+		bucket := &buckets[int(modified.Sub(since)/bucketLength)]
+		bucket.Segments++
+		bucket.WalBytes += int64(WalSegmentSize)
+		bucket.StoredBytes += object.GetSize()
+	}
+
+	summary := WalStatsSummary{}
+	for i := range buckets {
+		buckets[i].BytesPerSec = float64(buckets[i].WalBytes) / bucketLength.Seconds()
+		summary.Segments += buckets[i].Segments
+		summary.WalBytes += buckets[i].WalBytes
+		summary.StoredBytes += buckets[i].StoredBytes
+		if buckets[i].BytesPerSec > summary.PeakBytesPerSec {
+			summary.PeakBytesPerSec = buckets[i].BytesPerSec
+			summary.PeakStart = buckets[i].Start
+		}
+	}
+
+	window := until.Sub(since)
+	if window > 0 {
+		summary.AvgBytesPerSec = float64(summary.WalBytes) / window.Seconds()
+		summary.ProjectedDailyGrowth = int64(float64(summary.StoredBytes) / window.Hours() * 24)
+		summary.ProjectedMonthlyGrowth = summary.ProjectedDailyGrowth * walStatsProjectionDays
+	}
+	if summary.StoredBytes > 0 {
+		summary.CompressionRatio = float64(summary.WalBytes) / float64(summary.StoredBytes)
+	}
+
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Start.Before(buckets[j].Start)
+	})
+	return WalStats{Buckets: buckets, Summary: summary}
+}
+
+func walStatsBucketLength(period string) (time.Duration, error) {
+	switch period {
+	case WalStatsHourly:
+		return time.Hour, nil
+	case WalStatsDaily:
+		return 24 * time.Hour, nil
+	default:
+		return 0, fmt.Errorf("unknown period %q, expected %q or %q", period, WalStatsHourly, WalStatsDaily)
+	}
+}
+
+func prettySize(bytes int64) *string {
+	size := formatBytes(float64(bytes))
+	return &size
+}
+
+func prettyRate(bytesPerSec float64) *string {
+	rate := formatBytes(bytesPerSec) + "/s"
+	return &rate
+}
+
+func formatBytes(bytes float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+	unit := 0
+	for bytes >= 1024 && unit < len(units)-1 {
+		bytes /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s", bytes, units[unit])
+}
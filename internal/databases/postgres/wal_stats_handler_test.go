@@ -0,0 +1,38 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+func TestCalculateWalStats(t *testing.T) {
+	since := time.Date(2023, 7, 22, 0, 0, 0, 0, time.UTC)
+	segmentSize := int64(postgres.WalSegmentSize)
+	objects := []storage.Object{
+		storage.NewLocalObject("000000010000000000000001.lz4", since.Add(10*time.Minute), 1<<20),
+		storage.NewLocalObject("000000010000000000000002.lz4", since.Add(20*time.Minute), 1<<20),
+		storage.NewLocalObject("000000010000000000000003.lz4", since.Add(2*time.Hour), 1<<20),
+		storage.NewLocalObject("00000002.history.lz4", since.Add(2*time.Hour), 100),
+		storage.NewLocalObject("000000010000000000000003.00000028.backup.lz4", since.Add(2*time.Hour), 100),
+		storage.NewLocalObject("000000010000000000000000.lz4", since.Add(-time.Minute), 1<<20),
+	}
+
+	stats := postgres.CalculateWalStats(objects, time.Hour, since, since.Add(3*time.Hour))
+	require.Len(t, stats.Buckets, 3)
+	assert.Equal(t, 2, stats.Buckets[0].Segments)
+	assert.Equal(t, 0, stats.Buckets[1].Segments)
+	assert.Equal(t, 1, stats.Buckets[2].Segments)
+	assert.Equal(t, 2*segmentSize, stats.Buckets[0].WalBytes)
+
+	assert.Equal(t, 3, stats.Summary.Segments)
+	assert.Equal(t, int64(3<<20), stats.Summary.StoredBytes)
+	assert.Equal(t, since, stats.Summary.PeakStart)
+	assert.InDelta(t, float64(2*segmentSize)/3600, stats.Summary.PeakBytesPerSec, 0.001)
+	assert.Equal(t, int64(3<<20)*8, stats.Summary.ProjectedDailyGrowth)
+	assert.InDelta(t, float64(segmentSize)/float64(1<<20), stats.Summary.CompressionRatio, 0.001)
+}
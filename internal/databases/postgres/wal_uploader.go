@@ -1,16 +1,21 @@
 package postgres
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"path"
 
+	"github.com/spf13/viper"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal"
 	"github.com/wal-g/wal-g/internal/asm"
+	conf "github.com/wal-g/wal-g/internal/config"
 	"github.com/wal-g/wal-g/internal/multistorage"
 	"github.com/wal-g/wal-g/internal/multistorage/policies"
+	"github.com/wal-g/wal-g/internal/walz"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 
 	"github.com/wal-g/wal-g/internal/ioextensions"
@@ -51,24 +56,90 @@ func (walUploader *WalUploader) clone() *WalUploader {
 
 // TODO : unit tests
 func (walUploader *WalUploader) UploadWalFile(ctx context.Context, file ioextensions.NamedReader) error {
-	var walFileReader io.Reader
+	var walFileReader io.Reader = file
 
 	filename := path.Base(file.Name())
-	if walUploader.getUseWalDelta() && isWalFilename(filename) {
-		recordingReader, err := NewWalDeltaRecordingReader(file, filename, walUploader.DeltaFileManager)
+	var envelopeHeader *walz.Header
+	if viper.GetBool(conf.PgWalEnvelopeSetting) {
+		header, content, cleanup, err := readWalEnvelopeHeader(file, filename)
 		if err != nil {
-			walFileReader = file
-		} else {
+			return err
+		}
+		defer cleanup()
+		envelopeHeader = &header
+		walFileReader = content
+	}
+
+	if walUploader.getUseWalDelta() && isWalFilename(filename) {
+		recordingReader, err := NewWalDeltaRecordingReader(walFileReader, filename, walUploader.DeltaFileManager)
+		if err == nil {
 			walFileReader = recordingReader
 			defer utility.LoggedClose(recordingReader, "")
 		}
-	} else {
-		walFileReader = file
 	}
 
+	if envelopeHeader != nil {
+		return walUploader.uploadWalEnvelope(ctx, walFileReader, filename, *envelopeHeader)
+	}
 	return walUploader.UploadFile(ctx, ioextensions.NewNamedReaderImpl(walFileReader, file.Name()))
 }
 
+// readWalEnvelopeHeader reads the WAL file once to put its size and checksum into the envelope header and returns
+// the reader of the content to upload. The files that can't be rewound, e.g. the segments received by wal-receive,
+// are spooled to a temporary file meanwhile, so the file is never kept in memory.
+func readWalEnvelopeHeader(file io.Reader, filename string) (walz.Header, io.Reader, func(), error) {
+	if seeker, ok := file.(io.ReadSeeker); ok {
+		header, err := walz.NewHeaderFromReader(filename, seeker)
+		if err != nil {
+			return walz.Header{}, nil, nil, err
+		}
+		_, err = seeker.Seek(0, io.SeekStart)
+		return header, seeker, func() {}, err
+	}
+
+	spool, err := os.CreateTemp("", "walg.walz.")
+	if err != nil {
+		return walz.Header{}, nil, nil, err
+	}
+	cleanup := func() {
+		utility.LoggedClose(spool, "")
+		if err := os.Remove(spool.Name()); err != nil {
+			tracelog.WarningLogger.Printf("Failed to remove the WAL spool file %s: %v", spool.Name(), err)
+		}
+	}
+	header, err := walz.NewHeaderFromReader(filename, io.TeeReader(file, spool))
+	if err == nil {
+		_, err = spool.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		cleanup()
+		return walz.Header{}, nil, nil, err
+	}
+	return header, spool, cleanup, nil
+}
+
+// uploadWalEnvelope uploads the WAL file in the walz envelope: the header describing the file is followed by
+// the compressed and encrypted content.
+func (walUploader *WalUploader) uploadWalEnvelope(ctx context.Context, walFileReader io.Reader, filename string,
+	header walz.Header) error {
+	header.Compression = walUploader.Compression().FileExtension()
+	if crypter := internal.ConfigureCrypter(); crypter != nil {
+		keyID, err := internal.CrypterFingerprint(crypter)
+		if err != nil {
+			return err
+		}
+		header.KeyID = keyID
+	}
+
+	var headerBuffer bytes.Buffer
+	_, err := header.WriteTo(&headerBuffer)
+	if err != nil {
+		return err
+	}
+	dstPath := utility.SanitizePath(filename + "." + walz.FileExtension)
+	return walUploader.UploadFileWithHeader(ctx, dstPath, headerBuffer.Bytes(), walFileReader)
+}
+
 func (walUploader *WalUploader) FlushFiles(ctx context.Context) {
 	walUploader.DeltaFileManager.FlushFiles(ctx, walUploader)
 }
@@ -86,7 +157,7 @@ func PrepareMultiStorageWalUploader(folder storage.Folder, targetStorage string)
 	}
 	tracelog.InfoLogger.Printf("Files will be uploaded to storage: %v", multistorage.UsedStorages(folder)[0])
 
-	baseUploader, err := internal.ConfigureUploaderToFolder(folder)
+	baseUploader, err := internal.ConfigureWalStreamUploaderToFolder(folder)
 	if err != nil {
 		return nil, fmt.Errorf("configure base uploader: %w", err)
 	}
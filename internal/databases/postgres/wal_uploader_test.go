@@ -0,0 +1,54 @@
+package postgres_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/internal/walz"
+	"github.com/wal-g/wal-g/testtools"
+)
+
+func TestWalUploader_UploadWalFileInEnvelope(t *testing.T) {
+	viper.Set(conf.PgWalEnvelopeSetting, true)
+	defer viper.Set(conf.PgWalEnvelopeSetting, false)
+	content := bytes.Repeat([]byte("timeline history"), 1000)
+
+	historyPath := filepath.Join(t.TempDir(), "00000002.history")
+	require.NoError(t, os.WriteFile(historyPath, content, 0600))
+	seekableFile, err := os.Open(historyPath)
+	require.NoError(t, err)
+	defer seekableFile.Close()
+
+	files := map[string]ioextensions.NamedReader{
+		"seekable file": seekableFile,
+		"streamed file": ioextensions.NewNamedReaderImpl(bytes.NewReader(content), "00000003.history"),
+	}
+	for name, file := range files {
+		t.Run(name, func(t *testing.T) {
+			uploader := testtools.NewMockWalDirUploader(false, false)
+			require.NoError(t, uploader.UploadWalFile(context.Background(), file))
+
+			reader, err := uploader.Folder().ReadObject(filepath.Base(file.Name()) + "." + walz.FileExtension)
+			require.NoError(t, err)
+			header, err := walz.ReadHeader(reader)
+			require.NoError(t, err)
+			expected := walz.NewHeader(filepath.Base(file.Name()), content)
+			assert.Equal(t, expected.Size, header.Size)
+			assert.Equal(t, expected.Checksum, header.Checksum)
+			assert.Equal(t, uploader.Compression().FileExtension(), header.Compression)
+
+			// the envelope goes through the shared upload path, which tracks the uploaded sizes
+			rawSize, err := uploader.RawDataSize()
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(content)), rawSize)
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// WalVerifyShard is the part of the WAL segments range verified by a single worker: the range between
+// the earliest backup and the current segment is split into Count parts of the same size
+type WalVerifyShard struct {
+	Index int
+	Count int
+}
+
+// ParseWalVerifyShard parses the shard given as "index/count", e.g. "2/8"
+func ParseWalVerifyShard(shard string) (WalVerifyShard, error) {
+	indexStr, countStr, ok := strings.Cut(shard, "/")
+	index, indexErr := strconv.Atoi(indexStr)
+	count, countErr := strconv.Atoi(countStr)
+	if !ok || indexErr != nil || countErr != nil || count < 1 || index < 1 || index > count {
+		return WalVerifyShard{}, fmt.Errorf("invalid shard '%s': expected 'index/count' with 1 <= index <= count", shard)
+	}
+	return WalVerifyShard{Index: index, Count: count}, nil
+}
+
+func (shard WalVerifyShard) String() string {
+	return fmt.Sprintf("%d/%d", shard.Index, shard.Count)
+}
+
+// WalVerifyShardReport is the partial result of the integrity check made by a single shard.
+// The reports of all the shards are merged into the final result.
+type WalVerifyShardReport struct {
+	Shard          int                   `json:"shard"`
+	Shards         int                   `json:"shards"`
+	StartSegment   string                `json:"start_segment"`
+	StopSegmentNo  WalSegmentNo          `json:"stop_segment_no"`
+	NoBackupsFound bool                  `json:"no_backups_found"`
+	Sequences      IntegrityCheckDetails `json:"sequences"`
+}
+
+// HandleWalVerifyShard runs the integrity check of the shard and writes its partial report as JSON
+func HandleWalVerifyShard(rootFolder storage.Folder, currentWalSegment WalSegmentDescription,
+	shard WalVerifyShard, output io.Writer) error {
+	check, err := NewIntegrityCheckRunner(rootFolder, nil, currentWalSegment)
+	if err != nil {
+		return err
+	}
+	check.walFolderFilenames, err = check.listShardFilenames(rootFolder.GetSubFolder(utility.WalPath), shard)
+	if err != nil {
+		return errors.Wrap(err, "Failed to fetch WAL folder filenames")
+	}
+	tracelog.InfoLogger.Printf("Running the integrity check of shard %s\n", shard)
+	report, err := check.RunShard(shard)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(output).Encode(report)
+}
+
+// RunShard scans the shard of the segments range. The recent segments which are probably delayed or still uploading
+// are found the same way as without the sharding, so the merged reports of all the shards match the result of Run.
+func (check IntegrityCheckRunner) RunShard(shard WalVerifyShard) (WalVerifyShardReport, error) {
+	shardStartNo, shardStopNo := check.shardBounds(shard)
+	storageSegments := getSegmentsFromFiles(check.walFolderFilenames)
+
+	recentScanner := NewWalSegmentScanner(NewWalSegmentRunner(check.startWalSegment,
+		storageSegments,
+		check.stopWalSegmentNo,
+		check.timelineSwitchMap))
+	err := scanRecentSegments(recentScanner, check.uploadingSegmentRangeSize, check.delayedSegmentRangeSize)
+	if err != nil {
+		return WalVerifyShardReport{}, err
+	}
+	scannedSegments := make([]ScannedSegmentDescription, 0)
+	for _, segment := range recentScanner.ScannedSegments {
+		if segment.Number >= shardStopNo && segment.Number < shardStartNo {
+			scannedSegments = append(scannedSegments, segment)
+		}
+	}
+
+	// scan the rest of the shard below the recent segments
+	scanStartSegment := WalSegmentDescription{Number: shardStartNo, Timeline: check.timelineAt(shardStartNo)}
+	if recentEndSegment := recentScanner.walSegmentRunner.Current(); recentEndSegment.Number < shardStartNo {
+		scanStartSegment = recentEndSegment
+	}
+	segmentScanner := NewWalSegmentScanner(NewWalSegmentRunner(scanStartSegment,
+		storageSegments,
+		shardStopNo,
+		check.timelineSwitchMap))
+	err = segmentScanner.Scan(SegmentScanConfig{UnlimitedScan: true, MissingSegmentStatus: Lost})
+	if err != nil {
+		return WalVerifyShardReport{}, err
+	}
+	scannedSegments = append(scannedSegments, segmentScanner.ScannedSegments...)
+
+	return WalVerifyShardReport{
+		Shard:          shard.Index,
+		Shards:         shard.Count,
+		StartSegment:   check.startWalSegment.GetFileName(),
+		StopSegmentNo:  check.stopWalSegmentNo,
+		NoBackupsFound: check.noBackupsFound,
+		Sequences:      collapseSegmentsByStatusAndTimeline(scannedSegments),
+	}, nil
+}
+
+// shardBounds provides the segments the shard is scanned from (exclusive) and down to (inclusive)
+func (check IntegrityCheckRunner) shardBounds(shard WalVerifyShard) (startNo, stopNo WalSegmentNo) {
+	var rangeSize WalSegmentNo
+	if check.startWalSegment.Number > check.stopWalSegmentNo {
+		rangeSize = check.startWalSegment.Number - check.stopWalSegmentNo
+	}
+	// the runner scans the segments from the start one (exclusive) down to the stop one (inclusive)
+	startNo = check.stopWalSegmentNo + rangeSize*WalSegmentNo(shard.Index)/WalSegmentNo(shard.Count)
+	stopNo = check.stopWalSegmentNo + rangeSize*WalSegmentNo(shard.Index-1)/WalSegmentNo(shard.Count)
+	return startNo, stopNo
+}
+
+// listShardFilenames lists only the WAL files the shard may scan: the ones of its own range and the recent ones,
+// which are scanned by every shard. The storages supporting the prefix listing don't list the rest of the archive.
+func (check IntegrityCheckRunner) listShardFilenames(walFolder storage.Folder, shard WalVerifyShard) ([]string, error) {
+	shardStartNo, shardStopNo := check.shardBounds(shard)
+	recentStopNo := check.stopWalSegmentNo
+	recentRangeSize := WalSegmentNo(check.delayedSegmentRangeSize + check.uploadingSegmentRangeSize)
+	if check.startWalSegment.Number > recentStopNo+recentRangeSize {
+		recentStopNo = check.startWalSegment.Number - recentRangeSize
+	}
+
+	timelines := map[uint32]bool{check.startWalSegment.Timeline: true}
+	for _, record := range check.timelineSwitchMap {
+		timelines[record.timeline] = true
+	}
+	prefixes := make(map[string]bool)
+	for timeline := range timelines {
+		for _, prefix := range walSegmentRangePrefixes(timeline, shardStopNo, shardStartNo) {
+			prefixes[prefix] = true
+		}
+		for _, prefix := range walSegmentRangePrefixes(timeline, recentStopNo, check.startWalSegment.Number) {
+			prefixes[prefix] = true
+		}
+	}
+
+	filenames := make([]string, 0)
+	for prefix := range prefixes {
+		objects, err := storage.ListFolderWithPrefix(walFolder, prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			filenames = append(filenames, object.GetName())
+		}
+	}
+	return filenames, nil
+}
+
+// walSegmentRangePrefixes provides the few name prefixes of the WAL files of the timeline covering the segments
+// from stopNo to startNo inclusive. The prefixes cover the whole log files at the range bounds.
+func walSegmentRangePrefixes(timeline uint32, stopNo, startNo WalSegmentNo) []string {
+	const logIDDigits = 8
+	prefixes := make([]string, 0)
+	logID := uint64(stopNo) / xLogSegmentsPerXLogID
+	lastLogID := uint64(startNo) / xLogSegmentsPerXLogID
+	for logID <= lastLogID {
+		// take the largest block of the log IDs sharing the hex prefix which starts at logID and fits the range
+		digits, blockSize := 0, uint64(1)
+		for digits < logIDDigits && logID%(blockSize*16) == 0 && logID+blockSize*16-1 <= lastLogID {
+			digits++
+			blockSize *= 16
+		}
+		logIDHex := fmt.Sprintf("%08X", logID)
+		prefixes = append(prefixes, fmt.Sprintf("%08X", timeline)+logIDHex[:logIDDigits-digits])
+		logID += blockSize
+	}
+	return prefixes
+}
+
+// timelineAt provides the timeline of the segment the runner reaches when it walks down from the start segment
+func (check IntegrityCheckRunner) timelineAt(segmentNo WalSegmentNo) uint32 {
+	timeline := check.startWalSegment.Timeline
+	var switchSegmentNo WalSegmentNo
+	for recordSegmentNo, record := range check.timelineSwitchMap {
+		passed := recordSegmentNo > segmentNo && recordSegmentNo <= check.startWalSegment.Number
+		if passed && (switchSegmentNo == 0 || recordSegmentNo < switchSegmentNo) {
+			switchSegmentNo = recordSegmentNo
+			timeline = record.timeline
+		}
+	}
+	return timeline
+}
+
+// HandleWalVerifyMerge merges the partial reports of all the shards and writes the integrity check result
+func HandleWalVerifyMerge(reportPaths []string, outputWriter WalVerifyOutputWriter) error {
+	reports := make([]WalVerifyShardReport, 0, len(reportPaths))
+	for _, reportPath := range reportPaths {
+		report, err := readWalVerifyShardReport(reportPath)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+	}
+	result, err := MergeWalVerifyShardReports(reports)
+	if err != nil {
+		return err
+	}
+	return outputWriter.Write(map[WalVerifyCheckType]WalVerifyCheckResult{WalVerifyIntegrityCheck: result})
+}
+
+func readWalVerifyShardReport(reportPath string) (WalVerifyShardReport, error) {
+	file, err := os.Open(reportPath)
+	if err != nil {
+		return WalVerifyShardReport{}, err
+	}
+	defer utility.LoggedClose(file, "")
+	var report WalVerifyShardReport
+	if err = json.NewDecoder(file).Decode(&report); err != nil {
+		return WalVerifyShardReport{}, errors.Wrapf(err, "failed to read the shard report %s", reportPath)
+	}
+	return report, nil
+}
+
+// MergeWalVerifyShardReports produces the integrity check result from the reports of all the shards,
+// which must have verified the same range of the WAL segments
+func MergeWalVerifyShardReports(reports []WalVerifyShardReport) (WalVerifyCheckResult, error) {
+	if len(reports) == 0 {
+		return WalVerifyCheckResult{}, errors.New("no shard reports to merge")
+	}
+	first := reports[0]
+	seen := make(map[int]bool, len(reports))
+	var sequences []*IntegrityScanSegmentSequence
+	for _, report := range reports {
+		if report.Shards != first.Shards || report.StartSegment != first.StartSegment ||
+			report.StopSegmentNo != first.StopSegmentNo {
+			return WalVerifyCheckResult{}, fmt.Errorf("shard %d/%d has verified the segments from %d to %s, "+
+				"but shard %d/%d from %d to %s: run all the shards with the same --until segment and backups",
+				report.Shard, report.Shards, report.StopSegmentNo, report.StartSegment,
+				first.Shard, first.Shards, first.StopSegmentNo, first.StartSegment)
+		}
+		if seen[report.Shard] || report.Shard < 1 || report.Shard > report.Shards {
+			return WalVerifyCheckResult{}, fmt.Errorf("duplicate or invalid report of shard %d/%d", report.Shard, report.Shards)
+		}
+		seen[report.Shard] = true
+		sequences = append(sequences, report.Sequences...)
+	}
+	if len(seen) != first.Shards {
+		return WalVerifyCheckResult{}, fmt.Errorf("got the reports of %d shards out of %d", len(seen), first.Shards)
+	}
+
+	startNumbers := make(map[*IntegrityScanSegmentSequence]WalSegmentNo, len(sequences))
+	for _, sequence := range sequences {
+		startNo, err := newWalSegmentNoFromFilename(sequence.StartSegment)
+		if err != nil {
+			return WalVerifyCheckResult{}, errors.Wrap(err, "invalid segments sequence in the shard report")
+		}
+		startNumbers[sequence] = startNo
+	}
+	sort.Slice(sequences, func(i, j int) bool {
+		return startNumbers[sequences[i]] < startNumbers[sequences[j]]
+	})
+	return newWalIntegrityCheckResult(joinSegmentSequences(sequences), first.NoBackupsFound), nil
+}
+
+// joinSegmentSequences joins the adjacent sequences with the same timeline and status split by the shard bounds
+func joinSegmentSequences(sequences []*IntegrityScanSegmentSequence) []*IntegrityScanSegmentSequence {
+	joined := make([]*IntegrityScanSegmentSequence, 0, len(sequences))
+	for _, sequence := range sequences {
+		if len(joined) > 0 {
+			last := joined[len(joined)-1]
+			lastEndNo, lastErr := newWalSegmentNoFromFilename(last.EndSegment)
+			startNo, err := newWalSegmentNoFromFilename(sequence.StartSegment)
+			if lastErr == nil && err == nil && lastEndNo.Next() == startNo &&
+				last.TimelineID == sequence.TimelineID && last.Status == sequence.Status {
+				joined[len(joined)-1] = &IntegrityScanSegmentSequence{
+					TimelineID:    last.TimelineID,
+					StartSegment:  last.StartSegment,
+					EndSegment:    sequence.EndSegment,
+					SegmentsCount: last.SegmentsCount + sequence.SegmentsCount,
+					Status:        last.Status,
+				}
+				continue
+			}
+		}
+		joined = append(joined, sequence)
+	}
+	return joined
+}
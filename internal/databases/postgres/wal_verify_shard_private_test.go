@@ -0,0 +1,17 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWalSegmentRangePrefixes(t *testing.T) {
+	segmentsPerLog := WalSegmentNo(xLogSegmentsPerXLogID)
+	assert.Equal(t, []string{"0000000200000000"}, walSegmentRangePrefixes(2, 1, 5))
+	assert.Equal(t, []string{"000000020000000E", "000000020000000F", "0000000200000010"},
+		walSegmentRangePrefixes(2, 0xE*segmentsPerLog, 0x10*segmentsPerLog+1))
+	assert.Equal(t, []string{"000000030000000E", "000000030000000F", "000000030000001", "0000000300000020"},
+		walSegmentRangePrefixes(3, 0xE*segmentsPerLog, 0x20*segmentsPerLog))
+	assert.Equal(t, []string{"000000010000001"}, walSegmentRangePrefixes(1, 0x10*segmentsPerLog, 0x20*segmentsPerLog-1))
+}
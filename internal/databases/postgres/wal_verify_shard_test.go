@@ -0,0 +1,80 @@
+package postgres_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/databases/postgres"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestParseWalVerifyShard(t *testing.T) {
+	shard, err := postgres.ParseWalVerifyShard("2/8")
+	require.NoError(t, err)
+	assert.Equal(t, postgres.WalVerifyShard{Index: 2, Count: 8}, shard)
+
+	for _, invalid := range []string{"", "2", "0/8", "9/8", "a/8", "1/0"} {
+		_, err = postgres.ParseWalVerifyShard(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+// the merged reports of the shards must match the result of the whole range scan
+func TestWalVerifyShards_MergeMatchesWholeScan(t *testing.T) {
+	storageSegments := []string{
+		"000000050000000000000001",
+		"000000050000000000000002",
+		"000000050000000000000004",
+		"000000050000000000000005",
+		"000000060000000000000007",
+		"000000060000000000000008",
+		"00000006000000000000000B",
+		"00000006000000000000000C",
+		"00000006000000000000000D",
+		"00000006000000000000000E",
+	}
+	switchPointLsn := 5*postgres.WalSegmentSize + 100
+	historyContents := fmt.Sprintf("%d\t0/%X\tsome comment...\n\n", 5, switchPointLsn)
+	historyName, historyFile, err := newTimelineHistoryFile(historyContents, 6)
+	require.NoError(t, err)
+	historyContent := historyFile.Bytes()
+	currentSegment, _ := postgres.NewWalSegmentDescription("000000060000000000000012")
+
+	storageFiles := func() map[string]*bytes.Buffer {
+		return map[string]*bytes.Buffer{utility.WalPath + historyName: bytes.NewBuffer(historyContent)}
+	}
+	wholeResult, _ := executeWalVerify(storageSegments, storageFiles(), currentSegment)
+	expected := wholeResult[postgres.WalVerifyIntegrityCheck]
+
+	rootFolder := setupTestStorageFolder()
+	for name, content := range storageFiles() {
+		require.NoError(t, rootFolder.PutObject(name, content))
+	}
+	putWalSegments(storageSegments, rootFolder.GetSubFolder(utility.WalPath))
+
+	for _, shardsCount := range []int{1, 2, 3, 7, 30} {
+		reports := make([]postgres.WalVerifyShardReport, 0, shardsCount)
+		for index := shardsCount; index >= 1; index-- {
+			var output bytes.Buffer
+			shard := postgres.WalVerifyShard{Index: index, Count: shardsCount}
+			require.NoError(t, postgres.HandleWalVerifyShard(rootFolder, currentSegment, shard, &output))
+			var report postgres.WalVerifyShardReport
+			require.NoError(t, json.Unmarshal(output.Bytes(), &report))
+			reports = append(reports, report)
+		}
+
+		merged, err := postgres.MergeWalVerifyShardReports(reports)
+		require.NoError(t, err)
+		assert.Equal(t, expected.Status, merged.Status, "%d shards", shardsCount)
+		assert.Equal(t, expected.Details, merged.Details, "%d shards", shardsCount)
+
+		_, err = postgres.MergeWalVerifyShardReports(reports[1:])
+		if shardsCount > 1 {
+			assert.Error(t, err, "the missing shard must fail the merge")
+		}
+	}
+}
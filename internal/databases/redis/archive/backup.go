@@ -23,6 +23,10 @@ type Backup struct {
 	Permanent       bool        `json:"Permanent"`
 	DataSize        int64       `json:"DataSize,omitempty"`
 	BackupSize      int64       `json:"BackupSize,omitempty"`
+	// KeyPatterns are set for the backups of the keys dumped with the SCAN and DUMP commands
+	KeyPatterns []string `json:"KeyPatterns,omitempty"`
+	// WalgVersion is the version of WAL-G which made the backup, empty for the backups made before it was recorded
+	WalgVersion string `json:"WalgVersion,omitempty"`
 }
 
 func (b Backup) Name() string {
@@ -124,10 +128,11 @@ type BackupMeta struct {
 }
 
 type RedisMetaConstructor struct {
-	ctx       context.Context
-	folder    storage.Folder
-	meta      BackupMeta
-	permanent bool
+	ctx         context.Context
+	folder      storage.Folder
+	meta        BackupMeta
+	permanent   bool
+	keyPatterns []string
 }
 
 // Init - required for internal.MetaConstructor
@@ -151,6 +156,8 @@ func (m *RedisMetaConstructor) MetaInfo() interface{} {
 		UserData:        meta.User,
 		StartLocalTime:  meta.StartTime,
 		FinishLocalTime: meta.FinishTime,
+		KeyPatterns:     m.keyPatterns,
+		WalgVersion:     internal.WalgVersion,
 	}
 }
 
@@ -163,6 +170,11 @@ func NewBackupRedisMetaConstructor(ctx context.Context, folder storage.Folder, p
 	return &RedisMetaConstructor{ctx: ctx, folder: folder, permanent: permanent}
 }
 
+func NewKeysBackupRedisMetaConstructor(ctx context.Context, folder storage.Folder, permanent bool,
+	keyPatterns []string) internal.MetaConstructor {
+	return &RedisMetaConstructor{ctx: ctx, folder: folder, permanent: permanent, keyPatterns: keyPatterns}
+}
+
 type StorageUploader struct {
 	internal.Uploader
 }
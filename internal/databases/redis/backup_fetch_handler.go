@@ -14,5 +14,14 @@ func HandleBackupFetch(ctx context.Context, folder storage.Folder, backupName st
 	if err != nil {
 		return err
 	}
-	return internal.StreamBackupToCommandStdin(restoreCmd, backup)
+	backup.RestoreReport = internal.NewRestoreReporter(backup.Name)
+	backup.RestoreReport.Write(folder, false)
+	finishFetch := backup.RestoreReport.StartPhase("fetch")
+	err = internal.StreamBackupToCommandStdin(restoreCmd, backup)
+	finishFetch()
+	if err != nil {
+		return err
+	}
+	backup.RestoreReport.Write(folder, true)
+	return nil
 }
@@ -17,6 +17,9 @@ type PurgeSettings struct {
 	retainAfter  *time.Time
 	purgeGarbage bool
 	dryRun       bool
+	// guardrailConfirmation is the cluster name passed with --yes-i-know
+	guardrailConfirmation string
+	guardrailConfirmed    bool
 }
 
 type PurgeOption func(*PurgeSettings)
@@ -49,6 +52,13 @@ func PurgeDryRun(dryRun bool) PurgeOption {
 	}
 }
 
+// PurgeGuardrailConfirmation ...
+func PurgeGuardrailConfirmation(yesIKnow string) PurgeOption {
+	return func(args *PurgeSettings) {
+		args.guardrailConfirmation = yesIKnow
+	}
+}
+
 // HandlePurge delete backups and oplog archives according to settings
 func HandlePurge(backupsPath string, setters ...PurgeOption) error {
 	opts := PurgeSettings{dryRun: true}
@@ -61,6 +71,15 @@ func HandlePurge(backupsPath string, setters ...PurgeOption) error {
 		return err
 	}
 
+	if opts.purgeGarbage && !opts.dryRun {
+		// like delete garbage of the other databases, the garbage deletion always requires naming the cluster
+		err = internal.CheckGuardrail("delete --purge-garbage", opts.guardrailConfirmation)
+		if err != nil {
+			return err
+		}
+		opts.guardrailConfirmed = true
+	}
+
 	backupFolder := st.RootFolder().GetSubFolder(backupsPath)
 
 	backupTimes, garbage, err := internal.GetBackupsAndGarbage(backupFolder)
@@ -115,6 +134,12 @@ func HandleBackupsDelete(backupTimes []internal.BackupTime,
 	tracelog.InfoLogger.Printf("Backups selected to be retained: %v", BackupNamesFromBackups(retain))
 
 	if !opts.dryRun {
+		if !opts.guardrailConfirmed {
+			err = internal.CheckWideDeletionGuardrail("delete", len(purge), opts.guardrailConfirmation)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
 		if err := internal.DeleteBackups(folder, purgeFiles); err != nil {
 			return nil, nil, err
 		}
@@ -0,0 +1,210 @@
+package redis
+
+import (
+	"context"
+	"io"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/databases/redis/archive"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// keysScanCount is the COUNT hint of the SCAN command, the keys of one SCAN call are dumped in one pipeline
+const keysScanCount = 1000
+
+// keysDumpWaiter reports the result of the dump of the keys to the uploader
+type keysDumpWaiter chan error
+
+func (waiter keysDumpWaiter) Wait() error {
+	return <-waiter
+}
+
+// HandleKeysBackupPush dumps the keys matching the patterns with the SCAN and DUMP commands and uploads them
+// as a stream backup, so the keys of a single tenant can be backed up without the whole dataset
+func HandleKeysBackupPush(ctx context.Context, uploader internal.Uploader, patterns []string,
+	metaConstructor internal.MetaConstructor) error {
+	client := getRedisConnection().WithContext(ctx)
+	defer utility.LoggedClose(client, "failed to close redis connection")
+
+	reader, writer := io.Pipe()
+	defer utility.LoggedClose(reader, "")
+	waiter := make(keysDumpWaiter, 1)
+	go func() {
+		err := dumpKeys(client, patterns, writer)
+		_ = writer.CloseWithError(err)
+		waiter <- err
+	}()
+
+	redisUploader := archive.NewRedisStorageUploader(uploader)
+	return redisUploader.UploadBackup(reader, waiter, metaConstructor)
+}
+
+func dumpKeys(client *redis.Client, patterns []string, writer io.Writer) error {
+	dumpWriter, err := NewKeysDumpWriter(writer)
+	if err != nil {
+		return err
+	}
+	for i, pattern := range patterns {
+		var cursor uint64
+		for {
+			keys, nextCursor, err := client.Scan(cursor, pattern, keysScanCount).Result()
+			if err != nil {
+				return errors.Wrapf(err, "failed to scan the keys matching '%s'", pattern)
+			}
+			// the keys matching the previous patterns are dumped already
+			newKeys := make([]string, 0, len(keys))
+			for _, key := range keys {
+				if i == 0 || !matchAnyKeyPattern(patterns[:i], key) {
+					newKeys = append(newKeys, key)
+				}
+			}
+			err = dumpKeyBatch(client, newKeys, dumpWriter)
+			if err != nil {
+				return err
+			}
+			if nextCursor == 0 {
+				break
+			}
+			cursor = nextCursor
+		}
+	}
+	tracelog.InfoLogger.Printf("Dumped %d keys", dumpWriter.records)
+	return dumpWriter.Close()
+}
+
+func dumpKeyBatch(client *redis.Client, keys []string, dumpWriter *KeysDumpWriter) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	pipe := client.Pipeline()
+	defer utility.LoggedClose(pipe, "")
+	dumpCmds := make([]*redis.StringCmd, len(keys))
+	ttlCmds := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		dumpCmds[i] = pipe.Dump(key)
+		ttlCmds[i] = pipe.PTTL(key)
+	}
+	_, err := pipe.Exec()
+	if err != nil && err != redis.Nil {
+		return errors.Wrap(err, "failed to dump the keys")
+	}
+
+	for i, key := range keys {
+		payload, err := dumpCmds[i].Result()
+		if err == redis.Nil {
+			// the key has expired or has been deleted since the scan
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to dump key '%s'", key)
+		}
+		ttl, err := ttlCmds[i].Result()
+		if err != nil {
+			return errors.Wrapf(err, "failed to get the TTL of key '%s'", key)
+		}
+		if ttl < 0 {
+			ttl = 0
+		}
+		err = dumpWriter.WriteRecord(KeyRecord{Key: key, TTL: ttl, Payload: []byte(payload)})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsKeysBackup tells if the backup is made of the keys dumped with the SCAN and DUMP commands
+func IsKeysBackup(folder storage.Folder, backupName string) (bool, error) {
+	backup, err := internal.GetBackupByName(backupName, utility.BaseBackupPath, folder)
+	if err != nil {
+		return false, err
+	}
+	var sentinel archive.Backup
+	err = backup.FetchSentinel(&sentinel)
+	if err != nil {
+		return false, err
+	}
+	return len(sentinel.KeyPatterns) > 0, nil
+}
+
+// HandleKeysBackupFetch restores the keys of the keys backup with the RESTORE command. If the patterns are set,
+// only the keys matching them are restored. The existing keys are overwritten only if replace is set.
+func HandleKeysBackupFetch(ctx context.Context, folder storage.Folder, backupName string, patterns []string,
+	replace bool) error {
+	backup, err := internal.GetBackupByName(backupName, utility.BaseBackupPath, folder)
+	if err != nil {
+		return err
+	}
+	backup.RestoreReport = internal.NewRestoreReporter(backup.Name)
+	backup.RestoreReport.Write(folder, false)
+
+	client := getRedisConnection().WithContext(ctx)
+	defer utility.LoggedClose(client, "failed to close redis connection")
+
+	reader, writer := io.Pipe()
+	downloadErr := make(chan error, 1)
+	go func() {
+		downloadErr <- internal.DownloadAndDecompressStream(backup, writer)
+	}()
+
+	finishFetch := backup.RestoreReport.StartPhase("fetch")
+	err = restoreKeys(client, reader, patterns, replace)
+	finishFetch()
+	utility.LoggedClose(reader, "")
+	// the failed download ends the stream, so the dump looks truncated
+	downloadError := <-downloadErr
+	if downloadError != nil && isTruncatedKeysDump(err) {
+		return errors.Wrap(downloadError, "failed to download and decompress stream")
+	}
+	if err != nil {
+		return err
+	}
+	backup.RestoreReport.Write(folder, true)
+	return nil
+}
+
+func isTruncatedKeysDump(err error) bool {
+	cause := errors.Cause(err)
+	return cause == io.EOF || cause == io.ErrUnexpectedEOF
+}
+
+func restoreKeys(client *redis.Client, reader io.Reader, patterns []string, replace bool) error {
+	dumpReader, err := NewKeysDumpReader(reader)
+	if err != nil {
+		return err
+	}
+	// SCAN may return a key more than once, so the duplicates are not restored twice
+	restored := make(map[string]bool)
+	skipped := 0
+	for {
+		record, err := dumpReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if restored[record.Key] {
+			continue
+		}
+		if !matchAnyKeyPattern(patterns, record.Key) {
+			skipped++
+			continue
+		}
+		if replace {
+			err = client.RestoreReplace(record.Key, record.TTL, string(record.Payload)).Err()
+		} else {
+			err = client.Restore(record.Key, record.TTL, string(record.Payload)).Err()
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to restore key '%s'", record.Key)
+		}
+		restored[record.Key] = true
+	}
+	tracelog.InfoLogger.Printf("Restored %d keys, skipped %d keys not matching the patterns", len(restored), skipped)
+	return nil
+}
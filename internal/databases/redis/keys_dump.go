@@ -0,0 +1,244 @@
+package redis
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// keysDumpMagic starts the stream of the keys backup, the stream consists of the records of the dumped keys
+// and ends with the record of the empty key followed by the number of the records
+const keysDumpMagic = "WALGRKD1"
+
+// maxKeysDumpFieldSize protects the reader from allocating the memory for a corrupted length
+const maxKeysDumpFieldSize = 1 << 30
+
+// KeyRecord is the key dumped with the DUMP command. TTL is zero for the keys without expiration.
+type KeyRecord struct {
+	Key     string
+	TTL     time.Duration
+	Payload []byte
+}
+
+type KeysDumpWriter struct {
+	writer  *bufio.Writer
+	records uint64
+	buf     [binary.MaxVarintLen64]byte
+}
+
+func NewKeysDumpWriter(writer io.Writer) (*KeysDumpWriter, error) {
+	dumpWriter := &KeysDumpWriter{writer: bufio.NewWriter(writer)}
+	_, err := dumpWriter.writer.WriteString(keysDumpMagic)
+	if err != nil {
+		return nil, err
+	}
+	return dumpWriter, nil
+}
+
+func (dumpWriter *KeysDumpWriter) WriteRecord(record KeyRecord) error {
+	if record.Key == "" {
+		return errors.New("empty key can not be dumped")
+	}
+	err := dumpWriter.writeBytes([]byte(record.Key))
+	if err != nil {
+		return err
+	}
+	err = dumpWriter.writeUvarint(uint64(record.TTL.Milliseconds()))
+	if err != nil {
+		return err
+	}
+	err = dumpWriter.writeBytes(record.Payload)
+	if err != nil {
+		return err
+	}
+	dumpWriter.records++
+	return nil
+}
+
+// Close writes the end of the stream, the stream without it is considered truncated
+func (dumpWriter *KeysDumpWriter) Close() error {
+	err := dumpWriter.writeUvarint(0)
+	if err != nil {
+		return err
+	}
+	err = dumpWriter.writeUvarint(dumpWriter.records)
+	if err != nil {
+		return err
+	}
+	return dumpWriter.writer.Flush()
+}
+
+func (dumpWriter *KeysDumpWriter) writeBytes(data []byte) error {
+	err := dumpWriter.writeUvarint(uint64(len(data)))
+	if err != nil {
+		return err
+	}
+	_, err = dumpWriter.writer.Write(data)
+	return err
+}
+
+func (dumpWriter *KeysDumpWriter) writeUvarint(value uint64) error {
+	n := binary.PutUvarint(dumpWriter.buf[:], value)
+	_, err := dumpWriter.writer.Write(dumpWriter.buf[:n])
+	return err
+}
+
+type KeysDumpReader struct {
+	reader  *bufio.Reader
+	records uint64
+}
+
+func NewKeysDumpReader(reader io.Reader) (*KeysDumpReader, error) {
+	dumpReader := &KeysDumpReader{reader: bufio.NewReader(reader)}
+	magic := make([]byte, len(keysDumpMagic))
+	_, err := io.ReadFull(dumpReader.reader, magic)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the keys dump header")
+	}
+	if string(magic) != keysDumpMagic {
+		return nil, errors.New("the stream is not a keys dump")
+	}
+	return dumpReader, nil
+}
+
+// Next returns the next record or io.EOF after the last one
+func (dumpReader *KeysDumpReader) Next() (KeyRecord, error) {
+	key, err := dumpReader.readBytes()
+	if err != nil {
+		return KeyRecord{}, noEOF(err)
+	}
+	if len(key) == 0 {
+		records, err := binary.ReadUvarint(dumpReader.reader)
+		if err != nil {
+			return KeyRecord{}, noEOF(err)
+		}
+		if records != dumpReader.records {
+			return KeyRecord{}, fmt.Errorf("keys dump has %d records, expected %d", dumpReader.records, records)
+		}
+		return KeyRecord{}, io.EOF
+	}
+	ttl, err := binary.ReadUvarint(dumpReader.reader)
+	if err != nil {
+		return KeyRecord{}, noEOF(err)
+	}
+	payload, err := dumpReader.readBytes()
+	if err != nil {
+		return KeyRecord{}, noEOF(err)
+	}
+	dumpReader.records++
+	return KeyRecord{Key: string(key), TTL: time.Duration(ttl) * time.Millisecond, Payload: payload}, nil
+}
+
+func (dumpReader *KeysDumpReader) readBytes() ([]byte, error) {
+	size, err := binary.ReadUvarint(dumpReader.reader)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxKeysDumpFieldSize {
+		return nil, fmt.Errorf("keys dump field of %d bytes is too large", size)
+	}
+	data := make([]byte, size)
+	_, err = io.ReadFull(dumpReader.reader, data)
+	return data, err
+}
+
+// noEOF reports the end of the stream in the middle of the dump as the truncation
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// MatchKeyPattern matches the key against the glob-style pattern of the Redis KEYS and SCAN commands
+func MatchKeyPattern(pattern, key string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(key); i++ {
+				if MatchKeyPattern(pattern[1:], key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+			pattern, key = pattern[1:], key[1:]
+		case '[':
+			if len(key) == 0 {
+				return false
+			}
+			var matched bool
+			pattern, matched = matchKeyPatternClass(pattern[1:], key[0])
+			if !matched {
+				return false
+			}
+			key = key[1:]
+		default:
+			if pattern[0] == '\\' && len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			if len(key) == 0 || pattern[0] != key[0] {
+				return false
+			}
+			pattern, key = pattern[1:], key[1:]
+		}
+	}
+	return len(key) == 0
+}
+
+// matchKeyPatternClass matches the character against the [...] class and returns the pattern after the class
+func matchKeyPatternClass(pattern string, char byte) (string, bool) {
+	negate := len(pattern) > 0 && pattern[0] == '^'
+	if negate {
+		pattern = pattern[1:]
+	}
+	matched := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		if pattern[0] == '\\' && len(pattern) > 1 {
+			pattern = pattern[1:]
+		}
+		low := pattern[0]
+		high := low
+		if len(pattern) > 2 && pattern[1] == '-' && pattern[2] != ']' {
+			high = pattern[2]
+			if low > high {
+				low, high = high, low
+			}
+			pattern = pattern[2:]
+		}
+		if low <= char && char <= high {
+			matched = true
+		}
+		pattern = pattern[1:]
+	}
+	if len(pattern) > 0 {
+		pattern = pattern[1:]
+	}
+	return pattern, matched != negate
+}
+
+// matchAnyKeyPattern tells if the key matches one of the patterns, no patterns match every key
+func matchAnyKeyPattern(patterns []string, key string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if MatchKeyPattern(pattern, key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package redis
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeysDumpRoundTrip(t *testing.T) {
+	records := []KeyRecord{
+		{Key: "tenant:1:user", Payload: []byte("\x00\x03abc")},
+		{Key: "tenant:1:session", TTL: 1500 * time.Millisecond, Payload: []byte{}},
+	}
+	var buf bytes.Buffer
+	writer, err := NewKeysDumpWriter(&buf)
+	require.NoError(t, err)
+	for _, record := range records {
+		require.NoError(t, writer.WriteRecord(record))
+	}
+	require.NoError(t, writer.Close())
+
+	reader, err := NewKeysDumpReader(&buf)
+	require.NoError(t, err)
+	for _, expected := range records {
+		record, err := reader.Next()
+		require.NoError(t, err)
+		assert.Equal(t, expected, record)
+	}
+	_, err = reader.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestKeysDumpTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewKeysDumpWriter(&buf)
+	require.NoError(t, err)
+	require.NoError(t, writer.WriteRecord(KeyRecord{Key: "key", Payload: []byte("payload")}))
+	require.NoError(t, writer.writer.Flush())
+
+	reader, err := NewKeysDumpReader(&buf)
+	require.NoError(t, err)
+	_, err = reader.Next()
+	require.NoError(t, err)
+	_, err = reader.Next()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestKeysDumpRejectsOtherStreams(t *testing.T) {
+	_, err := NewKeysDumpReader(bytes.NewReader([]byte("REDIS0009")))
+	assert.Error(t, err)
+}
+
+func TestMatchKeyPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		key     string
+		matched bool
+	}{
+		{"tenant:1:*", "tenant:1:user", true},
+		{"tenant:1:*", "tenant:10:user", false},
+		{"*", "a/b/c", true},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"h\\*llo", "h*llo", true},
+		{"h\\*llo", "hello", false},
+		{"*:session", "tenant:1:session", true},
+	}
+	for _, testCase := range cases {
+		assert.Equal(t, testCase.matched, MatchKeyPattern(testCase.pattern, testCase.key),
+			"pattern %s, key %s", testCase.pattern, testCase.key)
+	}
+}
@@ -18,12 +18,11 @@ func GetSettingWithLocalDefault(key string, defaultValue string) string {
 	return defaultValue
 }
 
-// getRedisConnection
-func _() *redis.Client {
-	redisAddr := GetSettingWithLocalDefault("WALG_REDIS_HOST", "localhost")
-	redisPort := GetSettingWithLocalDefault("WALG_REDIS_PORT", "6379")
+func getRedisConnection() *redis.Client {
+	redisAddr := GetSettingWithLocalDefault(conf.RedisHost, "localhost")
+	redisPort := GetSettingWithLocalDefault(conf.RedisPort, "6379")
 	redisPassword := GetSettingWithLocalDefault(conf.RedisPassword, "") // no password set
-	redisDBStr, ok := conf.GetSetting("WALG_REDIS_DB")
+	redisDBStr, ok := conf.GetSetting(conf.RedisDB)
 	redisDB := 0 // use default DB
 	if ok {
 		redisDBValue, err := strconv.Atoi(redisDBStr)
@@ -0,0 +1,116 @@
+package sqlserver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+)
+
+const SecondaryReplicaRole = "SECONDARY"
+
+// AvailabilityReplica is the Always On availability group replica of the database on this server
+type AvailabilityReplica struct {
+	Group            string
+	Replica          string
+	Role             string
+	BackupPreference string
+	// PreferredForBackup is the result of sys.fn_hadr_backup_is_preferred_replica at the time of the backup
+	PreferredForBackup bool `json:"-"`
+}
+
+func (replica *AvailabilityReplica) IsSecondary() bool {
+	return replica != nil && replica.Role == SecondaryReplicaRole
+}
+
+func (replica *AvailabilityReplica) String() string {
+	return fmt.Sprintf("%s/%s (%s)", replica.Group, replica.Replica, replica.Role)
+}
+
+// getAvailabilityReplicas returns the availability group replicas of the databases that belong to one
+func getAvailabilityReplicas(db *sql.DB, dbnames []string) (map[string]*AvailabilityReplica, error) {
+	replicas := make(map[string]*AvailabilityReplica)
+	for _, dbname := range dbnames {
+		replica, err := getAvailabilityReplica(db, dbname)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get availability group of database [%s]: %v", dbname, err)
+		}
+		if replica != nil {
+			tracelog.InfoLogger.Printf("database [%s] is in availability group %s", dbname, replica)
+			replicas[dbname] = replica
+		}
+	}
+	return replicas, nil
+}
+
+func getAvailabilityReplica(db *sql.DB, dbname string) (*AvailabilityReplica, error) {
+	query := `SELECT ag.name, ar.replica_server_name, ISNULL(rs.role_desc, ''), ag.automated_backup_preference_desc,
+			sys.fn_hadr_backup_is_preferred_replica(d.name)
+		FROM sys.databases d
+		JOIN sys.availability_replicas ar ON ar.replica_id = d.replica_id
+		JOIN sys.availability_groups ag ON ag.group_id = ar.group_id
+		LEFT JOIN sys.dm_hadr_availability_replica_states rs ON rs.replica_id = ar.replica_id
+		WHERE d.name = @dbname`
+	var replica AvailabilityReplica
+	err := db.QueryRow(query, sql.Named("dbname", dbname)).Scan(&replica.Group, &replica.Replica, &replica.Role,
+		&replica.BackupPreference, &replica.PreferredForBackup)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &replica, nil
+}
+
+// excludeNotPreferredReplicas leaves the databases that are not in availability groups and the ones for which
+// this server is the preferred backup replica according to the backup preference of the group
+func excludeNotPreferredReplicas(dbnames []string, replicas map[string]*AvailabilityReplica) []string {
+	res := make([]string, 0, len(dbnames))
+	for _, dbname := range dbnames {
+		replica, ok := replicas[dbname]
+		if ok && !replica.PreferredForBackup {
+			tracelog.InfoLogger.Printf("skipping database [%s]: replica %s is not preferred for backups (%s)",
+				dbname, replica, replica.BackupPreference)
+			continue
+		}
+		res = append(res, dbname)
+	}
+	return res
+}
+
+func honorBackupPreference() bool {
+	honor, _, err := conf.GetBoolSetting(conf.SQLServerHonorAGBackupPreference)
+	if err != nil {
+		tracelog.WarningLogger.Printf("config error: %v", err)
+		return false
+	}
+	return honor
+}
+
+// checkAvailabilityGroupsToJoin makes sure the databases can join their groups before they are restored
+func checkAvailabilityGroupsToJoin(sentinel *SentinelDto, fromnames []string) error {
+	for _, fromname := range fromnames {
+		if _, ok := sentinel.AvailabilityGroups[fromname]; !ok {
+			return fmt.Errorf("database [%s] was not in an availability group at the time of the backup", fromname)
+		}
+	}
+	return nil
+}
+
+// joinAvailabilityGroup joins the database restored WITH NORECOVERY on the secondary replica to the group
+func joinAvailabilityGroup(ctx context.Context, db *sql.DB, dbname string, replica *AvailabilityReplica) error {
+	sql := fmt.Sprintf("ALTER DATABASE %s SET HADR AVAILABILITY GROUP = %s", quoteName(dbname), quoteName(replica.Group))
+	tracelog.InfoLogger.Printf("joining database [%s] to availability group [%s]", dbname, replica.Group)
+	tracelog.DebugLogger.Printf("SQL: %s", sql)
+	_, err := db.ExecContext(ctx, sql)
+	if err != nil {
+		tracelog.ErrorLogger.Printf("database [%s] failed to join availability group: %v", dbname, err)
+	} else {
+		tracelog.InfoLogger.Printf("database [%s] joined availability group [%s]", dbname, replica.Group)
+	}
+	return err
+}
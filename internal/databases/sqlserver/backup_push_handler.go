@@ -30,6 +30,16 @@ func HandleBackupPush(dbnames []string, updateLatest bool) {
 
 	tracelog.ErrorLogger.FatalfOnError("failed to list databases to backup: %v", err)
 
+	replicas, err := getAvailabilityReplicas(db, dbnames)
+	tracelog.ErrorLogger.FatalfOnError("failed to detect availability groups: %v", err)
+	if honorBackupPreference() {
+		dbnames = excludeNotPreferredReplicas(dbnames, replicas)
+		if len(dbnames) == 0 {
+			tracelog.InfoLogger.Printf("this server is not the preferred backup replica of any database, nothing to backup")
+			return
+		}
+	}
+
 	lock, err := RunOrReuseProxy(ctx, cancel, storage.RootFolder())
 	tracelog.ErrorLogger.FatalOnError(err)
 	defer lock.Close()
@@ -46,17 +56,29 @@ func HandleBackupPush(dbnames []string, updateLatest bool) {
 		err = backup.FetchSentinel(sentinel)
 		tracelog.ErrorLogger.FatalOnError(err)
 		sentinel.Databases = uniq(append(sentinel.Databases, dbnames...))
+		sentinel.WalgVersion = internal.WalgVersion
 	} else {
 		backupName = generateDatabaseBackupName()
 		sentinel = &SentinelDto{
 			Server:         server,
 			Databases:      dbnames,
 			StartLocalTime: timeStart,
+			WalgVersion:    internal.WalgVersion,
+		}
+	}
+	for _, dbname := range dbnames {
+		if replica, ok := replicas[dbname]; ok {
+			if sentinel.AvailabilityGroups == nil {
+				sentinel.AvailabilityGroups = make(map[string]*AvailabilityReplica)
+			}
+			sentinel.AvailabilityGroups[dbname] = replica
 		}
 	}
 	builtinCompression := blob.UseBuiltinCompression()
 	err = runParallel(func(i int) error {
-		return backupSingleDatabase(ctx, db, backupName, dbnames[i], builtinCompression)
+		// only copy-only full backups can be taken on the secondary replicas
+		copyOnly := replicas[dbnames[i]].IsSecondary()
+		return backupSingleDatabase(ctx, db, backupName, dbnames[i], builtinCompression, copyOnly)
 	}, len(dbnames), getDBConcurrency())
 	tracelog.ErrorLogger.FatalfOnError("overall backup failed: %v", err)
 
@@ -71,7 +93,8 @@ func HandleBackupPush(dbnames []string, updateLatest bool) {
 	tracelog.InfoLogger.Printf("backup finished")
 }
 
-func backupSingleDatabase(ctx context.Context, db *sql.DB, backupName string, dbname string, builtinCompression bool) error {
+func backupSingleDatabase(ctx context.Context, db *sql.DB, backupName string, dbname string, builtinCompression bool,
+	copyOnly bool) error {
 	baseURL := getDatabaseBackupURL(backupName, dbname)
 	size, blobCount, err := estimateDBSize(db, dbname)
 	if err != nil {
@@ -84,6 +107,9 @@ func backupSingleDatabase(ctx context.Context, db *sql.DB, backupName string, db
 	if builtinCompression {
 		sql += ", COMPRESSION"
 	}
+	if copyOnly {
+		sql += ", COPY_ONLY"
+	}
 	tracelog.InfoLogger.Printf("starting backup database [%s] to %s", dbname, urls)
 	tracelog.DebugLogger.Printf("SQL: %s", sql)
 	_, err = db.ExecContext(ctx, sql)
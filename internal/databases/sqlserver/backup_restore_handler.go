@@ -14,7 +14,7 @@ import (
 	"github.com/wal-g/wal-g/utility"
 )
 
-func HandleBackupRestore(backupName string, dbnames []string, fromnames []string, noRecovery bool) {
+func HandleBackupRestore(backupName string, dbnames []string, fromnames []string, noRecovery bool, joinAG bool) {
 	ctx, cancel := context.WithCancel(context.Background())
 	signalHandler := utility.NewSignalHandler(ctx, cancel, []os.Signal{syscall.SIGINT, syscall.SIGTERM})
 	defer func() { _ = signalHandler.Close() }()
@@ -37,6 +37,11 @@ func HandleBackupRestore(backupName string, dbnames []string, fromnames []string
 	dbnames, fromnames, err = getDatabasesToRestore(sentinel, dbnames, fromnames)
 	tracelog.ErrorLogger.FatalfOnError("failed to list databases to restore: %v", err)
 
+	if joinAG {
+		err = checkAvailabilityGroupsToJoin(sentinel, fromnames)
+		tracelog.ErrorLogger.FatalOnError(err)
+	}
+
 	lock, err := RunOrReuseProxy(ctx, cancel, folder)
 	tracelog.ErrorLogger.FatalOnError(err)
 	defer lock.Close()
@@ -50,6 +55,10 @@ func HandleBackupRestore(backupName string, dbnames []string, fromnames []string
 		if err != nil {
 			return err
 		}
+		// the database joins the availability group in the restoring state
+		if joinAG {
+			return joinAvailabilityGroup(ctx, db, dbname, sentinel.AvailabilityGroups[fromname])
+		}
 		if !noRecovery {
 			return recoverSingleDatabase(ctx, db, dbname)
 		}
@@ -55,6 +55,7 @@ type Server struct {
 	decompressor compression.Decompressor
 	encryption   string
 	crypter      crypto.Crypter
+	scanner      *internal.ObjectScanner
 	readCache    *lru.Cache
 }
 
@@ -103,6 +104,10 @@ func NewServer(folder storage.Folder) (*Server, error) {
 	if bs.crypter != nil {
 		bs.encryption = bs.crypter.Name()
 	}
+	bs.scanner, err = internal.ConfigureObjectScanner()
+	if err != nil {
+		return nil, err
+	}
 	c, err := lru.NewWithEvict(BlockReadCacheSize, func(k, _ interface{}) {
 		tracelog.DebugLogger.Printf("EVICT_CACHE: %s", k)
 	})
@@ -429,7 +434,7 @@ func (bs *Server) HandleBlockPut(w http.ResponseWriter, req *http.Request) {
 	}
 	filename := idx.PutBlock(blockID, blockSize)
 	bs.uploadSem <- struct{}{}
-	err = folder.PutObject(filename, internal.CompressAndEncrypt(req.Body, bs.compressor, bs.crypter))
+	err = bs.putBlock(req.Context(), folder, filename, req.Body)
 	<-bs.uploadSem
 	req.Body.Close()
 	if err != nil {
@@ -618,8 +623,7 @@ func (bs *Server) blobPut(r io.Reader, idx *Index) ([]string, error) {
 		}
 		id := fmt.Sprintf("data_%05d", i)
 		name := idx.PutBlock(id, uint64(n))
-		encryptedReader := internal.CompressAndEncrypt(bytes.NewReader(buf[:n]), bs.compressor, bs.crypter)
-		err = idx.folder.PutObject(name, encryptedReader)
+		err = bs.putBlock(context.Background(), idx.folder, name, bytes.NewReader(buf[:n]))
 		if err != nil {
 			return nil, err
 		}
@@ -628,6 +632,13 @@ func (bs *Server) blobPut(r io.Reader, idx *Index) ([]string, error) {
 	return idx.PutBlockList(xblocklist)
 }
 
+// putBlock compresses and encrypts the block, its plaintext is passed to the upload scanner if it is configured
+func (bs *Server) putBlock(ctx context.Context, folder storage.Folder, name string, block io.Reader) error {
+	return bs.scanner.PutObject(ctx, folder, name, block, func(plaintext io.Reader) io.Reader {
+		return internal.CompressAndEncrypt(plaintext, bs.compressor, bs.crypter)
+	})
+}
+
 func (bs *Server) HandleBlobPut(w http.ResponseWriter, req *http.Request) {
 	folder := bs.getBlobFolder(req.URL.Path)
 	idx, err := bs.loadBlobIndex(folder)
@@ -21,6 +21,8 @@ func HandleLogPush(dbnames []string, norecovery bool) {
 
 	folder, err := internal.ConfigureStorage()
 	tracelog.ErrorLogger.FatalOnError(err)
+	// the backup metadata which failed to upload at the end of backup-push is uploaded by the next log-push
+	internal.FlushSpooledMetadata(folder.RootFolder())
 
 	db, err := getSQLServerConnection()
 	tracelog.ErrorLogger.FatalfOnError("failed to connect to SQLServer: %v", err)
@@ -30,6 +32,16 @@ func HandleLogPush(dbnames []string, norecovery bool) {
 
 	tracelog.ErrorLogger.FatalfOnError("failed to list databases to backup: %v", err)
 
+	if honorBackupPreference() {
+		replicas, err := getAvailabilityReplicas(db, dbnames)
+		tracelog.ErrorLogger.FatalfOnError("failed to detect availability groups: %v", err)
+		dbnames = excludeNotPreferredReplicas(dbnames, replicas)
+		if len(dbnames) == 0 {
+			tracelog.InfoLogger.Printf("this server is not the preferred backup replica of any database, nothing to backup")
+			return
+		}
+	}
+
 	lock, err := RunOrReuseProxy(ctx, cancel, folder.RootFolder())
 	tracelog.ErrorLogger.FatalOnError(err)
 	defer lock.Close()
@@ -15,7 +15,8 @@ import (
 	"github.com/wal-g/wal-g/utility"
 )
 
-func HandleLogRestore(backupName string, untilTS string, dbnames []string, fromnames []string, noRecovery bool) {
+func HandleLogRestore(backupName string, untilTS string, dbnames []string, fromnames []string, noRecovery bool,
+	joinAG bool) {
 	ctx, cancel := context.WithCancel(context.Background())
 	signalHandler := utility.NewSignalHandler(ctx, cancel, []os.Signal{syscall.SIGINT, syscall.SIGTERM})
 	defer func() { _ = signalHandler.Close() }()
@@ -38,6 +39,11 @@ func HandleLogRestore(backupName string, untilTS string, dbnames []string, fromn
 	dbnames, fromnames, err = getDatabasesToRestore(sentinel, dbnames, fromnames)
 	tracelog.ErrorLogger.FatalfOnError("failed to list databases to restore logs: %v", err)
 
+	if joinAG {
+		err = checkAvailabilityGroupsToJoin(sentinel, fromnames)
+		tracelog.ErrorLogger.FatalOnError(err)
+	}
+
 	lock, err := RunOrReuseProxy(ctx, cancel, folder)
 	tracelog.ErrorLogger.FatalOnError(err)
 	defer lock.Close()
@@ -94,6 +100,10 @@ func HandleLogRestore(backupName string, untilTS string, dbnames []string, fromn
 				break
 			}
 		}
+		// the database joins the availability group in the restoring state
+		if joinAG {
+			return joinAvailabilityGroup(ctx, db, dbname, sentinel.AvailabilityGroups[fromname])
+		}
 		if !noRecovery {
 			return recoverSingleDatabase(ctx, db, dbname)
 		}
@@ -53,6 +53,10 @@ type SentinelDto struct {
 	Databases      []string
 	StartLocalTime time.Time `json:"StartLocalTime,omitempty"`
 	StopLocalTime  time.Time `json:"StopLocalTime,omitempty"`
+	// AvailabilityGroups are the availability group replicas of the databases the backup was taken on
+	AvailabilityGroups map[string]*AvailabilityReplica `json:"AvailabilityGroups,omitempty"`
+	// WalgVersion is the version of WAL-G which made the backup, empty for the backups made before it was recorded
+	WalgVersion string `json:"WalgVersion,omitempty"`
 }
 
 func (s *SentinelDto) String() string {
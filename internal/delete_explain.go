@@ -0,0 +1,330 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/wal-g/wal-g/internal/multistorage"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+type DeleteDecision string
+
+const (
+	DecisionKeep   DeleteDecision = "KEEP"
+	DecisionDelete DeleteDecision = "DELETE"
+)
+
+// BackupDeleteDecision tells if the backup would be kept or deleted and why
+type BackupDeleteDecision struct {
+	BackupName string         `json:"backup_name"`
+	Storage    string         `json:"storage"`
+	Time       time.Time      `json:"time"`
+	IsFull     bool           `json:"is_full"`
+	BaseBackup string         `json:"base_backup,omitempty"`
+	Decision   DeleteDecision `json:"decision"`
+	Reason     string         `json:"reason"`
+	// Label is the user data of the backup, set by the database-specific handlers
+	Label string `json:"label,omitempty"`
+}
+
+// PurgedObjects sums up the objects of the storage folder which would be deleted
+type PurgedObjects struct {
+	Folder string `json:"folder"`
+	Count  int    `json:"count"`
+	Size   int64  `json:"size"`
+	First  string `json:"first"`
+	Last   string `json:"last"`
+}
+
+// DeleteExplanation is the decision trace of the delete command, made without deleting anything
+type DeleteExplanation struct {
+	Rule string `json:"rule"`
+	// Target is the oldest retained backup bounding the deletion, or the backup deleted by 'delete target'
+	Target string `json:"target,omitempty"`
+	// WalBoundary is the oldest WAL segment (or binlog) kept, set by the database-specific handlers
+	WalBoundary string                 `json:"wal_boundary,omitempty"`
+	Refused     string                 `json:"refused,omitempty"`
+	Backups     []BackupDeleteDecision `json:"backups"`
+	Purged      []PurgedObjects        `json:"purged"`
+	// KeptPermanent is the number of objects that would be deleted if they were not permanent
+	KeptPermanent int `json:"kept_permanent"`
+}
+
+// ExplainDeleteRetain explains 'delete retain' with the same arguments, afterStr is the value of --after
+func (h *DeleteHandler) ExplainDeleteRetain(args []string, afterStr string) (*DeleteExplanation, error) {
+	rule := "retain " + strings.Join(args, " ")
+	var target BackupObject
+	if afterStr == "" {
+		modifier, retentionStr := ExtractDeleteModifierFromArgs(args)
+		retentionCount, err := strconv.Atoi(retentionStr)
+		if err != nil {
+			return nil, err
+		}
+		target, err = h.FindTargetRetain(retentionCount, modifier)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		rule += " --after " + afterStr
+		modifier, retentionStr, _ := ExtractDeleteRetainAfterModifierFromArgs(append(args, afterStr))
+		retentionCount, err := strconv.Atoi(retentionStr)
+		if err != nil {
+			return nil, err
+		}
+		target, err = h.FindTargetRetainAfter(retentionCount, afterStr, modifier)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return h.ExplainDeleteBeforeTargetWhere(target, rule, func(storage.Object) bool { return true })
+}
+
+// ExplainDeleteBefore explains 'delete before' with the same arguments
+func (h *DeleteHandler) ExplainDeleteBefore(args []string) (*DeleteExplanation, error) {
+	modifier, beforeStr := ExtractDeleteModifierFromArgs(args)
+	target, err := h.FindTargetBefore(beforeStr, modifier)
+	if err != nil && err != errNotFound {
+		return nil, err
+	}
+	return h.ExplainDeleteBeforeTargetWhere(target, "before "+strings.Join(args, " "),
+		func(storage.Object) bool { return true })
+}
+
+// ExplainDeleteBeforeTargetWhere explains the deletion of the objects selected by objSelector which are older
+// than the target, the nil target means nothing is deleted
+func (h *DeleteHandler) ExplainDeleteBeforeTargetWhere(
+	target BackupObject,
+	rule string,
+	objSelector func(object storage.Object) bool,
+) (*DeleteExplanation, error) {
+	explanation := &DeleteExplanation{Rule: rule}
+	if target == nil {
+		explanation.Backups = h.explainBackups(func(BackupObject) (DeleteDecision, string) {
+			return DecisionKeep, "no backup is found outside of the policy"
+		})
+		return explanation, nil
+	}
+	explanation.Target = target.GetBackupName()
+	if !target.IsFullBackup() {
+		explanation.Refused = fmt.Sprintf("%s is incremental and its predecessors cannot be deleted. "+
+			"Consider FIND_FULL option.", target.GetName())
+	}
+
+	explanation.Backups = h.explainBackups(func(backup BackupObject) (DeleteDecision, string) {
+		switch {
+		case backup.GetBackupName() == target.GetBackupName():
+			return DecisionKeep, "the oldest backup kept by the policy"
+		case !h.less(backup, target):
+			return DecisionKeep, "newer than " + target.GetBackupName()
+		case h.isPermanent(backup):
+			return DecisionKeep, "permanent"
+		default:
+			return DecisionDelete, "older than " + target.GetBackupName()
+		}
+	})
+	if explanation.Refused != "" {
+		return explanation, nil
+	}
+
+	err := h.explainPurgedObjects(explanation, h.Folder, func(object storage.Object) bool {
+		return objSelector(object) && h.less(object, target)
+	}, true)
+	return explanation, err
+}
+
+// ExplainDeleteTarget explains 'delete target' with the backup chosen by the selector
+func (h *DeleteHandler) ExplainDeleteTarget(targetSelector BackupSelector, findFull bool) (*DeleteExplanation, error) {
+	target, err := h.FindTargetBySelector(targetSelector)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, fmt.Errorf("requested backup was not found")
+	}
+
+	explanation := &DeleteExplanation{Rule: "target", Target: target.GetBackupName()}
+	var backupsToDelete []BackupObject
+	if findFull {
+		explanation.Rule += " FIND_FULL"
+		backupsToDelete = h.findRelatedBackups(target)
+	} else {
+		backupsToDelete = h.findDependantBackups(target)
+	}
+	backupNamesToDelete := make(map[string]bool, len(backupsToDelete))
+	for _, backup := range backupsToDelete {
+		backupNamesToDelete[backup.GetBackupName()] = true
+	}
+
+	explanation.Backups = h.explainBackups(func(backup BackupObject) (DeleteDecision, string) {
+		switch {
+		case !backupNamesToDelete[backup.GetBackupName()]:
+			return DecisionKeep, "not related to " + target.GetBackupName()
+		case h.isPermanent(backup):
+			explanation.Refused = fmt.Sprintf("unable to delete permanent backup %s", backup.GetName())
+			return DecisionKeep, "permanent"
+		case backup.GetBackupName() == target.GetBackupName():
+			return DecisionDelete, "the target"
+		case findFull:
+			return DecisionDelete, "has the same base backup as " + target.GetBackupName()
+		default:
+			return DecisionDelete, "depends on " + target.GetBackupName()
+		}
+	})
+	if explanation.Refused != "" {
+		return explanation, nil
+	}
+
+	err = h.explainPurgedObjects(explanation, h.Folder.GetSubFolder(utility.BaseBackupPath), func(object storage.Object) bool {
+		return backupNamesToDelete[utility.StripLeftmostBackupName(object.GetName())]
+	}, true)
+	return explanation, err
+}
+
+// ExplainDeleteEverything explains 'delete everything' with the same arguments
+func (h *DeleteHandler) ExplainDeleteEverything(args []string, permanentBackups []string) (*DeleteExplanation, error) {
+	explanation := &DeleteExplanation{Rule: strings.TrimSpace("everything " + strings.Join(args, " "))}
+	force := ExtractDeleteEverythingModifierFromArgs(args) == ForceDeleteModifier
+	if len(permanentBackups) > 0 && !force {
+		explanation.Refused = fmt.Sprintf("found permanent backups=%v", permanentBackups)
+	}
+	explanation.Backups = h.explainBackups(func(backup BackupObject) (DeleteDecision, string) {
+		if h.isPermanent(backup) {
+			if !force {
+				return DecisionKeep, "permanent"
+			}
+			return DecisionDelete, "permanent, but FORCE is specified"
+		}
+		return DecisionDelete, "everything is deleted"
+	})
+	if explanation.Refused != "" {
+		return explanation, nil
+	}
+
+	// FORCE deletes the permanent objects too
+	err := h.explainPurgedObjects(explanation, h.Folder, func(storage.Object) bool { return true }, !force)
+	return explanation, err
+}
+
+// explainBackups decides on every backup, the newest backups go first
+func (h *DeleteHandler) explainBackups(decide func(backup BackupObject) (DeleteDecision, string)) []BackupDeleteDecision {
+	backups := make([]BackupObject, len(h.backups))
+	copy(backups, h.backups)
+	sort.SliceStable(backups, func(i, j int) bool {
+		return h.greater(backups[i], backups[j])
+	})
+
+	decisions := make([]BackupDeleteDecision, 0, len(backups))
+	for _, backup := range backups {
+		decision, reason := decide(backup)
+		decisions = append(decisions, BackupDeleteDecision{
+			BackupName: backup.GetBackupName(),
+			Storage:    backup.GetStorage(),
+			Time:       backup.GetBackupTime(),
+			IsFull:     backup.IsFullBackup(),
+			BaseBackup: backup.GetBaseBackupName(),
+			Decision:   decision,
+			Reason:     reason,
+		})
+	}
+	return decisions
+}
+
+// explainPurgedObjects sums up the objects which would be deleted by their top-level folder
+func (h *DeleteHandler) explainPurgedObjects(
+	explanation *DeleteExplanation,
+	folder storage.Folder,
+	objFilter func(object storage.Object) bool,
+	keepPermanent bool,
+) error {
+	objects, err := multistorage.ListFolderRecursivelyWithFilter(folder, func(string) bool { return true })
+	if err != nil {
+		return err
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].GetName() < objects[j].GetName()
+	})
+
+	purgedByFolder := make(map[string]*PurgedObjects)
+	explanation.Purged = make([]PurgedObjects, 0)
+	for _, object := range objects {
+		if !objFilter(object) {
+			continue
+		}
+		if keepPermanent && h.isPermanent(object) {
+			explanation.KeptPermanent++
+			continue
+		}
+		folderName := object.GetName()
+		if i := strings.Index(folderName, "/"); i >= 0 {
+			folderName = folderName[:i+1]
+		} else {
+			folderName = ""
+		}
+		purged, ok := purgedByFolder[folderName]
+		if !ok {
+			purged = &PurgedObjects{Folder: folderName, First: object.GetName()}
+			purgedByFolder[folderName] = purged
+		}
+		purged.Count++
+		purged.Size += object.GetSize()
+		purged.Last = object.GetName()
+	}
+	for _, purged := range purgedByFolder {
+		explanation.Purged = append(explanation.Purged, *purged)
+	}
+	sort.Slice(explanation.Purged, func(i, j int) bool {
+		return explanation.Purged[i].Folder < explanation.Purged[j].Folder
+	})
+	return nil
+}
+
+func PrintDeleteExplanation(explanation *DeleteExplanation, output io.Writer, isJSON bool) error {
+	if isJSON {
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "    ")
+		return encoder.Encode(explanation)
+	}
+
+	writer := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "Policy: delete %s\n", explanation.Rule)
+	if explanation.Target != "" {
+		fmt.Fprintf(writer, "Target: %s\n", explanation.Target)
+	}
+	if explanation.WalBoundary != "" {
+		fmt.Fprintf(writer, "Oldest WAL kept: %s\n", explanation.WalBoundary)
+	}
+	fmt.Fprintf(writer, "\nDecision\tBackup\tStorage\tTime\tType\tReason\tLabel\n")
+	for _, backup := range explanation.Backups {
+		backupType := "full"
+		if !backup.IsFull {
+			backupType = "delta of " + backup.BaseBackup
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", backup.Decision, backup.BackupName, backup.Storage,
+			backup.Time.Format(time.RFC3339), backupType, backup.Reason, backup.Label)
+	}
+	if explanation.Refused != "" {
+		fmt.Fprintf(writer, "\nThe deletion would be refused: %s\n", explanation.Refused)
+		return writer.Flush()
+	}
+
+	if len(explanation.Purged) == 0 {
+		fmt.Fprintf(writer, "\nNothing would be purged\n")
+	} else {
+		fmt.Fprintf(writer, "\nFolder\tObjects\tBytes\tFirst\tLast\n")
+		for _, purged := range explanation.Purged {
+			fmt.Fprintf(writer, "%s\t%d\t%d\t%s\t%s\n", purged.Folder, purged.Count, purged.Size, purged.First, purged.Last)
+		}
+	}
+	if explanation.KeptPermanent > 0 {
+		fmt.Fprintf(writer, "\n%d objects are kept as permanent\n", explanation.KeptPermanent)
+	}
+	return writer.Flush()
+}
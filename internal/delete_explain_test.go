@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+var explainSegmentRegexp = regexp.MustCompile("[0-9A-F]{24}")
+
+func explainSegmentLess(object1, object2 storage.Object) bool {
+	return explainSegmentRegexp.FindString(object1.GetName()) < explainSegmentRegexp.FindString(object2.GetName())
+}
+
+func createExplainTestHandler(t *testing.T, options ...DeleteHandlerOption) *DeleteHandler {
+	folder := memory.NewFolder("in_memory/", memory.NewKVS())
+	for _, segNo := range []int{2, 4, 6} {
+		backupName := fmt.Sprintf("base_%024X", segNo)
+		require.NoError(t, folder.PutObject("basebackups_005/"+backupName+"_backup_stop_sentinel.json", strings.NewReader("{}")))
+		require.NoError(t, folder.PutObject("basebackups_005/"+backupName+"/tar_partitions/part_1.tar.lz4",
+			strings.NewReader("data")))
+	}
+	for segNo := 1; segNo <= 6; segNo++ {
+		require.NoError(t, folder.PutObject(fmt.Sprintf("wal_005/%024X.lz4", segNo), strings.NewReader("wal")))
+	}
+	backups, err := FindBackupObjects(folder)
+	require.NoError(t, err)
+	return NewDeleteHandler(folder, backups, explainSegmentLess, options...)
+}
+
+func TestExplainDeleteRetain(t *testing.T) {
+	handler := createExplainTestHandler(t)
+
+	explanation, err := handler.ExplainDeleteRetain([]string{"2"}, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "retain 2", explanation.Rule)
+	assert.Equal(t, "base_000000000000000000000004", explanation.Target)
+	assert.Empty(t, explanation.Refused)
+	require.Len(t, explanation.Backups, 3)
+	assert.Equal(t, DecisionKeep, explanation.Backups[0].Decision)
+	assert.Equal(t, DecisionKeep, explanation.Backups[1].Decision)
+	assert.Equal(t, "base_000000000000000000000002", explanation.Backups[2].BackupName)
+	assert.Equal(t, DecisionDelete, explanation.Backups[2].Decision)
+
+	assert.Equal(t, []PurgedObjects{
+		{
+			Folder: "basebackups_005/",
+			Count:  2,
+			Size:   int64(len("{}") + len("data")),
+			First:  "basebackups_005/base_000000000000000000000002/tar_partitions/part_1.tar.lz4",
+			Last:   "basebackups_005/base_000000000000000000000002_backup_stop_sentinel.json",
+		},
+		{
+			Folder: "wal_005/",
+			Count:  3,
+			Size:   int64(3 * len("wal")),
+			First:  "wal_005/000000000000000000000001.lz4",
+			Last:   "wal_005/000000000000000000000003.lz4",
+		},
+	}, explanation.Purged)
+	assert.Zero(t, explanation.KeptPermanent)
+}
+
+func TestExplainDeleteRetain_NothingToDelete(t *testing.T) {
+	handler := createExplainTestHandler(t)
+
+	explanation, err := handler.ExplainDeleteRetain([]string{"5"}, "")
+	require.NoError(t, err)
+
+	assert.Empty(t, explanation.Target)
+	assert.Empty(t, explanation.Purged)
+	for _, backup := range explanation.Backups {
+		assert.Equal(t, DecisionKeep, backup.Decision)
+	}
+}
+
+func TestExplainDeleteBefore_KeepsPermanent(t *testing.T) {
+	isPermanent := func(object storage.Object) bool {
+		return strings.Contains(object.GetName(), "base_000000000000000000000002")
+	}
+	handler := createExplainTestHandler(t, IsPermanentFunc(isPermanent))
+
+	explanation, err := handler.ExplainDeleteBefore([]string{"base_000000000000000000000006"})
+	require.NoError(t, err)
+
+	require.Len(t, explanation.Backups, 3)
+	assert.Equal(t, DecisionKeep, explanation.Backups[0].Decision)
+	assert.Equal(t, DecisionDelete, explanation.Backups[1].Decision)
+	assert.Equal(t, DecisionKeep, explanation.Backups[2].Decision)
+	assert.Equal(t, "permanent", explanation.Backups[2].Reason)
+	assert.Equal(t, 2, explanation.KeptPermanent)
+}
+
+func TestExplainDeleteEverything_RefusedWithPermanent(t *testing.T) {
+	handler := createExplainTestHandler(t)
+
+	explanation, err := handler.ExplainDeleteEverything(nil, []string{"base_000000000000000000000002"})
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, explanation.Refused)
+	assert.Empty(t, explanation.Purged)
+}
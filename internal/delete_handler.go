@@ -2,7 +2,6 @@ package internal
 
 import (
 	"fmt"
-	"os"
 	"sort"
 	"strconv"
 	"strings"
@@ -24,6 +23,9 @@ const (
 	ConfirmFlag            = "confirm"
 	DeleteShortDescription = "Clears old backups and WALs"
 
+	// deleteBatchSize is the number of objects deleted between the checks of the metadata lock
+	deleteBatchSize = 1000
+
 	DeleteRetainExamples = `  retain 5                      keep 5 backups
   retain FULL 5                 keep 5 full backups and all deltas of them
   retain FIND_FULL 5            find necessary full for 5th and keep everything after it
@@ -109,67 +111,70 @@ type DeleteHandler struct {
 	greater func(object1, object2 storage.Object) bool
 
 	isPermanent func(object storage.Object) bool
+
+	guardrailConfirmation string
+	guardrailConfirmed    bool
 }
 
 func (h *DeleteHandler) HandleDeleteBefore(args []string, confirmed bool) {
 	modifier, beforeStr := ExtractDeleteModifierFromArgs(args)
 
 	target, err := h.FindTargetBefore(beforeStr, modifier)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnErrorReleasingLocks(err)
 	if target == nil {
 		tracelog.InfoLogger.Printf("No backup found for deletion")
-		os.Exit(0)
+		ExitReleasingLocks(0)
 	}
 
 	err = h.DeleteBeforeTarget(target, confirmed)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnErrorReleasingLocks(err)
 }
 
 func (h *DeleteHandler) HandleDeleteRetain(args []string, confirmed bool) {
 	modifier, retentionStr := ExtractDeleteModifierFromArgs(args)
 	retentionCount, err := strconv.Atoi(retentionStr)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnErrorReleasingLocks(err)
 
 	target, err := h.FindTargetRetain(retentionCount, modifier)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnErrorReleasingLocks(err)
 	if target == nil {
 		tracelog.InfoLogger.Printf("No backup found for deletion")
-		os.Exit(0)
+		ExitReleasingLocks(0)
 	}
 	err = h.DeleteBeforeTarget(target, confirmed)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnErrorReleasingLocks(err)
 }
 
 func (h *DeleteHandler) HandleDeleteRetainAfter(args []string, confirmed bool) {
 	modifier, retentionSir, afterStr := ExtractDeleteRetainAfterModifierFromArgs(args)
 	retentionCount, err := strconv.Atoi(retentionSir)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnErrorReleasingLocks(err)
 
 	target, err := h.FindTargetRetainAfter(retentionCount, afterStr, modifier)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnErrorReleasingLocks(err)
 
 	if target == nil {
 		tracelog.InfoLogger.Printf("No backup found for deletion")
-		os.Exit(0)
+		ExitReleasingLocks(0)
 	}
 
 	err = h.DeleteBeforeTarget(target, confirmed)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnErrorReleasingLocks(err)
 }
 
 func (h *DeleteHandler) HandleDeleteTarget(targetSelector BackupSelector, confirmed, findFull bool) {
 	target, err := h.FindTargetBySelector(targetSelector)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnErrorReleasingLocks(err)
 
 	if target == nil {
 		// since we want to delete the target backup, we should fail if
 		// we didn't find the requested backup for deletion
-		tracelog.ErrorLogger.Fatal("Requested backup was not found")
+		FatalOnErrorReleasingLocks(errors.New("Requested backup was not found"))
 	}
 
 	folderFilter := func(name string) bool { return true }
 	err = h.DeleteTarget(target, confirmed, findFull, folderFilter)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnErrorReleasingLocks(err)
 }
 
 func (h *DeleteHandler) HandleDeleteEverything(args []string, permanentBackups []string, confirmed bool) {
@@ -181,7 +186,7 @@ func (h *DeleteHandler) HandleDeleteEverything(args []string, permanentBackups [
 
 	if len(permanentBackups) > 0 {
 		if !forceModifier {
-			tracelog.ErrorLogger.Fatalf("Found permanent backups=%v\n", permanentBackups)
+			FatalOnErrorReleasingLocks(errors.Errorf("Found permanent backups=%v", permanentBackups))
 		}
 		tracelog.InfoLogger.Printf("Found permanent backups=%v\n", permanentBackups)
 	}
@@ -346,13 +351,22 @@ func (h *DeleteHandler) FindTargetRetainAfterTime(retentionCount int, timeLine t
 }
 
 func (h *DeleteHandler) DeleteEverything(confirmed bool) {
+	if confirmed {
+		FatalOnErrorReleasingLocks(h.CheckGuardrail("delete everything"))
+	}
 	filter := func(object storage.Object) bool { return true }
 	folderFilter := func(path string) bool { return true }
 	err := DeleteObjectsWhere(h.Folder, confirmed, filter, folderFilter)
-	tracelog.ErrorLogger.FatalOnError(err)
+	FatalOnErrorReleasingLocks(err)
 }
 
 func (h *DeleteHandler) DeleteBeforeTarget(target BackupObject, confirmed bool) error {
+	if confirmed {
+		err := h.CheckWideDeletionGuardrail(target)
+		if err != nil {
+			return err
+		}
+	}
 	objFilter := func(storage.Object) bool { return true }
 	folderFilter := func(string) bool { return true }
 
@@ -391,7 +405,7 @@ func (h *DeleteHandler) DeleteTarget(target BackupObject, confirmed, findFull bo
 	backupNamesToDelete := make(map[string]bool)
 	for _, bTarget := range backupsToDelete {
 		if h.isPermanent(bTarget) {
-			tracelog.ErrorLogger.Fatalf("Unable to delete permanent backup %s\n", bTarget.GetName())
+			FatalOnErrorReleasingLocks(errors.Errorf("Unable to delete permanent backup %s", bTarget.GetName()))
 		}
 		backupNamesToDelete[bTarget.GetBackupName()] = true
 	}
@@ -483,10 +497,22 @@ func DeleteObjectsWhere(
 	if len(filteredRelativePaths) == 0 {
 		return nil
 	}
-	if confirm {
-		return folder.DeleteObjects(filteredRelativePaths)
+	if !confirm {
+		tracelog.InfoLogger.Println("Dry run, nothing were deleted")
+		return nil
+	}
+	for start := 0; start < len(filteredRelativePaths); start += deleteBatchSize {
+		// the deletion stops as soon as the metadata lock it is made under is taken over
+		err = checkHeldMetadataLocks()
+		if err != nil {
+			return err
+		}
+		end := utility.Min(start+deleteBatchSize, len(filteredRelativePaths))
+		err = folder.DeleteObjects(filteredRelativePaths[start:end])
+		if err != nil {
+			return err
+		}
 	}
-	tracelog.InfoLogger.Println("Dry run, nothing were deleted")
 	return nil
 }
 
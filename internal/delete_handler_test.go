@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	conf "github.com/wal-g/wal-g/internal/config"
 	"github.com/wal-g/wal-g/pkg/storages/memory"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 )
@@ -60,3 +62,22 @@ func TestDeleteOldObjectsWithFilter(t *testing.T) {
 	assert.Equal(t, 1, len(savedObjects))
 	assert.Equal(t, expectedOnlyOneSavedObjectName, savedObjects[0].GetName())
 }
+
+func TestDeleteOldObjects_StopsOnLostMetadataLock(t *testing.T) {
+	ConfigureSettings("")
+	conf.InitConfig()
+	folder := CreateMockStorageFolder()
+	lock, err := AcquireMetadataLock(folder)
+	require.NoError(t, err)
+	defer lock.Release()
+	// the lock is taken over, e.g. after a long pause of the process
+	close(lock.lease.lost)
+
+	allObjects := func(storage.Object) bool { return true }
+	allFolders := func(string) bool { return true }
+	err = DeleteObjectsWhere(folder.GetSubFolder("basebackups_005/"), true, allObjects, allFolders)
+	assert.IsType(t, MetadataLockLostError{}, err)
+	savedObjects, err := storage.ListFolderRecursively(folder.GetSubFolder("basebackups_005/"))
+	require.NoError(t, err)
+	assert.Len(t, savedObjects, 10)
+}
@@ -15,8 +15,8 @@ func FatalOnUnrecoverableMetadataError(backupTime BackupTime, err error) {
 		tracelog.InfoLogger.Printf("Backup %s lacks metadata to check if it's permanent, ignoring...",
 			backupTime.BackupName)
 	} else {
-		tracelog.ErrorLogger.Fatalf("failed to fetch backup meta for backup %s with error %s",
-			backupTime.BackupName, err.Error())
+		FatalOnErrorReleasingLocks(fmt.Errorf("failed to fetch backup meta for backup %s with error %s",
+			backupTime.BackupName, err.Error()))
 	}
 }
 
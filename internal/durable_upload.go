@@ -0,0 +1,337 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	minMetadataUploadRetryWait = time.Second
+	maxMetadataUploadRetryWait = 30 * time.Second
+	metadataSpoolFolderName    = "metadata_spool"
+	metadataSpoolFileSuffix    = ".json"
+)
+
+// spooledMetadata is the metadata object which failed to upload, saved locally until the next WAL-G invocation
+// uploads it. FolderPath is the path of the storage folder the object belongs to. BackupFolder is the folder,
+// relative to FolderPath, holding the data of the backup the object describes, if any.
+type spooledMetadata struct {
+	FolderPath   string    `json:"folder_path"`
+	Name         string    `json:"name"`
+	Content      []byte    `json:"content"`
+	SpooledAt    time.Time `json:"spooled_at"`
+	BackupFolder string    `json:"backup_folder,omitempty"`
+}
+
+// MetadataSpooledError is returned by UploadDurably when the object is not uploaded, but saved to the local spool.
+// The backup isn't visible until the next invocation uploads the object.
+type MetadataSpooledError struct {
+	error
+}
+
+func newMetadataSpooledError(path, spoolPath string) MetadataSpooledError {
+	return MetadataSpooledError{errors.Errorf("%s is saved to %s and will be uploaded by the next WAL-G invocation",
+		path, spoolPath)}
+}
+
+func (err MetadataSpooledError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// IsMetadataSpooled tells if the upload failed only because the object is left in the local spool
+func IsMetadataSpooled(err error) bool {
+	var spooledErr MetadataSpooledError
+	return errors.As(err, &spooledErr)
+}
+
+// metadataSpoolMutex keeps the concurrent durable uploads of a single invocation from flushing the same files
+var metadataSpoolMutex sync.Mutex
+
+// UploadDtoDurably serializes given object to JSON and puts it to path with UploadDurably
+func UploadDtoDurably(folder storage.Folder, dto interface{}, path string) error {
+	return UploadDtoDurablyForBackup(folder, dto, path, backupFolderOf(path))
+}
+
+// UploadDtoDurablyForBackup is like UploadDtoDurably, but takes the folder holding the backup data, see UploadDurablyForBackup
+func UploadDtoDurablyForBackup(folder storage.Folder, dto interface{}, path, backupFolder string) error {
+	marshaller, err := NewDtoSerializer()
+	if err != nil {
+		return err
+	}
+	r, err := marshaller.Marshal(dto)
+	if err != nil {
+		return NewSentinelMarshallingError(path, err)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return NewSentinelMarshallingError(path, err)
+	}
+	return UploadDurablyForBackup(context.Background(), folder, path, content, backupFolder)
+}
+
+// UploadDurably puts the small but critical object, like the backup sentinel or metadata, to the storage.
+// The upload is retried WALG_METADATA_UPLOAD_RETRIES times, then it is tried through
+// WALG_METADATA_FALLBACK_ENDPOINT, if set. If the storage is still unreachable, the object is saved
+// to the local spool and uploaded by the next invocation, so a brief outage at the end of a backup
+// doesn't make it invalid, and MetadataSpooledError is returned. The retries stop when the context is done.
+func UploadDurably(ctx context.Context, folder storage.Folder, path string, content []byte) error {
+	return UploadDurablyForBackup(ctx, folder, path, content, backupFolderOf(path))
+}
+
+// UploadDurablyForBackup is like UploadDurably, but takes the folder, relative to folder, holding the data
+// of the backup the object describes. The spooled object is dropped if the backup folder is empty by the time
+// it is uploaded, so the deleted backup isn't resurrected. The empty backupFolder disables the check.
+func UploadDurablyForBackup(ctx context.Context, folder storage.Folder, path string, content []byte, backupFolder string) error {
+	spooled := spooledMetadata{FolderPath: folder.GetPath(), Name: path, Content: content, BackupFolder: backupFolder}
+	if pending := flushSpooledMetadata(folder); pending > 0 {
+		// the object must not overtake the ones spooled before it, e.g. the sentinel must not go before the metadata
+		return spoolPendingMetadata(spooled, nil)
+	}
+
+	err := putWithRetries(ctx, folder, path, content)
+	if err == nil {
+		return nil
+	}
+	tracelog.WarningLogger.Printf("Failed to upload %s: %v", path, err)
+
+	fallbackFolder, fallbackErr := configureMetadataFallbackFolder(folder)
+	if fallbackErr != nil {
+		tracelog.WarningLogger.Printf("Failed to configure the metadata fallback endpoint: %v", fallbackErr)
+	} else if fallbackFolder != nil {
+		tracelog.InfoLogger.Printf("Uploading %s through the fallback endpoint", path)
+		fallbackErr = putWithRetries(ctx, fallbackFolder, path, content)
+		if fallbackErr == nil {
+			return nil
+		}
+		tracelog.WarningLogger.Printf("Failed to upload %s through the fallback endpoint: %v", path, fallbackErr)
+	}
+
+	return spoolPendingMetadata(spooled, err)
+}
+
+// spoolPendingMetadata returns uploadErr if the object can't be spooled either
+func spoolPendingMetadata(spooled spooledMetadata, uploadErr error) error {
+	spoolPath, err := spoolMetadata(spooled)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to save %s to the metadata spool: %v", spooled.Name, err)
+		if uploadErr == nil {
+			return err
+		}
+		return uploadErr
+	}
+	return newMetadataSpooledError(spooled.Name, spoolPath)
+}
+
+// backupFolderOf returns the folder holding the data of the backup described by the metadata object at path:
+// the sentinel <name>_backup_stop_sentinel.json and the objects in <name>/ belong to the <name>/ folder
+func backupFolderOf(path string) string {
+	if strings.HasSuffix(path, utility.SentinelSuffix) {
+		return strings.TrimSuffix(path, utility.SentinelSuffix) + "/"
+	}
+	if i := strings.Index(path, "/"); i > 0 {
+		return path[:i+1]
+	}
+	return ""
+}
+
+// FlushSpooledMetadata uploads the metadata saved to the local spool by the previous invocations. The objects
+// are uploaded in the order they were spooled, so the backup sentinel never goes before the backup metadata.
+// The objects spooled outside the folder are left for the invocations using their folders. The objects spooled
+// longer than WALG_METADATA_SPOOL_TTL ago and the ones of the deleted backups are dropped.
+func FlushSpooledMetadata(folder storage.Folder) {
+	flushSpooledMetadata(folder)
+}
+
+// flushSpooledMetadata returns the number of the spooled objects left after the failed upload
+func flushSpooledMetadata(folder storage.Folder) (pending int) {
+	metadataSpoolMutex.Lock()
+	defer metadataSpoolMutex.Unlock()
+
+	spoolDir := getMetadataSpoolPath()
+	names := listSpoolFiles(spoolDir)
+
+	ttl, err := conf.GetDurationSetting(conf.MetadataSpoolTTLSetting)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to get the metadata spool TTL, the spooled metadata is kept: %v", err)
+		ttl = 0
+	}
+
+	for i, name := range names {
+		spoolFile := filepath.Join(spoolDir, name)
+		flushed, err := flushSpooledFile(folder, spoolFile, ttl)
+		if err != nil {
+			// the objects spooled later must wait for this one
+			tracelog.WarningLogger.Printf("Failed to upload the spooled metadata %s: %v", spoolFile, err)
+			return len(names) - i
+		}
+		if flushed {
+			if err = os.Remove(spoolFile); err != nil {
+				tracelog.WarningLogger.Printf("Failed to remove the spooled metadata %s: %v", spoolFile, err)
+			}
+		}
+	}
+	return 0
+}
+
+// isMetadataSpooled reports whether the object is waiting in the spool to be uploaded to the folder
+func isMetadataSpooled(folder storage.Folder, name string) bool {
+	metadataSpoolMutex.Lock()
+	defer metadataSpoolMutex.Unlock()
+
+	spoolDir := getMetadataSpoolPath()
+	objectPath := storage.JoinPath(folder.GetPath(), name)
+	for _, spoolName := range listSpoolFiles(spoolDir) {
+		spoolContent, err := os.ReadFile(filepath.Join(spoolDir, spoolName))
+		if err != nil {
+			continue
+		}
+		var spooled spooledMetadata
+		if json.Unmarshal(spoolContent, &spooled) != nil {
+			continue
+		}
+		if storage.JoinPath(spooled.FolderPath, spooled.Name) == objectPath {
+			return true
+		}
+	}
+	return false
+}
+
+// listSpoolFiles returns the names of the spooled objects in the order they were spooled in
+func listSpoolFiles(spoolDir string) []string {
+	entries, err := os.ReadDir(spoolDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			tracelog.WarningLogger.Printf("Failed to read the metadata spool %s: %v", spoolDir, err)
+		}
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), metadataSpoolFileSuffix) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// flushSpooledFile returns true if the spooled object is either uploaded or dropped
+func flushSpooledFile(folder storage.Folder, spoolFile string, ttl time.Duration) (bool, error) {
+	spoolContent, err := os.ReadFile(spoolFile)
+	if err != nil {
+		return false, err
+	}
+	var spooled spooledMetadata
+	if err = json.Unmarshal(spoolContent, &spooled); err != nil {
+		return false, errors.Wrap(err, "failed to parse the spooled metadata")
+	}
+
+	if !strings.HasPrefix(spooled.FolderPath, folder.GetPath()) {
+		return false, nil
+	}
+	if ttl > 0 && time.Since(spooled.SpooledAt) > ttl {
+		tracelog.WarningLogger.Printf("Dropping %s%s spooled at %s: it is older than %s %s", spooled.FolderPath, spooled.Name,
+			spooled.SpooledAt.Format(time.RFC3339), conf.MetadataSpoolTTLSetting, ttl)
+		return true, nil
+	}
+	target := folder.GetSubFolder(strings.TrimPrefix(spooled.FolderPath, folder.GetPath()))
+	if spooled.BackupFolder != "" {
+		objects, subFolders, err := target.GetSubFolder(spooled.BackupFolder).ListFolder()
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to check the backup folder %s", spooled.BackupFolder)
+		}
+		if len(objects) == 0 && len(subFolders) == 0 {
+			tracelog.WarningLogger.Printf("Dropping %s%s spooled at %s: the backup folder %s is empty, the backup is deleted",
+				spooled.FolderPath, spooled.Name, spooled.SpooledAt.Format(time.RFC3339), spooled.BackupFolder)
+			return true, nil
+		}
+	}
+	err = target.PutObject(spooled.Name, bytes.NewReader(spooled.Content))
+	if err != nil {
+		return false, err
+	}
+	tracelog.InfoLogger.Printf("Uploaded %s%s spooled at %s", spooled.FolderPath, spooled.Name,
+		spooled.SpooledAt.Format(time.RFC3339))
+	return true, nil
+}
+
+func putWithRetries(ctx context.Context, folder storage.Folder, path string, content []byte) error {
+	retries := viper.GetInt(conf.MetadataUploadRetriesSetting)
+	sleeper := NewExponentialSleeper(minMetadataUploadRetryWait, maxMetadataUploadRetryWait)
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = folder.PutObject(path, bytes.NewReader(content))
+		if err == nil || attempt >= retries || ctx.Err() != nil {
+			return err
+		}
+		tracelog.WarningLogger.Printf("Failed to upload %s (attempt %d of %d): %v", path, attempt+1, retries+1, err)
+		sleeper.Sleep()
+	}
+}
+
+// configureMetadataFallbackFolder returns the folder of the storage configured the same way as the default one,
+// but accessed through the fallback endpoint, or nil if the endpoint is not set
+func configureMetadataFallbackFolder(folder storage.Folder) (storage.Folder, error) {
+	endpoint, ok := conf.GetSetting(conf.MetadataFallbackEndpoint)
+	if !ok || endpoint == "" {
+		return nil, nil
+	}
+	config := viper.New()
+	for key, value := range viper.AllSettings() {
+		config.Set(key, value)
+	}
+	config.Set("AWS_ENDPOINT", endpoint)
+	config.Set("S3_UPLOAD_ENDPOINT", endpoint)
+
+	st, err := ConfigureStorageForSpecificConfig(config, configureRootWraps()...)
+	if err != nil {
+		return nil, err
+	}
+	root := st.RootFolder()
+	return root.GetSubFolder(relativeFolderPath(folder.GetPath(), []FetchSource{{Root: root}})), nil
+}
+
+func spoolMetadata(spooled spooledMetadata) (string, error) {
+	metadataSpoolMutex.Lock()
+	defer metadataSpoolMutex.Unlock()
+
+	spoolDir := getMetadataSpoolPath()
+	if err := os.MkdirAll(spoolDir, 0700); err != nil {
+		return "", err
+	}
+	spooled.SpooledAt = time.Now()
+	spoolContent, err := json.Marshal(spooled)
+	if err != nil {
+		return "", err
+	}
+	// the names keep the order the objects were spooled in
+	spoolFile := filepath.Join(spoolDir, fmt.Sprintf("%020d%s", spooled.SpooledAt.UnixNano(), metadataSpoolFileSuffix))
+	tmpFile := spoolFile + ".tmp"
+	if err = os.WriteFile(tmpFile, spoolContent, 0600); err != nil {
+		return "", err
+	}
+	return spoolFile, os.Rename(tmpFile, spoolFile)
+}
+
+func getMetadataSpoolPath() string {
+	if spoolPath, ok := conf.GetSetting(conf.MetadataSpoolPathSetting); ok && spoolPath != "" {
+		return spoolPath
+	}
+	return filepath.Join(GetDataFolderPath(), metadataSpoolFolderName)
+}
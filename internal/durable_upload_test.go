@@ -0,0 +1,220 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+type unreachableFolder struct {
+	storage.Folder
+}
+
+func (folder unreachableFolder) GetSubFolder(path string) storage.Folder {
+	return unreachableFolder{folder.Folder.GetSubFolder(path)}
+}
+
+func (folder unreachableFolder) PutObject(string, io.Reader) error {
+	return errors.New("storage is unreachable")
+}
+
+func (folder unreachableFolder) ListFolder() ([]storage.Object, []storage.Folder, error) {
+	return nil, nil, errors.New("storage is unreachable")
+}
+
+// noUploadFolder lists and deletes the objects, but fails to upload them
+type noUploadFolder struct {
+	storage.Folder
+}
+
+func (folder noUploadFolder) GetSubFolder(path string) storage.Folder {
+	return noUploadFolder{folder.Folder.GetSubFolder(path)}
+}
+
+func (folder noUploadFolder) PutObject(string, io.Reader) error {
+	return errors.New("storage refuses the uploads")
+}
+
+func setUpMetadataSpool(t *testing.T) string {
+	spoolPath := t.TempDir()
+	viper.Set(conf.MetadataSpoolPathSetting, spoolPath)
+	viper.Set(conf.MetadataUploadRetriesSetting, 0)
+	viper.Set(conf.MetadataSpoolTTLSetting, "72h")
+	t.Cleanup(func() {
+		viper.Set(conf.MetadataSpoolPathSetting, nil)
+		viper.Set(conf.MetadataUploadRetriesSetting, nil)
+		viper.Set(conf.MetadataSpoolTTLSetting, nil)
+	})
+	return spoolPath
+}
+
+func readObject(t *testing.T, folder storage.Folder, path string) string {
+	reader, err := folder.ReadObject(path)
+	require.NoError(t, err)
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestUploadDurably_Uploads(t *testing.T) {
+	spoolPath := setUpMetadataSpool(t)
+	folder := memory.NewFolder("in_memory/", memory.NewKVS())
+
+	err := UploadDurably(context.Background(), folder, "base_1_backup_stop_sentinel.json", []byte("{}"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "{}", readObject(t, folder, "base_1_backup_stop_sentinel.json"))
+	entries, err := os.ReadDir(spoolPath)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestUploadDurably_SpoolsAndFlushesInOrder(t *testing.T) {
+	spoolPath := setUpMetadataSpool(t)
+	folder := memory.NewFolder("in_memory/", memory.NewKVS())
+	backupsFolder := folder.GetSubFolder("basebackups_005")
+
+	err := UploadDurably(context.Background(), unreachableFolder{backupsFolder}, "base_1/metadata.json", []byte("meta"))
+	assert.True(t, IsMetadataSpooled(err))
+	err = UploadDurably(context.Background(), unreachableFolder{backupsFolder}, "base_1_backup_stop_sentinel.json",
+		[]byte("sentinel"))
+	assert.True(t, IsMetadataSpooled(err))
+	entries, err := os.ReadDir(spoolPath)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	err = backupsFolder.PutObject("base_1/tar_partitions/part_1.tar.br", strings.NewReader("data"))
+	require.NoError(t, err)
+	FlushSpooledMetadata(folder)
+
+	assert.Equal(t, "meta", readObject(t, backupsFolder, "base_1/metadata.json"))
+	assert.Equal(t, "sentinel", readObject(t, backupsFolder, "base_1_backup_stop_sentinel.json"))
+	entries, err = os.ReadDir(spoolPath)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestUploadDurably_DoesNotOvertakeSpooled(t *testing.T) {
+	spoolPath := setUpMetadataSpool(t)
+	folder := memory.NewFolder("in_memory/", memory.NewKVS())
+
+	err := UploadDurably(context.Background(), unreachableFolder{folder}, "base_1/metadata.json", []byte("meta"))
+	assert.True(t, IsMetadataSpooled(err))
+
+	// the spooled metadata can't be flushed to the unreachable folder, so the sentinel waits for it in the spool
+	err = UploadDurably(context.Background(), unreachableFolder{folder}, "base_1_backup_stop_sentinel.json",
+		[]byte("sentinel"))
+	assert.True(t, IsMetadataSpooled(err))
+	entries, err := os.ReadDir(spoolPath)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestFlushSpooledMetadata_SkipsOtherFolders(t *testing.T) {
+	spoolPath := setUpMetadataSpool(t)
+	folder := memory.NewFolder("in_memory/", memory.NewKVS())
+
+	err := UploadDurably(context.Background(), unreachableFolder{folder}, "base_1_backup_stop_sentinel.json", []byte("{}"))
+	assert.True(t, IsMetadataSpooled(err))
+
+	FlushSpooledMetadata(memory.NewFolder("other/", memory.NewKVS()))
+
+	entries, err := os.ReadDir(spoolPath)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestFlushSpooledMetadata_DropsDeletedBackups(t *testing.T) {
+	spoolPath := setUpMetadataSpool(t)
+	folder := memory.NewFolder("in_memory/", memory.NewKVS())
+
+	err := UploadDurably(context.Background(), unreachableFolder{folder}, "base_1_backup_stop_sentinel.json", []byte("{}"))
+	assert.True(t, IsMetadataSpooled(err))
+
+	// the backup folder is empty, i.e. the backup is deleted while its sentinel is spooled
+	FlushSpooledMetadata(folder)
+
+	exists, err := folder.Exists("base_1_backup_stop_sentinel.json")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	entries, err := os.ReadDir(spoolPath)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestFlushSpooledMetadata_DropsExpired(t *testing.T) {
+	spoolPath := setUpMetadataSpool(t)
+	folder := memory.NewFolder("in_memory/", memory.NewKVS())
+	err := folder.PutObject("base_1/tar_partitions/part_1.tar.br", strings.NewReader("data"))
+	require.NoError(t, err)
+
+	err = UploadDurably(context.Background(), unreachableFolder{folder}, "base_1_backup_stop_sentinel.json", []byte("{}"))
+	assert.True(t, IsMetadataSpooled(err))
+
+	viper.Set(conf.MetadataSpoolTTLSetting, "1ns")
+	FlushSpooledMetadata(folder)
+
+	exists, err := folder.Exists("base_1_backup_stop_sentinel.json")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	entries, err := os.ReadDir(spoolPath)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestCleanupIncompleteBackups_KeepsSpooledBackups(t *testing.T) {
+	spoolPath := setUpMetadataSpool(t)
+	viper.Set(conf.IncompleteBackupTTLSetting, "1m")
+	t.Cleanup(func() { viper.Set(conf.IncompleteBackupTTLSetting, nil) })
+	folder := memory.NewFolder("in_memory/", memory.NewKVS())
+	backupsFolder := folder.GetSubFolder(utility.BaseBackupPath)
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	backupName := "base_000000010000000000000002"
+	require.NoError(t, UploadDto(folder, IncompleteBackupMarker{
+		BackupName:    backupName,
+		BackupsPath:   utility.BaseBackupPath,
+		Hostname:      hostname,
+		HeartbeatTime: time.Now().Add(-time.Hour),
+	}, IncompleteBackupsPath+"/"+backupName+".json"))
+	require.NoError(t, backupsFolder.PutObject(backupName+"/tar_partitions/part_1.tar.lz4", strings.NewReader("data")))
+	err = UploadDurablyForBackup(context.Background(), unreachableFolder{backupsFolder},
+		SentinelNameFromBackup(backupName), []byte("sentinel"), backupName)
+	require.True(t, IsMetadataSpooled(err))
+
+	// the sentinel can't be uploaded yet, the backup must survive the cleanup
+	require.NoError(t, CleanupIncompleteBackups(noUploadFolder{folder}, true, true))
+	exists, err := backupsFolder.Exists(backupName + "/tar_partitions/part_1.tar.lz4")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	entries, err := os.ReadDir(spoolPath)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// the cleanup uploads the spooled sentinel before looking for the incomplete backups
+	require.NoError(t, CleanupIncompleteBackups(folder, true, true))
+	assert.Equal(t, "sentinel", readObject(t, backupsFolder, SentinelNameFromBackup(backupName)))
+	exists, err = backupsFolder.Exists(backupName + "/tar_partitions/part_1.tar.lz4")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	entries, err = os.ReadDir(spoolPath)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	markers, _, err := folder.GetSubFolder(IncompleteBackupsPath).ListFolder()
+	require.NoError(t, err)
+	assert.Empty(t, markers)
+}
@@ -156,9 +156,11 @@ func ExtractAllWithSleeper(tarInterpreter TarInterpreter, files []ReaderMaker, s
 		return err
 	}
 	retries := conf.GetFetchRetries()
+	alternateSources := newAlternateSourcesSwitcher()
 
 	for currentRun := files; len(currentRun) > 0; {
 		failed := tryExtractFiles(currentRun, tarInterpreter, downloadingConcurrency)
+		failed = alternateSources.switchFailed(failed)
 		if downloadingConcurrency > 1 {
 			downloadingConcurrency /= 2
 		} else if len(failed) == len(currentRun) && retries <= 0 {
@@ -168,7 +170,7 @@ func ExtractAllWithSleeper(tarInterpreter TarInterpreter, files []ReaderMaker, s
 		retries--
 		currentRun = failed
 		if len(failed) > 0 {
-			tracelog.WarningLogger.Printf("%d files failed to download: %s. Going to sleep and retry downloading them.\n",
+			getRestoreReport(failed).AddWarning("%d files failed to download: %s. Going to sleep and retry downloading them.",
 				len(failed), readerMakersToFilePaths(failed))
 			tracelog.WarningLogger.Printf("retries left: %d", retries)
 			sleeper.Sleep()
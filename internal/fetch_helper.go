@@ -9,9 +9,13 @@ import (
 	"path/filepath"
 
 	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 	"github.com/wal-g/tracelog"
 	"github.com/wal-g/wal-g/internal/compression"
+	conf "github.com/wal-g/wal-g/internal/config"
 	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/internal/objcache"
+	"github.com/wal-g/wal-g/internal/walz"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 	"github.com/wal-g/wal-g/utility"
 )
@@ -101,45 +105,83 @@ func DecryptBytes(archiveReader io.Reader) (io.Reader, error) {
 // CachedDecompressor is the file extension describing decompressor
 type CachedDecompressor struct {
 	FileExtension string
+	// WalEnvelopeSeen tells that the files in the walz envelope were seen in the storage
+	WalEnvelopeSeen bool `json:",omitempty"`
 }
 
 func GetLastDecompressor() (compression.Decompressor, error) {
-	var cache CachedDecompressor
-	var cacheFilename string
-
-	usr, err := user.Current()
-	if err == nil {
-		cacheFilename = filepath.Join(usr.HomeDir, ".walg_decompressor_cache")
-		file, err := os.ReadFile(cacheFilename)
-		if err == nil {
-			err = json.Unmarshal(file, &cache)
-			if err != nil {
-				return nil, err
-			}
-			return compression.FindDecompressor(cache.FileExtension), nil
-		}
+	cache, _, err := readDecompressorCache()
+	if err != nil || cache.FileExtension == "" {
 		return nil, err
 	}
-
-	return nil, nil
+	return compression.FindDecompressor(cache.FileExtension), nil
 }
 
 func SetLastDecompressor(decompressor compression.Decompressor) error {
-	var cache CachedDecompressor
-	usr, err := user.Current()
+	return updateDecompressorCache(func(cache *CachedDecompressor) bool {
+		if cache.FileExtension == decompressor.FileExtension() {
+			return false
+		}
+		cache.FileExtension = decompressor.FileExtension()
+		return true
+	})
+}
+
+// MarkWalEnvelopeSeen remembers that the storage holds the files in the walz envelope,
+// so they are looked up by the later fetches even if WALG_WAL_ENVELOPE isn't set
+func MarkWalEnvelopeSeen() error {
+	return updateDecompressorCache(func(cache *CachedDecompressor) bool {
+		if cache.WalEnvelopeSeen {
+			return false
+		}
+		cache.WalEnvelopeSeen = true
+		return true
+	})
+}
+
+func isWalEnvelopeSeen() bool {
+	cache, _, _ := readDecompressorCache()
+	return cache.WalEnvelopeSeen
+}
 
+// getDecompressorCacheFilename is replaced in the tests
+var getDecompressorCacheFilename = func() (string, error) {
+	usr, err := user.Current()
 	if err != nil {
-		return err
+		return "", err
 	}
+	return filepath.Join(usr.HomeDir, ".walg_decompressor_cache"), nil
+}
 
-	cacheFilename := filepath.Join(usr.HomeDir, ".walg_decompressor_cache")
-	cache.FileExtension = decompressor.FileExtension()
+func readDecompressorCache() (cache CachedDecompressor, cacheFilename string, err error) {
+	cacheFilename, err = getDecompressorCacheFilename()
+	if err != nil {
+		return cache, "", err
+	}
+	file, err := os.ReadFile(cacheFilename)
+	if err != nil {
+		return cache, cacheFilename, err
+	}
+	return cache, cacheFilename, json.Unmarshal(file, &cache)
+}
 
+// updateDecompressorCache rewrites the cache file if update changes the cache
+func updateDecompressorCache(update func(cache *CachedDecompressor) bool) error {
+	cache, cacheFilename, err := readDecompressorCache()
+	if cacheFilename == "" {
+		return err
+	}
+	if err != nil {
+		// the missing or broken cache is written anew
+		cache = CachedDecompressor{}
+	}
+	if !update(&cache) {
+		return nil
+	}
 	marshal, err := json.Marshal(&cache)
 	if err == nil {
 		return os.WriteFile(cacheFilename, marshal, 0644)
 	}
-
 	return err
 }
 
@@ -168,7 +210,22 @@ func putCachedDecompressorInFirstPlace(decompressors []compression.Decompressor)
 
 // TODO : unit tests
 func DownloadAndDecompressStorageFile(reader StorageFolderReader, fileName string) (io.ReadCloser, error) {
+	// the files in the walz envelope are looked up first only if they are expected to be there,
+	// and they aren't looked up at all unless the envelope is enabled or has been seen in the storage
+	envelopeEnabled := viper.GetBool(conf.PgWalEnvelopeSetting)
+	if envelopeEnabled {
+		envelopeReader, exists, err := tryDownloadWalzFile(reader, fileName)
+		if err != nil || exists {
+			return envelopeReader, err
+		}
+	}
 	archiveReader, decompressor, err := findDecompressorAndDownload(reader, fileName)
+	if _, ok := err.(ArchiveNonExistenceError); ok && !envelopeEnabled && isWalEnvelopeSeen() {
+		envelopeReader, exists, envelopeErr := tryDownloadWalzFile(reader, fileName)
+		if envelopeErr != nil || exists {
+			return envelopeReader, envelopeErr
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -210,6 +267,65 @@ func findDecompressorAndDownload(reader StorageFolderReader, fileName string) (i
 	return nil, nil, newArchiveNonExistenceError(fileName)
 }
 
+// tryDownloadWalzFile downloads the file stored in the walz envelope. The envelope header tells how to decode
+// the content and what file it holds, the decoded content is checked against the header checksum.
+func tryDownloadWalzFile(reader StorageFolderReader, fileName string) (io.ReadCloser, bool, error) {
+	archiveReader, exists, err := TryDownloadFile(reader, fileName+"."+walz.FileExtension)
+	if err != nil || !exists {
+		return nil, exists, err
+	}
+	if err := MarkWalEnvelopeSeen(); err != nil {
+		tracelog.WarningLogger.Printf("Failed to remember that the walz envelope is used: %v", err)
+	}
+	contentReader, err := decodeWalzFile(archiveReader, filepath.Base(fileName))
+	if err != nil {
+		utility.LoggedClose(archiveReader, "")
+		return nil, true, errors.Wrapf(err, "failed to decode %s.%s", fileName, walz.FileExtension)
+	}
+	return ioextensions.ReadCascadeCloser{
+		Reader: contentReader,
+		Closer: ioextensions.NewMultiCloser([]io.Closer{archiveReader, contentReader}),
+	}, true, nil
+}
+
+func decodeWalzFile(archiveReader io.Reader, fileName string) (io.ReadCloser, error) {
+	header, err := walz.ReadHeader(archiveReader)
+	if err != nil {
+		return nil, err
+	}
+	err = header.Validate(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	contentReader := archiveReader
+	if header.KeyID != "" {
+		crypter := ConfigureCrypter()
+		if crypter == nil {
+			return nil, errors.Errorf("the file is encrypted with the key '%s', but no encryption is configured", header.KeyID)
+		}
+		contentReader, err = crypter.Decrypt(archiveReader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to init decrypt reader: %w", err)
+		}
+	}
+	if header.Compression == "" {
+		return io.NopCloser(walz.NewVerifyingReader(contentReader, header)), nil
+	}
+	decompressor := compression.FindDecompressor(header.Compression)
+	if decompressor == nil {
+		return nil, errors.Errorf("decompressor for extension '%s' was not found", header.Compression)
+	}
+	decompressedReader, err := decompressor.Decompress(contentReader)
+	if err != nil {
+		return nil, err
+	}
+	return ioextensions.ReadCascadeCloser{
+		Reader: walz.NewVerifyingReader(decompressedReader, header),
+		Closer: decompressedReader,
+	}, nil
+}
+
 // TODO : unit tests
 // DownloadFileTo downloads a file and writes it to local file
 func DownloadFileTo(folderReader StorageFolderReader, fileName string, dstPath string) error {
@@ -232,3 +348,70 @@ func DownloadFileTo(folderReader StorageFolderReader, fileName string, dstPath s
 	// In case of error we may have some content within file. Leave it alone.
 	return err
 }
+
+const (
+	pipelinedFetchChunkSize  = 256 << 10
+	pipelinedFetchChunkCount = 8
+)
+
+// DownloadFileToPipelined downloads a file and writes it to local file like DownloadFileTo, but the download,
+// the decryption with decompression and the write run concurrently, so the file is written while it is still
+// being downloaded. The space for the expected size (if known) is preallocated before the download starts.
+func DownloadFileToPipelined(folderReader StorageFolderReader, fileName string, dstPath string, expectedSize int64) error {
+	file, err := os.OpenFile(dstPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(file, "")
+	if expectedSize > 0 {
+		if err = preallocateFile(file, expectedSize); err != nil {
+			tracelog.DebugLogger.Printf("Failed to preallocate %s: %v", dstPath, err)
+		}
+	}
+
+	reader, err := DownloadAndDecompressStorageFile(readAheadFolderReader{folderReader}, fileName)
+	if err != nil {
+		_ = os.Remove(dstPath)
+		return err
+	}
+	reader = ioextensions.NewReadAheadReader(reader, pipelinedFetchChunkSize, pipelinedFetchChunkCount)
+	defer utility.LoggedClose(reader, "")
+
+	_, err = utility.FastCopy(file, reader)
+	return err
+}
+
+// readAheadFolderReader reads the downloaded objects ahead, so the download doesn't wait for the data to be consumed
+type readAheadFolderReader struct {
+	StorageFolderReader
+}
+
+func (reader readAheadFolderReader) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	object, err := reader.StorageFolderReader.ReadObject(objectRelativePath)
+	if err != nil {
+		return nil, err
+	}
+	if readsStorageObjects(reader.StorageFolderReader) {
+		return ioextensions.NewInterruptibleReadAheadReader(object, pipelinedFetchChunkSize, pipelinedFetchChunkCount), nil
+	}
+	return ioextensions.NewReadAheadReader(object, pipelinedFetchChunkSize, pipelinedFetchChunkCount), nil
+}
+
+// readsStorageObjects tells if the folder reader returns the storage object readers as they are. The object cache
+// writes the cache entry and the erasure coding decodes the stripes as the object is read, so their readers
+// can't be closed while they are read.
+func readsStorageObjects(folderReader StorageFolderReader) bool {
+	if len(viper.GetStringMap(conf.ErasureStoragesSetting)) > 0 {
+		return false
+	}
+	folderReaderImpl, ok := folderReader.(*FolderReaderImpl)
+	if !ok {
+		return false
+	}
+	_, isCached := folderReaderImpl.Folder.(*objcache.Folder)
+	return !isCached
+}
+
+func (reader readAheadFolderReader) SubFolder(subFolderRelativePath string) StorageFolderReader {
+	return readAheadFolderReader{reader.StorageFolderReader.SubFolder(subFolderRelativePath)}
+}
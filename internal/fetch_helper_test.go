@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/objcache"
+	"github.com/wal-g/wal-g/internal/walz"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+// readsRecordingFolderReader records the objects read
+type readsRecordingFolderReader struct {
+	StorageFolderReader
+	reads []string
+}
+
+func (reader *readsRecordingFolderReader) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	reader.reads = append(reader.reads, objectRelativePath)
+	return reader.StorageFolderReader.ReadObject(objectRelativePath)
+}
+
+func TestDownloadAndDecompressStorageFile_ProbesWalEnvelope(t *testing.T) {
+	cacheFilename := filepath.Join(t.TempDir(), ".walg_decompressor_cache")
+	defer func(get func() (string, error)) { getDecompressorCacheFilename = get }(getDecompressorCacheFilename)
+	getDecompressorCacheFilename = func() (string, error) { return cacheFilename, nil }
+	reader := &readsRecordingFolderReader{StorageFolderReader: NewFolderReader(memory.NewFolder("", memory.NewKVS()))}
+	probesEnvelope := func() bool {
+		reader.reads = nil
+		_, err := DownloadAndDecompressStorageFile(reader, "000000010000000000000001")
+		assert.IsType(t, ArchiveNonExistenceError{}, err)
+		for _, read := range reader.reads {
+			if strings.HasSuffix(read, "."+walz.FileExtension) {
+				return true
+			}
+		}
+		return false
+	}
+
+	assert.False(t, probesEnvelope(), "the envelope is neither enabled nor seen")
+
+	viper.Set(conf.PgWalEnvelopeSetting, true)
+	assert.True(t, probesEnvelope())
+	viper.Set(conf.PgWalEnvelopeSetting, false)
+
+	require.NoError(t, MarkWalEnvelopeSeen())
+	assert.True(t, probesEnvelope())
+}
+
+func TestReadsStorageObjects(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewKVS())
+	assert.True(t, readsStorageObjects(NewFolderReader(folder)))
+
+	cache, err := objcache.NewDiskCache(t.TempDir(), 1024)
+	require.NoError(t, err)
+	assert.False(t, readsStorageObjects(NewFolderReader(objcache.NewFolder(folder, cache))),
+		"the cached objects can't be closed while they are read")
+}
@@ -0,0 +1,255 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/limiters"
+	"github.com/wal-g/wal-g/internal/multistorage/consts"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const (
+	MirrorStorageName = "mirror"
+
+	peerCacheSourcePrefix          = "peer:"
+	peerCacheResponseHeaderTimeout = 30 * time.Second
+)
+
+// FetchSource is a storage to download backup files from when the storage that holds the backup
+// repeatedly fails to serve them.
+type FetchSource struct {
+	Name string
+	Root storage.Folder
+	// PeerCacheURL is set for the peer caches, which are read over HTTP instead of Root
+	PeerCacheURL string
+}
+
+// ConfigureAlternateFetchSources returns the failover storages, the peer caches (WALG_FETCH_PEER_CACHES),
+// the mirror storage (WALG_FETCH_MIRROR_PREFIX) and finally the default storage, in the order they should be tried.
+func ConfigureAlternateFetchSources() ([]FetchSource, error) {
+	var sources []FetchSource
+
+	failovers, err := ConfigureFailoverStorages()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(failovers))
+	for name := range failovers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sources = append(sources, FetchSource{Name: name, Root: failovers[name].RootFolder()})
+	}
+
+	for _, peerCacheURL := range strings.Split(viper.GetString(conf.FetchPeerCachesSetting), ",") {
+		peerCacheURL = strings.TrimSpace(peerCacheURL)
+		if peerCacheURL != "" {
+			sources = append(sources, FetchSource{Name: peerCacheSourcePrefix + peerCacheURL, PeerCacheURL: peerCacheURL})
+		}
+	}
+
+	mirrorPrefix := viper.GetString(conf.FetchMirrorPrefixSetting)
+	if mirrorPrefix != "" {
+		mirror, err := ConfigureStorageWithPrefix(mirrorPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("mirror storage: %v", err)
+		}
+		sources = append(sources, FetchSource{Name: MirrorStorageName, Root: mirror.RootFolder()})
+	}
+
+	defaultStorage, err := ConfigureStorage()
+	if err != nil {
+		return nil, err
+	}
+	sources = append(sources, FetchSource{Name: consts.DefaultStorage, Root: defaultStorage.RootFolder()})
+	return sources, nil
+}
+
+// ConfigureStorageWithPrefix configures the storage of the same type and with the same settings as the default one,
+// but with another prefix, e.g. the mirror or the replica of the default storage.
+func ConfigureStorageWithPrefix(prefix string) (storage.HashableStorage, error) {
+	config := viper.New()
+	for key, value := range viper.AllSettings() {
+		config.Set(key, value)
+	}
+	prefixIsSet := false
+	for _, adapter := range StorageAdapters {
+		if _, ok := conf.GetWaleCompatibleSettingFrom(adapter.PrefixSettingKey(), viper.GetViper()); ok {
+			config.Set("WALG_"+adapter.PrefixSettingKey(), prefix)
+			prefixIsSet = true
+			break
+		}
+	}
+	if !prefixIsSet {
+		return nil, fmt.Errorf("no storage is configured to take the settings for the prefix %s from", prefix)
+	}
+
+	var rootWraps []storage.WrapRootFolder
+	if limiters.NetworkLimiter != nil {
+		rootWraps = append(rootWraps, func(prevFolder storage.Folder) (newFolder storage.Folder) {
+			return NewLimitedFolder(prevFolder, limiters.NetworkLimiter)
+		})
+	}
+	rootWraps = append(rootWraps, ConfigureNameObfuscation, ConfigureStoragePrefix)
+	return ConfigureStorageForSpecificConfig(config, rootWraps...)
+}
+
+// alternateSourcesSwitcher replaces the readers of the files that failed to download too many times
+// with the readers that go to the alternate sources.
+type alternateSourcesSwitcher struct {
+	threshold   int
+	failures    map[ReaderMaker]int
+	sources     []FetchSource
+	sourcesOnce sync.Once
+}
+
+func newAlternateSourcesSwitcher() *alternateSourcesSwitcher {
+	return &alternateSourcesSwitcher{
+		threshold: viper.GetInt(conf.FetchAlternatesAfterSetting),
+		failures:  make(map[ReaderMaker]int),
+	}
+}
+
+func (s *alternateSourcesSwitcher) switchFailed(failed []ReaderMaker) []ReaderMaker {
+	if s.threshold <= 0 {
+		return failed
+	}
+	for i, readerMaker := range failed {
+		s.failures[readerMaker]++
+		storageReaderMaker, ok := readerMaker.(*StorageReaderMaker)
+		if !ok || s.failures[readerMaker] < s.threshold {
+			continue
+		}
+		sources := s.getSources()
+		if len(sources) == 0 {
+			continue
+		}
+		tracelog.WarningLogger.Printf("%s failed to download %d times, switching to alternate sources",
+			storageReaderMaker.StoragePath(), s.failures[readerMaker])
+		failed[i] = newAlternateSourceReaderMaker(storageReaderMaker, sources)
+	}
+	return failed
+}
+
+func (s *alternateSourcesSwitcher) getSources() []FetchSource {
+	s.sourcesOnce.Do(func() {
+		var err error
+		s.sources, err = ConfigureAlternateFetchSources()
+		if err != nil {
+			tracelog.WarningLogger.Printf("Failed to configure alternate sources: %v", err)
+		}
+	})
+	return s.sources
+}
+
+// alternateSourceReaderMaker reads the file from the alternate sources. Each call of Reader starts with
+// the source next to the one that served the previous (failed) attempt.
+type alternateSourceReaderMaker struct {
+	*StorageReaderMaker
+	sources      []FetchSource
+	relativePath string
+	next         int
+}
+
+func newAlternateSourceReaderMaker(readerMaker *StorageReaderMaker, sources []FetchSource) *alternateSourceReaderMaker {
+	return &alternateSourceReaderMaker{
+		StorageReaderMaker: readerMaker,
+		sources:            sources,
+		relativePath:       relativeFolderPath(readerMaker.Folder.GetPath(), sources),
+	}
+}
+
+func (readerMaker *alternateSourceReaderMaker) Reader() (io.ReadCloser, error) {
+	var lastErr error
+	for i := 0; i < len(readerMaker.sources); i++ {
+		source := readerMaker.sources[(readerMaker.next+i)%len(readerMaker.sources)]
+		readCloser, err := source.readObject(readerMaker.relativePath, readerMaker.storagePath)
+		if err != nil {
+			tracelog.WarningLogger.Printf("Failed to read %s from %s: %v", readerMaker.storagePath, source.Name, err)
+			lastErr = err
+			continue
+		}
+		readerMaker.next = (readerMaker.next + i + 1) % len(readerMaker.sources)
+		tracelog.InfoLogger.Printf("Reading %s from %s", readerMaker.storagePath, source.Name)
+		recordFetchSource(readerMaker.Folder, readerMaker.storagePath, source.Name)
+		return readerMaker.RestoreReport.CountDownload(readCloser), nil
+	}
+	return nil, lastErr
+}
+
+func (source FetchSource) readObject(relativePath, objectPath string) (io.ReadCloser, error) {
+	if source.PeerCacheURL != "" {
+		return readFromPeerCache(source.PeerCacheURL, path.Join(relativePath, objectPath))
+	}
+	return source.Root.GetSubFolder(relativePath).ReadObject(objectPath)
+}
+
+var peerCacheClient = newPeerCacheClient()
+
+func newPeerCacheClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = peerCacheResponseHeaderTimeout
+	return &http.Client{Transport: transport}
+}
+
+// readFromPeerCache downloads the object from the peer cache, which serves the objects as they are stored
+// (compressed and encrypted) by their paths relative to the storage root, e.g. a caching HTTP proxy
+// in front of the bucket on another host
+func readFromPeerCache(baseURL, objectPath string) (io.ReadCloser, error) {
+	objectURL := strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(objectPath, "/")
+	response, err := peerCacheClient.Get(objectURL)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusOK {
+		return response.Body, nil
+	}
+	_ = response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, storage.NewObjectNotFoundError(objectURL)
+	}
+	return nil, fmt.Errorf("peer cache responded %s to GET %s", response.Status, objectURL)
+}
+
+// relativeFolderPath strips the longest source root path from the folder path. Multi-storage folders
+// already have paths relative to the root, so they are returned as is.
+func relativeFolderPath(folderPath string, sources []FetchSource) string {
+	longestRoot := ""
+	for _, source := range sources {
+		if source.Root == nil {
+			continue
+		}
+		rootPath := source.Root.GetPath()
+		if strings.HasPrefix(folderPath, rootPath) && len(rootPath) > len(longestRoot) {
+			longestRoot = rootPath
+		}
+	}
+	return strings.TrimPrefix(folderPath, longestRoot)
+}
+
+var fetchSources sync.Map
+
+func recordFetchSource(folder storage.Folder, objectPath, sourceName string) {
+	fetchSources.Store(path.Join(folder.GetPath(), objectPath), sourceName)
+}
+
+// GetFetchSources returns the name of the storage that served each downloaded backup file
+func GetFetchSources() map[string]string {
+	sources := make(map[string]string)
+	fetchSources.Range(func(key, value interface{}) bool {
+		sources[key.(string)] = value.(string)
+		return true
+	})
+	return sources
+}
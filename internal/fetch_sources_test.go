@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+func TestAlternateSourceReaderMaker_Reader(t *testing.T) {
+	primary := memory.NewFolder("walg/", memory.NewKVS())
+	failover := memory.NewFolder("walg-failover/", memory.NewKVS())
+	mirror := memory.NewFolder("walg-mirror/", memory.NewKVS())
+	partPath := "basebackups_005/base_000000010000000000000002/tar_partitions"
+	require.NoError(t, mirror.GetSubFolder(partPath).PutObject("part_1.tar.lz4", strings.NewReader("part")))
+
+	sources := []FetchSource{{Name: "failover", Root: failover}, {Name: "mirror", Root: mirror}, {Name: "default", Root: primary}}
+	readerMaker := newAlternateSourceReaderMaker(NewStorageReaderMaker(primary.GetSubFolder(partPath), "part_1.tar.lz4"), sources)
+	assert.Equal(t, partPath+"/", readerMaker.relativePath)
+
+	reader, err := readerMaker.Reader()
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "part", string(content))
+	assert.Equal(t, "mirror", GetFetchSources()["walg/"+partPath+"/part_1.tar.lz4"])
+}
+
+func TestAlternateSourcesSwitcher_SwitchFailed(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewKVS())
+	switcher := &alternateSourcesSwitcher{
+		threshold: 2,
+		failures:  make(map[ReaderMaker]int),
+		sources:   []FetchSource{{Name: "mirror", Root: folder}},
+	}
+	switcher.sourcesOnce.Do(func() {})
+
+	failed := []ReaderMaker{NewStorageReaderMaker(folder, "part_1.tar.lz4")}
+	failed = switcher.switchFailed(failed)
+	assert.IsType(t, &StorageReaderMaker{}, failed[0])
+
+	failed = switcher.switchFailed(failed)
+	assert.IsType(t, &alternateSourceReaderMaker{}, failed[0])
+	assert.Equal(t, "part_1.tar.lz4", failed[0].StoragePath())
+}
+
+func TestAlternateSourceReaderMaker_ReaderFromPeerCache(t *testing.T) {
+	primary := memory.NewFolder("walg/", memory.NewKVS())
+	partPath := "basebackups_005/base_000000010000000000000002/tar_partitions"
+	peerCache := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cache/"+partPath+"/part_1.tar.lz4" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("part"))
+	}))
+	defer peerCache.Close()
+	missingPeerCache := httptest.NewServer(http.NotFoundHandler())
+	defer missingPeerCache.Close()
+
+	reporter := NewRestoreReporter("base_000000010000000000000002")
+	storageReaderMaker := NewStorageReaderMaker(primary.GetSubFolder(partPath), "part_1.tar.lz4")
+	storageReaderMaker.RestoreReport = reporter
+	sources := []FetchSource{
+		{Name: "peer:missing", PeerCacheURL: missingPeerCache.URL},
+		{Name: "peer:cache", PeerCacheURL: peerCache.URL + "/cache/"},
+		{Name: "default", Root: primary},
+	}
+	readerMaker := newAlternateSourceReaderMaker(storageReaderMaker, sources)
+
+	reader, err := readerMaker.Reader()
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "part", string(content))
+	assert.Equal(t, "peer:cache", GetFetchSources()["walg/"+partPath+"/part_1.tar.lz4"])
+	// the downloads from the alternate sources are counted in the restore report
+	assert.Equal(t, int64(1), reporter.objects)
+	assert.Equal(t, int64(len("part")), reporter.bytes)
+}
@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/internal/limiters"
+	"github.com/wal-g/wal-g/utility"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// FileRangeOffsetPAXKey and FileRangeFileSizePAXKey mark the tar entry holding the range of the file
+	// instead of the whole file
+	FileRangeOffsetPAXKey   = "WALG.range.offset"
+	FileRangeFileSizePAXKey = "WALG.range.filesize"
+
+	// the ranges are aligned to the whole megabytes, so they never split the database pages
+	fileRangeAlignment = 1 << 20
+)
+
+// FileRange is the byte range of the large file which is packed into its own tar entry,
+// so the ranges of the file are uploaded concurrently
+type FileRange struct {
+	Offset int64
+	Size   int64
+}
+
+// GetFileRangeSize returns the size of the ranges the large files are split into (WALG_FILE_RANGE_SIZE),
+// zero means the files are never split
+func GetFileRangeSize() int64 {
+	rangeSize := viper.GetInt64(conf.FileRangeSizeSetting)
+	if rangeSize <= 0 {
+		return 0
+	}
+	return (rangeSize + fileRangeAlignment - 1) / fileRangeAlignment * fileRangeAlignment
+}
+
+// SplitIntoRanges returns the ranges of the file, or nil if the file is not larger than the range size
+func SplitIntoRanges(fileSize, rangeSize int64) []FileRange {
+	if rangeSize <= 0 || fileSize <= rangeSize {
+		return nil
+	}
+	ranges := make([]FileRange, 0, (fileSize+rangeSize-1)/rangeSize)
+	for offset := int64(0); offset < fileSize; offset += rangeSize {
+		size := rangeSize
+		if offset+size > fileSize {
+			size = fileSize - offset
+		}
+		ranges = append(ranges, FileRange{Offset: offset, Size: size})
+	}
+	return ranges
+}
+
+// NewFileRangeHeader makes the header of the tar entry holding the range of the file
+func NewFileRangeHeader(header *tar.Header, fileRange FileRange, fileSize int64) *tar.Header {
+	rangeHeader := *header
+	rangeHeader.Size = fileRange.Size
+	rangeHeader.Format = tar.FormatPAX
+	rangeHeader.PAXRecords = make(map[string]string, len(header.PAXRecords)+2)
+	for key, value := range header.PAXRecords {
+		rangeHeader.PAXRecords[key] = value
+	}
+	rangeHeader.PAXRecords[FileRangeOffsetPAXKey] = strconv.FormatInt(fileRange.Offset, 10)
+	rangeHeader.PAXRecords[FileRangeFileSizePAXKey] = strconv.FormatInt(fileSize, 10)
+	return &rangeHeader
+}
+
+// GetFileRange tells if the tar entry holds the range of the file and returns the range and the size of the whole file
+func GetFileRange(header *tar.Header) (fileRange FileRange, fileSize int64, isRange bool, err error) {
+	offsetStr, ok := header.PAXRecords[FileRangeOffsetPAXKey]
+	if !ok {
+		return FileRange{}, 0, false, nil
+	}
+	offset, err := strconv.ParseInt(offsetStr, 10, 64)
+	if err != nil {
+		return FileRange{}, 0, true, errors.Wrapf(err, "invalid range offset of %s", header.Name)
+	}
+	fileSize, err = strconv.ParseInt(header.PAXRecords[FileRangeFileSizePAXKey], 10, 64)
+	if err != nil {
+		return FileRange{}, 0, true, errors.Wrapf(err, "invalid range file size of %s", header.Name)
+	}
+	if offset < 0 || offset+header.Size > fileSize {
+		return FileRange{}, 0, true, errors.Errorf("range %d+%d is out of the file %s of %d bytes",
+			offset, header.Size, header.Name, fileSize)
+	}
+	return FileRange{Offset: offset, Size: header.Size}, fileSize, true, nil
+}
+
+// AddFileRanges packs the ranges of the file into the tarballs of the queue concurrently
+// and records the ranges in the file description
+func AddFileRanges(ctx context.Context, errorGroup *errgroup.Group, tarBallQueue *TarBallQueue, crypter crypto.Crypter,
+	files BundleFiles, tarFileSets TarFileSets, info *ComposeFileInfo, ranges []FileRange) {
+	fileSize := info.FileInfo.Size()
+	tracelog.DebugLogger.Printf("Splitting %s of %d bytes into %d ranges", info.Path, fileSize, len(ranges))
+	for _, fileRange := range ranges {
+		tarBall, err := tarBallQueue.DequeCtx(ctx)
+		if err != nil {
+			return
+		}
+		tarBall.SetUp(crypter)
+		rangeHeader := NewFileRangeHeader(info.Header, fileRange, fileSize)
+		tarFileSets.AddFile(tarBall.Name(), rangeHeader.Name)
+		fileRange := fileRange
+		errorGroup.Go(func() error {
+			err := packFileRange(tarBall, rangeHeader, info.Path, fileRange)
+			if _, ok := err.(FileNotExistError); ok {
+				// the file was deleted during the backup, the database recovery does not need it
+				tracelog.WarningLogger.Println(err)
+			} else if err != nil {
+				return err
+			}
+			return tarBallQueue.CheckSizeAndEnqueueBack(tarBall)
+		})
+	}
+
+	fileDescription := BackupFileDescription{MTime: info.FileInfo.ModTime(), Ranges: ranges}
+	fileDescription.SetSizes(info.Header, info.FileInfo)
+	files.AddFileDescription(info.Header.Name, fileDescription)
+}
+
+func packFileRange(tarBall TarBall, header *tar.Header, path string, fileRange FileRange) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return NewFileNotExistError(path)
+		}
+		return errors.Wrapf(err, "packFileRange: failed to open file '%s'\n", path)
+	}
+	defer utility.LoggedClose(file, "")
+
+	// the file may be truncated during the backup, the missing part of the range is padded with zeros
+	rangeReader := &io.LimitedReader{
+		R: io.MultiReader(limiters.NewDiskLimitReader(io.NewSectionReader(file, fileRange.Offset, fileRange.Size)),
+			&ioextensions.ZeroReader{}),
+		N: fileRange.Size,
+	}
+	packedSize, err := PackFileTo(tarBall, header, rangeReader)
+	if err != nil {
+		return errors.Wrapf(err, "packFileRange: failed to pack range of '%s'", path)
+	}
+	if packedSize != fileRange.Size {
+		return newTarSizeError(packedSize, fileRange.Size)
+	}
+	return nil
+}
+
+// WriteFileRange writes the range of the file restored from the tar entry at its offset. The ranges of the file
+// are restored concurrently, so the file is created without truncation and is only resized to its full size.
+func WriteFileRange(reader io.Reader, header *tar.Header, targetPath string, fsync bool) error {
+	fileRange, fileSize, _, err := GetFileRange(header)
+	if err != nil {
+		return err
+	}
+	err = utility.CreateParentDirs(header.Name, targetPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to create all directories")
+	}
+	file, err := os.OpenFile(targetPath, os.O_WRONLY|os.O_CREATE, os.FileMode(header.Mode))
+	if err != nil {
+		return errors.Wrapf(err, "failed to open file '%s'", targetPath)
+	}
+	defer utility.LoggedClose(file, "")
+
+	err = file.Truncate(fileSize)
+	if err != nil {
+		return errors.Wrapf(err, "failed to resize file '%s'", targetPath)
+	}
+	if err = file.Chmod(os.FileMode(header.Mode)); err != nil {
+		return errors.Wrap(err, "chmod failed")
+	}
+	written, err := io.Copy(io.NewOffsetWriter(file, fileRange.Offset), reader)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write range of '%s'", targetPath)
+	}
+	if written != fileRange.Size {
+		return newTarSizeError(written, fileRange.Size)
+	}
+	if fsync {
+		return errors.Wrap(file.Sync(), "fsync failed")
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package internal_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+)
+
+func TestSplitIntoRanges(t *testing.T) {
+	assert.Nil(t, internal.SplitIntoRanges(100, 0))
+	assert.Nil(t, internal.SplitIntoRanges(100, 100))
+	assert.Equal(t, []internal.FileRange{
+		{Offset: 0, Size: 40},
+		{Offset: 40, Size: 40},
+		{Offset: 80, Size: 20},
+	}, internal.SplitIntoRanges(100, 40))
+}
+
+func TestFileRangeHeader(t *testing.T) {
+	header := &tar.Header{Name: "base/5/16384", Size: 100, Mode: 0600, Typeflag: tar.TypeReg}
+
+	_, _, isRange, err := internal.GetFileRange(header)
+	assert.NoError(t, err)
+	assert.False(t, isRange)
+
+	rangeHeader := internal.NewFileRangeHeader(header, internal.FileRange{Offset: 40, Size: 40}, 100)
+	assert.Equal(t, int64(100), header.Size)
+	assert.Nil(t, header.PAXRecords)
+
+	// the PAX records survive the round trip through the tar
+	var buffer bytes.Buffer
+	writer := tar.NewWriter(&buffer)
+	require.NoError(t, writer.WriteHeader(rangeHeader))
+	_, err = writer.Write(make([]byte, 40))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	readHeader, err := tar.NewReader(&buffer).Next()
+	require.NoError(t, err)
+
+	fileRange, fileSize, isRange, err := internal.GetFileRange(readHeader)
+	assert.NoError(t, err)
+	assert.True(t, isRange)
+	assert.Equal(t, internal.FileRange{Offset: 40, Size: 40}, fileRange)
+	assert.Equal(t, int64(100), fileSize)
+}
+
+func TestGetFileRange_OutOfFile(t *testing.T) {
+	header := internal.NewFileRangeHeader(&tar.Header{Name: "file"}, internal.FileRange{Offset: 80, Size: 40}, 100)
+	_, _, isRange, err := internal.GetFileRange(header)
+	assert.True(t, isRange)
+	assert.Error(t, err)
+}
+
+func TestWriteFileRange(t *testing.T) {
+	content := make([]byte, 100)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	targetPath := filepath.Join(t.TempDir(), "data", "file")
+	header := &tar.Header{Name: "data/file", Size: int64(len(content)), Mode: 0600, Typeflag: tar.TypeReg}
+
+	// the ranges are restored in any order
+	ranges := internal.SplitIntoRanges(int64(len(content)), 40)
+	for i := len(ranges) - 1; i >= 0; i-- {
+		fileRange := ranges[i]
+		rangeHeader := internal.NewFileRangeHeader(header, fileRange, int64(len(content)))
+		reader := bytes.NewReader(content[fileRange.Offset : fileRange.Offset+fileRange.Size])
+		require.NoError(t, internal.WriteFileRange(reader, rangeHeader, targetPath, false))
+	}
+
+	restored, err := os.ReadFile(targetPath)
+	require.NoError(t, err)
+	assert.Equal(t, content, restored)
+}
@@ -52,6 +52,9 @@ func (tarInterpreter *FileTarInterpreter) Interpret(reader io.Reader, fileInfo *
 }
 
 func (tarInterpreter *FileTarInterpreter) interpretRegularFile(targetPath string, header *tar.Header, reader io.Reader) error {
+	if _, _, isRange, _ := GetFileRange(header); isRange {
+		return WriteFileRange(reader, header, targetPath, tarInterpreter.fsync)
+	}
 	localFile, _, err := utility.GetLocalFile(targetPath, header)
 	if err != nil {
 		return err
@@ -0,0 +1,100 @@
+package fleet
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest lists the clusters backed up by a single `wal-g fleet run`
+type Manifest struct {
+	// Parallelism is the number of clusters processed at the same time
+	Parallelism int `yaml:"parallelism"`
+	// Settings are the WAL-G settings shared by all the clusters, e.g. the storage credentials
+	Settings map[string]string `yaml:"settings"`
+	Clusters []Cluster         `yaml:"clusters"`
+}
+
+// Cluster describes how to back up the cluster and how long to keep its backups
+type Cluster struct {
+	Name string `yaml:"name"`
+	// Connection is the connection info, e.g. PGHOST and PGPORT
+	Connection map[string]string `yaml:"connection"`
+	// StoragePrefix is the WALG_STORAGE_PREFIX of the cluster, its name by default
+	StoragePrefix string `yaml:"storage_prefix"`
+	// DataDirectory is passed to backup-push, the backup is taken via the replication protocol if it is empty
+	DataDirectory string `yaml:"data_directory"`
+	// Schedule is the minimal interval between the backups, the backup is taken on every run if it is empty
+	Schedule string `yaml:"schedule"`
+	// Retention are the arguments of `delete retain`, e.g. "FULL 7", the backups aren't deleted if it is empty
+	Retention string `yaml:"retention"`
+	// Settings override the shared ones for this cluster
+	Settings map[string]string `yaml:"settings"`
+
+	interval time.Duration
+}
+
+// LoadManifest reads and validates the manifest file
+func LoadManifest(path string) (*Manifest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the fleet manifest")
+	}
+	return ParseManifest(content)
+}
+
+func ParseManifest(content []byte) (*Manifest, error) {
+	manifest := &Manifest{}
+	if err := yaml.Unmarshal(content, manifest); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the fleet manifest")
+	}
+	if manifest.Parallelism == 0 {
+		manifest.Parallelism = 1
+	}
+	if manifest.Parallelism < 0 {
+		return nil, fmt.Errorf("invalid parallelism %d in the fleet manifest", manifest.Parallelism)
+	}
+	if len(manifest.Clusters) == 0 {
+		return nil, errors.New("no clusters in the fleet manifest")
+	}
+
+	names := make(map[string]bool, len(manifest.Clusters))
+	for i := range manifest.Clusters {
+		cluster := &manifest.Clusters[i]
+		if cluster.Name == "" {
+			return nil, fmt.Errorf("cluster #%d in the fleet manifest has no name", i+1)
+		}
+		if names[cluster.Name] {
+			return nil, fmt.Errorf("cluster '%s' is listed twice in the fleet manifest", cluster.Name)
+		}
+		names[cluster.Name] = true
+		if cluster.StoragePrefix == "" {
+			cluster.StoragePrefix = cluster.Name
+		}
+		if cluster.Schedule != "" {
+			interval, err := time.ParseDuration(cluster.Schedule)
+			if err != nil || interval <= 0 {
+				return nil, fmt.Errorf("invalid schedule '%s' of cluster '%s': expected the interval like '24h'",
+					cluster.Schedule, cluster.Name)
+			}
+			cluster.interval = interval
+		}
+	}
+	return manifest, nil
+}
+
+// environment provides the settings of the cluster as the environment variables for WAL-G:
+// the shared settings are overridden by the cluster ones
+func (cluster *Cluster) environment(sharedSettings map[string]string) []string {
+	env := make([]string, 0, len(sharedSettings)+len(cluster.Connection)+len(cluster.Settings)+1)
+	for _, settings := range []map[string]string{sharedSettings, cluster.Connection, cluster.Settings} {
+		for name, value := range settings {
+			env = append(env, name+"="+value)
+		}
+	}
+	return append(env, conf.StoragePrefixSetting+"="+cluster.StoragePrefix)
+}
@@ -0,0 +1,51 @@
+package fleet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseManifest(t *testing.T) {
+	manifest, err := ParseManifest([]byte(`
+settings:
+  WALG_S3_PREFIX: s3://backups
+clusters:
+  - name: orders
+    connection:
+      PGHOST: orders-db
+    schedule: 24h
+    retention: FULL 7
+  - name: billing
+    storage_prefix: finance/billing
+    settings:
+      WALG_S3_PREFIX: s3://finance-backups
+`))
+	require.NoError(t, err)
+	assert.Equal(t, 1, manifest.Parallelism)
+	require.Len(t, manifest.Clusters, 2)
+	assert.Equal(t, "orders", manifest.Clusters[0].StoragePrefix)
+	assert.Equal(t, 24*time.Hour, manifest.Clusters[0].interval)
+	assert.ElementsMatch(t, []string{
+		"WALG_S3_PREFIX=s3://backups", "PGHOST=orders-db", "WALG_STORAGE_PREFIX=orders",
+	}, manifest.Clusters[0].environment(manifest.Settings))
+
+	env := manifest.Clusters[1].environment(manifest.Settings)
+	assert.Equal(t, "WALG_S3_PREFIX=s3://finance-backups", env[1], "the cluster settings override the shared ones")
+	assert.Equal(t, "WALG_STORAGE_PREFIX=finance/billing", env[2])
+}
+
+func TestParseManifest_Invalid(t *testing.T) {
+	for _, manifest := range []string{
+		`clusters: []`,
+		`clusters: [{name: orders}, {name: orders}]`,
+		`clusters: [{storage_prefix: orders}]`,
+		`clusters: [{name: orders, schedule: daily}]`,
+		`{parallelism: -1, clusters: [{name: orders}]}`,
+	} {
+		_, err := ParseManifest([]byte(manifest))
+		assert.Error(t, err, manifest)
+	}
+}
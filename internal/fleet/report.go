@@ -0,0 +1,26 @@
+package fleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// PrintReport prints the consolidated report of the fleet run
+func PrintReport(report Report, output io.Writer, isJSON bool) error {
+	if isJSON {
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "    ")
+		return encoder.Encode(report)
+	}
+
+	writer := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "Cluster\tBackup\tRetention\tDuration\tError")
+	for _, cluster := range report.Clusters {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n",
+			cluster.Name, cluster.Backup, cluster.Retention, cluster.Duration, cluster.Error)
+	}
+	fmt.Fprintf(writer, "\n%d clusters, %d failed\n", len(report.Clusters), report.Failed)
+	return writer.Flush()
+}
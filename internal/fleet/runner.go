@@ -0,0 +1,223 @@
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+const (
+	StatusDone    = "done"
+	StatusSkipped = "skipped"
+	StatusFailed  = "failed"
+)
+
+// ClusterReport is the result of the fleet run for a single cluster
+type ClusterReport struct {
+	Name           string     `json:"name"`
+	Backup         string     `json:"backup"`
+	Retention      string     `json:"retention"`
+	LastBackupTime *time.Time `json:"last_backup_time,omitempty"`
+	Duration       string     `json:"duration"`
+	Error          string     `json:"error,omitempty"`
+}
+
+// Report is the consolidated result of the fleet run
+type Report struct {
+	StartTime time.Time       `json:"start_time"`
+	Clusters  []ClusterReport `json:"clusters"`
+	Failed    int             `json:"failed"`
+}
+
+// Runner runs WAL-G with the settings of each cluster of the manifest: it takes the backups
+// of the clusters due by the schedule and then deletes the backups beyond the retention
+type Runner struct {
+	manifest   *Manifest
+	executable string
+	// commonArgs are passed to each WAL-G run, e.g. --config
+	commonArgs []string
+	// force makes the backups of all the clusters regardless of the schedule
+	force     bool
+	logOutput io.Writer
+	logMutex  sync.Mutex
+}
+
+func NewRunner(manifest *Manifest, executable string, commonArgs []string, force bool) *Runner {
+	return &Runner{
+		manifest:   manifest,
+		executable: executable,
+		commonArgs: commonArgs,
+		force:      force,
+		logOutput:  os.Stderr,
+	}
+}
+
+// Run processes the clusters, at most parallelism of them at the same time (the manifest one if it is 0)
+func (runner *Runner) Run(ctx context.Context, parallelism int) Report {
+	if parallelism <= 0 {
+		parallelism = runner.manifest.Parallelism
+	}
+	report := Report{StartTime: time.Now(), Clusters: make([]ClusterReport, len(runner.manifest.Clusters))}
+	semaphore := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i := range runner.manifest.Clusters {
+		semaphore <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			report.Clusters[i] = runner.runCluster(ctx, &runner.manifest.Clusters[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for _, cluster := range report.Clusters {
+		if cluster.Backup == StatusFailed || cluster.Retention == StatusFailed {
+			report.Failed++
+		}
+	}
+	return report
+}
+
+func (runner *Runner) runCluster(ctx context.Context, cluster *Cluster) ClusterReport {
+	startTime := time.Now()
+	report := ClusterReport{Name: cluster.Name, Backup: StatusSkipped, Retention: StatusSkipped}
+	tracelog.InfoLogger.Printf("Processing cluster %s", cluster.Name)
+
+	err := runner.backUp(ctx, cluster, &report)
+	if err == nil && cluster.Retention != "" {
+		args := append([]string{"delete", "retain"}, strings.Fields(cluster.Retention)...)
+		if _, err = runner.runWalg(ctx, cluster, append(args, "--confirm")...); err != nil {
+			report.Retention = StatusFailed
+		} else {
+			report.Retention = StatusDone
+		}
+	}
+	if err != nil {
+		report.Error = err.Error()
+		tracelog.ErrorLogger.Printf("Cluster %s failed: %v", cluster.Name, err)
+	}
+	report.Duration = time.Since(startTime).Round(time.Second).String()
+	return report
+}
+
+func (runner *Runner) backUp(ctx context.Context, cluster *Cluster, report *ClusterReport) error {
+	if !runner.force && cluster.interval > 0 {
+		lastBackupTime, err := runner.getLastBackupTime(ctx, cluster)
+		if err != nil {
+			report.Backup = StatusFailed
+			return err
+		}
+		report.LastBackupTime = lastBackupTime
+		if lastBackupTime != nil && time.Since(*lastBackupTime) < cluster.interval {
+			tracelog.InfoLogger.Printf("Cluster %s was backed up at %s, the backup isn't due yet",
+				cluster.Name, lastBackupTime.Format(time.RFC3339))
+			return nil
+		}
+	}
+
+	args := []string{"backup-push"}
+	if cluster.DataDirectory != "" {
+		args = append(args, cluster.DataDirectory)
+	}
+	if _, err := runner.runWalg(ctx, cluster, args...); err != nil {
+		report.Backup = StatusFailed
+		return err
+	}
+	report.Backup = StatusDone
+	return nil
+}
+
+func (runner *Runner) getLastBackupTime(ctx context.Context, cluster *Cluster) (*time.Time, error) {
+	output, err := runner.runWalg(ctx, cluster, "backup-list", "--json")
+	if err != nil {
+		return nil, err
+	}
+	// backup-list prints nothing if there are no backups
+	if len(bytes.TrimSpace(output)) == 0 {
+		return nil, nil
+	}
+	var backups []struct {
+		Time time.Time `json:"time"`
+	}
+	if err = json.Unmarshal(output, &backups); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the backup list")
+	}
+	var lastBackupTime *time.Time
+	for i := range backups {
+		if lastBackupTime == nil || backups[i].Time.After(*lastBackupTime) {
+			lastBackupTime = &backups[i].Time
+		}
+	}
+	return lastBackupTime, nil
+}
+
+// runWalg runs WAL-G with the cluster settings and provides its output. The logs are passed through,
+// prefixed with the cluster name, so the logs of the clusters processed at the same time can be told apart.
+func (runner *Runner) runWalg(ctx context.Context, cluster *Cluster, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, runner.executable, append(args, runner.commonArgs...)...)
+	cmd.Env = append(os.Environ(), cluster.environment(runner.manifest.Settings)...)
+	var stdout, stderr bytes.Buffer
+	logWriter := &prefixWriter{runner: runner, prefix: "[" + cluster.Name + "] "}
+	cmd.Stdout = &stdout
+	cmd.Stderr = io.MultiWriter(&stderr, logWriter)
+
+	err := cmd.Run()
+	logWriter.flush()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", args[0], err, lastLine(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return lines[len(lines)-1]
+}
+
+type prefixWriter struct {
+	runner  *Runner
+	prefix  string
+	pending []byte
+}
+
+func (writer *prefixWriter) Write(p []byte) (int, error) {
+	writer.pending = append(writer.pending, p...)
+	if end := bytes.LastIndexByte(writer.pending, '\n'); end >= 0 {
+		writer.writeLines(writer.pending[:end+1])
+		writer.pending = writer.pending[end+1:]
+	}
+	return len(p), nil
+}
+
+func (writer *prefixWriter) flush() {
+	if len(writer.pending) > 0 {
+		writer.writeLines(append(writer.pending, '\n'))
+		writer.pending = nil
+	}
+}
+
+func (writer *prefixWriter) writeLines(lines []byte) {
+	var prefixed bytes.Buffer
+	for _, line := range bytes.SplitAfter(lines, []byte{'\n'}) {
+		if len(line) > 0 {
+			prefixed.WriteString(writer.prefix)
+			prefixed.Write(line)
+		}
+	}
+	writer.runner.logMutex.Lock()
+	defer writer.runner.logMutex.Unlock()
+	_, _ = writer.runner.logOutput.Write(prefixed.Bytes())
+}
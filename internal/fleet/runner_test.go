@@ -0,0 +1,71 @@
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testWalg records its runs and pretends to back up the clusters
+const testWalg = `#!/bin/sh
+echo "$WALG_STORAGE_PREFIX $*" >> "$TEST_CALLS"
+case "$1" in
+backup-list)
+  if [ -n "$TEST_LAST_BACKUP" ]; then echo "[{\"backup_name\":\"base_1\",\"time\":\"$TEST_LAST_BACKUP\"}]"; fi ;;
+backup-push)
+  if [ "$PGHOST" = "unreachable" ]; then echo "connection refused" >&2; exit 1; fi ;;
+esac
+`
+
+func TestRunner_Run(t *testing.T) {
+	dir := t.TempDir()
+	executable := filepath.Join(dir, "wal-g")
+	require.NoError(t, os.WriteFile(executable, []byte(testWalg), 0755))
+	callsPath := filepath.Join(dir, "calls")
+
+	manifest := &Manifest{
+		Parallelism: 2,
+		Settings:    map[string]string{"TEST_CALLS": callsPath},
+		Clusters: []Cluster{
+			{Name: "fresh", StoragePrefix: "fresh", interval: 24 * time.Hour, Retention: "FULL 7",
+				Settings: map[string]string{"TEST_LAST_BACKUP": time.Now().Add(-time.Hour).Format(time.RFC3339)}},
+			{Name: "stale", StoragePrefix: "stale", interval: 24 * time.Hour, DataDirectory: "/var/lib/pgdata",
+				Settings: map[string]string{"TEST_LAST_BACKUP": time.Now().Add(-48 * time.Hour).Format(time.RFC3339)}},
+			{Name: "unreachable", StoragePrefix: "unreachable", Retention: "FULL 7",
+				Connection: map[string]string{"PGHOST": "unreachable"}},
+		},
+	}
+	runner := NewRunner(manifest, executable, []string{"--config", "/etc/wal-g.yaml"}, false)
+	var logs bytes.Buffer
+	runner.logOutput = &logs
+
+	report := runner.Run(context.Background(), 0)
+	assert.Equal(t, 1, report.Failed)
+	require.Len(t, report.Clusters, 3)
+	assert.Equal(t, StatusSkipped, report.Clusters[0].Backup)
+	assert.Equal(t, StatusDone, report.Clusters[0].Retention)
+	assert.NotNil(t, report.Clusters[0].LastBackupTime)
+	assert.Equal(t, StatusDone, report.Clusters[1].Backup)
+	assert.Equal(t, StatusSkipped, report.Clusters[1].Retention)
+	assert.Equal(t, StatusFailed, report.Clusters[2].Backup)
+	assert.Equal(t, StatusSkipped, report.Clusters[2].Retention, "the backups aren't deleted after the failed backup")
+	assert.Contains(t, report.Clusters[2].Error, "connection refused")
+	assert.Contains(t, logs.String(), "[unreachable] connection refused\n")
+
+	calls, err := os.ReadFile(callsPath)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"fresh backup-list --json --config /etc/wal-g.yaml",
+		"fresh delete retain FULL 7 --confirm --config /etc/wal-g.yaml",
+		"stale backup-list --json --config /etc/wal-g.yaml",
+		"stale backup-push /var/lib/pgdata --config /etc/wal-g.yaml",
+		"unreachable backup-push --config /etc/wal-g.yaml",
+	}, strings.Split(strings.TrimSpace(string(calls)), "\n"))
+}
@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+)
+
+const (
+	YesIKnowFlag        = "yes-i-know"
+	YesIKnowDescription = "Confirms the dangerous operation with the cluster name set in " + conf.ClusterNameSetting
+
+	defaultGuardrailMaxBackups = 3
+)
+
+type GuardrailError struct {
+	error
+}
+
+func newGuardrailError(operation, clusterName string) GuardrailError {
+	return GuardrailError{errors.Errorf("%s requires the confirmation: pass --%s %s or type the cluster name",
+		operation, YesIKnowFlag, clusterName)}
+}
+
+func (err GuardrailError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// GuardrailEnabled reports whether the guardrail is configured. It is off unless WALG_CLUSTER_NAME
+// or WALG_GUARDRAIL_MAX_BACKUPS is set, so the existing scripts running the deletions keep working.
+func GuardrailEnabled() bool {
+	return viper.GetString(conf.ClusterNameSetting) != "" || viper.IsSet(conf.GuardrailMaxBackupsSetting)
+}
+
+// CheckGuardrail requires naming the cluster before the dangerous operation, so it isn't run against
+// the wrong cluster from the wrong terminal. The name is passed with --yes-i-know or typed interactively.
+// The operation is refused if the guardrail is enabled by WALG_GUARDRAIL_MAX_BACKUPS alone,
+// since there is no cluster name to confirm it with.
+func CheckGuardrail(operation, yesIKnow string) error {
+	if !GuardrailEnabled() {
+		return nil
+	}
+	clusterName := viper.GetString(conf.ClusterNameSetting)
+	if clusterName == "" {
+		return GuardrailError{errors.Errorf("%s requires the confirmation with the cluster name, but %s is not set",
+			operation, conf.ClusterNameSetting)}
+	}
+	if yesIKnow == "" && isInteractiveInput() {
+		return confirmGuardrail(operation, clusterName, os.Stdin, os.Stderr)
+	}
+	if yesIKnow != clusterName {
+		return newGuardrailError(operation, clusterName)
+	}
+	return nil
+}
+
+// SetGuardrailConfirmation sets the cluster name passed with --yes-i-know to confirm the dangerous deletions
+func (h *DeleteHandler) SetGuardrailConfirmation(yesIKnow string) {
+	h.guardrailConfirmation = yesIKnow
+}
+
+// CheckGuardrail applies the guardrail to the operation run by the handler. Once the cluster name is confirmed,
+// the handler doesn't ask for it again.
+func (h *DeleteHandler) CheckGuardrail(operation string) error {
+	if h.guardrailConfirmed {
+		return nil
+	}
+	err := CheckGuardrail(operation, h.guardrailConfirmation)
+	if err != nil {
+		return err
+	}
+	h.guardrailConfirmed = true
+	return nil
+}
+
+// CheckWideDeletionGuardrail applies the guardrail to the deletion of the backups older than the target
+// if there are more than WALG_GUARDRAIL_MAX_BACKUPS (3 by default) of them
+func (h *DeleteHandler) CheckWideDeletionGuardrail(target BackupObject) error {
+	if !GuardrailEnabled() {
+		return nil
+	}
+	deletedCount := 0
+	for _, backup := range h.backups {
+		if backup.GetBackupName() != target.GetBackupName() && h.less(backup, target) && !h.isPermanent(backup) {
+			deletedCount++
+		}
+	}
+	if !isWideDeletion(deletedCount) {
+		return nil
+	}
+	return h.CheckGuardrail(fmt.Sprintf("delete before %s removing %d backups", target.GetBackupName(), deletedCount))
+}
+
+// CheckWideDeletionGuardrail is the guardrail of the deletions that don't go through the DeleteHandler,
+// e.g. the purge of MongoDB and Redis backups
+func CheckWideDeletionGuardrail(operation string, deletedCount int, yesIKnow string) error {
+	if !GuardrailEnabled() || !isWideDeletion(deletedCount) {
+		return nil
+	}
+	return CheckGuardrail(fmt.Sprintf("%s removing %d backups", operation, deletedCount), yesIKnow)
+}
+
+// isWideDeletion reports whether the deletion removes more than WALG_GUARDRAIL_MAX_BACKUPS (3 by default) backups
+func isWideDeletion(deletedCount int) bool {
+	maxBackups := defaultGuardrailMaxBackups
+	if viper.IsSet(conf.GuardrailMaxBackupsSetting) {
+		maxBackups = viper.GetInt(conf.GuardrailMaxBackupsSetting)
+	}
+	return deletedCount > maxBackups
+}
+
+func confirmGuardrail(operation, clusterName string, input io.Reader, output io.Writer) error {
+	fmt.Fprintf(output, "%s is about to run on the cluster %s. Type the cluster name to confirm: ", operation, clusterName)
+	answer, err := bufio.NewReader(input).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if strings.TrimSpace(answer) != clusterName {
+		return newGuardrailError(operation, clusterName)
+	}
+	return nil
+}
+
+func isInteractiveInput() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
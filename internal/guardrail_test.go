@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	conf "github.com/wal-g/wal-g/internal/config"
+)
+
+func TestConfirmGuardrail(t *testing.T) {
+	output := &bytes.Buffer{}
+	assert.NoError(t, confirmGuardrail("delete everything", "billing-prod", strings.NewReader("billing-prod\n"), output))
+	assert.Contains(t, output.String(), "billing-prod")
+
+	err := confirmGuardrail("delete everything", "billing-prod", strings.NewReader("billing-test\n"), &bytes.Buffer{})
+	assert.IsType(t, GuardrailError{}, err)
+
+	err = confirmGuardrail("delete everything", "billing-prod", strings.NewReader(""), &bytes.Buffer{})
+	assert.IsType(t, GuardrailError{}, err)
+}
+
+func TestCheckGuardrail(t *testing.T) {
+	defer viper.Set(conf.ClusterNameSetting, nil)
+	defer viper.Set(conf.GuardrailMaxBackupsSetting, nil)
+
+	// the guardrail isn't configured
+	viper.Set(conf.ClusterNameSetting, "")
+	assert.NoError(t, CheckGuardrail("delete everything", ""))
+
+	// the guardrail is enabled, but there is nothing to confirm the operation with, so it's refused
+	viper.Set(conf.GuardrailMaxBackupsSetting, 1)
+	assert.IsType(t, GuardrailError{}, CheckGuardrail("delete everything", ""))
+	viper.Set(conf.GuardrailMaxBackupsSetting, nil)
+
+	viper.Set(conf.ClusterNameSetting, "billing-prod")
+	assert.NoError(t, CheckGuardrail("delete everything", "billing-prod"))
+	assert.IsType(t, GuardrailError{}, CheckGuardrail("delete everything", "billing-test"))
+}
+
+func TestCheckWideDeletionGuardrail(t *testing.T) {
+	defer viper.Set(conf.ClusterNameSetting, nil)
+	defer viper.Set(conf.GuardrailMaxBackupsSetting, nil)
+	viper.Set(conf.ClusterNameSetting, "billing-prod")
+	viper.Set(conf.GuardrailMaxBackupsSetting, 1)
+	handler := createExplainTestHandler(t)
+	handler.SetGuardrailConfirmation("billing-test")
+
+	target, err := handler.FindTargetByName("base_000000000000000000000004")
+	require.NoError(t, err)
+	assert.NoError(t, handler.CheckWideDeletionGuardrail(target))
+
+	target, err = handler.FindTargetByName("base_000000000000000000000006")
+	require.NoError(t, err)
+	assert.IsType(t, GuardrailError{}, handler.CheckWideDeletionGuardrail(target))
+	assert.IsType(t, GuardrailError{}, handler.DeleteBeforeTarget(target, true))
+	assert.NoError(t, handler.DeleteBeforeTarget(target, false))
+
+	handler.SetGuardrailConfirmation("billing-prod")
+	assert.NoError(t, handler.CheckWideDeletionGuardrail(target))
+}
+
+func TestCheckWideDeletionGuardrail_DefaultMaxBackups(t *testing.T) {
+	defer viper.Set(conf.ClusterNameSetting, nil)
+	handler := createExplainTestHandler(t)
+
+	// the guardrail isn't configured
+	target, err := handler.FindTargetByName("base_000000000000000000000006")
+	require.NoError(t, err)
+	assert.NoError(t, handler.CheckWideDeletionGuardrail(target))
+
+	// there are up to 3 backups older than the target
+	viper.Set(conf.ClusterNameSetting, "billing-prod")
+	assert.NoError(t, handler.CheckWideDeletionGuardrail(target))
+}
+
+func TestDeleteHandlerCheckGuardrail_AsksOnce(t *testing.T) {
+	defer viper.Set(conf.ClusterNameSetting, nil)
+	viper.Set(conf.ClusterNameSetting, "billing-prod")
+	handler := createExplainTestHandler(t)
+	handler.SetGuardrailConfirmation("billing-prod")
+
+	require.NoError(t, handler.CheckGuardrail("delete garbage"))
+	handler.SetGuardrailConfirmation("")
+	assert.NoError(t, handler.CheckGuardrail("delete everything"))
+}
+
+func TestDeleteRetainFull_ChecksGuardrail(t *testing.T) {
+	ConfigureSettings("")
+	conf.InitConfig()
+	defer viper.Set(conf.ClusterNameSetting, nil)
+	defer viper.Set(conf.GuardrailMaxBackupsSetting, nil)
+	viper.Set(conf.ClusterNameSetting, "billing-prod")
+	viper.Set(conf.GuardrailMaxBackupsSetting, 1)
+	handler := createExplainTestHandler(t)
+
+	// the quota retention removing two backups isn't confirmed
+	err := handler.DeleteRetainFull(1, nil)
+	assert.IsType(t, GuardrailError{}, err)
+	exists, err := handler.Folder.Exists("basebackups_005/base_000000000000000000000002_backup_stop_sentinel.json")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	require.NoError(t, handler.DeleteRetainFull(2, nil))
+	exists, err = handler.Folder.Exists("basebackups_005/base_000000000000000000000002_backup_stop_sentinel.json")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = handler.Folder.Exists(MetadataLockPath)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
@@ -0,0 +1,195 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/webserver"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const (
+	HealthzPattern = "/healthz"
+	ReadyzPattern  = "/readyz"
+
+	storageCheckInterval = 30 * time.Second
+	storageCheckTimeout  = 10 * time.Second
+	// storageProbeObject doesn't need to exist, the storage answers whether it does
+	storageProbeObject = "walg_health_probe"
+)
+
+// LagFunc returns how far the archiving is behind the database
+type LagFunc func() (time.Duration, error)
+
+// StorageCheckFunc checks that the storage is reachable
+type StorageCheckFunc func(ctx context.Context) error
+
+// Status is the state of the archiver reported by the health endpoints
+type Status struct {
+	Healthy          bool       `json:"healthy"`
+	Ready            bool       `json:"ready"`
+	LagSeconds       float64    `json:"lag_seconds"`
+	MaxLagSeconds    float64    `json:"max_lag_seconds"`
+	LagError         string     `json:"lag_error,omitempty"`
+	StorageReachable bool       `json:"storage_reachable"`
+	StorageError     string     `json:"storage_error,omitempty"`
+	LastSuccess      *time.Time `json:"last_success,omitempty"`
+	LastError        string     `json:"last_error,omitempty"`
+	LastErrorTime    *time.Time `json:"last_error_time,omitempty"`
+}
+
+// Monitor tracks the archiver state for the health endpoints. The archiver is healthy if the lag
+// is below the threshold and the last archiving attempt has not failed. It is ready if it is healthy
+// and the storage is reachable.
+type Monitor struct {
+	maxLag       time.Duration
+	lag          LagFunc
+	checkStorage StorageCheckFunc
+
+	mutex         sync.Mutex
+	lastSuccess   time.Time
+	lastError     error
+	lastErrorTime time.Time
+
+	storageMutex     sync.Mutex
+	storageCheckedAt time.Time
+	storageErr       error
+}
+
+// NewMonitor creates the monitor, lag and checkStorage may be nil if the archiver can't measure them
+func NewMonitor(maxLag time.Duration, lag LagFunc, checkStorage StorageCheckFunc) *Monitor {
+	return &Monitor{
+		maxLag:       maxLag,
+		lag:          lag,
+		checkStorage: checkStorage,
+	}
+}
+
+// NewFolderCheck checks the storage by the existence check of a single object in the folder, which costs
+// the same regardless of the folder size, unlike listing it
+func NewFolderCheck(folder storage.Folder) StorageCheckFunc {
+	return func(ctx context.Context) error {
+		errs := make(chan error, 1)
+		go func() {
+			_, err := folder.Exists(storageProbeObject)
+			errs <- err
+		}()
+		select {
+		case err := <-errs:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ReportSuccess and ReportError do nothing on the nil monitor, so the archivers report unconditionally
+func (m *Monitor) ReportSuccess() {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.lastSuccess = time.Now()
+}
+
+func (m *Monitor) ReportError(err error) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.lastError = err
+	m.lastErrorTime = time.Now()
+}
+
+func (m *Monitor) Status() Status {
+	status := Status{MaxLagSeconds: m.maxLag.Seconds(), Healthy: true}
+
+	m.mutex.Lock()
+	if !m.lastSuccess.IsZero() {
+		lastSuccess := m.lastSuccess
+		status.LastSuccess = &lastSuccess
+	}
+	if m.lastError != nil {
+		lastErrorTime := m.lastErrorTime
+		status.LastError = m.lastError.Error()
+		status.LastErrorTime = &lastErrorTime
+		// the archiver is unhealthy until the failed operation succeeds again
+		status.Healthy = !m.lastErrorTime.After(m.lastSuccess)
+	}
+	m.mutex.Unlock()
+
+	if m.lag != nil {
+		lag, err := m.lag()
+		if err != nil {
+			status.LagError = err.Error()
+			status.Healthy = false
+		}
+		status.LagSeconds = lag.Seconds()
+		if m.maxLag > 0 && lag > m.maxLag {
+			status.Healthy = false
+		}
+	}
+
+	status.StorageReachable = true
+	if err := m.storageStatus(); err != nil {
+		status.StorageReachable = false
+		status.StorageError = err.Error()
+	}
+	status.Ready = status.Healthy && status.StorageReachable
+	return status
+}
+
+// storageStatus checks the storage at most once per storageCheckInterval, so the probes don't load the storage.
+// The check is not bound to the probe request, so the result of the check is not spoiled by the disconnected prober.
+func (m *Monitor) storageStatus() error {
+	if m.checkStorage == nil {
+		return nil
+	}
+	m.storageMutex.Lock()
+	defer m.storageMutex.Unlock()
+	if time.Since(m.storageCheckedAt) < storageCheckInterval {
+		return m.storageErr
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storageCheckTimeout)
+	defer cancel()
+	m.storageErr = m.checkStorage(ctx)
+	m.storageCheckedAt = time.Now()
+	if m.storageErr != nil {
+		tracelog.WarningLogger.Printf("Health check: storage is unreachable: %v", m.storageErr)
+	}
+	return m.storageErr
+}
+
+func (m *Monitor) ServeHealthz(w http.ResponseWriter, _ *http.Request) {
+	status := m.Status()
+	writeStatus(w, status, status.Healthy)
+}
+
+func (m *Monitor) ServeReadyz(w http.ResponseWriter, _ *http.Request) {
+	status := m.Status()
+	writeStatus(w, status, status.Ready)
+}
+
+func writeStatus(w http.ResponseWriter, status Status, ok bool) {
+	w.Header().Set("Content-Type", "application/json")
+	if ok {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		tracelog.WarningLogger.Printf("Failed to write the health status: %v", err)
+	}
+}
+
+// EnableHTTPHandlers registers /healthz and /readyz at the web server
+func EnableHTTPHandlers(ws webserver.WebServer, m *Monitor) {
+	ws.HandleFunc(HealthzPattern, m.ServeHealthz)
+	ws.HandleFunc(ReadyzPattern, m.ServeReadyz)
+}
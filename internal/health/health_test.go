@@ -0,0 +1,102 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/health"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+func TestMonitor_LastError(t *testing.T) {
+	monitor := health.NewMonitor(time.Minute, nil, nil)
+	assert.True(t, monitor.Status().Healthy)
+
+	monitor.ReportError(errors.New("upload failed"))
+	status := monitor.Status()
+	assert.False(t, status.Healthy)
+	assert.False(t, status.Ready)
+	assert.Equal(t, "upload failed", status.LastError)
+
+	time.Sleep(time.Millisecond)
+	monitor.ReportSuccess()
+	status = monitor.Status()
+	assert.True(t, status.Healthy)
+	assert.True(t, status.Ready)
+	assert.Equal(t, "upload failed", status.LastError)
+	require.NotNil(t, status.LastSuccess)
+}
+
+func TestMonitor_Nil(t *testing.T) {
+	var monitor *health.Monitor
+	monitor.ReportSuccess()
+	monitor.ReportError(errors.New("upload failed"))
+}
+
+func TestMonitor_Lag(t *testing.T) {
+	lag := 30 * time.Second
+	monitor := health.NewMonitor(time.Minute, func() (time.Duration, error) { return lag, nil }, nil)
+	assert.True(t, monitor.Status().Healthy)
+	assert.Equal(t, float64(30), monitor.Status().LagSeconds)
+
+	lag = 2 * time.Minute
+	assert.False(t, monitor.Status().Healthy)
+}
+
+func TestMonitor_StorageUnreachable(t *testing.T) {
+	monitor := health.NewMonitor(time.Minute, nil, func(context.Context) error { return errors.New("no route to host") })
+	status := monitor.Status()
+	assert.True(t, status.Healthy)
+	assert.False(t, status.StorageReachable)
+	assert.False(t, status.Ready)
+	assert.Equal(t, "no route to host", status.StorageError)
+}
+
+func TestMonitor_HTTP(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewKVS())
+	monitor := health.NewMonitor(time.Minute, nil, health.NewFolderCheck(folder))
+
+	recorder := httptest.NewRecorder()
+	monitor.ServeReadyz(recorder, httptest.NewRequest(http.MethodGet, health.ReadyzPattern, nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var status health.Status
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	assert.True(t, status.Ready)
+	assert.True(t, status.StorageReachable)
+
+	monitor.ReportError(errors.New("upload failed"))
+	recorder = httptest.NewRecorder()
+	monitor.ServeHealthz(recorder, httptest.NewRequest(http.MethodGet, health.HealthzPattern, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
+
+// probedFolder fails the listing, which is too costly for the periodic check of a large WAL folder
+type probedFolder struct {
+	storage.Folder
+	existsErr error
+}
+
+func (folder probedFolder) ListFolder() ([]storage.Object, []storage.Folder, error) {
+	return nil, nil, errors.New("the folder is listed")
+}
+
+func (folder probedFolder) Exists(string) (bool, error) {
+	return false, folder.existsErr
+}
+
+func TestNewFolderCheck(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewKVS())
+	check := health.NewFolderCheck(probedFolder{Folder: folder})
+	assert.NoError(t, check(context.Background()))
+
+	check = health.NewFolderCheck(probedFolder{Folder: folder, existsErr: errors.New("access denied")})
+	assert.EqualError(t, check(context.Background()), "access denied")
+}
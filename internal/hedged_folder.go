@@ -0,0 +1,278 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	hedgedLatencySamples    = 100
+	hedgedLatencyMinSamples = 10
+)
+
+// HedgedFolder sends the second request to the storage if the first one takes longer than the most of the
+// recent requests and takes the result of the one that finishes first. This bounds the tail latency of the
+// small requests (WAL segments, sentinels) that otherwise stall archive_command.
+// Only the reads and the uploads of the data objects (see IsDataObject) are hedged: the data objects
+// are never rewritten with another content, while the late duplicate upload of the sentinel, the lock or
+// the lease could overwrite the newer version written in between. The objects larger than maxSize are uploaded
+// without hedging too, as the second upload needs the content in memory.
+type HedgedFolder struct {
+	storage.Folder
+	reads   *latencyTracker
+	puts    *latencyTracker
+	maxSize int64
+}
+
+// NewHedgedFolder hedges the requests to the folder, the conditional writes and presigning are kept
+// if the folder supports them
+func NewHedgedFolder(folder storage.Folder, percentile float64, minDelay time.Duration, maxSize int64) storage.Folder {
+	return storage.WithOptionalInterfaces(&HedgedFolder{
+		Folder:  folder,
+		reads:   newLatencyTracker(percentile, minDelay),
+		puts:    newLatencyTracker(percentile, minDelay),
+		maxSize: maxSize,
+	}, folder)
+}
+
+// ConfigureHedgedRequests wraps the folder with HedgedFolder if WALG_HEDGED_REQUESTS_PERCENTILE is set
+func ConfigureHedgedRequests(folder storage.Folder) storage.Folder {
+	percentile := viper.GetFloat64(conf.HedgedRequestsPercentile)
+	if percentile <= 0 {
+		return folder
+	}
+	if percentile >= 100 {
+		tracelog.WarningLogger.Printf("%s must be less than 100, the requests aren't hedged", conf.HedgedRequestsPercentile)
+		return folder
+	}
+	return NewHedgedFolder(folder, percentile, viper.GetDuration(conf.HedgedRequestsMinDelay),
+		int64(viper.GetSizeInBytes(conf.HedgedRequestsMaxSize)))
+}
+
+// atomicPutStorageTypes are the storages where the uploaded object appears only when its upload completes. The local
+// and the SSH storages write the files in place and don't stop the write on the context cancellation, so the late
+// hedged upload would truncate the file the winner has already uploaded.
+var atomicPutStorageTypes = map[string]bool{"S3": true, "GS": true, "AZ": true, "SWIFT": true}
+
+// hedgedRequestsWrap hedges the requests to the storage of the type only if its uploads are atomic
+func hedgedRequestsWrap(storageType string) storage.WrapRootFolder {
+	if !atomicPutStorageTypes[storageType] {
+		return func(folder storage.Folder) storage.Folder { return folder }
+	}
+	return ConfigureHedgedRequests
+}
+
+func (folder *HedgedFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	subFolder := folder.Folder.GetSubFolder(subFolderRelativePath)
+	return storage.WithOptionalInterfaces(&HedgedFolder{
+		Folder:  subFolder,
+		reads:   folder.reads,
+		puts:    folder.puts,
+		maxSize: folder.maxSize,
+	}, subFolder)
+}
+
+func (folder *HedgedFolder) ListFolderWithPrefix(prefix string) ([]storage.Object, error) {
+	return storage.ListFolderWithPrefix(folder.Folder, prefix)
+}
+
+// ReadObject hedges the request only, the object is transferred once
+func (folder *HedgedFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	return hedge(context.Background(), folder.reads, "Reading "+objectRelativePath,
+		func(context.Context) (io.ReadCloser, error) {
+			return folder.Folder.ReadObject(objectRelativePath)
+		},
+		func(reader io.ReadCloser) {
+			utility.LoggedClose(reader, "Failed to close the hedged read")
+		})
+}
+
+func (folder *HedgedFolder) ReadObjectVersion(objectRelativePath string) (io.ReadCloser, string, error) {
+	conditional, err := storage.AsConditional(folder.Folder)
+	if err != nil {
+		return nil, "", err
+	}
+	type versionedReader struct {
+		io.ReadCloser
+		version string
+	}
+	result, err := hedge(context.Background(), folder.reads, "Reading "+objectRelativePath,
+		func(context.Context) (versionedReader, error) {
+			reader, version, err := conditional.ReadObjectVersion(objectRelativePath)
+			return versionedReader{reader, version}, err
+		},
+		func(reader versionedReader) {
+			utility.LoggedClose(reader, "Failed to close the hedged read")
+		})
+	if err != nil {
+		return nil, "", err
+	}
+	return result.ReadCloser, result.version, nil
+}
+
+func (folder *HedgedFolder) PutObject(name string, content io.Reader) error {
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+func (folder *HedgedFolder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
+	if !IsDataObject(storage.JoinPath(folder.GetPath(), name)) {
+		return folder.Folder.PutObjectWithContext(ctx, name, content)
+	}
+	buffered, err := io.ReadAll(io.LimitReader(content, folder.maxSize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(buffered)) > folder.maxSize {
+		return folder.Folder.PutObjectWithContext(ctx, name, io.MultiReader(bytes.NewReader(buffered), content))
+	}
+	_, err = hedge(ctx, folder.puts, "Uploading "+name,
+		func(ctx context.Context) (struct{}, error) {
+			return struct{}{}, folder.Folder.PutObjectWithContext(ctx, name, bytes.NewReader(buffered))
+		}, nil)
+	return err
+}
+
+// CopyObjectWithContext isn't hedged, the copies may take long regardless of the storage latency
+func (folder *HedgedFolder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string,
+	options storage.CopyOptions) error {
+	return storage.CopyObjectWithContext(ctx, folder.Folder, srcPath, dstPath, options)
+}
+
+// PutObjectIfVersion isn't hedged, the second conditional upload would fail after the first one succeeds
+func (folder *HedgedFolder) PutObjectIfVersion(ctx context.Context, name string, content io.Reader,
+	version string) error {
+	conditional, err := storage.AsConditional(folder.Folder)
+	if err != nil {
+		return err
+	}
+	return conditional.PutObjectIfVersion(ctx, name, content, version)
+}
+
+func (folder *HedgedFolder) PresignObject(objectRelativePath string, ttl time.Duration) (string, error) {
+	presignable, err := storage.AsPresignable(folder.Folder)
+	if err != nil {
+		return "", err
+	}
+	return presignable.PresignObject(objectRelativePath, ttl)
+}
+
+type hedgedResult[T any] struct {
+	value   T
+	err     error
+	latency time.Duration
+}
+
+// hedge runs the attempt and, if it doesn't finish within the delay, the second one alongside it.
+// The first successful result is returned and the context of the other attempt is canceled, discard releases
+// the result of the other attempt if it succeeds anyway. The attempt must not use the context after it returns.
+func hedge[T any](ctx context.Context, tracker *latencyTracker, name string,
+	attempt func(ctx context.Context) (T, error), discard func(T)) (T, error) {
+	results := make(chan hedgedResult[T], 2)
+	var cancels []context.CancelFunc
+	start := func() {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+		startTime := time.Now()
+		go func() {
+			value, err := attempt(attemptCtx)
+			results <- hedgedResult[T]{value: value, err: err, latency: time.Since(startTime)}
+		}()
+	}
+
+	start()
+	delay := tracker.delay()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	pending := 1
+	for {
+		select {
+		case <-timer.C:
+			tracelog.DebugLogger.Printf("%s takes longer than %v, sending the hedged request", name, delay)
+			start()
+			pending++
+			continue
+		case result := <-results:
+			pending--
+			if result.err != nil && pending > 0 {
+				continue
+			}
+			if result.err == nil {
+				tracker.add(result.latency)
+			}
+			for _, cancel := range cancels {
+				cancel()
+			}
+			go discardHedged(results, pending, discard)
+			return result.value, result.err
+		}
+	}
+}
+
+func discardHedged[T any](results <-chan hedgedResult[T], pending int, discard func(T)) {
+	for ; pending > 0; pending-- {
+		if result := <-results; result.err == nil && discard != nil {
+			discard(result.value)
+		}
+	}
+}
+
+// latencyTracker keeps the latencies of the recent successful requests to tell how long to wait before hedging
+type latencyTracker struct {
+	mutex      sync.Mutex
+	samples    []time.Duration
+	next       int
+	percentile float64
+	minDelay   time.Duration
+}
+
+func newLatencyTracker(percentile float64, minDelay time.Duration) *latencyTracker {
+	return &latencyTracker{
+		samples:    make([]time.Duration, 0, hedgedLatencySamples),
+		percentile: percentile,
+		minDelay:   minDelay,
+	}
+}
+
+func (tracker *latencyTracker) add(latency time.Duration) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	if len(tracker.samples) < hedgedLatencySamples {
+		tracker.samples = append(tracker.samples, latency)
+		return
+	}
+	tracker.samples[tracker.next] = latency
+	tracker.next = (tracker.next + 1) % hedgedLatencySamples
+}
+
+// delay is the percentile of the recent latencies, but not less than minDelay. The minDelay is used
+// until there are enough latencies to take the percentile of.
+func (tracker *latencyTracker) delay() time.Duration {
+	tracker.mutex.Lock()
+	sorted := make([]time.Duration, len(tracker.samples))
+	copy(sorted, tracker.samples)
+	tracker.mutex.Unlock()
+
+	if len(sorted) < hedgedLatencyMinSamples {
+		return tracker.minDelay
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	index := int(math.Ceil(tracker.percentile/100*float64(len(sorted)))) - 1
+	if index < 0 {
+		index = 0
+	}
+	if sorted[index] < tracker.minDelay {
+		return tracker.minDelay
+	}
+	return sorted[index]
+}
@@ -0,0 +1,203 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// stuckFirstFolder makes the first request stuck until it is released
+type stuckFirstFolder struct {
+	storage.Folder
+	calls   int32
+	release chan struct{}
+}
+
+func (folder *stuckFirstFolder) wait() {
+	if atomic.AddInt32(&folder.calls, 1) == 1 {
+		<-folder.release
+	}
+}
+
+func (folder *stuckFirstFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	folder.wait()
+	return folder.Folder.ReadObject(objectRelativePath)
+}
+
+func (folder *stuckFirstFolder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
+	folder.wait()
+	return folder.Folder.PutObjectWithContext(ctx, name, content)
+}
+
+func newStuckFirstFolder() *stuckFirstFolder {
+	return &stuckFirstFolder{
+		Folder:  memory.NewFolder("in_memory/", memory.NewKVS()),
+		release: make(chan struct{}),
+	}
+}
+
+func TestHedgedFolder_ReadObject(t *testing.T) {
+	stuck := newStuckFirstFolder()
+	defer close(stuck.release)
+	require.NoError(t, stuck.Folder.PutObject("sentinel.json", strings.NewReader("{}")))
+	folder := NewHedgedFolder(stuck, 95, time.Millisecond, 1024)
+
+	reader, err := folder.ReadObject("sentinel.json")
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "{}", string(data))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&stuck.calls))
+}
+
+func TestHedgedFolder_PutObject(t *testing.T) {
+	stuck := newStuckFirstFolder()
+	defer close(stuck.release)
+	folder := NewHedgedFolder(stuck, 95, time.Millisecond, 1024)
+
+	require.NoError(t, folder.PutObject("000000010000000000000001.lz4", strings.NewReader("segment")))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&stuck.calls))
+	reader, err := stuck.Folder.ReadObject("000000010000000000000001.lz4")
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "segment", string(data))
+}
+
+func TestHedgedFolder_PutMetadataObject(t *testing.T) {
+	for _, name := range []string{"base_000000010000000000000002" + utility.SentinelSuffix, MetadataLockPath} {
+		t.Run(name, func(t *testing.T) {
+			stuck := newStuckFirstFolder()
+			folder := NewHedgedFolder(stuck, 95, time.Millisecond, 1024)
+
+			done := make(chan error)
+			go func() {
+				done <- folder.PutObject(name, strings.NewReader("{}"))
+			}()
+			time.Sleep(10 * time.Millisecond)
+			close(stuck.release)
+			require.NoError(t, <-done)
+			assert.Equal(t, int32(1), atomic.LoadInt32(&stuck.calls))
+		})
+	}
+}
+
+func TestHedgedFolder_PutTarPart(t *testing.T) {
+	stuck := newStuckFirstFolder()
+	defer close(stuck.release)
+	folder := NewHedgedFolder(stuck, 95, time.Millisecond, 1024)
+
+	require.NoError(t, folder.PutObject("base_1"+TarPartitionFolderName+"part_raw_001.tar", strings.NewReader("tar")))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&stuck.calls))
+}
+
+func TestHedgedFolder_PutObjectTooLarge(t *testing.T) {
+	stuck := newStuckFirstFolder()
+	folder := NewHedgedFolder(stuck, 95, time.Millisecond, 4)
+	content := bytes.Repeat([]byte("data"), 8)
+
+	done := make(chan error)
+	go func() {
+		done <- folder.PutObject("part_1.tar.lz4", bytes.NewReader(content))
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(stuck.release)
+	require.NoError(t, <-done)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&stuck.calls))
+	reader, err := stuck.Folder.ReadObject("part_1.tar.lz4")
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+}
+
+func TestLatencyTracker_Delay(t *testing.T) {
+	tracker := newLatencyTracker(90, 5*time.Millisecond)
+	tracker.add(time.Second)
+	assert.Equal(t, 5*time.Millisecond, tracker.delay(), "too few latencies for the percentile")
+
+	for i := 1; i <= 150; i++ {
+		tracker.add(time.Duration(i) * time.Millisecond)
+	}
+	// the latest 100 latencies are 51ms..150ms
+	assert.Equal(t, 140*time.Millisecond, tracker.delay())
+
+	tracker = newLatencyTracker(90, time.Second)
+	for i := 1; i <= 20; i++ {
+		tracker.add(time.Millisecond)
+	}
+	assert.Equal(t, time.Second, tracker.delay())
+}
+
+// inPlaceFolder writes the objects in place like the local and the SSH storages: the object is truncated first
+// and the write stops on the context cancellation. The first write is stuck until it is released.
+type inPlaceFolder struct {
+	*stuckFirstFolder
+}
+
+func (folder inPlaceFolder) PutObject(name string, content io.Reader) error {
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+func (folder inPlaceFolder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	folder.wait()
+	err = folder.Folder.PutObject(name, strings.NewReader(""))
+	if err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return folder.Folder.PutObject(name, bytes.NewReader(data))
+}
+
+func TestHedgedRequestsWrap_DelayedLoser(t *testing.T) {
+	viper.Set(conf.HedgedRequestsPercentile, 95)
+	viper.Set(conf.HedgedRequestsMinDelay, time.Millisecond)
+	viper.Set(conf.HedgedRequestsMaxSize, 1024)
+	defer viper.Set(conf.HedgedRequestsPercentile, nil)
+	defer viper.Set(conf.HedgedRequestsMinDelay, nil)
+	defer viper.Set(conf.HedgedRequestsMaxSize, nil)
+
+	for storageType, hedged := range map[string]bool{"S3": true, "GS": true, "FILE": false, "SSH": false} {
+		t.Run(storageType, func(t *testing.T) {
+			stuck := newStuckFirstFolder()
+			folder := hedgedRequestsWrap(storageType)(inPlaceFolder{stuck})
+
+			done := make(chan error)
+			go func() {
+				done <- folder.PutObject("000000010000000000000001.lz4", strings.NewReader("segment"))
+			}()
+			time.Sleep(10 * time.Millisecond)
+			close(stuck.release)
+			require.NoError(t, <-done)
+			if hedged {
+				assert.Equal(t, int32(2), atomic.LoadInt32(&stuck.calls))
+				return
+			}
+			// the loser would truncate the segment uploaded by the winner
+			assert.Equal(t, int32(1), atomic.LoadInt32(&stuck.calls))
+			reader, err := stuck.Folder.ReadObject("000000010000000000000001.lz4")
+			require.NoError(t, err)
+			data, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			assert.Equal(t, "segment", string(data))
+		})
+	}
+}
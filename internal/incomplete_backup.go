@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	IncompleteBackupsPath = "incomplete_backups"
+
+	incompleteBackupHeartbeatInterval = time.Minute
+)
+
+// IncompleteBackupMarker is uploaded before the backup files and removed after the sentinel, so the files
+// of the backup-push attempts that failed partway can be found and removed
+type IncompleteBackupMarker struct {
+	BackupName string `json:"backup_name"`
+	// BackupsPath is the folder with the backups relative to the storage root
+	BackupsPath string    `json:"backups_path"`
+	Hostname    string    `json:"hostname"`
+	StartTime   time.Time `json:"start_time"`
+	// HeartbeatTime is updated while the backup is being pushed
+	HeartbeatTime time.Time `json:"heartbeat_time,omitempty"`
+}
+
+// lastHeartbeat is the start time for the markers uploaded before the heartbeats were introduced
+func (marker IncompleteBackupMarker) lastHeartbeat() time.Time {
+	if marker.HeartbeatTime.IsZero() {
+		return marker.StartTime
+	}
+	return marker.HeartbeatTime
+}
+
+func incompleteBackupMarkerPath(backupName string) string {
+	return path.Join(IncompleteBackupsPath, backupName+".json")
+}
+
+// IncompleteBackup keeps the marker of the backup being pushed updated, so the backup isn't mistaken
+// for a failed one until it is complete
+type IncompleteBackup struct {
+	rootFolder storage.Folder
+	marker     IncompleteBackupMarker
+
+	stopHeartbeat chan struct{}
+	heartbeatDone sync.WaitGroup
+}
+
+// MarkBackupIncomplete registers the backup whose files are about to be uploaded
+func MarkBackupIncomplete(rootFolder storage.Folder, backupsPath, backupName string) (*IncompleteBackup, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to get hostname for the incomplete backup marker: %v", err)
+	}
+	startTime := utility.TimeNowCrossPlatformUTC()
+	backup := &IncompleteBackup{
+		rootFolder: rootFolder,
+		marker: IncompleteBackupMarker{
+			BackupName:    backupName,
+			BackupsPath:   strings.Trim(backupsPath, "/"),
+			Hostname:      hostname,
+			StartTime:     startTime,
+			HeartbeatTime: startTime,
+		},
+		stopHeartbeat: make(chan struct{}),
+	}
+	err = UploadDto(rootFolder, backup.marker, incompleteBackupMarkerPath(backupName))
+	if err != nil {
+		return nil, err
+	}
+	backup.startHeartbeat()
+	return backup, nil
+}
+
+func (backup *IncompleteBackup) startHeartbeat() {
+	backup.heartbeatDone.Add(1)
+	go func() {
+		defer backup.heartbeatDone.Done()
+		ticker := time.NewTicker(incompleteBackupHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-backup.stopHeartbeat:
+				return
+			case <-ticker.C:
+				backup.marker.HeartbeatTime = utility.TimeNowCrossPlatformUTC()
+				err := UploadDto(backup.rootFolder, backup.marker, incompleteBackupMarkerPath(backup.marker.BackupName))
+				if err != nil {
+					tracelog.WarningLogger.Printf("Failed to update the incomplete backup marker: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Complete removes the marker of the backup once its sentinel is uploaded
+func (backup *IncompleteBackup) Complete() error {
+	close(backup.stopHeartbeat)
+	backup.heartbeatDone.Wait()
+	return backup.rootFolder.DeleteObjects([]string{incompleteBackupMarkerPath(backup.marker.BackupName)})
+}
+
+// CleanupIncompleteBackups deletes the files of the backups that have a marker but no sentinel. The backups
+// whose marker has been updated within WALG_INCOMPLETE_BACKUP_TTL may still be pushed, so they are kept.
+// If onlyThisHost is set, only the backups pushed from this host are deleted.
+func CleanupIncompleteBackups(rootFolder storage.Folder, onlyThisHost, confirm bool) error {
+	ttl, err := conf.GetDurationSetting(conf.IncompleteBackupTTLSetting)
+	if err != nil {
+		return err
+	}
+	// the backups whose sentinels are spooled are complete, upload the sentinels before looking for the incomplete ones
+	flushSpooledMetadata(rootFolder)
+	markerObjects, _, err := rootFolder.GetSubFolder(IncompleteBackupsPath).ListFolder()
+	if err != nil {
+		return errors.Wrap(err, "failed to list incomplete backups")
+	}
+	hostname, err := os.Hostname()
+	if err != nil && onlyThisHost {
+		return errors.Wrap(err, "failed to get hostname")
+	}
+
+	for _, markerObject := range markerObjects {
+		markerPath := path.Join(IncompleteBackupsPath, markerObject.GetName())
+		var marker IncompleteBackupMarker
+		err = FetchDto(rootFolder, &marker, markerPath)
+		if err != nil {
+			return err
+		}
+		if onlyThisHost && marker.Hostname != hostname {
+			continue
+		}
+
+		backupsFolder := rootFolder.GetSubFolder(marker.BackupsPath)
+		completed, err := backupsFolder.Exists(SentinelNameFromBackup(marker.BackupName))
+		if err != nil {
+			return err
+		}
+		if !completed && isMetadataSpooled(backupsFolder, SentinelNameFromBackup(marker.BackupName)) {
+			tracelog.InfoLogger.Printf("Backup %s has its sentinel spooled, keeping it until the sentinel is uploaded",
+				marker.BackupName)
+			continue
+		}
+		if !completed && time.Since(marker.lastHeartbeat()) < ttl {
+			tracelog.InfoLogger.Printf("Backup %s pushed from %s may still be running, its marker was updated at %s",
+				marker.BackupName, marker.Hostname, marker.lastHeartbeat().Format(time.RFC3339))
+			continue
+		}
+		if completed {
+			tracelog.InfoLogger.Printf("Backup %s has been completed, removing its stale marker", marker.BackupName)
+		} else {
+			tracelog.InfoLogger.Printf("Backup %s pushed from %s at %s is incomplete, removing its files",
+				marker.BackupName, marker.Hostname, marker.StartTime.Format(time.RFC3339))
+			allObjects := func(storage.Object) bool { return true }
+			allFolders := func(string) bool { return true }
+			err = DeleteObjectsWhere(backupsFolder.GetSubFolder(marker.BackupName), confirm, allObjects, allFolders)
+			if err != nil {
+				return errors.Wrapf(err, "failed to delete incomplete backup %s", marker.BackupName)
+			}
+		}
+		if confirm {
+			err = rootFolder.DeleteObjects([]string{markerPath})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package internal_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestCleanupIncompleteBackups(t *testing.T) {
+	internal.ConfigureSettings("")
+	conf.InitConfig()
+	folder := memory.NewFolder("", memory.NewKVS())
+	backupsFolder := folder.GetSubFolder(utility.BaseBackupPath)
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	putMarker := func(name, hostname string, heartbeatTime time.Time) {
+		require.NoError(t, internal.UploadDto(folder, internal.IncompleteBackupMarker{
+			BackupName:    name,
+			BackupsPath:   utility.BaseBackupPath,
+			Hostname:      hostname,
+			HeartbeatTime: heartbeatTime,
+		}, internal.IncompleteBackupsPath+"/"+name+".json"))
+		require.NoError(t, backupsFolder.PutObject(name+"/tar_partitions/part_1.tar.lz4", strings.NewReader("data")))
+	}
+	failedTime := time.Now().Add(-time.Hour)
+	putMarker("base_000000010000000000000002", hostname, failedTime)
+	putMarker("base_000000010000000000000004", hostname, failedTime)
+	// the first backup has been completed, but its marker wasn't removed
+	require.NoError(t, backupsFolder.PutObject(internal.SentinelNameFromBackup("base_000000010000000000000002"),
+		strings.NewReader("{}")))
+	// the backup of another host has failed
+	putMarker("base_000000010000000000000006", "another-host", time.Time{})
+	// and the backups of both hosts are running right now
+	putMarker("base_00000001000000000000000A", "another-host", time.Now())
+	running, err := internal.MarkBackupIncomplete(folder, utility.BaseBackupPath, "base_000000010000000000000008")
+	require.NoError(t, err)
+	require.NoError(t, backupsFolder.PutObject("base_000000010000000000000008/tar_partitions/part_1.tar.lz4",
+		strings.NewReader("data")))
+
+	require.NoError(t, internal.CleanupIncompleteBackups(folder, true, false))
+	assertExists(t, backupsFolder, "base_000000010000000000000004/tar_partitions/part_1.tar.lz4", true)
+
+	require.NoError(t, internal.CleanupIncompleteBackups(folder, true, true))
+	assertExists(t, backupsFolder, "base_000000010000000000000002/tar_partitions/part_1.tar.lz4", true)
+	assertExists(t, backupsFolder, "base_000000010000000000000004/tar_partitions/part_1.tar.lz4", false)
+	assertExists(t, backupsFolder, "base_000000010000000000000006/tar_partitions/part_1.tar.lz4", true)
+	assertExists(t, backupsFolder, "base_000000010000000000000008/tar_partitions/part_1.tar.lz4", true)
+	markers, _, err := folder.GetSubFolder(internal.IncompleteBackupsPath).ListFolder()
+	require.NoError(t, err)
+	require.Len(t, markers, 3)
+
+	require.NoError(t, internal.CleanupIncompleteBackups(folder, false, true))
+	assertExists(t, backupsFolder, "base_000000010000000000000006/tar_partitions/part_1.tar.lz4", false)
+	assertExists(t, backupsFolder, "base_000000010000000000000008/tar_partitions/part_1.tar.lz4", true)
+	assertExists(t, backupsFolder, "base_00000001000000000000000A/tar_partitions/part_1.tar.lz4", true)
+	markers, _, err = folder.GetSubFolder(internal.IncompleteBackupsPath).ListFolder()
+	require.NoError(t, err)
+	require.Len(t, markers, 2)
+
+	require.NoError(t, running.Complete())
+	markers, _, err = folder.GetSubFolder(internal.IncompleteBackupsPath).ListFolder()
+	require.NoError(t, err)
+	assert.Len(t, markers, 1)
+}
+
+func assertExists(t *testing.T, folder storage.Folder, path string, expected bool) {
+	exists, err := folder.Exists(path)
+	require.NoError(t, err)
+	assert.Equal(t, expected, exists, path)
+}
@@ -0,0 +1,292 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	EC2InterruptionNotices = "ec2"
+	GCPInterruptionNotices = "gcp"
+
+	ec2MetadataEndpoint = "http://169.254.169.254"
+	gcpMetadataEndpoint = "http://metadata.google.internal"
+
+	metadataRequestTimeout = 2 * time.Second
+	// the scheduled maintenance is reacted to only when it is this close
+	maintenanceNoticeLead = 30 * time.Minute
+	ec2EventTimeFormat    = "2 Jan 2006 15:04:05 GMT"
+)
+
+// InterruptionNotice tells that the instance is about to be stopped, e.g. the spot instance is reclaimed
+// or the instance is preempted or goes down for the maintenance
+type InterruptionNotice struct {
+	Source      string
+	Description string
+}
+
+// InterruptionNoticeError is the cause of the context canceled because of the interruption notice
+type InterruptionNoticeError struct {
+	Notice InterruptionNotice
+}
+
+func (err InterruptionNoticeError) Error() string {
+	return fmt.Sprintf("%s interruption notice: %s", err.Notice.Source, err.Notice.Description)
+}
+
+// InterruptionNoticeSource checks whether the instance is about to be interrupted
+type InterruptionNoticeSource interface {
+	// CheckNotice returns nil if there is no notice
+	CheckNotice(ctx context.Context) (*InterruptionNotice, error)
+}
+
+// ConfigureInterruptionNoticeSources returns the sources of WALG_INTERRUPTION_NOTICE_SOURCES
+// and WALG_INTERRUPTION_NOTICE_COMMAND
+func ConfigureInterruptionNoticeSources() ([]InterruptionNoticeSource, error) {
+	var sources []InterruptionNoticeSource
+	for _, name := range strings.Split(viper.GetString(conf.InterruptionNoticeSources), ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case EC2InterruptionNotices:
+			sources = append(sources, newEC2NoticeSource(ec2MetadataEndpoint))
+		case GCPInterruptionNotices:
+			sources = append(sources, newGCPNoticeSource(gcpMetadataEndpoint))
+		default:
+			return nil, fmt.Errorf("unknown interruption notice source %q in %s, expected %s or %s",
+				name, conf.InterruptionNoticeSources, EC2InterruptionNotices, GCPInterruptionNotices)
+		}
+	}
+	if viper.GetString(conf.InterruptionNoticeCommand) != "" {
+		sources = append(sources, commandNoticeSource{})
+	}
+	return sources, nil
+}
+
+// WithInterruptionNotices returns the context which is canceled once any of the configured sources gives
+// the interruption notice, the InterruptionNoticeError is its cause then. The long operations use it to save
+// their progress and exit before the instance is stopped, so the next run resumes them.
+func WithInterruptionNotices(parent context.Context) (context.Context, context.CancelFunc) {
+	sources, err := ConfigureInterruptionNoticeSources()
+	if err != nil {
+		tracelog.WarningLogger.Printf("The interruption notices aren't watched: %v", err)
+	}
+	if len(sources) == 0 {
+		return context.WithCancel(parent)
+	}
+	interval, err := conf.GetDurationSetting(conf.InterruptionNoticeInterval)
+	if err != nil {
+		tracelog.WarningLogger.Printf("The interruption notices aren't watched: %v", err)
+		return context.WithCancel(parent)
+	}
+
+	ctx, cancel := context.WithCancelCause(parent)
+	go watchInterruptionNotices(ctx, sources, interval, cancel)
+	return ctx, func() { cancel(context.Canceled) }
+}
+
+// GetInterruptionNotice returns the error of the notice the context is canceled because of
+func GetInterruptionNotice(ctx context.Context) (InterruptionNoticeError, bool) {
+	var noticeErr InterruptionNoticeError
+	ok := errors.As(context.Cause(ctx), &noticeErr)
+	return noticeErr, ok
+}
+
+func watchInterruptionNotices(ctx context.Context, sources []InterruptionNoticeSource, interval time.Duration,
+	cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	failing := make([]bool, len(sources))
+	for {
+		for i, source := range sources {
+			notice, err := source.CheckNotice(ctx)
+			if err != nil {
+				// the warning is logged once, the metadata of another cloud is never available
+				if !failing[i] && ctx.Err() == nil {
+					tracelog.WarningLogger.Printf("Failed to check the interruption notices: %v", err)
+				}
+				failing[i] = true
+				continue
+			}
+			failing[i] = false
+			if notice != nil {
+				tracelog.WarningLogger.Printf("The instance is about to be interrupted (%s: %s), "+
+					"saving the progress and exiting", notice.Source, notice.Description)
+				cancel(InterruptionNoticeError{Notice: *notice})
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// getMetadata returns the body of the metadata endpoint response, found is false if it responds with 404
+func getMetadata(ctx context.Context, client *http.Client, request *http.Request) (body string, found bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, metadataRequestTimeout)
+	defer cancel()
+	response, err := client.Do(request.WithContext(ctx))
+	if err != nil {
+		return "", false, err
+	}
+	defer utility.LoggedClose(response.Body, "")
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", false, err
+	}
+	switch response.StatusCode {
+	case http.StatusOK:
+		return strings.TrimSpace(string(data)), true, nil
+	case http.StatusNotFound:
+		return "", false, nil
+	default:
+		return "", false, fmt.Errorf("%s responded with %s", request.URL, response.Status)
+	}
+}
+
+// ec2NoticeSource checks the spot instance interruption and the scheduled maintenance in the EC2 instance metadata
+type ec2NoticeSource struct {
+	endpoint string
+	client   *http.Client
+}
+
+type ec2ScheduledEvent struct {
+	Code        string
+	Description string
+	NotBefore   string
+	State       string
+}
+
+func newEC2NoticeSource(endpoint string) *ec2NoticeSource {
+	return &ec2NoticeSource{endpoint: endpoint, client: &http.Client{}}
+}
+
+func (source *ec2NoticeSource) CheckNotice(ctx context.Context) (*InterruptionNotice, error) {
+	token, err := source.getToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get EC2 metadata token: %w", err)
+	}
+	action, found, err := source.get(ctx, token, "/latest/meta-data/spot/instance-action")
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return &InterruptionNotice{Source: EC2InterruptionNotices, Description: "spot instance action " + action}, nil
+	}
+
+	scheduled, found, err := source.get(ctx, token, "/latest/meta-data/events/maintenance/scheduled")
+	if err != nil || !found {
+		return nil, err
+	}
+	var events []ec2ScheduledEvent
+	if err := json.Unmarshal([]byte(scheduled), &events); err != nil {
+		return nil, fmt.Errorf("parse EC2 scheduled events: %w", err)
+	}
+	for _, event := range events {
+		if event.State == "completed" || event.State == "canceled" {
+			continue
+		}
+		notBefore, err := time.Parse(ec2EventTimeFormat, event.NotBefore)
+		if err == nil && time.Until(notBefore) > maintenanceNoticeLead {
+			continue
+		}
+		return &InterruptionNotice{
+			Source:      EC2InterruptionNotices,
+			Description: fmt.Sprintf("%s (%s) not before %s", event.Code, event.Description, event.NotBefore),
+		}, nil
+	}
+	return nil, nil
+}
+
+// getToken gets the IMDSv2 session token
+func (source *ec2NoticeSource) getToken(ctx context.Context) (string, error) {
+	request, err := http.NewRequest(http.MethodPut, source.endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	token, found, err := getMetadata(ctx, source.client, request)
+	if err == nil && !found {
+		err = errors.New("no token")
+	}
+	return token, err
+}
+
+func (source *ec2NoticeSource) get(ctx context.Context, token, path string) (string, bool, error) {
+	request, err := http.NewRequest(http.MethodGet, source.endpoint+path, nil)
+	if err != nil {
+		return "", false, err
+	}
+	request.Header.Set("X-aws-ec2-metadata-token", token)
+	return getMetadata(ctx, source.client, request)
+}
+
+// gcpNoticeSource checks the preemption and the upcoming host maintenance in the GCE instance metadata.
+// The instances that are live migrated during the maintenance aren't interrupted.
+type gcpNoticeSource struct {
+	endpoint string
+	client   *http.Client
+}
+
+func newGCPNoticeSource(endpoint string) *gcpNoticeSource {
+	return &gcpNoticeSource{endpoint: endpoint, client: &http.Client{}}
+}
+
+func (source *gcpNoticeSource) CheckNotice(ctx context.Context) (*InterruptionNotice, error) {
+	preempted, _, err := source.get(ctx, "/computeMetadata/v1/instance/preempted")
+	if err != nil {
+		return nil, err
+	}
+	if preempted == "TRUE" {
+		return &InterruptionNotice{Source: GCPInterruptionNotices, Description: "instance is preempted"}, nil
+	}
+	event, _, err := source.get(ctx, "/computeMetadata/v1/instance/maintenance-event")
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(event, "TERMINATE") {
+		return &InterruptionNotice{Source: GCPInterruptionNotices, Description: "maintenance event " + event}, nil
+	}
+	return nil, nil
+}
+
+func (source *gcpNoticeSource) get(ctx context.Context, path string) (string, bool, error) {
+	request, err := http.NewRequest(http.MethodGet, source.endpoint+path, nil)
+	if err != nil {
+		return "", false, err
+	}
+	request.Header.Set("Metadata-Flavor", "Google")
+	return getMetadata(ctx, source.client, request)
+}
+
+// commandNoticeSource runs WALG_INTERRUPTION_NOTICE_COMMAND, which prints the notice if the instance
+// is about to be interrupted and nothing otherwise
+type commandNoticeSource struct{}
+
+func (commandNoticeSource) CheckNotice(ctx context.Context) (*InterruptionNotice, error) {
+	cmd, err := GetCommandSettingContext(ctx, conf.InterruptionNoticeCommand)
+	if err != nil {
+		return nil, err
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", conf.InterruptionNoticeCommand, err)
+	}
+	if notice := strings.TrimSpace(string(output)); notice != "" {
+		return &InterruptionNotice{Source: "command", Description: notice}, nil
+	}
+	return nil, nil
+}
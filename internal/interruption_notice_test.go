@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMetadataServer responds to the requests with any of the headers set
+func newMetadataServer(t *testing.T, responses map[string]string, headers ...string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorized := false
+		for _, header := range headers {
+			authorized = authorized || r.Header.Get(header) != ""
+		}
+		if !authorized {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		response, ok := responses[r.Method+" "+r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestEC2NoticeSource(t *testing.T) {
+	soon := time.Now().Add(10 * time.Minute).UTC().Format(ec2EventTimeFormat)
+	later := time.Now().Add(48 * time.Hour).UTC().Format(ec2EventTimeFormat)
+	var tests = []struct {
+		name      string
+		responses map[string]string
+		notice    bool
+	}{
+		{name: "no notices", responses: map[string]string{}},
+		{
+			name:      "spot interruption",
+			responses: map[string]string{"GET /latest/meta-data/spot/instance-action": `{"action": "terminate"}`},
+			notice:    true,
+		},
+		{
+			name: "upcoming maintenance",
+			responses: map[string]string{"GET /latest/meta-data/events/maintenance/scheduled": `[
+				{"Code": "system-reboot", "NotBefore": "` + soon + `", "State": "active"}]`},
+			notice: true,
+		},
+		{
+			name: "distant maintenance",
+			responses: map[string]string{"GET /latest/meta-data/events/maintenance/scheduled": `[
+				{"Code": "system-reboot", "NotBefore": "` + later + `", "State": "active"},
+				{"Code": "instance-stop", "NotBefore": "` + soon + `", "State": "canceled"}]`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.responses["PUT /latest/api/token"] = "token"
+			server := newMetadataServer(t, tt.responses, "X-aws-ec2-metadata-token", "X-aws-ec2-metadata-token-ttl-seconds")
+			notice, err := newEC2NoticeSource(server.URL).CheckNotice(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tt.notice, notice != nil)
+		})
+	}
+}
+
+func TestGCPNoticeSource(t *testing.T) {
+	server := newMetadataServer(t, map[string]string{
+		"GET /computeMetadata/v1/instance/preempted":         "FALSE",
+		"GET /computeMetadata/v1/instance/maintenance-event": "MIGRATE_ON_HOST_MAINTENANCE",
+	}, "Metadata-Flavor")
+	notice, err := newGCPNoticeSource(server.URL).CheckNotice(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, notice, "the live migrated instance isn't interrupted")
+
+	server = newMetadataServer(t, map[string]string{
+		"GET /computeMetadata/v1/instance/preempted": "TRUE",
+	}, "Metadata-Flavor")
+	notice, err = newGCPNoticeSource(server.URL).CheckNotice(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, notice)
+	assert.Equal(t, GCPInterruptionNotices, notice.Source)
+}
+
+type countdownNoticeSource struct {
+	checks int
+}
+
+func (source *countdownNoticeSource) CheckNotice(context.Context) (*InterruptionNotice, error) {
+	source.checks--
+	if source.checks > 0 {
+		return nil, nil
+	}
+	return &InterruptionNotice{Source: "test", Description: "reclaimed"}, nil
+}
+
+func TestWatchInterruptionNotices(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	go watchInterruptionNotices(ctx, []InterruptionNoticeSource{&countdownNoticeSource{checks: 3}}, time.Millisecond, cancel)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the context isn't canceled on the notice")
+	}
+	noticeErr, ok := GetInterruptionNotice(ctx)
+	require.True(t, ok)
+	assert.Equal(t, "reclaimed", noticeErr.Notice.Description)
+}
@@ -0,0 +1,120 @@
+package ioextensions
+
+import (
+	"io"
+	"sync"
+)
+
+// ReadAheadReader reads the source in the background into a few chunks, so the source
+// and the consumer of the data work concurrently instead of waiting for each other
+type ReadAheadReader struct {
+	source    io.ReadCloser
+	chunks    chan []byte
+	free      chan []byte
+	done      chan struct{}
+	finished  chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+	// interruptible sources are closed without waiting for the source read in progress
+	interruptible bool
+	// err is the reason the source is not read anymore, it is set before chunks are closed
+	err     error
+	chunk   []byte
+	current []byte
+}
+
+// NewReadAheadReader reads the source ahead, its Close waits for the source read in progress to finish
+// before closing the source
+func NewReadAheadReader(source io.ReadCloser, chunkSize, chunkCount int) *ReadAheadReader {
+	return newReadAheadReader(source, chunkSize, chunkCount, false)
+}
+
+// NewInterruptibleReadAheadReader is like NewReadAheadReader, but its Close closes the source without waiting
+// for the source read in progress, so the read stuck e.g. on the network is interrupted by the close instead
+// of blocking it. The source has to allow being closed while it is read, like the storage object readers do.
+func NewInterruptibleReadAheadReader(source io.ReadCloser, chunkSize, chunkCount int) *ReadAheadReader {
+	return newReadAheadReader(source, chunkSize, chunkCount, true)
+}
+
+func newReadAheadReader(source io.ReadCloser, chunkSize, chunkCount int, interruptible bool) *ReadAheadReader {
+	reader := &ReadAheadReader{
+		source:        source,
+		chunks:        make(chan []byte, chunkCount),
+		free:          make(chan []byte, chunkCount),
+		done:          make(chan struct{}),
+		finished:      make(chan struct{}),
+		interruptible: interruptible,
+	}
+	for i := 0; i < chunkCount; i++ {
+		reader.free <- make([]byte, chunkSize)
+	}
+	go reader.readAhead()
+	return reader
+}
+
+func (reader *ReadAheadReader) readAhead() {
+	defer close(reader.finished)
+	defer close(reader.chunks)
+	for {
+		var chunk []byte
+		select {
+		case <-reader.done:
+			reader.err = io.ErrClosedPipe
+			return
+		default:
+		}
+		select {
+		case chunk = <-reader.free:
+		case <-reader.done:
+			reader.err = io.ErrClosedPipe
+			return
+		}
+		// the data is passed as soon as it is read, not when the chunk is full
+		n, err := reader.source.Read(chunk[:cap(chunk)])
+		if n > 0 {
+			reader.chunks <- chunk[:n]
+		} else {
+			reader.free <- chunk
+		}
+		if err != nil {
+			select {
+			case <-reader.done:
+				// the read failed because the source is closed
+				reader.err = io.ErrClosedPipe
+			default:
+				reader.err = err
+			}
+			return
+		}
+	}
+}
+
+func (reader *ReadAheadReader) Read(p []byte) (int, error) {
+	for len(reader.current) == 0 {
+		if reader.chunk != nil {
+			reader.free <- reader.chunk[:cap(reader.chunk)]
+			reader.chunk = nil
+		}
+		chunk, ok := <-reader.chunks
+		if !ok {
+			return 0, reader.err
+		}
+		reader.chunk = chunk
+		reader.current = chunk
+	}
+	n := copy(p, reader.current)
+	reader.current = reader.current[n:]
+	return n, nil
+}
+
+// Close stops reading ahead and closes the source, see NewInterruptibleReadAheadReader
+func (reader *ReadAheadReader) Close() error {
+	reader.closeOnce.Do(func() {
+		close(reader.done)
+		if !reader.interruptible {
+			<-reader.finished
+		}
+		reader.closeErr = reader.source.Close()
+	})
+	return reader.closeErr
+}
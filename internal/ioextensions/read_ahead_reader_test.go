@@ -0,0 +1,143 @@
+package ioextensions_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/ioextensions"
+)
+
+// blockingReader blocks the reads until it is closed, like a stuck network read
+type blockingReader struct {
+	closed chan struct{}
+}
+
+func (reader *blockingReader) Read([]byte) (int, error) {
+	<-reader.closed
+	return 0, errors.New("read of the closed reader")
+}
+
+func (reader *blockingReader) Close() error {
+	close(reader.closed)
+	return nil
+}
+
+// failingReader returns its data and then the error
+type failingReader struct {
+	io.Reader
+	err error
+}
+
+func (reader *failingReader) Read(p []byte) (int, error) {
+	n, err := reader.Reader.Read(p)
+	if err == io.EOF {
+		return n, reader.err
+	}
+	return n, err
+}
+
+func TestReadAheadReader_ReadsToEOF(t *testing.T) {
+	data := bytes.Repeat([]byte("read ahead"), 1000)
+	reader := ioextensions.NewReadAheadReader(io.NopCloser(bytes.NewReader(data)), 64, 3)
+
+	read, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, data, read)
+
+	n, err := reader.Read(make([]byte, 1))
+	assert.Equal(t, 0, n)
+	assert.Equal(t, io.EOF, err)
+	assert.NoError(t, reader.Close())
+}
+
+func TestReadAheadReader_PropagatesError(t *testing.T) {
+	sourceErr := errors.New("connection reset")
+	source := &failingReader{Reader: bytes.NewReader([]byte("partial data")), err: sourceErr}
+	reader := ioextensions.NewReadAheadReader(io.NopCloser(source), 4, 2)
+
+	read, err := io.ReadAll(reader)
+	assert.Equal(t, sourceErr, err)
+	assert.Equal(t, []byte("partial data"), read)
+	assert.NoError(t, reader.Close())
+}
+
+func TestReadAheadReader_CloseDuringRead(t *testing.T) {
+	reader := ioextensions.NewInterruptibleReadAheadReader(&blockingReader{closed: make(chan struct{})}, 64, 2)
+
+	readErr := make(chan error)
+	go func() {
+		_, err := reader.Read(make([]byte, 16))
+		readErr <- err
+	}()
+
+	closed := make(chan error)
+	go func() {
+		closed <- reader.Close()
+	}()
+	select {
+	case err := <-closed:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close is blocked by the source read in progress")
+	}
+	select {
+	case err := <-readErr:
+		assert.Equal(t, io.ErrClosedPipe, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Read isn't interrupted by Close")
+	}
+}
+
+// releasedReader blocks the read until it is released and records if it is closed during the read
+type releasedReader struct {
+	released chan struct{}
+	reading  int32
+	closed   int32
+	// closedDuringRead is set if the reader is closed while it is read
+	closedDuringRead int32
+}
+
+func (reader *releasedReader) Read(p []byte) (int, error) {
+	atomic.StoreInt32(&reader.reading, 1)
+	defer atomic.StoreInt32(&reader.reading, 0)
+	<-reader.released
+	return copy(p, "data"), nil
+}
+
+func (reader *releasedReader) Close() error {
+	if atomic.LoadInt32(&reader.reading) == 1 {
+		atomic.StoreInt32(&reader.closedDuringRead, 1)
+	}
+	atomic.StoreInt32(&reader.closed, 1)
+	return nil
+}
+
+func TestReadAheadReader_CloseWaitsForRead(t *testing.T) {
+	source := &releasedReader{released: make(chan struct{})}
+	reader := ioextensions.NewReadAheadReader(source, 64, 2)
+	for atomic.LoadInt32(&source.reading) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	closed := make(chan error)
+	go func() {
+		closed <- reader.Close()
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close doesn't wait for the source read in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, int32(0), atomic.LoadInt32(&source.closed))
+
+	close(source.released)
+	assert.NoError(t, <-closed)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&source.closed))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&source.closedDuringRead))
+}
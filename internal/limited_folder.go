@@ -3,6 +3,7 @@ package internal
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/wal-g/wal-g/internal/ioextensions"
 	"github.com/wal-g/wal-g/internal/limiters"
@@ -15,8 +16,10 @@ type LimitedFolder struct {
 	limiter *rate.Limiter
 }
 
-func NewLimitedFolder(folder storage.Folder, limiter *rate.Limiter) *LimitedFolder {
-	return &LimitedFolder{Folder: folder, limiter: limiter}
+// NewLimitedFolder limits the bandwidth of the folder, the conditional writes and presigning are kept
+// if the folder supports them
+func NewLimitedFolder(folder storage.Folder, limiter *rate.Limiter) storage.Folder {
+	return storage.WithOptionalInterfaces(&LimitedFolder{Folder: folder, limiter: limiter}, folder)
 }
 
 func (lf *LimitedFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
@@ -35,6 +38,25 @@ func (lf *LimitedFolder) ReadObject(objectRelativePath string) (io.ReadCloser, e
 	}, nil
 }
 
+func (lf *LimitedFolder) ReadObjectVersion(objectRelativePath string) (io.ReadCloser, string, error) {
+	conditional, err := storage.AsConditional(lf.Folder)
+	if err != nil {
+		return nil, "", err
+	}
+	readCloser, version, err := conditional.ReadObjectVersion(objectRelativePath)
+	if err != nil {
+		return nil, "", err
+	}
+	return ioextensions.ReadCascadeCloser{
+		Reader: limiters.NewReader(context.Background(), readCloser, lf.limiter),
+		Closer: readCloser,
+	}, version, nil
+}
+
+func (lf *LimitedFolder) ListFolderWithPrefix(prefix string) ([]storage.Object, error) {
+	return storage.ListFolderWithPrefix(lf.Folder, prefix)
+}
+
 func (lf *LimitedFolder) PutObject(name string, content io.Reader) error {
 	return lf.PutObjectWithContext(context.Background(), name, content)
 }
@@ -43,3 +65,33 @@ func (lf *LimitedFolder) PutObjectWithContext(ctx context.Context, name string,
 	limitedReader := limiters.NewReader(ctx, content, lf.limiter)
 	return lf.Folder.PutObjectWithContext(ctx, name, limitedReader)
 }
+
+func (lf *LimitedFolder) CopyObject(srcPath string, dstPath string) error {
+	return lf.CopyObjectWithContext(context.Background(), srcPath, dstPath, storage.CopyOptions{})
+}
+
+// CopyObjectWithContext limits the copies the storage streams through wal-g, the server-side copies aren't limited
+func (lf *LimitedFolder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string,
+	options storage.CopyOptions) error {
+	if options.Limiter == nil {
+		options.Limiter = lf.limiter
+	}
+	return storage.CopyObjectWithContext(ctx, lf.Folder, srcPath, dstPath, options)
+}
+
+func (lf *LimitedFolder) PutObjectIfVersion(ctx context.Context, name string, content io.Reader, version string) error {
+	conditional, err := storage.AsConditional(lf.Folder)
+	if err != nil {
+		return err
+	}
+	return conditional.PutObjectIfVersion(ctx, name, limiters.NewReader(ctx, content, lf.limiter), version)
+}
+
+// PresignObject isn't limited, the object is downloaded by the URL bypassing wal-g
+func (lf *LimitedFolder) PresignObject(objectRelativePath string, ttl time.Duration) (string, error) {
+	presignable, err := storage.AsPresignable(lf.Folder)
+	if err != nil {
+		return "", err
+	}
+	return presignable.PresignObject(objectRelativePath, ttl)
+}
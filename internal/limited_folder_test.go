@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"golang.org/x/time/rate"
+)
+
+type presignableFolder struct {
+	storage.Folder
+}
+
+func (folder presignableFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	return presignableFolder{folder.Folder.GetSubFolder(subFolderRelativePath)}
+}
+
+func (folder presignableFolder) PresignObject(objectRelativePath string, ttl time.Duration) (string, error) {
+	return "https://example.com/" + folder.GetPath() + objectRelativePath + "?ttl=" + ttl.String(), nil
+}
+
+func TestLimitedFolder_KeepsOptionalInterfaces(t *testing.T) {
+	limiter := rate.NewLimiter(rate.Inf, 1024)
+	root := memory.NewFolder("in_memory/", memory.NewKVS())
+
+	folder := NewLimitedFolder(root, limiter).GetSubFolder("a")
+	_, isPresignable := folder.(storage.PresignableFolder)
+	assert.False(t, isPresignable, "the memory folder can't presign")
+	conditional, ok := folder.(storage.ConditionalFolder)
+	require.True(t, ok)
+	require.NoError(t, conditional.PutObjectIfVersion(context.Background(), "b", strings.NewReader("1"), ""))
+	_, version, err := conditional.ReadObjectVersion("b")
+	require.NoError(t, err)
+	assert.Error(t, conditional.PutObjectIfVersion(context.Background(), "b", strings.NewReader("2"), ""))
+	require.NoError(t, conditional.PutObjectIfVersion(context.Background(), "b", strings.NewReader("2"), version))
+
+	folder = NewLimitedFolder(presignableFolder{root}, limiter).GetSubFolder("a")
+	_, isConditional := folder.(storage.ConditionalFolder)
+	assert.False(t, isConditional)
+	presignable, ok := folder.(storage.PresignableFolder)
+	require.True(t, ok)
+	url, err := presignable.PresignObject("b", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/in_memory/a/b?ttl=1h0m0s", url)
+}
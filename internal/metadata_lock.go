@@ -0,0 +1,231 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	MetadataLockPath = "metadata.lock"
+	// MetadataLockTTL is the time the lock object left by a crashed process stays valid,
+	// the holder rewrites the object more often
+	MetadataLockTTL = 10 * time.Minute
+
+	metadataLockRetryInterval = time.Second
+	modifyDtoAttempts         = 5
+)
+
+type MetadataLockedError struct {
+	error
+}
+
+func newMetadataLockedError(holder *leaseRecord) MetadataLockedError {
+	return MetadataLockedError{errors.Errorf("backups metadata is locked by %s (%s) until %s, remove '%s' if it is stale",
+		holder.Hostname, holder.Owner, holder.ExpiresAt.Format(time.RFC3339), MetadataLockPath)}
+}
+
+func (err MetadataLockedError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+type MetadataLockLostError struct {
+	error
+}
+
+func newMetadataLockLostError() MetadataLockLostError {
+	return MetadataLockLostError{errors.Errorf("the backups metadata lock has been taken over by someone else, " +
+		"aborting not to interfere with their changes")}
+}
+
+func (err MetadataLockLostError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// MetadataLock serializes the operations that modify the backups metadata, such as backup-mark and delete,
+// so they don't overwrite each other's changes.
+type MetadataLock struct {
+	lease       *storageLease
+	releaseOnce sync.Once
+}
+
+var (
+	heldMetadataLocksMutex sync.Mutex
+	heldMetadataLocks      = make(map[*MetadataLock]struct{})
+)
+
+// AcquireMetadataLock takes the lock in the storage root folder. If the lock is held by someone else,
+// it waits for WALG_METADATA_LOCK_TIMEOUT. Lock objects left by crashed processes expire after a while.
+func AcquireMetadataLock(rootFolder storage.Folder) (*MetadataLock, error) {
+	timeout, err := conf.GetDurationSetting(conf.MetadataLockTimeoutSetting)
+	if err != nil {
+		return nil, err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to get hostname for the metadata lock: %v", err)
+	}
+	lock := &MetadataLock{
+		lease: newStorageLease(rootFolder, MetadataLockPath, MetadataLockTTL, uuid.New().String(), hostname),
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		acquired, holder, err := lock.lease.tryAcquire()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to acquire the metadata lock")
+		}
+		if acquired {
+			lock.lease.startRefresh()
+			heldMetadataLocksMutex.Lock()
+			heldMetadataLocks[lock] = struct{}{}
+			heldMetadataLocksMutex.Unlock()
+			return lock, nil
+		}
+		if holder == nil {
+			// the lock object has been removed right after our write, try again
+			holder = &leaseRecord{Hostname: "unknown"}
+		}
+		if time.Now().After(deadline) {
+			return nil, newMetadataLockedError(holder)
+		}
+		tracelog.InfoLogger.Printf("Backups metadata is locked by %s, waiting", holder.Hostname)
+		time.Sleep(metadataLockRetryInterval)
+	}
+}
+
+// Release removes the lock object unless it has been taken over by someone else
+func (lock *MetadataLock) Release() {
+	lock.releaseOnce.Do(func() {
+		heldMetadataLocksMutex.Lock()
+		delete(heldMetadataLocks, lock)
+		heldMetadataLocksMutex.Unlock()
+		lock.lease.release()
+	})
+}
+
+// ExitReleasingLocks releases the metadata locks held by the process and exits. The deferred releases don't run
+// when the process exits, so the commands holding the lock must exit this way.
+func ExitReleasingLocks(code int) {
+	heldMetadataLocksMutex.Lock()
+	locks := make([]*MetadataLock, 0, len(heldMetadataLocks))
+	for lock := range heldMetadataLocks {
+		locks = append(locks, lock)
+	}
+	heldMetadataLocksMutex.Unlock()
+	for _, lock := range locks {
+		lock.Release()
+	}
+	os.Exit(code)
+}
+
+// FatalOnErrorReleasingLocks is tracelog.ErrorLogger.FatalOnError for the commands holding the metadata lock
+func FatalOnErrorReleasingLocks(err error) {
+	if err == nil {
+		return
+	}
+	tracelog.ErrorLogger.PrintError(err)
+	ExitReleasingLocks(1)
+}
+
+// Lost is closed when the lock is taken over by someone else, e.g. after the holder has been paused for longer
+// than MetadataLockTTL. The holder should stop modifying the metadata then.
+func (lock *MetadataLock) Lost() <-chan struct{} {
+	return lock.lease.Lost()
+}
+
+// Check returns MetadataLockLostError if the lock has been taken over, the holder checks it before each modification
+func (lock *MetadataLock) Check() error {
+	select {
+	case <-lock.Lost():
+		return newMetadataLockLostError()
+	default:
+		return nil
+	}
+}
+
+// checkHeldMetadataLocks checks all the metadata locks held by the process. It is used by the modifications
+// that don't know the lock they are made under, such as the deletion of the backups.
+func checkHeldMetadataLocks() error {
+	heldMetadataLocksMutex.Lock()
+	defer heldMetadataLocksMutex.Unlock()
+	for lock := range heldMetadataLocks {
+		if err := lock.Check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ModifyDto fetches the DTO from path, applies modify to it and uploads the result. On the storages that support
+// conditional writes the upload succeeds only if the object hasn't been changed since it was fetched,
+// otherwise the modification is retried. Elsewhere the caller should hold the MetadataLock.
+func ModifyDto(folder storage.Folder, dto interface{}, path string, modify func() error) error {
+	conditionalFolder, ok := conditionalFolderOf(folder)
+	if !ok {
+		err := FetchDto(folder, dto, path)
+		if err != nil {
+			return err
+		}
+		err = modify()
+		if err != nil {
+			return err
+		}
+		return UploadDto(folder, dto, path)
+	}
+
+	serializer, err := NewDtoSerializer()
+	if err != nil {
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		reader, version, err := conditionalFolder.ReadObjectVersion(path)
+		if err != nil {
+			return err
+		}
+		// the DTO may be left filled by the previous attempt
+		dtoValue := reflect.ValueOf(dto).Elem()
+		dtoValue.Set(reflect.Zero(dtoValue.Type()))
+		err = serializer.Unmarshal(reader, dto)
+		utility.LoggedClose(reader, "")
+		if err != nil {
+			return errors.Wrapf(err, "failed to fetch dto from %s", path)
+		}
+
+		err = modify()
+		if err != nil {
+			return err
+		}
+		content, err := serializer.Marshal(dto)
+		if err != nil {
+			return err
+		}
+		err = conditionalFolder.PutObjectIfVersion(context.Background(), path, content, version)
+		if _, ok := err.(storage.PreconditionFailedError); ok && attempt < modifyDtoAttempts {
+			tracelog.WarningLogger.Printf("%s has been modified concurrently, retrying", path)
+			continue
+		}
+		return err
+	}
+}
+
+// LockMetadataForDeletion takes the metadata lock for a confirmed deletion, so the backups can't be marked
+// as permanent while they are being deleted. It returns the function that releases the lock.
+func LockMetadataForDeletion(rootFolder storage.Folder, confirmed bool) func() {
+	if !confirmed {
+		return func() {}
+	}
+	lock, err := AcquireMetadataLock(rootFolder)
+	tracelog.ErrorLogger.FatalfOnError("Failed to lock the backups metadata: %v", err)
+	return lock.Release
+}
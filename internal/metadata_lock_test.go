@@ -0,0 +1,84 @@
+package internal_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"golang.org/x/time/rate"
+)
+
+func TestAcquireMetadataLock(t *testing.T) {
+	internal.ConfigureSettings("")
+	conf.InitConfig()
+	viper.Set(conf.MetadataLockTimeoutSetting, "0s")
+	defer viper.Set(conf.MetadataLockTimeoutSetting, "1m")
+
+	folder := memory.NewFolder("", memory.NewKVS())
+	lock, err := internal.AcquireMetadataLock(folder)
+	require.NoError(t, err)
+
+	_, err = internal.AcquireMetadataLock(folder)
+	assert.IsType(t, internal.MetadataLockedError{}, err)
+
+	lock.Release()
+	// the lock may be released again on the error path
+	lock.Release()
+	exists, err := folder.Exists(internal.MetadataLockPath)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	lock, err = internal.AcquireMetadataLock(folder)
+	require.NoError(t, err)
+	lock.Release()
+}
+
+func TestModifyDto_RetriesConcurrentModification(t *testing.T) {
+	internal.ConfigureSettings("")
+	conf.InitConfig()
+
+	type sentinel struct {
+		IsPermanent bool
+		UserData    string
+	}
+	// the conditional writes of the storage are used through the folder wrappers too
+	wraps := map[string]func(storage.Folder) storage.Folder{
+		"storage": func(folder storage.Folder) storage.Folder { return folder },
+		"wrapped": func(folder storage.Folder) storage.Folder {
+			folder = internal.NewPermissionAwareFolder(folder)
+			folder = internal.NewLimitedFolder(folder, rate.NewLimiter(rate.Inf, 1024))
+			return internal.NewHedgedFolder(folder, 95, time.Second, 1024).GetSubFolder("prefix")
+		},
+	}
+	for name, wrap := range wraps {
+		t.Run(name, func(t *testing.T) {
+			folder := wrap(memory.NewFolder("", memory.NewKVS()))
+			require.NoError(t, internal.UploadDto(folder, sentinel{}, "sentinel.json"))
+
+			attempts := 0
+			var dto sentinel
+			err := internal.ModifyDto(folder, &dto, "sentinel.json", func() error {
+				attempts++
+				if attempts == 1 {
+					// someone else changes the sentinel in between
+					return folder.PutObject("sentinel.json", strings.NewReader(`{"UserData":"concurrent"}`))
+				}
+				dto.IsPermanent = true
+				return nil
+			})
+			require.NoError(t, err)
+
+			var result sentinel
+			require.NoError(t, internal.FetchDto(folder, &result, "sentinel.json"))
+			assert.Equal(t, 2, attempts)
+			assert.Equal(t, sentinel{IsPermanent: true, UserData: "concurrent"}, result)
+		})
+	}
+}
@@ -113,6 +113,18 @@ func EnsureSingleStorageIsUsed(folder storage.Folder) error {
 	return nil
 }
 
+// ConditionalFolder provides the folder of the single used storage, if this storage supports conditional writes.
+// The error tells why the conditional writes can't be used.
+func ConditionalFolder(folder storage.Folder) (storage.ConditionalFolder, error) {
+	if mf, ok := folder.(Folder); ok {
+		if len(mf.usedFolders) != 1 {
+			return nil, fmt.Errorf("the conditional writes need a single storage, but %d are used", len(mf.usedFolders))
+		}
+		folder = mf.usedFolders[0].Folder
+	}
+	return storage.AsConditional(folder)
+}
+
 // SetPolicies makes a copy of the Folder that uses new policies.Policies.
 func SetPolicies(folder storage.Folder, policies policies.Policies) storage.Folder {
 	if mf, ok := folder.(Folder); ok {
@@ -383,6 +395,33 @@ func (mf Folder) ListFolderAll() (objects []storage.Object, subFolders []storage
 	return objects, subFolders, nil
 }
 
+// ListFolderWithPrefix lists only the objects with the prefix if a single storage is used, the listings of several
+// storages are merged by ListFolder according to the list policy and filtered
+func (mf Folder) ListFolderWithPrefix(prefix string) ([]storage.Object, error) {
+	if len(mf.usedFolders) != 1 {
+		objects, _, err := mf.ListFolder()
+		if err != nil {
+			return nil, err
+		}
+		return storage.FilterObjectsByPrefix(objects, prefix), nil
+	}
+
+	folder := mf.usedFolders[0]
+	objects, err := storage.ListFolderWithPrefix(folder.Folder, prefix)
+	if err != nil {
+		mf.statsCollector.ReportOperationResult(folder.StorageName, stats.OperationList, false)
+		return nil, fmt.Errorf("list folder in storage %q: %w", folder.StorageName, err)
+	}
+	mf.statsCollector.ReportOperationResult(folder.StorageName, stats.OperationList, true)
+	for i, obj := range objects {
+		objects[i] = multiObject{
+			Object:      obj,
+			storageName: folder.StorageName,
+		}
+	}
+	return objects, nil
+}
+
 func (mf Folder) listSpecificFolder(folder NamedFolder) ([]storage.Object, []storage.Folder, error) {
 	objects, subFolders, err := folder.ListFolder()
 	if err != nil {
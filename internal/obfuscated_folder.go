@@ -0,0 +1,234 @@
+package internal
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const (
+	obfuscatedNameMacSalt        = "walg name mac"
+	obfuscatedNameEncryptionSalt = "walg name encryption"
+)
+
+// obfuscatedNameEncoding keeps the obfuscated names valid and case-insensitive object keys on every storage
+var obfuscatedNameEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// ConfigureNameObfuscation wraps the storage root folder with ObfuscatedFolder if WALG_OBFUSCATION_KEY is set
+func ConfigureNameObfuscation(folder storage.Folder) storage.Folder {
+	key := viper.GetString(conf.ObfuscationKeySetting)
+	if key == "" {
+		return folder
+	}
+	return storage.WithOptionalInterfaces(NewObfuscatedFolder(folder, []byte(key)), folder)
+}
+
+// obfuscatedNames encrypts the names deterministically, so the same name is obfuscated the same way on every host
+// with the key and the obfuscated name is revealed by decrypting it, without storing the names anywhere.
+// It's the SIV construction: the IV is the HMAC of the name, the name is encrypted with AES-CTR using that IV,
+// and the decrypted name is authenticated by recomputing its IV.
+type obfuscatedNames struct {
+	macKey []byte
+	block  cipher.Block
+}
+
+// ObfuscatedFolder hides the names of the objects and the folders from the storage provider, so the keys
+// in the bucket don't reveal the database names, the backup times or the hostnames. Each component
+// of the path is replaced with its encryption, GetPath and ListFolder return the logical paths.
+type ObfuscatedFolder struct {
+	folder storage.Folder
+	names  *obfuscatedNames
+	path   string
+}
+
+func NewObfuscatedFolder(root storage.Folder, key []byte) *ObfuscatedFolder {
+	block, err := aes.NewCipher(deriveObfuscationKey(key, obfuscatedNameEncryptionSalt))
+	tracelog.ErrorLogger.FatalOnError(err)
+	names := &obfuscatedNames{
+		macKey: deriveObfuscationKey(key, obfuscatedNameMacSalt),
+		block:  block,
+	}
+	return &ObfuscatedFolder{folder: root, names: names, path: root.GetPath()}
+}
+
+func deriveObfuscationKey(key []byte, salt string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(salt))
+	return mac.Sum(nil)
+}
+
+func (folder *ObfuscatedFolder) GetPath() string {
+	return folder.path
+}
+
+func (folder *ObfuscatedFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	components := splitObjectPath(subFolderRelativePath)
+	if len(components) == 0 {
+		return storage.WithOptionalInterfaces(folder, folder.folder)
+	}
+	subFolder := folder.folder.GetSubFolder(folder.names.obfuscatePath(components))
+	return storage.WithOptionalInterfaces(&ObfuscatedFolder{
+		folder: subFolder,
+		names:  folder.names,
+		path:   folder.path + strings.Join(components, "/") + "/",
+	}, subFolder)
+}
+
+func (folder *ObfuscatedFolder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
+	obfuscatedObjects, obfuscatedSubFolders, err := folder.folder.ListFolder()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, object := range obfuscatedObjects {
+		name, ok := folder.names.reveal(object.GetName())
+		if !ok {
+			continue
+		}
+		objects = append(objects, storage.NewLocalObject(name, object.GetLastModified(), object.GetSize()))
+	}
+	for _, subFolder := range obfuscatedSubFolders {
+		obfuscatedName := strings.Trim(strings.TrimPrefix(subFolder.GetPath(), folder.folder.GetPath()), "/")
+		name, ok := folder.names.reveal(obfuscatedName)
+		if !ok {
+			continue
+		}
+		subFolders = append(subFolders, folder.GetSubFolder(name))
+	}
+	return objects, subFolders, nil
+}
+
+// ListFolderWithPrefix lists the whole folder, as the prefix of the name doesn't match the prefix of its obfuscated key
+func (folder *ObfuscatedFolder) ListFolderWithPrefix(prefix string) ([]storage.Object, error) {
+	objects, _, err := folder.ListFolder()
+	if err != nil {
+		return nil, err
+	}
+	return storage.FilterObjectsByPrefix(objects, prefix), nil
+}
+
+func (folder *ObfuscatedFolder) DeleteObjects(objectRelativePaths []string) error {
+	obfuscatedPaths := make([]string, 0, len(objectRelativePaths))
+	for _, objectPath := range objectRelativePaths {
+		obfuscatedPaths = append(obfuscatedPaths, folder.names.obfuscatePath(splitObjectPath(objectPath)))
+	}
+	return folder.folder.DeleteObjects(obfuscatedPaths)
+}
+
+func (folder *ObfuscatedFolder) Exists(objectRelativePath string) (bool, error) {
+	return folder.folder.Exists(folder.names.obfuscatePath(splitObjectPath(objectRelativePath)))
+}
+
+func (folder *ObfuscatedFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	reader, err := folder.folder.ReadObject(folder.names.obfuscatePath(splitObjectPath(objectRelativePath)))
+	return reader, folder.revealNotFound(err, objectRelativePath)
+}
+
+func (folder *ObfuscatedFolder) ReadObjectVersion(objectRelativePath string) (io.ReadCloser, string, error) {
+	conditional, err := storage.AsConditional(folder.folder)
+	if err != nil {
+		return nil, "", err
+	}
+	reader, version, err := conditional.ReadObjectVersion(folder.names.obfuscatePath(splitObjectPath(objectRelativePath)))
+	return reader, version, folder.revealNotFound(err, objectRelativePath)
+}
+
+// revealNotFound reports the logical path of the missing object instead of the obfuscated one
+func (folder *ObfuscatedFolder) revealNotFound(err error, objectRelativePath string) error {
+	var notFoundErr storage.ObjectNotFoundError
+	if errors.As(err, &notFoundErr) {
+		return storage.NewObjectNotFoundError(folder.path + objectRelativePath)
+	}
+	return err
+}
+
+func (folder *ObfuscatedFolder) PutObject(name string, content io.Reader) error {
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+func (folder *ObfuscatedFolder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
+	return folder.folder.PutObjectWithContext(ctx, folder.names.obfuscatePath(splitObjectPath(name)), content)
+}
+
+func (folder *ObfuscatedFolder) PutObjectIfVersion(ctx context.Context, name string, content io.Reader,
+	version string) error {
+	conditional, err := storage.AsConditional(folder.folder)
+	if err != nil {
+		return err
+	}
+	return conditional.PutObjectIfVersion(ctx, folder.names.obfuscatePath(splitObjectPath(name)), content, version)
+}
+
+// PresignObject signs the obfuscated path, the URL doesn't reveal the logical name either
+func (folder *ObfuscatedFolder) PresignObject(objectRelativePath string, ttl time.Duration) (string, error) {
+	presignable, err := storage.AsPresignable(folder.folder)
+	if err != nil {
+		return "", err
+	}
+	return presignable.PresignObject(folder.names.obfuscatePath(splitObjectPath(objectRelativePath)), ttl)
+}
+
+func (folder *ObfuscatedFolder) CopyObject(srcPath string, dstPath string) error {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath, storage.CopyOptions{})
+}
+
+func (folder *ObfuscatedFolder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string,
+	options storage.CopyOptions) error {
+	return storage.CopyObjectWithContext(ctx, folder.folder, folder.names.obfuscatePath(splitObjectPath(srcPath)),
+		folder.names.obfuscatePath(splitObjectPath(dstPath)), options)
+}
+
+func splitObjectPath(objectPath string) []string {
+	var components []string
+	for _, component := range strings.Split(objectPath, "/") {
+		if component != "" && component != "." {
+			components = append(components, component)
+		}
+	}
+	return components
+}
+
+func (names *obfuscatedNames) obfuscate(name string) string {
+	mac := hmac.New(sha256.New, names.macKey)
+	mac.Write([]byte(name))
+	iv := mac.Sum(nil)[:aes.BlockSize]
+	obfuscated := make([]byte, aes.BlockSize+len(name))
+	copy(obfuscated, iv)
+	cipher.NewCTR(names.block, iv).XORKeyStream(obfuscated[aes.BlockSize:], []byte(name))
+	return obfuscatedNameEncoding.EncodeToString(obfuscated)
+}
+
+func (names *obfuscatedNames) obfuscatePath(components []string) string {
+	obfuscated := make([]string, 0, len(components))
+	for _, component := range components {
+		obfuscated = append(obfuscated, names.obfuscate(component))
+	}
+	return strings.Join(obfuscated, "/")
+}
+
+// reveal decrypts the obfuscated name, false if the name is not obfuscated by this key,
+// e.g. it's the object written without the obfuscation or with another key
+func (names *obfuscatedNames) reveal(obfuscated string) (string, bool) {
+	decoded, err := obfuscatedNameEncoding.DecodeString(obfuscated)
+	if err != nil || len(decoded) < aes.BlockSize {
+		return "", false
+	}
+	iv := decoded[:aes.BlockSize]
+	name := make([]byte, len(decoded)-aes.BlockSize)
+	cipher.NewCTR(names.block, iv).XORKeyStream(name, decoded[aes.BlockSize:])
+	if !hmac.Equal([]byte(names.obfuscate(string(name))), []byte(obfuscated)) {
+		return "", false
+	}
+	return string(name), true
+}
@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+func listObjectNames(t *testing.T, folder storage.Folder) []string {
+	objects, err := storage.ListFolderRecursively(folder)
+	require.NoError(t, err)
+	names := make([]string, 0, len(objects))
+	for _, object := range objects {
+		names = append(names, object.GetName())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestObfuscatedFolder_HidesNames(t *testing.T) {
+	root := memory.NewFolder("in_memory/", memory.NewKVS())
+	folder := NewObfuscatedFolder(root, []byte("key"))
+
+	backups := folder.GetSubFolder("db1.example.com/basebackups_005")
+	require.NoError(t, backups.PutObject("base_000000010000000000000002/metadata.json", strings.NewReader("{}")))
+	require.NoError(t, backups.PutObject("base_000000010000000000000002_backup_stop_sentinel.json",
+		strings.NewReader("{}")))
+
+	for _, name := range listObjectNames(t, root) {
+		assert.NotContains(t, name, "db1")
+		assert.NotContains(t, name, "base_")
+	}
+	assert.Equal(t, []string{
+		"db1.example.com/basebackups_005/base_000000010000000000000002/metadata.json",
+		"db1.example.com/basebackups_005/base_000000010000000000000002_backup_stop_sentinel.json",
+	}, listObjectNames(t, folder))
+	assert.Equal(t, "in_memory/db1.example.com/basebackups_005/", backups.GetPath())
+
+	exists, err := backups.Exists("base_000000010000000000000002_backup_stop_sentinel.json")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	_, err = backups.ReadObject("base_000000010000000000000003_backup_stop_sentinel.json")
+	assert.ErrorContains(t, err, "base_000000010000000000000003")
+}
+
+func TestObfuscatedFolder_RevealsNamesWithTheKey(t *testing.T) {
+	root := memory.NewFolder("in_memory/", memory.NewKVS())
+	folder := NewObfuscatedFolder(root, []byte("key"))
+	require.NoError(t, folder.PutObject("wal_005/000000010000000000000001.lz4", strings.NewReader("wal")))
+	// the names are not stored anywhere, the storage keeps only the object itself
+	assert.Len(t, listObjectNames(t, root), 1)
+
+	otherHostFolder := NewObfuscatedFolder(root, []byte("key"))
+	assert.Equal(t, []string{"wal_005/000000010000000000000001.lz4"}, listObjectNames(t, otherHostFolder))
+
+	wrongKeyFolder := NewObfuscatedFolder(root, []byte("wrong key"))
+	assert.Empty(t, listObjectNames(t, wrongKeyFolder))
+}
+
+// wrappingFolder wraps the errors of the folder, like the retrying and the logging folders do
+type wrappingFolder struct {
+	storage.Folder
+}
+
+func (folder wrappingFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	reader, err := folder.Folder.ReadObject(objectRelativePath)
+	return reader, errors.Wrap(err, "read object")
+}
+
+func TestObfuscatedFolder_RevealsWrappedNotFound(t *testing.T) {
+	root := wrappingFolder{memory.NewFolder("in_memory/", memory.NewKVS())}
+	folder := NewObfuscatedFolder(root, []byte("key"))
+
+	_, err := folder.ReadObject("basebackups_005/base_000000010000000000000003_backup_stop_sentinel.json")
+	var notFoundErr storage.ObjectNotFoundError
+	assert.ErrorAs(t, err, &notFoundErr)
+	assert.ErrorContains(t, err, "base_000000010000000000000003")
+}
+
+func TestObfuscatedFolder_DeletesAndCopies(t *testing.T) {
+	root := memory.NewFolder("in_memory/", memory.NewKVS())
+	folder := NewObfuscatedFolder(root, []byte("key"))
+	require.NoError(t, folder.PutObject("a/1", strings.NewReader("1")))
+
+	require.NoError(t, folder.CopyObject("a/1", "b/2"))
+	require.NoError(t, folder.DeleteObjects([]string{"a/1"}))
+
+	assert.Equal(t, []string{"b/2"}, listObjectNames(t, folder))
+}
+
+func TestObfuscatedFolder_KeepsOptionalInterfaces(t *testing.T) {
+	viper.Set(conf.ObfuscationKeySetting, "key")
+	defer viper.Set(conf.ObfuscationKeySetting, "")
+
+	root := memory.NewFolder("in_memory/", memory.NewKVS())
+	folder := ConfigureNameObfuscation(root)
+	conditional, ok := folder.GetSubFolder("a").(storage.ConditionalFolder)
+	require.True(t, ok)
+	require.NoError(t, conditional.PutObjectIfVersion(context.Background(), "b", strings.NewReader("1"), ""))
+	_, _, err := conditional.ReadObjectVersion("b")
+	require.NoError(t, err)
+	_, _, err = conditional.ReadObjectVersion("c")
+	assert.ErrorContains(t, err, "in_memory/a/c")
+	assert.Equal(t, []string{"a/b"}, listObjectNames(t, folder))
+	_, isPresignable := folder.(storage.PresignableFolder)
+	assert.False(t, isPresignable)
+
+	presignable, ok := ConfigureNameObfuscation(presignableFolder{root}).GetSubFolder("a").(storage.PresignableFolder)
+	require.True(t, ok)
+	url, err := presignable.PresignObject("b", time.Hour)
+	require.NoError(t, err)
+	assert.NotContains(t, url, "in_memory/a/b", "the URL has the obfuscated path")
+}
@@ -0,0 +1,184 @@
+package objcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wal-g/tracelog"
+)
+
+const (
+	checksumSuffix = ".sha256"
+	tmpSuffix      = ".tmp"
+)
+
+// DiskCache keeps downloaded objects in a local directory. Each entry is stored alongside its SHA-256 checksum,
+// which is verified on every hit. The modification time of an entry is bumped on access, so the least recently used
+// entries are evicted first when the total size exceeds the limit. All the state lives on disk, so the cache is
+// shared between short-lived processes like wal-fetch.
+type DiskCache struct {
+	dir     string
+	maxSize int64
+}
+
+func NewDiskCache(dir string, maxSize int64) (*DiskCache, error) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("object cache size must be positive, got %d", maxSize)
+	}
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, fmt.Errorf("create object cache directory %q: %w", dir, err)
+	}
+	return &DiskCache{dir: dir, maxSize: maxSize}, nil
+}
+
+// Get returns the cached object content if it is present and its checksum matches.
+// Corrupted entries are removed from the cache.
+func (c *DiskCache) Get(key string) (io.ReadCloser, bool) {
+	dataPath := c.entryPath(key)
+	expectedSum, err := os.ReadFile(dataPath + checksumSuffix)
+	if err != nil {
+		return nil, false
+	}
+	file, err := os.Open(dataPath)
+	if err != nil {
+		return nil, false
+	}
+
+	hasher := sha256.New()
+	_, err = io.Copy(hasher, file)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil || !bytes.Equal(expectedSum, []byte(hex.EncodeToString(hasher.Sum(nil)))) {
+		_ = file.Close()
+		tracelog.WarningLogger.Printf("Object cache entry for %s is corrupted, removing it", key)
+		c.remove(dataPath)
+		return nil, false
+	}
+
+	now := time.Now()
+	_ = os.Chtimes(dataPath, now, now)
+	return file, true
+}
+
+func (c *DiskCache) Contains(key string) bool {
+	_, err := os.Stat(c.entryPath(key) + checksumSuffix)
+	return err == nil
+}
+
+// NewEntryWriter starts a new cache entry. The entry becomes visible only after Commit.
+func (c *DiskCache) NewEntryWriter(key string) (*EntryWriter, error) {
+	file, err := os.CreateTemp(c.dir, filepath.Base(c.entryPath(key))+"*"+tmpSuffix)
+	if err != nil {
+		return nil, err
+	}
+	return &EntryWriter{cache: c, key: key, file: file, hasher: sha256.New()}, nil
+}
+
+func (c *DiskCache) entryPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+func (c *DiskCache) remove(dataPath string) {
+	_ = os.Remove(dataPath + checksumSuffix)
+	_ = os.Remove(dataPath)
+}
+
+type cacheEntry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// evict removes the least recently used entries until the cache fits into its size limit.
+func (c *DiskCache) evict() {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to list object cache directory %q: %v", c.dir, err)
+		return
+	}
+
+	var entries []cacheEntry
+	var totalSize int64
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if strings.HasSuffix(name, checksumSuffix) || strings.HasSuffix(name, tmpSuffix) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, cacheEntry{filepath.Join(c.dir, name), info.Size(), info.ModTime()})
+		totalSize += info.Size()
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+	for i := 0; i < len(entries) && totalSize > c.maxSize; i++ {
+		tracelog.DebugLogger.Printf("Evicting %s from the object cache", entries[i].path)
+		c.remove(entries[i].path)
+		totalSize -= entries[i].size
+	}
+}
+
+// EntryWriter writes the content of a new cache entry and computes its checksum on the fly.
+type EntryWriter struct {
+	cache  *DiskCache
+	key    string
+	file   *os.File
+	hasher hash.Hash
+}
+
+func (w *EntryWriter) Write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	w.hasher.Write(p[:n])
+	return n, err
+}
+
+// Commit atomically publishes the entry and evicts old entries if the cache is full.
+func (w *EntryWriter) Commit() error {
+	tmpPath := w.file.Name()
+	err := w.file.Close()
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	// file system timestamps are too coarse to order entries written in a row
+	now := time.Now()
+	_ = os.Chtimes(tmpPath, now, now)
+
+	dataPath := w.cache.entryPath(w.key)
+	err = os.Rename(tmpPath, dataPath)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	err = os.WriteFile(dataPath+checksumSuffix, []byte(hex.EncodeToString(w.hasher.Sum(nil))), 0600)
+	if err != nil {
+		w.cache.remove(dataPath)
+		return err
+	}
+
+	w.cache.evict()
+	return nil
+}
+
+// Abort drops the unfinished entry.
+func (w *EntryWriter) Abort() {
+	_ = w.file.Close()
+	_ = os.Remove(w.file.Name())
+}
@@ -0,0 +1,110 @@
+package objcache
+
+import (
+	"io"
+	"path"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// Folder serves ReadObject from the DiskCache when possible and puts the downloaded objects into it otherwise.
+// All the other operations are passed to the underlying folder as is.
+type Folder struct {
+	storage.Folder
+	cache *DiskCache
+}
+
+func NewFolder(folder storage.Folder, cache *DiskCache) *Folder {
+	return &Folder{Folder: folder, cache: cache}
+}
+
+func (f *Folder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	return NewFolder(f.Folder.GetSubFolder(subFolderRelativePath), f.cache)
+}
+
+func (f *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	key := f.cacheKey(objectRelativePath)
+	if cached, ok := f.cache.Get(key); ok {
+		tracelog.DebugLogger.Printf("Object cache hit: %s", key)
+		return cached, nil
+	}
+
+	readCloser, err := f.Folder.ReadObject(objectRelativePath)
+	if err != nil {
+		return nil, err
+	}
+	entryWriter, err := f.cache.NewEntryWriter(key)
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to start object cache entry for %s: %v", key, err)
+		return readCloser, nil
+	}
+	return &teeReadCloser{source: readCloser, entryWriter: entryWriter}, nil
+}
+
+// Prefetch downloads the object into the cache unless it is already there.
+func (f *Folder) Prefetch(objectRelativePath string) error {
+	if f.cache.Contains(f.cacheKey(objectRelativePath)) {
+		return nil
+	}
+	readCloser, err := f.ReadObject(objectRelativePath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(io.Discard, readCloser)
+	closeErr := readCloser.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// PrefetchInBackground downloads the objects into the cache one by one without blocking the caller.
+func (f *Folder) PrefetchInBackground(objectRelativePaths ...string) {
+	go func() {
+		for _, objectRelativePath := range objectRelativePaths {
+			err := f.Prefetch(objectRelativePath)
+			if err != nil {
+				tracelog.WarningLogger.Printf("Failed to prefetch %s into the object cache: %v", objectRelativePath, err)
+			}
+		}
+	}()
+}
+
+func (f *Folder) cacheKey(objectRelativePath string) string {
+	return path.Join(f.GetPath(), objectRelativePath)
+}
+
+// teeReadCloser copies everything read from the source to the cache entry. The entry is committed only if the source
+// has been read till the end, so partially read objects never get into the cache.
+type teeReadCloser struct {
+	source      io.ReadCloser
+	entryWriter *EntryWriter
+	finished    bool
+	failed      bool
+}
+
+func (r *teeReadCloser) Read(p []byte) (int, error) {
+	n, err := r.source.Read(p)
+	if n > 0 && !r.failed {
+		if _, writeErr := r.entryWriter.Write(p[:n]); writeErr != nil {
+			tracelog.WarningLogger.Printf("Failed to write object cache entry: %v", writeErr)
+			r.failed = true
+		}
+	}
+	if err == io.EOF && !r.finished && !r.failed {
+		r.finished = true
+		if commitErr := r.entryWriter.Commit(); commitErr != nil {
+			tracelog.WarningLogger.Printf("Failed to commit object cache entry: %v", commitErr)
+		}
+	}
+	return n, err
+}
+
+func (r *teeReadCloser) Close() error {
+	if !r.finished {
+		r.finished = true
+		r.entryWriter.Abort()
+	}
+	return r.source.Close()
+}
@@ -0,0 +1,91 @@
+package objcache_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/objcache"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+func readAll(t *testing.T, folder *objcache.Folder, name string) string {
+	reader, err := folder.ReadObject(name)
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	return string(content)
+}
+
+func TestFolder_ReadObject(t *testing.T) {
+	underlying := memory.NewFolder("", memory.NewKVS())
+	cache, err := objcache.NewDiskCache(t.TempDir(), 1024)
+	require.NoError(t, err)
+	folder := objcache.NewFolder(underlying, cache).GetSubFolder("wal_005").(*objcache.Folder)
+
+	require.NoError(t, underlying.PutObject("wal_005/000000010000000000000001.lz4", strings.NewReader("segment")))
+	assert.Equal(t, "segment", readAll(t, folder, "000000010000000000000001.lz4"))
+
+	t.Run("serves cached objects", func(t *testing.T) {
+		require.NoError(t, underlying.DeleteObjects([]string{"wal_005/000000010000000000000001.lz4"}))
+		assert.Equal(t, "segment", readAll(t, folder, "000000010000000000000001.lz4"))
+	})
+
+	t.Run("does not cache partially read objects", func(t *testing.T) {
+		require.NoError(t, underlying.PutObject("wal_005/000000010000000000000002.lz4", strings.NewReader("segment")))
+		reader, err := folder.ReadObject("000000010000000000000002.lz4")
+		require.NoError(t, err)
+		_, err = reader.Read(make([]byte, 3))
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+
+		require.NoError(t, underlying.DeleteObjects([]string{"wal_005/000000010000000000000002.lz4"}))
+		_, err = folder.ReadObject("000000010000000000000002.lz4")
+		assert.Error(t, err)
+	})
+}
+
+func TestFolder_CorruptedEntryIsRefetched(t *testing.T) {
+	cacheDir := t.TempDir()
+	underlying := memory.NewFolder("", memory.NewKVS())
+	cache, err := objcache.NewDiskCache(cacheDir, 1024)
+	require.NoError(t, err)
+	folder := objcache.NewFolder(underlying, cache)
+
+	require.NoError(t, underlying.PutObject("binlog.000001", strings.NewReader("binlog")))
+	require.NoError(t, folder.Prefetch("binlog.000001"))
+
+	entries, err := filepath.Glob(filepath.Join(cacheDir, "*"))
+	require.NoError(t, err)
+	for _, entry := range entries {
+		if filepath.Ext(entry) == "" {
+			require.NoError(t, os.WriteFile(entry, []byte("garbage"), 0600))
+		}
+	}
+
+	assert.Equal(t, "binlog", readAll(t, folder, "binlog.000001"))
+}
+
+func TestDiskCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	underlying := memory.NewFolder("", memory.NewKVS())
+	cache, err := objcache.NewDiskCache(t.TempDir(), 10)
+	require.NoError(t, err)
+	folder := objcache.NewFolder(underlying, cache)
+
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, underlying.PutObject(name, bytes.NewReader(make([]byte, 4))))
+		require.NoError(t, folder.Prefetch(name))
+	}
+
+	require.NoError(t, underlying.DeleteObjects([]string{"a", "b", "c"}))
+	_, err = folder.ReadObject("a")
+	assert.Error(t, err)
+	assert.Len(t, readAll(t, folder, "b"), 4)
+	assert.Len(t, readAll(t, folder, "c"), 4)
+}
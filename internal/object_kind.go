@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"strings"
+
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/walz"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// IsDataObject tells the data objects (WAL segments, tar parts, binlogs, etc.) from the metadata objects
+// (sentinels, locks, reports, etc.). The data objects are encrypted when the encryption is on and never rewritten
+// with another content, the metadata objects are neither. Every object in the tar partitions folder of a backup
+// is a tar part, including the uncompressed part_raw_NNN.tar and the streamed part_NNN.tar ones. The other data
+// objects are compressed or wrapped in the walz envelope, so they are recognized by the extension.
+func IsDataObject(objectPath string) bool {
+	if isWalzFile(objectPath) || strings.Contains(objectPath, TarPartitionFolderName) {
+		return true
+	}
+	extension := utility.GetFileExtension(objectPath)
+	for _, decompressor := range compression.Decompressors {
+		if extension == decompressor.FileExtension() {
+			return true
+		}
+	}
+	return false
+}
+
+func isWalzFile(objectPath string) bool {
+	return strings.HasSuffix(objectPath, "."+walz.FileExtension)
+}
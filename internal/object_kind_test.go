@@ -0,0 +1,20 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/utility"
+)
+
+func TestIsDataObject(t *testing.T) {
+	assert.True(t, internal.IsDataObject(utility.WalPath+"000000010000000000000001.lz4"))
+	assert.True(t, internal.IsDataObject(utility.WalPath+"000000010000000000000001.walz"))
+	assert.True(t, internal.IsDataObject(utility.BaseBackupPath+"base_1/tar_partitions/part_1.tar.lzma"))
+	assert.True(t, internal.IsDataObject(utility.BaseBackupPath+"base_1/tar_partitions/part_raw_002.tar"))
+	assert.True(t, internal.IsDataObject(utility.BaseBackupPath+"base_1/tar_partitions/part_003.tar"))
+	assert.False(t, internal.IsDataObject(utility.BaseBackupPath+"base_1"+utility.SentinelSuffix))
+	assert.False(t, internal.IsDataObject(internal.MetadataLockPath))
+	assert.False(t, internal.IsDataObject(utility.WalPath+"00000002.history"))
+}
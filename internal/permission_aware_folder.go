@@ -0,0 +1,194 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const (
+	listOperation   = "list"
+	readOperation   = "read"
+	writeOperation  = "write"
+	deleteOperation = "delete"
+)
+
+// transientDenialCodes are the codes of the S3 403 responses caused by the request rather than by the permissions,
+// e.g. by the expired session token or the clock skew, so the operation is retried by the next request
+var transientDenialCodes = map[string]bool{
+	"ExpiredToken":          true,
+	"RequestExpired":        true,
+	"RequestTimeTooSkewed":  true,
+	"SignatureDoesNotMatch": true,
+}
+
+// PermissionAwareFolder degrades gracefully when the storage credentials lack some permissions, e.g. with the IAM role
+// that may only read the backups. Once the storage denies to list, write or delete objects, the operation is disabled
+// with the warning and then fails with storage.PermissionDeniedError without requests to the storage, while the other
+// operations proceed. The denied reads aren't disabled: S3 also denies to read the missing objects without
+// the permission to list them, which is the usual case e.g. for wal-fetch. The transient denials, like the expired
+// session token or the clock skew, don't disable the operation either.
+type PermissionAwareFolder struct {
+	storage.Folder
+	denied *deniedOperations
+}
+
+// NewPermissionAwareFolder wraps the folder keeping the conditional writes and presigning of the underlying folder
+func NewPermissionAwareFolder(folder storage.Folder) storage.Folder {
+	return wrapPermissionAware(folder, &deniedOperations{errs: make(map[string]error)})
+}
+
+func wrapPermissionAware(folder storage.Folder, denied *deniedOperations) storage.Folder {
+	return storage.WithOptionalInterfaces(&PermissionAwareFolder{Folder: folder, denied: denied}, folder)
+}
+
+func (folder *PermissionAwareFolder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	return wrapPermissionAware(folder.Folder.GetSubFolder(subFolderRelativePath), folder.denied)
+}
+
+func (folder *PermissionAwareFolder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
+	if err = folder.denied.check(listOperation); err != nil {
+		return nil, nil, err
+	}
+	objects, subFolders, err = folder.Folder.ListFolder()
+	if err != nil {
+		return nil, nil, folder.denied.detect(listOperation, err)
+	}
+	for i := range subFolders {
+		subFolders[i] = wrapPermissionAware(subFolders[i], folder.denied)
+	}
+	return objects, subFolders, nil
+}
+
+func (folder *PermissionAwareFolder) ListFolderWithPrefix(prefix string) ([]storage.Object, error) {
+	if err := folder.denied.check(listOperation); err != nil {
+		return nil, err
+	}
+	objects, err := storage.ListFolderWithPrefix(folder.Folder, prefix)
+	if err != nil {
+		return nil, folder.denied.detect(listOperation, err)
+	}
+	return objects, nil
+}
+
+func (folder *PermissionAwareFolder) DeleteObjects(objectRelativePaths []string) error {
+	if err := folder.denied.check(deleteOperation); err != nil {
+		return err
+	}
+	return folder.denied.detect(deleteOperation, folder.Folder.DeleteObjects(objectRelativePaths))
+}
+
+func (folder *PermissionAwareFolder) Exists(objectRelativePath string) (bool, error) {
+	exists, err := folder.Folder.Exists(objectRelativePath)
+	return exists, folder.denied.detect(readOperation, err)
+}
+
+func (folder *PermissionAwareFolder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	readCloser, err := folder.Folder.ReadObject(objectRelativePath)
+	return readCloser, folder.denied.detect(readOperation, err)
+}
+
+func (folder *PermissionAwareFolder) PutObject(name string, content io.Reader) error {
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+func (folder *PermissionAwareFolder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
+	if err := folder.denied.check(writeOperation); err != nil {
+		return err
+	}
+	return folder.denied.detect(writeOperation, folder.Folder.PutObjectWithContext(ctx, name, content))
+}
+
+func (folder *PermissionAwareFolder) CopyObject(srcPath string, dstPath string) error {
+	if err := folder.denied.check(writeOperation); err != nil {
+		return err
+	}
+	return folder.denied.detect(writeOperation, folder.Folder.CopyObject(srcPath, dstPath))
+}
+
+func (folder *PermissionAwareFolder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string,
+	options storage.CopyOptions) error {
+	if err := folder.denied.check(writeOperation); err != nil {
+		return err
+	}
+	return folder.denied.detect(writeOperation,
+		storage.CopyObjectWithContext(ctx, folder.Folder, srcPath, dstPath, options))
+}
+
+func (folder *PermissionAwareFolder) ReadObjectVersion(objectRelativePath string) (io.ReadCloser, string, error) {
+	conditional, err := storage.AsConditional(folder.Folder)
+	if err != nil {
+		return nil, "", err
+	}
+	readCloser, version, err := conditional.ReadObjectVersion(objectRelativePath)
+	return readCloser, version, folder.denied.detect(readOperation, err)
+}
+
+func (folder *PermissionAwareFolder) PutObjectIfVersion(
+	ctx context.Context, name string, content io.Reader, version string,
+) error {
+	conditional, err := storage.AsConditional(folder.Folder)
+	if err != nil {
+		return err
+	}
+	if err := folder.denied.check(writeOperation); err != nil {
+		return err
+	}
+	return folder.denied.detect(writeOperation, conditional.PutObjectIfVersion(ctx, name, content, version))
+}
+
+func (folder *PermissionAwareFolder) PresignObject(objectRelativePath string, ttl time.Duration) (string, error) {
+	presignable, err := storage.AsPresignable(folder.Folder)
+	if err != nil {
+		return "", err
+	}
+	return presignable.PresignObject(objectRelativePath, ttl)
+}
+
+// deniedOperations is shared by all the folders of the storage
+type deniedOperations struct {
+	mutex sync.Mutex
+	errs  map[string]error
+}
+
+func (denied *deniedOperations) check(operation string) error {
+	denied.mutex.Lock()
+	defer denied.mutex.Unlock()
+	return denied.errs[operation]
+}
+
+// detect disables the operation if the storage has denied it. The warning is logged once per operation.
+func (denied *deniedOperations) detect(operation string, err error) error {
+	if err == nil || !storage.IsPermissionDenied(err) {
+		return err
+	}
+	deniedErr, ok := err.(storage.PermissionDeniedError)
+	if !ok {
+		deniedErr = storage.NewPermissionDeniedError(operation, err)
+	}
+	if operation == readOperation {
+		return deniedErr
+	}
+	if isTransientDenial(err) {
+		return err
+	}
+
+	denied.mutex.Lock()
+	defer denied.mutex.Unlock()
+	if _, ok := denied.errs[operation]; !ok {
+		tracelog.WarningLogger.Printf("The storage credentials lack the permission to %s objects, "+
+			"the commands which %s objects are disabled: %v", operation, operation, err)
+		denied.errs[operation] = deniedErr
+	}
+	return deniedErr
+}
+
+func isTransientDenial(err error) bool {
+	var codeErr interface{ Code() string }
+	return errors.As(err, &codeErr) && transientDenialCodes[codeErr.Code()]
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// readOnlyFolder denies to change the objects like the storage with the read-only credentials
+type readOnlyFolder struct {
+	storage.Folder
+	deleteCalls int
+}
+
+func (folder *readOnlyFolder) DeleteObjects([]string) error {
+	folder.deleteCalls++
+	return &os.PathError{Op: "remove", Path: "object", Err: os.ErrPermission}
+}
+
+func TestPermissionAwareFolder_DisablesDeniedOperation(t *testing.T) {
+	underlying := &readOnlyFolder{Folder: memory.NewFolder("in_memory/", memory.NewKVS())}
+	require.NoError(t, underlying.PutObject("wal_005/segment", strings.NewReader("wal")))
+	folder := NewPermissionAwareFolder(underlying)
+
+	err := folder.DeleteObjects([]string{"wal_005/segment"})
+	assert.True(t, storage.IsPermissionDenied(err))
+	err = folder.GetSubFolder("wal_005").DeleteObjects([]string{"segment"})
+	assert.IsType(t, storage.PermissionDeniedError{}, err)
+	assert.Equal(t, 1, underlying.deleteCalls, "the disabled operation must not reach the storage")
+
+	exists, err := folder.GetSubFolder("wal_005").Exists("segment")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	objects, _, err := folder.GetSubFolder("wal_005").ListFolder()
+	require.NoError(t, err)
+	assert.Len(t, objects, 1)
+}
+
+// deniedWriteFolder denies to write the objects with the S3 error code
+type deniedWriteFolder struct {
+	storage.Folder
+	code     string
+	putCalls int
+}
+
+func (folder *deniedWriteFolder) PutObjectWithContext(context.Context, string, io.Reader) error {
+	folder.putCalls++
+	return awserr.NewRequestFailure(awserr.New(folder.code, "denied", nil), http.StatusForbidden, "request")
+}
+
+func TestPermissionAwareFolder_DisablesOnlyAccessDenied(t *testing.T) {
+	testCases := []struct {
+		code     string
+		putCalls int
+	}{
+		{code: "AccessDenied", putCalls: 1},
+		{code: "ExpiredToken", putCalls: 2},
+		{code: "RequestTimeTooSkewed", putCalls: 2},
+		{code: "SignatureDoesNotMatch", putCalls: 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.code, func(t *testing.T) {
+			underlying := &deniedWriteFolder{Folder: memory.NewFolder("in_memory/", memory.NewKVS()), code: tc.code}
+			folder := NewPermissionAwareFolder(underlying)
+
+			for i := 0; i < 2; i++ {
+				err := folder.PutObjectWithContext(context.Background(), "object", strings.NewReader("data"))
+				assert.True(t, storage.IsPermissionDenied(err))
+			}
+			assert.Equal(t, tc.putCalls, underlying.putCalls)
+		})
+	}
+}
+
+func TestPermissionAwareFolder_KeepsConditionalWrites(t *testing.T) {
+	folder := NewPermissionAwareFolder(memory.NewFolder("in_memory/", memory.NewKVS()))
+
+	_, ok := folder.(storage.ConditionalFolder)
+	assert.True(t, ok)
+	_, ok = folder.GetSubFolder("sub").(storage.ConditionalFolder)
+	assert.True(t, ok)
+	_, ok = folder.(storage.PresignableFolder)
+	assert.False(t, ok)
+	_, ok = folder.GetSubFolder("sub").(storage.PrefixListingFolder)
+	assert.True(t, ok)
+}
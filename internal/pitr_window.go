@@ -0,0 +1,170 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	ForcePITRBreakFlag        = "force-pitr-break"
+	ForcePITRBreakDescription = "Delete even if the archive can't be restored to some point of " + conf.MinPITRWindowSetting
+)
+
+type PITRWindowError struct {
+	error
+}
+
+func newPITRWindowError(violation string) PITRWindowError {
+	return PITRWindowError{errors.Errorf("%s. Pass --%s to delete anyway", violation, ForcePITRBreakFlag)}
+}
+
+func (err PITRWindowError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// GetMinPITRWindow is the period before now the archive has to stay restorable to any point of, 0 if it isn't set
+func GetMinPITRWindow() (time.Duration, error) {
+	if _, ok := conf.GetSetting(conf.MinPITRWindowSetting); !ok {
+		return 0, nil
+	}
+	return conf.GetDurationSetting(conf.MinPITRWindowSetting)
+}
+
+// LogGapFinder describes the first gap in the WAL or binlogs archived since the start of the backup up to the start
+// of the next one, or up to the newest log archived if next is empty. It returns an empty string if there is no gap.
+type LogGapFinder func(backupName, nextBackupName string) (string, error)
+
+// CheckMinPITRWindowUnsupported refuses to delete the backups of the databases the minimum PITR window
+// isn't checked for, so the window set for them isn't silently ignored
+func CheckMinPITRWindowUnsupported() error {
+	if _, ok := conf.GetSetting(conf.MinPITRWindowSetting); ok {
+		return errors.Errorf("%s isn't supported for this database, unset it to delete the backups",
+			conf.MinPITRWindowSetting)
+	}
+	return nil
+}
+
+// CheckDeletionPITRWindow explains the deletion and refuses it if it breaks the minimum PITR window.
+// The deletion isn't explained if the window isn't set. The logs aren't checked for gaps if findLogGap is nil.
+func CheckDeletionPITRWindow(explain func() (*DeleteExplanation, error), findLogGap LogGapFinder,
+	forcePITRBreak bool) error {
+	window, err := GetMinPITRWindow()
+	if err != nil || window == 0 {
+		return err
+	}
+	explanation, err := explain()
+	if err != nil {
+		return err
+	}
+	return CheckPITRWindow(explanation, findLogGap, forcePITRBreak)
+}
+
+// CheckPITRWindow refuses the explained deletion if the archive couldn't be restored to some point
+// within WALG_MIN_PITR_WINDOW after it, unless forcePITRBreak is set
+func CheckPITRWindow(explanation *DeleteExplanation, findLogGap LogGapFinder, forcePITRBreak bool) error {
+	window, err := GetMinPITRWindow()
+	if err != nil || window == 0 || explanation.Refused != "" {
+		return err
+	}
+	violation, err := findPITRWindowViolation(explanation, window, utility.TimeNowCrossPlatformUTC(), findLogGap)
+	if err != nil || violation == "" {
+		return err
+	}
+	if forcePITRBreak {
+		tracelog.WarningLogger.Printf("%s, deleting anyway as --%s is passed", violation, ForcePITRBreakFlag)
+		return nil
+	}
+	return newPITRWindowError(violation)
+}
+
+// findPITRWindowViolation tells why the archive wouldn't be restorable to the whole window after the deletion,
+// empty if it would. The deletion which doesn't move the oldest point to restore to is never a violation.
+// The logs from the backup the window start is restored from are checked for gaps up to the next backup kept.
+func findPITRWindowViolation(explanation *DeleteExplanation, window time.Duration, now time.Time,
+	findLogGap LogGapFinder) (string, error) {
+	windowStart := now.Add(-window)
+	oldest, oldestAfter := findOldestRestorableBackups(explanation)
+	if oldest == nil || oldest == oldestAfter {
+		return "", nil
+	}
+	if oldestAfter == nil {
+		return fmt.Sprintf("delete %s would remove all the backups, so the archive couldn't be restored "+
+			"to any point of the minimum PITR window of %s", explanation.Rule, window), nil
+	}
+	if oldestAfter.Time.After(windowStart) {
+		return fmt.Sprintf("delete %s would leave %s taken at %s as the oldest backup to restore from, "+
+			"so the archive couldn't be restored to the points between %s and %s of the minimum PITR window of %s",
+			explanation.Rule, oldestAfter.BackupName, oldestAfter.Time.Format(time.RFC3339),
+			windowStart.Format(time.RFC3339), oldestAfter.Time.Format(time.RFC3339), window), nil
+	}
+	if findLogGap == nil {
+		return "", nil
+	}
+	start, next := findWindowStartBackups(explanation, oldestAfter, windowStart)
+	nextName := ""
+	if next != nil {
+		nextName = next.BackupName
+	}
+	gap, err := findLogGap(start.BackupName, nextName)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to check the logs after backup %s", start.BackupName)
+	}
+	if gap == "" {
+		return "", nil
+	}
+	return fmt.Sprintf("delete %s would leave %s taken at %s as the backup to restore the start of "+
+		"the minimum PITR window of %s from, but the logs after it have a gap: %s",
+		explanation.Rule, start.BackupName, start.Time.Format(time.RFC3339), window, gap), nil
+}
+
+// findWindowStartBackups finds the newest backup kept to restore the window start from and the backup kept
+// after it, nil if there is none. The backups older than oldestAfter can't be restored from after the deletion.
+func findWindowStartBackups(explanation *DeleteExplanation, oldestAfter *BackupDeleteDecision,
+	windowStart time.Time) (start, next *BackupDeleteDecision) {
+	for i := range explanation.Backups {
+		backup := &explanation.Backups[i]
+		if backup.Decision != DecisionKeep || backup.Time.Before(oldestAfter.Time) {
+			continue
+		}
+		if !backup.Time.After(windowStart) {
+			if start == nil || backup.Time.After(start.Time) {
+				start = backup
+			}
+		} else if next == nil || backup.Time.Before(next.Time) {
+			next = backup
+		}
+	}
+	return start, next
+}
+
+// findOldestRestorableBackups finds the oldest backup to restore from before and after the explained deletion.
+// 'delete target' keeps the WAL, so any backup left can be restored from; the other deletions remove the WAL
+// older than their target, so the older backups kept (i.e. the permanent ones) can't be rolled forward.
+func findOldestRestorableBackups(explanation *DeleteExplanation) (oldest, oldestAfter *BackupDeleteDecision) {
+	keepsWal := strings.HasPrefix(explanation.Rule, "target")
+	for i := range explanation.Backups {
+		backup := &explanation.Backups[i]
+		if oldest == nil || backup.Time.Before(oldest.Time) {
+			oldest = backup
+		}
+		if backup.Decision != DecisionKeep {
+			continue
+		}
+		if !keepsWal && explanation.Target != "" {
+			if backup.BackupName == explanation.Target {
+				oldestAfter = backup
+			}
+			continue
+		}
+		if oldestAfter == nil || backup.Time.Before(oldestAfter.Time) {
+			oldestAfter = backup
+		}
+	}
+	return oldest, oldestAfter
+}
@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindPITRWindowViolation(t *testing.T) {
+	now := time.Unix(1692800000, 0).UTC()
+	day := 24 * time.Hour
+	// the newest backups go first, like in the explanation
+	backups := func(decisions ...DeleteDecision) []BackupDeleteDecision {
+		result := make([]BackupDeleteDecision, 0, len(decisions))
+		for i, decision := range decisions {
+			result = append(result, BackupDeleteDecision{
+				BackupName: []string{"b1", "b2", "b3", "b4"}[i],
+				Time:       now.Add(-time.Duration(i*3+1) * day),
+				Decision:   decision,
+			})
+		}
+		return result
+	}
+
+	var tests = []struct {
+		name        string
+		explanation DeleteExplanation
+		violation   bool
+	}{
+		{
+			name: "retain keeps the window",
+			explanation: DeleteExplanation{Rule: "retain 3", Target: "b3",
+				Backups: backups(DecisionKeep, DecisionKeep, DecisionKeep, DecisionDelete)},
+		},
+		{
+			name: "retain breaks the window",
+			explanation: DeleteExplanation{Rule: "retain 2", Target: "b2",
+				Backups: backups(DecisionKeep, DecisionKeep, DecisionDelete, DecisionDelete)},
+			violation: true,
+		},
+		{
+			name: "older permanent backup doesn't keep the window",
+			explanation: DeleteExplanation{Rule: "retain 2", Target: "b2",
+				Backups: backups(DecisionKeep, DecisionKeep, DecisionDelete, DecisionKeep)},
+			violation: true,
+		},
+		{
+			name: "nothing to delete",
+			explanation: DeleteExplanation{Rule: "retain 5",
+				Backups: backups(DecisionKeep, DecisionKeep)},
+		},
+		{
+			name: "window is already broken",
+			explanation: DeleteExplanation{Rule: "retain 2", Target: "b2",
+				Backups: backups(DecisionKeep, DecisionKeep)},
+		},
+		{
+			name: "target in the middle keeps the WAL",
+			explanation: DeleteExplanation{Rule: "target", Target: "b3",
+				Backups: backups(DecisionKeep, DecisionKeep, DecisionDelete, DecisionKeep)},
+		},
+		{
+			name: "target of the oldest backup",
+			explanation: DeleteExplanation{Rule: "target", Target: "b2",
+				Backups: backups(DecisionKeep, DecisionDelete)},
+			violation: true,
+		},
+		{
+			name: "everything",
+			explanation: DeleteExplanation{Rule: "everything",
+				Backups: backups(DecisionDelete, DecisionDelete)},
+			violation: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violation, err := findPITRWindowViolation(&tt.explanation, 7*day, now, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.violation, violation != "", violation)
+		})
+	}
+}
+
+func TestFindPITRWindowViolationLogGap(t *testing.T) {
+	now := time.Unix(1692800000, 0).UTC()
+	day := 24 * time.Hour
+	explanation := DeleteExplanation{Rule: "retain 3", Target: "b3",
+		Backups: []BackupDeleteDecision{
+			{BackupName: "b1", Time: now.Add(-1 * day), Decision: DecisionKeep},
+			{BackupName: "b2", Time: now.Add(-4 * day), Decision: DecisionKeep},
+			{BackupName: "b3", Time: now.Add(-8 * day), Decision: DecisionKeep},
+			{BackupName: "b4", Time: now.Add(-11 * day), Decision: DecisionDelete},
+		}}
+
+	var checked [2]string
+	gapAfter := func(gapBackup string) LogGapFinder {
+		return func(backupName, nextBackupName string) (string, error) {
+			checked = [2]string{backupName, nextBackupName}
+			if backupName == gapBackup {
+				return "segments 5-7 are missing", nil
+			}
+			return "", nil
+		}
+	}
+
+	violation, err := findPITRWindowViolation(&explanation, 7*day, now, gapAfter("b2"))
+	require.NoError(t, err)
+	assert.Empty(t, violation)
+	assert.Equal(t, [2]string{"b3", "b2"}, checked)
+
+	violation, err = findPITRWindowViolation(&explanation, 7*day, now, gapAfter("b3"))
+	require.NoError(t, err)
+	assert.Contains(t, violation, "segments 5-7 are missing")
+
+	// the window starting after the newest backup needs the logs from it up to the newest one
+	violation, err = findPITRWindowViolation(&explanation, 12*time.Hour, now, gapAfter("b3"))
+	require.NoError(t, err)
+	assert.Empty(t, violation)
+	assert.Equal(t, [2]string{"b1", ""}, checked)
+
+	explanation.Rule, explanation.Target = "retain 5", ""
+	explanation.Backups[3].Decision = DecisionKeep
+	checked = [2]string{}
+	_, err = findPITRWindowViolation(&explanation, 7*day, now, gapAfter("b3"))
+	require.NoError(t, err)
+	assert.Equal(t, [2]string{}, checked, "the deletion doesn't move the oldest point to restore to")
+}
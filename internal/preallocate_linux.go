@@ -0,0 +1,16 @@
+//go:build linux
+// +build linux
+
+package internal
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// preallocateFile reserves the space for the file of the given size without changing its size,
+// so the file system doesn't have to grow the file while it is written
+func preallocateFile(file *os.File, size int64) error {
+	return unix.Fallocate(int(file.Fd()), unix.FALLOC_FL_KEEP_SIZE, 0, size)
+}
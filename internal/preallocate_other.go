@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package internal
+
+import (
+	"os"
+)
+
+func preallocateFile(file *os.File, size int64) error {
+	return nil
+}
@@ -26,3 +26,14 @@ func readerMakersToFilePaths(readerMakers []ReaderMaker) []string {
 	}
 	return paths
 }
+
+// getRestoreReport returns the report of the restore the files are downloaded for, if any
+func getRestoreReport(readerMakers []ReaderMaker) *RestoreReporter {
+	for _, readerMaker := range readerMakers {
+		reported, ok := readerMaker.(interface{ restoreReport() *RestoreReporter })
+		if ok && reported.restoreReport() != nil {
+			return reported.restoreReport()
+		}
+	}
+	return nil
+}
@@ -17,6 +17,8 @@ type RegularTarBallComposer struct {
 	tarFileSets   TarFileSets
 	errorGroup    *errgroup.Group
 	ctx           context.Context
+	// the files larger than rangeSize are split into the ranges packed concurrently
+	rangeSize int64
 }
 
 func NewRegularTarBallComposer(
@@ -54,10 +56,16 @@ func (maker *RegularTarBallComposerMaker) Make(bundle *Bundle) (TarBallComposer,
 	bundleFiles := maker.files
 	tarFileSets := maker.tarFileSets
 	packer := NewRegularTarBallFilePacker(bundleFiles)
-	return NewRegularTarBallComposer(bundle.TarBallQueue, packer, bundleFiles, tarFileSets, bundle.Crypter), nil
+	composer := NewRegularTarBallComposer(bundle.TarBallQueue, packer, bundleFiles, tarFileSets, bundle.Crypter)
+	composer.rangeSize = GetFileRangeSize()
+	return composer, nil
 }
 
 func (c *RegularTarBallComposer) AddFile(info *ComposeFileInfo) {
+	if ranges := SplitIntoRanges(info.FileInfo.Size(), c.rangeSize); ranges != nil && !info.IsIncremented {
+		AddFileRanges(c.ctx, c.errorGroup, c.tarBallQueue, c.crypter, c.files, c.tarFileSets, info, ranges)
+		return
+	}
 	tarBall, err := c.tarBallQueue.DequeCtx(c.ctx)
 	if err != nil {
 		return
@@ -0,0 +1,381 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/crypto"
+	"github.com/wal-g/wal-g/internal/walz"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	// RekeyStateObject keeps the progress of an unfinished rekey, so it can be resumed
+	RekeyStateObject = "rekey_state.json"
+	// EncryptionKeyFingerprintField is added to the backup sentinels after their files are re-encrypted
+	EncryptionKeyFingerprintField = "EncryptionKeyFingerprint"
+
+	rekeyTmpSuffix          = ".rekey_tmp"
+	rekeyStateSaveFrequency = 100
+)
+
+// RekeyState is the progress of the rekey. The objects are re-encrypted in the order of their paths,
+// all of them up to Cursor inclusive are done.
+type RekeyState struct {
+	NewKeyFingerprint string `json:"new_key_fingerprint"`
+	Cursor            string `json:"cursor"`
+}
+
+// Rekeyer re-encrypts the objects in storage from the old key to the new one. Objects are streamed through
+// decryption and encryption, so nothing is downloaded to disk. The re-encrypted content is uploaded next to the
+// original object first and then copied over it. The copy isn't atomic on the storages writing the files in place
+// (the local and the SSH ones), so the object left half-written by the interrupted copy is restored from
+// the re-encrypted content when the rekey is resumed.
+type Rekeyer struct {
+	folder         storage.Folder
+	oldCrypter     crypto.Crypter
+	newCrypter     crypto.Crypter
+	concurrency    int
+	newFingerprint string
+
+	state RekeyState
+	// paths to re-encrypt in the order, done marks the completed ones and nextPending is the first not completed
+	paths       []string
+	done        []bool
+	nextPending int
+	unsaved     int
+	mu          sync.Mutex
+}
+
+func NewRekeyer(folder storage.Folder, oldCrypter, newCrypter crypto.Crypter, concurrency int) (*Rekeyer, error) {
+	if oldCrypter == nil || newCrypter == nil {
+		return nil, errors.New("both old and new encryption keys must be configured")
+	}
+	if concurrency < 1 {
+		return nil, fmt.Errorf("concurrency must be positive, got %d", concurrency)
+	}
+	newFingerprint, err := CrypterFingerprint(newCrypter)
+	if err != nil {
+		return nil, fmt.Errorf("get new key fingerprint: %w", err)
+	}
+	return &Rekeyer{
+		folder:         folder,
+		oldCrypter:     oldCrypter,
+		newCrypter:     newCrypter,
+		concurrency:    concurrency,
+		newFingerprint: newFingerprint,
+	}, nil
+}
+
+// HandleRekey re-encrypts all objects under the prefixes and records the new key fingerprint in the backup sentinels.
+func HandleRekey(folder storage.Folder, prefixes []string, oldCrypter, newCrypter crypto.Crypter, concurrency int) error {
+	rekeyer, err := NewRekeyer(folder, oldCrypter, newCrypter, concurrency)
+	if err != nil {
+		return err
+	}
+	return rekeyer.Rekey(prefixes)
+}
+
+func (r *Rekeyer) Rekey(prefixes []string) error {
+	err := r.loadState()
+	if err != nil {
+		return err
+	}
+
+	skipped := 0
+	for _, prefix := range prefixes {
+		objects, err := storage.ListFolderRecursively(r.folder.GetSubFolder(prefix))
+		if err != nil {
+			return fmt.Errorf("list %q: %w", prefix, err)
+		}
+		for _, object := range objects {
+			objectPath := prefix + object.GetName()
+			// only the data objects are encrypted, the temporary copies are left by the interrupted run
+			if !IsDataObject(objectPath) || strings.HasSuffix(objectPath, rekeyTmpSuffix) {
+				continue
+			}
+			if r.state.Cursor != "" && objectPath <= r.state.Cursor {
+				skipped++
+				continue
+			}
+			r.paths = append(r.paths, objectPath)
+		}
+	}
+	sort.Strings(r.paths)
+	r.done = make([]bool, len(r.paths))
+	tracelog.InfoLogger.Printf("Objects to re-encrypt: %d (already done: %d)", len(r.paths), skipped)
+
+	err = r.rekeyConcurrently(r.paths)
+	if err != nil {
+		// save the progress to resume from it
+		tracelog.ErrorLogger.PrintOnError(r.saveState())
+		return err
+	}
+
+	err = r.updateSentinels()
+	if err != nil {
+		tracelog.ErrorLogger.PrintOnError(r.saveState())
+		return err
+	}
+	return r.folder.DeleteObjects([]string{RekeyStateObject})
+}
+
+func (r *Rekeyer) rekeyConcurrently(paths []string) error {
+	pathsCh := make(chan int)
+	errsCh := make(chan error, len(paths))
+	wg := new(sync.WaitGroup)
+	for i := 0; i < r.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range pathsCh {
+				err := r.rekeyObject(paths[i])
+				if err != nil {
+					errsCh <- fmt.Errorf("re-encrypt %q: %w", paths[i], err)
+					continue
+				}
+				r.markCompleted(i)
+			}
+		}()
+	}
+	// on the interruption notice the objects in progress are finished and the progress is saved to resume from it
+	ctx, cancel := WithInterruptionNotices(context.Background())
+	defer cancel()
+feed:
+	for i := range paths {
+		select {
+		case pathsCh <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(pathsCh)
+	wg.Wait()
+	close(errsCh)
+
+	errsNum := 0
+	for err := range errsCh {
+		tracelog.ErrorLogger.PrintError(err)
+		errsNum++
+	}
+	if errsNum > 0 {
+		return fmt.Errorf("failed to re-encrypt %d objects, rerun the command to retry them", errsNum)
+	}
+	if noticeErr, ok := GetInterruptionNotice(ctx); ok {
+		return fmt.Errorf("re-encryption is stopped, rerun the command to resume it: %w", noticeErr)
+	}
+	return nil
+}
+
+// rekeyObject re-encrypts the object. The objects re-encrypted past the cursor saved by the interrupted
+// run are listed as not done, so the object failing to re-encrypt is checked to be encrypted with the new key already.
+// The object the interrupted run has failed to copy over is finished from the re-encrypted content left next to it.
+func (r *Rekeyer) rekeyObject(objectPath string) error {
+	err := r.reencryptObject(objectPath)
+	if err == nil {
+		return nil
+	}
+	if r.usesNewKey(objectPath) {
+		tracelog.InfoLogger.Printf("%q is already encrypted with the new key", objectPath)
+		return nil
+	}
+	tmpPath := objectPath + rekeyTmpSuffix
+	if !r.usesNewKey(tmpPath) {
+		return err
+	}
+	tracelog.InfoLogger.Printf("Finishing the interrupted copy of %q from %q", objectPath, tmpPath)
+	return r.replaceWithTmp(tmpPath, objectPath)
+}
+
+// usesNewKey checks that the whole object decrypts with the new key, the content of the authenticated
+// encryption can't be read with another key
+func (r *Rekeyer) usesNewKey(objectPath string) bool {
+	source, err := r.folder.ReadObject(objectPath)
+	if err != nil {
+		return false
+	}
+	defer utility.LoggedClose(source, "close object read for the key check")
+	if isWalzFile(strings.TrimSuffix(objectPath, rekeyTmpSuffix)) {
+		header, err := walz.ReadHeader(source)
+		if err != nil || header.KeyID != r.newFingerprint {
+			return false
+		}
+	}
+	decrypted, err := r.newCrypter.Decrypt(source)
+	if err != nil {
+		return false
+	}
+	_, err = io.Copy(io.Discard, decrypted)
+	return err == nil
+}
+
+func (r *Rekeyer) reencryptObject(objectPath string) error {
+	source, err := r.folder.ReadObject(objectPath)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(source, "close object read for re-encryption")
+
+	// the walz envelope keeps its header unencrypted, only the key ID in it is changed
+	var header *walz.Header
+	if isWalzFile(objectPath) {
+		envelopeHeader, err := walz.ReadHeader(source)
+		if err != nil {
+			return err
+		}
+		if envelopeHeader.KeyID == "" {
+			return nil
+		}
+		envelopeHeader.KeyID = r.newFingerprint
+		header = &envelopeHeader
+	}
+
+	decrypted, err := r.oldCrypter.Decrypt(source)
+	if err != nil {
+		return fmt.Errorf("decrypt with the old key: %w", err)
+	}
+	reencrypted := encryptingReader(decrypted, r.newCrypter)
+	if header != nil {
+		var headerBuffer bytes.Buffer
+		_, err = header.WriteTo(&headerBuffer)
+		if err != nil {
+			return err
+		}
+		reencrypted = io.MultiReader(&headerBuffer, reencrypted)
+	}
+	tmpPath := objectPath + rekeyTmpSuffix
+	err = r.folder.PutObject(tmpPath, reencrypted)
+	if err != nil {
+		return err
+	}
+	return r.replaceWithTmp(tmpPath, objectPath)
+}
+
+func (r *Rekeyer) replaceWithTmp(tmpPath, objectPath string) error {
+	err := r.folder.CopyObject(tmpPath, objectPath)
+	if err != nil {
+		return err
+	}
+	return r.folder.DeleteObjects([]string{tmpPath})
+}
+
+// updateSentinels records the new key fingerprint in every backup sentinel, keeping all the other fields intact.
+// The sentinels are modified under the metadata lock not to overwrite the concurrent changes, e.g. backup-mark.
+func (r *Rekeyer) updateSentinels() error {
+	lock, err := AcquireMetadataLock(r.folder)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	backupsFolder := r.folder.GetSubFolder(utility.BaseBackupPath)
+	objects, _, err := backupsFolder.ListFolder()
+	if err != nil {
+		return fmt.Errorf("list backups: %w", err)
+	}
+	for _, object := range objects {
+		if !strings.HasSuffix(object.GetName(), utility.SentinelSuffix) {
+			continue
+		}
+		err = lock.Check()
+		if err != nil {
+			return err
+		}
+		sentinel := map[string]json.RawMessage{}
+		err = ModifyDto(backupsFolder, &sentinel, object.GetName(), func() error {
+			fingerprint, err := json.Marshal(r.newFingerprint)
+			if err != nil {
+				return err
+			}
+			sentinel[EncryptionKeyFingerprintField] = fingerprint
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("update sentinel %q: %w", object.GetName(), err)
+		}
+	}
+	return nil
+}
+
+func (r *Rekeyer) loadState() error {
+	r.state = RekeyState{NewKeyFingerprint: r.newFingerprint}
+	exists, err := r.folder.Exists(RekeyStateObject)
+	if err != nil || !exists {
+		return err
+	}
+
+	var state RekeyState
+	err = FetchDto(r.folder, &state, RekeyStateObject)
+	if err != nil {
+		return fmt.Errorf("fetch rekey state: %w", err)
+	}
+	if state.NewKeyFingerprint != r.newFingerprint {
+		return fmt.Errorf("there is an unfinished rekey to another key (%s), finish it or remove %s first",
+			state.NewKeyFingerprint, RekeyStateObject)
+	}
+	tracelog.InfoLogger.Printf("Resuming the unfinished rekey")
+	r.state = state
+	return nil
+}
+
+// markCompleted moves the cursor over the completed objects that have no pending objects before them,
+// so the state stays the same size no matter how many objects are done
+func (r *Rekeyer) markCompleted(i int) {
+	r.mu.Lock()
+	r.done[i] = true
+	for r.nextPending < len(r.paths) && r.done[r.nextPending] {
+		r.state.Cursor = r.paths[r.nextPending]
+		r.nextPending++
+	}
+	r.unsaved++
+	shouldSave := r.unsaved >= rekeyStateSaveFrequency
+	r.mu.Unlock()
+
+	if shouldSave {
+		tracelog.ErrorLogger.PrintOnError(r.saveState())
+	}
+}
+
+func (r *Rekeyer) saveState() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unsaved = 0
+	return UploadDto(r.folder, r.state, RekeyStateObject)
+}
+
+// CrypterFingerprint identifies the key of the crypter, the crypters that can't do it are identified by name
+func CrypterFingerprint(crypter crypto.Crypter) (string, error) {
+	fingerprinter, ok := crypter.(crypto.Fingerprinter)
+	if !ok {
+		return crypter.Name(), nil
+	}
+	return fingerprinter.Fingerprint()
+}
+
+// encryptingReader encrypts the source as it's read. The crypter may write the header as soon as the encryption
+// starts, so it's started by the goroutine draining the source into the pipe, not to block on the pipe.
+func encryptingReader(source io.Reader, crypter crypto.Crypter) io.Reader {
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		encryptingWriter, err := crypter.Encrypt(pipeWriter)
+		if err != nil {
+			_ = pipeWriter.CloseWithError(fmt.Errorf("encrypt with the new key: %w", err))
+			return
+		}
+		_, err = utility.FastCopy(encryptingWriter, source)
+		if err == nil {
+			err = encryptingWriter.Close()
+		}
+		_ = pipeWriter.CloseWithError(err)
+	}()
+	return pipeReader
+}
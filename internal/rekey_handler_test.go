@@ -0,0 +1,147 @@
+package internal_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/walz"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// taggingCrypter "encrypts" the content by prepending its tag, which is enough to check which key is used.
+type taggingCrypter struct {
+	tag string
+}
+
+func (c taggingCrypter) Name() string {
+	return "tagging"
+}
+
+func (c taggingCrypter) Fingerprint() (string, error) {
+	return c.tag, nil
+}
+
+func (c taggingCrypter) Encrypt(writer io.Writer) (io.WriteCloser, error) {
+	_, err := writer.Write([]byte(c.tag))
+	return nopWriteCloser{writer}, err
+}
+
+func (c taggingCrypter) Decrypt(reader io.Reader) (io.Reader, error) {
+	tag := make([]byte, len(c.tag))
+	_, err := io.ReadFull(reader, tag)
+	if err != nil {
+		return nil, err
+	}
+	if string(tag) != c.tag {
+		return nil, fmt.Errorf("encrypted with another key")
+	}
+	return reader, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func TestHandleRekey(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewKVS())
+	oldCrypter, newCrypter := taggingCrypter{"old:"}, taggingCrypter{"new:"}
+
+	encrypted := map[string]string{
+		utility.BaseBackupPath + "base_1/tar_partitions/part_1.tar.lz4":   "old:part",
+		utility.BaseBackupPath + "base_1/tar_partitions/part_raw_002.tar": "old:raw part",
+		utility.WalPath + "000000010000000000000001.lz4":                  "old:wal",
+	}
+	for name, content := range encrypted {
+		require.NoError(t, folder.PutObject(name, strings.NewReader(content)))
+	}
+	walzPath := utility.WalPath + "000000010000000000000002.walz"
+	header := walz.NewHeader("000000010000000000000002", []byte("wal"))
+	header.KeyID = "old:"
+	envelope := new(bytes.Buffer)
+	_, err := header.WriteTo(envelope)
+	require.NoError(t, err)
+	envelope.WriteString("old:wal")
+	require.NoError(t, folder.PutObject(walzPath, envelope))
+	sentinelPath := utility.BaseBackupPath + "base_1" + utility.SentinelSuffix
+	require.NoError(t, folder.PutObject(sentinelPath, strings.NewReader(`{"LSN":42}`)))
+
+	err = internal.HandleRekey(folder, []string{utility.BaseBackupPath, utility.WalPath}, oldCrypter, newCrypter, 2)
+	require.NoError(t, err)
+
+	reader, err := folder.ReadObject(walzPath)
+	require.NoError(t, err)
+	header, err = walz.ReadHeader(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "new:", header.KeyID)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "new:wal", string(content))
+
+	for name, content := range encrypted {
+		assert.Equal(t, strings.Replace(content, "old:", "new:", 1), readObject(t, folder, name))
+		exists, err := folder.Exists(name + ".rekey_tmp")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	}
+	sentinel := readObject(t, folder, sentinelPath)
+	assert.Contains(t, sentinel, `"LSN":42`)
+	assert.Contains(t, sentinel, `"EncryptionKeyFingerprint":"new:"`)
+
+	exists, err := folder.Exists(internal.RekeyStateObject)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestHandleRekey_Resume(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewKVS())
+	oldCrypter, newCrypter := taggingCrypter{"old:"}, taggingCrypter{"new:"}
+
+	require.NoError(t, folder.PutObject(utility.WalPath+"1.lz4", strings.NewReader("new:done")))
+	require.NoError(t, folder.PutObject(utility.WalPath+"2.lz4", strings.NewReader("old:left")))
+	// re-encrypted by the interrupted run past the saved cursor
+	require.NoError(t, folder.PutObject(utility.WalPath+"3.lz4", strings.NewReader("new:unsaved")))
+	// truncated by the interrupted copy over it, the re-encrypted content is left next to it
+	require.NoError(t, folder.PutObject(utility.WalPath+"4.lz4", strings.NewReader("ne")))
+	require.NoError(t, folder.PutObject(utility.WalPath+"4.lz4.rekey_tmp", strings.NewReader("new:copied")))
+	require.NoError(t, internal.UploadDto(folder, internal.RekeyState{
+		NewKeyFingerprint: "new:",
+		Cursor:            utility.WalPath + "1.lz4",
+	}, internal.RekeyStateObject))
+
+	err := internal.HandleRekey(folder, []string{utility.WalPath}, oldCrypter, newCrypter, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "new:done", readObject(t, folder, utility.WalPath+"1.lz4"))
+	assert.Equal(t, "new:left", readObject(t, folder, utility.WalPath+"2.lz4"))
+	assert.Equal(t, "new:unsaved", readObject(t, folder, utility.WalPath+"3.lz4"))
+	assert.Equal(t, "new:copied", readObject(t, folder, utility.WalPath+"4.lz4"))
+	exists, err := folder.Exists(utility.WalPath + "4.lz4.rekey_tmp")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	t.Run("refuse to resume rekey to another key", func(t *testing.T) {
+		require.NoError(t, internal.UploadDto(folder, internal.RekeyState{NewKeyFingerprint: "other:"},
+			internal.RekeyStateObject))
+		err := internal.HandleRekey(folder, []string{utility.WalPath}, newCrypter, oldCrypter, 1)
+		assert.Error(t, err)
+	})
+}
+
+func readObject(t *testing.T, folder *memory.Folder, name string) string {
+	reader, err := folder.ReadObject(name)
+	require.NoError(t, err)
+	content := new(bytes.Buffer)
+	_, err = content.ReadFrom(reader)
+	require.NoError(t, err)
+	return content.String()
+}
@@ -0,0 +1,202 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/multistorage/consts"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const (
+	RestoreReportFileName = ".walg_restore_report.json"
+	RestoreReportsPath    = "restore_reports"
+
+	RestoreStatusRunning   = "running"
+	RestoreStatusSucceeded = "succeeded"
+)
+
+// RestoreReport is the machine-readable evidence of a backup-fetch. It is written when the restore starts
+// and rewritten when it succeeds, so the report of a failed restore stays in the running status.
+type RestoreReport struct {
+	BackupName        string                `json:"backup_name"`
+	Status            string                `json:"status"`
+	Hostname          string                `json:"hostname"`
+	StartTime         time.Time             `json:"start_time"`
+	FinishTime        *time.Time            `json:"finish_time,omitempty"`
+	ObjectsDownloaded int64                 `json:"objects_downloaded"`
+	BytesDownloaded   int64                 `json:"bytes_downloaded"`
+	Phases            []RestorePhase        `json:"phases"`
+	Verifications     []RestoreVerification `json:"verifications,omitempty"`
+	Warnings          []string              `json:"warnings,omitempty"`
+	// FetchSources lists the objects that were downloaded from the failover or mirror storages
+	FetchSources map[string]string `json:"fetch_sources,omitempty"`
+}
+
+type RestorePhase struct {
+	Name     string        `json:"name"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+type RestoreVerification struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// RestoreReporter accumulates the report of the running restore. The fetcher gets it with the backup,
+// see Backup.RestoreReport, and the nil reporter records nothing.
+type RestoreReporter struct {
+	mutex   sync.Mutex
+	report  RestoreReport
+	objects int64
+	bytes   int64
+}
+
+// NewRestoreReporter begins collecting the report of the restore of the backup
+func NewRestoreReporter(backupName string) *RestoreReporter {
+	hostname, err := os.Hostname()
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to get hostname for the restore report: %v", err)
+	}
+	return &RestoreReporter{report: RestoreReport{
+		BackupName: backupName,
+		Status:     RestoreStatusRunning,
+		Hostname:   hostname,
+		StartTime:  time.Now().UTC(),
+	}}
+}
+
+// StartPhase records the phase of the restore, the returned function marks its end
+func (reporter *RestoreReporter) StartPhase(name string) func() {
+	if reporter == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		reporter.mutex.Lock()
+		defer reporter.mutex.Unlock()
+		reporter.report.Phases = append(reporter.report.Phases,
+			RestorePhase{Name: name, Start: start.UTC(), Duration: time.Since(start)})
+	}
+}
+
+// AddVerification records the result of a check made during the restore
+func (reporter *RestoreReporter) AddVerification(name string, err error) {
+	if reporter == nil {
+		return
+	}
+	verification := RestoreVerification{Name: name, Passed: err == nil}
+	if err != nil {
+		verification.Error = err.Error()
+	}
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+	reporter.report.Verifications = append(reporter.report.Verifications, verification)
+}
+
+// AddWarning logs the warning and records it in the report of the restore
+func (reporter *RestoreReporter) AddWarning(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	tracelog.WarningLogger.Println(message)
+	if reporter == nil {
+		return
+	}
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+	reporter.report.Warnings = append(reporter.report.Warnings, message)
+}
+
+// CountDownload counts the object and the bytes read from it in the report of the restore
+func (reporter *RestoreReporter) CountDownload(readCloser io.ReadCloser) io.ReadCloser {
+	if reporter == nil {
+		return readCloser
+	}
+	atomic.AddInt64(&reporter.objects, 1)
+	return &restoreDownloadCounter{ReadCloser: readCloser, reporter: reporter}
+}
+
+type restoreDownloadCounter struct {
+	io.ReadCloser
+	reporter *RestoreReporter
+}
+
+func (counter *restoreDownloadCounter) Read(p []byte) (int, error) {
+	n, err := counter.ReadCloser.Read(p)
+	atomic.AddInt64(&counter.reporter.bytes, int64(n))
+	return n, err
+}
+
+// Write writes the report of the restore to WALG_RESTORE_REPORT_PATH and, if WALG_RESTORE_REPORT_UPLOAD
+// is set, to the restore_reports folder of the storage
+func (reporter *RestoreReporter) Write(rootFolder storage.Folder, succeeded bool) {
+	if reporter == nil {
+		return
+	}
+	reporter.mutex.Lock()
+	report := reporter.report
+	report.Phases = append([]RestorePhase{}, report.Phases...)
+	report.Verifications = append([]RestoreVerification{}, report.Verifications...)
+	report.Warnings = append([]string{}, report.Warnings...)
+	reporter.mutex.Unlock()
+
+	report.ObjectsDownloaded = atomic.LoadInt64(&reporter.objects)
+	report.BytesDownloaded = atomic.LoadInt64(&reporter.bytes)
+	for object, source := range GetFetchSources() {
+		if source == consts.DefaultStorage {
+			continue
+		}
+		if report.FetchSources == nil {
+			report.FetchSources = make(map[string]string)
+		}
+		report.FetchSources[object] = source
+	}
+	if succeeded {
+		report.Status = RestoreStatusSucceeded
+		finishTime := time.Now().UTC()
+		report.FinishTime = &finishTime
+	}
+
+	content, err := json.MarshalIndent(report, "", "\t")
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to marshal the restore report: %v", err)
+		return
+	}
+	reportPath := getRestoreReportPath()
+	if reportPath != "" {
+		err = os.WriteFile(reportPath, content, 0600)
+		if err != nil {
+			tracelog.WarningLogger.Printf("Failed to write the restore report to %s: %v", reportPath, err)
+		}
+	}
+	if viper.GetBool(conf.RestoreReportUploadSetting) {
+		objectPath := fmt.Sprintf("%s/%s_%s.json", RestoreReportsPath, report.BackupName,
+			report.StartTime.Format("20060102T150405Z"))
+		err = rootFolder.PutObject(objectPath, bytes.NewReader(content))
+		if err != nil {
+			tracelog.WarningLogger.Printf("Failed to upload the restore report to %s: %v", objectPath, err)
+		}
+	}
+}
+
+func getRestoreReportPath() string {
+	if reportPath, ok := conf.GetSetting(conf.RestoreReportPathSetting); ok {
+		return reportPath
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, RestoreReportFileName)
+}
@@ -0,0 +1,78 @@
+package internal_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+func TestRestoreReporter_Write(t *testing.T) {
+	internal.ConfigureSettings("")
+	conf.InitConfig()
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	viper.Set(conf.RestoreReportPathSetting, reportPath)
+	viper.Set(conf.RestoreReportUploadSetting, true)
+	defer viper.Set(conf.RestoreReportPathSetting, nil)
+	defer viper.Set(conf.RestoreReportUploadSetting, nil)
+
+	folder := memory.NewFolder("", memory.NewKVS())
+	require.NoError(t, folder.PutObject("part_1.tar", strings.NewReader("0123456789")))
+
+	reporter := internal.NewRestoreReporter("base_000000010000000000000002")
+	reporter.Write(folder, false)
+	report := readRestoreReport(t, reportPath)
+	assert.Equal(t, internal.RestoreStatusRunning, report.Status)
+	assert.Nil(t, report.FinishTime)
+
+	finishPhase := reporter.StartPhase("fetch")
+	readerMaker := internal.NewStorageReaderMaker(folder, "part_1.tar")
+	readerMaker.RestoreReport = reporter
+	reader, err := readerMaker.Reader()
+	require.NoError(t, err)
+	_, err = io.Copy(io.Discard, reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	reporter.AddVerification("pg_control", nil)
+	reporter.AddVerification("checksums", errors.New("mismatch"))
+	reporter.AddWarning("%d files failed to download", 1)
+	finishPhase()
+	reporter.Write(folder, true)
+
+	report = readRestoreReport(t, reportPath)
+	assert.Equal(t, "base_000000010000000000000002", report.BackupName)
+	assert.Equal(t, internal.RestoreStatusSucceeded, report.Status)
+	assert.NotNil(t, report.FinishTime)
+	assert.Equal(t, int64(1), report.ObjectsDownloaded)
+	assert.Equal(t, int64(10), report.BytesDownloaded)
+	require.Len(t, report.Phases, 1)
+	assert.Equal(t, "fetch", report.Phases[0].Name)
+	assert.Equal(t, []internal.RestoreVerification{
+		{Name: "pg_control", Passed: true},
+		{Name: "checksums", Passed: false, Error: "mismatch"},
+	}, report.Verifications)
+	assert.Equal(t, []string{"1 files failed to download"}, report.Warnings)
+
+	uploaded, _, err := folder.GetSubFolder(internal.RestoreReportsPath).ListFolder()
+	require.NoError(t, err)
+	require.Len(t, uploaded, 1)
+	assert.True(t, strings.HasPrefix(uploaded[0].GetName(), "base_000000010000000000000002_"))
+}
+
+func readRestoreReport(t *testing.T, path string) internal.RestoreReport {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var report internal.RestoreReport
+	require.NoError(t, json.Unmarshal(content, &report))
+	return report
+}
@@ -0,0 +1,197 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const (
+	// RetentionEvaluationsPath is the folder in the storage root keeping the evaluations of the retention policies
+	RetentionEvaluationsPath     = "retention_evaluations"
+	retentionEvaluationFormat    = "20060102T150405Z"
+	retentionEvaluationExtension = ".json"
+)
+
+// RetentionEvaluation is the decision trace of the retention policy saved to compare it with the next evaluation
+type RetentionEvaluation struct {
+	Time        time.Time          `json:"time"`
+	Explanation *DeleteExplanation `json:"explanation"`
+}
+
+// RetentionChange is the backup whose decision has changed since the previous evaluation
+type RetentionChange struct {
+	BackupName       string         `json:"backup_name"`
+	Storage          string         `json:"storage"`
+	Time             time.Time      `json:"time"`
+	PreviousDecision DeleteDecision `json:"previous_decision,omitempty"`
+	PreviousReason   string         `json:"previous_reason,omitempty"`
+	Decision         DeleteDecision `json:"decision"`
+	Reason           string         `json:"reason"`
+}
+
+// RetentionDiff shows what has changed between two evaluations of the retention policy
+type RetentionDiff struct {
+	PreviousTime time.Time `json:"previous_time"`
+	PreviousRule string    `json:"previous_rule"`
+	Time         time.Time `json:"time"`
+	Rule         string    `json:"rule"`
+	// NewlyDeleted are the backups the policy now deletes, but kept before or didn't see
+	NewlyDeleted []RetentionChange `json:"newly_deleted"`
+	// NewlyKept are the backups the policy now keeps, but deleted before
+	NewlyKept []RetentionChange `json:"newly_kept"`
+	// Gone are the backups which are no longer in the storage
+	Gone []string `json:"gone"`
+}
+
+// SaveRetentionEvaluation saves the decision trace of the retention policy to the storage
+func SaveRetentionEvaluation(rootFolder storage.Folder, explanation *DeleteExplanation) error {
+	evaluation := RetentionEvaluation{Time: time.Now().UTC(), Explanation: explanation}
+	path := RetentionEvaluationsPath + "/" + evaluation.Time.Format(retentionEvaluationFormat) + retentionEvaluationExtension
+	return UploadDto(rootFolder, evaluation, path)
+}
+
+// GetLastRetentionEvaluations fetches at most count last evaluations of the retention policies, the oldest first
+func GetLastRetentionEvaluations(rootFolder storage.Folder, count int) ([]RetentionEvaluation, error) {
+	folder := rootFolder.GetSubFolder(RetentionEvaluationsPath)
+	objects, _, err := folder.ListFolder()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the retention evaluations")
+	}
+	names := make([]string, 0, len(objects))
+	for _, object := range objects {
+		if strings.HasSuffix(object.GetName(), retentionEvaluationExtension) {
+			names = append(names, object.GetName())
+		}
+	}
+	// the names are the evaluation times, so they sort chronologically
+	sort.Strings(names)
+	if len(names) > count {
+		names = names[len(names)-count:]
+	}
+
+	evaluations := make([]RetentionEvaluation, len(names))
+	for i, name := range names {
+		if err = FetchDto(folder, &evaluations[i], name); err != nil {
+			return nil, err
+		}
+	}
+	return evaluations, nil
+}
+
+// DiffRetentionEvaluations finds the backups whose decisions have changed between the evaluations
+func DiffRetentionEvaluations(previous, current RetentionEvaluation) RetentionDiff {
+	diff := RetentionDiff{
+		PreviousTime: previous.Time,
+		PreviousRule: previous.Explanation.Rule,
+		Time:         current.Time,
+		Rule:         current.Explanation.Rule,
+		NewlyDeleted: make([]RetentionChange, 0),
+		NewlyKept:    make([]RetentionChange, 0),
+		Gone:         make([]string, 0),
+	}
+	previousDecisions := make(map[string]BackupDeleteDecision, len(previous.Explanation.Backups))
+	for _, backup := range previous.Explanation.Backups {
+		previousDecisions[backup.Storage+"/"+backup.BackupName] = backup
+	}
+
+	for _, backup := range current.Explanation.Backups {
+		key := backup.Storage + "/" + backup.BackupName
+		previousBackup, existed := previousDecisions[key]
+		delete(previousDecisions, key)
+		if existed && previousBackup.Decision == backup.Decision {
+			continue
+		}
+		change := RetentionChange{
+			BackupName:       backup.BackupName,
+			Storage:          backup.Storage,
+			Time:             backup.Time,
+			PreviousDecision: previousBackup.Decision,
+			PreviousReason:   previousBackup.Reason,
+			Decision:         backup.Decision,
+			Reason:           backup.Reason,
+		}
+		switch {
+		case backup.Decision == DecisionDelete:
+			diff.NewlyDeleted = append(diff.NewlyDeleted, change)
+		case existed:
+			diff.NewlyKept = append(diff.NewlyKept, change)
+		}
+	}
+
+	for _, backup := range previous.Explanation.Backups {
+		if _, gone := previousDecisions[backup.Storage+"/"+backup.BackupName]; gone {
+			diff.Gone = append(diff.Gone, backup.BackupName)
+		}
+	}
+	return diff
+}
+
+// HandleRetentionDiff prints what has changed since the previous evaluation of the retention policy
+func HandleRetentionDiff(rootFolder storage.Folder, output io.Writer, isJSON bool) error {
+	evaluations, err := GetLastRetentionEvaluations(rootFolder, 2)
+	if err != nil {
+		return err
+	}
+	if len(evaluations) < 2 {
+		return fmt.Errorf("found %d retention evaluations in %s, at least 2 are needed to compare",
+			len(evaluations), RetentionEvaluationsPath)
+	}
+	return PrintRetentionDiff(DiffRetentionEvaluations(evaluations[0], evaluations[1]), output, isJSON)
+}
+
+func PrintRetentionDiff(diff RetentionDiff, output io.Writer, isJSON bool) error {
+	if isJSON {
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "    ")
+		return encoder.Encode(diff)
+	}
+
+	writer := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "Previous: delete %s at %s\n", diff.PreviousRule, diff.PreviousTime.Format(time.RFC3339))
+	fmt.Fprintf(writer, "Current: delete %s at %s\n", diff.Rule, diff.Time.Format(time.RFC3339))
+	if diff.Rule != diff.PreviousRule {
+		fmt.Fprintf(writer, "The policy has changed\n")
+	}
+	printRetentionChanges(writer, "Newly eligible for deletion", diff.NewlyDeleted)
+	printRetentionChanges(writer, "Newly protected", diff.NewlyKept)
+	if len(diff.Gone) > 0 {
+		fmt.Fprintf(writer, "\nNo longer in storage: %s\n", strings.Join(diff.Gone, ", "))
+	}
+	return writer.Flush()
+}
+
+func printRetentionChanges(writer io.Writer, title string, changes []RetentionChange) {
+	if len(changes) == 0 {
+		fmt.Fprintf(writer, "\n%s: none\n", title)
+		return
+	}
+	fmt.Fprintf(writer, "\n%s:\nBackup\tStorage\tTime\tPrevious reason\tReason\n", title)
+	for _, change := range changes {
+		previousReason := change.PreviousReason
+		if change.PreviousDecision == "" {
+			previousReason = "new backup"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", change.BackupName, change.Storage,
+			change.Time.Format(time.RFC3339), previousReason, change.Reason)
+	}
+}
+
+// RecordRetentionEvaluation saves the evaluation of the retention policy, i.e. 'delete retain' or 'delete before'.
+// The other explained deletions, like 'delete target', aren't compared. The failure to save is only logged.
+func RecordRetentionEvaluation(rootFolder storage.Folder, explanation *DeleteExplanation) {
+	if !strings.HasPrefix(explanation.Rule, "retain") && !strings.HasPrefix(explanation.Rule, "before") {
+		return
+	}
+	if err := SaveRetentionEvaluation(rootFolder, explanation); err != nil {
+		tracelog.WarningLogger.Printf("Failed to save the retention evaluation: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+func newTestRetentionEvaluation(evaluationTime time.Time, rule string, decisions map[string]DeleteDecision) RetentionEvaluation {
+	explanation := &DeleteExplanation{Rule: rule}
+	for _, name := range []string{"base_1", "base_2", "base_3", "base_4"} {
+		if decision, ok := decisions[name]; ok {
+			explanation.Backups = append(explanation.Backups, BackupDeleteDecision{
+				BackupName: name, Storage: "default", Decision: decision, Reason: string(decision) + " by " + rule,
+			})
+		}
+	}
+	return RetentionEvaluation{Time: evaluationTime, Explanation: explanation}
+}
+
+func TestDiffRetentionEvaluations(t *testing.T) {
+	now := time.Now()
+	previous := newTestRetentionEvaluation(now.Add(-24*time.Hour), "retain FULL 3", map[string]DeleteDecision{
+		"base_1": DecisionDelete, "base_2": DecisionKeep, "base_3": DecisionKeep,
+	})
+	current := newTestRetentionEvaluation(now, "retain FULL 1", map[string]DeleteDecision{
+		"base_2": DecisionDelete, "base_3": DecisionKeep, "base_4": DecisionKeep,
+	})
+
+	diff := DiffRetentionEvaluations(previous, current)
+	assert.Equal(t, "retain FULL 3", diff.PreviousRule)
+	assert.Equal(t, "retain FULL 1", diff.Rule)
+	require.Len(t, diff.NewlyDeleted, 1)
+	assert.Equal(t, "base_2", diff.NewlyDeleted[0].BackupName)
+	assert.Equal(t, DecisionKeep, diff.NewlyDeleted[0].PreviousDecision)
+	assert.Empty(t, diff.NewlyKept, "the new backup kept by the policy isn't a change")
+	assert.Equal(t, []string{"base_1"}, diff.Gone)
+
+	diff = DiffRetentionEvaluations(current, previous)
+	require.Len(t, diff.NewlyKept, 1)
+	assert.Equal(t, "base_2", diff.NewlyKept[0].BackupName)
+}
+
+func TestGetLastRetentionEvaluations(t *testing.T) {
+	folder := memory.NewFolder("in_memory/", memory.NewKVS())
+	evaluations, err := GetLastRetentionEvaluations(folder, 2)
+	require.NoError(t, err)
+	assert.Empty(t, evaluations)
+
+	now := time.Now().UTC()
+	for i, rule := range []string{"retain 3", "retain 2", "retain 1"} {
+		evaluation := newTestRetentionEvaluation(now.Add(time.Duration(i)*time.Hour), rule, nil)
+		path := RetentionEvaluationsPath + "/" + evaluation.Time.Format(retentionEvaluationFormat) + retentionEvaluationExtension
+		require.NoError(t, UploadDto(folder, evaluation, path))
+	}
+
+	evaluations, err = GetLastRetentionEvaluations(folder, 2)
+	require.NoError(t, err)
+	require.Len(t, evaluations, 2)
+	assert.Equal(t, "retain 2", evaluations[0].Explanation.Rule)
+	assert.Equal(t, "retain 1", evaluations[1].Explanation.Rule)
+}
@@ -0,0 +1,108 @@
+package selfupdate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	DefaultReleasesURL = "https://api.github.com/repos/wal-g/wal-g/releases"
+
+	StableChannel     = "stable"
+	PrereleaseChannel = "prerelease"
+)
+
+type release struct {
+	TagName    string         `json:"tag_name"`
+	Draft      bool           `json:"draft"`
+	Prerelease bool           `json:"prerelease"`
+	Assets     []releaseAsset `json:"assets"`
+}
+
+type releaseAsset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+func (r *release) findAsset(name string) (releaseAsset, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return releaseAsset{}, false
+}
+
+// fetchRelease returns the release of the version, or the latest release of the channel if the version is empty
+func (u *Updater) fetchRelease(ctx context.Context, channel, version string) (*release, error) {
+	if version != "" {
+		var tagged release
+		err := u.getJSON(ctx, u.ReleasesURL+"/tags/"+url.PathEscape(version), &tagged)
+		if err != nil {
+			return nil, fmt.Errorf("get release %s: %w", version, err)
+		}
+		return &tagged, nil
+	}
+
+	switch channel {
+	case StableChannel:
+		var latest release
+		if err := u.getJSON(ctx, u.ReleasesURL+"/latest", &latest); err != nil {
+			return nil, fmt.Errorf("get the latest release: %w", err)
+		}
+		return &latest, nil
+	case PrereleaseChannel:
+		// the releases are listed from the newest one
+		var releases []release
+		if err := u.getJSON(ctx, u.ReleasesURL, &releases); err != nil {
+			return nil, fmt.Errorf("list the releases: %w", err)
+		}
+		for i := range releases {
+			if !releases[i].Draft {
+				return &releases[i], nil
+			}
+		}
+		return nil, fmt.Errorf("no releases found at %s", u.ReleasesURL)
+	default:
+		return nil, fmt.Errorf("unknown release channel %q, expected %s or %s", channel, StableChannel, PrereleaseChannel)
+	}
+}
+
+func (u *Updater) getJSON(ctx context.Context, url string, value interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/vnd.github+json")
+	response, err := u.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(response.Body, "")
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with %s", url, response.Status)
+	}
+	return json.NewDecoder(response.Body).Decode(value)
+}
+
+// download requests the asset, the caller closes the body
+func (u *Updater) download(ctx context.Context, asset releaseAsset) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.BrowserDownloadURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := u.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", asset.Name, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		utility.LoggedClose(response.Body, "")
+		return nil, fmt.Errorf("download %s: %s responded with %s", asset.Name, asset.BrowserDownloadURL, response.Status)
+	}
+	return response, nil
+}
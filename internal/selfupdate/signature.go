@@ -0,0 +1,50 @@
+package selfupdate
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	_ "embed"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+//go:embed release_keys.pem
+var releaseKeysPEM []byte
+
+var errNoReleaseKeys = errors.New("this build has no release keys embedded to verify the signature with, " +
+	"give them with --release-keys or turn the verification off with --verify-signature=false")
+
+// ParseKeys parses the PEM-encoded Ed25519 public keys, the text around the PEM blocks is ignored
+func ParseKeys(data []byte) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return keys, nil
+		}
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse release key: %w", err)
+		}
+		key, ok := parsed.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("release key is %T, expected Ed25519 key", parsed)
+		}
+		keys = append(keys, key)
+	}
+}
+
+// verifySignature checks that the content is signed with any of the keys
+func verifySignature(keys []ed25519.PublicKey, content, signature []byte) error {
+	if len(keys) == 0 {
+		return errNoReleaseKeys
+	}
+	for _, key := range keys {
+		if ed25519.Verify(key, content, signature) {
+			return nil
+		}
+	}
+	return errors.New("the signature doesn't match any of the release keys")
+}
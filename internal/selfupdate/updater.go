@@ -0,0 +1,315 @@
+package selfupdate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	pinSuffix       = ".pin"
+	checksumSuffix  = ".sha256"
+	signatureSuffix = ".sig"
+	// the checksum and the signature assets are tiny, the limit guards against the wrong ones
+	maxSmallAssetSize = 64 * 1024
+)
+
+// Updater replaces the running wal-g binary with the release binary built for the same database and platform
+type Updater struct {
+	ReleasesURL string
+	// Asset is the name of the release binary, e.g. wal-g-pg-ubuntu-20.04-amd64
+	Asset string
+	// Executable is the path of the binary to replace
+	Executable      string
+	CurrentVersion  string
+	VerifySignature bool
+
+	keys   []ed25519.PublicKey
+	client *http.Client
+}
+
+// NewUpdater creates the Updater of the running binary, the platform is detected if it is empty.
+// The signature is always verified unless VerifySignature is turned off explicitly.
+func NewUpdater(dbName, platform, currentVersion string) (*Updater, error) {
+	if platform == "" {
+		var err error
+		platform, err = DetectPlatform()
+		if err != nil {
+			return nil, err
+		}
+	}
+	executable, err := executablePath()
+	if err != nil {
+		return nil, err
+	}
+	keys, err := ParseKeys(releaseKeysPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &Updater{
+		ReleasesURL:     DefaultReleasesURL,
+		Asset:           fmt.Sprintf("wal-g-%s-%s", strings.ToLower(dbName), platform),
+		Executable:      executable,
+		CurrentVersion:  currentVersion,
+		VerifySignature: true,
+		keys:            keys,
+		client:          &http.Client{},
+	}, nil
+}
+
+// AddKeys trusts the PEM-encoded release keys from the file in addition to the embedded ones, e.g. the keys
+// the mirror of your own builds is signed with
+func (u *Updater) AddKeys(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read the release keys: %w", err)
+	}
+	keys, err := ParseKeys(content)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no release keys found in %s", path)
+	}
+	u.keys = append(u.keys, keys...)
+	return nil
+}
+
+// executablePath is the path of the running binary, the symlinks to which are resolved to replace the binary itself
+func executablePath() (string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("find the wal-g binary: %w", err)
+	}
+	executable, err = filepath.EvalSymlinks(executable)
+	if err != nil {
+		return "", fmt.Errorf("resolve the wal-g binary path: %w", err)
+	}
+	return executable, nil
+}
+
+// DetectPlatform names the platform the way the release binaries are named, e.g. ubuntu-20.04-amd64
+// or ubuntu20.04-aarch64. The binaries are built for Ubuntu only.
+func DetectPlatform() (string, error) {
+	osRelease, err := os.ReadFile("/etc/os-release")
+	if err != nil {
+		return "", fmt.Errorf("detect the platform, set it explicitly: %w", err)
+	}
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(osRelease))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if found {
+			fields[key] = strings.Trim(value, `"`)
+		}
+	}
+	if fields["ID"] != "ubuntu" || fields["VERSION_ID"] == "" {
+		return "", fmt.Errorf("no release binaries are built for %s %s, set the platform explicitly",
+			fields["ID"], fields["VERSION_ID"])
+	}
+	switch runtime.GOARCH {
+	case "amd64":
+		return fmt.Sprintf("ubuntu-%s-amd64", fields["VERSION_ID"]), nil
+	case "arm64":
+		return fmt.Sprintf("ubuntu%s-aarch64", fields["VERSION_ID"]), nil
+	default:
+		return "", fmt.Errorf("no release binaries are built for %s, set the platform explicitly", runtime.GOARCH)
+	}
+}
+
+// Update installs the latest release of the channel, or the pinned version if wal-g is pinned
+func (u *Updater) Update(ctx context.Context, channel string) error {
+	pinned, err := u.PinnedVersion()
+	if err != nil {
+		return err
+	}
+	if pinned != "" {
+		tracelog.InfoLogger.Printf("wal-g is pinned to %s, the %s channel isn't followed", pinned, channel)
+	}
+	return u.install(ctx, channel, pinned)
+}
+
+// Pin installs the version and keeps the following updates at it until Unpin
+func (u *Updater) Pin(ctx context.Context, version string) error {
+	if err := u.install(ctx, "", version); err != nil {
+		return err
+	}
+	if err := os.WriteFile(u.pinPath(), []byte(version+"\n"), 0644); err != nil {
+		return fmt.Errorf("pin wal-g to %s: %w", version, err)
+	}
+	tracelog.InfoLogger.Printf("wal-g is pinned to %s", version)
+	return nil
+}
+
+// Unpin removes the pin of the running binary, so the updates follow the channel again
+func Unpin() error {
+	executable, err := executablePath()
+	if err != nil {
+		return err
+	}
+	return (&Updater{Executable: executable}).Unpin()
+}
+
+func (u *Updater) Unpin() error {
+	err := os.Remove(u.pinPath())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unpin wal-g: %w", err)
+	}
+	tracelog.InfoLogger.Println("wal-g is unpinned")
+	return nil
+}
+
+// PinnedVersion returns the version wal-g is pinned to, it is empty if wal-g isn't pinned
+func (u *Updater) PinnedVersion() (string, error) {
+	pin, err := os.ReadFile(u.pinPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read the wal-g pin: %w", err)
+	}
+	return strings.TrimSpace(string(pin)), nil
+}
+
+// pinPath is next to the binary, so the pin is kept as long as the binary is
+func (u *Updater) pinPath() string {
+	return u.Executable + pinSuffix
+}
+
+func (u *Updater) install(ctx context.Context, channel, version string) error {
+	// fail before downloading anything: without the keys no release can be verified
+	if u.VerifySignature && len(u.keys) == 0 {
+		return errNoReleaseKeys
+	}
+	release, err := u.fetchRelease(ctx, channel, version)
+	if err != nil {
+		return err
+	}
+	if release.TagName == u.CurrentVersion {
+		tracelog.InfoLogger.Printf("wal-g %s is up to date", u.CurrentVersion)
+		return nil
+	}
+
+	binary, ok := release.findAsset(u.Asset)
+	if !ok {
+		return fmt.Errorf("release %s has no binary %s", release.TagName, u.Asset)
+	}
+	checksum, err := u.readChecksum(ctx, release)
+	if err != nil {
+		return err
+	}
+	tmpPath, err := u.downloadBinary(ctx, binary, checksum)
+	if err != nil {
+		return err
+	}
+	// the rename within the directory replaces the binary atomically, the running process keeps the old one
+	if err := os.Rename(tmpPath, u.Executable); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("replace %s: %w", u.Executable, err)
+	}
+	tracelog.InfoLogger.Printf("wal-g is updated from %s to %s", u.CurrentVersion, release.TagName)
+	return nil
+}
+
+// readChecksum returns the SHA-256 of the binary from its checksum asset, the signature of which is verified
+// if VerifySignature is set
+func (u *Updater) readChecksum(ctx context.Context, release *release) ([]byte, error) {
+	asset, ok := release.findAsset(u.Asset + checksumSuffix)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no checksum of %s", release.TagName, u.Asset)
+	}
+	content, err := u.readSmallAsset(ctx, asset)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.VerifySignature {
+		signatureAsset, ok := release.findAsset(asset.Name + signatureSuffix)
+		if !ok {
+			return nil, fmt.Errorf("release %s isn't signed: no %s", release.TagName, asset.Name+signatureSuffix)
+		}
+		signature, err := u.readSmallAsset(ctx, signatureAsset)
+		if err != nil {
+			return nil, err
+		}
+		if err := verifySignature(u.keys, content, signature); err != nil {
+			return nil, fmt.Errorf("verify the signature of release %s: %w", release.TagName, err)
+		}
+	}
+
+	// the sha256sum output: "<hex digest>  <file name>"
+	digest, _, _ := strings.Cut(strings.TrimSpace(string(content)), " ")
+	checksum, err := hex.DecodeString(digest)
+	if err != nil || len(checksum) != sha256.Size {
+		return nil, fmt.Errorf("malformed checksum in %s", asset.Name)
+	}
+	return checksum, nil
+}
+
+func (u *Updater) readSmallAsset(ctx context.Context, asset releaseAsset) ([]byte, error) {
+	response, err := u.download(ctx, asset)
+	if err != nil {
+		return nil, err
+	}
+	defer utility.LoggedClose(response.Body, "")
+	content, err := io.ReadAll(io.LimitReader(response.Body, maxSmallAssetSize))
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", asset.Name, err)
+	}
+	return content, nil
+}
+
+// downloadBinary saves the binary next to the executable, so it can be renamed over it, and checks its checksum
+func (u *Updater) downloadBinary(ctx context.Context, asset releaseAsset, checksum []byte) (tmpPath string, err error) {
+	mode := os.FileMode(0755)
+	if stat, err := os.Stat(u.Executable); err == nil {
+		mode = stat.Mode().Perm()
+	}
+	file, err := os.CreateTemp(filepath.Dir(u.Executable), "."+filepath.Base(u.Executable)+".update-*")
+	if err != nil {
+		return "", fmt.Errorf("create the file for the new binary: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			utility.LoggedClose(file, "")
+			_ = os.Remove(file.Name())
+		}
+	}()
+
+	response, err := u.download(ctx, asset)
+	if err != nil {
+		return "", err
+	}
+	defer utility.LoggedClose(response.Body, "")
+	hash := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(file, hash), response.Body); err != nil {
+		return "", fmt.Errorf("download %s: %w", asset.Name, err)
+	}
+	if !bytes.Equal(hash.Sum(nil), checksum) {
+		return "", fmt.Errorf("checksum mismatch of the downloaded %s", asset.Name)
+	}
+	if err = file.Chmod(mode); err != nil {
+		return "", err
+	}
+	if err = file.Sync(); err != nil {
+		return "", err
+	}
+	if err = file.Close(); err != nil {
+		return "", err
+	}
+	return file.Name(), nil
+}
@@ -0,0 +1,209 @@
+package selfupdate
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAsset = "wal-g-pg-ubuntu-20.04-amd64"
+
+// releaseServer serves the releases API and the assets of the releases with the binaries
+type releaseServer struct {
+	*httptest.Server
+	releases []release
+	assets   map[string][]byte
+}
+
+func newReleaseServer(t *testing.T, key ed25519.PrivateKey, binaries map[string]string, prerelease string) *releaseServer {
+	server := &releaseServer{assets: make(map[string][]byte)}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.serve))
+	t.Cleanup(server.Close)
+
+	// the newest releases are listed first
+	for _, tag := range []string{prerelease, "v2.0.0", "v1.0.0"} {
+		binary, ok := binaries[tag]
+		if !ok {
+			continue
+		}
+		digest := sha256.Sum256([]byte(binary))
+		checksum := []byte(hex.EncodeToString(digest[:]) + "  " + testAsset + "\n")
+		files := map[string][]byte{
+			testAsset:                           []byte(binary),
+			testAsset + checksumSuffix:          checksum,
+			testAsset + checksumSuffix + ".sig": ed25519.Sign(key, checksum),
+		}
+		rel := release{TagName: tag, Prerelease: tag == prerelease}
+		for name, content := range files {
+			url := server.URL + "/download/" + tag + "/" + name
+			server.assets[url] = content
+			rel.Assets = append(rel.Assets, releaseAsset{Name: name, BrowserDownloadURL: url})
+		}
+		server.releases = append(server.releases, rel)
+	}
+	return server
+}
+
+func (server *releaseServer) serve(w http.ResponseWriter, r *http.Request) {
+	if content, ok := server.assets[server.URL+r.URL.Path]; ok {
+		_, _ = w.Write(content)
+		return
+	}
+	var response interface{}
+	switch {
+	case r.URL.Path == "/releases":
+		response = server.releases
+	case r.URL.Path == "/releases/latest":
+		for _, rel := range server.releases {
+			if !rel.Prerelease {
+				response = rel
+				break
+			}
+		}
+	default:
+		for _, rel := range server.releases {
+			if r.URL.Path == "/releases/tags/"+rel.TagName {
+				response = rel
+			}
+		}
+	}
+	if response == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func newTestUpdater(t *testing.T, server *releaseServer, key ed25519.PublicKey) *Updater {
+	executable := filepath.Join(t.TempDir(), "wal-g")
+	require.NoError(t, os.WriteFile(executable, []byte("v1.0.0 binary"), 0755))
+	return &Updater{
+		ReleasesURL:     server.URL + "/releases",
+		Asset:           testAsset,
+		Executable:      executable,
+		CurrentVersion:  "v1.0.0",
+		VerifySignature: true,
+		keys:            []ed25519.PublicKey{key},
+		client:          server.Client(),
+	}
+}
+
+func readBinary(t *testing.T, updater *Updater) string {
+	content, err := os.ReadFile(updater.Executable)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func generateKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	public, private, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	return public, private
+}
+
+func TestUpdater_Update(t *testing.T) {
+	public, private := generateKey(t)
+	server := newReleaseServer(t, private, map[string]string{
+		"v1.0.0": "v1.0.0 binary", "v2.0.0": "v2.0.0 binary", "v2.1.0-rc1": "v2.1.0-rc1 binary",
+	}, "v2.1.0-rc1")
+
+	updater := newTestUpdater(t, server, public)
+	require.NoError(t, updater.Update(context.Background(), StableChannel))
+	assert.Equal(t, "v2.0.0 binary", readBinary(t, updater))
+	stat, err := os.Stat(updater.Executable)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), stat.Mode().Perm())
+
+	updater = newTestUpdater(t, server, public)
+	require.NoError(t, updater.Update(context.Background(), PrereleaseChannel))
+	assert.Equal(t, "v2.1.0-rc1 binary", readBinary(t, updater))
+}
+
+func TestUpdater_UpdateVerifiesSignature(t *testing.T) {
+	_, private := generateKey(t)
+	otherPublic, _ := generateKey(t)
+	server := newReleaseServer(t, private, map[string]string{"v2.0.0": "v2.0.0 binary"}, "")
+
+	updater := newTestUpdater(t, server, otherPublic)
+	assert.Error(t, updater.Update(context.Background(), StableChannel))
+	assert.Equal(t, "v1.0.0 binary", readBinary(t, updater), "the binary isn't replaced")
+
+	updater.VerifySignature = false
+	require.NoError(t, updater.Update(context.Background(), StableChannel))
+	assert.Equal(t, "v2.0.0 binary", readBinary(t, updater))
+}
+
+func TestUpdater_UpdateVerifiesChecksum(t *testing.T) {
+	public, private := generateKey(t)
+	server := newReleaseServer(t, private, map[string]string{"v2.0.0": "v2.0.0 binary"}, "")
+	server.assets[server.URL+"/download/v2.0.0/"+testAsset] = []byte("tampered binary")
+
+	updater := newTestUpdater(t, server, public)
+	assert.Error(t, updater.Update(context.Background(), StableChannel))
+	assert.Equal(t, "v1.0.0 binary", readBinary(t, updater))
+	entries, err := os.ReadDir(filepath.Dir(updater.Executable))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "the downloaded binary is removed")
+}
+
+func TestUpdater_Pin(t *testing.T) {
+	public, private := generateKey(t)
+	server := newReleaseServer(t, private, map[string]string{"v1.0.0": "v1.0.0 binary", "v2.0.0": "v2.0.0 binary"}, "")
+
+	updater := newTestUpdater(t, server, public)
+	require.NoError(t, updater.Pin(context.Background(), "v1.0.0"))
+	pinned, err := updater.PinnedVersion()
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0", pinned)
+
+	require.NoError(t, updater.Update(context.Background(), StableChannel))
+	assert.Equal(t, "v1.0.0 binary", readBinary(t, updater), "the pinned binary isn't upgraded")
+
+	require.NoError(t, updater.Unpin())
+	require.NoError(t, updater.Update(context.Background(), StableChannel))
+	assert.Equal(t, "v2.0.0 binary", readBinary(t, updater))
+}
+
+func TestUpdater_AddKeys(t *testing.T) {
+	public, private := generateKey(t)
+	server := newReleaseServer(t, private, map[string]string{"v2.0.0": "v2.0.0 binary"}, "")
+	der, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+	keysPath := filepath.Join(t.TempDir(), "release_keys.pem")
+	keysPEM := append([]byte("mirror keys\n"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})...)
+	require.NoError(t, os.WriteFile(keysPath, keysPEM, 0600))
+
+	updater := newTestUpdater(t, server, nil)
+	updater.keys = nil
+	require.NoError(t, updater.AddKeys(keysPath))
+	require.NoError(t, updater.Update(context.Background(), StableChannel))
+	assert.Equal(t, "v2.0.0 binary", readBinary(t, updater))
+
+	require.NoError(t, os.WriteFile(keysPath, []byte("no keys"), 0600))
+	assert.Error(t, updater.AddKeys(keysPath))
+}
+
+func TestUpdater_UpdateRefusedWithoutKeys(t *testing.T) {
+	_, private := generateKey(t)
+	server := newReleaseServer(t, private, map[string]string{"v2.0.0": "v2.0.0 binary"}, "")
+
+	updater := newTestUpdater(t, server, nil)
+	updater.keys = nil
+	assert.ErrorIs(t, updater.Update(context.Background(), StableChannel), errNoReleaseKeys)
+	assert.Equal(t, "v1.0.0 binary", readBinary(t, updater), "the binary isn't replaced")
+
+	updater.VerifySignature = false
+	require.NoError(t, updater.Update(context.Background(), StableChannel))
+	assert.Equal(t, "v2.0.0 binary", readBinary(t, updater))
+}
@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/multistorage"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// PrepareSentinelUserData unmarshals the user data provided for the backup
+// and merges the output of WALG_SENTINEL_USER_DATA_CMD into it.
+func PrepareSentinelUserData(ctx context.Context, userDataRaw string) (interface{}, error) {
+	userData, err := UnmarshalSentinelUserData(userDataRaw)
+	if err != nil {
+		return nil, err
+	}
+	return ExtendSentinelUserData(ctx, userData)
+}
+
+// ExtendSentinelUserData runs the WALG_SENTINEL_USER_DATA_CMD command (if configured) and merges
+// the JSON object it prints into the user data. The fields provided explicitly take precedence
+// over the ones emitted by the command.
+func ExtendSentinelUserData(ctx context.Context, userData interface{}) (interface{}, error) {
+	if _, ok := conf.GetSetting(conf.SentinelUserDataCmdSetting); !ok {
+		return userData, nil
+	}
+	cmd, err := GetCommandSettingContext(ctx, conf.SentinelUserDataCmdSetting)
+	if err != nil {
+		return nil, err
+	}
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	err = cmd.Run()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to run %s", conf.SentinelUserDataCmdSetting)
+	}
+
+	var extension map[string]interface{}
+	err = json.Unmarshal(stdout.Bytes(), &extension)
+	if err != nil {
+		return nil, errors.Wrapf(newUnmarshallingError(stdout.String(), err),
+			"%s must print a JSON object", conf.SentinelUserDataCmdSetting)
+	}
+	return mergeSentinelUserData(userData, extension)
+}
+
+func mergeSentinelUserData(userData interface{}, extension map[string]interface{}) (interface{}, error) {
+	if userData == nil {
+		return extension, nil
+	}
+	fields, ok := userData.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("user data must be a JSON object to be extended by %s", conf.SentinelUserDataCmdSetting)
+	}
+	for key, value := range extension {
+		if _, exists := fields[key]; exists {
+			tracelog.WarningLogger.Printf("User data field '%s' is provided explicitly, ignoring the value from %s",
+				key, conf.SentinelUserDataCmdSetting)
+			continue
+		}
+		fields[key] = value
+	}
+	return fields, nil
+}
+
+// UserDataFilter selects the backups whose user data fields have the specified values.
+// Nested fields are addressed with dot-separated paths, e.g. "app.release".
+type UserDataFilter map[string]string
+
+func (filter UserDataFilter) Match(userData interface{}) bool {
+	for fieldPath, expected := range filter {
+		value, ok := lookupUserDataField(userData, fieldPath)
+		if !ok || formatUserDataValue(value) != expected {
+			return false
+		}
+	}
+	return true
+}
+
+func lookupUserDataField(userData interface{}, fieldPath string) (interface{}, bool) {
+	value := userData
+	for _, key := range strings.Split(fieldPath, ".") {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		value, ok = fields[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func formatUserDataValue(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+// FilterBackupsByUserData returns the backups whose metadata matches the filter
+func FilterBackupsByUserData(folder storage.Folder, backups []BackupTime, filter UserDataFilter,
+	metaFetcher GenericMetaFetcher) ([]BackupTime, error) {
+	if len(filter) == 0 {
+		return backups, nil
+	}
+	filtered := make([]BackupTime, 0, len(backups))
+	for _, backup := range backups {
+		specificFolder, err := multistorage.UseSpecificStorage(backup.StorageName, folder)
+		if err != nil {
+			return nil, err
+		}
+		meta, err := metaFetcher.Fetch(backup.BackupName, specificFolder)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to fetch metadata of backup %s", backup.BackupName)
+		}
+		if filter.Match(meta.UserData) {
+			filtered = append(filtered, backup)
+		}
+	}
+	return filtered, nil
+}
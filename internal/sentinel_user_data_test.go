@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeSentinelUserData(t *testing.T) {
+	extension := map[string]interface{}{"release": "1.2.0", "migration": float64(42)}
+
+	merged, err := mergeSentinelUserData(nil, extension)
+	require.NoError(t, err)
+	assert.Equal(t, extension, merged)
+
+	merged, err = mergeSentinelUserData(map[string]interface{}{"release": "1.1.0", "owner": "billing"}, extension)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"release": "1.1.0", "owner": "billing", "migration": float64(42)}, merged)
+
+	_, err = mergeSentinelUserData("plain string", extension)
+	assert.Error(t, err)
+}
+
+func TestUserDataFilter_Match(t *testing.T) {
+	userData := map[string]interface{}{
+		"app":       map[string]interface{}{"release": "1.2.0"},
+		"migration": float64(42),
+		"canary":    true,
+	}
+
+	assert.True(t, UserDataFilter{}.Match(nil))
+	assert.True(t, UserDataFilter{"app.release": "1.2.0", "migration": "42", "canary": "true"}.Match(userData))
+	assert.False(t, UserDataFilter{"app.release": "1.1.0"}.Match(userData))
+	assert.False(t, UserDataFilter{"app.release.major": "1"}.Match(userData))
+	assert.False(t, UserDataFilter{"owner": "billing"}.Match(userData))
+	assert.False(t, UserDataFilter{"migration": "42"}.Match("plain string"))
+}
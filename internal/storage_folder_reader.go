@@ -39,5 +39,9 @@ func PrepareMultiStorageFolderReader(folder storage.Folder, targetStorage string
 		return nil, err
 	}
 
+	folder, err = ConfigureObjectCache(folder)
+	if err != nil {
+		return nil, err
+	}
 	return NewFolderReader(folder), nil
 }
@@ -0,0 +1,256 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal/multistorage"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const leaseSettleDelay = time.Second
+
+type leaseRecord struct {
+	Owner     string    `json:"owner"`
+	Hostname  string    `json:"hostname"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// storageLease is an object in the storage that is held by a single owner until it expires.
+// The object is created with a conditional write on the storages that support it. Elsewhere it is taken
+// on a best-effort basis: the object is written, then read back after a short delay to check
+// that nobody has overwritten it.
+type storageLease struct {
+	folder storage.Folder
+	path   string
+	ttl    time.Duration
+	record leaseRecord
+	// version is the version of the lease object written by us, it is known on the storages with conditional writes
+	version string
+
+	stopRefresh chan struct{}
+	refreshDone sync.WaitGroup
+	lost        chan struct{}
+}
+
+var warnNoConditionalWrites sync.Once
+
+// conditionalFolderOf provides the conditional writes of the folder. Without them the concurrent modifications
+// of the metadata are detected on a best-effort basis only, which is warned about once per run.
+func conditionalFolderOf(folder storage.Folder) (storage.ConditionalFolder, bool) {
+	conditionalFolder, err := multistorage.ConditionalFolder(folder)
+	if err != nil {
+		warnNoConditionalWrites.Do(func() {
+			tracelog.WarningLogger.Printf("The concurrent modifications of the backups metadata are detected "+
+				"on a best-effort basis only: %v", err)
+		})
+		return nil, false
+	}
+	return conditionalFolder, true
+}
+
+func newStorageLease(folder storage.Folder, path string, ttl time.Duration, owner, hostname string) *storageLease {
+	return &storageLease{
+		folder: folder,
+		path:   path,
+		ttl:    ttl,
+		record: leaseRecord{Owner: owner, Hostname: hostname},
+		lost:   make(chan struct{}),
+	}
+}
+
+// tryAcquire takes the lease if it is free or expired, or prolongs it if it is already ours
+func (lease *storageLease) tryAcquire() (acquired bool, holder *leaseRecord, err error) {
+	conditionalFolder, isConditional := conditionalFolderOf(lease.folder)
+	holder, version, err := lease.read()
+	if err != nil {
+		return false, nil, err
+	}
+	if holder != nil && holder.Owner != lease.record.Owner && holder.ExpiresAt.After(time.Now()) {
+		return false, holder, nil
+	}
+	if holder != nil && holder.Owner != lease.record.Owner {
+		tracelog.WarningLogger.Printf("%s of %s has expired, taking it over", lease.path, holder.Hostname)
+	}
+
+	lease.record.ExpiresAt = time.Now().Add(lease.ttl)
+	content, err := json.Marshal(lease.record)
+	if err != nil {
+		return false, nil, err
+	}
+	if isConditional {
+		err = conditionalFolder.PutObjectIfVersion(context.Background(), lease.path, bytes.NewReader(content), version)
+		if _, ok := err.(storage.PreconditionFailedError); ok {
+			holder, _, err = lease.read()
+			return false, holder, err
+		}
+		if err != nil {
+			return false, nil, err
+		}
+		return lease.readOwnVersion()
+	}
+
+	err = lease.folder.PutObject(lease.path, bytes.NewReader(content))
+	if err != nil {
+		return false, nil, err
+	}
+	time.Sleep(leaseSettleDelay)
+	holder, _, err = lease.read()
+	if err != nil {
+		return false, nil, err
+	}
+	return holder != nil && holder.Owner == lease.record.Owner, holder, nil
+}
+
+// readOwnVersion remembers the version of the lease object just written by us, so it is refreshed conditionally
+func (lease *storageLease) readOwnVersion() (acquired bool, holder *leaseRecord, err error) {
+	holder, version, err := lease.read()
+	if err != nil {
+		return false, nil, err
+	}
+	if holder == nil || holder.Owner != lease.record.Owner {
+		return false, holder, nil
+	}
+	lease.version = version
+	return true, holder, nil
+}
+
+func (lease *storageLease) read() (*leaseRecord, string, error) {
+	var reader io.ReadCloser
+	var version string
+	var err error
+	if conditionalFolder, ok := conditionalFolderOf(lease.folder); ok {
+		reader, version, err = conditionalFolder.ReadObjectVersion(lease.path)
+	} else {
+		reader, err = lease.folder.ReadObject(lease.path)
+	}
+	if _, ok := err.(storage.ObjectNotFoundError); ok {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	defer utility.LoggedClose(reader, "")
+
+	var record leaseRecord
+	err = json.NewDecoder(reader).Decode(&record)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to read %s", lease.path)
+	}
+	return &record, version, nil
+}
+
+// startRefresh prolongs the lease while the operation is running. If the lease turns out to be taken over,
+// the refresh stops and Lost is closed.
+func (lease *storageLease) startRefresh() {
+	lease.stopRefresh = make(chan struct{})
+	lease.refreshDone.Add(1)
+	go func() {
+		defer lease.refreshDone.Done()
+		ticker := time.NewTicker(lease.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-lease.stopRefresh:
+				return
+			case <-ticker.C:
+				lost, holder, err := lease.refresh()
+				if err != nil {
+					tracelog.WarningLogger.Printf("Failed to prolong %s: %v", lease.path, err)
+					continue
+				}
+				if !lost {
+					continue
+				}
+				if holder != nil {
+					tracelog.ErrorLogger.Printf("%s has been taken over by %s (%s), giving it up",
+						lease.path, holder.Hostname, holder.Owner)
+				} else {
+					tracelog.ErrorLogger.Printf("%s has been removed by someone else, giving it up", lease.path)
+				}
+				close(lease.lost)
+				return
+			}
+		}
+	}()
+}
+
+// refresh prolongs the lease if it is still ours, otherwise it reports the lease as lost along with
+// the current holder, if any. On the storages that support it the lease is rewritten only if it hasn't changed
+// since our last write, elsewhere the holder is checked right before the write.
+func (lease *storageLease) refresh() (lost bool, holder *leaseRecord, err error) {
+	record := lease.record
+	record.ExpiresAt = time.Now().Add(lease.ttl)
+	content, err := json.Marshal(record)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if conditionalFolder, ok := conditionalFolderOf(lease.folder); ok {
+		err = conditionalFolder.PutObjectIfVersion(context.Background(), lease.path, bytes.NewReader(content),
+			lease.version)
+		if _, ok := err.(storage.PreconditionFailedError); ok {
+			holder, _, err = lease.read()
+			return err == nil, holder, err
+		}
+		if err != nil {
+			return false, nil, err
+		}
+		lease.record = record
+		acquired, holder, err := lease.readOwnVersion()
+		if err != nil {
+			return false, nil, err
+		}
+		return !acquired, holder, nil
+	}
+
+	holder, _, err = lease.read()
+	if err != nil {
+		return false, nil, err
+	}
+	if holder == nil || holder.Owner != lease.record.Owner {
+		return true, holder, nil
+	}
+	err = lease.folder.PutObject(lease.path, bytes.NewReader(content))
+	if err != nil {
+		return false, nil, err
+	}
+	lease.record = record
+	return false, nil, nil
+}
+
+// Lost is closed when the lease is taken over by someone else while we hold it
+func (lease *storageLease) Lost() <-chan struct{} {
+	return lease.lost
+}
+
+func (lease *storageLease) stop() {
+	close(lease.stopRefresh)
+	lease.refreshDone.Wait()
+}
+
+// release removes the lease object unless it has been taken over by someone else
+func (lease *storageLease) release() {
+	lease.stop()
+
+	holder, _, err := lease.read()
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to release %s: %v", lease.path, err)
+		return
+	}
+	if holder == nil || holder.Owner != lease.record.Owner {
+		tracelog.WarningLogger.Printf("%s has been taken over by someone else", lease.path)
+		return
+	}
+	err = lease.folder.DeleteObjects([]string{lease.path})
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to release %s: %v", lease.path, err)
+	}
+}
@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// unconditionalFolder hides the conditional writes of the wrapped folder
+type unconditionalFolder struct {
+	storage.Folder
+}
+
+func TestStorageLease_GivesUpTakenOverLease(t *testing.T) {
+	folders := map[string]storage.Folder{
+		"conditional":   memory.NewFolder("", memory.NewKVS()),
+		"unconditional": unconditionalFolder{memory.NewFolder("", memory.NewKVS())},
+	}
+	for name, folder := range folders {
+		t.Run(name, func(t *testing.T) {
+			lease := newStorageLease(folder, "test.lease", 150*time.Millisecond, "owner-a", "host-a")
+			acquired, _, err := lease.tryAcquire()
+			require.NoError(t, err)
+			require.True(t, acquired)
+			lease.startRefresh()
+			defer lease.release()
+
+			// the lease is prolonged while it is ours
+			time.Sleep(120 * time.Millisecond)
+			holder, _, err := lease.read()
+			require.NoError(t, err)
+			assert.Equal(t, "owner-a", holder.Owner)
+
+			// someone else takes the lease over, e.g. after a long pause of the holder
+			content, err := json.Marshal(leaseRecord{Owner: "owner-b", Hostname: "host-b",
+				ExpiresAt: time.Now().Add(time.Hour)})
+			require.NoError(t, err)
+			require.NoError(t, folder.PutObject("test.lease", bytes.NewReader(content)))
+
+			select {
+			case <-lease.Lost():
+			case <-time.After(time.Second):
+				t.Fatal("the lease hasn't been given up")
+			}
+			holder, _, err = lease.read()
+			require.NoError(t, err)
+			assert.Equal(t, "owner-b", holder.Owner)
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const StorageUsageCacheFileName = ".walg_storage_usage_cache"
+
+type StorageQuotaAction string
+
+const (
+	StorageQuotaFail   StorageQuotaAction = "fail"
+	StorageQuotaWarn   StorageQuotaAction = "warn"
+	StorageQuotaRetain StorageQuotaAction = "retain"
+)
+
+// StorageQuota limits the total size of the objects under the configured storage prefix
+type StorageQuota struct {
+	Limit       int64
+	Action      StorageQuotaAction
+	RetainCount int
+	// UsageMaxAge is how long the usage computed by a previous run can be reused
+	UsageMaxAge time.Duration
+	// UsageCache is the path to the local file with the usage computed by previous runs
+	UsageCache string
+}
+
+// ConfigureStorageQuota returns nil if WALG_STORAGE_QUOTA is not set
+func ConfigureStorageQuota() (*StorageQuota, error) {
+	if !viper.IsSet(conf.StorageQuotaSetting) {
+		return nil, nil
+	}
+	quota := &StorageQuota{
+		Limit:       int64(viper.GetSizeInBytes(conf.StorageQuotaSetting)),
+		Action:      StorageQuotaAction(strings.ToLower(viper.GetString(conf.StorageQuotaActionSetting))),
+		RetainCount: viper.GetInt(conf.StorageQuotaRetainSetting),
+	}
+	if quota.Limit <= 0 {
+		return nil, fmt.Errorf("%s must be positive", conf.StorageQuotaSetting)
+	}
+
+	switch quota.Action {
+	case StorageQuotaFail, StorageQuotaWarn:
+	case StorageQuotaRetain:
+		if quota.RetainCount <= 0 {
+			return nil, fmt.Errorf("%s must be positive for the '%s' quota action", conf.StorageQuotaRetainSetting, quota.Action)
+		}
+	default:
+		return nil, fmt.Errorf("unknown %s value '%s', expected one of: %s, %s, %s", conf.StorageQuotaActionSetting,
+			quota.Action, StorageQuotaFail, StorageQuotaWarn, StorageQuotaRetain)
+	}
+
+	var err error
+	quota.UsageMaxAge, err = conf.GetDurationSetting(conf.StorageQuotaUsageAgeSetting)
+	if err != nil {
+		return nil, err
+	}
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		quota.UsageCache = filepath.Join(homeDir, StorageUsageCacheFileName)
+	}
+	return quota, nil
+}
+
+// EnforceStorageQuota enforces the configured quota, if any, before pushing a new backup into the folder
+func EnforceStorageQuota(folder storage.Folder, makeRoom func(retainCount int) error) error {
+	quota, err := ConfigureStorageQuota()
+	if err != nil || quota == nil {
+		return err
+	}
+	return quota.Enforce(folder, makeRoom)
+}
+
+// Enforce checks the storage usage before a new backup is pushed into the folder. Depending on the action,
+// it either fails, logs a warning or calls makeRoom to delete old backups when the quota is exceeded.
+func (quota *StorageQuota) Enforce(folder storage.Folder, makeRoom func(retainCount int) error) error {
+	usage, err := quota.getUsage(folder, false)
+	if err != nil {
+		return errors.Wrap(err, "failed to get storage usage")
+	}
+	tracelog.InfoLogger.Printf("Storage usage is %d of %d bytes quota", usage, quota.Limit)
+	if usage < quota.Limit {
+		return nil
+	}
+
+	switch quota.Action {
+	case StorageQuotaWarn:
+		tracelog.WarningLogger.Printf("Storage quota is exceeded: %d of %d bytes used", usage, quota.Limit)
+		return nil
+	case StorageQuotaRetain:
+		tracelog.WarningLogger.Printf("Storage quota is exceeded: %d of %d bytes used, keeping %d latest full backups",
+			usage, quota.Limit, quota.RetainCount)
+		err = makeRoom(quota.RetainCount)
+		if err != nil {
+			return errors.Wrap(err, "failed to make room for the backup")
+		}
+		usage, err = quota.getUsage(folder, true)
+		if err != nil {
+			return errors.Wrap(err, "failed to get storage usage")
+		}
+		if usage < quota.Limit {
+			return nil
+		}
+	}
+	return newStorageQuotaExceededError(usage, quota.Limit)
+}
+
+// storageUsageCacheMutex keeps the concurrent uploads of a single invocation from losing each other's usage
+var storageUsageCacheMutex sync.Mutex
+
+type storageUsageCacheEntry struct {
+	Usage     int64     `json:"usage"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// getUsage returns the cached usage if it is fresh enough, so frequent backup-push runs don't list
+// the whole storage every time.
+func (quota *StorageQuota) getUsage(folder storage.Folder, refresh bool) (int64, error) {
+	storageUsageCacheMutex.Lock()
+	defer storageUsageCacheMutex.Unlock()
+
+	cache := quota.readUsageCache()
+	key := folder.GetPath()
+	if entry, ok := cache[key]; ok && !refresh && time.Since(entry.Timestamp) < quota.UsageMaxAge {
+		return entry.Usage, nil
+	}
+
+	usage, err := GetStorageUsage(folder)
+	if err != nil {
+		return 0, err
+	}
+	cache[key] = storageUsageCacheEntry{Usage: usage, Timestamp: time.Now()}
+	quota.writeUsageCache(cache)
+	return usage, nil
+}
+
+// trackStorageUsage counts the uploaded bytes into size if the storage quota is used. The seekable content
+// is measured without wrapping it, so the storage still sees its interfaces, e.g. io.ReaderAt.
+func trackStorageUsage(content io.Reader, size *int64) io.Reader {
+	if !viper.IsSet(conf.StorageQuotaSetting) {
+		return content
+	}
+	if seeker, ok := content.(io.Seeker); ok {
+		current, err := seeker.Seek(0, io.SeekCurrent)
+		if err == nil {
+			var end int64
+			end, err = seeker.Seek(0, io.SeekEnd)
+			if err == nil {
+				*size = end - current
+				_, err = seeker.Seek(current, io.SeekStart)
+			}
+		}
+		if err == nil {
+			return content
+		}
+	}
+	return utility.NewWithSizeReader(content, size)
+}
+
+// AddStorageUsage adds the size of the uploaded object to the cached usage of the storages holding the folder,
+// so the usage stays close to the real one until the storage is listed again
+func AddStorageUsage(folder storage.Folder, size int64) {
+	if size == 0 || !viper.IsSet(conf.StorageQuotaSetting) {
+		return
+	}
+	quota, err := ConfigureStorageQuota()
+	if err != nil || quota == nil {
+		return
+	}
+	quota.addUsage(folder.GetPath(), size)
+}
+
+func (quota *StorageQuota) addUsage(path string, size int64) {
+	storageUsageCacheMutex.Lock()
+	defer storageUsageCacheMutex.Unlock()
+
+	cache := quota.readUsageCache()
+	updated := false
+	for key, entry := range cache {
+		if strings.HasPrefix(path, key) {
+			entry.Usage += size
+			cache[key] = entry
+			updated = true
+		}
+	}
+	if updated {
+		quota.writeUsageCache(cache)
+	}
+}
+
+func (quota *StorageQuota) readUsageCache() map[string]storageUsageCacheEntry {
+	cache := make(map[string]storageUsageCacheEntry)
+	if quota.UsageCache == "" {
+		return cache
+	}
+	content, err := os.ReadFile(quota.UsageCache)
+	if err == nil {
+		err = json.Unmarshal(content, &cache)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		tracelog.WarningLogger.Printf("Failed to read storage usage cache: %v", err)
+	}
+	return cache
+}
+
+func (quota *StorageQuota) writeUsageCache(cache map[string]storageUsageCacheEntry) {
+	if quota.UsageCache == "" {
+		return
+	}
+	content, err := json.Marshal(cache)
+	if err == nil {
+		err = os.WriteFile(quota.UsageCache, content, 0600)
+	}
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to write storage usage cache: %v", err)
+	}
+}
+
+// GetStorageUsage returns the total size of all the objects in the folder
+func GetStorageUsage(folder storage.Folder) (int64, error) {
+	objects, err := storage.ListFolderRecursively(folder)
+	if err != nil {
+		return 0, err
+	}
+	var usage int64
+	for _, object := range objects {
+		usage += object.GetSize()
+	}
+	return usage, nil
+}
+
+// DeleteRetainFull deletes everything before the retainCount-th latest full backup. The deletion is refused
+// by the same guardrail and minimum PITR window checks as the delete command, and it holds the metadata lock.
+func (h *DeleteHandler) DeleteRetainFull(retainCount int, findLogGap LogGapFinder) error {
+	lock, err := AcquireMetadataLock(h.Folder)
+	if err != nil {
+		return errors.Wrap(err, "failed to lock the backups metadata")
+	}
+	defer lock.Release()
+
+	target, err := h.FindTargetRetain(retainCount, FullDeleteModifier)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		tracelog.InfoLogger.Printf("No backup found for deletion")
+		return nil
+	}
+	explanation, err := h.ExplainDeleteBeforeTargetWhere(target, fmt.Sprintf("retain FULL %d", retainCount),
+		func(storage.Object) bool { return true })
+	if err != nil {
+		return err
+	}
+	err = CheckPITRWindow(explanation, findLogGap, false)
+	if err != nil {
+		return err
+	}
+	return h.DeleteBeforeTarget(target, true)
+}
+
+type StorageQuotaExceededError struct {
+	error
+}
+
+func newStorageQuotaExceededError(usage, limit int64) StorageQuotaExceededError {
+	return StorageQuotaExceededError{errors.Errorf("storage quota is exceeded: %d of %d bytes used", usage, limit)}
+}
+
+func (err StorageQuotaExceededError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
@@ -0,0 +1,98 @@
+package internal_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+func TestStorageQuota_Enforce(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewKVS())
+	require.NoError(t, folder.PutObject("basebackups_005/base_1/part_1.tar.lz4", bytes.NewReader(make([]byte, 60))))
+	require.NoError(t, folder.PutObject("basebackups_005/base_2/part_1.tar.lz4", bytes.NewReader(make([]byte, 60))))
+
+	newQuota := func(action internal.StorageQuotaAction) *internal.StorageQuota {
+		return &internal.StorageQuota{
+			Limit:       100,
+			Action:      action,
+			RetainCount: 1,
+			UsageCache:  filepath.Join(t.TempDir(), "usage"),
+		}
+	}
+	noRoom := func(int) error {
+		t.Fatal("unexpected retention")
+		return nil
+	}
+
+	t.Run("passes under the quota", func(t *testing.T) {
+		quota := newQuota(internal.StorageQuotaFail)
+		quota.Limit = 200
+		assert.NoError(t, quota.Enforce(folder, noRoom))
+	})
+
+	t.Run("fails over the quota", func(t *testing.T) {
+		err := newQuota(internal.StorageQuotaFail).Enforce(folder, noRoom)
+		assert.IsType(t, internal.StorageQuotaExceededError{}, err)
+	})
+
+	t.Run("warns over the quota", func(t *testing.T) {
+		assert.NoError(t, newQuota(internal.StorageQuotaWarn).Enforce(folder, noRoom))
+	})
+
+	t.Run("runs retention over the quota", func(t *testing.T) {
+		var retained int
+		err := newQuota(internal.StorageQuotaRetain).Enforce(folder, func(retainCount int) error {
+			retained = retainCount
+			return folder.DeleteObjects([]string{"basebackups_005/base_1/part_1.tar.lz4"})
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, retained)
+	})
+}
+
+func TestStorageQuota_UsesCachedUsage(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewKVS())
+	require.NoError(t, folder.PutObject("wal_005/000000010000000000000001.lz4", bytes.NewReader(make([]byte, 60))))
+	quota := &internal.StorageQuota{
+		Limit:       100,
+		Action:      internal.StorageQuotaFail,
+		UsageMaxAge: time.Hour,
+		UsageCache:  filepath.Join(t.TempDir(), "usage"),
+	}
+	require.NoError(t, quota.Enforce(folder, nil))
+
+	// the cached usage is still under the quota
+	require.NoError(t, folder.PutObject("wal_005/000000010000000000000002.lz4", bytes.NewReader(make([]byte, 60))))
+	assert.NoError(t, quota.Enforce(folder, nil))
+
+	quota.UsageMaxAge = 0
+	assert.Error(t, quota.Enforce(folder, nil))
+}
+
+func TestAddStorageUsage(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	viper.Set(conf.StorageQuotaSetting, "100")
+	viper.Set(conf.StorageQuotaUsageAgeSetting, "1h")
+	defer viper.Set(conf.StorageQuotaSetting, nil)
+	defer viper.Set(conf.StorageQuotaUsageAgeSetting, nil)
+	folder := memory.NewFolder("", memory.NewKVS())
+	require.NoError(t, folder.PutObject("wal_005/000000010000000000000001.lz4", bytes.NewReader(make([]byte, 60))))
+	quota, err := internal.ConfigureStorageQuota()
+	require.NoError(t, err)
+	require.NoError(t, quota.Enforce(folder, nil))
+
+	// the upload is added to the cached usage, the storage isn't listed again
+	uploader := internal.NewRegularUploader(nil, folder.GetSubFolder("wal_005"))
+	require.NoError(t, uploader.Upload(context.Background(), "000000010000000000000002.lz4",
+		bytes.NewReader(make([]byte, 60))))
+	assert.IsType(t, internal.StorageQuotaExceededError{}, quota.Enforce(folder, nil))
+}
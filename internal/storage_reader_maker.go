@@ -3,6 +3,7 @@ package internal
 import (
 	"io"
 
+	"github.com/wal-g/wal-g/internal/multistorage"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 )
 
@@ -13,14 +14,16 @@ type StorageReaderMaker struct {
 	localPath       string
 	StorageFileType FileType
 	FileMode        int64
+	// RestoreReport counts the downloads in the report of the restore, if any
+	RestoreReport *RestoreReporter
 }
 
 func NewStorageReaderMaker(folder storage.Folder, relativePath string) *StorageReaderMaker {
-	return &StorageReaderMaker{folder, relativePath, relativePath, TarFileType, 0}
+	return &StorageReaderMaker{folder, relativePath, relativePath, TarFileType, 0, nil}
 }
 
 func NewRegularFileStorageReaderMarker(folder storage.Folder, storagePath, localPath string, fileMode int64) *StorageReaderMaker {
-	return &StorageReaderMaker{folder, storagePath, localPath, RegularFileType, fileMode}
+	return &StorageReaderMaker{folder, storagePath, localPath, RegularFileType, fileMode, nil}
 }
 
 func (readerMaker *StorageReaderMaker) StoragePath() string { return readerMaker.storagePath }
@@ -28,7 +31,16 @@ func (readerMaker *StorageReaderMaker) StoragePath() string { return readerMaker
 func (readerMaker *StorageReaderMaker) LocalPath() string { return readerMaker.localPath }
 
 func (readerMaker *StorageReaderMaker) Reader() (io.ReadCloser, error) {
-	return readerMaker.Folder.ReadObject(readerMaker.storagePath)
+	readCloser, storageName, err := multistorage.ReadObject(readerMaker.Folder, readerMaker.storagePath)
+	if err != nil {
+		return nil, err
+	}
+	recordFetchSource(readerMaker.Folder, readerMaker.storagePath, storageName)
+	return readerMaker.RestoreReport.CountDownload(readCloser), nil
+}
+
+func (readerMaker *StorageReaderMaker) restoreReport() *RestoreReporter {
+	return readerMaker.RestoreReport
 }
 
 func (readerMaker *StorageReaderMaker) FileType() FileType { return readerMaker.StorageFileType }
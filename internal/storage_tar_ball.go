@@ -25,6 +25,8 @@ type StorageTarBall struct {
 	tarWriter   *tar.Writer
 	uploader    Uploader
 	name        string
+
+	skipCompression bool
 }
 
 func (tarBall *StorageTarBall) Name() string {
@@ -34,11 +36,13 @@ func (tarBall *StorageTarBall) Name() string {
 // SetUp creates a new tar writer and starts upload to storage.
 // Upload will block until the tar file is finished writing.
 // If a name for the file is not given, default name is of
-// the form `part_....tar.[Compressor file extension]`.
+// the form `part_....tar.[Compressor file extension]`, or `part_raw_....tar` if the compression is skipped.
 func (tarBall *StorageTarBall) SetUp(crypter crypto.Crypter, names ...string) {
 	if tarBall.tarWriter == nil {
 		if len(names) > 0 {
 			tarBall.name = names[0]
+		} else if tarBall.skipCompression {
+			tarBall.name = fmt.Sprintf("part_raw_%0.3d.tar", tarBall.partNumber)
 		} else {
 			tarBall.name = fmt.Sprintf("part_%0.3d.tar.%v", tarBall.partNumber, tarBall.uploader.Compression().FileExtension())
 		}
@@ -83,6 +87,9 @@ func (tarBall *StorageTarBall) startUpload(name string, crypter crypto.Crypter)
 
 	tracelog.InfoLogger.Printf("Starting part %d ...\n", tarBall.partNumber)
 
+	scanInput, err := uploader.StartPlaintextScan(context.Background(), path)
+	tracelog.ErrorLogger.FatalfOnError("upload: failed to start the upload scanner: %v", err)
+
 	go func() {
 		err := uploader.Upload(context.Background(), path, pipeReader)
 		if compressingError, ok := err.(CompressAndEncryptError); ok {
@@ -111,8 +118,15 @@ func (tarBall *StorageTarBall) startUpload(name string, crypter crypto.Crypter)
 		writerToCompress = &utility.CascadeWriteCloser{WriteCloser: encryptedWriter, Underlying: pipeWriter}
 	}
 
-	return &utility.CascadeWriteCloser{WriteCloser: uploader.Compression().NewWriter(writerToCompress),
-		Underlying: writerToCompress}
+	if tarBall.skipCompression {
+		return &scanningWriteCloser{WriteCloser: writerToCompress, scanInput: scanInput}
+	}
+
+	return &scanningWriteCloser{
+		WriteCloser: &utility.CascadeWriteCloser{WriteCloser: uploader.Compression().NewWriter(writerToCompress),
+			Underlying: writerToCompress},
+		scanInput: scanInput,
+	}
 }
 
 // Size accumulated in this tarball
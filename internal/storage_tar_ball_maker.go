@@ -2,13 +2,20 @@ package internal
 
 // StorageTarBallMaker creates tarballs that are uploaded to storage.
 type StorageTarBallMaker struct {
-	partCount  int
-	backupName string
-	uploader   Uploader
+	partCount       int
+	backupName      string
+	uploader        Uploader
+	skipCompression bool
 }
 
 func NewStorageTarBallMaker(backupName string, uploader Uploader) *StorageTarBallMaker {
-	return &StorageTarBallMaker{0, backupName, uploader}
+	return &StorageTarBallMaker{0, backupName, uploader, false}
+}
+
+// NewUncompressedStorageTarBallMaker creates the maker of tarballs that are uploaded without compression,
+// it is used for the files that are already compressed.
+func NewUncompressedStorageTarBallMaker(backupName string, uploader Uploader) *StorageTarBallMaker {
+	return &StorageTarBallMaker{0, backupName, uploader, true}
 }
 
 // Make returns a tarball with required storage fields.
@@ -24,5 +31,7 @@ func (tarBallMaker *StorageTarBallMaker) Make(dedicatedUploader bool) TarBall {
 		backupName: tarBallMaker.backupName,
 		uploader:   uploader,
 		partSize:   &size,
+
+		skipCompression: tarBallMaker.skipCompression,
 	}
 }
@@ -0,0 +1,48 @@
+package storageevents
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type EventKind string
+
+const (
+	ObjectCreated EventKind = "created"
+	ObjectRemoved EventKind = "removed"
+)
+
+// Event is the notification about the change of the object in the bucket
+type Event struct {
+	Kind EventKind
+	// Key is the path of the object from the bucket root
+	Key  string
+	Size int64
+	// MD5 is the hex MD5 of the object content, empty if the storage doesn't report it, e.g. for the multipart uploads
+	MD5  string
+	Time time.Time
+}
+
+func (event Event) String() string {
+	return fmt.Sprintf("%s %s at %s", event.Kind, event.Key, event.Time.Format(time.RFC3339))
+}
+
+// HandleFunc processes the event, the event is delivered again if the handler fails
+type HandleFunc func(event Event) error
+
+// Source delivers the bucket notification events
+type Source interface {
+	// Consume receives the events until the context is done, the message is acknowledged
+	// when all of its events are handled
+	Consume(ctx context.Context, handle HandleFunc) error
+}
+
+func handleAll(events []Event, handle HandleFunc) error {
+	for _, event := range events {
+		if err := handle(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package storageevents_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/storageevents"
+)
+
+const s3EventBody = `{"Records":[
+{"eventName":"ObjectCreated:Put","eventTime":"2024-05-01T10:00:00.000Z",
+ "s3":{"object":{"key":"walg/wal_005/000000010000000000000002.lz4","size":1024,"eTag":"0123456789abcdef0123456789abcdef"}}},
+{"eventName":"ObjectCreated:CompleteMultipartUpload","eventTime":"2024-05-01T10:00:01.000Z",
+ "s3":{"object":{"key":"walg/basebackups_005/base+1/tar_partitions/part_1.tar.lz4","size":2048,"eTag":"0123-2"}}},
+{"eventName":"ObjectRemoved:Delete","eventTime":"2024-05-01T10:00:02.000Z",
+ "s3":{"object":{"key":"walg/wal_005/000000010000000000000001.lz4"}}},
+{"eventName":"ObjectRestore:Completed","eventTime":"2024-05-01T10:00:03.000Z",
+ "s3":{"object":{"key":"walg/wal_005/000000010000000000000001.lz4"}}}
+]}`
+
+func TestParseS3Events(t *testing.T) {
+	events, err := storageevents.ParseS3Events([]byte(s3EventBody))
+	require.NoError(t, err)
+
+	require.Len(t, events, 3)
+	assert.Equal(t, storageevents.Event{
+		Kind: storageevents.ObjectCreated,
+		Key:  "walg/wal_005/000000010000000000000002.lz4",
+		Size: 1024,
+		MD5:  "0123456789abcdef0123456789abcdef",
+		Time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+	}, events[0])
+	assert.Equal(t, "walg/basebackups_005/base 1/tar_partitions/part_1.tar.lz4", events[1].Key)
+	assert.Empty(t, events[1].MD5)
+	assert.Equal(t, storageevents.ObjectRemoved, events[2].Kind)
+}
+
+func TestParseS3Events_FromSNS(t *testing.T) {
+	body, err := json.Marshal(map[string]string{"Type": "Notification", "Message": s3EventBody})
+	require.NoError(t, err)
+
+	events, err := storageevents.ParseS3Events(body)
+	require.NoError(t, err)
+	assert.Len(t, events, 3)
+}
+
+func TestParseS3Events_TestEvent(t *testing.T) {
+	events, err := storageevents.ParseS3Events([]byte(`{"Service":"Amazon S3","Event":"s3:TestEvent"}`))
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestParsePubSubEvent(t *testing.T) {
+	object := `{"name":"walg/wal_005/000000010000000000000002.lz4","size":"1024",` +
+		`"md5Hash":"ASNFZ4mrze8BI0VniavN7w==","updated":"2024-05-01T10:00:00Z"}`
+	message := storageevents.PubSubMessage{
+		Data: base64.StdEncoding.EncodeToString([]byte(object)),
+		Attributes: map[string]string{
+			"eventType": "OBJECT_FINALIZE",
+			"objectId":  "walg/wal_005/000000010000000000000002.lz4",
+		},
+	}
+
+	events, err := storageevents.ParsePubSubEvent(message)
+	require.NoError(t, err)
+
+	assert.Equal(t, []storageevents.Event{{
+		Kind: storageevents.ObjectCreated,
+		Key:  "walg/wal_005/000000010000000000000002.lz4",
+		Size: 1024,
+		MD5:  "0123456789abcdef0123456789abcdef",
+		Time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+	}}, events)
+}
+
+func TestParsePubSubEvent_SkipsArchive(t *testing.T) {
+	events, err := storageevents.ParsePubSubEvent(storageevents.PubSubMessage{
+		Attributes: map[string]string{"eventType": "OBJECT_ARCHIVE", "objectId": "walg/wal_005/1"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
@@ -0,0 +1,170 @@
+package storageevents
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	pubSubEndpoint    = "https://pubsub.googleapis.com/v1/"
+	pubSubScope       = "https://www.googleapis.com/auth/pubsub"
+	pubSubMaxMessages = 100
+	pubSubRetryWait   = 5 * time.Second
+)
+
+// PubSubSource receives the GCS object change notifications from the Pub/Sub subscription
+// like projects/<project>/subscriptions/<subscription>
+type PubSubSource struct {
+	client       *http.Client
+	subscription string
+}
+
+func NewPubSubSource(ctx context.Context, subscription string) (*PubSubSource, error) {
+	client, err := google.DefaultClient(ctx, pubSubScope)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the Pub/Sub client")
+	}
+	return &PubSubSource{client: client, subscription: subscription}, nil
+}
+
+type PubSubMessage struct {
+	Data        string            `json:"data"`
+	Attributes  map[string]string `json:"attributes"`
+	MessageID   string            `json:"messageId"`
+	PublishTime time.Time         `json:"publishTime"`
+}
+
+type pubSubPullResponse struct {
+	ReceivedMessages []struct {
+		AckID   string        `json:"ackId"`
+		Message PubSubMessage `json:"message"`
+	} `json:"receivedMessages"`
+}
+
+func (source *PubSubSource) Consume(ctx context.Context, handle HandleFunc) error {
+	for ctx.Err() == nil {
+		var response pubSubPullResponse
+		err := source.call(ctx, "pull", map[string]interface{}{"maxMessages": pubSubMaxMessages}, &response)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			tracelog.WarningLogger.Printf("Failed to receive the events from %s: %v", source.subscription, err)
+			time.Sleep(pubSubRetryWait)
+			continue
+		}
+
+		ackIDs := make([]string, 0, len(response.ReceivedMessages))
+		for _, received := range response.ReceivedMessages {
+			events, err := ParsePubSubEvent(received.Message)
+			if err == nil {
+				err = handleAll(events, handle)
+			}
+			if err != nil {
+				tracelog.WarningLogger.Printf("Failed to handle the event message %s, it will be delivered again: %v",
+					received.Message.MessageID, err)
+				continue
+			}
+			ackIDs = append(ackIDs, received.AckID)
+		}
+		if len(ackIDs) > 0 {
+			if err = source.call(ctx, "acknowledge", map[string]interface{}{"ackIds": ackIDs}, nil); err != nil {
+				tracelog.WarningLogger.Printf("Failed to acknowledge the event messages: %v", err)
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+func (source *PubSubSource) call(ctx context.Context, method string, request, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s%s:%s", pubSubEndpoint, source.subscription, method)
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpResponse, err := source.client.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+	responseBody, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return err
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s: %s", method, httpResponse.Status, responseBody)
+	}
+	if response == nil {
+		return nil
+	}
+	return json.Unmarshal(responseBody, response)
+}
+
+type gcsObject struct {
+	Name    string    `json:"name"`
+	Size    string    `json:"size"`
+	MD5Hash string    `json:"md5Hash"`
+	Updated time.Time `json:"updated"`
+}
+
+// ParsePubSubEvent parses the GCS notification in the JSON_API_V1 payload format. The OBJECT_ARCHIVE
+// and OBJECT_METADATA_UPDATE notifications don't change the current objects, so they are skipped.
+func ParsePubSubEvent(message PubSubMessage) ([]Event, error) {
+	var kind EventKind
+	switch message.Attributes["eventType"] {
+	case "OBJECT_FINALIZE":
+		kind = ObjectCreated
+	case "OBJECT_DELETE":
+		kind = ObjectRemoved
+	default:
+		return nil, nil
+	}
+	event := Event{Kind: kind, Key: message.Attributes["objectId"], Time: message.PublishTime}
+
+	data, err := base64.StdEncoding.DecodeString(message.Data)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid message data")
+	}
+	if len(data) == 0 {
+		// the notification is configured with the NONE payload format
+		return []Event{event}, nil
+	}
+	var object gcsObject
+	if err = json.Unmarshal(data, &object); err != nil {
+		return nil, errors.Wrap(err, "invalid object resource")
+	}
+	if object.Size != "" {
+		if event.Size, err = strconv.ParseInt(object.Size, 10, 64); err != nil {
+			return nil, errors.Wrapf(err, "invalid object size %q", object.Size)
+		}
+	}
+	// the composite objects have no MD5
+	if object.MD5Hash != "" {
+		md5, err := base64.StdEncoding.DecodeString(object.MD5Hash)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid object MD5 %q", object.MD5Hash)
+		}
+		event.MD5 = hex.EncodeToString(md5)
+	}
+	if !object.Updated.IsZero() {
+		event.Time = object.Updated
+	}
+	return []Event{event}, nil
+}
@@ -0,0 +1,152 @@
+package storageevents
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+const (
+	sqsMaxMessages     = 10
+	sqsWaitTimeSeconds = 20
+	sqsRetryWait       = 5 * time.Second
+)
+
+// SQSSource receives the S3 event notifications delivered to the SQS queue directly or through SNS
+type SQSSource struct {
+	client   *sqs.SQS
+	queueURL string
+}
+
+func NewSQSSource(queueURL string) (*SQSSource, error) {
+	config := aws.NewConfig()
+	if region := sqsQueueRegion(queueURL); region != "" {
+		config = config.WithRegion(region)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{Config: *config, SharedConfigState: session.SharedConfigEnable})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the SQS session")
+	}
+	return &SQSSource{client: sqs.New(sess), queueURL: queueURL}, nil
+}
+
+// sqsQueueRegion extracts the region from the queue URL like https://sqs.eu-west-1.amazonaws.com/123/queue
+func sqsQueueRegion(queueURL string) string {
+	parsed, err := url.Parse(queueURL)
+	if err != nil {
+		return ""
+	}
+	hostParts := strings.Split(parsed.Hostname(), ".")
+	if len(hostParts) < 3 || hostParts[0] != "sqs" {
+		return ""
+	}
+	return hostParts[1]
+}
+
+func (source *SQSSource) Consume(ctx context.Context, handle HandleFunc) error {
+	for ctx.Err() == nil {
+		output, err := source.client.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(source.queueURL),
+			MaxNumberOfMessages: aws.Int64(sqsMaxMessages),
+			WaitTimeSeconds:     aws.Int64(sqsWaitTimeSeconds),
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			tracelog.WarningLogger.Printf("Failed to receive the events from %s: %v", source.queueURL, err)
+			time.Sleep(sqsRetryWait)
+			continue
+		}
+		for _, message := range output.Messages {
+			source.handleMessage(ctx, message, handle)
+		}
+	}
+	return ctx.Err()
+}
+
+func (source *SQSSource) handleMessage(ctx context.Context, message *sqs.Message, handle HandleFunc) {
+	events, err := ParseS3Events([]byte(aws.StringValue(message.Body)))
+	if err != nil {
+		// the message is left in the queue to be moved to the dead-letter queue, if any
+		tracelog.WarningLogger.Printf("Failed to parse the event message %s: %v", aws.StringValue(message.MessageId), err)
+		return
+	}
+	if err = handleAll(events, handle); err != nil {
+		tracelog.WarningLogger.Printf("Failed to handle the event message %s, it will be delivered again: %v",
+			aws.StringValue(message.MessageId), err)
+		return
+	}
+	_, err = source.client.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(source.queueURL),
+		ReceiptHandle: message.ReceiptHandle,
+	})
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to delete the event message %s: %v", aws.StringValue(message.MessageId), err)
+	}
+}
+
+type snsNotification struct {
+	Type    string `json:"Type"`
+	Message string `json:"Message"`
+}
+
+type s3EventNotification struct {
+	Records []struct {
+		EventName string    `json:"eventName"`
+		EventTime time.Time `json:"eventTime"`
+		S3        struct {
+			Object struct {
+				Key  string `json:"key"`
+				Size int64  `json:"size"`
+				ETag string `json:"eTag"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+}
+
+// ParseS3Events parses the S3 event notification, unwrapping it from the SNS notification if needed.
+// The test events sent by S3 on the notification setup have no records.
+func ParseS3Events(body []byte) ([]Event, error) {
+	var sns snsNotification
+	if err := json.Unmarshal(body, &sns); err == nil && sns.Type == "Notification" {
+		body = []byte(sns.Message)
+	}
+
+	var notification s3EventNotification
+	if err := json.Unmarshal(body, &notification); err != nil {
+		return nil, err
+	}
+	events := make([]Event, 0, len(notification.Records))
+	for _, record := range notification.Records {
+		var kind EventKind
+		switch {
+		case strings.HasPrefix(record.EventName, "ObjectCreated:"):
+			kind = ObjectCreated
+		case strings.HasPrefix(record.EventName, "ObjectRemoved:"):
+			kind = ObjectRemoved
+		default:
+			continue
+		}
+		// the keys are URL-encoded, the spaces are encoded as '+'
+		key, err := url.QueryUnescape(record.S3.Object.Key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid object key %q", record.S3.Object.Key)
+		}
+		event := Event{Kind: kind, Key: key, Size: record.S3.Object.Size, Time: record.EventTime}
+		// the ETag of the multipart upload is not the MD5 of the content
+		if etag := strings.Trim(record.S3.Object.ETag, `"`); !strings.Contains(etag, "-") {
+			event.MD5 = etag
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
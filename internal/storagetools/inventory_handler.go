@@ -0,0 +1,288 @@
+package storagetools
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+const (
+	InventoryFormatCSV  = "csv"
+	InventoryFormatJSON = "json"
+
+	inventoryFormatParquet = "parquet"
+)
+
+// InventoryRecord describes a single storage object in the inventory export
+type InventoryRecord struct {
+	Path         string    `json:"path"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+	Checksum     string    `json:"checksum,omitempty"`
+}
+
+type inventoryWriter interface {
+	Write(record InventoryRecord) error
+	Flush() error
+}
+
+// HandleInventory exports the full listing of the folder. If manifestPath is set, the listing is read from the
+// S3 Inventory or GCS Storage Insights inventory report described by the manifest instead of the storage itself.
+func HandleInventory(folder storage.Folder, manifestPath, format string, output io.Writer) error {
+	err := ValidateInventoryFormat(format)
+	if err != nil {
+		return err
+	}
+	writer, err := newInventoryWriter(format, output)
+	if err != nil {
+		return err
+	}
+
+	if manifestPath != "" {
+		err = readInventoryReport(manifestPath, writer.Write)
+	} else {
+		err = listInventory(folder, writer.Write)
+	}
+	if err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+func listInventory(folder storage.Folder, consume func(InventoryRecord) error) error {
+	objects, err := storage.ListFolderRecursively(folder)
+	if err != nil {
+		return fmt.Errorf("list folder: %v", err)
+	}
+	for _, object := range objects {
+		err = consume(InventoryRecord{
+			Path:         object.GetName(),
+			Size:         object.GetSize(),
+			LastModified: object.GetLastModified(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateInventoryFormat checks the format before anything is listed or written
+func ValidateInventoryFormat(format string) error {
+	switch format {
+	case InventoryFormatCSV, InventoryFormatJSON:
+		return nil
+	case inventoryFormatParquet:
+		return fmt.Errorf("inventory format '%s' is not supported, export to %s and convert it with the data tools",
+			format, InventoryFormatCSV)
+	default:
+		return fmt.Errorf("unsupported inventory format '%s', expected one of: %s, %s",
+			format, InventoryFormatCSV, InventoryFormatJSON)
+	}
+}
+
+func newInventoryWriter(format string, output io.Writer) (inventoryWriter, error) {
+	switch format {
+	case InventoryFormatCSV:
+		writer := &csvInventoryWriter{writer: csv.NewWriter(output)}
+		return writer, writer.writer.Write([]string{"path", "size", "last_modified", "checksum"})
+	case InventoryFormatJSON:
+		return &jsonInventoryWriter{encoder: json.NewEncoder(output)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported inventory format '%s', expected one of: %s, %s",
+			format, InventoryFormatCSV, InventoryFormatJSON)
+	}
+}
+
+type csvInventoryWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvInventoryWriter) Write(record InventoryRecord) error {
+	return w.writer.Write([]string{
+		record.Path,
+		strconv.FormatInt(record.Size, 10),
+		record.LastModified.UTC().Format(time.RFC3339),
+		record.Checksum,
+	})
+}
+
+func (w *csvInventoryWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// jsonInventoryWriter writes one JSON object per line
+type jsonInventoryWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *jsonInventoryWriter) Write(record InventoryRecord) error {
+	return w.encoder.Encode(record)
+}
+
+func (w *jsonInventoryWriter) Flush() error {
+	return nil
+}
+
+// inventoryManifest holds the fields of both S3 Inventory and GCS Storage Insights manifests
+type inventoryManifest struct {
+	// S3 Inventory
+	FileFormat string `json:"fileFormat"`
+	FileSchema string `json:"fileSchema"`
+	Files      []struct {
+		Key string `json:"key"`
+	} `json:"files"`
+
+	// GCS Storage Insights
+	ReportShardsFileNames []string `json:"report_shards_file_names"`
+}
+
+// readInventoryReport reads the report files listed in the manifest. The files are expected
+// to be downloaded into the same local directory as the manifest itself.
+func readInventoryReport(manifestPath string, consume func(InventoryRecord) error) error {
+	content, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return fmt.Errorf("read inventory manifest: %v", err)
+	}
+	var manifest inventoryManifest
+	err = json.Unmarshal(content, &manifest)
+	if err != nil {
+		return fmt.Errorf("parse inventory manifest: %v", err)
+	}
+
+	var files []string
+	var s3Schema []string
+	switch {
+	case len(manifest.Files) > 0:
+		if !strings.EqualFold(manifest.FileFormat, "CSV") {
+			return fmt.Errorf("unsupported S3 Inventory file format '%s', only CSV is supported", manifest.FileFormat)
+		}
+		for _, column := range strings.Split(manifest.FileSchema, ",") {
+			s3Schema = append(s3Schema, strings.TrimSpace(column))
+		}
+		for _, file := range manifest.Files {
+			files = append(files, file.Key)
+		}
+	case len(manifest.ReportShardsFileNames) > 0:
+		files = manifest.ReportShardsFileNames
+	default:
+		return fmt.Errorf("no report files found in inventory manifest %s", manifestPath)
+	}
+
+	for _, file := range files {
+		localPath := filepath.Join(filepath.Dir(manifestPath), path.Base(file))
+		err = readInventoryReportFile(localPath, s3Schema, consume)
+		if err != nil {
+			return fmt.Errorf("read inventory report file %s: %v", localPath, err)
+		}
+	}
+	return nil
+}
+
+// readInventoryReportFile parses a CSV report file. S3 Inventory files have no header and their columns are
+// described by the manifest schema, while GCS Storage Insights files start with a header row.
+func readInventoryReportFile(filePath string, s3Schema []string, consume func(InventoryRecord) error) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(file, "")
+
+	var reader io.Reader = file
+	if strings.HasSuffix(filePath, ".gz") {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer utility.LoggedClose(gzipReader, "")
+		reader = gzipReader
+	}
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+
+	columns := s3Schema
+	if columns == nil {
+		columns, err = csvReader.Read()
+		if err != nil {
+			return err
+		}
+	}
+	parseRow := newInventoryRowParser(columns, s3Schema != nil)
+
+	for {
+		row, err := csvReader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		record, err := parseRow(row)
+		if err != nil {
+			return err
+		}
+		err = consume(record)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func newInventoryRowParser(columns []string, isS3 bool) func(row []string) (InventoryRecord, error) {
+	index := make(map[string]int, len(columns))
+	for i, column := range columns {
+		index[column] = i
+	}
+	field := func(row []string, names ...string) string {
+		for _, name := range names {
+			if i, ok := index[name]; ok && i < len(row) {
+				return row[i]
+			}
+		}
+		return ""
+	}
+
+	return func(row []string) (InventoryRecord, error) {
+		var record InventoryRecord
+		var err error
+
+		if isS3 {
+			// S3 Inventory URL-encodes object keys
+			record.Path, err = url.QueryUnescape(field(row, "Key"))
+			if err != nil {
+				return record, err
+			}
+			record.Checksum = field(row, "ETag")
+		} else {
+			record.Path = field(row, "name")
+			record.Checksum = field(row, "md5Hash", "crc32c")
+		}
+
+		if size := field(row, "Size", "size"); size != "" {
+			record.Size, err = strconv.ParseInt(size, 10, 64)
+			if err != nil {
+				return record, err
+			}
+		}
+		if lastModified := field(row, "LastModifiedDate", "updated"); lastModified != "" {
+			record.LastModified, err = time.Parse(time.RFC3339Nano, lastModified)
+			if err != nil {
+				return record, err
+			}
+		}
+		return record, nil
+	}
+}
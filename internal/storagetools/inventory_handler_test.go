@@ -0,0 +1,78 @@
+package storagetools
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+func TestHandleInventory_LiveListing(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewKVS())
+	require.NoError(t, folder.PutObject("basebackups_005/base_1/tar_partitions/part_1.tar.lz4", strings.NewReader("abc")))
+	require.NoError(t, folder.PutObject("wal_005/000000010000000000000001.lz4", strings.NewReader("abcdef")))
+
+	output := &bytes.Buffer{}
+	require.NoError(t, HandleInventory(folder, "", InventoryFormatCSV, output))
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "path,size,last_modified,checksum", lines[0])
+	assert.Contains(t, output.String(), "basebackups_005/base_1/tar_partitions/part_1.tar.lz4,3,")
+	assert.Contains(t, output.String(), "wal_005/000000010000000000000001.lz4,6,")
+}
+
+func TestHandleInventory_UnknownFormat(t *testing.T) {
+	folder := memory.NewFolder("", memory.NewKVS())
+	require.NoError(t, folder.PutObject("wal_005/000000010000000000000001.lz4", strings.NewReader("wal")))
+
+	output := &bytes.Buffer{}
+	assert.ErrorContains(t, HandleInventory(folder, "", "parquet", output), "'parquet' is not supported")
+	assert.ErrorContains(t, HandleInventory(folder, "", "xml", output), "unsupported inventory format")
+	assert.Empty(t, output.String())
+}
+
+func TestHandleInventory_S3Manifest(t *testing.T) {
+	dir := t.TempDir()
+	manifest := `{
+		"fileFormat": "CSV",
+		"fileSchema": "Bucket, Key, Size, LastModifiedDate, ETag",
+		"files": [{"key": "walg-bucket/inventory/data/0001.csv.gz"}]
+	}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(manifest), 0600))
+
+	data := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(data)
+	_, err := gzipWriter.Write([]byte(
+		`"walg-bucket","wal_005%2F000000010000000000000001.lz4","6","2023-07-22T10:00:00.000Z","d41d8cd98f00b204"` + "\n"))
+	require.NoError(t, err)
+	require.NoError(t, gzipWriter.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001.csv.gz"), data.Bytes(), 0600))
+
+	output := &bytes.Buffer{}
+	err = HandleInventory(nil, filepath.Join(dir, "manifest.json"), InventoryFormatJSON, output)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"path": "wal_005/000000010000000000000001.lz4", "size": 6,
+		"last_modified": "2023-07-22T10:00:00Z", "checksum": "d41d8cd98f00b204"}`, output.String())
+}
+
+func TestHandleInventory_GCSManifest(t *testing.T) {
+	dir := t.TempDir()
+	manifest := `{"report_shards_file_names": ["inventory_2023-07-22_shard_0.csv"]}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(manifest), 0600))
+	report := "bucket,name,size,updated,crc32c\n" +
+		"walg-bucket,wal_005/000000010000000000000001.lz4,6,2023-07-22T10:00:00Z,AAAAAA==\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "inventory_2023-07-22_shard_0.csv"), []byte(report), 0600))
+
+	output := &bytes.Buffer{}
+	err := HandleInventory(nil, filepath.Join(dir, "manifest.json"), InventoryFormatCSV, output)
+	require.NoError(t, err)
+	assert.Equal(t, "path,size,last_modified,checksum\n"+
+		"wal_005/000000010000000000000001.lz4,6,2023-07-22T10:00:00Z,AAAAAA==\n", output.String())
+}
@@ -0,0 +1,34 @@
+package storagetools
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+func HandlePresign(objectPath string, ttl time.Duration, folder storage.Folder, output io.Writer) error {
+	if ttl <= 0 {
+		return fmt.Errorf("ttl must be positive, got %v", ttl)
+	}
+	presignable, ok := folder.(storage.PresignableFolder)
+	if !ok {
+		return fmt.Errorf("the storage doesn't support pre-signed URLs")
+	}
+
+	exists, err := folder.Exists(objectPath)
+	if err != nil {
+		return fmt.Errorf("check object existence: %v", err)
+	}
+	if !exists {
+		return storage.NewObjectNotFoundError(objectPath)
+	}
+
+	url, err := presignable.PresignObject(objectPath, ttl)
+	if err != nil {
+		return fmt.Errorf("presign the object: %v", err)
+	}
+	_, err = fmt.Fprintln(output, url)
+	return err
+}
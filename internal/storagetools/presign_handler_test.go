@@ -0,0 +1,45 @@
+package storagetools
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+type presignableFolder struct {
+	storage.Folder
+}
+
+func (f presignableFolder) PresignObject(objectRelativePath string, ttl time.Duration) (string, error) {
+	return "https://example.com/" + f.GetPath() + objectRelativePath + "?ttl=" + ttl.String(), nil
+}
+
+func TestHandlePresign(t *testing.T) {
+	memFolder := memory.NewFolder("test/", memory.NewKVS())
+	require.NoError(t, memFolder.PutObject("a/b", strings.NewReader("123")))
+
+	t.Run("print url", func(t *testing.T) {
+		output := &bytes.Buffer{}
+		err := HandlePresign("a/b", time.Hour, presignableFolder{memFolder}, output)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/test/a/b?ttl=1h0m0s\n", output.String())
+	})
+
+	t.Run("throw err when object does not exist", func(t *testing.T) {
+		err := HandlePresign("a/c", time.Hour, presignableFolder{memFolder}, &bytes.Buffer{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not found")
+	})
+
+	t.Run("throw err when storage does not support presigning", func(t *testing.T) {
+		err := HandlePresign("a/b", time.Hour, memFolder, &bytes.Buffer{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "doesn't support")
+	})
+}
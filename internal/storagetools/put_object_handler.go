@@ -82,6 +82,9 @@ func uploadFile(ctx context.Context, name string, content io.Reader, uploader in
 		name += "." + uploader.Compression().FileExtension()
 	}
 
-	uploadContents := internal.CompressAndEncrypt(content, compressor, crypter)
+	uploadContents, err := internal.CompressAndEncryptScanned(ctx, uploader, name, content, compressor, crypter)
+	if err != nil {
+		return err
+	}
 	return uploader.Upload(ctx, name, uploadContents)
 }
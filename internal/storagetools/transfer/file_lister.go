@@ -57,6 +57,9 @@ func listMissingFiles(source, target storage.Folder, prefix string, overwrite bo
 
 	missingFiles := make(map[string]storage.Object, len(sourceFiles))
 	for _, sourceFile := range sourceFiles {
+		if storage.IsTemporaryPath(sourceFile.GetName()) {
+			continue
+		}
 		missingFiles[sourceFile.GetName()] = sourceFile
 	}
 	for _, targetFile := range targetFiles {
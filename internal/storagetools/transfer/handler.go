@@ -1,8 +1,11 @@
 package transfer
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"sync"
@@ -10,9 +13,12 @@ import (
 	"time"
 
 	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/limiters"
 	"github.com/wal-g/wal-g/internal/multistorage/exec"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 	"github.com/wal-g/wal-g/utility"
+	"golang.org/x/time/rate"
 )
 
 type Handler struct {
@@ -23,6 +29,9 @@ type Handler struct {
 	fileStatuses    *sync.Map
 	filesLeft       int32
 	jobRequirements map[jobKey][]jobRequirement
+	state           *State
+	limiter         *rate.Limiter
+	checksums       *sync.Map
 }
 
 type HandlerConfig struct {
@@ -31,6 +40,16 @@ type HandlerConfig struct {
 	Concurrency              int
 	AppearanceChecks         uint
 	AppearanceChecksInterval time.Duration
+	// StateFilePath is a file to record transferred files in, so that the transfer can be resumed. Empty means none.
+	StateFilePath string
+	// VerifyChecksums enables reading each file back from the target storage and comparing its checksum with the
+	// source one before the file is considered transferred.
+	VerifyChecksums bool
+	// BandwidthLimit is the max number of bytes per second to read from the source storage. Zero means no limit.
+	BandwidthLimit int64
+	// TemporaryTTL makes each file be written to a temporary object expiring after it and then copied to its place,
+	// so an interrupted transfer leaves no partially written files in the target storage. Zero means writing in place.
+	TemporaryTTL time.Duration
 }
 
 func NewHandler(
@@ -47,6 +66,19 @@ func NewHandler(
 		return nil, fmt.Errorf("configure target storage folder: %w", err)
 	}
 
+	var state *State
+	if cfg.StateFilePath != "" {
+		state, err = OpenState(cfg.StateFilePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var limiter *rate.Limiter
+	if cfg.BandwidthLimit > 0 {
+		limiter = rate.NewLimiter(rate.Limit(cfg.BandwidthLimit), int(cfg.BandwidthLimit))
+	}
+
 	return &Handler{
 		source:          source.RootFolder(),
 		target:          target.RootFolder(),
@@ -54,10 +86,22 @@ func NewHandler(
 		cfg:             cfg,
 		fileStatuses:    new(sync.Map),
 		jobRequirements: map[jobKey][]jobRequirement{},
+		state:           state,
+		limiter:         limiter,
+		checksums:       new(sync.Map),
 	}, nil
 }
 
 func (h *Handler) Handle() error {
+	defer utility.LoggedClose(h.state, "close transfer state file")
+
+	// the temporary objects left by the interrupted transfers are removed once they expire
+	if deleted, err := storage.ScrubTemporaries(h.target, time.Now()); err != nil {
+		tracelog.WarningLogger.Printf("Failed to scrub the expired temporary objects in the target storage: %v", err)
+	} else if deleted > 0 {
+		tracelog.InfoLogger.Printf("Deleted %d expired temporary objects from the target storage", deleted)
+	}
+
 	files, filesNum, err := h.fileLister.ListFilesToMove(h.source, h.target)
 	if err != nil {
 		return err
@@ -120,9 +164,14 @@ func (ts transferStatus) String() string {
 
 func (h *Handler) transferConcurrently(workers int, files []FilesGroup, filesNum int) (finErr error) {
 	jobsQueue := make(chan transferJob, filesNum)
-	h.filesLeft += int32(filesNum)
 	for _, group := range files {
 		for _, file := range group {
+			if h.state.IsCompleted(file.path) {
+				// Has been transferred by one of the previous runs
+				h.fileStatuses.Store(file.path, transferStatusDeleted)
+				continue
+			}
+			h.filesLeft++
 			h.saveRequirements(file)
 			h.fileStatuses.Store(file.path, transferStatusNew)
 			jobsQueue <- transferJob{
@@ -136,7 +185,9 @@ func (h *Handler) transferConcurrently(workers int, files []FilesGroup, filesNum
 
 	errs := make(chan error, len(files))
 
-	workersCtx, cancelWorkers := context.WithCancel(context.Background())
+	// the files in progress are finished on the interruption notice, so the rerun resumes from the state file
+	workersCtx, cancelWorkers := internal.WithInterruptionNotices(context.Background())
+	defer cancelWorkers()
 	cancelOnSignal(cancelWorkers)
 	workersWG := new(sync.WaitGroup)
 	workersWG.Add(workers)
@@ -165,6 +216,9 @@ func (h *Handler) transferConcurrently(workers int, files []FilesGroup, filesNum
 	close(errs)
 	errsWG.Wait()
 
+	if noticeErr, ok := internal.GetInterruptionNotice(workersCtx); ok && finErr == nil {
+		return fmt.Errorf("transfer is stopped with %d files left: %w", atomic.LoadInt32(&h.filesLeft), noticeErr)
+	}
 	return finErr
 }
 
@@ -259,6 +313,9 @@ func (h *Handler) transferFilesWorker(
 		}
 
 		atomic.AddInt32(&h.filesLeft, -1)
+		if err := h.state.MarkCompleted(job.key.filePath); err != nil {
+			tracelog.WarningLogger.Printf("Failed to save transfer state for file %q: %v", job.key.filePath, err)
+		}
 		tracelog.InfoLogger.Printf("File is transferred (%d left): %q", atomic.LoadInt32(&h.filesLeft), job.key.filePath)
 	}
 }
@@ -292,10 +349,22 @@ func (h *Handler) copyFile(job transferJob) (newJob *transferJob, err error) {
 	}
 	defer utility.LoggedClose(content, "close object content read from the source storage")
 
-	err = h.target.PutObject(job.key.filePath, content)
+	var reader io.Reader = content
+	if h.limiter != nil {
+		reader = limiters.NewReader(context.Background(), reader, h.limiter)
+	}
+	hasher := sha256.New()
+	if h.cfg.VerifyChecksums {
+		reader = io.TeeReader(reader, hasher)
+	}
+
+	err = h.putToTarget(job.key.filePath, reader)
 	if err != nil {
 		return nil, fmt.Errorf("write file to the target storage: %w", err)
 	}
+	if h.cfg.VerifyChecksums {
+		h.checksums.Store(job.key.filePath, hasher.Sum(nil))
+	}
 
 	h.fileStatuses.Store(job.key.filePath, transferStatusCopied)
 	job.key.jobType = jobTypeWait
@@ -304,6 +373,26 @@ func (h *Handler) copyFile(job transferJob) (newJob *transferJob, err error) {
 	return newJob, nil
 }
 
+func (h *Handler) putToTarget(filePath string, content io.Reader) error {
+	if h.cfg.TemporaryTTL == 0 {
+		return h.target.PutObject(filePath, content)
+	}
+	temporaryPath := storage.NewTemporaryPath(filePath, h.cfg.TemporaryTTL)
+	if err := h.target.PutObject(temporaryPath, content); err != nil {
+		return err
+	}
+	// the storage copies the temporary object server-side if it can, otherwise the copy is limited like the transfer
+	err := storage.CopyObjectWithContext(context.Background(), h.target, temporaryPath, filePath,
+		storage.CopyOptions{Limiter: h.limiter})
+	if err != nil {
+		return err
+	}
+	if err := h.target.DeleteObjects([]string{temporaryPath}); err != nil {
+		tracelog.WarningLogger.Printf("Failed to delete the temporary object %q, it will be scrubbed: %v", temporaryPath, err)
+	}
+	return nil
+}
+
 func (h *Handler) waitFile(job transferJob) (newJob *transferJob, err error) {
 	var appeared bool
 
@@ -318,6 +407,12 @@ func (h *Handler) waitFile(job transferJob) (newJob *transferJob, err error) {
 	}
 
 	if appeared {
+		if h.cfg.VerifyChecksums {
+			err = h.verifyFile(job.key.filePath)
+			if err != nil {
+				return nil, err
+			}
+		}
 		h.fileStatuses.Store(job.key.filePath, transferStatusAppeared)
 		if h.cfg.PreserveInSource {
 			return nil, nil
@@ -362,6 +457,29 @@ func (h *Handler) checkForAppearance(prevCheck time.Time, filePath string) (appe
 	return appeared, nil
 }
 
+func (h *Handler) verifyFile(filePath string) error {
+	expected, ok := h.checksums.Load(filePath)
+	if !ok {
+		return fmt.Errorf("no checksum is computed for the file")
+	}
+
+	content, err := h.target.ReadObject(filePath)
+	if err != nil {
+		return fmt.Errorf("read file from the target storage to verify it: %w", err)
+	}
+	defer utility.LoggedClose(content, "close object content read from the target storage")
+
+	hasher := sha256.New()
+	_, err = io.Copy(hasher, content)
+	if err != nil {
+		return fmt.Errorf("read file from the target storage to verify it: %w", err)
+	}
+	if !bytes.Equal(expected.([]byte), hasher.Sum(nil)) {
+		return fmt.Errorf("checksum of the file in the target storage doesn't match the source one")
+	}
+	return nil
+}
+
 func (h *Handler) deleteFile(job transferJob) error {
 	err := h.source.DeleteObjects([]string{job.key.filePath})
 	if err != nil {
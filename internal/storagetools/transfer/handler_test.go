@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"path"
 	"strconv"
 	"strings"
 	"sync"
@@ -235,6 +236,87 @@ func TestTransferHandler_Handle(t *testing.T) {
 		assert.Equal(t, 100, countFiles(h.target, 100))
 		assert.Equal(t, 85, countFiles(h.source, 100))
 	})
+
+	t.Run("skip files completed by previous runs", func(t *testing.T) {
+		statePath := path.Join(t.TempDir(), "state")
+		prevState, err := OpenState(statePath)
+		require.NoError(t, err)
+		for i := 0; i < 10; i++ {
+			require.NoError(t, prevState.MarkCompleted(strconv.Itoa(i)))
+		}
+		require.NoError(t, prevState.Close())
+
+		h := defaultHandler()
+		h.fileLister.(*RegularFileLister).Overwrite = true
+		h.state, err = OpenState(statePath)
+		require.NoError(t, err)
+
+		for i := 0; i < 100; i++ {
+			_ = h.source.PutObject(strconv.Itoa(i), &bytes.Buffer{})
+		}
+
+		err = h.Handle()
+		require.NoError(t, err)
+
+		assert.Equal(t, 90, countFiles(h.target, 100))
+		assert.Equal(t, 10, countFiles(h.source, 100))
+
+		resumedState, err := OpenState(statePath)
+		require.NoError(t, err)
+		defer resumedState.Close()
+		for i := 0; i < 100; i++ {
+			assert.True(t, resumedState.IsCompleted(strconv.Itoa(i)))
+		}
+	})
+
+	t.Run("write files through temporary objects", func(t *testing.T) {
+		h := defaultHandler()
+		h.cfg.TemporaryTTL = time.Hour
+
+		expiredPath := storage.NewTemporaryPath("leftover", -time.Hour)
+		_ = h.target.PutObject(expiredPath, &bytes.Buffer{})
+		for i := 0; i < 10; i++ {
+			_ = h.source.PutObject(strconv.Itoa(i), bytes.NewBufferString("content"))
+		}
+
+		err := h.Handle()
+		require.NoError(t, err)
+
+		assert.Equal(t, 10, countFiles(h.target, 10))
+		assert.Equal(t, 0, countFiles(h.source, 10))
+		temporaries, _, err := h.target.GetSubFolder(storage.TemporaryFolder).ListFolder()
+		require.NoError(t, err)
+		assert.Empty(t, temporaries, "the temporary objects must be deleted, the expired ones scrubbed")
+	})
+
+	t.Run("keep files in source if checksums differ", func(t *testing.T) {
+		targetMock := mock.NewFolder(memory.NewFolder("target/", memory.NewKVS()))
+		targetMock.PutObjectMock = func(_ context.Context, name string, content io.Reader) error {
+			_, _ = io.ReadAll(content)
+			if name == "5" {
+				return targetMock.MemFolder.PutObject(name, bytes.NewBufferString("corrupted"))
+			}
+			return targetMock.MemFolder.PutObject(name, bytes.NewBufferString("content"))
+		}
+
+		h := defaultHandler()
+		h.target = targetMock
+		h.checksums = new(sync.Map)
+		h.cfg.VerifyChecksums = true
+
+		for i := 0; i < 10; i++ {
+			_ = h.source.PutObject(strconv.Itoa(i), bytes.NewBufferString("content"))
+		}
+
+		err := h.Handle()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "finished with 1 errors")
+
+		assert.Equal(t, 1, countFiles(h.source, 10))
+		exists, err := h.source.Exists("5")
+		require.NoError(t, err)
+		assert.True(t, exists)
+	})
 }
 
 func TestTransferHandler_saveRequirements(t *testing.T) {
@@ -351,6 +433,7 @@ func TestTransferHandler_copyFile(t *testing.T) {
 		return &Handler{
 			source:       memory.NewFolder("source/", memory.NewKVS()),
 			target:       memory.NewFolder("target/", memory.NewKVS()),
+			cfg:          &HandlerConfig{},
 			fileStatuses: new(sync.Map),
 		}
 	}
@@ -0,0 +1,90 @@
+package transfer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// State keeps track of the files that have been completely transferred, so an interrupted transfer can be resumed
+// without processing them again. It's persisted as a file with a JSON record per line, which is appended as soon as
+// another file is done.
+type State struct {
+	file      *os.File
+	completed map[string]bool
+	mu        sync.Mutex
+}
+
+type stateRecord struct {
+	Path string `json:"path"`
+}
+
+// OpenState loads the state file if it exists or creates a new one.
+func OpenState(path string) (*State, error) {
+	completed := map[string]bool{}
+
+	existing, err := os.Open(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("open transfer state file: %w", err)
+	}
+	if err == nil {
+		scanner := bufio.NewScanner(existing)
+		for scanner.Scan() {
+			var record stateRecord
+			if json.Unmarshal(scanner.Bytes(), &record) != nil {
+				// The last line might be partially written if the previous run has crashed
+				continue
+			}
+			completed[record.Path] = true
+		}
+		err = scanner.Err()
+		_ = existing.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read transfer state file: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("open transfer state file for writing: %w", err)
+	}
+	return &State{file: file, completed: completed}, nil
+}
+
+func (s *State) IsCompleted(filePath string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.completed[filePath]
+}
+
+func (s *State) MarkCompleted(filePath string) error {
+	if s == nil {
+		return nil
+	}
+	line, err := json.Marshal(stateRecord{Path: filePath})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("write transfer state file: %w", err)
+	}
+	s.completed[filePath] = true
+	return nil
+}
+
+func (s *State) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.file.Close()
+}
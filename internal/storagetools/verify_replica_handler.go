@@ -0,0 +1,153 @@
+package storagetools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// ReplicaReport is the result of the comparison of the primary storage with its replica
+type ReplicaReport struct {
+	PrimaryObjects int `json:"primary_objects"`
+	ReplicaObjects int `json:"replica_objects"`
+	// Missing objects are not replicated yet, Pending ones are younger than the allowed lag
+	Missing          []string `json:"missing,omitempty"`
+	Pending          []string `json:"pending,omitempty"`
+	SizeMismatch     []string `json:"size_mismatch,omitempty"`
+	ChecksumMismatch []string `json:"checksum_mismatch,omitempty"`
+	// Extra objects exist only in the replica, e.g. when the deletions are not replicated
+	Extra []string `json:"extra,omitempty"`
+	// ReplicationLag is the age of the oldest object that is not replicated yet
+	ReplicationLag time.Duration `json:"replication_lag_ns"`
+}
+
+// IsConsistent tells if every object older than the allowed lag is replicated intact
+func (report *ReplicaReport) IsConsistent() bool {
+	return len(report.Missing) == 0 && len(report.SizeMismatch) == 0 && len(report.ChecksumMismatch) == 0
+}
+
+// HandleVerifyReplica compares the objects in the primary folder with the ones in the replica folder.
+// Sizes are always compared, the contents are compared only if checkContent is set since it means reading
+// every object from both storages.
+func HandleVerifyReplica(primary, replica storage.Folder, checkContent bool, maxLag time.Duration, output io.Writer) error {
+	report, err := verifyReplica(primary, replica, checkContent, maxLag, time.Now())
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(report)
+	if err != nil {
+		return err
+	}
+	if !report.IsConsistent() {
+		return fmt.Errorf("replica is inconsistent: %d missing, %d size mismatches, %d checksum mismatches",
+			len(report.Missing), len(report.SizeMismatch), len(report.ChecksumMismatch))
+	}
+	tracelog.InfoLogger.Printf("Replica is consistent, replication lag is %s", report.ReplicationLag)
+	return nil
+}
+
+func verifyReplica(primary, replica storage.Folder, checkContent bool, maxLag time.Duration, now time.Time) (*ReplicaReport, error) {
+	primaryObjects, err := listObjectsByPath(primary)
+	if err != nil {
+		return nil, fmt.Errorf("list primary folder: %v", err)
+	}
+	replicaObjects, err := listObjectsByPath(replica)
+	if err != nil {
+		return nil, fmt.Errorf("list replica folder: %v", err)
+	}
+
+	report := &ReplicaReport{PrimaryObjects: len(primaryObjects), ReplicaObjects: len(replicaObjects)}
+	for _, objectPath := range sortedPaths(primaryObjects) {
+		primaryObject := primaryObjects[objectPath]
+		replicaObject, ok := replicaObjects[objectPath]
+		if !ok {
+			age := now.Sub(primaryObject.GetLastModified())
+			if age > report.ReplicationLag {
+				report.ReplicationLag = age
+			}
+			if age > maxLag {
+				report.Missing = append(report.Missing, objectPath)
+			} else {
+				report.Pending = append(report.Pending, objectPath)
+			}
+			continue
+		}
+		if primaryObject.GetSize() != replicaObject.GetSize() {
+			report.SizeMismatch = append(report.SizeMismatch, objectPath)
+			continue
+		}
+		if !checkContent {
+			continue
+		}
+		equal, err := compareContents(primary, replica, objectPath)
+		if err != nil {
+			return nil, err
+		}
+		if !equal {
+			report.ChecksumMismatch = append(report.ChecksumMismatch, objectPath)
+		}
+	}
+	for _, objectPath := range sortedPaths(replicaObjects) {
+		if _, ok := primaryObjects[objectPath]; !ok {
+			report.Extra = append(report.Extra, objectPath)
+		}
+	}
+	return report, nil
+}
+
+func listObjectsByPath(folder storage.Folder) (map[string]storage.Object, error) {
+	objects, err := storage.ListFolderRecursively(folder)
+	if err != nil {
+		return nil, err
+	}
+	objectsByPath := make(map[string]storage.Object, len(objects))
+	for _, object := range objects {
+		objectsByPath[object.GetName()] = object
+	}
+	return objectsByPath, nil
+}
+
+func sortedPaths(objects map[string]storage.Object) []string {
+	paths := make([]string, 0, len(objects))
+	for objectPath := range objects {
+		paths = append(paths, objectPath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func compareContents(primary, replica storage.Folder, objectPath string) (bool, error) {
+	primaryChecksum, err := objectChecksum(primary, objectPath)
+	if err != nil {
+		return false, fmt.Errorf("read %s from primary: %v", objectPath, err)
+	}
+	replicaChecksum, err := objectChecksum(replica, objectPath)
+	if err != nil {
+		return false, fmt.Errorf("read %s from replica: %v", objectPath, err)
+	}
+	return primaryChecksum == replicaChecksum, nil
+}
+
+func objectChecksum(folder storage.Folder, objectPath string) (string, error) {
+	reader, err := folder.ReadObject(objectPath)
+	if err != nil {
+		return "", err
+	}
+	defer utility.LoggedClose(reader, "")
+	hash := sha256.New()
+	_, err = io.Copy(hash, reader)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
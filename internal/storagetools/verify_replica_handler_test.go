@@ -0,0 +1,58 @@
+package storagetools
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+func TestVerifyReplica(t *testing.T) {
+	primary := memory.NewFolder("", memory.NewKVS())
+	replica := memory.NewFolder("", memory.NewKVS())
+	put := func(folder *memory.Folder, path, content string) {
+		require.NoError(t, folder.PutObject(path, strings.NewReader(content)))
+	}
+	put(primary, "basebackups_005/base_1/tar_partitions/part_1.tar.lz4", "backup")
+	put(replica, "basebackups_005/base_1/tar_partitions/part_1.tar.lz4", "backup")
+	put(primary, "wal_005/000000010000000000000001.lz4", "wal 1")
+	put(replica, "wal_005/000000010000000000000001.lz4", "wal 2")
+	put(primary, "wal_005/000000010000000000000002.lz4", "wal")
+	put(replica, "wal_005/000000010000000000000002.lz4", "truncated")
+	put(primary, "wal_005/000000010000000000000003.lz4", "wal")
+	put(replica, "wal_005/000000010000000000000000.lz4", "deleted on primary")
+
+	report, err := verifyReplica(primary, replica, false, time.Hour, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.PrimaryObjects)
+	assert.Equal(t, 4, report.ReplicaObjects)
+	assert.Empty(t, report.Missing)
+	assert.Equal(t, []string{"wal_005/000000010000000000000003.lz4"}, report.Pending)
+	assert.Equal(t, []string{"wal_005/000000010000000000000002.lz4"}, report.SizeMismatch)
+	assert.Empty(t, report.ChecksumMismatch)
+	assert.Equal(t, []string{"wal_005/000000010000000000000000.lz4"}, report.Extra)
+	assert.False(t, report.IsConsistent())
+
+	report, err = verifyReplica(primary, replica, true, time.Hour, time.Now().Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"wal_005/000000010000000000000003.lz4"}, report.Missing)
+	assert.Empty(t, report.Pending)
+	assert.Equal(t, []string{"wal_005/000000010000000000000001.lz4"}, report.ChecksumMismatch)
+	assert.Greater(t, report.ReplicationLag, 2*time.Hour-time.Minute)
+}
+
+func TestHandleVerifyReplica_Consistent(t *testing.T) {
+	primary := memory.NewFolder("", memory.NewKVS())
+	replica := memory.NewFolder("", memory.NewKVS())
+	for _, folder := range []*memory.Folder{primary, replica} {
+		require.NoError(t, folder.PutObject("wal_005/000000010000000000000001.lz4", strings.NewReader("wal")))
+	}
+
+	var output bytes.Buffer
+	require.NoError(t, HandleVerifyReplica(primary, replica, true, time.Hour, &output))
+	assert.Contains(t, output.String(), `"primary_objects": 1`)
+}
@@ -50,6 +50,7 @@ func DownloadAndDecompressStream(backup Backup, writeCloser io.WriteCloser) erro
 			continue
 		}
 		tracelog.DebugLogger.Printf("Found file: %s.%s", backup.Name, decompressor.FileExtension())
+		archiveReader = backup.RestoreReport.CountDownload(archiveReader)
 		defer utility.LoggedClose(archiveReader, "")
 
 		decompressedReader, err := DecompressDecryptBytes(archiveReader, decompressor)
@@ -127,7 +128,7 @@ func downloadAndDecompressFile(backup Backup, decompressor compression.Decompres
 			return nil, io.EOF
 		} else {
 			tracelog.DebugLogger.Printf("Found file: %s", fileName)
-			return archiveReader, nil
+			return backup.RestoreReport.CountDownload(archiveReader), nil
 		}
 	}
 
@@ -95,8 +95,11 @@ func (uploader *RegularUploader) PushStreamToDestination(ctx context.Context, st
 	if uploader.dataSize != nil {
 		stream = utility.NewWithSizeReader(stream, uploader.dataSize)
 	}
-	compressed := CompressAndEncrypt(stream, uploader.Compressor, ConfigureCrypter())
-	err := uploader.Upload(ctx, dstPath, compressed)
+	compressed, err := CompressAndEncryptScanned(ctx, uploader, dstPath, stream, uploader.Compressor, ConfigureCrypter())
+	if err != nil {
+		return err
+	}
+	err = uploader.Upload(ctx, dstPath, compressed)
 	tracelog.InfoLogger.Println("FILE PATH:", dstPath)
 
 	return err
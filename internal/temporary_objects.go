@@ -0,0 +1,28 @@
+package internal
+
+import (
+	"time"
+
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// GetTemporaryObjectsTTL returns the time after which the temporary objects of the interrupted operations
+// are removed, see storage.TemporaryFolder. It must exceed the duration of the longest operation.
+func GetTemporaryObjectsTTL() (time.Duration, error) {
+	return conf.GetDurationSetting(conf.TemporaryObjectsTTLSetting)
+}
+
+// ScrubExpiredTemporaries removes the expired temporary objects left by the interrupted operations. The failure
+// is only logged: the objects are removed by the next command.
+func ScrubExpiredTemporaries(root storage.Folder) {
+	deleted, err := storage.ScrubTemporaries(root, time.Now())
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to scrub the expired temporary objects: %v", err)
+		return
+	}
+	if deleted > 0 {
+		tracelog.InfoLogger.Printf("Deleted %d expired temporary objects", deleted)
+	}
+}
@@ -0,0 +1,288 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/wal-g/tracelog"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+const (
+	// UploadScanObjectPathEnv is the environment variable with the path of the object passed to the scanner command
+	UploadScanObjectPathEnv = "WALG_SCAN_OBJECT_PATH"
+
+	UploadScanActionFail = "fail"
+	UploadScanActionTag  = "tag"
+
+	// the scanner command exits with this code if the data is flagged, like clamscan does
+	uploadScanFlaggedExitCode = 1
+	maxUploadScanDetailsSize  = 1024
+)
+
+// UploadScanVerdict is the result of the scan of the uploaded object
+type UploadScanVerdict struct {
+	Flagged bool
+	Details string
+}
+
+// UploadScanFinding is the uploaded object flagged by the scanner
+type UploadScanFinding struct {
+	Path    string `json:"Path"`
+	Details string `json:"Details,omitempty"`
+}
+
+// UploadScan passes the plaintext of the uploaded object written to it to the scanner. The write never fails,
+// as the scanner may stop reading once it has made the verdict.
+type UploadScan interface {
+	io.Writer
+	// Verdict waits for the scanner to check the data written so far
+	Verdict() (UploadScanVerdict, error)
+}
+
+// UploadScanner inspects the data on its way to the storage. It gets the plaintext, before the compression
+// and the encryption.
+type UploadScanner interface {
+	StartScan(ctx context.Context, path string) (UploadScan, error)
+}
+
+type UploadScanFlaggedError struct {
+	error
+}
+
+func NewUploadScanFlaggedError(path, details string) UploadScanFlaggedError {
+	return UploadScanFlaggedError{fmt.Errorf("upload of %s is rejected by the scanner: %s", path, details)}
+}
+
+// CommandUploadScanner streams each uploaded object to the standard input of the new scanner process.
+// The process exits with 0 if the data is clean and with 1 if it is flagged; its output is the verdict details.
+type CommandUploadScanner struct {
+	newCommand func(ctx context.Context) (*exec.Cmd, error)
+}
+
+func NewCommandUploadScanner(newCommand func(ctx context.Context) (*exec.Cmd, error)) *CommandUploadScanner {
+	return &CommandUploadScanner{newCommand: newCommand}
+}
+
+func (scanner *CommandUploadScanner) StartScan(ctx context.Context, path string) (UploadScan, error) {
+	cmd, err := scanner.newCommand(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Env = append(os.Environ(), UploadScanObjectPathEnv+"="+path)
+	scan := &commandUploadScan{cmd: cmd}
+	cmd.Stdout = &scan.output
+	scan.stdin, err = cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, errors.Wrap(err, "failed to start the upload scanner")
+	}
+	scan.input = &scannerInput{stdin: scan.stdin}
+	return scan, nil
+}
+
+type commandUploadScan struct {
+	input  *scannerInput
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	output bytes.Buffer
+}
+
+func (scan *commandUploadScan) Write(p []byte) (int, error) {
+	return scan.input.Write(p)
+}
+
+func (scan *commandUploadScan) Verdict() (UploadScanVerdict, error) {
+	_ = scan.stdin.Close()
+	err := scan.cmd.Wait()
+	details := strings.TrimSpace(scan.output.String())
+	if len(details) > maxUploadScanDetailsSize {
+		details = details[:maxUploadScanDetailsSize]
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return UploadScanVerdict{Details: details}, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == uploadScanFlaggedExitCode:
+		return UploadScanVerdict{Flagged: true, Details: details}, nil
+	default:
+		return UploadScanVerdict{}, errors.Wrapf(err, "upload scanner failed: %s", details)
+	}
+}
+
+// scannerInput passes the data to the scanner. The scanner may stop reading once it has made the verdict,
+// so the failure to write doesn't break the upload.
+type scannerInput struct {
+	stdin   io.Writer
+	stopped bool
+}
+
+func (input *scannerInput) Write(p []byte) (int, error) {
+	if !input.stopped {
+		if _, err := input.stdin.Write(p); err != nil {
+			input.stopped = true
+		}
+	}
+	return len(p), nil
+}
+
+// scanningWriteCloser passes the plaintext written to the encoding writer to the scanner as well
+type scanningWriteCloser struct {
+	io.WriteCloser
+	scanInput io.Writer
+}
+
+func (writer *scanningWriteCloser) Write(p []byte) (int, error) {
+	_, _ = writer.scanInput.Write(p)
+	return writer.WriteCloser.Write(p)
+}
+
+// uploadScanState is shared by the clones of the uploader, so the findings of the whole backup are collected
+type uploadScanState struct {
+	scanner    UploadScanner
+	tagFlagged bool
+	mutex      sync.Mutex
+	findings   []UploadScanFinding
+	// pending are the scans of the plaintext of the objects being encoded, by the object path in the storage
+	pending map[string]UploadScan
+}
+
+func (state *uploadScanState) addPending(path string, scan UploadScan) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	if state.pending == nil {
+		state.pending = make(map[string]UploadScan)
+	}
+	state.pending[path] = scan
+}
+
+func (state *uploadScanState) takePending(path string) UploadScan {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	scan := state.pending[path]
+	delete(state.pending, path)
+	return scan
+}
+
+func (state *uploadScanState) addFinding(finding UploadScanFinding) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	state.findings = append(state.findings, finding)
+}
+
+func (state *uploadScanState) getFindings() []UploadScanFinding {
+	if state == nil {
+		return nil
+	}
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	return append([]UploadScanFinding(nil), state.findings...)
+}
+
+// ConfigureUploadScanner configures the scanner of the uploaded data, nil if WALG_UPLOAD_SCAN_COMMAND isn't set.
+// The flagged uploads fail if WALG_UPLOAD_SCAN_ACTION is "fail", or are recorded in the backup metadata if it is "tag".
+func ConfigureUploadScanner() (scanner UploadScanner, tagFlagged bool, err error) {
+	if _, ok := conf.GetSetting(conf.UploadScanCommandSetting); !ok {
+		return nil, false, nil
+	}
+	action := viper.GetString(conf.UploadScanActionSetting)
+	if action != UploadScanActionFail && action != UploadScanActionTag {
+		return nil, false, fmt.Errorf("invalid %s '%s': expected '%s' or '%s'",
+			conf.UploadScanActionSetting, action, UploadScanActionFail, UploadScanActionTag)
+	}
+	scanner = NewCommandUploadScanner(func(ctx context.Context) (*exec.Cmd, error) {
+		return GetCommandSettingContext(ctx, conf.UploadScanCommandSetting)
+	})
+	return scanner, action == UploadScanActionTag, nil
+}
+
+func configureUploadScanning(uploader *RegularUploader) error {
+	scanner, tagFlagged, err := ConfigureUploadScanner()
+	if err != nil {
+		return err
+	}
+	if scanner != nil {
+		uploader.SetUploadScanner(scanner, tagFlagged)
+	}
+	return nil
+}
+
+// checkUploadScanVerdict waits for the verdict on the uploaded object. The flagged object is deleted
+// and the upload fails, unless the flagged uploads are only tagged.
+func (uploader *RegularUploader) checkUploadScanVerdict(path string, scan UploadScan) error {
+	finding, err := checkScanVerdict(uploader.UploadingFolder, path, scan, uploader.scan.tagFlagged)
+	if finding != nil {
+		uploader.scan.addFinding(*finding)
+	}
+	return err
+}
+
+// checkScanVerdict waits for the verdict on the object put into the folder, and deletes the flagged object
+// unless the flagged objects are only tagged: then the finding is returned
+func checkScanVerdict(folder storage.Folder, path string, scan UploadScan,
+	tagFlagged bool) (*UploadScanFinding, error) {
+	verdict, err := scan.Verdict()
+	if err == nil && !verdict.Flagged {
+		return nil, nil
+	}
+	if err == nil && tagFlagged {
+		tracelog.WarningLogger.Printf("Upload of %s is flagged by the scanner: %s", path, verdict.Details)
+		return &UploadScanFinding{Path: path, Details: verdict.Details}, nil
+	}
+	if err == nil {
+		err = NewUploadScanFlaggedError(path, verdict.Details)
+	}
+	if deleteErr := folder.DeleteObjects([]string{path}); deleteErr != nil {
+		tracelog.ErrorLogger.Printf("Failed to delete the unverified object %s: %v", path, deleteErr)
+	}
+	return nil, err
+}
+
+// ObjectScanner passes the plaintext of the objects put into the storage without the uploader,
+// like the SQL Server blocks, to the scanner. The nil ObjectScanner puts the objects without the scan.
+type ObjectScanner struct {
+	scanner    UploadScanner
+	tagFlagged bool
+}
+
+// ConfigureObjectScanner configures the ObjectScanner the same way as the uploader scanner,
+// nil if WALG_UPLOAD_SCAN_COMMAND isn't set
+func ConfigureObjectScanner() (*ObjectScanner, error) {
+	scanner, tagFlagged, err := ConfigureUploadScanner()
+	if err != nil || scanner == nil {
+		return nil, err
+	}
+	return &ObjectScanner{scanner: scanner, tagFlagged: tagFlagged}, nil
+}
+
+// PutObject puts the plaintext encoded by encode, e.g. with CompressAndEncrypt, into the folder. The flagged object
+// is deleted and the error is returned, unless the flagged objects are only tagged: then a warning is logged.
+func (objectScanner *ObjectScanner) PutObject(ctx context.Context, folder storage.Folder, name string,
+	plaintext io.Reader, encode func(io.Reader) io.Reader) error {
+	if objectScanner == nil {
+		return folder.PutObjectWithContext(ctx, name, encode(plaintext))
+	}
+	scan, err := objectScanner.scanner.StartScan(ctx, name)
+	if err != nil {
+		return err
+	}
+	err = folder.PutObjectWithContext(ctx, name, encode(io.TeeReader(plaintext, scan)))
+	if err != nil {
+		_, _ = scan.Verdict()
+		return err
+	}
+	_, err = checkScanVerdict(folder, name, scan, objectScanner.tagFlagged)
+	return err
+}
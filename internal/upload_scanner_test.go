@@ -0,0 +1,106 @@
+package internal_test
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal"
+	"github.com/wal-g/wal-g/internal/compression"
+	"github.com/wal-g/wal-g/internal/compression/lz4"
+	conf "github.com/wal-g/wal-g/internal/config"
+	"github.com/wal-g/wal-g/internal/ioextensions"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+// testScanCommand flags the data containing the signature, the way the antivirus does
+const testScanCommand = `if grep -q SIGNATURE; then echo "$WALG_SCAN_OBJECT_PATH: Test-Signature FOUND"; exit 1; fi`
+
+func newScanningUploader(command string, tagFlagged bool) *internal.RegularUploader {
+	uploader := internal.NewRegularUploader(nil, memory.NewFolder("in_memory/", memory.NewKVS()))
+	uploader.SetUploadScanner(internal.NewCommandUploadScanner(func(ctx context.Context) (*exec.Cmd, error) {
+		return exec.CommandContext(ctx, "/bin/sh", "-c", command), nil
+	}), tagFlagged)
+	return uploader
+}
+
+func TestUploadScanner_FailsFlaggedUpload(t *testing.T) {
+	uploader := newScanningUploader(testScanCommand, false)
+
+	require.NoError(t, uploader.Upload(context.Background(), "clean", strings.NewReader("some data")))
+	err := uploader.Upload(context.Background(), "flagged", strings.NewReader("data with SIGNATURE inside"))
+	assert.IsType(t, internal.UploadScanFlaggedError{}, err)
+	assert.Contains(t, err.Error(), "flagged: Test-Signature FOUND")
+
+	exists, err := uploader.Folder().Exists("clean")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = uploader.Folder().Exists("flagged")
+	require.NoError(t, err)
+	assert.False(t, exists, "the flagged object must be deleted")
+	assert.True(t, uploader.Failed())
+}
+
+func TestUploadScanner_TagsFlaggedUpload(t *testing.T) {
+	uploader := newScanningUploader(testScanCommand, true)
+	clone := uploader.Clone()
+
+	require.NoError(t, clone.Upload(context.Background(), "flagged", strings.NewReader("data with SIGNATURE inside")))
+	exists, err := uploader.Folder().Exists("flagged")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, []internal.UploadScanFinding{{Path: "flagged", Details: "flagged: Test-Signature FOUND"}},
+		uploader.UploadScanFindings())
+}
+
+func TestUploadScanner_FailsIfScannerFails(t *testing.T) {
+	uploader := newScanningUploader("cat > /dev/null; exit 2", true)
+
+	err := uploader.Upload(context.Background(), "object", strings.NewReader("some data"))
+	assert.Error(t, err)
+	assert.Empty(t, uploader.UploadScanFindings())
+}
+
+// testPlaintextScanCommand flags the data only if it gets exactly the plaintext, not the compressed data
+const testPlaintextScanCommand = `if [ "$(cat)" = "plain data" ]; then echo "plaintext FOUND"; exit 1; fi`
+
+func TestUploadScanner_ScansPlaintextOfCompressedUpload(t *testing.T) {
+	uploader := newScanningUploader(testPlaintextScanCommand, false)
+	uploader.Compressor = compression.Compressors[lz4.AlgorithmName]
+	content := "plain data"
+
+	err := uploader.UploadFile(context.Background(), ioextensions.NewNamedReaderImpl(strings.NewReader(content), "file"))
+	assert.IsType(t, internal.UploadScanFlaggedError{}, err)
+	exists, err := uploader.Folder().Exists("file.lz4")
+	require.NoError(t, err)
+	assert.False(t, exists, "the flagged object must be deleted")
+
+	err = uploader.PushStreamToDestination(context.Background(), strings.NewReader(content), "stream.lz4")
+	assert.IsType(t, internal.UploadScanFlaggedError{}, err)
+}
+
+func TestObjectScanner_PutObject(t *testing.T) {
+	viper.Set(conf.UploadScanCommandSetting, testPlaintextScanCommand)
+	defer viper.Set(conf.UploadScanCommandSetting, nil)
+	objectScanner, err := internal.ConfigureObjectScanner()
+	require.NoError(t, err)
+	folder := memory.NewFolder("in_memory/", memory.NewKVS())
+	encode := func(plaintext io.Reader) io.Reader {
+		return internal.CompressAndEncrypt(plaintext, compression.Compressors[lz4.AlgorithmName], nil)
+	}
+
+	require.NoError(t, objectScanner.PutObject(context.Background(), folder, "clean", strings.NewReader("data"), encode))
+	err = objectScanner.PutObject(context.Background(), folder, "flagged", strings.NewReader("plain data"), encode)
+	assert.IsType(t, internal.UploadScanFlaggedError{}, err)
+	exists, err := folder.Exists("flagged")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	var noScanner *internal.ObjectScanner
+	require.NoError(t, noScanner.PutObject(context.Background(), folder, "flagged", strings.NewReader("plain data"), encode))
+}
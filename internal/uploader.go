@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -23,6 +24,9 @@ var ErrorSizeTrackingDisabled = fmt.Errorf("size tracking disabled by DisableSiz
 type Uploader interface {
 	Upload(ctx context.Context, path string, content io.Reader) error
 	UploadFile(ctx context.Context, file ioextensions.NamedReader) error
+	// UploadFileWithHeader uploads the file to dstPath like UploadFile, the header is stored as is
+	// in front of the compressed and encrypted content
+	UploadFileWithHeader(ctx context.Context, dstPath string, header []byte, file io.Reader) error
 	PushStream(ctx context.Context, stream io.Reader) (string, error)
 	PushStreamToDestination(ctx context.Context, stream io.Reader, dstPath string) error
 	Compression() compression.Compressor
@@ -32,6 +36,10 @@ type Uploader interface {
 	ChangeDirectory(relativePath string)
 	Folder() storage.Folder
 	Clone() Uploader
+	// StartPlaintextScan returns the writer the plaintext of the object uploaded to the path is written to,
+	// while it is compressed and encrypted, so the scanner gets the plaintext. Upload checks the verdict.
+	StartPlaintextScan(ctx context.Context, path string) (io.Writer, error)
+	UploadScanFindings() []UploadScanFinding
 	Failed() bool
 	Finish()
 }
@@ -45,6 +53,7 @@ type RegularUploader struct {
 	failed          *abool.AtomicBool
 	tarSize         *int64
 	dataSize        *int64
+	scan            *uploadScanState
 }
 
 var _ Uploader = &RegularUploader{}
@@ -135,22 +144,33 @@ func (uploader *RegularUploader) Clone() Uploader {
 		failed:          abool.NewBool(uploader.Failed()),
 		tarSize:         uploader.tarSize,
 		dataSize:        uploader.dataSize,
+		scan:            uploader.scan,
 	}
 }
 
 // TODO : unit tests
 // UploadFile compresses a file and uploads it.
 func (uploader *RegularUploader) UploadFile(ctx context.Context, file ioextensions.NamedReader) error {
-	filename := file.Name()
+	dstPath := utility.SanitizePath(filepath.Base(file.Name()) + "." + uploader.Compressor.FileExtension())
+	return uploader.UploadFileWithHeader(ctx, dstPath, nil, file)
+}
 
-	fileReader := file.(io.Reader)
+func (uploader *RegularUploader) UploadFileWithHeader(ctx context.Context, dstPath string, header []byte,
+	file io.Reader) error {
+	fileReader := file
 	if uploader.dataSize != nil {
 		fileReader = utility.NewWithSizeReader(fileReader, uploader.dataSize)
 	}
-	compressedFile := CompressAndEncrypt(fileReader, uploader.Compressor, ConfigureCrypter())
-	dstPath := utility.SanitizePath(filepath.Base(filename) + "." + uploader.Compressor.FileExtension())
+	compressedFile, err := CompressAndEncryptScanned(ctx, uploader, dstPath, fileReader,
+		uploader.Compressor, ConfigureCrypter())
+	if err != nil {
+		return err
+	}
+	if len(header) > 0 {
+		compressedFile = io.MultiReader(bytes.NewReader(header), compressedFile)
+	}
 
-	err := uploader.Upload(ctx, dstPath, compressedFile)
+	err = uploader.Upload(ctx, dstPath, compressedFile)
 	tracelog.InfoLogger.Println("FILE PATH:", dstPath)
 	return err
 }
@@ -175,13 +195,35 @@ func (uploader *RegularUploader) Upload(ctx context.Context, path string, conten
 	if uploader.tarSize != nil {
 		content = utility.NewWithSizeReader(content, uploader.tarSize)
 	}
-	err := uploader.UploadingFolder.PutObjectWithContext(ctx, path, content)
+	var uploadedSize int64
+	content = trackStorageUsage(content, &uploadedSize)
+	var scan UploadScan
+	var err error
+	if uploader.scan != nil {
+		scan = uploader.scan.takePending(uploader.scanKey(path))
+		if scan == nil {
+			// the content isn't encoded by WAL-G, e.g. the metadata, so it is the plaintext itself
+			scan, err = uploader.scan.scanner.StartScan(ctx, path)
+			if err == nil {
+				content = io.TeeReader(content, scan)
+			}
+		}
+	}
+	if err == nil {
+		err = uploader.UploadingFolder.PutObjectWithContext(ctx, path, content)
+		if scan != nil && err != nil {
+			_, _ = scan.Verdict()
+		} else if scan != nil {
+			err = uploader.checkUploadScanVerdict(path, scan)
+		}
+	}
 	if err != nil {
 		statistics.WalgMetrics.UploadedFilesFailedTotal.Inc()
 		uploader.failed.Set()
 		tracelog.ErrorLogger.Printf(tracelog.GetErrorFormatter()+"\n", err)
 		return err
 	}
+	AddStorageUsage(uploader.UploadingFolder, uploadedSize)
 	return nil
 }
 
@@ -207,6 +249,34 @@ func (uploader *RegularUploader) Folder() storage.Folder {
 	return uploader.UploadingFolder
 }
 
+// SetUploadScanner makes the uploader pass the uploaded data to the scanner. The flagged uploads fail,
+// unless tagFlagged is set: then they are only listed in UploadScanFindings.
+func (uploader *RegularUploader) SetUploadScanner(scanner UploadScanner, tagFlagged bool) {
+	uploader.scan = &uploadScanState{scanner: scanner, tagFlagged: tagFlagged}
+}
+
+func (uploader *RegularUploader) StartPlaintextScan(ctx context.Context, path string) (io.Writer, error) {
+	if uploader.scan == nil {
+		return io.Discard, nil
+	}
+	scan, err := uploader.scan.scanner.StartScan(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	uploader.scan.addPending(uploader.scanKey(path), scan)
+	return scan, nil
+}
+
+// scanKey identifies the object among the ones uploaded by the clones of the uploader
+func (uploader *RegularUploader) scanKey(path string) string {
+	return uploader.UploadingFolder.GetPath() + path
+}
+
+// UploadScanFindings lists the uploads flagged by the scanner and tagged instead of failed
+func (uploader *RegularUploader) UploadScanFindings() []UploadScanFinding {
+	return uploader.scan.getFindings()
+}
+
 func (uploader *RegularUploader) Failed() bool {
 	return uploader.failed.IsSet()
 }
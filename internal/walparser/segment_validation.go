@@ -0,0 +1,99 @@
+package walparser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+const (
+	// offsets of xlp_seg_size and xlp_xlog_blcksz in XLogLongPageHeaderData
+	longHeaderSegmentSizeOffset = 32
+	longHeaderBlockSizeOffset   = 36
+)
+
+type InvalidSegmentError struct {
+	error
+}
+
+func NewInvalidSegmentError(format string, args ...interface{}) InvalidSegmentError {
+	return InvalidSegmentError{errors.Errorf("invalid WAL segment: "+format, args...)}
+}
+
+func (err InvalidSegmentError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// ValidateSegment checks that the reader contains a complete WAL segment of segmentSize bytes
+// which starts at segmentStart and whose pages belong to timelines not later than timeline.
+// Zero pages are allowed after the first one. Pages of the previous timelines are expected
+// in the first segment of a new timeline, since it is a copy of the old timeline segment.
+func ValidateSegment(reader io.Reader, timeline uint32, segmentStart uint64, segmentSize uint64) error {
+	page := make([]byte, WalPageSize)
+	var magic uint16
+	for offset := uint64(0); offset < segmentSize; offset += uint64(WalPageSize) {
+		readCount, err := io.ReadFull(reader, page)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return NewInvalidSegmentError("segment is truncated to %d bytes, expected %d", offset+uint64(readCount), segmentSize)
+		}
+		if err != nil {
+			return err
+		}
+
+		header, err := readXLogPageHeader(bytes.NewReader(page))
+		if offset == 0 {
+			if err != nil {
+				return NewInvalidSegmentError("first page: %v", err)
+			}
+			err = validateLongPageHeader(header, page, segmentSize)
+			if err != nil {
+				return err
+			}
+			magic = header.Magic
+		} else {
+			if _, ok := err.(ZeroPageHeaderError); ok {
+				continue
+			}
+			if err != nil {
+				return NewInvalidSegmentError("page at offset %d: %v", offset, err)
+			}
+			if header.Magic != magic {
+				return NewInvalidSegmentError("page at offset %d has magic %X, expected %X", offset, header.Magic, magic)
+			}
+		}
+
+		if uint64(header.PageAddress) != segmentStart+offset {
+			return NewInvalidSegmentError("page at offset %d has address %X, expected %X",
+				offset, uint64(header.PageAddress), segmentStart+offset)
+		}
+		if uint32(header.TimeLineID) > timeline {
+			return NewInvalidSegmentError("page at offset %d belongs to timeline %d, expected %d or earlier",
+				offset, header.TimeLineID, timeline)
+		}
+	}
+
+	readCount, _ := reader.Read(make([]byte, 1))
+	if readCount > 0 {
+		return NewInvalidSegmentError("segment is longer than %d bytes", segmentSize)
+	}
+	return nil
+}
+
+func validateLongPageHeader(header *XLogPageHeader, page []byte, segmentSize uint64) error {
+	if !header.IsLong() {
+		return NewInvalidSegmentError("first page has no long header")
+	}
+	headerSegmentSize := binary.LittleEndian.Uint32(page[longHeaderSegmentSizeOffset:])
+	if uint64(headerSegmentSize) != segmentSize {
+		return NewInvalidSegmentError("segment size in the header is %d, expected %d", headerSegmentSize, segmentSize)
+	}
+	blockSize := binary.LittleEndian.Uint32(page[longHeaderBlockSizeOffset:])
+	if blockSize != uint32(WalPageSize) {
+		return NewInvalidSegmentError("WAL block size in the header is %d, expected %d", blockSize, WalPageSize)
+	}
+	return nil
+}
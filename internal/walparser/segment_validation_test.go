@@ -0,0 +1,65 @@
+package walparser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testSegmentSize  = 4 * uint64(WalPageSize)
+	testSegmentStart = 0x2b000000
+)
+
+func makeTestSegment(timeline uint32) []byte {
+	segment := make([]byte, testSegmentSize)
+	for offset := uint64(0); offset < testSegmentSize; offset += uint64(WalPageSize) {
+		page := segment[offset:]
+		binary.LittleEndian.PutUint16(page[0:], 0xd10d)
+		if offset == 0 {
+			binary.LittleEndian.PutUint16(page[2:], XlpLongHeader)
+			binary.LittleEndian.PutUint32(page[longHeaderSegmentSizeOffset:], uint32(testSegmentSize))
+			binary.LittleEndian.PutUint32(page[longHeaderBlockSizeOffset:], uint32(WalPageSize))
+		}
+		binary.LittleEndian.PutUint32(page[4:], timeline)
+		binary.LittleEndian.PutUint64(page[8:], testSegmentStart+offset)
+	}
+	return segment
+}
+
+func TestValidateSegment(t *testing.T) {
+	t.Run("accepts valid segment", func(t *testing.T) {
+		err := ValidateSegment(bytes.NewReader(makeTestSegment(2)), 2, testSegmentStart, testSegmentSize)
+		assert.NoError(t, err)
+	})
+
+	t.Run("accepts pages of previous timelines and zero pages", func(t *testing.T) {
+		segment := makeTestSegment(1)
+		copy(segment[3*uint64(WalPageSize):], make([]byte, WalPageSize))
+		err := ValidateSegment(bytes.NewReader(segment), 2, testSegmentStart, testSegmentSize)
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejects truncated segment", func(t *testing.T) {
+		segment := makeTestSegment(1)[:testSegmentSize-100]
+		err := ValidateSegment(bytes.NewReader(segment), 1, testSegmentStart, testSegmentSize)
+		assert.IsType(t, InvalidSegmentError{}, err)
+	})
+
+	t.Run("rejects segment of another position", func(t *testing.T) {
+		err := ValidateSegment(bytes.NewReader(makeTestSegment(1)), 1, testSegmentStart+testSegmentSize, testSegmentSize)
+		assert.IsType(t, InvalidSegmentError{}, err)
+	})
+
+	t.Run("rejects segment of a later timeline", func(t *testing.T) {
+		err := ValidateSegment(bytes.NewReader(makeTestSegment(3)), 2, testSegmentStart, testSegmentSize)
+		assert.IsType(t, InvalidSegmentError{}, err)
+	})
+
+	t.Run("rejects segment of another size", func(t *testing.T) {
+		err := ValidateSegment(bytes.NewReader(makeTestSegment(1)), 1, testSegmentStart, testSegmentSize/2)
+		assert.IsType(t, InvalidSegmentError{}, err)
+	})
+}
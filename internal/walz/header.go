@@ -0,0 +1,190 @@
+package walz
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+// FileExtension is appended to the names of the WAL files stored in the walz envelope
+const FileExtension = "walz"
+
+const (
+	formatVersion  = 1
+	walNameLength  = 24
+	maxStringField = 255
+)
+
+var magic = [4]byte{'W', 'A', 'L', 'Z'}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+type InvalidHeaderError struct {
+	error
+}
+
+func newInvalidHeaderError(format string, args ...interface{}) InvalidHeaderError {
+	return InvalidHeaderError{errors.Errorf(format, args...)}
+}
+
+func (err InvalidHeaderError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// Header makes the archived WAL file self-describing: it is stored unencrypted in front of
+// the compressed and encrypted content and tells how to decode the content and what it must be.
+//
+// Layout (little endian): magic "WALZ", version (1 byte), timeline (4), start LSN (8), end LSN (8),
+// content size (8), CRC-32C of the content (4), compression (1 byte length + string),
+// encryption key ID (1 byte length + string), CRC-32C of all the previous header bytes (4).
+type Header struct {
+	Timeline uint32
+	StartLSN uint64
+	EndLSN   uint64
+	Size     uint64
+	// Checksum is CRC-32C of the WAL file content
+	Checksum uint32
+	// Compression is the file extension of the compression method, empty if the content is not compressed
+	Compression string
+	// KeyID identifies the encryption key, empty if the content is not encrypted
+	KeyID string
+}
+
+// NewHeader describes the WAL file content. The timeline and the LSN range are known only for WAL segments.
+func NewHeader(walFileName string, content []byte) Header {
+	return newHeader(walFileName, uint64(len(content)), crc32.Checksum(content, castagnoli))
+}
+
+// NewHeaderFromReader describes the WAL file content read to the end, the content isn't kept in memory
+func NewHeaderFromReader(walFileName string, reader io.Reader) (Header, error) {
+	hash := crc32.New(castagnoli)
+	size, err := io.Copy(hash, reader)
+	if err != nil {
+		return Header{}, err
+	}
+	return newHeader(walFileName, uint64(size), hash.Sum32()), nil
+}
+
+func newHeader(walFileName string, size uint64, checksum uint32) Header {
+	header := Header{
+		Size:     size,
+		Checksum: checksum,
+	}
+	if timeline, segmentNo, ok := parseSegmentName(walFileName, header.Size); ok {
+		header.Timeline = timeline
+		header.StartLSN = segmentNo * header.Size
+		header.EndLSN = header.StartLSN + header.Size
+	}
+	return header
+}
+
+func (header Header) WriteTo(writer io.Writer) (int64, error) {
+	if len(header.Compression) > maxStringField || len(header.KeyID) > maxStringField {
+		return 0, errors.New("walz: compression and key ID must be at most 255 bytes")
+	}
+	var buffer bytes.Buffer
+	buffer.Write(magic[:])
+	buffer.WriteByte(formatVersion)
+	_ = binary.Write(&buffer, binary.LittleEndian, header.Timeline)
+	_ = binary.Write(&buffer, binary.LittleEndian, header.StartLSN)
+	_ = binary.Write(&buffer, binary.LittleEndian, header.EndLSN)
+	_ = binary.Write(&buffer, binary.LittleEndian, header.Size)
+	_ = binary.Write(&buffer, binary.LittleEndian, header.Checksum)
+	buffer.WriteByte(byte(len(header.Compression)))
+	buffer.WriteString(header.Compression)
+	buffer.WriteByte(byte(len(header.KeyID)))
+	buffer.WriteString(header.KeyID)
+	_ = binary.Write(&buffer, binary.LittleEndian, crc32.Checksum(buffer.Bytes(), castagnoli))
+
+	n, err := writer.Write(buffer.Bytes())
+	return int64(n), err
+}
+
+// ReadHeader reads the header and leaves the reader at the beginning of the content
+func ReadHeader(reader io.Reader) (Header, error) {
+	var raw bytes.Buffer
+	reader = io.TeeReader(reader, &raw)
+
+	var prefix [5]byte
+	if _, err := io.ReadFull(reader, prefix[:]); err != nil {
+		return Header{}, newInvalidHeaderError("walz: failed to read the header: %v", err)
+	}
+	if !bytes.Equal(prefix[:4], magic[:]) {
+		return Header{}, newInvalidHeaderError("walz: wrong magic %x", prefix[:4])
+	}
+	if prefix[4] != formatVersion {
+		return Header{}, newInvalidHeaderError("walz: unsupported format version %d", prefix[4])
+	}
+
+	var header Header
+	fields := []interface{}{&header.Timeline, &header.StartLSN, &header.EndLSN, &header.Size, &header.Checksum}
+	for _, field := range fields {
+		if err := binary.Read(reader, binary.LittleEndian, field); err != nil {
+			return Header{}, newInvalidHeaderError("walz: failed to read the header: %v", err)
+		}
+	}
+	var err error
+	if header.Compression, err = readString(reader); err != nil {
+		return Header{}, err
+	}
+	if header.KeyID, err = readString(reader); err != nil {
+		return Header{}, err
+	}
+
+	expectedChecksum := crc32.Checksum(raw.Bytes(), castagnoli)
+	var checksum uint32
+	if err = binary.Read(reader, binary.LittleEndian, &checksum); err != nil {
+		return Header{}, newInvalidHeaderError("walz: failed to read the header: %v", err)
+	}
+	if checksum != expectedChecksum {
+		return Header{}, newInvalidHeaderError("walz: header checksum mismatch")
+	}
+	return header, nil
+}
+
+func readString(reader io.Reader) (string, error) {
+	var length [1]byte
+	if _, err := io.ReadFull(reader, length[:]); err != nil {
+		return "", newInvalidHeaderError("walz: failed to read the header: %v", err)
+	}
+	value := make([]byte, length[0])
+	if _, err := io.ReadFull(reader, value); err != nil {
+		return "", newInvalidHeaderError("walz: failed to read the header: %v", err)
+	}
+	return string(value), nil
+}
+
+// Validate checks that the envelope holds the requested WAL file
+func (header Header) Validate(walFileName string) error {
+	timeline, segmentNo, ok := parseSegmentName(walFileName, header.Size)
+	if !ok {
+		return nil
+	}
+	if header.Timeline != timeline || header.StartLSN != segmentNo*header.Size {
+		return newInvalidHeaderError("walz: envelope holds WAL of timeline %d at LSN %X, not %s",
+			header.Timeline, header.StartLSN, walFileName)
+	}
+	return nil
+}
+
+// parseSegmentName parses the name of a WAL segment of the given size, e.g. 000000010000000A00000023
+func parseSegmentName(name string, segmentSize uint64) (timeline uint32, segmentNo uint64, ok bool) {
+	if len(name) != walNameLength || segmentSize == 0 || segmentSize > 0x100000000 {
+		return 0, 0, false
+	}
+	parsed := make([]uint64, 3)
+	for i := range parsed {
+		value, err := strconv.ParseUint(name[i*8:(i+1)*8], 16, 32)
+		if err != nil {
+			return 0, 0, false
+		}
+		parsed[i] = value
+	}
+	return uint32(parsed[0]), parsed[1]*(0x100000000/segmentSize) + parsed[2], true
+}
@@ -0,0 +1,86 @@
+package walz_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/internal/walz"
+)
+
+const testSegmentSize = 16 * 1024 * 1024
+
+func TestHeader_RoundTrip(t *testing.T) {
+	content := bytes.Repeat([]byte("wal record"), testSegmentSize/10)
+	content = append(content, make([]byte, testSegmentSize-len(content))...)
+
+	header := walz.NewHeader("000000030000000A00000023", content)
+	assert.Equal(t, uint32(3), header.Timeline)
+	assert.Equal(t, uint64(0xA23000000), header.StartLSN)
+	assert.Equal(t, uint64(0xA24000000), header.EndLSN)
+	header.Compression = "lz4"
+	header.KeyID = "key-1"
+
+	var buffer bytes.Buffer
+	_, err := header.WriteTo(&buffer)
+	require.NoError(t, err)
+	buffer.WriteString("payload")
+
+	read, err := walz.ReadHeader(&buffer)
+	require.NoError(t, err)
+	assert.Equal(t, header, read)
+	assert.Equal(t, "payload", buffer.String())
+
+	assert.NoError(t, read.Validate("000000030000000A00000023"))
+	assert.IsType(t, walz.InvalidHeaderError{}, read.Validate("000000030000000A00000024"))
+	assert.IsType(t, walz.InvalidHeaderError{}, read.Validate("000000020000000A00000023"))
+	assert.NoError(t, read.Validate("00000003.history"))
+}
+
+func TestReadHeader_Corrupted(t *testing.T) {
+	var buffer bytes.Buffer
+	_, err := walz.NewHeader("00000001.history", []byte("history")).WriteTo(&buffer)
+	require.NoError(t, err)
+	raw := buffer.Bytes()
+
+	_, err = walz.ReadHeader(bytes.NewReader(raw[:len(raw)-2]))
+	assert.IsType(t, walz.InvalidHeaderError{}, err)
+
+	corrupted := append([]byte{}, raw...)
+	corrupted[6]++
+	_, err = walz.ReadHeader(bytes.NewReader(corrupted))
+	assert.IsType(t, walz.InvalidHeaderError{}, err)
+
+	_, err = walz.ReadHeader(bytes.NewReader([]byte("not an envelope at all")))
+	assert.IsType(t, walz.InvalidHeaderError{}, err)
+}
+
+func TestVerifyingReader(t *testing.T) {
+	content := []byte("00000001.history content")
+	header := walz.NewHeader("00000001.history", content)
+
+	read, err := io.ReadAll(walz.NewVerifyingReader(bytes.NewReader(content), header))
+	require.NoError(t, err)
+	assert.Equal(t, content, read)
+
+	corrupted := append([]byte{}, content...)
+	corrupted[0] = 'X'
+	_, err = io.ReadAll(walz.NewVerifyingReader(bytes.NewReader(corrupted), header))
+	assert.IsType(t, walz.ChecksumMismatchError{}, err)
+
+	_, err = io.ReadAll(walz.NewVerifyingReader(bytes.NewReader(content[:10]), header))
+	assert.IsType(t, walz.ChecksumMismatchError{}, err)
+
+	_, err = io.ReadAll(walz.NewVerifyingReader(bytes.NewReader(append(content, '!')), header))
+	assert.IsType(t, walz.ChecksumMismatchError{}, err)
+}
+
+func TestNewHeaderFromReader(t *testing.T) {
+	content := bytes.Repeat([]byte("wal record"), 1000)
+
+	header, err := walz.NewHeaderFromReader("00000002.history", bytes.NewReader(content))
+	require.NoError(t, err)
+	assert.Equal(t, walz.NewHeader("00000002.history", content), header)
+}
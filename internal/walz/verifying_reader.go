@@ -0,0 +1,59 @@
+package walz
+
+import (
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+)
+
+type ChecksumMismatchError struct {
+	error
+}
+
+func newChecksumMismatchError(format string, args ...interface{}) ChecksumMismatchError {
+	return ChecksumMismatchError{errors.Errorf(format, args...)}
+}
+
+func (err ChecksumMismatchError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// VerifyingReader checks the size and the checksum of the decoded content declared in the header.
+// The mismatch is reported instead of io.EOF, so the corrupted file is never handed over as complete.
+type VerifyingReader struct {
+	reader io.Reader
+	header Header
+	hash   hash.Hash32
+	read   uint64
+}
+
+func NewVerifyingReader(reader io.Reader, header Header) *VerifyingReader {
+	return &VerifyingReader{
+		reader: reader,
+		header: header,
+		hash:   crc32.New(castagnoli),
+	}
+}
+
+func (verifier *VerifyingReader) Read(p []byte) (int, error) {
+	n, err := verifier.reader.Read(p)
+	verifier.hash.Write(p[:n])
+	verifier.read += uint64(n)
+	if verifier.read > verifier.header.Size {
+		return n, newChecksumMismatchError("walz: content is longer than %d bytes", verifier.header.Size)
+	}
+	if err == io.EOF {
+		if verifier.read != verifier.header.Size {
+			return n, newChecksumMismatchError("walz: content size %d doesn't match %d", verifier.read, verifier.header.Size)
+		}
+		if verifier.hash.Sum32() != verifier.header.Checksum {
+			return n, newChecksumMismatchError("walz: content checksum %08x doesn't match %08x",
+				verifier.hash.Sum32(), verifier.header.Checksum)
+		}
+	}
+	return n, err
+}
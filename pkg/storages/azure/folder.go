@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,6 +15,10 @@ import (
 	"github.com/pkg/errors"
 )
 
+var _ storage.PresignableFolder = &Folder{}
+
+const copyPollInterval = time.Second
+
 // TODO: Unit tests
 type Folder struct {
 	path                string
@@ -121,6 +126,23 @@ func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, erro
 	return reader, nil
 }
 
+// PresignObject issues a read-only SAS for the blob. It is only possible when the storage is accessed with
+// the account access key.
+func (folder *Folder) PresignObject(objectRelativePath string, ttl time.Duration) (string, error) {
+	path := storage.JoinPath(folder.path, objectRelativePath)
+	blobClient, err := folder.containerClient.NewBlockBlobClient(path)
+	if err != nil {
+		return "", fmt.Errorf("init Azure Blob client to presign object %q: %w", path, err)
+	}
+
+	now := time.Now().UTC()
+	sas, err := blobClient.GetSASToken(azblob.BlobSASPermissions{Read: true}, now, now.Add(ttl))
+	if err != nil {
+		return "", fmt.Errorf("issue SAS for blob %q: %w", path, err)
+	}
+	return blobClient.URL() + "?" + sas.Encode(), nil
+}
+
 func (folder *Folder) PutObject(name string, content io.Reader) error {
 	return folder.PutObjectWithContext(context.Background(), name, content)
 }
@@ -144,6 +166,12 @@ func (folder *Folder) PutObjectWithContext(ctx context.Context, name string, con
 }
 
 func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath, storage.CopyOptions{})
+}
+
+// CopyObjectWithContext copies the blob server-side. Azure copies the blobs asynchronously, so the copy is waited for
+// and aborted if the context is done before it completes.
+func (folder *Folder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string, options storage.CopyOptions) error {
 	var exists bool
 	var err error
 	if exists, err = folder.Exists(srcPath); !exists {
@@ -152,6 +180,8 @@ func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
 		}
 		return err
 	}
+	srcPath = storage.JoinPath(folder.path, srcPath)
+	dstPath = storage.JoinPath(folder.path, dstPath)
 	var srcClient, dstClient *azblob.BlockBlobClient
 	srcClient, err = folder.containerClient.NewBlockBlobClient(srcPath)
 	if err != nil {
@@ -161,9 +191,52 @@ func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
 	if err != nil {
 		return fmt.Errorf("init Azure Blob client for copy destination %q: %w", dstPath, err)
 	}
-	_, err = dstClient.StartCopyFromURL(context.Background(), srcClient.URL(),
+	started, err := dstClient.StartCopyFromURL(ctx, srcClient.URL(),
 		&azblob.BlobStartCopyOptions{Tier: azblob.AccessTierHot.ToPtr()})
-	return err
+	if err != nil {
+		return fmt.Errorf("start copying Azure blob %q to %q: %w", srcPath, dstPath, err)
+	}
+
+	status := started.CopyStatus
+	ticker := time.NewTicker(copyPollInterval)
+	defer ticker.Stop()
+	for status != nil && *status == azblob.CopyStatusTypePending {
+		select {
+		case <-ctx.Done():
+			if started.CopyID != nil {
+				_, abortErr := dstClient.AbortCopyFromURL(context.Background(), *started.CopyID, nil)
+				if abortErr != nil {
+					tracelog.WarningLogger.Printf("Failed to abort copying Azure blob %q: %v", dstPath, abortErr)
+				}
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		props, err := dstClient.GetProperties(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("get the status of copying Azure blob %q: %w", dstPath, err)
+		}
+		status = props.CopyStatus
+		if options.Progress != nil && props.CopyProgress != nil {
+			if copied, ok := parseCopyProgress(*props.CopyProgress); ok {
+				options.Progress(copied)
+			}
+		}
+	}
+	if status != nil && *status != azblob.CopyStatusTypeSuccess {
+		return fmt.Errorf("copy Azure blob %q to %q: copy status is %s", srcPath, dstPath, *status)
+	}
+	return nil
+}
+
+// parseCopyProgress parses the number of the copied bytes out of the "<copied>/<total>" copy progress
+func parseCopyProgress(progress string) (int64, bool) {
+	copied, _, found := strings.Cut(progress, "/")
+	if !found {
+		return 0, false
+	}
+	bytes, err := strconv.ParseInt(copied, 10, 64)
+	return bytes, err == nil
 }
 
 func (folder *Folder) DeleteObjects(objectRelativePaths []string) error {
@@ -0,0 +1,167 @@
+package erasure
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The shards are encoded with the systematic Reed-Solomon code over GF(2^8): the data shards are stored as is,
+// and the parity shards are the products of the Cauchy matrix and the data shards. Any square submatrix of
+// the Cauchy matrix is invertible, so the data can be reconstructed from any dataShards of the shards.
+
+const fieldPolynomial = 0x11d
+
+var (
+	fieldExp [510]byte
+	fieldLog [256]byte
+	fieldMul [256][256]byte
+)
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		fieldExp[i] = byte(x)
+		fieldExp[i+255] = byte(x)
+		fieldLog[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= fieldPolynomial
+		}
+	}
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			fieldMul[a][b] = fieldExp[int(fieldLog[a])+int(fieldLog[b])]
+		}
+	}
+}
+
+func fieldInverse(a byte) byte {
+	return fieldExp[255-int(fieldLog[a])]
+}
+
+type codec struct {
+	dataShards  int
+	totalShards int
+	// matrix has totalShards rows of dataShards coefficients, the first dataShards rows are the identity
+	matrix [][]byte
+}
+
+func newCodec(dataShards, totalShards int) (*codec, error) {
+	if dataShards < 1 || totalShards <= dataShards || totalShards > 256 {
+		return nil, fmt.Errorf("invalid erasure coding scheme: %d of %d shards", dataShards, totalShards)
+	}
+	matrix := make([][]byte, totalShards)
+	for row := range matrix {
+		matrix[row] = make([]byte, dataShards)
+		if row < dataShards {
+			matrix[row][row] = 1
+			continue
+		}
+		for column := range matrix[row] {
+			// row and column are distinct, so their sum in GF(2^8) is never zero
+			matrix[row][column] = fieldInverse(byte(row) ^ byte(column))
+		}
+	}
+	return &codec{dataShards: dataShards, totalShards: totalShards, matrix: matrix}, nil
+}
+
+// encode computes the parity shards from the data shards, all the shards must have the same length
+func (c *codec) encode(shards [][]byte) {
+	for row := c.dataShards; row < c.totalShards; row++ {
+		c.combine(shards[row], c.matrix[row], shards[:c.dataShards])
+	}
+}
+
+// reconstructData restores the missing data shards from any dataShards of the present ones.
+// The missing shards are nil, the present ones must have the same length.
+func (c *codec) reconstructData(shards [][]byte) error {
+	present := make([]int, 0, c.dataShards)
+	missingData := false
+	for index, shard := range shards {
+		if shard == nil {
+			missingData = missingData || index < c.dataShards
+			continue
+		}
+		if len(present) < c.dataShards {
+			present = append(present, index)
+		}
+	}
+	if !missingData {
+		return nil
+	}
+	if len(present) < c.dataShards {
+		return fmt.Errorf("only %d shards are present, %d are required", len(present), c.dataShards)
+	}
+
+	subMatrix := make([][]byte, c.dataShards)
+	inputs := make([][]byte, c.dataShards)
+	for i, index := range present {
+		subMatrix[i] = append([]byte(nil), c.matrix[index]...)
+		inputs[i] = shards[index]
+	}
+	decodeMatrix, err := invertMatrix(subMatrix)
+	if err != nil {
+		return err
+	}
+	shardSize := len(inputs[0])
+	for index := 0; index < c.dataShards; index++ {
+		if shards[index] != nil {
+			continue
+		}
+		shards[index] = make([]byte, shardSize)
+		c.combine(shards[index], decodeMatrix[index], inputs)
+	}
+	return nil
+}
+
+// combine writes the linear combination of the inputs with the coefficients to the output
+func (c *codec) combine(output []byte, coefficients []byte, inputs [][]byte) {
+	for i := range output {
+		output[i] = 0
+	}
+	for i, input := range inputs {
+		products := &fieldMul[coefficients[i]]
+		for j, value := range input {
+			output[j] ^= products[value]
+		}
+	}
+}
+
+// invertMatrix inverts the square matrix in place with the Gauss-Jordan elimination
+func invertMatrix(matrix [][]byte) ([][]byte, error) {
+	size := len(matrix)
+	inverse := make([][]byte, size)
+	for row := range inverse {
+		inverse[row] = make([]byte, size)
+		inverse[row][row] = 1
+	}
+	for column := 0; column < size; column++ {
+		pivot := column
+		for pivot < size && matrix[pivot][column] == 0 {
+			pivot++
+		}
+		if pivot == size {
+			return nil, errors.New("erasure coding matrix is singular")
+		}
+		matrix[column], matrix[pivot] = matrix[pivot], matrix[column]
+		inverse[column], inverse[pivot] = inverse[pivot], inverse[column]
+
+		scale := &fieldMul[fieldInverse(matrix[column][column])]
+		for j := 0; j < size; j++ {
+			matrix[column][j] = scale[matrix[column][j]]
+			inverse[column][j] = scale[inverse[column][j]]
+		}
+		for row := 0; row < size; row++ {
+			factor := matrix[row][column]
+			if row == column || factor == 0 {
+				continue
+			}
+			products := &fieldMul[factor]
+			for j := 0; j < size; j++ {
+				matrix[row][j] ^= products[matrix[column][j]]
+				inverse[row][j] ^= products[inverse[column][j]]
+			}
+		}
+	}
+	return inverse, nil
+}
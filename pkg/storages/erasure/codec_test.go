@@ -0,0 +1,48 @@
+package erasure
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodecReconstructsFromAnyDataShards(t *testing.T) {
+	const dataShards, totalShards, shardSize = 3, 5, 64
+	c, err := newCodec(dataShards, totalShards)
+	require.NoError(t, err)
+
+	random := rand.New(rand.NewSource(1))
+	shards := make([][]byte, totalShards)
+	for i := range shards {
+		shards[i] = make([]byte, shardSize)
+		if i < dataShards {
+			random.Read(shards[i])
+		}
+	}
+	c.encode(shards)
+
+	for lost1 := 0; lost1 < totalShards; lost1++ {
+		for lost2 := lost1 + 1; lost2 < totalShards; lost2++ {
+			damaged := make([][]byte, totalShards)
+			copy(damaged, shards)
+			damaged[lost1], damaged[lost2] = nil, nil
+			require.NoError(t, c.reconstructData(damaged))
+			assert.Equal(t, shards[:dataShards], damaged[:dataShards], "lost shards %d and %d", lost1, lost2)
+		}
+	}
+}
+
+func TestCodecNotEnoughShards(t *testing.T) {
+	c, err := newCodec(2, 3)
+	require.NoError(t, err)
+	assert.Error(t, c.reconstructData([][]byte{nil, {1}, nil}))
+}
+
+func TestNewCodecInvalidScheme(t *testing.T) {
+	_, err := newCodec(3, 3)
+	assert.Error(t, err)
+	_, err = newCodec(0, 2)
+	assert.Error(t, err)
+}
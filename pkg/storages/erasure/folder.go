@@ -0,0 +1,276 @@
+// Package erasure provides a storage.Folder that splits every object into erasure-coded shards spread across
+// several folders. With k data shards out of n, the objects survive the loss of any n-k of the folders, while
+// taking n/k of the object size instead of n full copies.
+package erasure
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+// DefaultBlockSize is the size of the shard block, a stripe of blocks from every shard is encoded at once
+const DefaultBlockSize = 1 << 20
+
+// The shard starts with the header: the magic, the data shards number, the total shards number, the shard index
+// and the block size. Each stripe of the object follows as the length of the object data in the stripe,
+// the CRC32 of the block and the block. The stripe with less data than the full one is the last.
+const (
+	shardMagic       = "WALGEC01"
+	shardHeaderSize  = len(shardMagic) + 3 + 4
+	stripeHeaderSize = 8
+)
+
+var _ storage.Folder = &Folder{}
+
+var errShardUploadFinished = errors.New("the shard upload has finished before the end of the shard")
+
+type Folder struct {
+	shards    []storage.Folder
+	codec     *codec
+	blockSize int
+}
+
+// NewFolder creates the folder keeping dataShards of the shards of every object in the shard folders
+// and the rest as the parity, the shard folders must always be listed in the same order
+func NewFolder(dataShards int, shards []storage.Folder) (*Folder, error) {
+	codec, err := newCodec(dataShards, len(shards))
+	if err != nil {
+		return nil, err
+	}
+	return &Folder{shards: shards, codec: codec, blockSize: DefaultBlockSize}, nil
+}
+
+func (folder *Folder) withShards(shards []storage.Folder) *Folder {
+	return &Folder{shards: shards, codec: folder.codec, blockSize: folder.blockSize}
+}
+
+func (folder *Folder) GetPath() string {
+	return folder.shards[0].GetPath()
+}
+
+// ListFolder lists the objects that have enough shards to be read. The size of the original object is not
+// known without reading it, so the reported size is the size of the data shards.
+func (folder *Folder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
+	shardCounts := make(map[string]int)
+	shardObjects := make(map[string]storage.Object)
+	subFolderNames := make(map[string]bool)
+	failed := 0
+	for index, shard := range folder.shards {
+		shardObjectList, shardSubFolders, err := shard.ListFolder()
+		if err != nil {
+			failed++
+			if failed > folder.codec.totalShards-folder.codec.dataShards {
+				return nil, nil, err
+			}
+			tracelog.WarningLogger.Printf("Failed to list erasure coding shard %d in %s: %v", index, shard.GetPath(), err)
+			continue
+		}
+		for _, object := range shardObjectList {
+			shardCounts[object.GetName()]++
+			if _, ok := shardObjects[object.GetName()]; !ok {
+				shardObjects[object.GetName()] = object
+			}
+		}
+		for _, subFolder := range shardSubFolders {
+			subFolderNames[strings.TrimPrefix(subFolder.GetPath(), shard.GetPath())] = true
+		}
+	}
+
+	for name, object := range shardObjects {
+		if shardCounts[name] < folder.codec.dataShards {
+			continue
+		}
+		size := (object.GetSize() - int64(shardHeaderSize)) * int64(folder.codec.dataShards)
+		objects = append(objects, storage.NewLocalObject(name, object.GetLastModified(), size))
+	}
+	for name := range subFolderNames {
+		subFolders = append(subFolders, folder.GetSubFolder(name))
+	}
+	return objects, subFolders, nil
+}
+
+func (folder *Folder) DeleteObjects(objectRelativePaths []string) error {
+	var firstErr error
+	for _, shard := range folder.shards {
+		err := shard.DeleteObjects(objectRelativePaths)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Exists tells if the object has enough shards to be read
+func (folder *Folder) Exists(objectRelativePath string) (bool, error) {
+	found := 0
+	var lastErr error
+	for _, shard := range folder.shards {
+		exists, err := shard.Exists(objectRelativePath)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if exists {
+			found++
+		}
+	}
+	if found >= folder.codec.dataShards || lastErr == nil {
+		return found >= folder.codec.dataShards, nil
+	}
+	return false, lastErr
+}
+
+func (folder *Folder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	shards := make([]storage.Folder, len(folder.shards))
+	for i, shard := range folder.shards {
+		shards[i] = shard.GetSubFolder(subFolderRelativePath)
+	}
+	return folder.withShards(shards)
+}
+
+func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	reader := &decodingReader{
+		folder: folder,
+		name:   objectRelativePath,
+		states: make([]shardState, folder.codec.totalShards),
+		shards: make([]io.ReadCloser, folder.codec.totalShards),
+	}
+	// the first stripe is read right away, so the missing object is reported here
+	err := reader.readStripe()
+	if err != nil {
+		utility.LoggedClose(reader, "")
+		return nil, err
+	}
+	return reader, nil
+}
+
+func (folder *Folder) PutObject(name string, content io.Reader) error {
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+// PutObjectWithContext uploads the shards to all the shard folders at once, it fails if any of them fails,
+// since the object stored with less shards would not survive the loss of the folders it is meant to
+func (folder *Folder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
+	writers := make([]io.Writer, len(folder.shards))
+	pipeWriters := make([]*io.PipeWriter, len(folder.shards))
+	errs := make([]error, len(folder.shards))
+	wg := sync.WaitGroup{}
+	for i, shard := range folder.shards {
+		pipeReader, pipeWriter := io.Pipe()
+		writers[i] = pipeWriter
+		pipeWriters[i] = pipeWriter
+		wg.Add(1)
+		go func(i int, shard storage.Folder) {
+			defer wg.Done()
+			err := shard.PutObjectWithContext(ctx, name, pipeReader)
+			errs[i] = err
+			if err == nil {
+				// unblocks the encoder if the upload has stopped reading before the end of the shard
+				err = errShardUploadFinished
+			}
+			_ = pipeReader.CloseWithError(err)
+		}(i, shard)
+	}
+
+	encodeErr := folder.encode(content, writers)
+	for _, pipeWriter := range pipeWriters {
+		_ = pipeWriter.CloseWithError(encodeErr)
+	}
+	wg.Wait()
+	if encodeErr != nil {
+		return fmt.Errorf("upload erasure coded %s: %w", name, encodeErr)
+	}
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("upload erasure coding shard %d of %s: %w", i, name, err)
+		}
+	}
+	return nil
+}
+
+func (folder *Folder) encode(content io.Reader, writers []io.Writer) error {
+	for index, writer := range writers {
+		_, err := writer.Write(folder.shardHeader(index))
+		if err != nil {
+			return err
+		}
+	}
+	dataShards := folder.codec.dataShards
+	stripe := make([]byte, dataShards*folder.blockSize)
+	buffers := make([][]byte, folder.codec.totalShards)
+	for i := range buffers {
+		buffers[i] = make([]byte, stripeHeaderSize+folder.blockSize)
+	}
+	blocks := make([][]byte, len(buffers))
+	for {
+		dataLen, err := io.ReadFull(content, stripe)
+		last := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !last {
+			return err
+		}
+		blockLen := folder.blockLength(dataLen)
+		for index := range blocks {
+			blocks[index] = buffers[index][stripeHeaderSize : stripeHeaderSize+blockLen]
+			if index < dataShards {
+				fillBlock(blocks[index], stripe[:dataLen], index*blockLen)
+			}
+		}
+		folder.codec.encode(blocks)
+		for index, writer := range writers {
+			binary.BigEndian.PutUint32(buffers[index], uint32(dataLen))
+			binary.BigEndian.PutUint32(buffers[index][4:], crc32.ChecksumIEEE(blocks[index]))
+			_, err = writer.Write(buffers[index][:stripeHeaderSize+blockLen])
+			if err != nil {
+				return err
+			}
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// fillBlock copies the part of the stripe data to the block, padding it with zeros
+func fillBlock(block []byte, data []byte, offset int) {
+	n := 0
+	if offset < len(data) {
+		n = copy(block, data[offset:])
+	}
+	for i := n; i < len(block); i++ {
+		block[i] = 0
+	}
+}
+
+// blockLength is the length of the blocks of the stripe with dataLen bytes of the object,
+// the blocks of the last stripe are shortened, so the small objects do not take the whole blocks
+func (folder *Folder) blockLength(dataLen int) int {
+	return (dataLen + folder.codec.dataShards - 1) / folder.codec.dataShards
+}
+
+func (folder *Folder) shardHeader(index int) []byte {
+	header := bytes.NewBufferString(shardMagic)
+	header.Write([]byte{byte(folder.codec.dataShards), byte(folder.codec.totalShards - 1), byte(index)})
+	_ = binary.Write(header, binary.BigEndian, uint32(folder.blockSize))
+	return header.Bytes()
+}
+
+func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
+	for index, shard := range folder.shards {
+		err := shard.CopyObject(srcPath, dstPath)
+		if err != nil {
+			return fmt.Errorf("copy erasure coding shard %d: %w", index, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package erasure
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+func newTestFolder(t *testing.T, dataShards, totalShards, blockSize int) (*Folder, []storage.Folder) {
+	shards := make([]storage.Folder, totalShards)
+	for i := range shards {
+		shards[i] = memory.NewFolder("shard/", memory.NewKVS())
+	}
+	folder, err := NewFolder(dataShards, shards)
+	require.NoError(t, err)
+	folder.blockSize = blockSize
+	return folder, shards
+}
+
+func readAll(t *testing.T, folder storage.Folder, name string) []byte {
+	reader, err := folder.ReadObject(name)
+	require.NoError(t, err)
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return content
+}
+
+func TestErasureFolder(t *testing.T) {
+	folder, _ := newTestFolder(t, 2, 3, DefaultBlockSize)
+	storage.RunFolderTest(folder, t)
+}
+
+func TestErasureFolderSurvivesLostShards(t *testing.T) {
+	// the sizes cover the empty object, the short last stripe and the exact multiple of the stripe
+	for _, size := range []int{0, 1, 100, 3 * 16, 3*16*5 + 7} {
+		folder, shards := newTestFolder(t, 3, 5, 16)
+		content := make([]byte, size)
+		rand.New(rand.NewSource(int64(size))).Read(content)
+		require.NoError(t, folder.PutObject("dir/object", bytes.NewReader(content)))
+
+		assert.Equal(t, content, readAll(t, folder, "dir/object"))
+
+		require.NoError(t, shards[0].DeleteObjects([]string{"dir/object"}))
+		require.NoError(t, shards[3].DeleteObjects([]string{"dir/object"}))
+		assert.Equal(t, content, readAll(t, folder, "dir/object"), "size %d", size)
+
+		exists, err := folder.Exists("dir/object")
+		require.NoError(t, err)
+		assert.True(t, exists)
+
+		require.NoError(t, shards[4].DeleteObjects([]string{"dir/object"}))
+		_, err = folder.ReadObject("dir/object")
+		assert.Error(t, err)
+		exists, err = folder.Exists("dir/object")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	}
+}
+
+func TestErasureFolderSkipsCorruptedShard(t *testing.T) {
+	folder, shards := newTestFolder(t, 2, 3, 8)
+	content := []byte(strings.Repeat("erasure coded content ", 10))
+	require.NoError(t, folder.PutObject("object", bytes.NewReader(content)))
+
+	// corrupt a block in the middle of the first data shard, so it fails after some stripes have been read
+	reader, err := shards[0].ReadObject("object")
+	require.NoError(t, err)
+	shard, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	shard[shardHeaderSize+3*(stripeHeaderSize+8)+stripeHeaderSize] ^= 0xff
+	require.NoError(t, shards[0].PutObject("object", bytes.NewReader(shard)))
+
+	assert.Equal(t, content, readAll(t, folder, "object"))
+}
+
+func TestErasureFolderMissingObject(t *testing.T) {
+	folder, _ := newTestFolder(t, 2, 3, 8)
+	_, err := folder.ReadObject("missing")
+	assert.IsType(t, storage.ObjectNotFoundError{}, err)
+}
+
+func TestErasureFolderListsReadableObjects(t *testing.T) {
+	folder, shards := newTestFolder(t, 2, 3, 8)
+	require.NoError(t, folder.PutObject("a/object", strings.NewReader("content")))
+	require.NoError(t, folder.PutObject("b", strings.NewReader("content")))
+	require.NoError(t, shards[0].DeleteObjects([]string{"b"}))
+	require.NoError(t, shards[1].DeleteObjects([]string{"b"}))
+
+	objects, subFolders, err := folder.ListFolder()
+	require.NoError(t, err)
+	assert.Empty(t, objects)
+	require.Len(t, subFolders, 1)
+	assert.Equal(t, "shard/a/", subFolders[0].GetPath())
+	assert.Equal(t, []byte("content"), readAll(t, subFolders[0], "object"))
+}
@@ -0,0 +1,202 @@
+package erasure
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/wal-g/tracelog"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"github.com/wal-g/wal-g/utility"
+)
+
+type shardState int
+
+const (
+	shardUntried shardState = iota
+	shardOpen
+	shardFailed
+	shardMissing
+)
+
+// decodingReader reads the object from the shards in the order of their indexes, so the data shards are read
+// while they are healthy and the parity shards are opened only to replace the failed ones
+type decodingReader struct {
+	folder *Folder
+	name   string
+	states []shardState
+	shards []io.ReadCloser
+	// stripe is the number of the stripes read so far
+	stripe int64
+	buffer []byte
+	last   bool
+}
+
+func (reader *decodingReader) Read(p []byte) (int, error) {
+	for len(reader.buffer) == 0 {
+		if reader.last {
+			return 0, io.EOF
+		}
+		err := reader.readStripe()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, reader.buffer)
+	reader.buffer = reader.buffer[n:]
+	return n, nil
+}
+
+func (reader *decodingReader) Close() error {
+	for index, shard := range reader.shards {
+		if shard != nil {
+			utility.LoggedClose(shard, "")
+			reader.shards[index] = nil
+		}
+	}
+	return nil
+}
+
+func (reader *decodingReader) readStripe() error {
+	codec := reader.folder.codec
+	blocks := make([][]byte, codec.totalShards)
+	collected := 0
+	dataLen := -1
+	for index := 0; index < codec.totalShards && collected < codec.dataShards; index++ {
+		if reader.states[index] == shardUntried {
+			reader.openShard(index)
+		}
+		if reader.states[index] != shardOpen {
+			continue
+		}
+		block, blockDataLen, err := reader.readBlock(index)
+		if err == nil && dataLen >= 0 && blockDataLen != dataLen {
+			err = fmt.Errorf("stripe has %d bytes of data, other shards have %d", blockDataLen, dataLen)
+		}
+		if err != nil {
+			tracelog.WarningLogger.Printf("Erasure coding shard %d of %s is broken: %v", index, reader.name, err)
+			reader.failShard(index)
+			continue
+		}
+		dataLen = blockDataLen
+		blocks[index] = block
+		collected++
+	}
+	if collected < codec.dataShards {
+		return reader.notEnoughShardsError(collected)
+	}
+
+	err := codec.reconstructData(blocks)
+	if err != nil {
+		return err
+	}
+	reader.buffer = make([]byte, 0, dataLen)
+	for _, block := range blocks[:codec.dataShards] {
+		reader.buffer = append(reader.buffer, block...)
+	}
+	reader.buffer = reader.buffer[:dataLen]
+	reader.last = dataLen < codec.dataShards*reader.folder.blockSize
+	reader.stripe++
+	return nil
+}
+
+func (reader *decodingReader) notEnoughShardsError(collected int) error {
+	missing := 0
+	for _, state := range reader.states {
+		if state == shardMissing {
+			missing++
+		}
+	}
+	if missing == len(reader.states) {
+		return storage.NewObjectNotFoundError(reader.name)
+	}
+	return fmt.Errorf("only %d of %d erasure coding shards of %s are readable, %d are required",
+		collected, reader.folder.codec.totalShards, reader.name, reader.folder.codec.dataShards)
+}
+
+// openShard opens the shard and skips the stripes that have been read from the other shards
+func (reader *decodingReader) openShard(index int) {
+	shard, err := reader.folder.shards[index].ReadObject(reader.name)
+	if _, ok := errors.Cause(err).(storage.ObjectNotFoundError); ok {
+		reader.states[index] = shardMissing
+		return
+	}
+	if err != nil {
+		tracelog.WarningLogger.Printf("Failed to open erasure coding shard %d of %s: %v", index, reader.name, err)
+		reader.states[index] = shardFailed
+		return
+	}
+	reader.shards[index] = shard
+	reader.states[index] = shardOpen
+
+	err = reader.readHeader(index)
+	if err == nil {
+		// the stripes before the current one are full, since only the last stripe is shorter
+		skip := reader.stripe * int64(stripeHeaderSize+reader.folder.blockSize)
+		_, err = io.CopyN(io.Discard, shard, skip)
+	}
+	if err != nil {
+		tracelog.WarningLogger.Printf("Erasure coding shard %d of %s is broken: %v", index, reader.name, err)
+		reader.failShard(index)
+	}
+}
+
+func (reader *decodingReader) readHeader(index int) error {
+	header := make([]byte, shardHeaderSize)
+	_, err := io.ReadFull(reader.shards[index], header)
+	if err != nil {
+		return err
+	}
+	if string(header[:len(shardMagic)]) != shardMagic {
+		return errors.New("the object is not an erasure coding shard")
+	}
+	fields := header[len(shardMagic):]
+	dataShards, totalShards, shardIndex := int(fields[0]), int(fields[1])+1, int(fields[2])
+	blockSize := int(binary.BigEndian.Uint32(fields[3:]))
+	codec := reader.folder.codec
+	if dataShards != codec.dataShards || totalShards != codec.totalShards || blockSize != reader.folder.blockSize {
+		return fmt.Errorf("the shard is encoded with %d of %d shards and %d bytes blocks, expected %d of %d and %d",
+			dataShards, totalShards, blockSize, codec.dataShards, codec.totalShards, reader.folder.blockSize)
+	}
+	if shardIndex != index {
+		return fmt.Errorf("the shard has index %d, the shard storages must be configured in the same order", shardIndex)
+	}
+	return nil
+}
+
+func (reader *decodingReader) readBlock(index int) ([]byte, int, error) {
+	header := make([]byte, stripeHeaderSize)
+	_, err := io.ReadFull(reader.shards[index], header)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	dataLen := int(binary.BigEndian.Uint32(header))
+	if dataLen > reader.folder.codec.dataShards*reader.folder.blockSize {
+		return nil, 0, fmt.Errorf("stripe data length %d is too large", dataLen)
+	}
+	block := make([]byte, reader.folder.blockLength(dataLen))
+	_, err = io.ReadFull(reader.shards[index], block)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if crc32.ChecksumIEEE(block) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, 0, errors.New("block checksum mismatch")
+	}
+	return block, dataLen, nil
+}
+
+func (reader *decodingReader) failShard(index int) {
+	if reader.shards[index] != nil {
+		utility.LoggedClose(reader.shards[index], "")
+		reader.shards[index] = nil
+	}
+	reader.states[index] = shardFailed
+}
@@ -0,0 +1,55 @@
+package erasure
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+var _ storage.HashableStorage = &Storage{}
+
+type Storage struct {
+	storages   []storage.HashableStorage
+	rootFolder *Folder
+	hash       string
+}
+
+// NewStorage creates the storage keeping dataShards of the shards of every object in the storages
+// and the rest as the parity
+func NewStorage(dataShards int, storages []storage.HashableStorage) (*Storage, error) {
+	rootFolders := make([]storage.Folder, len(storages))
+	hashes := make([]string, len(storages))
+	for i, st := range storages {
+		rootFolders[i] = st.RootFolder()
+		hashes[i] = st.ConfigHash()
+	}
+	rootFolder, err := NewFolder(dataShards, rootFolders)
+	if err != nil {
+		return nil, err
+	}
+	return &Storage{
+		storages:   storages,
+		rootFolder: rootFolder,
+		hash:       fmt.Sprintf("erasure:%d:%s", dataShards, strings.Join(hashes, ",")),
+	}, nil
+}
+
+func (s *Storage) RootFolder() storage.Folder {
+	return s.rootFolder
+}
+
+func (s *Storage) Close() error {
+	var firstErr error
+	for _, st := range s.storages {
+		err := st.Close()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (s *Storage) ConfigHash() string {
+	return s.hash
+}
@@ -0,0 +1,198 @@
+// Package faulty provides a storage.Folder wrapper that injects latencies, transient errors and partial reads/writes
+// into any other folder. It's meant for testing how tools built on top of the storages behave in bad conditions.
+package faulty
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+// ErrInjected is returned from operations that have been failed on purpose.
+var ErrInjected = errors.New("faulty storage: injected failure")
+
+// Config describes which failures are injected. All the rates are probabilities in range [0, 1] that are applied to
+// each operation independently, so the injected errors are transient: retrying the operation may succeed.
+type Config struct {
+	// Latency is added to every operation.
+	Latency time.Duration
+	// LatencyJitter is the max random delay added to every operation in addition to Latency.
+	LatencyJitter time.Duration
+	// ErrorRate is the probability of an operation to fail with ErrInjected without affecting the storage.
+	ErrorRate float64
+	// PartialReadRate is the probability of ReadObject to provide a reader that fails with ErrInjected after
+	// returning a random part of the object.
+	PartialReadRate float64
+	// PartialWriteRate is the probability of PutObject to store a random part of the content and then fail with
+	// ErrInjected.
+	PartialWriteRate float64
+	// Seed makes the sequence of injected failures reproducible.
+	Seed int64
+}
+
+var _ storage.Folder = &Folder{}
+
+type Folder struct {
+	underlying storage.Folder
+	config     Config
+	random     *lockedRand
+}
+
+func NewFolder(underlying storage.Folder, config Config) *Folder {
+	return &Folder{
+		underlying: underlying,
+		config:     config,
+		random:     &lockedRand{rand: rand.New(rand.NewSource(config.Seed))},
+	}
+}
+
+// WrapRootFolder can be passed to storage constructors to make their root folders faulty.
+func WrapRootFolder(config Config) storage.WrapRootFolder {
+	return func(prevFolder storage.Folder) storage.Folder {
+		return NewFolder(prevFolder, config)
+	}
+}
+
+func (folder *Folder) GetPath() string {
+	return folder.underlying.GetPath()
+}
+
+func (folder *Folder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
+	if err = folder.inject(); err != nil {
+		return nil, nil, err
+	}
+	objects, subFolders, err = folder.underlying.ListFolder()
+	for i := range subFolders {
+		subFolders[i] = folder.wrap(subFolders[i])
+	}
+	return objects, subFolders, err
+}
+
+func (folder *Folder) DeleteObjects(objectRelativePaths []string) error {
+	if err := folder.inject(); err != nil {
+		return err
+	}
+	return folder.underlying.DeleteObjects(objectRelativePaths)
+}
+
+func (folder *Folder) Exists(objectRelativePath string) (bool, error) {
+	if err := folder.inject(); err != nil {
+		return false, err
+	}
+	return folder.underlying.Exists(objectRelativePath)
+}
+
+func (folder *Folder) GetSubFolder(subFolderRelativePath string) storage.Folder {
+	return folder.wrap(folder.underlying.GetSubFolder(subFolderRelativePath))
+}
+
+func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, error) {
+	if err := folder.inject(); err != nil {
+		return nil, err
+	}
+	readCloser, err := folder.underlying.ReadObject(objectRelativePath)
+	if err != nil || !folder.random.chance(folder.config.PartialReadRate) {
+		return readCloser, err
+	}
+
+	defer readCloser.Close()
+	content, err := io.ReadAll(readCloser)
+	if err != nil {
+		return nil, err
+	}
+	cut := folder.random.intn(len(content) + 1)
+	return io.NopCloser(io.MultiReader(bytes.NewReader(content[:cut]), errReader{})), nil
+}
+
+func (folder *Folder) PutObject(name string, content io.Reader) error {
+	return folder.PutObjectWithContext(context.Background(), name, content)
+}
+
+func (folder *Folder) PutObjectWithContext(ctx context.Context, name string, content io.Reader) error {
+	if err := folder.inject(); err != nil {
+		return err
+	}
+	if !folder.random.chance(folder.config.PartialWriteRate) {
+		return folder.underlying.PutObjectWithContext(ctx, name, content)
+	}
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	cut := folder.random.intn(len(data) + 1)
+	err = folder.underlying.PutObjectWithContext(ctx, name, bytes.NewReader(data[:cut]))
+	if err != nil {
+		return err
+	}
+	return ErrInjected
+}
+
+func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
+	if err := folder.inject(); err != nil {
+		return err
+	}
+	return folder.underlying.CopyObject(srcPath, dstPath)
+}
+
+func (folder *Folder) wrap(subFolder storage.Folder) *Folder {
+	return &Folder{
+		underlying: subFolder,
+		config:     folder.config,
+		random:     folder.random,
+	}
+}
+
+// inject sleeps for the configured latency and decides whether the operation should fail.
+func (folder *Folder) inject() error {
+	delay := folder.config.Latency
+	if folder.config.LatencyJitter > 0 {
+		delay += time.Duration(folder.random.int63n(int64(folder.config.LatencyJitter)))
+	}
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	if folder.random.chance(folder.config.ErrorRate) {
+		return ErrInjected
+	}
+	return nil
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, ErrInjected
+}
+
+// lockedRand is shared by a folder and all its subfolders, so it must be safe for concurrent use.
+type lockedRand struct {
+	rand *rand.Rand
+	mu   sync.Mutex
+}
+
+func (r *lockedRand) chance(probability float64) bool {
+	if probability <= 0 {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rand.Float64() < probability
+}
+
+func (r *lockedRand) intn(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rand.Intn(n)
+}
+
+func (r *lockedRand) int63n(n int64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rand.Int63n(n)
+}
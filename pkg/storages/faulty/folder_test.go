@@ -0,0 +1,100 @@
+package faulty_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/pkg/storages/faulty"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+)
+
+func TestFolder_PassesThroughWithoutFaults(t *testing.T) {
+	memFolder := memory.NewFolder("test/", memory.NewKVS())
+	folder := faulty.NewFolder(memFolder, faulty.Config{})
+
+	require.NoError(t, folder.GetSubFolder("a").PutObject("b", bytes.NewBufferString("content")))
+
+	reader, err := folder.ReadObject("a/b")
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(content))
+
+	_, subFolders, err := folder.ListFolder()
+	require.NoError(t, err)
+	require.Len(t, subFolders, 1)
+	assert.IsType(t, &faulty.Folder{}, subFolders[0])
+}
+
+func TestFolder_InjectsErrors(t *testing.T) {
+	memFolder := memory.NewFolder("test/", memory.NewKVS())
+	folder := faulty.NewFolder(memFolder, faulty.Config{ErrorRate: 1})
+
+	err := folder.PutObject("a", bytes.NewBufferString("content"))
+	assert.ErrorIs(t, err, faulty.ErrInjected)
+	exists, err := memFolder.Exists("a")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	_, err = folder.GetSubFolder("sub").Exists("a")
+	assert.ErrorIs(t, err, faulty.ErrInjected)
+}
+
+func TestFolder_ErrorsAreTransient(t *testing.T) {
+	folder := faulty.NewFolder(memory.NewFolder("test/", memory.NewKVS()), faulty.Config{ErrorRate: 0.5, Seed: 42})
+
+	failed, succeeded := 0, 0
+	for i := 0; i < 100; i++ {
+		_, err := folder.Exists("a")
+		if errors.Is(err, faulty.ErrInjected) {
+			failed++
+		} else {
+			succeeded++
+		}
+	}
+	assert.Greater(t, failed, 0)
+	assert.Greater(t, succeeded, 0)
+}
+
+func TestFolder_PartialReadsAndWrites(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 1000)
+
+	t.Run("partial write", func(t *testing.T) {
+		memFolder := memory.NewFolder("test/", memory.NewKVS())
+		folder := faulty.NewFolder(memFolder, faulty.Config{PartialWriteRate: 1, Seed: 1})
+
+		err := folder.PutObject("a", bytes.NewReader(content))
+		assert.ErrorIs(t, err, faulty.ErrInjected)
+
+		reader, err := memFolder.ReadObject("a")
+		require.NoError(t, err)
+		stored, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Less(t, len(stored), len(content))
+	})
+
+	t.Run("partial read", func(t *testing.T) {
+		memFolder := memory.NewFolder("test/", memory.NewKVS())
+		require.NoError(t, memFolder.PutObject("a", bytes.NewReader(content)))
+		folder := faulty.NewFolder(memFolder, faulty.Config{PartialReadRate: 1, Seed: 1})
+
+		reader, err := folder.ReadObject("a")
+		require.NoError(t, err)
+		_, err = io.ReadAll(reader)
+		assert.ErrorIs(t, err, faulty.ErrInjected)
+	})
+}
+
+func TestFolder_InjectsLatency(t *testing.T) {
+	folder := faulty.NewFolder(memory.NewFolder("test/", memory.NewKVS()), faulty.Config{Latency: 20 * time.Millisecond})
+
+	start := time.Now()
+	_, err := folder.Exists("a")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
@@ -126,8 +126,11 @@ func (folder *Folder) PutObjectWithContext(ctx context.Context, name string, con
 }
 
 func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
-	src := path.Join(folder.rootPath, srcPath)
-	srcStat, err := os.Stat(src)
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath, storage.CopyOptions{})
+}
+
+func (folder *Folder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string, options storage.CopyOptions) error {
+	srcStat, err := os.Stat(folder.GetFilePath(srcPath))
 	if errors.Is(err, os.ErrNotExist) {
 		return storage.NewObjectNotFoundError(srcPath)
 	}
@@ -137,11 +140,7 @@ func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
 	if !srcStat.Mode().IsRegular() {
 		return fmt.Errorf("unable to copy file: %s is not a regular file", srcPath)
 	}
-	file, err := os.Open(src)
-	if err != nil {
-		return fmt.Errorf("unable to open file to copy %q: %w", srcPath, err)
-	}
-	err = folder.PutObject(dstPath, file)
+	err = storage.StreamCopyObject(ctx, folder, srcPath, dstPath, options)
 	if err != nil {
 		return fmt.Errorf("unable to copy: %w", err)
 	}
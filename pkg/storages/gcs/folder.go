@@ -2,21 +2,34 @@ package gcs
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"os"
 	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/wal-g/tracelog"
 
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 
 	gcs "cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 )
 
-const composeChunkLimit = 32
+const (
+	composeChunkLimit = 32
+
+	credentialsEnvVariable = "GOOGLE_APPLICATION_CREDENTIALS"
+)
+
+var _ storage.PresignableFolder = &Folder{}
+var _ storage.ConditionalFolder = &Folder{}
 
 func NewFolder(bucket *gcs.BucketHandle, path string, encryptionKey []byte, config *Config) *Folder {
 	// Trim leading slash because there's no difference between absolute and relative paths in GCS.
@@ -146,6 +159,103 @@ func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, erro
 	return io.NopCloser(reader), err
 }
 
+// ReadObjectVersion reads the object along with its generation
+func (folder *Folder) ReadObjectVersion(objectRelativePath string) (io.ReadCloser, string, error) {
+	objPath := folder.joinPath(folder.path, objectRelativePath)
+	object := folder.BuildObjectHandle(objPath)
+	reader, err := object.NewReader(context.Background())
+	if err == gcs.ErrObjectNotExist {
+		return nil, "", storage.NewObjectNotFoundError(objPath)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("read GCS object %q: %w", objPath, err)
+	}
+	return reader, strconv.FormatInt(reader.Attrs.Generation, 10), nil
+}
+
+// PutObjectIfVersion uploads the object with the generation precondition. Conditional writes are intended
+// for small metadata objects, so the object is uploaded at once without splitting it into chunks.
+func (folder *Folder) PutObjectIfVersion(ctx context.Context, name string, content io.Reader, version string) error {
+	objectPath := folder.joinPath(folder.path, name)
+	conditions := gcs.Conditions{DoesNotExist: true}
+	if version != "" {
+		generation, err := strconv.ParseInt(version, 10, 64)
+		if err != nil {
+			return fmt.Errorf("parse GCS object generation %q: %w", version, err)
+		}
+		conditions = gcs.Conditions{GenerationMatch: generation}
+	}
+
+	ctx, cancel := folder.createTimeoutContext(ctx)
+	defer cancel()
+
+	writer := folder.BuildObjectHandle(objectPath).If(conditions).NewWriter(ctx)
+	_, err := io.Copy(writer, content)
+	if err != nil {
+		_ = writer.Close()
+		return fmt.Errorf("upload object %q to GCS: %w", objectPath, err)
+	}
+	err = writer.Close()
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+		return storage.NewPreconditionFailedError(objectPath, version)
+	}
+	if err != nil {
+		return fmt.Errorf("upload object %q to GCS: %w", objectPath, err)
+	}
+	return nil
+}
+
+// PresignObject signs the URL with the service account key from GOOGLE_APPLICATION_CREDENTIALS,
+// other kinds of credentials can't be used to sign URLs locally.
+func (folder *Folder) PresignObject(objectRelativePath string, ttl time.Duration) (string, error) {
+	if len(folder.encryptionKey) != 0 {
+		return "", fmt.Errorf("objects encrypted with a customer supplied key can't be downloaded by a signed URL")
+	}
+	account, err := loadServiceAccountKey()
+	if err != nil {
+		return "", err
+	}
+
+	objPath := folder.joinPath(folder.path, objectRelativePath)
+	url, err := gcs.SignedURL(folder.config.Bucket, objPath, &gcs.SignedURLOptions{
+		GoogleAccessID: account.ClientEmail,
+		PrivateKey:     []byte(account.PrivateKey),
+		Method:         http.MethodGet,
+		Expires:        time.Now().Add(ttl),
+		Scheme:         gcs.SigningSchemeV4,
+	})
+	if err != nil {
+		return "", fmt.Errorf("sign GCS object URL %q: %w", objPath, err)
+	}
+	return url, nil
+}
+
+type serviceAccountKey struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+}
+
+func loadServiceAccountKey() (*serviceAccountKey, error) {
+	keyPath, ok := os.LookupEnv(credentialsEnvVariable)
+	if !ok {
+		return nil, fmt.Errorf("%s must point to a service account key to sign URLs", credentialsEnvVariable)
+	}
+	keyJSON, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("read service account key: %w", err)
+	}
+	account := &serviceAccountKey{}
+	err = json.Unmarshal(keyJSON, account)
+	if err != nil {
+		return nil, fmt.Errorf("parse service account key: %w", err)
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, fmt.Errorf("%q is not a service account key", keyPath)
+	}
+	return account, nil
+}
+
 func (folder *Folder) PutObject(name string, content io.Reader) error {
 	ctx, cancel := folder.createTimeoutContext(context.Background())
 	defer cancel()
@@ -226,6 +336,11 @@ func (folder *Folder) PutObjectWithContext(ctx context.Context, name string, con
 }
 
 func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath, storage.CopyOptions{})
+}
+
+// CopyObjectWithContext copies the object server-side, the content isn't transferred through wal-g
+func (folder *Folder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string, options storage.CopyOptions) error {
 	if exists, err := folder.Exists(srcPath); !exists {
 		if err == nil {
 			return storage.NewObjectNotFoundError(srcPath)
@@ -235,8 +350,13 @@ func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
 	source := path.Join(folder.path, srcPath)
 	dst := path.Join(folder.path, dstPath)
 
-	ctx := context.Background()
-	_, err := folder.bucket.Object(dst).CopierFrom(folder.bucket.Object(source)).Run(ctx)
+	copier := folder.bucket.Object(dst).CopierFrom(folder.bucket.Object(source))
+	if options.Progress != nil {
+		copier.ProgressFunc = func(copiedBytes, _ uint64) {
+			options.Progress(int64(copiedBytes))
+		}
+	}
+	_, err := copier.Run(ctx)
 	if err != nil {
 		return fmt.Errorf("copy GCS object %q to %q: %w", srcPath, dstPath, err)
 	}
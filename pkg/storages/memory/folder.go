@@ -1,8 +1,12 @@
+// Package memory provides a storage that keeps all objects in memory. Together with the faulty package it allows
+// testing tools built on top of the storages without any real storage available.
 package memory
 
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"fmt"
 	"io"
 	"path"
 	"path/filepath"
@@ -14,6 +18,8 @@ import (
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 )
 
+var _ storage.ConditionalFolder = &Folder{}
+
 // TODO: Unit tests
 type Folder struct {
 	path string
@@ -57,6 +63,17 @@ func (folder *Folder) ListFolder() (objects []storage.Object, subFolders []stora
 	return
 }
 
+func (folder *Folder) ListFolderWithPrefix(prefix string) (objects []storage.Object, err error) {
+	folder.KVS.Range(func(key string, value TimeStampedData) bool {
+		name := strings.TrimPrefix(key, folder.path)
+		if strings.HasPrefix(key, folder.path+prefix) && !strings.Contains(name, "/") {
+			objects = append(objects, storage.NewLocalObject(name, value.Timestamp, int64(value.Size)))
+		}
+		return true
+	})
+	return objects, nil
+}
+
 func (folder *Folder) DeleteObjects(objectRelativePaths []string) error {
 	for _, objectName := range objectRelativePaths {
 		folder.KVS.Delete(storage.JoinPath(folder.path, objectName))
@@ -92,20 +109,48 @@ func (folder *Folder) PutObjectWithContext(ctx context.Context, name string, con
 	return folder.PutObject(name, ctxReader)
 }
 
+func (folder *Folder) ReadObjectVersion(objectRelativePath string) (io.ReadCloser, string, error) {
+	objectAbsPath := path.Join(folder.path, objectRelativePath)
+	object, exists := folder.KVS.Load(objectAbsPath)
+	if !exists {
+		return nil, "", storage.NewObjectNotFoundError(objectAbsPath)
+	}
+	return io.NopCloser(&object.Data), objectVersion(object), nil
+}
+
+func (folder *Folder) PutObjectIfVersion(ctx context.Context, name string, content io.Reader, version string) error {
+	data, err := io.ReadAll(contextio.NewReader(ctx, content))
+	objectPath := path.Join(folder.path, name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to put '%s' in memory storage", objectPath)
+	}
+	stored := folder.KVS.StoreIf(objectPath, *bytes.NewBuffer(data), func(current TimeStampedData, exists bool) bool {
+		if !exists {
+			return version == ""
+		}
+		return objectVersion(current) == version
+	})
+	if !stored {
+		return storage.NewPreconditionFailedError(objectPath, version)
+	}
+	return nil
+}
+
+func objectVersion(object TimeStampedData) string {
+	checksum := sha256.Sum256(object.Data.Bytes())
+	return fmt.Sprintf("%x-%d", checksum, object.Timestamp.UnixNano())
+}
+
 func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath, storage.CopyOptions{})
+}
+
+func (folder *Folder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string, options storage.CopyOptions) error {
 	if exists, err := folder.Exists(srcPath); !exists {
 		if err == nil {
 			return storage.NewObjectNotFoundError(srcPath)
 		}
 		return err
 	}
-	file, err := folder.ReadObject(srcPath)
-	if err != nil {
-		return err
-	}
-	err = folder.PutObject(dstPath, file)
-	if err != nil {
-		return err
-	}
-	return nil
+	return storage.StreamCopyObject(ctx, folder, srcPath, dstPath, options)
 }
@@ -1,11 +1,38 @@
 package memory
 
 import (
+	"context"
+	"io"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 )
 
 func TestMemoryFolder(t *testing.T) {
 	storage.RunFolderTest(NewFolder("in_memory/", NewKVS()), t)
 }
+
+func TestMemoryFolder_PutObjectIfVersion(t *testing.T) {
+	folder := NewFolder("in_memory/", NewKVS())
+	ctx := context.Background()
+
+	require.NoError(t, folder.PutObjectIfVersion(ctx, "sentinel.json", strings.NewReader("v1"), ""))
+	err := folder.PutObjectIfVersion(ctx, "sentinel.json", strings.NewReader("v1"), "")
+	assert.IsType(t, storage.PreconditionFailedError{}, err)
+
+	reader, version, err := folder.ReadObjectVersion("sentinel.json")
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+
+	require.NoError(t, folder.PutObjectIfVersion(ctx, "sentinel.json", strings.NewReader("v2"), version))
+	err = folder.PutObjectIfVersion(ctx, "sentinel.json", strings.NewReader("v3"), version)
+	assert.IsType(t, storage.PreconditionFailedError{}, err)
+
+	_, _, err = folder.ReadObjectVersion("missing.json")
+	assert.IsType(t, storage.ObjectNotFoundError{}, err)
+}
@@ -30,6 +30,7 @@ func TimeStampData(data bytes.Buffer, timeNow func() time.Time) TimeStampedData
 type KVS struct {
 	underlying *sync.Map
 	timeNow    func() time.Time
+	storeMutex sync.Mutex
 }
 
 func NewKVS(opts ...func(*KVS)) *KVS {
@@ -58,6 +59,19 @@ func (storage *KVS) Store(key string, value bytes.Buffer) {
 	storage.underlying.Store(key, TimeStampData(value, storage.timeNow))
 }
 
+// StoreIf stores the value only if the condition holds for the current value of the key.
+// It is atomic only with respect to other StoreIf calls.
+func (storage *KVS) StoreIf(key string, value bytes.Buffer, condition func(current TimeStampedData, exists bool) bool) bool {
+	storage.storeMutex.Lock()
+	defer storage.storeMutex.Unlock()
+	current, exists := storage.Load(key)
+	if !condition(current, exists) {
+		return false
+	}
+	storage.Store(key, value)
+	return true
+}
+
 func (storage *KVS) Delete(key string) {
 	storage.underlying.Delete(key)
 }
@@ -2,8 +2,12 @@ package s3
 
 import (
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go/service/s3"
+
 	"github.com/wal-g/wal-g/pkg/storages/storage"
 	"github.com/wal-g/wal-g/pkg/storages/storage/setting"
 )
@@ -12,6 +16,12 @@ import (
 
 const (
 	endpointSetting                 = "AWS_ENDPOINT"
+	uploadEndpointSetting           = "S3_UPLOAD_ENDPOINT"
+	downloadEndpointSetting         = "S3_DOWNLOAD_ENDPOINT"
+	uploadAccessKeyIDSetting        = "S3_UPLOAD_ACCESS_KEY_ID"
+	uploadSecretAccessKeySetting    = "S3_UPLOAD_SECRET_ACCESS_KEY"
+	downloadAccessKeyIDSetting      = "S3_DOWNLOAD_ACCESS_KEY_ID"
+	downloadSecretAccessKeySetting  = "S3_DOWNLOAD_SECRET_ACCESS_KEY"
 	regionSetting                   = "AWS_REGION"
 	forcePathStyleSetting           = "AWS_S3_FORCE_PATH_STYLE"
 	accessKeyIDSetting              = "AWS_ACCESS_KEY_ID"
@@ -36,6 +46,9 @@ const (
 	rangeBatchEnabledSetting        = "S3_RANGE_BATCH_ENABLED"
 	rangeQueriesMaxRetriesSetting   = "S3_RANGE_MAX_RETRIES"
 	requestAdditionalHeadersSetting = "S3_REQUEST_ADDITIONAL_HEADERS"
+	requestPayerSetting             = "S3_REQUEST_PAYER"
+	costAllocationTagsSetting       = "S3_COST_ALLOCATION_TAGS"
+	conditionalWritesSetting        = "S3_CONDITIONAL_WRITES"
 	// limiters for retry policy during interaction with S3
 	maxRetriesSetting              = "S3_MAX_RETRIES"
 	minThrottlingRetryDelaySetting = "S3_MIN_THROTTLING_RETRY_DELAY"
@@ -45,6 +58,12 @@ const (
 var SettingList = []string{
 	endpointPortSetting,
 	endpointSetting,
+	uploadEndpointSetting,
+	downloadEndpointSetting,
+	uploadAccessKeyIDSetting,
+	uploadSecretAccessKeySetting,
+	downloadAccessKeyIDSetting,
+	downloadSecretAccessKeySetting,
 	endpointSourceSetting,
 	regionSetting,
 	forcePathStyleSetting,
@@ -69,6 +88,9 @@ var SettingList = []string{
 	rangeQueriesMaxRetriesSetting,
 	maxRetriesSetting,
 	requestAdditionalHeadersSetting,
+	requestPayerSetting,
+	costAllocationTagsSetting,
+	conditionalWritesSetting,
 	minThrottlingRetryDelaySetting,
 	maxThrottlingRetryDelaySetting,
 }
@@ -84,6 +106,7 @@ const (
 	defaultStorageClass            = "STANDARD"
 	defaultRangeBatchEnabled       = false
 	defaultRangeMaxRetries         = 10
+	defaultConditionalWrites       = false
 )
 
 // TODO: Unit tests
@@ -141,13 +164,40 @@ func ConfigureStorage(
 	if err != nil {
 		return nil, err
 	}
+	conditionalWrites, err := setting.BoolOptional(settings, conditionalWritesSetting, defaultConditionalWrites)
+	if err != nil {
+		return nil, err
+	}
+	requestPayer := settings[requestPayerSetting]
+	if requestPayer != "" && requestPayer != s3.RequestPayerRequester {
+		return nil, fmt.Errorf("%s must be %q, got %q", requestPayerSetting, s3.RequestPayerRequester, requestPayer)
+	}
+	tagging, err := encodeCostAllocationTags(settings[costAllocationTagsSetting])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", costAllocationTagsSetting, err)
+	}
+
+	for _, keys := range [][2]string{
+		{uploadAccessKeyIDSetting, uploadSecretAccessKeySetting},
+		{downloadAccessKeyIDSetting, downloadSecretAccessKeySetting},
+	} {
+		if (settings[keys[0]] == "") != (settings[keys[1]] == "") {
+			return nil, fmt.Errorf("%s and %s must be set together", keys[0], keys[1])
+		}
+	}
 
 	config := &Config{
 		Secrets: &Secrets{
-			SecretKey: setting.FirstDefined(settings, secretAccessKeySetting, secretKeySetting),
+			SecretKey:         setting.FirstDefined(settings, secretAccessKeySetting, secretKeySetting),
+			UploadSecretKey:   settings[uploadSecretAccessKeySetting],
+			DownloadSecretKey: settings[downloadSecretAccessKeySetting],
 		},
 		Region:                   settings[regionSetting],
 		Endpoint:                 settings[endpointSetting],
+		UploadEndpoint:           settings[uploadEndpointSetting],
+		DownloadEndpoint:         settings[downloadEndpointSetting],
+		UploadAccessKey:          settings[uploadAccessKeyIDSetting],
+		DownloadAccessKey:        settings[downloadAccessKeyIDSetting],
 		EndpointSource:           settings[endpointSourceSetting],
 		EndpointPort:             port,
 		Bucket:                   bucket,
@@ -160,6 +210,7 @@ func ConfigureStorage(
 		UseYCSessionToken:        settings[useYcSessionTokenSetting],
 		ForcePathStyle:           forcePathStyle,
 		RequestAdditionalHeaders: settings[requestAdditionalHeadersSetting],
+		RequestPayer:             requestPayer,
 		UseListObjectsV1:         useListObjectsV1,
 		MaxRetries:               maxRetries,
 		LogLevel:                 settings[logLevelSetting],
@@ -170,9 +221,11 @@ func ConfigureStorage(
 			ServerSideEncryption:         settings[sseSetting],
 			ServerSideEncryptionCustomer: settings[sseCSetting],
 			ServerSideEncryptionKMSID:    settings[sseKmsIDSetting],
+			Tagging:                      tagging,
 		},
 		RangeBatchEnabled:       rangeBatchEnabled,
 		RangeMaxRetries:         rangeMaxRetries,
+		ConditionalWrites:       conditionalWrites,
 		MinThrottlingRetryDelay: time.Duration(minThrottlingRetryDelay) * time.Millisecond,
 		MaxThrottlingRetryDelay: time.Duration(maxThrottlingRetryDelay) * time.Millisecond,
 	}
@@ -183,3 +236,20 @@ func ConfigureStorage(
 	}
 	return st, nil
 }
+
+// encodeCostAllocationTags encodes the comma-separated key=value pairs as the object tagging header value
+func encodeCostAllocationTags(tags string) (string, error) {
+	values := url.Values{}
+	for _, tag := range strings.Split(tags, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(tag, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return "", fmt.Errorf("tag %q is expected to be key=value", tag)
+		}
+		values.Set(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+	return values.Encode(), nil
+}
@@ -0,0 +1,21 @@
+package s3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeCostAllocationTags(t *testing.T) {
+	tagging, err := encodeCostAllocationTags("team=db, cluster=main db ,empty=")
+	require.NoError(t, err)
+	assert.Equal(t, "cluster=main+db&empty=&team=db", tagging)
+
+	tagging, err = encodeCostAllocationTags("")
+	require.NoError(t, err)
+	assert.Empty(t, tagging)
+
+	_, err = encodeCostAllocationTags("team")
+	assert.Error(t, err)
+}
@@ -3,8 +3,10 @@ package s3
 import (
 	"context"
 	"io"
+	"net/http"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -15,21 +17,28 @@ import (
 )
 
 const (
-	NotFoundAWSErrorCode  = "NotFound"
-	NoSuchKeyAWSErrorCode = "NoSuchKey"
+	NotFoundAWSErrorCode     = "NotFound"
+	NoSuchKeyAWSErrorCode    = "NoSuchKey"
+	AccessDeniedAWSErrorCode = "AccessDenied"
 )
 
+var _ storage.PresignableFolder = &Folder{}
+var _ storage.ConditionalFolder = &conditionalFolder{}
+
 // TODO: Unit tests
 type Folder struct {
-	s3API    s3iface.S3API
-	uploader *Uploader
-	bucket   *string
-	path     string
-	config   *Config
+	s3API s3iface.S3API
+	// downloadAPI reads the objects, it may use another endpoint than s3API (S3_DOWNLOAD_ENDPOINT)
+	downloadAPI s3iface.S3API
+	uploader    *Uploader
+	bucket      *string
+	path        string
+	config      *Config
 }
 
 func NewFolder(
 	s3API s3iface.S3API,
+	downloadAPI s3iface.S3API,
 	uploader *Uploader,
 	path string,
 	config *Config,
@@ -37,11 +46,12 @@ func NewFolder(
 	// Trim leading slash because there's no difference between absolute and relative paths in S3.
 	path = strings.TrimPrefix(path, "/")
 	return &Folder{
-		uploader: uploader,
-		s3API:    s3API,
-		bucket:   aws.String(config.Bucket),
-		path:     storage.AddDelimiterToPath(path),
-		config:   config,
+		uploader:    uploader,
+		s3API:       s3API,
+		downloadAPI: downloadAPI,
+		bucket:      aws.String(config.Bucket),
+		path:        storage.AddDelimiterToPath(path),
+		config:      config,
 	}
 }
 
@@ -71,6 +81,11 @@ func (folder *Folder) PutObjectWithContext(ctx context.Context, name string, con
 }
 
 func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath, storage.CopyOptions{})
+}
+
+// CopyObjectWithContext copies the object server-side, the content isn't transferred through wal-g
+func (folder *Folder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string, _ storage.CopyOptions) error {
 	if exists, err := folder.Exists(srcPath); !exists {
 		if err == nil {
 			return storage.NewObjectNotFoundError(srcPath)
@@ -80,7 +95,7 @@ func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
 	source := path.Join(*folder.bucket, folder.path, srcPath)
 	dst := path.Join(folder.path, dstPath)
 	input := &s3.CopyObjectInput{CopySource: &source, Bucket: folder.bucket, Key: &dst}
-	_, err := folder.s3API.CopyObject(input)
+	_, err := folder.s3API.CopyObjectWithContext(ctx, input)
 	return err
 }
 
@@ -91,7 +106,7 @@ func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, erro
 		Key:    aws.String(objectPath),
 	}
 
-	object, err := folder.s3API.GetObject(input)
+	object, err := folder.downloadAPI.GetObject(input)
 	if err != nil {
 		if isAwsNotExist(err) {
 			return nil, storage.NewObjectNotFoundError(objectPath)
@@ -106,14 +121,75 @@ func (folder *Folder) ReadObject(objectRelativePath string) (io.ReadCloser, erro
 	return reader, nil
 }
 
+// conditionalFolder is the folder of the storage which supports the conditional writes, see S3_CONDITIONAL_WRITES.
+// Many S3-compatible storages accept the If-Match and If-None-Match headers but ignore them, so the folders
+// don't claim the conditional writes unless they are enabled, and the metadata lock falls back to reading back.
+type conditionalFolder struct {
+	*Folder
+}
+
+// withConditionalWrites returns the folder implementing storage.ConditionalFolder if the conditional writes are enabled
+func (folder *Folder) withConditionalWrites() storage.Folder {
+	if folder.config.ConditionalWrites {
+		return &conditionalFolder{folder}
+	}
+	return folder
+}
+
+// ReadObjectVersion reads the object along with its ETag. The object is read from the main endpoint, so its version
+// is consistent with the conditional writes.
+func (folder *conditionalFolder) ReadObjectVersion(objectRelativePath string) (io.ReadCloser, string, error) {
+	objectPath := folder.path + objectRelativePath
+	object, err := folder.s3API.GetObject(&s3.GetObjectInput{
+		Bucket: folder.bucket,
+		Key:    aws.String(objectPath),
+	})
+	if err != nil {
+		if isAwsNotExist(err) {
+			return nil, "", storage.NewObjectNotFoundError(objectPath)
+		}
+		return nil, "", errors.Wrapf(err, "failed to read object: '%s' from S3", objectPath)
+	}
+	return object.Body, aws.StringValue(object.ETag), nil
+}
+
+// PutObjectIfVersion uploads the object with the If-Match precondition on its ETag, or If-None-Match if the object
+// must not exist. The storage must support the conditional writes, as AWS S3 and MinIO do.
+func (folder *conditionalFolder) PutObjectIfVersion(ctx context.Context, name string, content io.Reader, version string) error {
+	objectPath := folder.path + name
+	condition := map[string]string{"If-None-Match": "*"}
+	if version != "" {
+		condition = map[string]string{"If-Match": version}
+	}
+	err := folder.uploader.uploadConditionally(ctx, *folder.bucket, objectPath, content, condition)
+	if isAwsPreconditionFailed(err) {
+		return storage.NewPreconditionFailedError(objectPath, version)
+	}
+	return err
+}
+
+func (folder *Folder) PresignObject(objectRelativePath string, ttl time.Duration) (string, error) {
+	objectPath := folder.path + objectRelativePath
+	request, _ := folder.downloadAPI.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: folder.bucket,
+		Key:    aws.String(objectPath),
+	})
+	url, err := request.Presign(ttl)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to presign object: '%s' in S3", objectPath)
+	}
+	return url, nil
+}
+
 func (folder *Folder) GetSubFolder(subFolderRelativePath string) storage.Folder {
 	subFolder := NewFolder(
 		folder.s3API,
+		folder.downloadAPI,
 		folder.uploader,
 		storage.JoinPath(folder.path, subFolderRelativePath)+"/",
 		folder.config,
 	)
-	return subFolder
+	return subFolder.withConditionalWrites()
 }
 
 func (folder *Folder) GetPath() string {
@@ -123,8 +199,8 @@ func (folder *Folder) GetPath() string {
 func (folder *Folder) ListFolder() (objects []storage.Object, subFolders []storage.Folder, err error) {
 	listFunc := func(commonPrefixes []*s3.CommonPrefix, contents []*s3.Object) {
 		for _, prefix := range commonPrefixes {
-			subFolder := NewFolder(folder.s3API, folder.uploader, *prefix.Prefix, folder.config)
-			subFolders = append(subFolders, subFolder)
+			subFolder := NewFolder(folder.s3API, folder.downloadAPI, folder.uploader, *prefix.Prefix, folder.config)
+			subFolders = append(subFolders, subFolder.withConditionalWrites())
 		}
 		for _, object := range contents {
 			// Some storages return root tar_partitions folder as a Key.
@@ -158,6 +234,32 @@ func (folder *Folder) ListFolder() (objects []storage.Object, subFolders []stora
 	return objects, subFolders, nil
 }
 
+func (folder *Folder) ListFolderWithPrefix(prefix string) (objects []storage.Object, err error) {
+	listFunc := func(_ []*s3.CommonPrefix, contents []*s3.Object) {
+		for _, object := range contents {
+			objectRelativePath := strings.TrimPrefix(*object.Key, folder.path)
+			objects = append(objects, storage.NewLocalObject(objectRelativePath, *object.LastModified, *object.Size))
+		}
+	}
+
+	// the delimiter leaves out the objects of the subfolders matching the prefix
+	objectsPrefix := aws.String(folder.path + prefix)
+	delimiter := aws.String("/")
+	if folder.config.UseListObjectsV1 {
+		err = folder.listObjectsPagesV1(objectsPrefix, delimiter, listFunc)
+	} else {
+		err = folder.listObjectsPagesV2(objectsPrefix, delimiter, listFunc)
+	}
+
+	if err != nil {
+		if isAwsNotExist(err) {
+			return objects, nil
+		}
+		return nil, errors.Wrapf(err, "failed to list s3 folder: '%s' with prefix '%s'", folder.path, prefix)
+	}
+	return objects, nil
+}
+
 func (folder *Folder) listObjectsPagesV1(prefix *string, delimiter *string,
 	listFunc func(commonPrefixes []*s3.CommonPrefix, contents []*s3.Object)) error {
 	s3Objects := &s3.ListObjectsInput{
@@ -193,10 +295,17 @@ func (folder *Folder) DeleteObjects(objectRelativePaths []string) error {
 		input := &s3.DeleteObjectsInput{Bucket: folder.bucket, Delete: &s3.Delete{
 			Objects: folder.partitionToObjects(part),
 		}}
-		_, err := folder.s3API.DeleteObjects(input)
+		output, err := folder.s3API.DeleteObjects(input)
 		if err != nil {
 			return errors.Wrapf(err, "failed to delete s3 object: '%s'", part)
 		}
+		// the batch deletion succeeds even if the objects can't be deleted, so the denials are reported per object
+		for _, deleteErr := range output.Errors {
+			if aws.StringValue(deleteErr.Code) == AccessDeniedAWSErrorCode {
+				return storage.NewPermissionDeniedError("delete",
+					errors.Errorf("failed to delete s3 object: '%s'", aws.StringValue(deleteErr.Key)))
+			}
+		}
 	}
 	return nil
 }
@@ -209,6 +318,15 @@ func (folder *Folder) partitionToObjects(keys []string) []*s3.ObjectIdentifier {
 	return objects
 }
 
+// isAwsPreconditionFailed also reports the conflicts of the concurrent conditional writes, only one of them succeeds
+func isAwsPreconditionFailed(err error) bool {
+	var requestErr awserr.RequestFailure
+	if errors.As(err, &requestErr) {
+		return requestErr.StatusCode() == http.StatusPreconditionFailed || requestErr.StatusCode() == http.StatusConflict
+	}
+	return false
+}
+
 func isAwsNotExist(err error) bool {
 	if awsErr, ok := err.(awserr.Error); ok {
 		if awsErr.Code() == NotFoundAWSErrorCode || awsErr.Code() == NoSuchKeyAWSErrorCode {
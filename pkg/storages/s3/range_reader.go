@@ -43,7 +43,7 @@ func (reader *RangeReader) getObjectRange(from, to int64) (*s3.GetObjectOutput,
 		Range:  aws.String(bytesRange),
 	}
 	reader.debugLog("GetObject with range %s", bytesRange)
-	return reader.folder.s3API.GetObject(input)
+	return reader.folder.downloadAPI.GetObject(input)
 }
 
 func (reader *RangeReader) Read(p []byte) (n int, err error) {
@@ -75,6 +75,13 @@ func createSession(config *Config) (*session.Session, error) {
 		})
 	}
 
+	if config.RequestPayer != "" {
+		// the bucket owner doesn't pay for the requests to the requester-pays bucket, so they're refused without it
+		sess.Handlers.Validate.PushBack(func(request *request.Request) {
+			request.HTTPRequest.Header.Set("X-Amz-Request-Payer", config.RequestPayer)
+		})
+	}
+
 	if config.RequestAdditionalHeaders != "" {
 		headers, err := decodeHeaders(config.RequestAdditionalHeaders)
 		if err != nil {
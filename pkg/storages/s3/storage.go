@@ -19,6 +19,10 @@ type Config struct {
 	Secrets                  *Secrets `json:"-"`
 	Region                   string
 	Endpoint                 string
+	UploadEndpoint           string
+	DownloadEndpoint         string
+	UploadAccessKey          string
+	DownloadAccessKey        string
 	EndpointSource           string
 	EndpointPort             string
 	Bucket                   string
@@ -31,6 +35,7 @@ type Config struct {
 	UseYCSessionToken        string
 	ForcePathStyle           bool
 	RequestAdditionalHeaders string
+	RequestPayer             string
 	UseListObjectsV1         bool
 	MaxRetries               int
 	LogLevel                 string
@@ -39,10 +44,14 @@ type Config struct {
 	RangeMaxRetries          int
 	MinThrottlingRetryDelay  time.Duration
 	MaxThrottlingRetryDelay  time.Duration
+	// ConditionalWrites means the storage honors the If-Match and If-None-Match preconditions of the uploads
+	ConditionalWrites bool
 }
 
 type Secrets struct {
-	SecretKey string
+	SecretKey         string
+	UploadSecretKey   string
+	DownloadSecretKey string
 }
 
 // TODO: Unit tests
@@ -53,13 +62,23 @@ func NewStorage(config *Config, rootWraps ...storage.WrapRootFolder) (*Storage,
 	}
 
 	s3Client := s3.New(sess)
+	uploadClient, err := createOperationClient(s3Client, config, config.UploadEndpoint,
+		config.UploadAccessKey, config.Secrets.UploadSecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("create S3 client for the uploads: %w", err)
+	}
+	downloadClient, err := createOperationClient(s3Client, config, config.DownloadEndpoint,
+		config.DownloadAccessKey, config.Secrets.DownloadSecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("create S3 client for the downloads: %w", err)
+	}
 
-	uploader, err := createUploader(s3Client, config.Uploader)
+	uploader, err := createUploader(uploadClient, config.Uploader)
 	if err != nil {
 		return nil, fmt.Errorf("create new S3 uploader: %w", err)
 	}
 
-	var folder storage.Folder = NewFolder(s3Client, uploader, config.RootPath, config)
+	folder := NewFolder(s3Client, downloadClient, uploader, config.RootPath, config).withConditionalWrites()
 
 	for _, wrap := range rootWraps {
 		folder = wrap(folder)
@@ -73,6 +92,32 @@ func NewStorage(config *Config, rootWraps ...storage.WrapRootFolder) (*Storage,
 	return &Storage{folder, hash}, nil
 }
 
+// createOperationClient returns the client for the operations that have their own endpoint or credentials,
+// or the default client if neither is set. The static credentials replace the default ones, including the role
+// and the session token, while the other settings are the same as of the default client.
+func createOperationClient(defaultClient *s3.S3, config *Config, endpoint, accessKey, secretKey string) (*s3.S3, error) {
+	if endpoint == "" && accessKey == "" {
+		return defaultClient, nil
+	}
+	operationConfig := *config
+	if endpoint != "" {
+		operationConfig.Endpoint = endpoint
+		operationConfig.EndpointSource = ""
+	}
+	if accessKey != "" {
+		operationConfig.AccessKey = accessKey
+		operationConfig.Secrets = &Secrets{SecretKey: secretKey}
+		operationConfig.SessionToken = ""
+		operationConfig.RoleARN = ""
+		operationConfig.UseYCSessionToken = ""
+	}
+	sess, err := createSession(&operationConfig)
+	if err != nil {
+		return nil, err
+	}
+	return s3.New(sess), nil
+}
+
 func (s *Storage) RootFolder() storage.Folder {
 	return s.rootFolder
 }
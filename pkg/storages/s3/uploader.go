@@ -1,6 +1,7 @@
 package s3
 
 import (
+	"bytes"
 	"context"
 	"crypto/md5"
 	"encoding/base64"
@@ -8,6 +9,7 @@ import (
 	"io"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
@@ -22,6 +24,8 @@ type UploaderConfig struct {
 	ServerSideEncryption         string
 	ServerSideEncryptionCustomer string
 	ServerSideEncryptionKMSID    string
+	// Tagging is the URL-encoded tags of the uploaded objects
+	Tagging string
 }
 
 func createUploader(s3Client *s3.S3, config *UploaderConfig) (*Uploader, error) {
@@ -30,13 +34,15 @@ func createUploader(s3Client *s3.S3, config *UploaderConfig) (*Uploader, error)
 	if (config.ServerSideEncryption == "aws:kms") == (config.ServerSideEncryptionKMSID == "") {
 		return nil, fmt.Errorf("server-side encryption KMS key ID must be set if 'aws:kms' encryption is used")
 	}
-	return NewUploader(
+	uploader := NewUploader(
 		uploaderAPI,
 		config.ServerSideEncryption,
 		config.ServerSideEncryptionCustomer,
 		config.ServerSideEncryptionKMSID,
 		config.StorageClass,
-	), nil
+	)
+	uploader.Tagging = config.Tagging
+	return uploader, nil
 }
 
 type Uploader struct {
@@ -45,10 +51,17 @@ type Uploader struct {
 	SSECustomerKey       string
 	SSEKMSKeyID          string
 	StorageClass         string
+	Tagging              string
 }
 
 func NewUploader(uploaderAPI s3manageriface.UploaderAPI, serverSideEncryption, sseCustomerKey, sseKmsKeyID, storageClass string) *Uploader {
-	return &Uploader{uploaderAPI, serverSideEncryption, sseCustomerKey, sseKmsKeyID, storageClass}
+	return &Uploader{
+		uploaderAPI:          uploaderAPI,
+		serverSideEncryption: serverSideEncryption,
+		SSECustomerKey:       sseCustomerKey,
+		SSEKMSKeyID:          sseKmsKeyID,
+		StorageClass:         storageClass,
+	}
 }
 
 // TODO : unit tests
@@ -59,6 +72,9 @@ func (uploader *Uploader) createUploadInput(bucket, path string, content io.Read
 		Body:         content,
 		StorageClass: aws.String(uploader.StorageClass),
 	}
+	if uploader.Tagging != "" {
+		uploadInput.Tagging = aws.String(uploader.Tagging)
+	}
 
 	if uploader.serverSideEncryption != "" {
 		if uploader.SSECustomerKey != "" {
@@ -86,6 +102,25 @@ func (uploader *Uploader) upload(ctx context.Context, bucket, path string, conte
 	return errors.Wrapf(err, "failed to upload '%s' to bucket '%s'", path, bucket)
 }
 
+// uploadConditionally uploads the object in a single request with the precondition headers. Conditional writes
+// are intended for small metadata objects, so the content is read at once.
+func (uploader *Uploader) uploadConditionally(ctx context.Context, bucket, path string, content io.Reader,
+	condition map[string]string) error {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read '%s' to upload it", path)
+	}
+	input := uploader.createUploadInput(bucket, path, bytes.NewReader(data))
+	_, err = uploader.uploaderAPI.UploadWithContext(ctx, input, func(u *s3manager.Uploader) {
+		// the preconditions are checked only for the single part uploads
+		if partSize := int64(len(data)) + 1; partSize > u.PartSize {
+			u.PartSize = partSize
+		}
+		u.RequestOptions = append(u.RequestOptions, request.WithSetRequestHeaders(condition))
+	})
+	return errors.Wrapf(err, "failed to upload '%s' to bucket '%s'", path, bucket)
+}
+
 // CreateUploaderAPI returns an uploader with customizable concurrency
 // and part size.
 func CreateUploaderAPI(svc s3iface.S3API, partsize, concurrency int) s3manageriface.UploaderAPI {
@@ -60,3 +60,13 @@ func TestPartitionStrings(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateUploadInput_Tagging(t *testing.T) {
+	uploader := NewUploader(nil, "", "", "", defaultStorageClass)
+	input := uploader.createUploadInput("bucket", "path", nil)
+	assert.Nil(t, input.Tagging)
+
+	uploader.Tagging = "cluster=main&team=db"
+	input = uploader.createUploadInput("bucket", "path", nil)
+	assert.Equal(t, "cluster=main&team=db", *input.Tagging)
+}
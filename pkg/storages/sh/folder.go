@@ -189,19 +189,20 @@ func (folder *Folder) PutObjectWithContext(ctx context.Context, name string, con
 }
 
 func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath, storage.CopyOptions{})
+}
+
+// CopyObjectWithContext streams the object through wal-g, SFTP has no server-side copy
+func (folder *Folder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string, options storage.CopyOptions) error {
 	if exists, err := folder.Exists(srcPath); !exists {
 		if err == nil {
 			return storage.NewObjectNotFoundError(srcPath)
 		}
 		return fmt.Errorf("copy via SFTP: check if source file %q exists: %w", srcPath, err)
 	}
-	file, err := folder.ReadObject(srcPath)
-	if err != nil {
-		return fmt.Errorf("copy via SFTP: read source file %q: %w", srcPath, err)
-	}
-	err = folder.PutObject(dstPath, file)
+	err := storage.StreamCopyObject(ctx, folder, srcPath, dstPath, options)
 	if err != nil {
-		return fmt.Errorf("copy via SFTP: write destination file %q: %w", dstPath, err)
+		return fmt.Errorf("copy via SFTP %q to %q: %w", srcPath, dstPath, err)
 	}
 	return nil
 }
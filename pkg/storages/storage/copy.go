@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/wal-g/wal-g/internal/contextio"
+	"github.com/wal-g/wal-g/internal/limiters"
+	"github.com/wal-g/wal-g/utility"
+	"golang.org/x/time/rate"
+)
+
+// CopyOptions control how CopyObjectWithContext copies an object
+type CopyOptions struct {
+	// Limiter limits the bandwidth of the copies streamed through wal-g, the server-side copies transfer nothing
+	Limiter *rate.Limiter
+	// Progress is called with the number of bytes copied so far. The server-side copies report it only
+	// if the storage tells the progress of the copy.
+	Progress func(copiedBytes int64)
+}
+
+// ContextCopyFolder is implemented by the folders that copy the objects with the cancellation and the progress.
+// The folders of the storages that can copy the objects server-side must do so, the rest stream the objects
+// with StreamCopyObject.
+type ContextCopyFolder interface {
+	Folder
+
+	// CopyObjectWithContext copies an object like CopyObject, but the copy is terminated once the context is done.
+	CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string, options CopyOptions) error
+}
+
+// CopyObjectWithContext copies the object inside the folder with its CopyObjectWithContext. The folders that don't
+// implement ContextCopyFolder copy it with CopyObject, which can't be terminated once it is started.
+func CopyObjectWithContext(ctx context.Context, folder Folder, srcPath string, dstPath string, options CopyOptions) error {
+	if copyFolder, ok := folder.(ContextCopyFolder); ok {
+		return copyFolder.CopyObjectWithContext(ctx, srcPath, dstPath, options)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return folder.CopyObject(srcPath, dstPath)
+}
+
+// StreamCopyObject copies the object by reading it from the folder and writing it back, the content is
+// rate limited and its progress is reported as the options tell
+func StreamCopyObject(ctx context.Context, folder Folder, srcPath string, dstPath string, options CopyOptions) error {
+	src, err := folder.ReadObject(srcPath)
+	if err != nil {
+		return err
+	}
+	defer utility.LoggedClose(src, fmt.Sprintf("close the copied object %q", srcPath))
+
+	reader := contextio.NewReader(ctx, src)
+	if options.Limiter != nil {
+		reader = limiters.NewReader(ctx, reader, options.Limiter)
+	}
+	if options.Progress != nil {
+		reader = &progressReader{reader: reader, progress: options.Progress}
+	}
+	return folder.PutObjectWithContext(ctx, dstPath, reader)
+}
+
+type progressReader struct {
+	reader   io.Reader
+	read     int64
+	progress func(int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.progress(atomic.AddInt64(&r.read, int64(n)))
+	}
+	return n, err
+}
@@ -0,0 +1,55 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+	"golang.org/x/time/rate"
+)
+
+func TestCopyObjectWithContext_Progress(t *testing.T) {
+	folder := memory.NewFolder("in_memory/", memory.NewKVS())
+	content := bytes.Repeat([]byte("wal-g"), 1000)
+	require.NoError(t, folder.PutObject("basebackups_005/base_000/part_1.tar.br", bytes.NewReader(content)))
+
+	var copied int64
+	err := storage.CopyObjectWithContext(context.Background(), folder,
+		"basebackups_005/base_000/part_1.tar.br", "basebackups_005/base_001/part_1.tar.br",
+		storage.CopyOptions{
+			Limiter:  rate.NewLimiter(rate.Inf, 1024),
+			Progress: func(copiedBytes int64) { copied = copiedBytes },
+		})
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), copied)
+
+	reader, err := folder.ReadObject("basebackups_005/base_001/part_1.tar.br")
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+}
+
+func TestCopyObjectWithContext_Canceled(t *testing.T) {
+	folder := memory.NewFolder("in_memory/", memory.NewKVS())
+	require.NoError(t, folder.PutObject("file", bytes.NewReader([]byte("content"))))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := storage.CopyObjectWithContext(ctx, folder, "file", "copy", storage.CopyOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+	exists, err := folder.Exists("copy")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestCopyObjectWithContext_NotFound(t *testing.T) {
+	folder := memory.NewFolder("in_memory/", memory.NewKVS())
+	err := storage.CopyObjectWithContext(context.Background(), folder, "missing", "copy", storage.CopyOptions{})
+	assert.ErrorAs(t, err, new(storage.ObjectNotFoundError))
+}
@@ -2,6 +2,8 @@ package storage
 
 import (
 	"fmt"
+	"net/http"
+	"os"
 
 	"github.com/pkg/errors"
 	"github.com/wal-g/tracelog"
@@ -19,6 +21,41 @@ func (err ObjectNotFoundError) Error() string {
 	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
 }
 
+type PreconditionFailedError struct {
+	error
+}
+
+func NewPreconditionFailedError(path, version string) PreconditionFailedError {
+	return PreconditionFailedError{errors.Errorf("object '%s' doesn't have the expected version '%s'", path, version)}
+}
+
+func (err PreconditionFailedError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// PermissionDeniedError means the storage credentials lack the permission for the operation
+type PermissionDeniedError struct {
+	error
+}
+
+func NewPermissionDeniedError(operation string, err error) PermissionDeniedError {
+	return PermissionDeniedError{errors.Wrapf(err, "permission to %s objects in storage is denied", operation)}
+}
+
+func (err PermissionDeniedError) Error() string {
+	return fmt.Sprintf(tracelog.GetErrorFormatter(), err.error)
+}
+
+// IsPermissionDenied checks if the storage has refused the operation because of the credentials: the error is
+// PermissionDeniedError, the file system permission error or the HTTP 403 response, e.g. from S3.
+func IsPermissionDenied(err error) bool {
+	if errors.As(err, &PermissionDeniedError{}) || errors.Is(err, os.ErrPermission) {
+		return true
+	}
+	var responseErr interface{ StatusCode() int }
+	return errors.As(err, &responseErr) && responseErr.StatusCode() == http.StatusForbidden
+}
+
 type Error struct {
 	error
 }
@@ -6,6 +6,7 @@ import (
 	"io"
 	"path"
 	"strings"
+	"time"
 )
 
 //go:generate mockery --name Folder
@@ -44,6 +45,130 @@ type Folder interface {
 	CopyObject(srcPath string, dstPath string) error
 }
 
+// PresignableFolder is implemented by the folders of storages that can issue time-limited download URLs,
+// so an object can be shared without granting access to the whole storage.
+type PresignableFolder interface {
+	Folder
+
+	// PresignObject returns a URL that allows anyone to download the object until the ttl expires.
+	PresignObject(objectRelativePath string, ttl time.Duration) (string, error)
+}
+
+// ConditionalFolder is implemented by the folders of storages that support conditional writes, so an object
+// can be updated with compare-and-swap semantics.
+type ConditionalFolder interface {
+	Folder
+
+	// ReadObjectVersion reads an object along with its current version (e.g. ETag or generation).
+	// Must return ObjectNotFoundError in case the object doesn't exist.
+	ReadObjectVersion(objectRelativePath string) (io.ReadCloser, string, error)
+
+	// PutObjectIfVersion uploads an object only if its current version matches the provided one. The empty version
+	// means that the object must not exist. Must return PreconditionFailedError if the object has another version.
+	PutObjectIfVersion(ctx context.Context, name string, content io.Reader, version string) error
+}
+
+// PrefixListingFolder is implemented by the folders of storages that can list only the objects with the name prefix,
+// so a part of the large flat folder, like the WAL one, is listed without listing the whole folder.
+type PrefixListingFolder interface {
+	Folder
+
+	// ListFolderWithPrefix lists the objects of the folder whose names start with the prefix. Subfolders aren't listed.
+	ListFolderWithPrefix(prefix string) ([]Object, error)
+}
+
+// WrapperFolder is implemented by the folders wrapping another one, e.g. to limit the bandwidth. Its conditional
+// writes and presigning may be used only if the wrapped folder supports them, WithOptionalInterfaces hides them
+// otherwise. The prefix listing is always available, see ListFolderWithPrefix.
+type WrapperFolder interface {
+	ContextCopyFolder
+	conditionalMethods
+	presignableMethods
+	prefixListingMethods
+}
+
+type prefixListingMethods interface {
+	ListFolderWithPrefix(prefix string) ([]Object, error)
+}
+
+type conditionalMethods interface {
+	ReadObjectVersion(objectRelativePath string) (io.ReadCloser, string, error)
+	PutObjectIfVersion(ctx context.Context, name string, content io.Reader, version string) error
+}
+
+type presignableMethods interface {
+	PresignObject(objectRelativePath string, ttl time.Duration) (string, error)
+}
+
+// WithOptionalInterfaces returns the wrapper implementing ConditionalFolder and PresignableFolder only if
+// the wrapped folder implements them, so the wrappers don't hide these capabilities of the storage
+func WithOptionalInterfaces(wrapper WrapperFolder, wrapped Folder) Folder {
+	_, isConditional := wrapped.(ConditionalFolder)
+	_, isPresignable := wrapped.(PresignableFolder)
+	switch {
+	case isConditional && isPresignable:
+		return wrapper
+	case isConditional:
+		return struct {
+			ContextCopyFolder
+			prefixListingMethods
+			conditionalMethods
+		}{wrapper, wrapper, wrapper}
+	case isPresignable:
+		return struct {
+			ContextCopyFolder
+			prefixListingMethods
+			presignableMethods
+		}{wrapper, wrapper, wrapper}
+	}
+	return struct {
+		ContextCopyFolder
+		prefixListingMethods
+	}{wrapper, wrapper}
+}
+
+// ListFolderWithPrefix lists the objects of the folder whose names start with the prefix. Only these objects
+// are listed if the folder supports it, otherwise the whole folder is listed and filtered.
+func ListFolderWithPrefix(folder Folder, prefix string) ([]Object, error) {
+	if prefixListing, ok := folder.(PrefixListingFolder); ok {
+		return prefixListing.ListFolderWithPrefix(prefix)
+	}
+	objects, _, err := folder.ListFolder()
+	if err != nil {
+		return nil, err
+	}
+	return FilterObjectsByPrefix(objects, prefix), nil
+}
+
+// FilterObjectsByPrefix returns the objects whose names start with the prefix
+func FilterObjectsByPrefix(objects []Object, prefix string) []Object {
+	filtered := make([]Object, 0, len(objects))
+	for _, object := range objects {
+		if strings.HasPrefix(object.GetName(), prefix) {
+			filtered = append(filtered, object)
+		}
+	}
+	return filtered
+}
+
+// AsConditional returns the folder as ConditionalFolder, or the error if it doesn't support the conditional writes
+func AsConditional(folder Folder) (ConditionalFolder, error) {
+	conditional, ok := folder.(ConditionalFolder)
+	if !ok {
+		return nil, fmt.Errorf("the storage folder %s doesn't support the conditional writes", folder.GetPath())
+	}
+	return conditional, nil
+}
+
+// AsPresignable returns the folder as PresignableFolder, or the error if it doesn't support presigning
+func AsPresignable(folder Folder) (PresignableFolder, error) {
+	presignable, ok := folder.(PresignableFolder)
+	if !ok {
+		return nil, fmt.Errorf("the storage folder %s doesn't support the pre-signed URLs", folder.GetPath())
+	}
+	return presignable, nil
+}
+
 func ListFolderRecursively(folder Folder) (relativePathObjects []Object, err error) {
 	return ListFolderRecursivelyWithFilter(folder, func(string) bool { return true })
 }
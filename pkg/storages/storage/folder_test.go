@@ -218,3 +218,22 @@ func TestListFolderRecursivelyWithPrefix(t *testing.T) {
 		assertFiles(t, files, []string{"a/111", "a/b/222"})
 	})
 }
+
+func TestListFolderWithPrefix(t *testing.T) {
+	folder := memory.NewFolder("memory/", memory.NewKVS())
+	_ = folder.PutObject("000000010000000000000001", &bytes.Buffer{})
+	_ = folder.PutObject("000000010000000100000001", &bytes.Buffer{})
+	_ = folder.PutObject("000000020000000000000001", &bytes.Buffer{})
+	_ = folder.PutObject("0000000100000000/waste", &bytes.Buffer{})
+
+	objects, err := storage.ListFolderWithPrefix(folder, "0000000100000000")
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, "000000010000000000000001", objects[0].GetName())
+
+	// the folders without the prefix listing list the whole folder and filter it
+	wrapped := struct{ storage.Folder }{folder}
+	objects, err = storage.ListFolderWithPrefix(wrapped, "00000001")
+	require.NoError(t, err)
+	assert.Len(t, objects, 2)
+}
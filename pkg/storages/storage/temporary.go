@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"fmt"
+	"math/rand"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TemporaryFolder is the folder at the storage root for the temporary objects of the operations which may be
+// interrupted, e.g. the objects written before they are copied to their places. The storages have no common way
+// to attach the metadata to the objects, so the name of the temporary object carries its expiration time, and
+// the expired temporary objects are removed by ScrubTemporaries regardless of the operation which left them.
+const TemporaryFolder = "walg_tmp/"
+
+// NewTemporaryPath returns the path of the temporary object for the object name, which expires after the ttl
+func NewTemporaryPath(name string, ttl time.Duration) string {
+	expiresAt := time.Now().Add(ttl).Unix()
+	return fmt.Sprintf("%s%d_%s_%s", TemporaryFolder, expiresAt, strconv.FormatUint(rand.Uint64(), 36), path.Base(name))
+}
+
+// IsTemporaryPath checks if the path relative to the storage root is inside the TemporaryFolder
+func IsTemporaryPath(objectPath string) bool {
+	return strings.HasPrefix(objectPath, TemporaryFolder)
+}
+
+// TemporaryExpiration returns the expiration time of the temporary object by its name inside the TemporaryFolder
+func TemporaryExpiration(name string) (time.Time, bool) {
+	expiresAt, _, found := strings.Cut(path.Base(name), "_")
+	if !found {
+		return time.Time{}, false
+	}
+	seconds, err := strconv.ParseInt(expiresAt, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
+
+// ScrubTemporaries deletes the temporary objects expired by now from the storage root folder. The objects which
+// don't follow the naming of the temporary objects are left intact.
+func ScrubTemporaries(root Folder, now time.Time) (deleted int, err error) {
+	temporaryFolder := root.GetSubFolder(TemporaryFolder)
+	objects, _, err := temporaryFolder.ListFolder()
+	if err != nil {
+		return 0, fmt.Errorf("list temporary objects: %w", err)
+	}
+	var expired []string
+	for _, object := range objects {
+		if expiresAt, ok := TemporaryExpiration(object.GetName()); ok && expiresAt.Before(now) {
+			expired = append(expired, object.GetName())
+		}
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+	if err = temporaryFolder.DeleteObjects(expired); err != nil {
+		return 0, fmt.Errorf("delete expired temporary objects: %w", err)
+	}
+	return len(expired), nil
+}
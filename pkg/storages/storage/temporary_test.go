@@ -0,0 +1,46 @@
+package storage_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wal-g/wal-g/pkg/storages/memory"
+	"github.com/wal-g/wal-g/pkg/storages/storage"
+)
+
+func TestTemporaryExpiration(t *testing.T) {
+	temporaryPath := storage.NewTemporaryPath("wal_005/000000010000000000000001.lz4", time.Hour)
+	assert.True(t, storage.IsTemporaryPath(temporaryPath))
+	assert.True(t, strings.HasSuffix(temporaryPath, "_000000010000000000000001.lz4"))
+
+	expiresAt, ok := storage.TemporaryExpiration(temporaryPath)
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Minute)
+
+	_, ok = storage.TemporaryExpiration("walg_tmp/unrelated")
+	assert.False(t, ok)
+}
+
+func TestScrubTemporaries(t *testing.T) {
+	root := memory.NewFolder("in_memory/", memory.NewKVS())
+	expiredPath := storage.NewTemporaryPath("expired", time.Hour)
+	livePath := storage.NewTemporaryPath("live", 3*time.Hour)
+	for _, path := range []string{expiredPath, livePath, "walg_tmp/unrelated", "wal_005/1"} {
+		require.NoError(t, root.PutObject(path, strings.NewReader("data")))
+	}
+
+	deleted, err := storage.ScrubTemporaries(root, time.Now().Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	for path, shouldExist := range map[string]bool{
+		expiredPath: false, livePath: true, "walg_tmp/unrelated": true, "wal_005/1": true,
+	} {
+		exists, err := root.Exists(path)
+		require.NoError(t, err)
+		assert.Equal(t, shouldExist, exists, path)
+	}
+}
@@ -118,6 +118,11 @@ func (folder *Folder) PutObjectWithContext(ctx context.Context, name string, con
 }
 
 func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
+	return folder.CopyObjectWithContext(context.Background(), srcPath, dstPath, storage.CopyOptions{})
+}
+
+// CopyObjectWithContext copies the object server-side, the content isn't transferred through wal-g
+func (folder *Folder) CopyObjectWithContext(ctx context.Context, srcPath string, dstPath string, _ storage.CopyOptions) error {
 	if exists, err := folder.Exists(srcPath); !exists {
 		if err == nil {
 			return storage.NewObjectNotFoundError(srcPath)
@@ -126,7 +131,7 @@ func (folder *Folder) CopyObject(srcPath string, dstPath string) error {
 	}
 	srcPath = storage.JoinPath(folder.path, srcPath)
 	dstPath = storage.JoinPath(folder.path, dstPath)
-	_, err := folder.connection.ObjectCopy(context.Background(), folder.container.Name, srcPath, folder.container.Name, dstPath, nil)
+	_, err := folder.connection.ObjectCopy(ctx, folder.container.Name, srcPath, folder.container.Name, dstPath, nil)
 	if err != nil {
 		return fmt.Errorf("copy Swift object %q -> %q: %w", srcPath, dstPath, err)
 	}
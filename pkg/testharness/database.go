@@ -0,0 +1,129 @@
+package testharness
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	defaultPostgresImage = "postgres:15"
+	defaultMySQLImage    = "mysql:8.0"
+	defaultMongoImage    = "mongo:4.4"
+
+	databasePassword = "testharness"
+)
+
+// Database is a database container with WAL-G installed and configured to use the harness storage.
+type Database struct {
+	harness   *Harness
+	Container string
+	// User runs WAL-G and the database clients inside the container.
+	User string
+}
+
+// databaseSpec describes how to run a particular database in docker.
+type databaseSpec struct {
+	name         string
+	image        string
+	user         string
+	env          map[string]string
+	command      []string
+	readyCommand []string
+}
+
+// StartPostgres runs PostgreSQL that archives WAL with WAL-G. An empty image means the default one.
+func (h *Harness) StartPostgres(ctx context.Context, image string) (*Database, error) {
+	return h.startDatabase(ctx, databaseSpec{
+		name:  "postgres",
+		image: valueOrDefault(image, defaultPostgresImage),
+		user:  "postgres",
+		env: map[string]string{
+			"POSTGRES_PASSWORD": databasePassword,
+			"PGDATA":            "/var/lib/postgresql/data",
+			"PGHOST":            "/var/run/postgresql",
+			"PGUSER":            "postgres",
+		},
+		command: []string{
+			"postgres",
+			"-c", "wal_level=replica",
+			"-c", "archive_mode=on",
+			"-c", "archive_command=" + walgContainerPath + " wal-push %p",
+			"-c", "archive_timeout=10",
+		},
+		readyCommand: []string{"pg_isready", "-h", "localhost"},
+	})
+}
+
+// StartMySQL runs MySQL with binary logging. Backups are taken with mysqldump, since it's available in the image.
+func (h *Harness) StartMySQL(ctx context.Context, image string) (*Database, error) {
+	mysqlArgs := "-uroot -p" + databasePassword
+	return h.startDatabase(ctx, databaseSpec{
+		name:  "mysql",
+		image: valueOrDefault(image, defaultMySQLImage),
+		env: map[string]string{
+			"MYSQL_ROOT_PASSWORD":         databasePassword,
+			"WALG_MYSQL_DATASOURCE_NAME":  "root:" + databasePassword + "@tcp(localhost:3306)/mysql",
+			"WALG_STREAM_CREATE_COMMAND":  "mysqldump " + mysqlArgs + " --all-databases --single-transaction",
+			"WALG_STREAM_RESTORE_COMMAND": "mysql " + mysqlArgs,
+		},
+		command:      []string{"mysqld", "--log-bin=mysql-bin", "--server-id=1"},
+		readyCommand: []string{"mysql", "-uroot", "-p" + databasePassword, "-h127.0.0.1", "-e", "SELECT 1"},
+	})
+}
+
+// StartMongo runs MongoDB. Backups are taken with mongodump, since it's available in the image.
+func (h *Harness) StartMongo(ctx context.Context, image string) (*Database, error) {
+	return h.startDatabase(ctx, databaseSpec{
+		name:  "mongo",
+		image: valueOrDefault(image, defaultMongoImage),
+		env: map[string]string{
+			"MONGODB_URI":                 "mongodb://localhost:27017",
+			"WALG_STREAM_CREATE_COMMAND":  "mongodump --archive",
+			"WALG_STREAM_RESTORE_COMMAND": "mongorestore --archive --drop",
+		},
+		readyCommand: []string{"mongo", "--quiet", "--eval", "db.adminCommand('ping').ok"},
+	})
+}
+
+func (h *Harness) startDatabase(ctx context.Context, spec databaseSpec) (*Database, error) {
+	container := h.config.Name + "-" + spec.name
+	env := h.StorageEnv(spec.name)
+	for key, value := range spec.env {
+		env[key] = value
+	}
+
+	err := h.runContainer(ctx, container, spec.image, env, true, spec.command...)
+	if err != nil {
+		return nil, fmt.Errorf("start %s: %w", spec.name, err)
+	}
+	err = h.waitReady(ctx, container, spec.readyCommand...)
+	if err != nil {
+		return nil, err
+	}
+	return &Database{harness: h, Container: container, User: spec.user}, nil
+}
+
+// WalG runs WAL-G with the provided arguments inside the database container.
+func (db *Database) WalG(ctx context.Context, args ...string) (ExecResult, error) {
+	return db.Exec(ctx, append([]string{walgContainerPath}, args...)...)
+}
+
+// Exec runs an arbitrary command inside the database container.
+func (db *Database) Exec(ctx context.Context, command ...string) (ExecResult, error) {
+	return db.harness.Exec(ctx, db.Container, db.User, command...)
+}
+
+// MustSucceed turns a non-zero exit code into an error, e.g. result, err := MustSucceed(db.WalG(ctx, "backup-list")).
+func MustSucceed(result ExecResult, err error) (ExecResult, error) {
+	if err == nil && result.ExitCode != 0 {
+		err = fmt.Errorf("command failed with exit code %d:\n%s", result.ExitCode, result)
+	}
+	return result, err
+}
+
+func valueOrDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
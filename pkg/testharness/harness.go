@@ -0,0 +1,244 @@
+// Package testharness runs databases with WAL-G in docker containers and provides a MinIO-based S3 storage for them.
+// It allows writing end-to-end tests of realistic backup and restore flows in plain Go, e.g. to validate custom WAL-G
+// builds or plugins. Docker CLI must be available on the host running the tests.
+package testharness
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMinIOImage   = "minio/minio:latest"
+	defaultStartTimeout = 2 * time.Minute
+
+	minIOPort      = 9000
+	minIOAccessKey = "testharness"
+	minIOSecretKey = "testharness-secret"
+	minIOBucket    = "walg"
+
+	walgContainerPath = "/usr/local/bin/wal-g"
+)
+
+type Config struct {
+	// WalgBinary is a path to the WAL-G binary on the host. It must be built for Linux and for the tested database.
+	WalgBinary string
+	// Name prefixes all the docker objects created by the harness. A random one is generated if it's empty.
+	Name string
+	// MinIOImage overrides the MinIO docker image.
+	MinIOImage string
+	// StartTimeout limits waiting for the containers to become ready.
+	StartTimeout time.Duration
+}
+
+// Harness owns a docker network with a MinIO container, and the database containers started in it.
+type Harness struct {
+	config     Config
+	network    string
+	minIO      string
+	containers []string
+	mu         sync.Mutex
+}
+
+type ExecResult struct {
+	ExitCode int
+	Stdout   string
+	Stderr   string
+}
+
+func (res ExecResult) String() string {
+	return fmt.Sprintf("code: %d\nstdout:\n%s\nstderr:\n%s\n", res.ExitCode, res.Stdout, res.Stderr)
+}
+
+// New creates the docker network and starts MinIO with an empty bucket in it. Close must be called to clean up.
+func New(ctx context.Context, config Config) (*Harness, error) {
+	if config.WalgBinary == "" {
+		return nil, fmt.Errorf("path to the WAL-G binary must be specified")
+	}
+	if config.Name == "" {
+		config.Name = fmt.Sprintf("walg-testharness-%d", rand.New(rand.NewSource(time.Now().UnixNano())).Int63())
+	}
+	if config.MinIOImage == "" {
+		config.MinIOImage = defaultMinIOImage
+	}
+	if config.StartTimeout == 0 {
+		config.StartTimeout = defaultStartTimeout
+	}
+
+	h := &Harness{
+		config:  config,
+		network: config.Name,
+		minIO:   config.Name + "-minio",
+	}
+	_, err := docker(ctx, "network", "create", h.network)
+	if err != nil {
+		return nil, fmt.Errorf("create docker network: %w", err)
+	}
+
+	err = h.startMinIO(ctx)
+	if err != nil {
+		_ = h.Close(context.Background())
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *Harness) startMinIO(ctx context.Context) error {
+	err := h.runContainer(ctx, h.minIO, h.config.MinIOImage,
+		map[string]string{"MINIO_ROOT_USER": minIOAccessKey, "MINIO_ROOT_PASSWORD": minIOSecretKey},
+		false, "server", "/data")
+	if err != nil {
+		return fmt.Errorf("start MinIO: %w", err)
+	}
+
+	createBucket := fmt.Sprintf("mc alias set local http://localhost:%d %s %s && mc mb -p local/%s",
+		minIOPort, minIOAccessKey, minIOSecretKey, minIOBucket)
+	err = h.waitReady(ctx, h.minIO, "sh", "-c", createBucket)
+	if err != nil {
+		return fmt.Errorf("create MinIO bucket: %w", err)
+	}
+	return nil
+}
+
+// StorageEnv provides the settings to use the MinIO bucket as WAL-G storage. Each database gets its own prefix.
+func (h *Harness) StorageEnv(prefix string) map[string]string {
+	return map[string]string{
+		"AWS_ACCESS_KEY_ID":       minIOAccessKey,
+		"AWS_SECRET_ACCESS_KEY":   minIOSecretKey,
+		"AWS_ENDPOINT":            fmt.Sprintf("http://%s:%d", h.minIO, minIOPort),
+		"AWS_REGION":              "us-east-1",
+		"AWS_S3_FORCE_PATH_STYLE": "true",
+		"WALG_S3_PREFIX":          fmt.Sprintf("s3://%s/%s", minIOBucket, prefix),
+	}
+}
+
+// Exec runs the command in the container and returns its result. A non-zero exit code isn't an error.
+func (h *Harness) Exec(ctx context.Context, container, user string, command ...string) (ExecResult, error) {
+	args := []string{"exec"}
+	if user != "" {
+		args = append(args, "--user", user)
+	}
+	args = append(args, container)
+	args = append(args, command...)
+
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+
+	result := ExecResult{Stdout: stdout.String(), Stderr: stderr.String()}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		result.ExitCode = exitErr.ExitCode()
+		return result, nil
+	}
+	return result, err
+}
+
+// Close removes all the containers and the network created by the harness.
+func (h *Harness) Close(ctx context.Context) error {
+	h.mu.Lock()
+	containers := h.containers
+	h.containers = nil
+	h.mu.Unlock()
+
+	var errs []string
+	if len(containers) > 0 {
+		_, err := docker(ctx, append([]string{"rm", "--force", "--volumes"}, containers...)...)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	_, err := docker(ctx, "network", "rm", h.network)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("clean up test harness: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// runContainer creates the container, copies WAL-G into it if needed, and starts it.
+func (h *Harness) runContainer(
+	ctx context.Context,
+	name, image string,
+	env map[string]string,
+	withWalg bool,
+	command ...string,
+) error {
+	args := []string{"create", "--name", name, "--hostname", name, "--network", h.network}
+	args = append(args, envArgs(env)...)
+	args = append(args, image)
+	args = append(args, command...)
+	_, err := docker(ctx, args...)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	h.containers = append(h.containers, name)
+	h.mu.Unlock()
+
+	if withWalg {
+		_, err = docker(ctx, "cp", h.config.WalgBinary, name+":"+walgContainerPath)
+		if err != nil {
+			return fmt.Errorf("copy WAL-G binary: %w", err)
+		}
+	}
+
+	_, err = docker(ctx, "start", name)
+	return err
+}
+
+// waitReady repeats the command until it succeeds or the start timeout expires.
+func (h *Harness) waitReady(ctx context.Context, container string, command ...string) error {
+	ctx, cancel := context.WithTimeout(ctx, h.config.StartTimeout)
+	defer cancel()
+
+	var lastResult ExecResult
+	for {
+		result, err := h.Exec(ctx, container, "", command...)
+		if err == nil && result.ExitCode == 0 {
+			return nil
+		}
+		if err == nil {
+			lastResult = result
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s isn't ready in %v, last check result:\n%s", container, h.config.StartTimeout, lastResult)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func docker(ctx context.Context, args ...string) (string, error) {
+	output, err := exec.CommandContext(ctx, "docker", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("docker %s: %w: %s", args[0], err, output)
+	}
+	return string(output), nil
+}
+
+// envArgs converts the environment to docker arguments in a stable order.
+func envArgs(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	args := make([]string, 0, 2*len(keys))
+	for _, key := range keys {
+		args = append(args, "--env", key+"="+env[key])
+	}
+	return args
+}
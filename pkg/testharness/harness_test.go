@@ -0,0 +1,72 @@
+package testharness
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// walgBinaryEnv points to a Linux PostgreSQL WAL-G build to run the end-to-end test with.
+const walgBinaryEnv = "WALG_TESTHARNESS_PG_BINARY"
+
+const (
+	restoredDataDir = "/tmp/restored"
+	restoredPort    = "5433"
+)
+
+func TestEnvArgs(t *testing.T) {
+	args := envArgs(map[string]string{"B": "2", "A": "1=1"})
+	assert.Equal(t, []string{"--env", "A=1=1", "--env", "B=2"}, args)
+}
+
+func TestPostgresBackupRoundTrip(t *testing.T) {
+	binary, ok := os.LookupEnv(walgBinaryEnv)
+	if !ok {
+		t.Skipf("%s is not set", walgBinaryEnv)
+	}
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker is not available")
+	}
+
+	ctx := context.Background()
+	harness, err := New(ctx, Config{WalgBinary: binary})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, harness.Close(ctx))
+	}()
+
+	pg, err := harness.StartPostgres(ctx, "")
+	require.NoError(t, err)
+
+	_, err = MustSucceed(pg.Exec(ctx, "psql", "-c", "CREATE TABLE testharness AS SELECT 42 AS answer"))
+	require.NoError(t, err)
+
+	_, err = MustSucceed(pg.WalG(ctx, "backup-push", "/var/lib/postgresql/data"))
+	require.NoError(t, err)
+
+	result, err := MustSucceed(pg.WalG(ctx, "backup-list"))
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(result.Stdout, "base_"), result.String())
+
+	// restore the backup next to the running cluster and replay the archived WAL into it
+	_, err = MustSucceed(pg.Exec(ctx, "mkdir", "-m", "0700", restoredDataDir))
+	require.NoError(t, err)
+	_, err = MustSucceed(pg.WalG(ctx, "backup-fetch", restoredDataDir, "LATEST"))
+	require.NoError(t, err)
+	_, err = MustSucceed(pg.Exec(ctx, "touch", restoredDataDir+"/recovery.signal"))
+	require.NoError(t, err)
+	_, err = MustSucceed(pg.Exec(ctx, "pg_ctl", "start", "-w", "-t", "120",
+		"-D", restoredDataDir, "-l", restoredDataDir+"/startup.log",
+		"-o", "-p "+restoredPort+" -c unix_socket_directories=/tmp -c archive_mode=off"+
+			" -c restore_command='"+walgContainerPath+" wal-fetch %f %p'"))
+	require.NoError(t, err)
+
+	result, err = MustSucceed(pg.Exec(ctx, "psql", "-h", "/tmp", "-p", restoredPort, "-Atc", "SELECT answer FROM testharness"))
+	require.NoError(t, err)
+	assert.Equal(t, "42", strings.TrimSpace(result.Stdout), result.String())
+}
@@ -53,7 +53,7 @@ func NewMockUploader(apiMultiErr, apiErr bool) internal.Uploader {
 	}
 	return internal.NewRegularUploader(
 		&MockCompressor{},
-		s3.NewFolder(apiMock, s3Uploader, "server/", config),
+		s3.NewFolder(apiMock, apiMock, s3Uploader, "server/", config),
 	)
 }
 
@@ -72,7 +72,7 @@ func NewMockWalUploader(apiMultiErr, apiErr bool) *postgres.WalUploader {
 	}
 	upl := internal.NewRegularUploader(
 		&MockCompressor{},
-		s3.NewFolder(apiMock, s3Uploader, "server/", config),
+		s3.NewFolder(apiMock, apiMock, s3Uploader, "server/", config),
 	)
 	return postgres.NewWalUploader(
 		upl,